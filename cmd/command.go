@@ -23,7 +23,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/apache/answer/internal/base/conf"
 	"github.com/apache/answer/internal/base/path"
@@ -59,6 +61,12 @@ var (
 	resetPasswordEmail string
 	// resetPasswordPassword new password for password reset
 	resetPasswordPassword string
+	// searchReindexBatchSize the number of contents read at once when reindexing
+	searchReindexBatchSize int
+	// searchReindexConcurrency the number of contents sent to the search plugin at the same time when reindexing
+	searchReindexConcurrency int
+	// searchReindexRestart reindex from the beginning instead of resuming the last interrupted reindex
+	searchReindexRestart bool
 )
 
 func init() {
@@ -85,7 +93,12 @@ func init() {
 	resetPasswordCmd.Flags().StringVarP(&resetPasswordEmail, "email", "e", "", "user email address")
 	resetPasswordCmd.Flags().StringVarP(&resetPasswordPassword, "password", "p", "", "new password (not recommended, will be recorded in shell history)")
 
-	for _, cmd := range []*cobra.Command{initCmd, checkCmd, runCmd, dumpCmd, upgradeCmd, buildCmd, pluginCmd, configCmd, i18nCmd, resetPasswordCmd} {
+	searchReindexCmd.Flags().IntVarP(&searchReindexBatchSize, "batch-size", "b", 100, "the number of contents read at once")
+	searchReindexCmd.Flags().IntVarP(&searchReindexConcurrency, "concurrency", "c", 4, "the number of contents indexed at the same time")
+	searchReindexCmd.Flags().BoolVarP(&searchReindexRestart, "restart", "r", false, "reindex from the beginning instead of resuming the last interrupted reindex")
+	searchCmd.AddCommand(searchReindexCmd, searchVerifyCmd)
+
+	for _, cmd := range []*cobra.Command{initCmd, checkCmd, runCmd, dumpCmd, upgradeCmd, buildCmd, pluginCmd, configCmd, i18nCmd, resetPasswordCmd, searchCmd} {
 		rootCmd.AddCommand(cmd)
 	}
 }
@@ -332,6 +345,48 @@ To run answer, use:
 			}
 		},
 	}
+
+	searchCmd = &cobra.Command{
+		Use:   "search",
+		Short: "Manage the search index",
+		Long:  `Rebuild and verify the index of the enabled search plugin`,
+	}
+
+	searchReindexCmd = &cobra.Command{
+		Use:   "reindex",
+		Short: "Rebuild the search index",
+		Long:  `Index all the questions and answers with the enabled search plugin. An interrupted reindex is resumed when it runs again.`,
+		Example: `  # Reindex, resuming the last interrupted reindex
+  answer search reindex -C ./answer-data
+
+  # Reindex from the beginning, 500 contents at once and 8 at the same time
+  answer search reindex -C ./answer-data --restart -b 500 -c 8`,
+		Run: func(_ *cobra.Command, _ []string) {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			opts := &cli.SearchReindexOptions{
+				BatchSize:   searchReindexBatchSize,
+				Concurrency: searchReindexConcurrency,
+				Restart:     searchReindexRestart,
+			}
+			if err := cli.SearchReindex(ctx, dataDirPath, opts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	searchVerifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the search index",
+		Long:  `Compare the number of questions and answers in the database with those in the index of the enabled search plugin`,
+		Run: func(_ *cobra.Command, _ []string) {
+			if err := cli.SearchVerify(context.Background(), dataDirPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
 )
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -30,6 +30,7 @@ import (
 	"github.com/apache/answer/internal/base/cron"
 	"github.com/apache/answer/internal/base/path"
 	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/plugin/remote"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/segmentfault/pacman"
@@ -77,6 +78,9 @@ func runApp() {
 	if err != nil {
 		panic(err)
 	}
+	// the plugins are registered before the application reads them
+	stopPlugins := remote.StartPlugins(c.ExternalPlugins)
+	defer stopPlugins()
 	app, cleanup, err := initApplication(
 		c.Debug, c.Server, c.Data.Database, c.Data.Cache, c.I18n, c.Swaggerui, c.ServiceConfig, c.UI, log.GetLogger())
 	if err != nil {
@@ -39,6 +39,7 @@ import (
 	"github.com/apache/answer/internal/repo/activity"
 	"github.com/apache/answer/internal/repo/activity_common"
 	"github.com/apache/answer/internal/repo/ai_conversation"
+	"github.com/apache/answer/internal/repo/ai_embedding"
 	"github.com/apache/answer/internal/repo/answer"
 	"github.com/apache/answer/internal/repo/api_key"
 	"github.com/apache/answer/internal/repo/auth"
@@ -63,7 +64,10 @@ import (
 	"github.com/apache/answer/internal/repo/review"
 	"github.com/apache/answer/internal/repo/revision"
 	"github.com/apache/answer/internal/repo/role"
+	"github.com/apache/answer/internal/repo/saved_search"
+	"github.com/apache/answer/internal/repo/search_analytics"
 	"github.com/apache/answer/internal/repo/search_common"
+	"github.com/apache/answer/internal/repo/search_sync"
 	"github.com/apache/answer/internal/repo/site_info"
 	"github.com/apache/answer/internal/repo/tag"
 	"github.com/apache/answer/internal/repo/tag_common"
@@ -77,6 +81,9 @@ import (
 	activity_common2 "github.com/apache/answer/internal/service/activity_common"
 	"github.com/apache/answer/internal/service/activityqueue"
 	ai_conversation2 "github.com/apache/answer/internal/service/ai_conversation"
+	ai_embedding2 "github.com/apache/answer/internal/service/ai_embedding"
+	"github.com/apache/answer/internal/service/ai_prompt"
+	"github.com/apache/answer/internal/service/ai_provider"
 	"github.com/apache/answer/internal/service/answer_common"
 	"github.com/apache/answer/internal/service/apikey"
 	auth2 "github.com/apache/answer/internal/service/auth"
@@ -88,6 +95,7 @@ import (
 	config2 "github.com/apache/answer/internal/service/config"
 	"github.com/apache/answer/internal/service/content"
 	"github.com/apache/answer/internal/service/dashboard"
+	"github.com/apache/answer/internal/service/event_listener"
 	"github.com/apache/answer/internal/service/eventqueue"
 	export2 "github.com/apache/answer/internal/service/export"
 	"github.com/apache/answer/internal/service/feature_toggle"
@@ -110,7 +118,10 @@ import (
 	review2 "github.com/apache/answer/internal/service/review"
 	"github.com/apache/answer/internal/service/revision_common"
 	role2 "github.com/apache/answer/internal/service/role"
+	saved_search2 "github.com/apache/answer/internal/service/saved_search"
+	search_analytics2 "github.com/apache/answer/internal/service/search_analytics"
 	"github.com/apache/answer/internal/service/search_parser"
+	"github.com/apache/answer/internal/service/search_reindex"
 	"github.com/apache/answer/internal/service/service_config"
 	"github.com/apache/answer/internal/service/siteinfo"
 	"github.com/apache/answer/internal/service/siteinfo_common"
@@ -208,7 +219,6 @@ func initApplication(debug bool, serverConf *conf.Server, dbConf *data.Database,
 	rankService := rank2.NewRankService(userCommon, userRankRepo, objService, userRoleRelService, rolePowerRelService, configService)
 	limitRepo := limit.NewRateLimitRepo(dataData)
 	rateLimitMiddleware := middleware.NewRateLimitMiddleware(limitRepo)
-	commentController := controller.NewCommentController(commentService, rankService, captchaService, rateLimitMiddleware)
 	reportRepo := report.NewReportRepo(dataData, uniqueIDRepo)
 	tagService := tag2.NewTagService(tagRepo, tagCommonService, revisionService, followRepo, siteInfoCommonService, service)
 	answerActivityRepo := activity.NewAnswerActivityRepo(dataData, activityRepo, userRankRepo, noticequeueService)
@@ -216,6 +226,8 @@ func initApplication(debug bool, serverConf *conf.Server, dbConf *data.Database,
 	externalNotificationService := notification.NewExternalNotificationService(dataData, userNotificationConfigRepo, followRepo, emailService, userRepo, externalService, userExternalLoginRepo, siteInfoCommonService)
 	questionService := content.NewQuestionService(activityRepo, questionRepo, answerRepo, tagCommonService, tagService, questionCommon, userCommon, userRepo, userRoleRelService, revisionService, metaCommonService, collectionCommon, answerActivityService, emailService, noticequeueService, externalService, service, siteInfoCommonService, externalNotificationService, reviewService, configService, eventqueueService, reviewRepo)
 	answerService := content.NewAnswerService(answerRepo, questionRepo, questionCommon, userCommon, collectionCommon, userRepo, revisionService, answerActivityService, answerCommon, voteRepo, emailService, userRoleRelService, noticequeueService, externalService, service, reviewService, eventqueueService)
+	writeGateService := content.NewWriteGateService(rankService, captchaService, questionService, answerService, siteInfoCommonService)
+	commentController := controller.NewCommentController(commentService, rankService, captchaService, rateLimitMiddleware, writeGateService)
 	reportHandle := report_handle.NewReportHandle(questionService, answerService, commentService)
 	reportService := report2.NewReportService(reportRepo, objService, userCommon, answerRepo, questionRepo, commentCommonRepo, reportHandle, configService, eventqueueService)
 	reportController := controller.NewReportController(reportService, rankService, captchaService)
@@ -229,12 +241,18 @@ func initApplication(debug bool, serverConf *conf.Server, dbConf *data.Database,
 	collectionGroupRepo := collection.NewCollectionGroupRepo(dataData)
 	collectionService := collection2.NewCollectionService(collectionRepo, collectionGroupRepo, questionCommon)
 	collectionController := controller.NewCollectionController(collectionService)
-	questionController := controller.NewQuestionController(questionService, answerService, rankService, siteInfoCommonService, captchaService, rateLimitMiddleware)
-	answerController := controller.NewAnswerController(answerService, rankService, captchaService, siteInfoCommonService, rateLimitMiddleware)
-	searchParser := search_parser.NewSearchParser(tagCommonService, userCommon)
+	questionController := controller.NewQuestionController(questionService, answerService, rankService, siteInfoCommonService, captchaService, rateLimitMiddleware, writeGateService)
+	answerController := controller.NewAnswerController(answerService, rankService, captchaService, siteInfoCommonService, rateLimitMiddleware, writeGateService)
+	forumRepo := forumrepo.NewForumRepo(dataData, uniqueIDRepo)
+	searchParser := search_parser.NewSearchParser(tagCommonService, userCommon, forumRepo)
 	searchRepo := search_common.NewSearchRepo(dataData, uniqueIDRepo, userCommon, tagCommonService)
 	searchService := content.NewSearchService(searchParser, searchRepo)
-	searchController := controller.NewSearchController(searchService, captchaService)
+	aiEmbeddingRepo := ai_embedding.NewAIEmbeddingRepo(dataData)
+	aiProviderService := ai_provider.NewAIProviderService(siteInfoCommonService)
+	aiEmbeddingService := ai_embedding2.NewAIEmbeddingService(aiEmbeddingRepo, questionRepo, answerRepo, forumRepo, siteInfoCommonService, aiProviderService, eventqueueService)
+	searchAnalyticsRepo := search_analytics.NewSearchAnalyticsRepo(dataData)
+	searchAnalyticsService := search_analytics2.NewSearchAnalyticsService(searchAnalyticsRepo, configService)
+	searchController := controller.NewSearchController(searchService, captchaService, aiEmbeddingService, searchAnalyticsService)
 	reviewActivityRepo := activity.NewReviewActivityRepo(dataData, activityRepo, userRankRepo, configService)
 	contentRevisionService := content.NewRevisionService(revisionRepo, userCommon, questionCommon, answerService, objService, questionRepo, answerRepo, tagRepo, tagCommonService, noticequeueService, service, reportRepo, reviewService, reviewActivityRepo)
 	revisionController := controller.NewRevisionController(contentRevisionService, rankService)
@@ -250,7 +268,8 @@ func initApplication(debug bool, serverConf *conf.Server, dbConf *data.Database,
 	reasonController := controller.NewReasonController(reasonService)
 	themeController := controller_admin.NewThemeController()
 	siteInfoService := siteinfo.NewSiteInfoService(siteInfoRepo, siteInfoCommonService, emailService, tagCommonService, configService, questionCommon, fileRecordService)
-	siteInfoController := controller_admin.NewSiteInfoController(siteInfoService)
+	aiPromptService := ai_prompt.NewAIPromptService(siteInfoCommonService, userCommon)
+	siteInfoController := controller_admin.NewSiteInfoController(siteInfoService, aiProviderService, aiPromptService)
 	controllerSiteInfoController := controller.NewSiteInfoController(siteInfoCommonService)
 	notificationCommon := notificationcommon.NewNotificationCommon(dataData, notificationRepo, userCommon, activityRepo, followRepo, objService, noticequeueService, userExternalLoginRepo, siteInfoCommonService)
 	badgeRepo := badge.NewBadgeRepo(dataData, uniqueIDRepo)
@@ -267,12 +286,16 @@ func initApplication(debug bool, serverConf *conf.Server, dbConf *data.Database,
 	activityController := controller.NewActivityController(activityService)
 	roleController := controller_admin.NewRoleController(roleService)
 	pluginConfigRepo := plugin_config.NewPluginConfigRepo(dataData)
-	forumRepo := forumrepo.NewForumRepo(dataData, uniqueIDRepo)
 	importerService := importer.NewImporterService(questionService, rankService, userCommon)
+	eventListenerService := event_listener.NewEventListenerService(eventqueueService)
 	pluginCommonService := plugin_common.NewPluginCommonService(pluginConfigRepo, pluginUserConfigRepo, configService, dataData, importerService)
-	forumService := forum2.NewForumService(forumRepo, pluginCommonService)
+	forumService := forum2.NewForumService(forumRepo, pluginCommonService, tagCommonService, eventqueueService, siteInfoCommonService)
 	forumController := controller.NewForumController(forumService)
-	pluginController := controller_admin.NewPluginController(pluginCommonService)
+	savedSearchRepo := saved_search.NewSavedSearchRepo(dataData)
+	savedSearchService := saved_search2.NewSavedSearchService(dataData, savedSearchRepo, searchService, userRepo, noticequeueService, externalService)
+	savedSearchController := controller.NewSavedSearchController(savedSearchService)
+	pluginRuntimeService := plugin_common.NewPluginRuntimeService(pluginCommonService, userRoleRelService, externalService, eventListenerService)
+	pluginController := controller_admin.NewPluginController(pluginCommonService, pluginRuntimeService)
 	permissionController := controller.NewPermissionController(rankService)
 	userPluginController := controller.NewUserPluginController(pluginCommonService)
 	reviewController := controller.NewReviewController(reviewService, rankService, captchaService)
@@ -288,20 +311,24 @@ func initApplication(debug bool, serverConf *conf.Server, dbConf *data.Database,
 	apiKeyService := apikey.NewAPIKeyService(apiKeyRepo)
 	adminAPIKeyController := controller_admin.NewAdminAPIKeyController(apiKeyService)
 	featureToggleService := feature_toggle.NewFeatureToggleService(siteInfoRepo)
-	mcpController := controller.NewMCPController(searchService, siteInfoCommonService, tagCommonService, questionCommon, commentRepo, userCommon, answerRepo, featureToggleService)
+	mcpController := controller.NewMCPController(searchService, siteInfoCommonService, tagCommonService, questionCommon, commentRepo, userCommon, answerRepo, featureToggleService, forumService, questionService, answerService, commentService, writeGateService, userRoleRelService, rateLimitMiddleware)
 	aiConversationRepo := ai_conversation.NewAIConversationRepo(dataData)
-	aiConversationService := ai_conversation2.NewAIConversationService(aiConversationRepo, userCommon)
-	aiController := controller.NewAIController(searchService, siteInfoCommonService, tagCommonService, questionCommon, commentRepo, userCommon, answerRepo, mcpController, aiConversationService, featureToggleService)
-	aiConversationController := controller.NewAIConversationController(aiConversationService, featureToggleService)
+	aiConversationService := ai_conversation2.NewAIConversationService(aiConversationRepo, userCommon, userRepo, aiProviderService)
+	aiController := controller.NewAIController(searchService, siteInfoCommonService, tagCommonService, questionCommon, commentRepo, userCommon, answerRepo, mcpController, aiConversationService, featureToggleService, forumService, aiEmbeddingService, aiProviderService, aiPromptService)
+	aiConversationController := controller.NewAIConversationController(aiConversationService, featureToggleService, questionService, answerService, writeGateService)
 	aiConversationAdminController := controller_admin.NewAIConversationAdminController(aiConversationService, featureToggleService)
-	answerAPIRouter := router.NewAnswerAPIRouter(langController, userController, commentController, reportController, voteController, tagController, followController, collectionController, questionController, answerController, searchController, revisionController, rankController, userAdminController, reasonController, themeController, siteInfoController, controllerSiteInfoController, notificationController, dashboardController, uploadController, activityController, roleController, pluginController, permissionController, userPluginController, reviewController, metaController, badgeController, controller_adminBadgeController, adminAPIKeyController, aiController, aiConversationController, aiConversationAdminController, mcpController, forumController)
+	searchAnalyticsController := controller_admin.NewSearchAnalyticsController(searchAnalyticsService)
+	searchReindexRepo := search_sync.NewSearchReindexRepo(dataData)
+	searchReindexService := search_reindex.NewSearchReindexService(searchReindexRepo, configService)
+	searchReindexController := controller_admin.NewSearchReindexController(searchReindexService)
+	answerAPIRouter := router.NewAnswerAPIRouter(langController, userController, commentController, reportController, voteController, tagController, followController, collectionController, questionController, answerController, searchController, revisionController, rankController, userAdminController, reasonController, themeController, siteInfoController, controllerSiteInfoController, notificationController, dashboardController, uploadController, activityController, roleController, pluginController, permissionController, userPluginController, reviewController, metaController, badgeController, controller_adminBadgeController, adminAPIKeyController, aiController, aiConversationController, aiConversationAdminController, mcpController, forumController, searchAnalyticsController, searchReindexController, savedSearchController)
 	swaggerRouter := router.NewSwaggerRouter(swaggerConf)
 	uiRouter := router.NewUIRouter(controllerSiteInfoController, siteInfoCommonService)
 	authUserMiddleware := middleware.NewAuthUserMiddleware(authService, siteInfoCommonService)
 	avatarMiddleware := middleware.NewAvatarMiddleware(serviceConf, uploaderService)
 	shortIDMiddleware := middleware.NewShortIDMiddleware(siteInfoCommonService)
-	templateRenderController := templaterender.NewTemplateRenderController(questionService, userService, tagService, answerService, commentService, siteInfoCommonService, questionRepo)
-	templateController := controller.NewTemplateController(templateRenderController, siteInfoCommonService, eventqueueService, userService, questionService)
+	templateRenderController := templaterender.NewTemplateRenderController(questionService, userService, tagService, answerService, commentService, siteInfoCommonService, questionRepo, forumService)
+	templateController := controller.NewTemplateController(templateRenderController, siteInfoCommonService, eventqueueService, userService, questionService, forumService)
 	templateRouter := router.NewTemplateRouter(templateController, templateRenderController, siteInfoController, authUserMiddleware)
 	connectorController := controller.NewConnectorController(siteInfoCommonService, emailService, userExternalLoginService)
 	userCenterLoginService := user_external_login2.NewUserCenterLoginService(userRepo, userCommon, userExternalLoginRepo, userActiveActivityRepo, siteInfoCommonService)
@@ -312,7 +339,7 @@ func initApplication(debug bool, serverConf *conf.Server, dbConf *data.Database,
 	sidebarController := controller.NewSidebarController()
 	pluginAPIRouter := router.NewPluginAPIRouter(connectorController, userCenterController, captchaController, embedController, renderController, sidebarController)
 	ginEngine := server.NewHTTPServer(debug, staticRouter, answerAPIRouter, swaggerRouter, uiRouter, authUserMiddleware, avatarMiddleware, shortIDMiddleware, templateRouter, pluginAPIRouter, uiConf)
-	scheduledTaskManager := cron.NewScheduledTaskManager(siteInfoCommonService, questionService, fileRecordService, userAdminService, serviceConf)
+	scheduledTaskManager := cron.NewScheduledTaskManager(siteInfoCommonService, questionService, fileRecordService, userAdminService, forumService, aiEmbeddingService, searchAnalyticsService, savedSearchService, serviceConf)
 	application := newApplication(serverConf, ginEngine, scheduledTaskManager)
 	return application, func() {
 		cleanup2()
@@ -31,6 +31,7 @@ import (
 	"github.com/apache/answer/internal/router"
 	"github.com/apache/answer/internal/service/service_config"
 	"github.com/apache/answer/pkg/writer"
+	"github.com/apache/answer/plugin/remote"
 	"github.com/segmentfault/pacman/contrib/conf/viper"
 	"gopkg.in/yaml.v3"
 )
@@ -44,6 +45,8 @@ type AllConfig struct {
 	ServiceConfig *service_config.ServiceConfig `json:"service_config" mapstructure:"service_config" yaml:"service_config"`
 	Swaggerui     *router.SwaggerConfig         `json:"swaggerui" mapstructure:"swaggerui" yaml:"swaggerui"`
 	UI            *server.UI                    `json:"ui" mapstructure:"ui" yaml:"ui"`
+	// ExternalPlugins the plugins running as separate processes
+	ExternalPlugins []*remote.Config `json:"external_plugins" mapstructure:"external_plugins" yaml:"external_plugins,omitempty"`
 }
 
 type envConfigOverrides struct {
@@ -23,8 +23,9 @@ const (
 	AIConfigProvider = "ai_config.provider"
 )
 
+// DefaultAIPromptConfigZhCN and DefaultAIPromptConfigEnUS are text/template templates of schema.AIPromptData
 const (
-	DefaultAIPromptConfigZhCN = `你是一个智能助手，可以帮助用户查询系统中的信息。用户问题：%s
+	DefaultAIPromptConfigZhCN = `你是 {{.SiteName}} 的智能助手，可以帮助用户查询系统中的信息。用户问题：{{.Question}}
 
 你可以使用以下工具来查询系统信息：
 - get_questions: 搜索系统中已存在的问题，使用这个工具可以获取问题列表后注意需要使用 get_answers_by_question_id 获取问题的答案
@@ -35,7 +36,7 @@ const (
 - get_user: 搜索用户信息
 
 请根据用户的问题智能地使用这些工具来提供准确的答案。如果需要查询系统信息，请先使用相应的工具获取数据。`
-	DefaultAIPromptConfigEnUS = `You are an intelligent assistant that can help users query information in the system. User question: %s
+	DefaultAIPromptConfigEnUS = `You are the intelligent assistant of {{.SiteName}} that can help users query information in the system. User question: {{.Question}}
 
 You can use the following tools to query system information:
 - get_questions: Search for existing questions in the system. After using this tool to get the question list, you need to use get_answers_by_question_id to get the answers to the questions
@@ -46,4 +47,26 @@ You can use the following tools to query system information:
 - get_user: Search for user information
 
 Please intelligently use these tools based on the user's question to provide accurate answers. If you need to query system information, please use the appropriate tools to get the data first.`
+	DefaultForumSummaryPrompt = `You are summarising a discussion from a community forum. Reply in the language used by the posts, in Markdown, with exactly these sections:
+
+## Key points
+## Open questions
+## Proposed resolution
+
+Only use what the posts say. Mention who proposed what when it matters. Write "None" under a section that has nothing to report.
+
+%s`
+	DefaultAIRetrievalPrompt = `The following passages were retrieved from this site and may help answer the user's latest message. Ignore the passages that are not relevant. When you use a passage, cite it by its number like [1] so the user can check the source.
+
+%s`
+	DefaultAIConversationSummaryPrompt = `Update the running summary of a conversation between a user and the assistant of a community site with the messages below. Keep the facts, decisions, names, links and open questions the conversation may come back to, and drop greetings and repetition. Reply with the summary only, in the language of the conversation, in at most 300 words.
+
+Current summary:
+%s
+
+New messages:
+%s`
+	DefaultAIConversationSummaryContext = `Summary of the earlier part of this conversation:
+
+%s`
 )
@@ -44,12 +44,21 @@ const (
 	ConnectorUserExternalInfoCacheTime         = 10 * time.Minute
 	SiteMapQuestionCacheKeyPrefix              = "answer:sitemap:question:%d"
 	SiteMapQuestionCacheTime                   = time.Hour
+	SiteMapTopicCacheKeyPrefix                 = "answer:sitemap:topic:%d"
+	SiteMapTopicCacheTime                      = time.Hour
+	ForumTopicSummaryCacheKeyPrefix            = "answer:forum:summary:%s:%s"
+	ForumTopicSummaryCacheTime                 = 7 * 24 * time.Hour
 	SitemapMaxSize                             = 50000
 	NewQuestionNotificationLimitCacheKeyPrefix = "answer:new-question-notification-limit:"
 	NewQuestionNotificationLimitCacheTime      = 7 * 24 * time.Hour
 	NewQuestionNotificationLimitMax            = 50
+	SavedSearchAlertLimitCacheKeyPrefix        = "answer:saved-search-alert-limit:"
+	SavedSearchAlertLimitCacheTime             = 24 * time.Hour
+	SavedSearchAlertLimitMax                   = 50
 	RateLimitCacheKeyPrefix                    = "answer:rate-limit:"
 	RateLimitCacheTime                         = 5 * time.Minute
 	RedDotCacheKey                             = "answer:red-dot:%s:%s"
 	RedDotCacheTime                            = 30 * 24 * time.Hour
+	SimilarContentCacheKeyPrefix               = "answer:similar-content:"
+	SimilarContentCacheTime                    = time.Minute
 )
@@ -43,4 +43,10 @@ const (
 
 	EmailTplKeyNewQuestionTitle = "email_tpl.new_question.title"
 	EmailTplKeyNewQuestionBody  = "email_tpl.new_question.body"
+
+	EmailTplKeySavedSearchDigestTitle = "email_tpl.saved_search_digest.title"
+	EmailTplKeySavedSearchDigestBody  = "email_tpl.saved_search_digest.body"
+
+	EmailTplKeyPluginDisabledTitle = "email_tpl.plugin_disabled.title"
+	EmailTplKeyPluginDisabledBody  = "email_tpl.plugin_disabled.body"
 )
@@ -28,6 +28,10 @@ const (
 	eventAnswer   = "answer"
 	eventComment  = "comment"
 	eventUser     = "user"
+	eventWiki     = "wiki"
+	eventTopic    = "topic"
+	eventPost     = "post"
+	eventMergeJob = "merge_job"
 )
 
 // event action
@@ -40,6 +44,8 @@ const (
 	eventShare  = "share"  // the object share link has been clicked
 	eventFlag   = "flag"
 	eventReact  = "react"
+	eventSolve  = "solve" // the post solving the topic has been chosen
+	eventApply  = "apply" // the merge job has been applied to the wiki
 )
 
 const (
@@ -73,3 +79,23 @@ const (
 	EventCommentVote   EventType = eventComment + "." + eventVote
 	EventCommentFlag   EventType = eventComment + "." + eventFlag
 )
+
+const (
+	EventWikiUpdate EventType = eventWiki + "." + eventUpdate
+)
+
+const (
+	EventTopicCreate EventType = eventTopic + "." + eventCreate
+	EventTopicVote   EventType = eventTopic + "." + eventVote
+	EventTopicSolve  EventType = eventTopic + "." + eventSolve
+)
+
+const (
+	EventPostCreate EventType = eventPost + "." + eventCreate
+	EventPostVote   EventType = eventPost + "." + eventVote
+)
+
+const (
+	EventMergeJobCreate EventType = eventMergeJob + "." + eventCreate
+	EventMergeJobApply  EventType = eventMergeJob + "." + eventApply
+)
@@ -58,6 +58,8 @@ const (
 	NotificationInvitedYouToAnswer = "notification.action.invited_you_to_answer"
 	// NotificationEarnedBadge earned badge
 	NotificationEarnedBadge = "notification.action.earned_badge"
+	// NotificationMatchedSavedSearch matched your saved search
+	NotificationMatchedSavedSearch = "notification.action.matched_saved_search"
 )
 
 type NotificationChannelKey string
@@ -99,5 +101,6 @@ var (
 		NotificationYourAnswerWasDeleted:   1,
 		NotificationYourCommentWasDeleted:  1,
 		NotificationInvitedYouToAnswer:     3,
+		NotificationMatchedSavedSearch:     1,
 	}
 )
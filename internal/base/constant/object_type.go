@@ -40,6 +40,10 @@ const (
 	TopicVoteObjectType  = "topic_votes"
 	PostVoteObjectType   = "post_votes"
 	TopicSolutionType    = "topic_solutions"
+	ObjectConversionType = "object_conversions"
+	TopicPollType        = "topic_polls"
+	TopicPollOptionType  = "topic_poll_options"
+	TopicPollVoteType    = "topic_poll_votes"
 )
 
 var (
@@ -64,6 +68,10 @@ var (
 		TopicVoteObjectType:  19,
 		PostVoteObjectType:   20,
 		TopicSolutionType:    21,
+		ObjectConversionType: 22,
+		TopicPollType:        23,
+		TopicPollOptionType:  24,
+		TopicPollVoteType:    25,
 	}
 
 	ObjectTypeNumberMapping = map[int]string{
@@ -87,5 +95,9 @@ var (
 		19: TopicVoteObjectType,
 		20: PostVoteObjectType,
 		21: TopicSolutionType,
+		22: ObjectConversionType,
+		23: TopicPollType,
+		24: TopicPollOptionType,
+		25: TopicPollVoteType,
 	}
 )
@@ -0,0 +1,30 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package constant
+
+const (
+	// SearchAnalyticsSaltKey the config of the salt the users are hashed with in the search logs
+	SearchAnalyticsSaltKey = "search.analytics_salt"
+	// SearchReindexCheckpointKey the config of the progress of the last search reindex, to resume it
+	SearchReindexCheckpointKey = "search.reindex_checkpoint"
+	// SearchReindexLockKey the config of the process running the search reindex and until when it holds it,
+	// so the server and the command line do not reindex at the same time
+	SearchReindexLockKey = "search.reindex_lock"
+)
@@ -23,8 +23,12 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/apache/answer/internal/service/ai_embedding"
 	"github.com/apache/answer/internal/service/content"
 	"github.com/apache/answer/internal/service/file_record"
+	"github.com/apache/answer/internal/service/forum"
+	"github.com/apache/answer/internal/service/saved_search"
+	"github.com/apache/answer/internal/service/search_analytics"
 	"github.com/apache/answer/internal/service/service_config"
 	"github.com/apache/answer/internal/service/siteinfo_common"
 	"github.com/apache/answer/internal/service/user_admin"
@@ -34,11 +38,15 @@ import (
 
 // ScheduledTaskManager scheduled task manager
 type ScheduledTaskManager struct {
-	siteInfoService   siteinfo_common.SiteInfoCommonService
-	questionService   *content.QuestionService
-	fileRecordService *file_record.FileRecordService
-	userAdminService  *user_admin.UserAdminService
-	serviceConfig     *service_config.ServiceConfig
+	siteInfoService        siteinfo_common.SiteInfoCommonService
+	questionService        *content.QuestionService
+	fileRecordService      *file_record.FileRecordService
+	userAdminService       *user_admin.UserAdminService
+	forumService           *forum.ForumService
+	aiEmbeddingService     ai_embedding.AIEmbeddingService
+	searchAnalyticsService search_analytics.SearchAnalyticsService
+	savedSearchService     saved_search.SavedSearchService
+	serviceConfig          *service_config.ServiceConfig
 }
 
 // NewScheduledTaskManager new scheduled task manager
@@ -47,14 +55,22 @@ func NewScheduledTaskManager(
 	questionService *content.QuestionService,
 	fileRecordService *file_record.FileRecordService,
 	userAdminService *user_admin.UserAdminService,
+	forumService *forum.ForumService,
+	aiEmbeddingService ai_embedding.AIEmbeddingService,
+	searchAnalyticsService search_analytics.SearchAnalyticsService,
+	savedSearchService saved_search.SavedSearchService,
 	serviceConfig *service_config.ServiceConfig,
 ) *ScheduledTaskManager {
 	manager := &ScheduledTaskManager{
-		siteInfoService:   siteInfoService,
-		questionService:   questionService,
-		fileRecordService: fileRecordService,
-		userAdminService:  userAdminService,
-		serviceConfig:     serviceConfig,
+		siteInfoService:        siteInfoService,
+		questionService:        questionService,
+		fileRecordService:      fileRecordService,
+		userAdminService:       userAdminService,
+		forumService:           forumService,
+		aiEmbeddingService:     aiEmbeddingService,
+		searchAnalyticsService: searchAnalyticsService,
+		savedSearchService:     savedSearchService,
+		serviceConfig:          serviceConfig,
 	}
 	return manager
 }
@@ -63,11 +79,13 @@ func (s *ScheduledTaskManager) Run() {
 	log.Infof("cron job manager start")
 
 	s.questionService.SitemapCron(context.Background())
+	s.forumService.SitemapCron(context.Background())
 	c := cron.New()
 	_, err := c.AddFunc("0 */1 * * *", func() {
 		ctx := context.Background()
 		log.Infof("sitemap cron execution")
 		s.questionService.SitemapCron(ctx)
+		s.forumService.SitemapCron(ctx)
 	})
 	if err != nil {
 		log.Error(err)
@@ -82,6 +100,42 @@ func (s *ScheduledTaskManager) Run() {
 		log.Error(err)
 	}
 
+	_, err = c.AddFunc("*/5 * * * *", func() {
+		ctx := context.Background()
+		log.Infof("close topic polls cron execution")
+		s.forumService.ClosePollsCron(ctx)
+	})
+	if err != nil {
+		log.Error(err)
+	}
+
+	_, err = c.AddFunc("*/30 * * * *", func() {
+		ctx := context.Background()
+		log.Infof("backfill ai embeddings cron execution")
+		s.aiEmbeddingService.BackfillCron(ctx)
+	})
+	if err != nil {
+		log.Error(err)
+	}
+
+	_, err = c.AddFunc("30 0 * * *", func() {
+		ctx := context.Background()
+		log.Infof("roll up search logs cron execution")
+		s.searchAnalyticsService.RollUpCron(ctx)
+	})
+	if err != nil {
+		log.Error(err)
+	}
+
+	_, err = c.AddFunc("*/15 * * * *", func() {
+		ctx := context.Background()
+		log.Infof("saved search alert cron execution")
+		s.savedSearchService.AlertCron(ctx)
+	})
+	if err != nil {
+		log.Error(err)
+	}
+
 	// Check for expired user suspensions every 10 minutes
 	_, err = c.AddFunc("*/10 * * * *", func() {
 		ctx := context.Background()
@@ -20,12 +20,25 @@
 package middleware
 
 import (
+	"context"
+
 	"github.com/apache/answer/internal/base/handler"
 	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
 	"github.com/gin-gonic/gin"
 	"github.com/segmentfault/pacman/errors"
 )
 
+type ctxAPIKeyKey struct{}
+
+type ctxClientKey struct{}
+
+// clientInfo the address and the user agent of the client that sent the request
+type clientInfo struct {
+	ip        string
+	userAgent string
+}
+
 // AuthAPIKey middleware to authenticate API key
 func (am *AuthUserMiddleware) AuthAPIKey() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -35,7 +48,7 @@ func (am *AuthUserMiddleware) AuthAPIKey() gin.HandlerFunc {
 			ctx.Abort()
 			return
 		}
-		pass, err := am.authService.AuthAPIKey(ctx, ctx.Request.Method == "GET", token)
+		apiKeyInfo, pass, err := am.authService.AuthAPIKey(ctx, ctx.Request.Method == "GET", token)
 		if err != nil {
 			handler.HandleResponse(ctx, errors.Unauthorized(reason.UnauthorizedError), nil)
 			ctx.Abort()
@@ -46,6 +59,28 @@ func (am *AuthUserMiddleware) AuthAPIKey() gin.HandlerFunc {
 			ctx.Abort()
 			return
 		}
+		// handlers that are not gin handlers, such as the MCP tools, read the key from the request context
+		reqCtx := context.WithValue(ctx.Request.Context(), ctxAPIKeyKey{}, apiKeyInfo)
+		reqCtx = context.WithValue(reqCtx, ctxClientKey{}, &clientInfo{ip: ctx.ClientIP(), userAgent: ctx.GetHeader("User-Agent")})
+		ctx.Request = ctx.Request.WithContext(reqCtx)
 		ctx.Next()
 	}
 }
+
+// GetAPIKeyFromContext get the api key that authenticated the request
+func GetAPIKeyFromContext(ctx context.Context) *entity.APIKey {
+	apiKeyInfo, ok := ctx.Value(ctxAPIKeyKey{}).(*entity.APIKey)
+	if !ok {
+		return nil
+	}
+	return apiKeyInfo
+}
+
+// GetClientFromContext get the address and the user agent of the client that sent a request authenticated by API key
+func GetClientFromContext(ctx context.Context) (ip, userAgent string) {
+	client, ok := ctx.Value(ctxClientKey{}).(*clientInfo)
+	if !ok {
+		return "", ""
+	}
+	return client.ip, client.userAgent
+}
@@ -20,6 +20,7 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -46,26 +47,33 @@ func NewRateLimitMiddleware(limitRepo *limit.LimitRepo) *RateLimitMiddleware {
 // DuplicateRequestRejection detects and rejects duplicate requests
 // It only works for the requests that post content. Such as add question, add answer, comment etc.
 func (rm *RateLimitMiddleware) DuplicateRequestRejection(ctx *gin.Context, req any) (reject bool, key string) {
-	userID := GetLoginUserIDFromContext(ctx)
-	fullPath := ctx.FullPath()
+	reject, key = rm.CheckDuplicateRequest(ctx, GetLoginUserIDFromContext(ctx), ctx.FullPath(), req)
+	if !reject {
+		return false, key
+	}
+	handler.HandleResponse(ctx, errors.BadRequest(reason.DuplicateRequestError), nil)
+	return true, key
+}
+
+// CheckDuplicateRequest records the request and reports whether the same user sent it to the same path recently.
+// It is used directly by callers that are not gin handlers, such as the MCP tools.
+func (rm *RateLimitMiddleware) CheckDuplicateRequest(ctx context.Context, userID, path string, req any) (reject bool, key string) {
 	reqJson, _ := json.Marshal(req)
-	key = encryption.MD5(fmt.Sprintf("%s:%s:%s", userID, fullPath, string(reqJson)))
+	key = encryption.MD5(fmt.Sprintf("%s:%s:%s", userID, path, string(reqJson)))
 	var err error
 	reject, err = rm.limitRepo.CheckAndRecord(ctx, key)
 	if err != nil {
 		log.Errorf("check and record rate limit error: %s", err.Error())
 		return false, key
 	}
-	if !reject {
-		return false, key
+	if reject {
+		log.Debugf("duplicate request: [%s] %s", path, string(reqJson))
 	}
-	log.Debugf("duplicate request: [%s] %s", fullPath, string(reqJson))
-	handler.HandleResponse(ctx, errors.BadRequest(reason.DuplicateRequestError), nil)
-	return true, key
+	return reject, key
 }
 
 // DuplicateRequestClear clear duplicate request record
-func (rm *RateLimitMiddleware) DuplicateRequestClear(ctx *gin.Context, key string) {
+func (rm *RateLimitMiddleware) DuplicateRequestClear(ctx context.Context, key string) {
 	err := rm.limitRepo.ClearRecord(ctx, key)
 	if err != nil {
 		log.Errorf("clear rate limit error: %s", err.Error())
@@ -132,6 +132,36 @@ func TestQueue_RegisterHandlerAfterSend(t *testing.T) {
 	}
 }
 
+func TestQueue_MultipleHandlers(t *testing.T) {
+	q := New[*testMessage]("test", 10)
+	defer q.Close()
+
+	first := make(chan *testMessage, 1)
+	second := make(chan *testMessage, 1)
+	q.RegisterHandler(func(ctx context.Context, msg *testMessage) error {
+		first <- msg
+		return fmt.Errorf("first handler failed")
+	})
+	q.RegisterHandler(func(ctx context.Context, msg *testMessage) error {
+		second <- msg
+		return nil
+	})
+
+	q.Send(context.Background(), &testMessage{ID: 1})
+
+	// A failing handler must not stop the message reaching the next one
+	for _, ch := range []chan *testMessage{first, second} {
+		select {
+		case r := <-ch:
+			if r.ID != 1 {
+				t.Errorf("expected message ID 1, got %d", r.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+	}
+}
+
 func TestQueue_Close(t *testing.T) {
 	q := New[*testMessage]("test", 10)
 
@@ -118,6 +118,29 @@ const (
 	UserStatusSuspendedUntil         = "error.user.status_suspended_until"
 	UserStatusDeleted                = "error.user.status_deleted"
 	ErrFeatureDisabled               = "error.feature.disabled"
+	AIQuotaExceeded                  = "error.ai.quota_exceeded"
+	AIPromptTemplateInvalid          = "error.ai.prompt_template_invalid"
+	AIPromptLanguageInvalid          = "error.ai.prompt_language_invalid"
+	AIConversationPublished          = "error.ai.conversation_published"
+	AIConversationNoReply            = "error.ai.conversation_no_reply"
+	AIConversationTitleRequired      = "error.ai.conversation_title_required"
+	SearchQueryUnclosedQuote         = "error.search.unclosed_quote"
+	SearchQueryUnclosedBracket       = "error.search.unclosed_bracket"
+	SearchQueryUnclosedParen         = "error.search.unclosed_paren"
+	SearchQueryEmptyTag              = "error.search.empty_tag"
+	SearchQueryUnexpectedToken       = "error.search.unexpected_token"
+	SearchQueryMissingOperand        = "error.search.missing_operand"
+	SearchQueryTooManyTerms          = "error.search.too_many_terms"
+	SearchQueryTooDeep               = "error.search.too_deep"
+	SearchQueryInvalidFilter         = "error.search.invalid_filter"
+	SearchQueryFilterNotAllowed      = "error.search.filter_not_allowed"
+	SearchQueryConflictingFilter     = "error.search.conflicting_filter"
+	SearchQueryUnknownValue          = "error.search.unknown_value"
+	SearchReindexNoPlugin            = "error.search.reindex_no_plugin"
+	SearchReindexRunning             = "error.search.reindex_running"
+	SavedSearchNotFound              = "error.saved_search.not_found"
+	SavedSearchLimitExceeded         = "error.saved_search.limit_exceeded"
+	SimilarContentTooFrequent        = "error.similar_content.too_frequent"
 )
 
 // user external login reasons
@@ -0,0 +1,143 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/answer/internal/base/conf"
+	"github.com/apache/answer/internal/base/data"
+	"github.com/apache/answer/internal/base/path"
+	"github.com/apache/answer/internal/repo/config"
+	"github.com/apache/answer/internal/repo/plugin_config"
+	"github.com/apache/answer/internal/repo/search_sync"
+	"github.com/apache/answer/internal/schema"
+	configService "github.com/apache/answer/internal/service/config"
+	"github.com/apache/answer/internal/service/plugin_common"
+	"github.com/apache/answer/internal/service/search_reindex"
+	"github.com/apache/answer/plugin/remote"
+)
+
+type SearchReindexOptions struct {
+	BatchSize   int
+	Concurrency int
+	Restart     bool
+}
+
+// SearchReindex indexes all the questions and answers with the enabled search plugin and prints the progress,
+// it resumes the last reindex interrupted unless opts.Restart
+func SearchReindex(ctx context.Context, dataDirPath string, opts *SearchReindexOptions) error {
+	service, cleanup, err := initSearchReindexService(dataDirPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	startAt := time.Now()
+	err = service.Reindex(ctx, &schema.SearchReindexReq{
+		BatchSize:   opts.BatchSize,
+		Concurrency: opts.Concurrency,
+		Restart:     opts.Restart,
+	}, printSearchReindexProgress)
+	if ctx.Err() != nil {
+		return fmt.Errorf("reindex interrupted, run it again to resume")
+	}
+	if err != nil {
+		return fmt.Errorf("reindex failed: %w", err)
+	}
+	fmt.Printf("reindex done in %s\n", time.Since(startAt).Round(time.Second))
+	return nil
+}
+
+// SearchVerify compares the number of questions and answers in the database with those in the index
+// of the enabled search plugin, it fails when they do not match
+func SearchVerify(ctx context.Context, dataDirPath string) error {
+	service, cleanup, err := initSearchReindexService(dataDirPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	resp, err := service.Verify(ctx)
+	if err != nil {
+		return fmt.Errorf("verify failed: %w", err)
+	}
+	fmt.Printf("search plugin: %s\n", resp.Plugin)
+	fmt.Printf("questions: %d in database, %d in index\n", resp.Questions.DB, resp.Questions.Index)
+	fmt.Printf("answers: %d in database, %d in index\n", resp.Answers.DB, resp.Answers.Index)
+	if !resp.Match {
+		return fmt.Errorf("the index does not match the database, run 'answer search reindex' to rebuild it")
+	}
+	fmt.Println("the index matches the database [✔]")
+	return nil
+}
+
+func printSearchReindexProgress(progress *schema.SearchReindexProgress) {
+	fmt.Printf("[%s] questions %d/%d (failed %d), answers %d/%d (failed %d)\n",
+		progress.Plugin,
+		progress.Questions.Indexed, progress.Questions.Total, progress.Questions.Failed,
+		progress.Answers.Indexed, progress.Answers.Total, progress.Answers.Failed)
+}
+
+// initSearchReindexService connects to the database and sets up the plugins with their status and config
+// as the application does
+func initSearchReindexService(dataDirPath string) (
+	service search_reindex.SearchReindexService, cleanup func(), err error) {
+	path.FormatAllPath(dataDirPath)
+
+	c, err := conf.ReadConfig(path.GetConfigFilePath())
+	if err != nil {
+		return nil, nil, fmt.Errorf("read config file failed: %w", err)
+	}
+
+	db, err := initDatabase(c.Data.Database.Driver, c.Data.Database.Connection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect database failed: %w", err)
+	}
+
+	cache, cacheCleanup, err := data.NewCache(c.Data.Cache)
+	if err != nil {
+		_ = db.Close()
+		return nil, nil, fmt.Errorf("initialize cache failed: %w", err)
+	}
+
+	dataData, dataCleanup, err := data.NewData(db, cache)
+	if err != nil {
+		cacheCleanup()
+		_ = db.Close()
+		return nil, nil, fmt.Errorf("initialize data layer failed: %w", err)
+	}
+	stopPlugins := remote.StartPlugins(c.ExternalPlugins)
+	cleanup = func() {
+		stopPlugins()
+		dataCleanup()
+		cacheCleanup()
+	}
+
+	confService := configService.NewConfigService(config.NewConfigRepo(dataData))
+	// the importer is only used when a plugin config is updated
+	_ = plugin_common.NewPluginCommonService(plugin_config.NewPluginConfigRepo(dataData),
+		plugin_config.NewPluginUserConfigRepo(dataData), confService, dataData, nil)
+
+	service = search_reindex.NewSearchReindexService(search_sync.NewSearchReindexRepo(dataData), confService)
+	return service, cleanup, nil
+}
@@ -27,27 +27,33 @@ import (
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/apache/answer/internal/base/constant"
 	"github.com/apache/answer/internal/base/handler"
 	"github.com/apache/answer/internal/base/middleware"
+	"github.com/apache/answer/internal/entity"
 	"github.com/apache/answer/internal/schema"
 	"github.com/apache/answer/internal/schema/mcp_tools"
 	"github.com/apache/answer/internal/service/ai_conversation"
+	"github.com/apache/answer/internal/service/ai_embedding"
+	"github.com/apache/answer/internal/service/ai_prompt"
+	"github.com/apache/answer/internal/service/ai_provider"
 	answercommon "github.com/apache/answer/internal/service/answer_common"
 	"github.com/apache/answer/internal/service/comment"
 	"github.com/apache/answer/internal/service/content"
 	"github.com/apache/answer/internal/service/feature_toggle"
+	"github.com/apache/answer/internal/service/forum"
 	questioncommon "github.com/apache/answer/internal/service/question_common"
 	"github.com/apache/answer/internal/service/siteinfo_common"
 	tagcommonser "github.com/apache/answer/internal/service/tag_common"
 	usercommon "github.com/apache/answer/internal/service/user_common"
+	"github.com/apache/answer/pkg/htmltext"
 	"github.com/apache/answer/pkg/token"
 	"github.com/gin-gonic/gin"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/sashabaranov/go-openai"
 	"github.com/segmentfault/pacman/errors"
-	"github.com/segmentfault/pacman/i18n"
 	"github.com/segmentfault/pacman/log"
 )
 
@@ -62,6 +68,10 @@ type AIController struct {
 	mcpController         *MCPController
 	aiConversationService ai_conversation.AIConversationService
 	featureToggleSvc      *feature_toggle.FeatureToggleService
+	forumService          *forum.ForumService
+	aiEmbeddingService    ai_embedding.AIEmbeddingService
+	aiProviderService     ai_provider.AIProviderService
+	aiPromptService       ai_prompt.AIPromptService
 }
 
 // NewAIController new site info controller.
@@ -76,6 +86,10 @@ func NewAIController(
 	mcpController *MCPController,
 	aiConversationService ai_conversation.AIConversationService,
 	featureToggleSvc *feature_toggle.FeatureToggleService,
+	forumService *forum.ForumService,
+	aiEmbeddingService ai_embedding.AIEmbeddingService,
+	aiProviderService ai_provider.AIProviderService,
+	aiPromptService ai_prompt.AIPromptService,
 ) *AIController {
 	return &AIController{
 		searchService:         searchService,
@@ -88,6 +102,10 @@ func NewAIController(
 		mcpController:         mcpController,
 		aiConversationService: aiConversationService,
 		featureToggleSvc:      featureToggleSvc,
+		forumService:          forumService,
+		aiEmbeddingService:    aiEmbeddingService,
+		aiProviderService:     aiProviderService,
+		aiPromptService:       aiPromptService,
 	}
 }
 
@@ -128,6 +146,8 @@ type StreamResponse struct {
 	Created          int64          `json:"created"`
 	Model            string         `json:"model"`
 	Choices          []StreamChoice `json:"choices"`
+	// Sources the site content used for the answer, only sent with the final chunk
+	Sources []*schema.AIConversationSource `json:"sources,omitempty"`
 }
 
 type Choice struct {
@@ -157,18 +177,51 @@ type ConversationContext struct {
 	ConversationID    string
 	UserID            string
 	UserQuestion      string
+	Query             string // the latest user message, used for retrieval
 	Messages          []*ai_conversation.ConversationMessage
 	IsNewConversation bool
 	Model             string
+	Sources           []*schema.AIConversationSource
+	PromptTokens      int
+	CompletionTokens  int
+	// Summary is the running summary of the earlier turns that are not in Messages
+	Summary string
+	// ContextTokens is the token budget of the messages sent in each round
+	ContextTokens int
+}
+
+// AddSources records the site content gathered for the answer, each object is kept once
+func (c *ConversationContext) AddSources(sources ...*schema.AIConversationSource) {
+	for _, source := range sources {
+		if len(source.ObjectID) == 0 {
+			continue
+		}
+		exist := false
+		for _, s := range c.Sources {
+			if s.ObjectType == source.ObjectType && s.ObjectID == source.ObjectID {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			c.Sources = append(c.Sources, source)
+		}
+	}
 }
 
 func (c *ConversationContext) GetOpenAIMessages() []openai.ChatCompletionMessage {
-	messages := make([]openai.ChatCompletionMessage, len(c.Messages))
-	for i, msg := range c.Messages {
-		messages[i] = openai.ChatCompletionMessage{
+	messages := make([]openai.ChatCompletionMessage, 0, len(c.Messages)+1)
+	if len(c.Summary) > 0 {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: fmt.Sprintf(constant.DefaultAIConversationSummaryContext, c.Summary),
+		})
+	}
+	for _, msg := range c.Messages {
+		messages = append(messages, openai.ChatCompletionMessage{
 			Role:    msg.Role,
 			Content: msg.Content,
-		}
+		})
 	}
 	return messages
 }
@@ -210,6 +263,11 @@ func (c *AIController) ChatCompletions(ctx *gin.Context) {
 	}
 	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
 
+	if err := c.aiConversationService.CheckQuota(ctx, req.UserID, aiConfig.Quota); err != nil {
+		handler.HandleResponse(ctx, err, nil)
+		return
+	}
+
 	data, _ := json.Marshal(req)
 	log.Infof("ai chat request data: %s", string(data))
 
@@ -240,7 +298,7 @@ func (c *AIController) ChatCompletions(ctx *gin.Context) {
 
 	sendStreamData(w, firstResponse)
 
-	conversationCtx := c.initializeConversationContext(ctx, aiProvider.Model, req)
+	conversationCtx := c.initializeConversationContext(ctx, aiProvider.Model, aiConfig.Memory, req)
 	if conversationCtx == nil {
 		log.Error("Failed to initialize conversation context")
 		c.sendErrorResponse(w, chatcmplID, aiProvider.Model, "Failed to initialize conversation context")
@@ -256,6 +314,7 @@ func (c *AIController) ChatCompletions(ctx *gin.Context) {
 		Created:          created,
 		Model:            aiProvider.Model,
 		Choices:          []StreamChoice{{Index: 0, Delta: Delta{}, FinishReason: &finishReason}},
+		Sources:          conversationCtx.Sources,
 	}
 
 	sendStreamData(w, endResponse)
@@ -268,78 +327,128 @@ func (c *AIController) ChatCompletions(ctx *gin.Context) {
 	c.saveConversationRecord(ctx, chatcmplID, conversationCtx)
 }
 
-func (c *AIController) redirectRequestToAI(ctx *gin.Context, w http.ResponseWriter, id string, conversationCtx *ConversationContext) {
-	client := c.createOpenAIClient()
-
-	c.handleAIConversation(ctx, w, id, client, conversationCtx)
+type TopicSummaryRequest struct {
+	MergeJobID string `json:"merge_job_id"`
 }
 
-// createOpenAIClient
-func (c *AIController) createOpenAIClient() *openai.Client {
-	config := openai.DefaultConfig("")
-	config.BaseURL = ""
-
-	aiConfig, err := c.siteInfoService.GetSiteAI(context.Background())
+// TopicSummary streams a summary of the active posts of a topic, or of the posts of one merge job.
+// The summary is cached until the summarised posts change.
+func (c *AIController) TopicSummary(ctx *gin.Context) {
+	if c.featureToggleSvc != nil {
+		if err := c.featureToggleSvc.EnsureEnabled(ctx, feature_toggle.FeatureForumSummary); err != nil {
+			handler.HandleResponse(ctx, err, nil)
+			return
+		}
+	}
+	aiConfig, err := c.siteInfoService.GetSiteAI(ctx)
 	if err != nil {
 		log.Errorf("Failed to get AI config: %v", err)
-		return openai.NewClientWithConfig(config)
+		handler.HandleResponse(ctx, errors.BadRequest("AI service configuration error"), nil)
+		return
 	}
-
 	if !aiConfig.Enabled {
-		log.Warn("AI feature is disabled")
-		return openai.NewClientWithConfig(config)
+		handler.HandleResponse(ctx, errors.ServiceUnavailable("AI service is not enabled"), nil)
+		return
 	}
-
 	aiProvider := aiConfig.GetProvider()
 
-	config = openai.DefaultConfig(aiProvider.APIKey)
-	config.BaseURL = aiProvider.APIHost
-	if !strings.HasSuffix(config.BaseURL, "/v1") {
-		config.BaseURL += "/v1"
+	req := &TopicSummaryRequest{}
+	if ctx.Request.ContentLength > 0 && handler.BindAndCheck(ctx, req) {
+		return
 	}
-	return openai.NewClientWithConfig(config)
-}
-
-// getPromptByLanguage
-func (c *AIController) getPromptByLanguage(language i18n.Language, question string) string {
-	aiConfig, err := c.siteInfoService.GetSiteAI(context.Background())
+	source, err := c.forumService.GetTopicSummarySource(ctx, ctx.Param("id"), req.MergeJobID)
 	if err != nil {
-		log.Errorf("Failed to get AI config: %v", err)
-		return c.getDefaultPrompt(language, question)
+		handler.HandleResponse(ctx, err, nil)
+		return
+	}
+	userID := middleware.GetLoginUserIDFromContext(ctx)
+	if source.CachedSummary == "" {
+		if err := c.aiConversationService.CheckQuota(ctx, userID, aiConfig.Quota); err != nil {
+			handler.HandleResponse(ctx, err, nil)
+			return
+		}
 	}
 
-	var promptTemplate string
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Status(http.StatusOK)
+	w := ctx.Writer
 
-	switch language {
-	case i18n.LanguageChinese:
-		promptTemplate = aiConfig.PromptConfig.ZhCN
-	case i18n.LanguageEnglish:
-		promptTemplate = aiConfig.PromptConfig.EnUS
-	default:
-		promptTemplate = aiConfig.PromptConfig.EnUS
-	}
+	chatcmplID := "chatcmpl-" + token.GenerateToken()
+	created := time.Now().Unix()
+	sendStreamData(w, StreamResponse{
+		ChatCompletionID: chatcmplID,
+		Object:           "chat.completion.chunk",
+		Created:          created,
+		Model:            aiProvider.Model,
+		Choices:          []StreamChoice{{Index: 0, Delta: Delta{Role: "assistant"}, FinishReason: nil}},
+	})
 
-	if promptTemplate == "" {
-		return c.getDefaultPrompt(language, question)
+	if source.CachedSummary != "" {
+		sendStreamData(w, StreamResponse{
+			ChatCompletionID: chatcmplID,
+			Object:           "chat.completion.chunk",
+			Created:          created,
+			Model:            aiProvider.Model,
+			Choices:          []StreamChoice{{Index: 0, Delta: Delta{Content: source.CachedSummary}, FinishReason: nil}},
+		})
+	} else {
+		messages := []openai.ChatCompletionMessage{{
+			Role:    openai.ChatMessageRoleUser,
+			Content: forum.BuildTopicSummaryPrompt(source),
+		}}
+		aiReq := openai.ChatCompletionRequest{
+			Model:    aiProvider.Model,
+			Messages: messages,
+			Stream:   true,
+			StreamOptions: &openai.StreamOptions{
+				IncludeUsage: true,
+			},
+		}
+		stats := &streamStats{}
+		_, _, _, summary := c.processAIStream(ctx, w, chatcmplID, aiProvider.Model, aiReq, messages, stats)
+		// the stream was started when the model is known, the tokens are counted even if it was cut short
+		if len(stats.Model) > 0 {
+			usage := stats.usage(aiReq.Messages, summary, nil)
+			err := c.aiConversationService.SaveUsage(ctx, userID, entity.AIUsageSourceTopicSummary, source.Topic.ID,
+				&ai_conversation.ConversationUsage{
+					Model:            stats.Model,
+					PromptTokens:     usage.PromptTokens,
+					CompletionTokens: usage.CompletionTokens,
+				})
+			if err != nil {
+				log.Errorf("Failed to save topic summary usage: %v", err)
+			}
+		}
+		if summary != "" {
+			if err := c.forumService.SaveTopicSummary(ctx, source, summary); err != nil {
+				log.Errorf("Failed to cache topic summary: %v", err)
+			}
+		}
 	}
 
-	return fmt.Sprintf(promptTemplate, question)
+	finishReason := "stop"
+	sendStreamData(w, StreamResponse{
+		ChatCompletionID: chatcmplID,
+		Object:           "chat.completion.chunk",
+		Created:          created,
+		Model:            aiProvider.Model,
+		Choices:          []StreamChoice{{Index: 0, Delta: Delta{}, FinishReason: &finishReason}},
+	})
+	_, _ = fmt.Fprintf(w, "data: [DONE]\n\n")
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
 }
 
-// getDefaultPrompt prompt
-func (c *AIController) getDefaultPrompt(language i18n.Language, question string) string {
-	switch language {
-	case i18n.LanguageChinese:
-		return fmt.Sprintf(constant.DefaultAIPromptConfigZhCN, question)
-	case i18n.LanguageEnglish:
-		return fmt.Sprintf(constant.DefaultAIPromptConfigEnUS, question)
-	default:
-		return fmt.Sprintf(constant.DefaultAIPromptConfigEnUS, question)
-	}
+func (c *AIController) redirectRequestToAI(ctx *gin.Context, w http.ResponseWriter, id string, conversationCtx *ConversationContext) {
+	c.handleAIConversation(ctx, w, id, conversationCtx)
 }
 
-// initializeConversationContext
-func (c *AIController) initializeConversationContext(ctx *gin.Context, model string, req *ChatCompletionsRequest) *ConversationContext {
+// initializeConversationContext loads the history of the conversation that fits the token budget of the memory config
+func (c *AIController) initializeConversationContext(ctx *gin.Context, model string, memory *schema.AIMemoryConfig,
+	req *ChatCompletionsRequest) *ConversationContext {
 	if len(req.ConversationID) == 0 {
 		req.ConversationID = token.GenerateToken()
 	}
@@ -348,6 +457,15 @@ func (c *AIController) initializeConversationContext(ctx *gin.Context, model str
 		Messages:       make([]*ai_conversation.ConversationMessage, 0),
 		ConversationID: req.ConversationID,
 		Model:          model,
+		Query:          req.Messages[len(req.Messages)-1].Content,
+		ContextTokens:  ai_conversation.DefaultContextTokens,
+	}
+	recentTurns := ai_conversation.DefaultRecentTurns
+	if memory != nil && memory.ContextTokens > 0 {
+		conversationCtx.ContextTokens = memory.ContextTokens
+	}
+	if memory != nil && memory.RecentTurns > 0 {
+		recentTurns = memory.RecentTurns
 	}
 
 	conversationDetail, exist, err := c.aiConversationService.GetConversationDetail(ctx, &schema.AIConversationDetailReq{
@@ -366,13 +484,28 @@ func (c *AIController) initializeConversationContext(ctx *gin.Context, model str
 	}
 	conversationCtx.IsNewConversation = false
 
+	records := make([]*ai_conversation.ConversationMessage, 0, len(conversationDetail.Records))
 	for _, record := range conversationDetail.Records {
-		conversationCtx.Messages = append(conversationCtx.Messages, &ai_conversation.ConversationMessage{
+		records = append(records, &ai_conversation.ConversationMessage{
 			ChatCompletionID: record.ChatCompletionID,
 			Role:             record.Role,
 			Content:          record.Content,
 		})
 	}
+	// the new message and the tool definitions are sent on every round, the history gets the rest of the budget
+	budget := conversationCtx.ContextTokens - ai_conversation.EstimateTokens(req.Messages[0].Content) -
+		estimateToolTokens(c.getMCPTools())
+	history, err := c.aiConversationService.BuildHistory(ctx, req.ConversationID, records, max(budget, 0), recentTurns)
+	if err != nil {
+		log.Errorf("Failed to build conversation history: %v", err)
+		return nil
+	}
+	conversationCtx.Summary = history.Summary
+	conversationCtx.Messages = append(conversationCtx.Messages, history.Messages...)
+	if history.Usage != nil {
+		conversationCtx.PromptTokens += history.Usage.PromptTokens
+		conversationCtx.CompletionTokens += history.Usage.CompletionTokens
+	}
 	conversationCtx.Messages = append(conversationCtx.Messages, &ai_conversation.ConversationMessage{
 		Role:    req.Messages[0].Role,
 		Content: req.Messages[0].Content,
@@ -398,7 +531,7 @@ func (c *AIController) buildInitialMessages(ctx *gin.Context, req *ChatCompletio
 
 	currentLang := handler.GetLangByCtx(ctx)
 
-	prompt := c.getPromptByLanguage(currentLang, question)
+	prompt := c.aiPromptService.BuildPrompt(ctx, currentLang, req.UserID, question)
 
 	return []*ai_conversation.ConversationMessage{{Role: openai.ChatMessageRoleUser, Content: prompt}}
 }
@@ -423,27 +556,45 @@ func (c *AIController) saveConversationRecord(ctx context.Context, chatcmplID st
 		}
 	}
 
-	err := c.aiConversationService.SaveConversationRecords(ctx, conversationCtx.ConversationID, chatcmplID, conversationCtx.Messages)
+	err := c.aiConversationService.SaveConversationRecords(ctx, conversationCtx.ConversationID, chatcmplID, conversationCtx.Messages,
+		conversationCtx.Sources, &ai_conversation.ConversationUsage{
+			Model:            conversationCtx.Model,
+			PromptTokens:     conversationCtx.PromptTokens,
+			CompletionTokens: conversationCtx.CompletionTokens,
+		})
 	if err != nil {
 		log.Errorf("Failed to save conversation records: %v", err)
 	}
 }
 
-func (c *AIController) handleAIConversation(ctx *gin.Context, w http.ResponseWriter, id string, client *openai.Client, conversationCtx *ConversationContext) {
+func (c *AIController) handleAIConversation(ctx *gin.Context, w http.ResponseWriter, id string, conversationCtx *ConversationContext) {
 	maxRounds := 10
-	messages := conversationCtx.GetOpenAIMessages()
+	messages := c.addRetrievedPassages(ctx, conversationCtx, conversationCtx.GetOpenAIMessages())
+	tools := c.getMCPTools()
+	budget := conversationCtx.ContextTokens - estimateToolTokens(tools)
 
 	for round := range maxRounds {
 		log.Debugf("AI conversation round: %d", round+1)
 
+		messages = trimToolResults(messages, budget)
 		aiReq := openai.ChatCompletionRequest{
 			Model:    conversationCtx.Model,
 			Messages: messages,
-			Tools:    c.getMCPTools(),
+			Tools:    tools,
 			Stream:   true,
+			StreamOptions: &openai.StreamOptions{
+				IncludeUsage: true,
+			},
 		}
 
-		toolCalls, newMessages, finished, aiResponse := c.processAIStream(ctx, w, id, conversationCtx.Model, client, aiReq, messages)
+		stats := &streamStats{}
+		toolCalls, newMessages, finished, aiResponse := c.processAIStream(ctx, w, id, conversationCtx.Model, aiReq, messages, stats)
+		if len(stats.Model) > 0 {
+			conversationCtx.Model = stats.Model
+		}
+		usage := stats.usage(aiReq.Messages, aiResponse, toolCalls)
+		conversationCtx.PromptTokens += usage.PromptTokens
+		conversationCtx.CompletionTokens += usage.CompletionTokens
 		messages = newMessages
 
 		if aiResponse != "" {
@@ -458,7 +609,7 @@ func (c *AIController) handleAIConversation(ctx *gin.Context, w http.ResponseWri
 		}
 
 		if len(toolCalls) > 0 {
-			messages = c.executeToolCalls(ctx, w, id, conversationCtx.Model, toolCalls, messages)
+			messages = c.executeToolCalls(ctx, conversationCtx, w, id, conversationCtx.Model, toolCalls, messages)
 		} else {
 			return
 		}
@@ -467,11 +618,110 @@ func (c *AIController) handleAIConversation(ctx *gin.Context, w http.ResponseWri
 	log.Warnf("AI conversation reached maximum rounds limit: %d", maxRounds)
 }
 
-// processAIStream
+// addRetrievedPassages inserts the passages most similar to the query before the latest message.
+// The passages are only sent to the model and are not saved with the conversation.
+func (c *AIController) addRetrievedPassages(ctx context.Context, conversationCtx *ConversationContext,
+	messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	if c.aiEmbeddingService == nil || len(messages) == 0 {
+		return messages
+	}
+	passages, err := c.aiEmbeddingService.Search(ctx, conversationCtx.Query, 0)
+	if err != nil {
+		log.Errorf("Failed to retrieve passages: %v", err)
+		return messages
+	}
+	if len(passages) == 0 {
+		return messages
+	}
+
+	var b strings.Builder
+	for i, passage := range passages {
+		_, _ = fmt.Fprintf(&b, "[%d] %s (%s)\n%s\n\n", i+1, passage.Title, passage.URL, passage.Content)
+		conversationCtx.AddSources(&schema.AIConversationSource{
+			ObjectType: passage.ObjectType,
+			ObjectID:   passage.ObjectID,
+			Title:      passage.Title,
+			URL:        passage.URL,
+		})
+	}
+	retrieval := openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: fmt.Sprintf(constant.DefaultAIRetrievalPrompt, strings.TrimSpace(b.String())),
+	}
+
+	last := len(messages) - 1
+	result := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	result = append(result, messages[:last]...)
+	result = append(result, retrieval, messages[last])
+	return result
+}
+
+// estimateTokens roughly estimates the tokens of messages, about four characters per token
+func estimateTokens(messages []openai.ChatCompletionMessage) int {
+	characters := 0
+	for _, msg := range messages {
+		characters += utf8.RuneCountInString(msg.Content)
+		for _, toolCall := range msg.ToolCalls {
+			characters += utf8.RuneCountInString(toolCall.Function.Name) + utf8.RuneCountInString(toolCall.Function.Arguments)
+		}
+	}
+	return (characters + 3) / 4
+}
+
+// estimateToolTokens estimates the tokens of the tool definitions sent with each request
+func estimateToolTokens(tools []openai.Tool) int {
+	data, _ := json.Marshal(tools)
+	return ai_conversation.EstimateTokens(string(data))
+}
+
+// trimToolResults shortens the oldest tool results until the messages fit the token budget, the messages of the
+// conversation are left as they are
+func trimToolResults(messages []openai.ChatCompletionMessage, budget int) []openai.ChatCompletionMessage {
+	const minToolResultRunes = 200
+	overflow := estimateTokens(messages) - budget
+	for i := range messages {
+		if overflow <= 0 {
+			break
+		}
+		if messages[i].Role != openai.ChatMessageRoleTool {
+			continue
+		}
+		content := []rune(messages[i].Content)
+		keep := max(len(content)-overflow*4, minToolResultRunes)
+		if keep >= len(content) {
+			continue
+		}
+		overflow -= (len(content) - keep) / 4
+		messages[i].Content = string(content[:keep]) + "\n[truncated]"
+	}
+	return messages
+}
+
+// streamStats is what is known about a streamed reply once it ends
+type streamStats struct {
+	// Model of the provider that served the reply, which may be a fallback provider
+	Model string
+	Usage openai.Usage
+}
+
+// usage returns the usage reported by the provider, or an estimate from the text sent and received when the
+// provider does not report it
+func (s *streamStats) usage(messages []openai.ChatCompletionMessage, response string, toolCalls []openai.ToolCall) *openai.Usage {
+	usage := &s.Usage
+	if usage.TotalTokens == 0 && usage.PromptTokens == 0 && usage.CompletionTokens == 0 {
+		usage.PromptTokens = estimateTokens(messages)
+		usage.CompletionTokens = estimateTokens([]openai.ChatCompletionMessage{{Content: response, ToolCalls: toolCalls}})
+	}
+	return usage
+}
+
+// processAIStream streams the reply to the client. When stats is not nil the model and the token usage reported
+// by the provider are added to it.
 func (c *AIController) processAIStream(
-	_ *gin.Context, w http.ResponseWriter, id, model string, client *openai.Client, aiReq openai.ChatCompletionRequest, messages []openai.ChatCompletionMessage) (
+	_ *gin.Context, w http.ResponseWriter, id, model string, aiReq openai.ChatCompletionRequest,
+	messages []openai.ChatCompletionMessage, stats *streamStats) (
 	[]openai.ToolCall, []openai.ChatCompletionMessage, bool, string) {
-	stream, err := client.CreateChatCompletionStream(context.Background(), aiReq)
+	stream, provider, err := c.aiProviderService.CreateChatCompletionStream(context.Background(), aiReq)
 	if err != nil {
 		log.Errorf("Failed to create stream: %v", err)
 		c.sendErrorResponse(w, id, model, "Failed to create AI stream")
@@ -480,11 +730,15 @@ func (c *AIController) processAIStream(
 	defer func() {
 		_ = stream.Close()
 	}()
+	if stats != nil {
+		stats.Model = provider.Model
+	}
 
 	var currentToolCalls []openai.ToolCall
 	var accumulatedContent strings.Builder
 	var accumulatedMessage openai.ChatCompletionMessage
 	toolCallsMap := make(map[int]*openai.ToolCall)
+	var finishReason openai.FinishReason
 
 	for {
 		response, err := stream.Recv()
@@ -497,6 +751,15 @@ func (c *AIController) processAIStream(
 			break
 		}
 
+		// with include_usage the usage is sent in a last chunk without choices
+		if response.Usage != nil && stats != nil {
+			stats.Usage.PromptTokens += response.Usage.PromptTokens
+			stats.Usage.CompletionTokens += response.Usage.CompletionTokens
+			stats.Usage.TotalTokens += response.Usage.TotalTokens
+		}
+		if len(response.Choices) == 0 {
+			continue
+		}
 		choice := response.Choices[0]
 
 		if len(choice.Delta.ToolCalls) > 0 {
@@ -545,25 +808,17 @@ func (c *AIController) processAIStream(
 		}
 
 		if len(choice.FinishReason) > 0 {
-			if choice.FinishReason == "tool_calls" {
-				for _, toolCall := range toolCallsMap {
-					currentToolCalls = append(currentToolCalls, *toolCall)
-				}
-				return currentToolCalls, messages, false, accumulatedContent.String()
-			} else {
-				aiResponseContent := accumulatedContent.String()
-				if aiResponseContent != "" {
-					accumulatedMessage = openai.ChatCompletionMessage{
-						Role:    openai.ChatMessageRoleAssistant,
-						Content: aiResponseContent,
-					}
-					messages = append(messages, accumulatedMessage)
-				}
-				return nil, messages, true, aiResponseContent
-			}
+			finishReason = choice.FinishReason
 		}
 	}
 
+	if finishReason == openai.FinishReasonToolCalls {
+		for _, toolCall := range toolCallsMap {
+			currentToolCalls = append(currentToolCalls, *toolCall)
+		}
+		return currentToolCalls, messages, false, accumulatedContent.String()
+	}
+
 	aiResponseContent := accumulatedContent.String()
 	if aiResponseContent != "" {
 		accumulatedMessage = openai.ChatCompletionMessage{
@@ -573,6 +828,10 @@ func (c *AIController) processAIStream(
 		messages = append(messages, accumulatedMessage)
 	}
 
+	if len(finishReason) > 0 {
+		return nil, messages, true, aiResponseContent
+	}
+
 	if len(toolCallsMap) > 0 {
 		for _, toolCall := range toolCallsMap {
 			currentToolCalls = append(currentToolCalls, *toolCall)
@@ -584,7 +843,8 @@ func (c *AIController) processAIStream(
 }
 
 // executeToolCalls
-func (c *AIController) executeToolCalls(ctx *gin.Context, _ http.ResponseWriter, _, _ string, toolCalls []openai.ToolCall, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+func (c *AIController) executeToolCalls(ctx *gin.Context, conversationCtx *ConversationContext, _ http.ResponseWriter, _, _ string,
+	toolCalls []openai.ToolCall, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
 	validToolCalls := make([]openai.ToolCall, 0)
 	for _, toolCall := range toolCalls {
 		if toolCall.ID == "" || toolCall.Function.Name == "" {
@@ -630,6 +890,8 @@ func (c *AIController) executeToolCalls(ctx *gin.Context, _ http.ResponseWriter,
 			if err != nil {
 				log.Errorf("Failed to call MCP tool %s: %v", toolCall.Function.Name, err)
 				result = fmt.Sprintf("Error calling tool %s: %v", toolCall.Function.Name, err)
+			} else {
+				conversationCtx.AddSources(mcpToolSources(toolCall.Function.Name, result)...)
 			}
 
 			toolMessage := openai.ChatCompletionMessage{
@@ -735,6 +997,18 @@ func (c *AIController) callMCPTool(ctx context.Context, toolName string, argumen
 		result, err = c.mcpController.MCPTagDetailsHandler()(ctx, request)
 	case "get_user":
 		result, err = c.mcpController.MCPUserDetailsHandler()(ctx, request)
+	case "get_categories":
+		result, err = c.mcpController.MCPCategoriesHandler()(ctx, request)
+	case "get_topics":
+		result, err = c.mcpController.MCPTopicsHandler()(ctx, request)
+	case "get_topic_posts":
+		result, err = c.mcpController.MCPTopicPostsHandler()(ctx, request)
+	case "get_topic_wiki":
+		result, err = c.mcpController.MCPTopicWikiHandler()(ctx, request)
+	case "get_wiki_revisions":
+		result, err = c.mcpController.MCPWikiRevisionsHandler()(ctx, request)
+	case "get_doc_graph":
+		result, err = c.mcpController.MCPDocGraphHandler()(ctx, request)
 	default:
 		return "", fmt.Errorf("unknown tool: %s", toolName)
 	}
@@ -754,3 +1028,89 @@ func (c *AIController) callMCPTool(ctx context.Context, toolName string, argumen
 
 	return "No result found", nil
 }
+
+// mcpToolSources collects the questions, answers, comments, topics and wiki documents returned by a tool
+func mcpToolSources(toolName, result string) []*schema.AIConversationSource {
+	sources := make([]*schema.AIConversationSource, 0)
+	switch toolName {
+	case "get_questions":
+		list := make([]*schema.MCPSearchQuestionInfoResp, 0)
+		if json.Unmarshal([]byte(result), &list) != nil {
+			return nil
+		}
+		for _, item := range list {
+			sources = append(sources, &schema.AIConversationSource{
+				ObjectType: schema.AIConversationSourceQuestion,
+				ObjectID:   item.QuestionID,
+				Title:      item.Title,
+				URL:        item.Link,
+			})
+		}
+	case "get_answers_by_question_id":
+		list := make([]*schema.MCPSearchAnswerInfoResp, 0)
+		if json.Unmarshal([]byte(result), &list) != nil {
+			return nil
+		}
+		for _, item := range list {
+			title := item.QuestionTitle
+			if len(title) == 0 {
+				title = htmltext.FetchExcerpt(item.AnswerContent, "...", 80)
+			}
+			sources = append(sources, &schema.AIConversationSource{
+				ObjectType: schema.AIConversationSourceAnswer,
+				ObjectID:   item.AnswerID,
+				Title:      title,
+				URL:        item.Link,
+			})
+		}
+	case "get_comments":
+		list := make([]*schema.MCPSearchCommentInfoResp, 0)
+		if json.Unmarshal([]byte(result), &list) != nil {
+			return nil
+		}
+		for _, item := range list {
+			sources = append(sources, &schema.AIConversationSource{
+				ObjectType: schema.AIConversationSourceComment,
+				ObjectID:   item.CommentID,
+				Title:      htmltext.FetchExcerpt(item.Content, "...", 80),
+				URL:        item.Link,
+			})
+		}
+	case "get_topics":
+		list := make([]*schema.MCPTopicResp, 0)
+		if json.Unmarshal([]byte(result), &list) != nil {
+			return nil
+		}
+		for _, item := range list {
+			sources = append(sources, &schema.AIConversationSource{
+				ObjectType: schema.AIConversationSourceTopic,
+				ObjectID:   item.TopicID,
+				Title:      item.Title,
+				URL:        item.Link,
+			})
+		}
+	case "get_topic_posts":
+		item := &schema.MCPTopicPostsResp{}
+		if json.Unmarshal([]byte(result), item) != nil {
+			return nil
+		}
+		sources = append(sources, &schema.AIConversationSource{
+			ObjectType: schema.AIConversationSourceTopic,
+			ObjectID:   item.TopicID,
+			Title:      item.Title,
+			URL:        item.Link,
+		})
+	case "get_topic_wiki":
+		item := &schema.MCPWikiResp{}
+		if json.Unmarshal([]byte(result), item) != nil {
+			return nil
+		}
+		sources = append(sources, &schema.AIConversationSource{
+			ObjectType: schema.AIConversationSourceWiki,
+			ObjectID:   item.TopicID,
+			Title:      item.Title,
+			URL:        item.Link,
+		})
+	}
+	return sources
+}
@@ -22,26 +22,40 @@ package controller
 import (
 	"github.com/apache/answer/internal/base/handler"
 	"github.com/apache/answer/internal/base/middleware"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/base/validator"
 	"github.com/apache/answer/internal/schema"
 	"github.com/apache/answer/internal/service/ai_conversation"
+	"github.com/apache/answer/internal/service/content"
 	"github.com/apache/answer/internal/service/feature_toggle"
+	"github.com/apache/answer/pkg/uid"
 	"github.com/gin-gonic/gin"
+	"github.com/segmentfault/pacman/errors"
 )
 
 // AIConversationController ai conversation controller
 type AIConversationController struct {
 	aiConversationService ai_conversation.AIConversationService
 	featureToggleSvc      *feature_toggle.FeatureToggleService
+	questionService       *content.QuestionService
+	answerService         *content.AnswerService
+	writeGateService      *content.WriteGateService
 }
 
 // NewAIConversationController creates a new AI conversation controller
 func NewAIConversationController(
 	aiConversationService ai_conversation.AIConversationService,
 	featureToggleSvc *feature_toggle.FeatureToggleService,
+	questionService *content.QuestionService,
+	answerService *content.AnswerService,
+	writeGateService *content.WriteGateService,
 ) *AIConversationController {
 	return &AIConversationController{
 		aiConversationService: aiConversationService,
 		featureToggleSvc:      featureToggleSvc,
+		questionService:       questionService,
+		answerService:         answerService,
+		writeGateService:      writeGateService,
 	}
 }
 
@@ -128,3 +142,102 @@ func (ctrl *AIConversationController) VoteRecord(ctx *gin.Context) {
 	err := ctrl.aiConversationService.VoteRecord(ctx, req)
 	handler.HandleResponse(ctx, err, nil)
 }
+
+// PublishConversation publishes the conversation as a question, the last reply becomes its answer by the bot user
+// @Summary publish conversation as question and answer
+// @Description the first prompt becomes the question and the last reply a suggested answer, both are reviewed as usual
+// @Tags ai-conversation
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body schema.AIConversationPublishReq true "publish request"
+// @Success 200 {object} handler.RespBody{data=schema.AIConversationPublishResp}
+// @Router /answer/api/v1/ai/conversation/publish [post]
+func (ctrl *AIConversationController) PublishConversation(ctx *gin.Context) {
+	if !ctrl.ensureEnabled(ctx) {
+		return
+	}
+	req := &schema.AIConversationPublishReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
+
+	draft, err := ctrl.aiConversationService.GetPublishDraft(ctx, req)
+	if err != nil {
+		handler.HandleResponse(ctx, err, nil)
+		return
+	}
+	botUserID, err := ctrl.aiConversationService.GetBotUserID(ctx)
+	if err != nil {
+		handler.HandleResponse(ctx, err, nil)
+		return
+	}
+
+	questionReq := &schema.QuestionAdd{
+		Title:   draft.Title,
+		Content: draft.Question,
+		Tags:    req.Tags,
+		UserID:  req.UserID,
+	}
+	_, _ = questionReq.Check()
+	pass, errFields, err := ctrl.writeGateService.CheckAddQuestion(ctx, &content.WriteActor{
+		UserID:      req.UserID,
+		IsAdmin:     middleware.GetUserIsAdminModerator(ctx),
+		CaptchaID:   req.CaptchaID,
+		CaptchaCode: req.CaptchaCode,
+	}, questionReq)
+	if err != nil {
+		handler.HandleResponse(ctx, err, errFields)
+		return
+	}
+	if errList, err := ctrl.questionService.CheckAddQuestion(ctx, questionReq); err != nil {
+		errFields, _ := errList.([]*validator.FormErrorField)
+		handler.HandleResponse(ctx, errors.BadRequest(reason.RequestFormatError), errFields)
+		return
+	}
+
+	// claim the conversation before the question is created, so concurrent requests publish it only once
+	if err = ctrl.aiConversationService.ClaimPublish(ctx, req.ConversationID); err != nil {
+		handler.HandleResponse(ctx, err, nil)
+		return
+	}
+	questionReq.UserAgent = ctx.GetHeader("User-Agent")
+	questionReq.IP = ctx.ClientIP()
+	resp, err := ctrl.questionService.AddQuestion(ctx, questionReq)
+	ctrl.writeGateService.RecordWrite(ctx, pass)
+	if err != nil {
+		ctrl.aiConversationService.ReleasePublish(ctx, req.ConversationID)
+		errFields, _ := resp.([]*validator.FormErrorField)
+		handler.HandleResponse(ctx, err, errFields)
+		return
+	}
+	questionInfo, ok := resp.(*schema.QuestionInfoResp)
+	if !ok {
+		ctrl.aiConversationService.ReleasePublish(ctx, req.ConversationID)
+		handler.HandleResponse(ctx, errors.InternalServer(reason.UnknownError), nil)
+		return
+	}
+	// link the question first, so a failed answer does not let the conversation be published twice
+	if err = ctrl.aiConversationService.SetPublishedQuestion(ctx, req.ConversationID, questionInfo.ID); err != nil {
+		handler.HandleResponse(ctx, err, nil)
+		return
+	}
+
+	answerReq := &schema.AnswerAddReq{
+		QuestionID: uid.DeShortID(questionInfo.ID),
+		UserID:     botUserID,
+		Content:    draft.Answer,
+		IP:         questionReq.IP,
+		UserAgent:  questionReq.UserAgent,
+	}
+	if _, err = answerReq.Check(); err != nil {
+		handler.HandleResponse(ctx, err, nil)
+		return
+	}
+	answerID, err := ctrl.answerService.Insert(ctx, answerReq)
+	handler.HandleResponse(ctx, err, &schema.AIConversationPublishResp{
+		QuestionID: questionInfo.ID,
+		AnswerID:   answerID,
+	})
+}
@@ -47,6 +47,7 @@ type AnswerController struct {
 	actionService         *action.CaptchaService
 	siteInfoCommonService siteinfo_common.SiteInfoCommonService
 	rateLimitMiddleware   *middleware.RateLimitMiddleware
+	writeGateService      *content.WriteGateService
 }
 
 // NewAnswerController new controller
@@ -56,6 +57,7 @@ func NewAnswerController(
 	actionService *action.CaptchaService,
 	siteInfoCommonService siteinfo_common.SiteInfoCommonService,
 	rateLimitMiddleware *middleware.RateLimitMiddleware,
+	writeGateService *content.WriteGateService,
 ) *AnswerController {
 	return &AnswerController{
 		answerService:         answerService,
@@ -63,6 +65,7 @@ func NewAnswerController(
 		actionService:         actionService,
 		siteInfoCommonService: siteInfoCommonService,
 		rateLimitMiddleware:   rateLimitMiddleware,
+		writeGateService:      writeGateService,
 	}
 }
 
@@ -208,58 +211,17 @@ func (ac *AnswerController) AddAnswer(ctx *gin.Context) {
 	req.QuestionID = uid.DeShortID(req.QuestionID)
 	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
 
-	canList, err := ac.rankService.CheckOperationPermissions(ctx, req.UserID, []string{
-		permission.AnswerEdit,
-		permission.AnswerDelete,
-		permission.LinkUrlLimit,
-	})
+	pass, errFields, err := ac.writeGateService.CheckAddAnswer(ctx, &content.WriteActor{
+		UserID:      req.UserID,
+		IsAdmin:     middleware.GetUserIsAdminModerator(ctx),
+		CaptchaID:   req.CaptchaID,
+		CaptchaCode: req.CaptchaCode,
+	}, req)
 	if err != nil {
-		handler.HandleResponse(ctx, err, nil)
+		handler.HandleResponse(ctx, err, errFields)
 		return
 	}
 
-	linkUrlLimitUser := canList[2]
-	isAdmin := middleware.GetUserIsAdminModerator(ctx)
-	if !isAdmin || !linkUrlLimitUser {
-		captchaPass := ac.actionService.ActionRecordVerifyCaptcha(ctx, entity.CaptchaActionAnswer, req.UserID, req.CaptchaID, req.CaptchaCode)
-		if !captchaPass {
-			errFields := append([]*validator.FormErrorField{}, &validator.FormErrorField{
-				ErrorField: "captcha_code",
-				ErrorMsg:   translator.Tr(handler.GetLangByCtx(ctx), reason.CaptchaVerificationFailed),
-			})
-			handler.HandleResponse(ctx, errors.BadRequest(reason.CaptchaVerificationFailed), errFields)
-			return
-		}
-	}
-
-	can, err := ac.rankService.CheckOperationPermission(ctx, req.UserID, permission.AnswerAdd, "")
-	if err != nil {
-		handler.HandleResponse(ctx, err, nil)
-		return
-	}
-	if !can {
-		handler.HandleResponse(ctx, errors.Forbidden(reason.RankFailToMeetTheCondition), nil)
-		return
-	}
-
-	write, err := ac.siteInfoCommonService.GetSiteQuestion(ctx)
-	if err != nil {
-		handler.HandleResponse(ctx, err, nil)
-		return
-	}
-	if write.RestrictAnswer {
-		// check if there's already an answer by this user
-		ids, err := ac.answerService.GetCountByUserIDQuestionID(ctx, req.UserID, req.QuestionID)
-		if err != nil {
-			handler.HandleResponse(ctx, err, nil)
-			return
-		}
-		if len(ids) >= 1 {
-			handler.HandleResponse(ctx, errors.Forbidden(reason.AnswerRestrictAnswer), nil)
-			return
-		}
-	}
-
 	req.UserAgent = ctx.GetHeader("User-Agent")
 	req.IP = ctx.ClientIP()
 
@@ -268,9 +230,7 @@ func (ac *AnswerController) AddAnswer(ctx *gin.Context) {
 		handler.HandleResponse(ctx, err, nil)
 		return
 	}
-	if !isAdmin || !linkUrlLimitUser {
-		ac.actionService.ActionRecordAdd(ctx, entity.CaptchaActionAnswer, req.UserID)
-	}
+	ac.writeGateService.RecordWrite(ctx, pass)
 	info, questionInfo, has, err := ac.answerService.Get(ctx, answerID, req.UserID)
 	if err != nil {
 		handler.HandleResponse(ctx, err, nil)
@@ -282,8 +242,8 @@ func (ac *AnswerController) AddAnswer(ctx *gin.Context) {
 	}
 
 	objectOwner := ac.rankService.CheckOperationObjectOwner(ctx, req.UserID, info.ID)
-	req.CanEdit = canList[0] || objectOwner
-	req.CanDelete = canList[1] || objectOwner
+	req.CanEdit = req.CanEdit || objectOwner
+	req.CanDelete = req.CanDelete || objectOwner
 	info.MemberActions = permission.GetAnswerPermission(ctx, req.UserID, info.UserID,
 		0, req.CanEdit, req.CanDelete, false)
 	handler.HandleResponse(ctx, nil, gin.H{
@@ -31,6 +31,7 @@ import (
 	"github.com/apache/answer/internal/schema"
 	"github.com/apache/answer/internal/service/action"
 	"github.com/apache/answer/internal/service/comment"
+	"github.com/apache/answer/internal/service/content"
 	"github.com/apache/answer/internal/service/permission"
 	"github.com/apache/answer/internal/service/rank"
 	"github.com/apache/answer/pkg/uid"
@@ -44,6 +45,7 @@ type CommentController struct {
 	rankService         *rank.RankService
 	actionService       *action.CaptchaService
 	rateLimitMiddleware *middleware.RateLimitMiddleware
+	writeGateService    *content.WriteGateService
 }
 
 // NewCommentController new controller
@@ -52,12 +54,14 @@ func NewCommentController(
 	rankService *rank.RankService,
 	actionService *action.CaptchaService,
 	rateLimitMiddleware *middleware.RateLimitMiddleware,
+	writeGateService *content.WriteGateService,
 ) *CommentController {
 	return &CommentController{
 		commentService:      commentService,
 		rankService:         rankService,
 		actionService:       actionService,
 		rateLimitMiddleware: rateLimitMiddleware,
+		writeGateService:    writeGateService,
 	}
 }
 
@@ -89,35 +93,14 @@ func (cc *CommentController) AddComment(ctx *gin.Context) {
 	req.ObjectID = uid.DeShortID(req.ObjectID)
 	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
 
-	canList, err := cc.rankService.CheckOperationPermissions(ctx, req.UserID, []string{
-		permission.CommentAdd,
-		permission.CommentEdit,
-		permission.CommentDelete,
-		permission.LinkUrlLimit,
-	})
+	pass, errFields, err := cc.writeGateService.CheckAddComment(ctx, &content.WriteActor{
+		UserID:      req.UserID,
+		IsAdmin:     middleware.GetUserIsAdminModerator(ctx),
+		CaptchaID:   req.CaptchaID,
+		CaptchaCode: req.CaptchaCode,
+	}, req)
 	if err != nil {
-		handler.HandleResponse(ctx, err, nil)
-		return
-	}
-	linkUrlLimitUser := canList[3]
-	isAdmin := middleware.GetUserIsAdminModerator(ctx)
-	if !isAdmin || !linkUrlLimitUser {
-		captchaPass := cc.actionService.ActionRecordVerifyCaptcha(ctx, entity.CaptchaActionComment, req.UserID, req.CaptchaID, req.CaptchaCode)
-		if !captchaPass {
-			errFields := append([]*validator.FormErrorField{}, &validator.FormErrorField{
-				ErrorField: "captcha_code",
-				ErrorMsg:   translator.Tr(handler.GetLangByCtx(ctx), reason.CaptchaVerificationFailed),
-			})
-			handler.HandleResponse(ctx, errors.BadRequest(reason.CaptchaVerificationFailed), errFields)
-			return
-		}
-	}
-
-	req.CanAdd = canList[0]
-	req.CanEdit = canList[1]
-	req.CanDelete = canList[2]
-	if !req.CanAdd {
-		handler.HandleResponse(ctx, errors.Forbidden(reason.RankFailToMeetTheCondition), nil)
+		handler.HandleResponse(ctx, err, errFields)
 		return
 	}
 
@@ -125,9 +108,7 @@ func (cc *CommentController) AddComment(ctx *gin.Context) {
 	req.IP = ctx.ClientIP()
 
 	resp, err := cc.commentService.AddComment(ctx, req)
-	if !isAdmin || !linkUrlLimitUser {
-		cc.actionService.ActionRecordAdd(ctx, entity.CaptchaActionComment, req.UserID)
-	}
+	cc.writeGateService.RecordWrite(ctx, pass)
 	handler.HandleResponse(ctx, err, resp)
 }
 
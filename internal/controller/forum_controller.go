@@ -23,6 +23,7 @@ import (
 	"github.com/apache/answer/internal/base/handler"
 	"github.com/apache/answer/internal/base/middleware"
 	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
 	"github.com/apache/answer/internal/schema"
 	"github.com/apache/answer/internal/service/forum"
 	"github.com/gin-gonic/gin"
@@ -90,7 +91,54 @@ func (fc *ForumController) ListTopicPosts(ctx *gin.Context) {
 
 func (fc *ForumController) GetTopic(ctx *gin.Context) {
 	topic, err := fc.forumService.GetTopic(ctx, ctx.Param("id"))
-	handler.HandleResponse(ctx, err, topic)
+	if err != nil {
+		handler.HandleResponse(ctx, err, nil)
+		return
+	}
+	if topic.Status == entity.TopicStatusConverted {
+		conversion, exist, err := fc.forumService.GetObjectConversion(ctx, topic.ID)
+		if err != nil {
+			handler.HandleResponse(ctx, err, nil)
+			return
+		}
+		if exist {
+			handler.HandleResponse(ctx, nil, &schema.TopicDetailResp{Topic: topic, ConvertedTo: &schema.ObjectConversionResp{
+				ObjectType: conversion.TargetObjectType,
+				ObjectID:   forumURLID(ctx, conversion.TargetObjectID),
+			}})
+			return
+		}
+	}
+	poll, err := fc.forumService.GetTopicPoll(ctx, topic.ID, middleware.GetLoginUserIDFromContext(ctx))
+	handler.HandleResponse(ctx, err, &schema.TopicDetailResp{Topic: topic, Poll: poll})
+}
+
+func (fc *ForumController) GetTopicPoll(ctx *gin.Context) {
+	poll, err := fc.forumService.GetTopicPoll(ctx, ctx.Param("id"), middleware.GetLoginUserIDFromContext(ctx))
+	if err == nil && poll == nil {
+		err = errors.NotFound(reason.ObjectNotFound)
+	}
+	handler.HandleResponse(ctx, err, poll)
+}
+
+func (fc *ForumController) SaveTopicPoll(ctx *gin.Context) {
+	req := &schema.TopicPollReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
+	poll, err := fc.forumService.SaveTopicPoll(ctx, ctx.Param("id"), req)
+	handler.HandleResponse(ctx, err, poll)
+}
+
+func (fc *ForumController) VoteTopicPoll(ctx *gin.Context) {
+	req := &schema.TopicPollVoteReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
+	poll, err := fc.forumService.VoteTopicPoll(ctx, ctx.Param("id"), req)
+	handler.HandleResponse(ctx, err, poll)
 }
 
 func (fc *ForumController) CreateTopic(ctx *gin.Context) {
@@ -99,10 +147,40 @@ func (fc *ForumController) CreateTopic(ctx *gin.Context) {
 		return
 	}
 	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
+	req.CanUseReservedTag = middleware.GetUserIsAdminModerator(ctx)
 	topic, err := fc.forumService.CreateTopic(ctx, req)
 	handler.HandleResponse(ctx, err, topic)
 }
 
+func (fc *ForumController) GetTopicTags(ctx *gin.Context) {
+	tags, err := fc.forumService.GetTopicTags(ctx, ctx.Param("id"))
+	handler.HandleResponse(ctx, err, tags)
+}
+
+func (fc *ForumController) UpdateTopicTags(ctx *gin.Context) {
+	req := &schema.UpdateTopicTagsReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
+	req.IsAdminModerator = middleware.GetUserIsAdminModerator(ctx)
+	req.CanUseReservedTag = req.IsAdminModerator
+	tags, err := fc.forumService.UpdateTopicTags(ctx, ctx.Param("id"), req)
+	handler.HandleResponse(ctx, err, tags)
+}
+
+func (fc *ForumController) ListTagContents(ctx *gin.Context) {
+	req := &schema.TagContentListReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+	contents, total, err := fc.forumService.ListTagContents(ctx, ctx.Param("name"), req)
+	handler.HandleResponse(ctx, err, gin.H{
+		"list":  contents,
+		"total": total,
+	})
+}
+
 func (fc *ForumController) CreateTopicPost(ctx *gin.Context) {
 	req := &schema.CreatePostReq{}
 	if handler.BindAndCheck(ctx, req) {
@@ -241,6 +319,28 @@ func (fc *ForumController) VoteTopic(ctx *gin.Context) {
 	handler.HandleResponse(ctx, err, nil)
 }
 
+// ConvertQuestionToTopic converts a question into a forum topic
+func (fc *ForumController) ConvertQuestionToTopic(ctx *gin.Context) {
+	req := &schema.ConvertQuestionToTopicReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+	req.OperatorID = middleware.GetLoginUserIDFromContext(ctx)
+	topic, err := fc.forumService.ConvertQuestionToTopic(ctx, req)
+	handler.HandleResponse(ctx, err, topic)
+}
+
+// ConvertTopicToQuestion converts a forum topic into a question
+func (fc *ForumController) ConvertTopicToQuestion(ctx *gin.Context) {
+	req := &schema.ConvertTopicToQuestionReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+	req.OperatorID = middleware.GetLoginUserIDFromContext(ctx)
+	question, err := fc.forumService.ConvertTopicToQuestion(ctx, req)
+	handler.HandleResponse(ctx, err, question)
+}
+
 func (fc *ForumController) GetPlatformPlugins(ctx *gin.Context) {
 	plugins, err := fc.forumService.GetPlatformPlugins(ctx)
 	handler.HandleResponse(ctx, err, plugins)
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/apache/answer/internal/base/middleware"
 	"github.com/apache/answer/internal/base/pager"
 	"github.com/apache/answer/internal/entity"
 	"github.com/apache/answer/internal/schema"
@@ -32,11 +33,14 @@ import (
 	"github.com/apache/answer/internal/service/comment"
 	"github.com/apache/answer/internal/service/content"
 	"github.com/apache/answer/internal/service/feature_toggle"
+	"github.com/apache/answer/internal/service/forum"
 	questioncommon "github.com/apache/answer/internal/service/question_common"
+	"github.com/apache/answer/internal/service/role"
 	"github.com/apache/answer/internal/service/siteinfo_common"
 	tagcommonser "github.com/apache/answer/internal/service/tag_common"
 	usercommon "github.com/apache/answer/internal/service/user_common"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/segmentfault/pacman/errors"
 	"github.com/segmentfault/pacman/log"
 )
 
@@ -49,6 +53,14 @@ type MCPController struct {
 	userCommon       *usercommon.UserCommon
 	answerRepo       answercommon.AnswerRepo
 	featureToggleSvc *feature_toggle.FeatureToggleService
+	forumService     *forum.ForumService
+
+	questionService     *content.QuestionService
+	answerService       *content.AnswerService
+	commentService      *comment.CommentService
+	writeGateService    *content.WriteGateService
+	userRoleRelService  *role.UserRoleRelService
+	rateLimitMiddleware *middleware.RateLimitMiddleware
 }
 
 // NewMCPController new site info controller.
@@ -61,6 +73,13 @@ func NewMCPController(
 	userCommon *usercommon.UserCommon,
 	answerRepo answercommon.AnswerRepo,
 	featureToggleSvc *feature_toggle.FeatureToggleService,
+	forumService *forum.ForumService,
+	questionService *content.QuestionService,
+	answerService *content.AnswerService,
+	commentService *comment.CommentService,
+	writeGateService *content.WriteGateService,
+	userRoleRelService *role.UserRoleRelService,
+	rateLimitMiddleware *middleware.RateLimitMiddleware,
 ) *MCPController {
 	return &MCPController{
 		searchService:    searchService,
@@ -71,6 +90,14 @@ func NewMCPController(
 		userCommon:       userCommon,
 		answerRepo:       answerRepo,
 		featureToggleSvc: featureToggleSvc,
+		forumService:     forumService,
+
+		questionService:     questionService,
+		answerService:       answerService,
+		commentService:      commentService,
+		writeGateService:    writeGateService,
+		userRoleRelService:  userRoleRelService,
+		rateLimitMiddleware: rateLimitMiddleware,
 	}
 }
 
@@ -349,3 +376,291 @@ func (c *MCPController) MCPUserDetailsHandler() func(ctx context.Context, reques
 		return mcp.NewToolResultText(string(res)), nil
 	}
 }
+
+func (c *MCPController) MCPCategoriesHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := c.ensureMCPEnabled(ctx); err != nil {
+			return nil, err
+		}
+		cond := schema.NewMCPForumCond(request)
+
+		siteGeneral, err := c.siteInfoService.GetSiteGeneral(ctx)
+		if err != nil {
+			log.Errorf("get site general info failed: %v", err)
+			return nil, err
+		}
+
+		categories, total, err := c.forumService.ListCategories(ctx, &schema.CategoryListReq{Page: cond.Page, PageSize: 20})
+		if err != nil {
+			log.Errorf("get categories failed: %v", err)
+			return nil, err
+		}
+		if total == 0 {
+			return mcp.NewToolResultText("No categories found."), nil
+		}
+
+		resp := make([]*schema.MCPCategoryResp, 0, len(categories))
+		for _, category := range categories {
+			resp = append(resp, &schema.MCPCategoryResp{
+				CategoryID:  category.ID,
+				Slug:        category.Slug,
+				Name:        category.Name,
+				Description: category.Description,
+				Link:        fmt.Sprintf("%s/categories/%s", siteGeneral.SiteUrl, category.ID),
+			})
+		}
+		data, _ := json.Marshal(resp)
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+func (c *MCPController) MCPTopicsHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := c.ensureMCPEnabled(ctx); err != nil {
+			return nil, err
+		}
+		cond := schema.NewMCPForumCond(request)
+		if len(cond.CategoryID) == 0 {
+			return mcp.NewToolResultError("category_id is required"), nil
+		}
+
+		siteGeneral, err := c.siteInfoService.GetSiteGeneral(ctx)
+		if err != nil {
+			log.Errorf("get site general info failed: %v", err)
+			return nil, err
+		}
+
+		topics, total, err := c.forumService.ListTopicsByCategory(ctx, cond.CategoryID, &schema.TopicListReq{
+			Page:     cond.Page,
+			PageSize: 10,
+			Tag:      cond.Tag,
+		})
+		if err != nil {
+			log.Errorf("get topics failed: %v", err)
+			return nil, err
+		}
+		if total == 0 {
+			return mcp.NewToolResultText("No topics found."), nil
+		}
+
+		resp := make([]*schema.MCPTopicResp, 0, len(topics))
+		for _, topic := range topics {
+			resp = append(resp, &schema.MCPTopicResp{
+				TopicID:   topic.ID,
+				Title:     topic.Title,
+				TopicKind: topic.TopicKind,
+				Status:    topic.Status,
+				PostCount: topic.PostCount,
+				VoteCount: topic.VoteCount,
+				HasWiki:   topic.CurrentWikiRevisionID != "" && topic.CurrentWikiRevisionID != "0",
+				Link:      fmt.Sprintf("%s/topics/%s", siteGeneral.SiteUrl, topic.ID),
+			})
+		}
+		data, _ := json.Marshal(resp)
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+func (c *MCPController) MCPTopicPostsHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := c.ensureMCPEnabled(ctx); err != nil {
+			return nil, err
+		}
+		cond := schema.NewMCPForumCond(request)
+
+		siteGeneral, err := c.siteInfoService.GetSiteGeneral(ctx)
+		if err != nil {
+			log.Errorf("get site general info failed: %v", err)
+			return nil, err
+		}
+
+		topic, notFound, err := c.getMCPTopic(ctx, cond.TopicID)
+		if err != nil || notFound != nil {
+			return notFound, err
+		}
+		posts, total, err := c.forumService.ListTopicPosts(ctx, topic.ID, &schema.PostListReq{Page: cond.Page, PageSize: 20})
+		if err != nil {
+			log.Errorf("get topic posts failed: %v", err)
+			return nil, err
+		}
+
+		resp := &schema.MCPTopicPostsResp{
+			TopicID: topic.ID,
+			Title:   topic.Title,
+			Total:   total,
+			Posts:   make([]*schema.MCPPostResp, 0, len(posts)),
+			Link:    fmt.Sprintf("%s/topics/%s", siteGeneral.SiteUrl, topic.ID),
+		}
+		for _, post := range posts {
+			author := post.AuthorDisplayName
+			if len(author) == 0 {
+				author = post.AuthorUsername
+			}
+			resp.Posts = append(resp.Posts, &schema.MCPPostResp{
+				PostID:     post.ID,
+				Author:     author,
+				Content:    post.OriginalText,
+				VoteCount:  post.VoteCount,
+				MergeState: post.MergeState,
+				CreatedAt:  post.CreatedAt.Unix(),
+			})
+		}
+		data, _ := json.Marshal(resp)
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+func (c *MCPController) MCPTopicWikiHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := c.ensureMCPEnabled(ctx); err != nil {
+			return nil, err
+		}
+		cond := schema.NewMCPForumCond(request)
+
+		siteGeneral, err := c.siteInfoService.GetSiteGeneral(ctx)
+		if err != nil {
+			log.Errorf("get site general info failed: %v", err)
+			return nil, err
+		}
+
+		topic, notFound, err := c.getMCPTopic(ctx, cond.TopicID)
+		if err != nil || notFound != nil {
+			return notFound, err
+		}
+		revision, err := c.forumService.GetTopicWiki(ctx, topic.ID)
+		if err != nil {
+			log.Errorf("get topic wiki failed: %v", err)
+			return nil, err
+		}
+		if revision == nil {
+			return mcp.NewToolResultText("This topic has no wiki document yet."), nil
+		}
+
+		resp := &schema.MCPWikiResp{
+			TopicID:    topic.ID,
+			RevisionID: revision.ID,
+			Title:      revision.Title,
+			Document:   revision.Document,
+			Summary:    revision.Summary,
+			EditorID:   revision.EditorID,
+			CreatedAt:  revision.CreatedAt.Unix(),
+			Link:       fmt.Sprintf("%s/topics/%s/wiki", siteGeneral.SiteUrl, topic.ID),
+		}
+		data, _ := json.Marshal(resp)
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+func (c *MCPController) MCPWikiRevisionsHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := c.ensureMCPEnabled(ctx); err != nil {
+			return nil, err
+		}
+		cond := schema.NewMCPForumCond(request)
+
+		topic, notFound, err := c.getMCPTopic(ctx, cond.TopicID)
+		if err != nil || notFound != nil {
+			return notFound, err
+		}
+		revisions, err := c.forumService.ListWikiRevisions(ctx, topic.ID)
+		if err != nil {
+			log.Errorf("get wiki revisions failed: %v", err)
+			return nil, err
+		}
+		if len(revisions) == 0 {
+			return mcp.NewToolResultText("This topic has no wiki revisions yet."), nil
+		}
+
+		resp := make([]*schema.MCPWikiRevisionResp, 0, len(revisions))
+		for _, revision := range revisions {
+			resp = append(resp, &schema.MCPWikiRevisionResp{
+				RevisionID:       revision.ID,
+				ParentRevisionID: revision.ParentRevisionID,
+				Title:            revision.Title,
+				Summary:          revision.Summary,
+				EditorID:         revision.EditorID,
+				Current:          revision.ID == topic.CurrentWikiRevisionID,
+				CreatedAt:        revision.CreatedAt.Unix(),
+			})
+		}
+		data, _ := json.Marshal(resp)
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+func (c *MCPController) MCPDocGraphHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := c.ensureMCPEnabled(ctx); err != nil {
+			return nil, err
+		}
+		cond := schema.NewMCPForumCond(request)
+		if cond.Depth < 1 || cond.Depth > 5 {
+			cond.Depth = 2
+		}
+
+		siteGeneral, err := c.siteInfoService.GetSiteGeneral(ctx)
+		if err != nil {
+			log.Errorf("get site general info failed: %v", err)
+			return nil, err
+		}
+
+		topic, notFound, err := c.getMCPTopic(ctx, cond.TopicID)
+		if err != nil || notFound != nil {
+			return notFound, err
+		}
+		graph, err := c.forumService.GetDocGraph(ctx, &schema.GetDocGraphReq{RootTopicID: topic.ID, Depth: cond.Depth})
+		if err != nil {
+			log.Errorf("get doc graph failed: %v", err)
+			return nil, err
+		}
+		topics, err := c.forumService.GetTopicsByIDs(ctx, graph.Nodes)
+		if err != nil {
+			log.Errorf("get doc graph topics failed: %v", err)
+			return nil, err
+		}
+		titles := make(map[string]string, len(topics))
+		for _, t := range topics {
+			titles[t.ID] = t.Title
+		}
+
+		resp := &schema.MCPDocGraphResp{
+			Nodes: make([]*schema.MCPDocGraphNode, 0, len(graph.Nodes)),
+			Edges: make([]*schema.MCPDocGraphEdge, 0, len(graph.Edges)),
+		}
+		for _, id := range graph.Nodes {
+			resp.Nodes = append(resp.Nodes, &schema.MCPDocGraphNode{
+				TopicID: id,
+				Title:   titles[id],
+				Link:    fmt.Sprintf("%s/topics/%s/wiki", siteGeneral.SiteUrl, id),
+			})
+		}
+		for _, edge := range graph.Edges {
+			resp.Edges = append(resp.Edges, &schema.MCPDocGraphEdge{
+				SourceTopicID: edge.SourceTopicID,
+				TargetTopicID: edge.TargetTopicID,
+				LinkType:      edge.LinkType,
+			})
+		}
+		data, _ := json.Marshal(resp)
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+// getMCPTopic returns the topic, or a tool result explaining why it can't be read.
+func (c *MCPController) getMCPTopic(ctx context.Context, topicID string) (*entity.Topic, *mcp.CallToolResult, error) {
+	if len(topicID) == 0 {
+		return nil, mcp.NewToolResultError("topic_id is required"), nil
+	}
+	topic, err := c.forumService.GetTopic(ctx, topicID)
+	if err != nil {
+		if e, ok := err.(*errors.Error); ok && errors.IsNotFound(e) {
+			return nil, mcp.NewToolResultText("Topic not found."), nil
+		}
+		log.Errorf("get topic failed: %v", err)
+		return nil, nil, err
+	}
+	if topic.Status == entity.TopicStatusConverted {
+		return nil, mcp.NewToolResultText("This topic was converted into a question."), nil
+	}
+	return topic, nil, nil
+}
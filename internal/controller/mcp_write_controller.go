@@ -0,0 +1,405 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/handler"
+	"github.com/apache/answer/internal/base/middleware"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/base/translator"
+	"github.com/apache/answer/internal/base/validator"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/content"
+	"github.com/apache/answer/internal/service/role"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
+)
+
+// mcpWriter the owner of the API key that calls an MCP write tool, and the client it calls from
+type mcpWriter struct {
+	userID    string
+	isAdmin   bool
+	ip        string
+	userAgent string
+}
+
+// actor an API key cannot solve a captcha, so once the action limit asks for one the write is refused
+func (w *mcpWriter) actor() *content.WriteActor {
+	return &content.WriteActor{UserID: w.userID, IsAdmin: w.isAdmin}
+}
+
+// MCPToolFilter hides the write tools that the calling API key has not enabled
+func (c *MCPController) MCPToolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	apiKeyInfo := middleware.GetAPIKeyFromContext(ctx)
+	filtered := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if isMCPWriteTool(tool.Name) && (apiKeyInfo == nil || !apiKeyInfo.MCPWriteToolEnabled(tool.Name)) {
+			continue
+		}
+		filtered = append(filtered, tool)
+	}
+	return filtered
+}
+
+func (c *MCPController) MCPCreateQuestionHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		writer, denied, err := c.getMCPWriter(ctx, schema.MCPToolCreateQuestion)
+		if err != nil || denied != nil {
+			return denied, err
+		}
+		cond := schema.NewMCPWriteCond(request)
+		req := &schema.QuestionAdd{
+			Title:     cond.Title,
+			Content:   cond.Content,
+			Tags:      make([]*schema.TagItem, 0, len(cond.Tags)),
+			UserID:    writer.userID,
+			IP:        writer.ip,
+			UserAgent: writer.userAgent,
+		}
+		for _, tag := range cond.Tags {
+			req.Tags = append(req.Tags, &schema.TagItem{SlugName: tag, DisplayName: tag})
+		}
+		if errFields, err := validator.GetValidatorByLang(handler.GetLangByCtx(ctx)).Check(req); err != nil {
+			return mcpToolResultFromError(ctx, err, errFields)
+		}
+
+		pass, errFields, err := c.writeGateService.CheckAddQuestion(ctx, writer.actor(), req)
+		if err != nil {
+			return mcpWriteGateResult(ctx, err, errFields)
+		}
+		if errList, err := c.questionService.CheckAddQuestion(ctx, req); err != nil {
+			errFields, _ := errList.([]*validator.FormErrorField)
+			return mcpToolResultFromError(ctx, err, errFields)
+		}
+
+		rejectKey, duplicate := c.checkMCPDuplicateRequest(ctx, writer, schema.MCPToolCreateQuestion, req)
+		if duplicate != nil {
+			return duplicate, nil
+		}
+		resp, err := c.questionService.AddQuestion(ctx, req)
+		if err != nil {
+			c.rateLimitMiddleware.DuplicateRequestClear(ctx, rejectKey)
+			errFields, _ := resp.([]*validator.FormErrorField)
+			return mcpToolResultFromError(ctx, err, errFields)
+		}
+		c.writeGateService.RecordWrite(ctx, pass)
+		questionInfo, ok := resp.(*schema.QuestionInfoResp)
+		if !ok {
+			return mcp.NewToolResultText("The question was created."), nil
+		}
+		siteGeneral, err := c.siteInfoService.GetSiteGeneral(ctx)
+		if err != nil {
+			log.Errorf("get site general info failed: %v", err)
+			return nil, err
+		}
+		return mcpWriteResult(&schema.MCPWriteResp{
+			ObjectType: constant.QuestionObjectType,
+			ObjectID:   questionInfo.ID,
+			Pending:    questionInfo.Status == entity.QuestionStatusPending,
+			Link:       fmt.Sprintf("%s/questions/%s", siteGeneral.SiteUrl, questionInfo.ID),
+		})
+	}
+}
+
+func (c *MCPController) MCPPostAnswerHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		writer, denied, err := c.getMCPWriter(ctx, schema.MCPToolPostAnswer)
+		if err != nil || denied != nil {
+			return denied, err
+		}
+		cond := schema.NewMCPWriteCond(request)
+		if len(cond.QuestionID) == 0 {
+			return mcp.NewToolResultError("question_id is required"), nil
+		}
+		req := &schema.AnswerAddReq{
+			QuestionID: uid.DeShortID(cond.QuestionID),
+			Content:    cond.Content,
+			UserID:     writer.userID,
+			IP:         writer.ip,
+			UserAgent:  writer.userAgent,
+		}
+		if errFields, err := validator.GetValidatorByLang(handler.GetLangByCtx(ctx)).Check(req); err != nil {
+			return mcpToolResultFromError(ctx, err, errFields)
+		}
+
+		pass, errFields, err := c.writeGateService.CheckAddAnswer(ctx, writer.actor(), req)
+		if err != nil {
+			return mcpWriteGateResult(ctx, err, errFields)
+		}
+
+		rejectKey, duplicate := c.checkMCPDuplicateRequest(ctx, writer, schema.MCPToolPostAnswer, req)
+		if duplicate != nil {
+			return duplicate, nil
+		}
+		answerID, err := c.answerService.Insert(ctx, req)
+		if err != nil {
+			c.rateLimitMiddleware.DuplicateRequestClear(ctx, rejectKey)
+			return mcpToolResultFromError(ctx, err, nil)
+		}
+		c.writeGateService.RecordWrite(ctx, pass)
+		answerInfo, exist, err := c.answerRepo.GetAnswer(ctx, answerID)
+		if err != nil {
+			return nil, err
+		}
+		siteGeneral, err := c.siteInfoService.GetSiteGeneral(ctx)
+		if err != nil {
+			log.Errorf("get site general info failed: %v", err)
+			return nil, err
+		}
+		return mcpWriteResult(&schema.MCPWriteResp{
+			ObjectType: constant.AnswerObjectType,
+			ObjectID:   answerID,
+			Pending:    exist && answerInfo.Status == entity.AnswerStatusPending,
+			Link:       fmt.Sprintf("%s/questions/%s/%s", siteGeneral.SiteUrl, cond.QuestionID, answerID),
+		})
+	}
+}
+
+func (c *MCPController) MCPAddCommentHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		writer, denied, err := c.getMCPWriter(ctx, schema.MCPToolAddComment)
+		if err != nil || denied != nil {
+			return denied, err
+		}
+		cond := schema.NewMCPWriteCond(request)
+		req := &schema.AddCommentReq{
+			ObjectID:       uid.DeShortID(cond.ObjectID),
+			ReplyCommentID: cond.ReplyCommentID,
+			OriginalText:   cond.Content,
+			UserID:         writer.userID,
+			IP:             writer.ip,
+			UserAgent:      writer.userAgent,
+		}
+		if errFields, err := validator.GetValidatorByLang(handler.GetLangByCtx(ctx)).Check(req); err != nil {
+			return mcpToolResultFromError(ctx, err, errFields)
+		}
+
+		pass, errFields, err := c.writeGateService.CheckAddComment(ctx, writer.actor(), req)
+		if err != nil {
+			return mcpWriteGateResult(ctx, err, errFields)
+		}
+
+		rejectKey, duplicate := c.checkMCPDuplicateRequest(ctx, writer, schema.MCPToolAddComment, req)
+		if duplicate != nil {
+			return duplicate, nil
+		}
+		resp, err := c.commentService.AddComment(ctx, req)
+		c.writeGateService.RecordWrite(ctx, pass)
+		if err != nil {
+			c.rateLimitMiddleware.DuplicateRequestClear(ctx, rejectKey)
+			return mcpToolResultFromError(ctx, err, nil)
+		}
+		commentInfo, exist, err := c.commentRepo.GetComment(ctx, resp.CommentID)
+		if err != nil {
+			return nil, err
+		}
+		result := &schema.MCPWriteResp{
+			ObjectType: constant.CommentObjectType,
+			ObjectID:   resp.CommentID,
+		}
+		if exist {
+			result.Pending = commentInfo.Status == entity.CommentStatusPending
+			siteGeneral, err := c.siteInfoService.GetSiteGeneral(ctx)
+			if err != nil {
+				log.Errorf("get site general info failed: %v", err)
+				return nil, err
+			}
+			result.Link = fmt.Sprintf("%s/questions/%s", siteGeneral.SiteUrl, uid.EnShortID(commentInfo.QuestionID))
+		}
+		return mcpWriteResult(result)
+	}
+}
+
+func (c *MCPController) MCPCreateForumPostHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		writer, denied, err := c.getMCPWriter(ctx, schema.MCPToolCreateForumPost)
+		if err != nil || denied != nil {
+			return denied, err
+		}
+		cond := schema.NewMCPWriteCond(request)
+		topic, notFound, err := c.getMCPTopic(ctx, cond.TopicID)
+		if err != nil || notFound != nil {
+			return notFound, err
+		}
+		req := &schema.CreatePostReq{
+			OriginalText: cond.Content,
+			UserID:       writer.userID,
+		}
+		if errFields, err := validator.GetValidatorByLang(handler.GetLangByCtx(ctx)).Check(req); err != nil {
+			return mcpToolResultFromError(ctx, err, errFields)
+		}
+
+		rejectKey, duplicate := c.checkMCPDuplicateRequest(ctx, writer, schema.MCPToolCreateForumPost+":"+topic.ID, req)
+		if duplicate != nil {
+			return duplicate, nil
+		}
+		post, err := c.forumService.CreatePost(ctx, topic.ID, req)
+		if err != nil {
+			c.rateLimitMiddleware.DuplicateRequestClear(ctx, rejectKey)
+			return mcpToolResultFromError(ctx, err, nil)
+		}
+		siteGeneral, err := c.siteInfoService.GetSiteGeneral(ctx)
+		if err != nil {
+			log.Errorf("get site general info failed: %v", err)
+			return nil, err
+		}
+		return mcpWriteResult(&schema.MCPWriteResp{
+			ObjectType: constant.PostObjectType,
+			ObjectID:   post.ID,
+			Link:       fmt.Sprintf("%s/topics/%s", siteGeneral.SiteUrl, topic.ID),
+		})
+	}
+}
+
+func (c *MCPController) MCPProposeMergeJobHandler() func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		writer, denied, err := c.getMCPWriter(ctx, schema.MCPToolProposeMergeJob)
+		if err != nil || denied != nil {
+			return denied, err
+		}
+		cond := schema.NewMCPWriteCond(request)
+		topic, notFound, err := c.getMCPTopic(ctx, cond.TopicID)
+		if err != nil || notFound != nil {
+			return notFound, err
+		}
+		req := &schema.CreateMergeJobReq{
+			PostIDs:   cond.PostIDs,
+			Summary:   cond.Summary,
+			CreatorID: writer.userID,
+		}
+		if errFields, err := validator.GetValidatorByLang(handler.GetLangByCtx(ctx)).Check(req); err != nil {
+			return mcpToolResultFromError(ctx, err, errFields)
+		}
+
+		rejectKey, duplicate := c.checkMCPDuplicateRequest(ctx, writer, schema.MCPToolProposeMergeJob+":"+topic.ID, req)
+		if duplicate != nil {
+			return duplicate, nil
+		}
+		job, err := c.forumService.CreateMergeJob(ctx, topic.ID, req)
+		if err != nil {
+			c.rateLimitMiddleware.DuplicateRequestClear(ctx, rejectKey)
+			return mcpToolResultFromError(ctx, err, nil)
+		}
+		siteGeneral, err := c.siteInfoService.GetSiteGeneral(ctx)
+		if err != nil {
+			log.Errorf("get site general info failed: %v", err)
+			return nil, err
+		}
+		return mcpWriteResult(&schema.MCPWriteResp{
+			ObjectType: constant.MergeJobObjectType,
+			ObjectID:   job.ID,
+			Pending:    job.Status == entity.MergeJobStatusPending,
+			Link:       fmt.Sprintf("%s/topics/%s", siteGeneral.SiteUrl, topic.ID),
+		})
+	}
+}
+
+// getMCPWriter checks that the calling API key enables the write tool and that its owner can still write.
+// A refusal is returned as a tool result so that the agent can tell the user about it.
+func (c *MCPController) getMCPWriter(ctx context.Context, tool string) (*mcpWriter, *mcp.CallToolResult, error) {
+	if err := c.ensureMCPEnabled(ctx); err != nil {
+		return nil, nil, err
+	}
+	apiKeyInfo := middleware.GetAPIKeyFromContext(ctx)
+	if apiKeyInfo == nil || !apiKeyInfo.MCPWriteToolEnabled(tool) {
+		return nil, mcp.NewToolResultError(fmt.Sprintf("The API key does not enable the %s tool.", tool)), nil
+	}
+	userInfo, exist, err := c.userCommon.GetUserBasicInfoByID(ctx, apiKeyInfo.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exist {
+		return nil, mcp.NewToolResultError("The owner of the API key no longer exists."), nil
+	}
+	if userInfo.Status != constant.UserNormal {
+		return nil, mcp.NewToolResultError(fmt.Sprintf("The owner of the API key cannot write, account status: %s.", userInfo.Status)), nil
+	}
+	roleID, err := c.userRoleRelService.GetUserRole(ctx, userInfo.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ip, userAgent := middleware.GetClientFromContext(ctx)
+	return &mcpWriter{
+		userID:    userInfo.ID,
+		isAdmin:   roleID == role.RoleAdminID || roleID == role.RoleModeratorID,
+		ip:        ip,
+		userAgent: userAgent,
+	}, nil, nil
+}
+
+// checkMCPDuplicateRequest rejects the same content sent twice in a short time, like the HTTP API does.
+// The returned key should be cleared when the write fails, so that the agent can retry.
+func (c *MCPController) checkMCPDuplicateRequest(ctx context.Context, writer *mcpWriter, path string, req any) (
+	rejectKey string, duplicate *mcp.CallToolResult) {
+	reject, rejectKey := c.rateLimitMiddleware.CheckDuplicateRequest(ctx, writer.userID, "mcp:"+path, req)
+	if !reject {
+		return rejectKey, nil
+	}
+	return rejectKey, mcp.NewToolResultError(translator.Tr(handler.GetLangByCtx(ctx), reason.DuplicateRequestError))
+}
+
+// mcpWriteGateResult turns a refused write into a tool error. The captcha asked for by the action limit
+// cannot be solved by an API key, so it is reported as the limit being reached.
+func mcpWriteGateResult(ctx context.Context, err error, errFields []*validator.FormErrorField) (*mcp.CallToolResult, error) {
+	if e, ok := err.(*errors.Error); ok && e.Reason == reason.CaptchaVerificationFailed {
+		return mcp.NewToolResultError("The owner of the API key has reached the action limit. Please try again later."), nil
+	}
+	return mcpToolResultFromError(ctx, err, errFields)
+}
+
+// mcpToolResultFromError turns a client error into a tool error the agent can read. Server errors are returned as is.
+func mcpToolResultFromError(ctx context.Context, err error, errFields []*validator.FormErrorField) (*mcp.CallToolResult, error) {
+	e, ok := err.(*errors.Error)
+	if !ok || errors.IsInternalServer(e) {
+		return nil, err
+	}
+	msg := handler.NewRespBodyFromError(e).TrMsg(handler.GetLangByCtx(ctx)).Message
+	details := make([]string, 0, len(errFields))
+	for _, field := range errFields {
+		details = append(details, fmt.Sprintf("%s: %s", field.ErrorField, field.ErrorMsg))
+	}
+	if len(details) > 0 {
+		msg = fmt.Sprintf("%s (%s)", msg, strings.Join(details, "; "))
+	}
+	return mcp.NewToolResultError(msg), nil
+}
+
+func mcpWriteResult(resp *schema.MCPWriteResp) (*mcp.CallToolResult, error) {
+	res, _ := json.Marshal(resp)
+	return mcp.NewToolResultText(string(res)), nil
+}
+
+func isMCPWriteTool(name string) bool {
+	for _, tool := range schema.MCPWriteTools {
+		if tool == name {
+			return true
+		}
+	}
+	return false
+}
@@ -49,6 +49,7 @@ type QuestionController struct {
 	siteInfoService     siteinfo_common.SiteInfoCommonService
 	actionService       *action.CaptchaService
 	rateLimitMiddleware *middleware.RateLimitMiddleware
+	writeGateService    *content.WriteGateService
 }
 
 // NewQuestionController new controller
@@ -59,6 +60,7 @@ func NewQuestionController(
 	siteInfoService siteinfo_common.SiteInfoCommonService,
 	actionService *action.CaptchaService,
 	rateLimitMiddleware *middleware.RateLimitMiddleware,
+	writeGateService *content.WriteGateService,
 ) *QuestionController {
 	return &QuestionController{
 		questionService:     questionService,
@@ -67,6 +69,7 @@ func NewQuestionController(
 		siteInfoService:     siteInfoService,
 		actionService:       actionService,
 		rateLimitMiddleware: rateLimitMiddleware,
+		writeGateService:    writeGateService,
 	}
 }
 
@@ -398,56 +401,14 @@ func (qc *QuestionController) AddQuestion(ctx *gin.Context) {
 	}()
 
 	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
-	canList, requireRanks, err := qc.rankService.CheckOperationPermissionsForRanks(ctx, req.UserID, []string{
-		permission.QuestionAdd,
-		permission.QuestionEdit,
-		permission.QuestionDelete,
-		permission.QuestionClose,
-		permission.QuestionReopen,
-		permission.TagUseReservedTag,
-		permission.TagAdd,
-		permission.LinkUrlLimit,
-	})
+	pass, gateErrFields, err := qc.writeGateService.CheckAddQuestion(ctx, &content.WriteActor{
+		UserID:      req.UserID,
+		IsAdmin:     middleware.GetUserIsAdminModerator(ctx),
+		CaptchaID:   req.CaptchaID,
+		CaptchaCode: req.CaptchaCode,
+	}, req)
 	if err != nil {
-		handler.HandleResponse(ctx, err, nil)
-		return
-	}
-	linkUrlLimitUser := canList[7]
-	isAdmin := middleware.GetUserIsAdminModerator(ctx)
-	if !isAdmin || !linkUrlLimitUser {
-		captchaPass := qc.actionService.ActionRecordVerifyCaptcha(ctx, entity.CaptchaActionQuestion, req.UserID, req.CaptchaID, req.CaptchaCode)
-		if !captchaPass {
-			errFields := append([]*validator.FormErrorField{}, &validator.FormErrorField{
-				ErrorField: "captcha_code",
-				ErrorMsg:   translator.Tr(handler.GetLangByCtx(ctx), reason.CaptchaVerificationFailed),
-			})
-			handler.HandleResponse(ctx, errors.BadRequest(reason.CaptchaVerificationFailed), errFields)
-			return
-		}
-	}
-
-	req.CanAdd = canList[0]
-	req.CanEdit = canList[1]
-	req.CanDelete = canList[2]
-	req.CanClose = canList[3]
-	req.CanReopen = canList[4]
-	req.CanUseReservedTag = canList[5]
-	req.CanAddTag = canList[6]
-	if !req.CanAdd {
-		handler.HandleResponse(ctx, errors.Forbidden(reason.RankFailToMeetTheCondition), nil)
-		return
-	}
-
-	// can add tag
-	hasNewTag, err := qc.questionService.HasNewTag(ctx, req.Tags)
-	if err != nil {
-		handler.HandleResponse(ctx, err, nil)
-		return
-	}
-	if !req.CanAddTag && hasNewTag {
-		lang := handler.GetLangByCtx(ctx)
-		msg := translator.TrWithData(lang, reason.NoEnoughRankToOperate, &schema.PermissionTrTplData{Rank: requireRanks[6]})
-		handler.HandleResponse(ctx, errors.Forbidden(reason.NoEnoughRankToOperate).WithMsg(msg), nil)
+		handler.HandleResponse(ctx, err, gateErrFields)
 		return
 	}
 
@@ -479,9 +440,7 @@ func (qc *QuestionController) AddQuestion(ctx *gin.Context) {
 		handler.HandleResponse(ctx, errors.BadRequest(reason.RequestFormatError), errFields)
 		return
 	}
-	if !isAdmin || !linkUrlLimitUser {
-		qc.actionService.ActionRecordAdd(ctx, entity.CaptchaActionQuestion, req.UserID)
-	}
+	qc.writeGateService.RecordWrite(ctx, pass)
 	handler.HandleResponse(ctx, err, resp)
 }
 
@@ -20,6 +20,9 @@
 package controller
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/apache/answer/internal/base/handler"
 	"github.com/apache/answer/internal/base/middleware"
 	"github.com/apache/answer/internal/base/reason"
@@ -28,26 +31,40 @@ import (
 	"github.com/apache/answer/internal/entity"
 	"github.com/apache/answer/internal/schema"
 	"github.com/apache/answer/internal/service/action"
+	"github.com/apache/answer/internal/service/ai_embedding"
 	"github.com/apache/answer/internal/service/content"
+	"github.com/apache/answer/internal/service/search_analytics"
 	"github.com/apache/answer/plugin"
 	"github.com/gin-gonic/gin"
 	"github.com/segmentfault/pacman/errors"
 )
 
+const (
+	// similarContentLimit lookups of similar content are allowed in each similarContentWindow
+	similarContentLimit  = 30
+	similarContentWindow = time.Minute
+)
+
 // SearchController tag controller
 type SearchController struct {
-	searchService *content.SearchService
-	actionService *action.CaptchaService
+	searchService          *content.SearchService
+	actionService          *action.CaptchaService
+	aiEmbeddingService     ai_embedding.AIEmbeddingService
+	searchAnalyticsService search_analytics.SearchAnalyticsService
 }
 
 // NewSearchController new controller
 func NewSearchController(
 	searchService *content.SearchService,
 	actionService *action.CaptchaService,
+	aiEmbeddingService ai_embedding.AIEmbeddingService,
+	searchAnalyticsService search_analytics.SearchAnalyticsService,
 ) *SearchController {
 	return &SearchController{
-		searchService: searchService,
-		actionService: actionService,
+		searchService:          searchService,
+		actionService:          actionService,
+		aiEmbeddingService:     aiEmbeddingService,
+		searchAnalyticsService: searchAnalyticsService,
 	}
 }
 
@@ -89,9 +106,35 @@ func (sc *SearchController) Search(ctx *gin.Context) {
 		sc.actionService.ActionRecordAdd(ctx, entity.CaptchaActionSearch, unit)
 	}
 	resp, err := sc.searchService.Search(ctx, &dto)
+	// the searches are counted once, not for each page
+	if err == nil && dto.Page == 1 {
+		resp.SearchID = sc.searchAnalyticsService.RecordSearch(ctx, dto.UserID, ctx.ClientIP(), dto.Query, resp.Total)
+	}
 	handler.HandleResponse(ctx, err, resp)
 }
 
+// SearchClick record the result clicked in a search
+// @Summary record the result clicked in a search
+// @Description record the first result clicked on the first page of a search, for the search analytics
+// @Tags Search
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body schema.SearchClickReq true "search click"
+// @Success 200 {object} handler.RespBody
+// @Router /answer/api/v1/search/click [post]
+func (sc *SearchController) SearchClick(ctx *gin.Context) {
+	req := &schema.SearchClickReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
+	req.IP = ctx.ClientIP()
+
+	err := sc.searchAnalyticsService.RecordClick(ctx, req)
+	handler.HandleResponse(ctx, err, nil)
+}
+
 // SearchDesc get search description
 // @Summary get search description
 // @Description get search description
@@ -113,3 +156,31 @@ func (sc *SearchController) SearchDesc(ctx *gin.Context) {
 	}
 	handler.HandleResponse(ctx, nil, resp)
 }
+
+// SimilarContent get the questions, topics and wiki documents similar to a title
+// @Summary get the questions, topics and wiki documents similar to a title
+// @Description ranked with the embedding index when AI is enabled and with BM25 otherwise, the confidence tells how likely each is a duplicate. Titles shorter than 10 characters get no results and each user can do 30 lookups a minute.
+// @Tags Search
+// @Produce json
+// @Security ApiKeyAuth
+// @Param title query string true "title of the question or topic being written"
+// @Param limit query int false "limit, at most 20"
+// @Success 200 {object} handler.RespBody{data=schema.SimilarContentResp}
+// @Router /answer/api/v1/similar [get]
+func (sc *SearchController) SimilarContent(ctx *gin.Context) {
+	req := &schema.SimilarContentReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+	unit := ctx.ClientIP()
+	if userID := middleware.GetLoginUserIDFromContext(ctx); userID != "" {
+		unit = userID
+	}
+	if !middleware.GetUserIsAdminModerator(ctx) &&
+		sc.actionService.ActionRecordOverLimit(ctx, entity.CaptchaActionSimilar, unit, similarContentLimit, similarContentWindow) {
+		handler.HandleResponse(ctx, errors.New(http.StatusTooManyRequests, reason.SimilarContentTooFrequent), nil)
+		return
+	}
+	resp, err := sc.aiEmbeddingService.SimilarContent(ctx, req)
+	handler.HandleResponse(ctx, err, resp)
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/apache/answer/internal/base/pager"
 	"github.com/apache/answer/internal/service/content"
 	"github.com/apache/answer/internal/service/eventqueue"
+	"github.com/apache/answer/internal/service/forum"
 	"github.com/apache/answer/plugin"
 
 	"github.com/apache/answer/internal/base/constant"
@@ -44,6 +45,7 @@ import (
 	"github.com/apache/answer/internal/service/siteinfo_common"
 	"github.com/apache/answer/pkg/checker"
 	"github.com/apache/answer/pkg/converter"
+	"github.com/apache/answer/pkg/display"
 	"github.com/apache/answer/pkg/htmltext"
 	"github.com/apache/answer/pkg/obj"
 	"github.com/apache/answer/pkg/uid"
@@ -62,6 +64,7 @@ type TemplateController struct {
 	eventQueueService        eventqueue.Service
 	userService              *content.UserService
 	questionService          *content.QuestionService
+	forumService             *forum.ForumService
 }
 
 // NewTemplateController new controller
@@ -71,6 +74,7 @@ func NewTemplateController(
 	eventQueueService eventqueue.Service,
 	userService *content.UserService,
 	questionService *content.QuestionService,
+	forumService *forum.ForumService,
 ) *TemplateController {
 	script, css := GetStyle()
 	return &TemplateController{
@@ -81,6 +85,7 @@ func NewTemplateController(
 		eventQueueService:        eventQueueService,
 		userService:              userService,
 		questionService:          questionService,
+		forumService:             forumService,
 	}
 }
 func GetStyle() (script []string, css string) {
@@ -294,6 +299,20 @@ func (tc *TemplateController) QuestionInfoRedirect(ctx *gin.Context, siteInfo *s
 	}
 }
 
+// convertedQuestionURL returns the forum topic url of a question that has been converted into a topic
+func (tc *TemplateController) convertedQuestionURL(ctx *gin.Context, questionID string) (url string, ok bool) {
+	conversion, exist, err := tc.forumService.GetObjectConversion(ctx, questionID)
+	if err != nil {
+		log.Error(err)
+		return "", false
+	}
+	if !exist || conversion.TargetObjectType != constant.TopicObjectType {
+		return "", false
+	}
+	siteInfo := tc.SiteInfo(ctx)
+	return fmt.Sprintf("%s/topics/%s", siteInfo.General.SiteUrl, forumURLID(ctx, conversion.TargetRootID)), true
+}
+
 // QuestionInfo question and answers info
 func (tc *TemplateController) QuestionInfo(ctx *gin.Context) {
 	id := ctx.Param("id")
@@ -317,6 +336,10 @@ func (tc *TemplateController) QuestionInfo(ctx *gin.Context) {
 
 	detail, err := tc.templateRenderController.QuestionDetail(ctx, id)
 	if err != nil {
+		if url, ok := tc.convertedQuestionURL(ctx, id); ok {
+			ctx.Redirect(http.StatusMovedPermanently, url)
+			return
+		}
 		tc.Page404(ctx)
 		return
 	}
@@ -484,6 +507,18 @@ func (tc *TemplateController) TagInfo(ctx *gin.Context) {
 		return
 	}
 	page := templaterender.Paginator(nowPage, req.PageSize, questionCount)
+	// the topics carrying the tag are listed next to its questions, the newest on the first page only
+	topics, topicCount, err := tc.forumService.ListTagTopics(ctx, tag, 1, constant.DefaultPageSize)
+	if err != nil {
+		tc.Page404(ctx)
+		return
+	}
+	if nowPage > 1 {
+		topics = nil
+	}
+	for _, topic := range topics {
+		topic.ID = forumURLID(ctx, topic.ID)
+	}
 
 	siteInfo := tc.SiteInfo(ctx)
 	siteInfo.Canonical = fmt.Sprintf("%s/tags/%s", siteInfo.General.SiteUrl, tag)
@@ -504,11 +539,217 @@ func (tc *TemplateController) TagInfo(ctx *gin.Context) {
 		"tag":           tagInfo,
 		"questionList":  questionList,
 		"questionCount": questionCount,
+		"topics":        topics,
+		"topicCount":    topicCount,
 		"useTitle":      UrlUseTitle,
 		"page":          page,
 	})
 }
 
+// forumURLID returns the id used in forum page urls, short when short ids are enabled
+func forumURLID(ctx *gin.Context, id string) string {
+	if handler.GetEnableShortID(ctx) {
+		return uid.EnShortID(id)
+	}
+	return id
+}
+
+// CategoryInfo forum category with its topics
+func (tc *TemplateController) CategoryInfo(ctx *gin.Context) {
+	id := ctx.Param("id")
+	req := &schema.TopicListReq{}
+	if handler.BindAndCheck(ctx, req) {
+		tc.Page404(ctx)
+		return
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	req.PageSize = constant.DefaultPageSize
+	category, err := tc.forumService.GetCategory(ctx, id)
+	if err != nil {
+		tc.Page404(ctx)
+		return
+	}
+	topics, total, err := tc.forumService.ListTopicsByCategory(ctx, category.ID, req)
+	if err != nil || pager.ValPageOutOfRange(total, req.Page, req.PageSize) {
+		tc.Page404(ctx)
+		return
+	}
+	for _, topic := range topics {
+		topic.ID = forumURLID(ctx, topic.ID)
+	}
+	page := templaterender.Paginator(req.Page, req.PageSize, total)
+
+	siteInfo := tc.SiteInfo(ctx)
+	siteInfo.Canonical = fmt.Sprintf("%s/categories/%s", siteInfo.General.SiteUrl, forumURLID(ctx, category.ID))
+	if req.Page > 1 {
+		siteInfo.Canonical = fmt.Sprintf("%s?page=%d", siteInfo.Canonical, req.Page)
+	}
+	siteInfo.Description = category.Description
+	siteInfo.Keywords = category.Name
+	siteInfo.Title = fmt.Sprintf("%s - %s", category.Name, siteInfo.General.Name)
+	tc.html(ctx, http.StatusOK, "category-detail.html", siteInfo, gin.H{
+		"category":   category,
+		"topics":     topics,
+		"topicCount": total,
+		"page":       page,
+	})
+}
+
+// TopicInfo forum topic with its posts
+func (tc *TemplateController) TopicInfo(ctx *gin.Context) {
+	id := ctx.Param("id")
+	req := &schema.PostListReq{}
+	if handler.BindAndCheck(ctx, req) {
+		tc.Page404(ctx)
+		return
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	req.PageSize = constant.DefaultPageSize
+	topic, err := tc.forumService.GetTopic(ctx, id)
+	if err != nil {
+		tc.Page404(ctx)
+		return
+	}
+	siteInfo := tc.SiteInfo(ctx)
+	if topic.Status == entity.TopicStatusConverted {
+		conversion, exist, err := tc.forumService.GetObjectConversion(ctx, topic.ID)
+		if err == nil && exist {
+			ctx.Redirect(http.StatusMovedPermanently, display.QuestionURL(siteInfo.SiteSeo.Permalink,
+				siteInfo.General.SiteUrl, conversion.TargetRootID, topic.Title))
+			return
+		}
+		tc.Page404(ctx)
+		return
+	}
+
+	posts, total, err := tc.forumService.ListTopicPosts(ctx, topic.ID, req)
+	if err != nil || pager.ValPageOutOfRange(total, req.Page, req.PageSize) {
+		tc.Page404(ctx)
+		return
+	}
+	openingPosts := posts
+	if req.Page > 1 {
+		openingPosts, _, err = tc.forumService.ListTopicPosts(ctx, topic.ID, &schema.PostListReq{Page: 1, PageSize: 1})
+		if err != nil {
+			tc.Page404(ctx)
+			return
+		}
+	}
+	postList := make([]*schema.TemplateForumPost, 0, len(posts))
+	for _, post := range posts {
+		postList = append(postList, &schema.TemplateForumPost{
+			ID:          post.ID,
+			HTML:        converter.Markdown2HTML(post.OriginalText),
+			VoteCount:   post.VoteCount,
+			CreateTime:  post.CreatedAt.Unix(),
+			Username:    post.AuthorUsername,
+			DisplayName: post.AuthorDisplayName,
+			Solution:    post.ID == topic.SolvedPostID,
+			Archived:    post.MergeState == entity.PostMergeStateArchived,
+		})
+	}
+	page := templaterender.Paginator(req.Page, req.PageSize, total)
+
+	topicURL := fmt.Sprintf("%s/topics/%s", siteInfo.General.SiteUrl, forumURLID(ctx, topic.ID))
+	siteInfo.Canonical = topicURL
+	if req.Page > 1 {
+		siteInfo.Canonical = fmt.Sprintf("%s?page=%d", topicURL, req.Page)
+	}
+
+	jsonLD := &schema.DiscussionForumPostingJsonLD{
+		Context:       "https://schema.org",
+		Type:          "DiscussionForumPosting",
+		Headline:      topic.Title,
+		URL:           topicURL,
+		DatePublished: topic.CreatedAt,
+		InteractionStatistic: &schema.JsonLDInteraction{
+			Type:                 "InteractionCounter",
+			InteractionType:      "https://schema.org/LikeAction",
+			UserInteractionCount: topic.VoteCount,
+		},
+	}
+	if len(openingPosts) > 0 {
+		opening := openingPosts[0]
+		jsonLD.Text = htmltext.FetchExcerpt(converter.Markdown2HTML(opening.OriginalText), "...", 240)
+		jsonLD.Author = &schema.JsonLDPerson{
+			Type: "Person",
+			Name: opening.AuthorDisplayName,
+			URL:  fmt.Sprintf("%s/users/%s", siteInfo.General.SiteUrl, opening.AuthorUsername),
+		}
+		siteInfo.Description = jsonLD.Text
+	}
+	for _, post := range postList {
+		if len(openingPosts) > 0 && post.ID == openingPosts[0].ID {
+			continue
+		}
+		jsonLD.Comment = append(jsonLD.Comment, &schema.DiscussionForumItem{
+			Type:          "Comment",
+			Text:          htmltext.FetchExcerpt(post.HTML, "...", 240),
+			URL:           fmt.Sprintf("%s#%s", topicURL, post.ID),
+			DatePublished: time.Unix(post.CreateTime, 0),
+			UpvoteCount:   post.VoteCount,
+			Author: &schema.JsonLDPerson{
+				Type: "Person",
+				Name: post.DisplayName,
+				URL:  fmt.Sprintf("%s/users/%s", siteInfo.General.SiteUrl, post.Username),
+			},
+		})
+	}
+	jsonLDStr, err := json.Marshal(jsonLD)
+	if err == nil {
+		siteInfo.JsonLD = `<script data-react-helmet="true" type="application/ld+json">` + string(jsonLDStr) + ` </script>`
+	}
+
+	siteInfo.Title = fmt.Sprintf("%s - %s", topic.Title, siteInfo.General.Name)
+	tc.html(ctx, http.StatusOK, "topic-detail.html", siteInfo, gin.H{
+		"topic":     topic,
+		"topicID":   forumURLID(ctx, topic.ID),
+		"posts":     postList,
+		"postCount": total,
+		"hasWiki":   topic.CurrentWikiRevisionID != "" && topic.CurrentWikiRevisionID != "0",
+		"page":      page,
+	})
+}
+
+// TopicWiki current wiki document of a forum topic
+func (tc *TemplateController) TopicWiki(ctx *gin.Context) {
+	id := ctx.Param("id")
+	topic, err := tc.forumService.GetTopic(ctx, id)
+	if err != nil || topic.Status == entity.TopicStatusConverted {
+		tc.Page404(ctx)
+		return
+	}
+	revision, err := tc.forumService.GetTopicWiki(ctx, topic.ID)
+	if err != nil || revision == nil {
+		tc.Page404(ctx)
+		return
+	}
+	html := converter.Markdown2HTML(revision.Document)
+
+	siteInfo := tc.SiteInfo(ctx)
+	siteInfo.Canonical = fmt.Sprintf("%s/topics/%s/wiki", siteInfo.General.SiteUrl, forumURLID(ctx, topic.ID))
+	siteInfo.Description = revision.Summary
+	if len(siteInfo.Description) == 0 {
+		siteInfo.Description = htmltext.FetchExcerpt(html, "...", 240)
+	}
+	title := revision.Title
+	if len(title) == 0 {
+		title = topic.Title
+	}
+	siteInfo.Title = fmt.Sprintf("%s - %s", title, siteInfo.General.Name)
+	tc.html(ctx, http.StatusOK, "topic-wiki.html", siteInfo, gin.H{
+		"topic":     topic,
+		"topicID":   forumURLID(ctx, topic.ID),
+		"wikiTitle": title,
+		"revision":  revision,
+		"html":      html,
+	})
+}
+
 // UserInfo user info
 func (tc *TemplateController) UserInfo(ctx *gin.Context) {
 	username := ctx.Param("username")
@@ -637,18 +878,23 @@ func (tc *TemplateController) SitemapPage(ctx *gin.Context) {
 	}
 	page := 0
 	pageParam := ctx.Param("page")
-	pageRegexp := regexp.MustCompile(`question-(.*).xml`)
+	pageRegexp := regexp.MustCompile(`(question|topic)-(.*).xml`)
 	pageStr := pageRegexp.FindStringSubmatch(pageParam)
-	if len(pageStr) != 2 {
+	if len(pageStr) != 3 {
 		tc.Page404(ctx)
 		return
 	}
-	page = converter.StringToInt(pageStr[1])
+	page = converter.StringToInt(pageStr[2])
 	if page == 0 {
 		tc.Page404(ctx)
 		return
 	}
-	err := tc.templateRenderController.SitemapPage(ctx, page)
+	var err error
+	if pageStr[1] == "topic" {
+		err = tc.templateRenderController.SitemapTopicPage(ctx, page)
+	} else {
+		err = tc.templateRenderController.SitemapPage(ctx, page)
+	}
 	if err != nil {
 		tc.Page404(ctx)
 		return
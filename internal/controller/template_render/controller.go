@@ -23,6 +23,7 @@ import (
 	"math"
 
 	"github.com/apache/answer/internal/service/content"
+	"github.com/apache/answer/internal/service/forum"
 	questioncommon "github.com/apache/answer/internal/service/question_common"
 
 	"github.com/apache/answer/internal/service/comment"
@@ -46,6 +47,7 @@ type TemplateRenderController struct {
 	commentService  *comment.CommentService
 	siteInfoService siteinfo_common.SiteInfoCommonService
 	questionRepo    questioncommon.QuestionRepo
+	forumService    *forum.ForumService
 }
 
 func NewTemplateRenderController(
@@ -56,6 +58,7 @@ func NewTemplateRenderController(
 	commentService *comment.CommentService,
 	siteInfoService siteinfo_common.SiteInfoCommonService,
 	questionRepo questioncommon.QuestionRepo,
+	forumService *forum.ForumService,
 ) *TemplateRenderController {
 	return &TemplateRenderController{
 		questionService: questionService,
@@ -65,6 +68,7 @@ func NewTemplateRenderController(
 		commentService:  commentService,
 		questionRepo:    questionRepo,
 		siteInfoService: siteInfoService,
+		forumService:    forumService,
 	}
 }
 
@@ -56,8 +56,14 @@ func (t *TemplateRenderController) Sitemap(ctx *gin.Context) {
 		return
 	}
 
+	topicNum, err := t.forumService.GetSitemapTopicCount(ctx)
+	if err != nil {
+		log.Error("GetSitemapTopicCount error", err)
+		return
+	}
+
 	ctx.Header("Content-Type", "application/xml")
-	if len(questions) < constant.SitemapMaxSize {
+	if len(questions) < constant.SitemapMaxSize && topicNum == 0 {
 		ctx.HTML(
 			http.StatusOK, "sitemap.xml", gin.H{
 				"xmlHeader": template.HTML(`<?xml version="1.0" encoding="UTF-8"?>`),
@@ -75,20 +81,26 @@ func (t *TemplateRenderController) Sitemap(ctx *gin.Context) {
 		log.Error("GetQuestionCount error", err)
 		return
 	}
-	var pageList []int
-	totalPages := int(math.Ceil(float64(questionNum) / float64(constant.SitemapMaxSize)))
-	for i := 1; i <= totalPages; i++ {
-		pageList = append(pageList, i)
-	}
 	ctx.HTML(
 		http.StatusOK, "sitemap-list.xml", gin.H{
 			"xmlHeader": template.HTML(`<?xml version="1.0" encoding="UTF-8"?>`),
-			"page":      pageList,
+			"page":      sitemapPageList(questionNum),
+			"topicPage": sitemapPageList(topicNum),
 			"general":   general,
 		},
 	)
 }
 
+// sitemapPageList returns the page numbers needed to list total items in sitemaps
+func sitemapPageList(total int64) []int {
+	var pageList []int
+	totalPages := int(math.Ceil(float64(total) / float64(constant.SitemapMaxSize)))
+	for i := 1; i <= totalPages; i++ {
+		pageList = append(pageList, i)
+	}
+	return pageList
+}
+
 func (t *TemplateRenderController) OpenSearch(ctx *gin.Context) {
 	general, err := t.siteInfoService.GetSiteGeneral(ctx)
 	if err != nil {
@@ -140,3 +152,26 @@ func (t *TemplateRenderController) SitemapPage(ctx *gin.Context, page int) error
 	)
 	return nil
 }
+
+func (t *TemplateRenderController) SitemapTopicPage(ctx *gin.Context, page int) error {
+	general, err := t.siteInfoService.GetSiteGeneral(ctx)
+	if err != nil {
+		log.Error("get site general failed:", err)
+		return err
+	}
+
+	topics, err := t.forumService.SitemapTopics(ctx, page, constant.SitemapMaxSize)
+	if err != nil {
+		log.Errorf("get sitemap topics failed: %s", err)
+		return err
+	}
+	ctx.Header("Content-Type", "application/xml")
+	ctx.HTML(
+		http.StatusOK, "sitemap-topic.xml", gin.H{
+			"xmlHeader": template.HTML(`<?xml version="1.0" encoding="UTF-8"?>`),
+			"list":      topics,
+			"general":   general,
+		},
+	)
+	return nil
+}
@@ -121,3 +121,26 @@ func (ctrl *AIConversationAdminController) DeleteConversation(ctx *gin.Context)
 	err := ctrl.aiConversationService.DeleteConversationForAdmin(ctx, req)
 	handler.HandleResponse(ctx, err, nil)
 }
+
+// GetUsageReport get AI token usage report
+// @Summary get AI token usage report for admin
+// @Description get AI token usage by user, model and day, the range defaults to the last 30 days
+// @Tags ai-conversation-admin
+// @Accept json
+// @Produce json
+// @Param start_date query string false "start date, 2006-01-02"
+// @Param end_date query string false "end date, 2006-01-02"
+// @Success 200 {object} handler.RespBody{data=schema.AIUsageReportResp}
+// @Router /answer/admin/api/ai/usage [get]
+func (ctrl *AIConversationAdminController) GetUsageReport(ctx *gin.Context) {
+	if !ctrl.ensureEnabled(ctx) {
+		return
+	}
+	req := &schema.AIUsageReportReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+
+	resp, err := ctrl.aiConversationService.GetUsageReport(ctx, req)
+	handler.HandleResponse(ctx, err, resp)
+}
@@ -31,4 +31,6 @@ var ProviderSetController = wire.NewSet(
 	NewBadgeController,
 	NewAdminAPIKeyController,
 	NewAIConversationAdminController,
+	NewSearchAnalyticsController,
+	NewSearchReindexController,
 )
@@ -31,12 +31,19 @@ import (
 
 // PluginController role controller
 type PluginController struct {
-	pluginCommonService *plugin_common.PluginCommonService
+	pluginCommonService  *plugin_common.PluginCommonService
+	pluginRuntimeService *plugin_common.PluginRuntimeService
 }
 
 // NewPluginController new controller
-func NewPluginController(pluginCommonService *plugin_common.PluginCommonService) *PluginController {
-	return &PluginController{pluginCommonService: pluginCommonService}
+func NewPluginController(
+	pluginCommonService *plugin_common.PluginCommonService,
+	pluginRuntimeService *plugin_common.PluginRuntimeService,
+) *PluginController {
+	return &PluginController{
+		pluginCommonService:  pluginCommonService,
+		pluginRuntimeService: pluginRuntimeService,
+	}
 }
 
 // GetAllPluginStatus get all plugins status
@@ -109,6 +116,19 @@ func (pc *PluginController) GetPluginList(ctx *gin.Context) {
 	handler.HandleResponse(ctx, nil, resp)
 }
 
+// GetPluginHealth get plugin health
+// @Summary get the health of the calls of each plugin
+// @Description get the calls, errors, panics and timeouts of each plugin, and whether the circuit breaker disabled it
+// @Tags AdminPlugin
+// @Security ApiKeyAuth
+// @Produce  json
+// @Success 200 {object} handler.RespBody{data=[]schema.GetPluginHealthResp}
+// @Router /answer/admin/api/plugins/health [get]
+func (pc *PluginController) GetPluginHealth(ctx *gin.Context) {
+	resp := pc.pluginRuntimeService.GetPluginHealth(ctx)
+	handler.HandleResponse(ctx, nil, resp)
+}
+
 func (pc *PluginController) filterNoConfigPlugin(list []*schema.GetPluginListResp) []*schema.GetPluginListResp {
 	resp := make([]*schema.GetPluginListResp, 0)
 	for _, t := range list {
@@ -0,0 +1,62 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package controller_admin
+
+import (
+	"github.com/apache/answer/internal/base/handler"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/search_analytics"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchAnalyticsController search analytics controller
+type SearchAnalyticsController struct {
+	searchAnalyticsService search_analytics.SearchAnalyticsService
+}
+
+// NewSearchAnalyticsController new search analytics controller
+func NewSearchAnalyticsController(
+	searchAnalyticsService search_analytics.SearchAnalyticsService,
+) *SearchAnalyticsController {
+	return &SearchAnalyticsController{
+		searchAnalyticsService: searchAnalyticsService,
+	}
+}
+
+// GetSearchAnalytics get search analytics
+// @Summary get search analytics for admin
+// @Description get the top queries, the queries without results and the click-through rate of each day, the range defaults to the last 30 days
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param start_date query string false "start date, 2006-01-02"
+// @Param end_date query string false "end date, 2006-01-02"
+// @Success 200 {object} handler.RespBody{data=schema.SearchAnalyticsResp}
+// @Router /answer/admin/api/search/analytics [get]
+func (sc *SearchAnalyticsController) GetSearchAnalytics(ctx *gin.Context) {
+	req := &schema.SearchAnalyticsReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+
+	resp, err := sc.searchAnalyticsService.GetReport(ctx, req)
+	handler.HandleResponse(ctx, err, resp)
+}
@@ -0,0 +1,87 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package controller_admin
+
+import (
+	"github.com/apache/answer/internal/base/handler"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/search_reindex"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchReindexController search reindex controller
+type SearchReindexController struct {
+	searchReindexService search_reindex.SearchReindexService
+}
+
+// NewSearchReindexController new search reindex controller
+func NewSearchReindexController(
+	searchReindexService search_reindex.SearchReindexService,
+) *SearchReindexController {
+	return &SearchReindexController{
+		searchReindexService: searchReindexService,
+	}
+}
+
+// StartSearchReindex start search reindex
+// @Summary start to rebuild the index of the search plugin
+// @Description index all the questions and answers in the background, resuming the last reindex interrupted unless restart
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body schema.SearchReindexReq true "search reindex"
+// @Success 200 {object} handler.RespBody
+// @Router /answer/admin/api/search/reindex [post]
+func (sc *SearchReindexController) StartSearchReindex(ctx *gin.Context) {
+	req := &schema.SearchReindexReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+
+	err := sc.searchReindexService.StartReindex(ctx, req)
+	handler.HandleResponse(ctx, err, nil)
+}
+
+// GetSearchReindexProgress get search reindex progress
+// @Summary get the progress of the search reindex
+// @Description get the progress of the running search reindex, or that of the last one when none is running
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} handler.RespBody{data=schema.SearchReindexProgress}
+// @Router /answer/admin/api/search/reindex [get]
+func (sc *SearchReindexController) GetSearchReindexProgress(ctx *gin.Context) {
+	resp, err := sc.searchReindexService.GetReindexProgress(ctx)
+	handler.HandleResponse(ctx, err, resp)
+}
+
+// VerifySearchIndex verify search index
+// @Summary verify the index of the search plugin
+// @Description compare the number of questions and answers not deleted in the database with those in the index
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} handler.RespBody{data=schema.SearchVerifyResp}
+// @Router /answer/admin/api/search/verify [get]
+func (sc *SearchReindexController) VerifySearchIndex(ctx *gin.Context) {
+	resp, err := sc.searchReindexService.Verify(ctx)
+	handler.HandleResponse(ctx, err, resp)
+}
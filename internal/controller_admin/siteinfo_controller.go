@@ -26,6 +26,8 @@ import (
 	"github.com/apache/answer/internal/base/handler"
 	"github.com/apache/answer/internal/base/middleware"
 	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/ai_prompt"
+	"github.com/apache/answer/internal/service/ai_provider"
 	"github.com/apache/answer/internal/service/siteinfo"
 	"github.com/gin-gonic/gin"
 	"github.com/segmentfault/pacman/log"
@@ -33,13 +35,21 @@ import (
 
 // SiteInfoController site info controller
 type SiteInfoController struct {
-	siteInfoService *siteinfo.SiteInfoService
+	siteInfoService   *siteinfo.SiteInfoService
+	aiProviderService ai_provider.AIProviderService
+	aiPromptService   ai_prompt.AIPromptService
 }
 
 // NewSiteInfoController new site info controller
-func NewSiteInfoController(siteInfoService *siteinfo.SiteInfoService) *SiteInfoController {
+func NewSiteInfoController(
+	siteInfoService *siteinfo.SiteInfoService,
+	aiProviderService ai_provider.AIProviderService,
+	aiPromptService ai_prompt.AIPromptService,
+) *SiteInfoController {
 	return &SiteInfoController{
-		siteInfoService: siteInfoService,
+		siteInfoService:   siteInfoService,
+		aiProviderService: aiProviderService,
+		aiPromptService:   aiPromptService,
 	}
 }
 
@@ -656,6 +666,40 @@ func (sc *SiteInfoController) RequestAIModels(ctx *gin.Context) {
 	handler.HandleResponse(ctx, nil, resp)
 }
 
+// GetAIProviderHealth probe the configured AI providers
+// @Summary probe the configured AI providers
+// @Description send a one token completion to every configured AI provider and report whether it answered
+// @Security ApiKeyAuth
+// @Tags admin
+// @Produce json
+// @Success 200 {object} handler.RespBody{data=[]schema.AIProviderHealthResp}
+// @Router /answer/admin/api/ai-provider/health [get]
+func (sc *SiteInfoController) GetAIProviderHealth(ctx *gin.Context) {
+	resp, err := sc.aiProviderService.CheckHealth(ctx)
+	handler.HandleResponse(ctx, err, resp)
+}
+
+// PreviewAIPrompt preview AI prompt
+// @Summary preview AI prompt
+// @Description render the AI chat prompt of a language for the current user, with the given template or the saved one
+// @Security ApiKeyAuth
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param data body schema.AIPromptPreviewReq true "preview"
+// @Success 200 {object} handler.RespBody{data=schema.AIPromptPreviewResp}
+// @Router /answer/admin/api/ai-prompt/preview [post]
+func (sc *SiteInfoController) PreviewAIPrompt(ctx *gin.Context) {
+	req := &schema.AIPromptPreviewReq{}
+	if handler.BindAndCheck(ctx, req) {
+		return
+	}
+	req.UserID = middleware.GetLoginUserIDFromContext(ctx)
+
+	resp, err := sc.aiPromptService.PreviewPrompt(ctx, req)
+	handler.HandleResponse(ctx, err, resp)
+}
+
 // GetMCPConfig get MCP configuration
 // @Summary get MCP configuration
 // @Description get MCP configuration
@@ -29,6 +29,11 @@ type AIConversation struct {
 	ConversationID string    `xorm:"not null unique VARCHAR(255) conversation_id"`
 	Topic          string    `xorm:"not null MEDIUMTEXT topic"`
 	UserID         string    `xorm:"not null default 0 BIGINT(20) user_id"`
+	// Summary is the running summary of the first SummarizedRecords records, which are no longer sent verbatim
+	Summary           string `xorm:"MEDIUMTEXT summary"`
+	SummarizedRecords int    `xorm:"not null default 0 INT(11) summarized_records"`
+	// QuestionID is the question the conversation was published as
+	QuestionID string `xorm:"not null default 0 BIGINT(20) question_id"`
 }
 
 // TableName returns the table name
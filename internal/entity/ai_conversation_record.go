@@ -32,9 +32,22 @@ type AIConversationRecord struct {
 	Content          string    `xorm:"not null MEDIUMTEXT content"`
 	Helpful          int       `xorm:"not null default 0 INT(11) helpful"`
 	Unhelpful        int       `xorm:"not null default 0 INT(11) unhelpful"`
+	Sources          string    `xorm:"MEDIUMTEXT sources"`
+	Model            string    `xorm:"not null default '' VARCHAR(100) model"`
+	PromptTokens     int       `xorm:"not null default 0 INT(11) prompt_tokens"`
+	CompletionTokens int       `xorm:"not null default 0 INT(11) completion_tokens"`
 }
 
 // TableName returns the table name
 func (AIConversationRecord) TableName() string {
 	return "ai_conversation_record"
 }
+
+// AIConversationUsage token usage of an assistant reply with the user who asked
+type AIConversationUsage struct {
+	UserID           string    `xorm:"user_id"`
+	Model            string    `xorm:"model"`
+	PromptTokens     int       `xorm:"prompt_tokens"`
+	CompletionTokens int       `xorm:"completion_tokens"`
+	CreatedAt        time.Time `xorm:"created_at"`
+}
@@ -0,0 +1,50 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package entity
+
+import "time"
+
+const (
+	AIEmbeddingObjectTypeQuestion = "question"
+	AIEmbeddingObjectTypeAnswer   = "answer"
+	AIEmbeddingObjectTypeWiki     = "wiki"
+	AIEmbeddingObjectTypeTopic    = "topic"
+)
+
+// AIEmbedding embedding vector of a question, accepted answer, topic or wiki document
+type AIEmbedding struct {
+	ID          int       `xorm:"not null pk autoincr INT(11) id"`
+	CreatedAt   time.Time `xorm:"created not null default CURRENT_TIMESTAMP TIMESTAMP created_at"`
+	UpdatedAt   time.Time `xorm:"updated not null default CURRENT_TIMESTAMP TIMESTAMP updated_at"`
+	ObjectID    string    `xorm:"not null default 0 BIGINT(20) UNIQUE(object) object_id"`
+	ObjectType  string    `xorm:"not null default '' VARCHAR(30) UNIQUE(object) object_type"`
+	QuestionID  string    `xorm:"not null default 0 BIGINT(20) INDEX question_id"`
+	Title       string    `xorm:"not null default '' VARCHAR(255) title"`
+	Content     string    `xorm:"not null MEDIUMTEXT content"`
+	ContentHash string    `xorm:"not null default '' VARCHAR(64) content_hash"`
+	Model       string    `xorm:"not null default '' VARCHAR(100) INDEX model"`
+	Dimensions  int       `xorm:"not null default 0 INT(11) dimensions"`
+	Vector      string    `xorm:"not null MEDIUMTEXT vector"`
+}
+
+// TableName returns the table name
+func (AIEmbedding) TableName() string {
+	return "ai_embedding"
+}
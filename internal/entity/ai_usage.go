@@ -0,0 +1,43 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package entity
+
+import "time"
+
+const (
+	AIUsageSourceTopicSummary = "topic_summary"
+)
+
+// AIUsage tokens used by the AI features that are not a conversation, they count towards the quotas
+type AIUsage struct {
+	ID               int       `xorm:"not null pk autoincr INT(11) id"`
+	CreatedAt        time.Time `xorm:"created not null default CURRENT_TIMESTAMP TIMESTAMP INDEX created_at"`
+	UserID           string    `xorm:"not null default 0 BIGINT(20) INDEX user_id"`
+	Source           string    `xorm:"not null default '' VARCHAR(30) source"`
+	ObjectID         string    `xorm:"not null default 0 BIGINT(20) object_id"`
+	Model            string    `xorm:"not null default '' VARCHAR(100) model"`
+	PromptTokens     int       `xorm:"not null default 0 INT(11) prompt_tokens"`
+	CompletionTokens int       `xorm:"not null default 0 INT(11) completion_tokens"`
+}
+
+// TableName returns the table name
+func (AIUsage) TableName() string {
+	return "ai_usage"
+}
@@ -20,6 +20,7 @@
 package entity
 
 import (
+	"strings"
 	"time"
 )
 
@@ -34,9 +35,40 @@ type APIKey struct {
 	Scope       string    `xorm:"not null VARCHAR(255) scope"`
 	UserID      string    `xorm:"not null default 0 BIGINT(20) user_id"`
 	Hidden      int       `xorm:"not null default 0 INT(11) hidden"`
+	// MCPWriteTools comma separated names of the MCP write tools this key may call
+	MCPWriteTools string `xorm:"not null default '' VARCHAR(255) mcp_write_tools"`
 }
 
 // TableName category table name
 func (c *APIKey) TableName() string {
 	return "api_key"
 }
+
+// GetMCPWriteTools get the MCP write tools enabled for this key
+func (c *APIKey) GetMCPWriteTools() []string {
+	tools := make([]string, 0)
+	for _, tool := range strings.Split(c.MCPWriteTools, ",") {
+		if tool = strings.TrimSpace(tool); len(tool) > 0 {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// SetMCPWriteTools set the MCP write tools enabled for this key
+func (c *APIKey) SetMCPWriteTools(tools []string) {
+	c.MCPWriteTools = strings.Join(tools, ",")
+}
+
+// MCPWriteToolEnabled whether the key may call the given MCP write tool
+func (c *APIKey) MCPWriteToolEnabled(tool string) bool {
+	if c.Scope == "read-only" {
+		return false
+	}
+	for _, t := range c.GetMCPWriteTools() {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
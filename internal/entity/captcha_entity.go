@@ -32,6 +32,7 @@ const (
 	CaptchaActionReport           = "report"
 	CaptchaActionDelete           = "delete"
 	CaptchaActionVote             = "vote"
+	CaptchaActionSimilar          = "similar"
 )
 
 type ActionRecordInfo struct {
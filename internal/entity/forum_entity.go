@@ -27,6 +27,7 @@ const (
 
 	TopicStatusAvailable = "available"
 	TopicStatusClosed    = "closed"
+	TopicStatusConverted = "converted"
 
 	PostMergeStateActive   = "active"
 	PostMergeStateArchived = "archived"
@@ -36,6 +37,9 @@ const (
 	MergeJobStatusApplied  = "applied"
 
 	DocLinkTypeRelated = "related"
+
+	TopicPollStatusOpen   = "open"
+	TopicPollStatusClosed = "closed"
 )
 
 type Category struct {
@@ -202,3 +206,76 @@ type TopicSolution struct {
 func (TopicSolution) TableName() string {
 	return "topic_solutions"
 }
+
+// ObjectConversion records that a Q&A object was converted into a forum object or the other way around.
+// It is kept so that the old URL of the source object can redirect to the target.
+type ObjectConversion struct {
+	ID               string    `xorm:"not null pk BIGINT(20) id"`
+	CreatedAt        time.Time `xorm:"not null default CURRENT_TIMESTAMP TIMESTAMP created_at"`
+	UpdatedAt        time.Time `xorm:"updated TIMESTAMP"`
+	SourceObjectType string    `xorm:"not null default '' VARCHAR(30) source_object_type"`
+	SourceObjectID   string    `xorm:"not null default 0 BIGINT(20) INDEX source_object_id"`
+	TargetObjectType string    `xorm:"not null default '' VARCHAR(30) target_object_type"`
+	TargetObjectID   string    `xorm:"not null default 0 BIGINT(20) target_object_id"`
+	TargetRootID     string    `xorm:"not null default 0 BIGINT(20) target_root_id"`
+	OperatorID       string    `xorm:"not null default 0 BIGINT(20) operator_id"`
+}
+
+func (ObjectConversion) TableName() string {
+	return "object_conversions"
+}
+
+// TopicPoll is an optional poll attached to a topic. A topic has at most one poll.
+type TopicPoll struct {
+	ID         string     `xorm:"not null pk BIGINT(20) id"`
+	CreatedAt  time.Time  `xorm:"not null default CURRENT_TIMESTAMP TIMESTAMP created_at"`
+	UpdatedAt  time.Time  `xorm:"updated TIMESTAMP"`
+	TopicID    string     `xorm:"not null default 0 BIGINT(20) unique topic_id"`
+	UserID     string     `xorm:"not null default 0 BIGINT(20) user_id"`
+	Question   string     `xorm:"not null default '' VARCHAR(255) question"`
+	Multiple   bool       `xorm:"not null default false BOOL multiple"`
+	Anonymous  bool       `xorm:"not null default false BOOL anonymous"`
+	Status     string     `xorm:"not null default 'open' VARCHAR(30) INDEX status"`
+	CloseAt    *time.Time `xorm:"TIMESTAMP close_at"`
+	ClosedAt   *time.Time `xorm:"TIMESTAMP closed_at"`
+	VoterCount int        `xorm:"not null default 0 INT(11) voter_count"`
+}
+
+func (TopicPoll) TableName() string {
+	return "topic_polls"
+}
+
+// IsOpen reports whether the poll still accepts votes at the given time.
+func (p *TopicPoll) IsOpen(now time.Time) bool {
+	if p.Status != TopicPollStatusOpen {
+		return false
+	}
+	return p.CloseAt == nil || now.Before(*p.CloseAt)
+}
+
+type TopicPollOption struct {
+	ID        string    `xorm:"not null pk BIGINT(20) id"`
+	CreatedAt time.Time `xorm:"not null default CURRENT_TIMESTAMP TIMESTAMP created_at"`
+	UpdatedAt time.Time `xorm:"updated TIMESTAMP"`
+	PollID    string    `xorm:"not null default 0 BIGINT(20) INDEX poll_id"`
+	Position  int       `xorm:"not null default 0 INT(11) position"`
+	Title     string    `xorm:"not null default '' VARCHAR(200) title"`
+	VoteCount int       `xorm:"not null default 0 INT(11) vote_count"`
+}
+
+func (TopicPollOption) TableName() string {
+	return "topic_poll_options"
+}
+
+type TopicPollVote struct {
+	ID        string    `xorm:"not null pk BIGINT(20) id"`
+	CreatedAt time.Time `xorm:"not null default CURRENT_TIMESTAMP TIMESTAMP created_at"`
+	UpdatedAt time.Time `xorm:"updated TIMESTAMP"`
+	PollID    string    `xorm:"not null default 0 BIGINT(20) unique(poll_vote_user_option) poll_id"`
+	UserID    string    `xorm:"not null default 0 BIGINT(20) unique(poll_vote_user_option) user_id"`
+	OptionID  string    `xorm:"not null default 0 BIGINT(20) unique(poll_vote_user_option) option_id"`
+}
+
+func (TopicPollVote) TableName() string {
+	return "topic_poll_votes"
+}
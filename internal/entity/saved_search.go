@@ -0,0 +1,53 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package entity
+
+import "time"
+
+// SavedSearch a search query watched by a user, the new matches are sent to the inbox or by email
+type SavedSearch struct {
+	ID          int       `xorm:"not null pk autoincr INT(11) id"`
+	CreatedAt   time.Time `xorm:"created not null default CURRENT_TIMESTAMP TIMESTAMP created_at"`
+	UpdatedAt   time.Time `xorm:"updated not null default CURRENT_TIMESTAMP TIMESTAMP updated_at"`
+	LastRunAt   time.Time `xorm:"not null default CURRENT_TIMESTAMP TIMESTAMP INDEX last_run_at"`
+	UserID      string    `xorm:"not null default 0 BIGINT(20) INDEX user_id"`
+	Name        string    `xorm:"not null default '' VARCHAR(100) name"`
+	Query       string    `xorm:"not null default '' VARCHAR(255) query"`
+	NotifyInbox bool      `xorm:"not null default false BOOL notify_inbox"`
+	NotifyEmail bool      `xorm:"not null default false BOOL notify_email"`
+}
+
+// TableName returns the table name
+func (SavedSearch) TableName() string {
+	return "saved_search"
+}
+
+// SavedSearchMatch a result of a saved search that was sent already
+type SavedSearchMatch struct {
+	ID            int64     `xorm:"not null pk autoincr BIGINT(20) id"`
+	CreatedAt     time.Time `xorm:"created not null default CURRENT_TIMESTAMP TIMESTAMP created_at"`
+	SavedSearchID int       `xorm:"not null default 0 INT(11) UNIQUE(search_object) saved_search_id"`
+	ObjectID      string    `xorm:"not null default 0 BIGINT(20) UNIQUE(search_object) object_id"`
+}
+
+// TableName returns the table name
+func (SavedSearchMatch) TableName() string {
+	return "saved_search_match"
+}
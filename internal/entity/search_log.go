@@ -0,0 +1,53 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package entity
+
+import "time"
+
+// SearchLog a search and the first result clicked in it, the user is hashed
+type SearchLog struct {
+	ID              int64     `xorm:"not null pk autoincr BIGINT(20) id"`
+	CreatedAt       time.Time `xorm:"created not null default CURRENT_TIMESTAMP TIMESTAMP INDEX created_at"`
+	UserHash        string    `xorm:"not null default '' VARCHAR(16) user_hash"`
+	Query           string    `xorm:"not null default '' VARCHAR(128) query"`
+	ResultCount     int64     `xorm:"not null default 0 INT(11) result_count"`
+	ClickedObjectID string    `xorm:"not null default 0 BIGINT(20) clicked_object_id"`
+	ClickedPosition int       `xorm:"not null default 0 INT(11) clicked_position"`
+}
+
+// TableName returns the table name
+func (SearchLog) TableName() string {
+	return "search_log"
+}
+
+// SearchLogDaily the searches of a query in a day, the old search logs are rolled up into it
+type SearchLogDaily struct {
+	ID          int64  `xorm:"not null pk autoincr BIGINT(20) id"`
+	Day         string `xorm:"not null default '' VARCHAR(10) UNIQUE(day_query) day"`
+	Query       string `xorm:"not null default '' VARCHAR(128) UNIQUE(day_query) query"`
+	Searches    int64  `xorm:"not null default 0 INT(11) searches"`
+	ZeroResults int64  `xorm:"not null default 0 INT(11) zero_results"`
+	Clicks      int64  `xorm:"not null default 0 INT(11) clicks"`
+}
+
+// TableName returns the table name
+func (SearchLogDaily) TableName() string {
+	return "search_log_daily"
+}
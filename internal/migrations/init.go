@@ -65,9 +65,11 @@ type InitNeedUserInputData struct {
 func (m *Mentor) InitDB() error {
 	m.do("check table exist", m.checkTableExist)
 	m.do("sync table", m.syncTable)
+	m.do("init full text search", m.initFullTextSearch)
 	m.do("init version table", m.initVersionTable)
 	m.do("init admin user", m.initAdminUser)
 	m.do("init config", m.initConfig)
+	m.do("init search analytics salt", m.initSearchAnalyticsSalt)
 	m.do("init default privileges config", m.initDefaultRankPrivileges)
 	m.do("init role", m.initRole)
 	m.do("init power", m.initPower)
@@ -113,6 +115,10 @@ func (m *Mentor) syncTable() {
 	m.err = m.engine.Context(m.ctx).Sync(tables...)
 }
 
+func (m *Mentor) initFullTextSearch() {
+	m.err = addFullTextSearch(m.ctx, m.engine)
+}
+
 func (m *Mentor) initVersionTable() {
 	_, m.err = m.engine.Context(m.ctx).Insert(&entity.Version{ID: 1, VersionNumber: ExpectedVersion()})
 }
@@ -136,6 +142,10 @@ func (m *Mentor) initConfig() {
 	_, m.err = m.engine.Context(m.ctx).Insert(defaultConfigTable)
 }
 
+func (m *Mentor) initSearchAnalyticsSalt() {
+	m.err = generateSearchAnalyticsSalt(m.ctx, m.engine)
+}
+
 func (m *Mentor) initDefaultRankPrivileges() {
 	chooseOption := schema.DefaultPrivilegeOptions.Choose(schema.PrivilegeLevel2)
 	for _, privilege := range chooseOption.Privileges {
@@ -20,10 +20,18 @@
 package migrations
 
 import (
+	"github.com/apache/answer/internal/base/constant"
 	"github.com/apache/answer/internal/entity"
 	"github.com/apache/answer/internal/service/permission"
 )
 
+// the ids of the configs of defaultConfigTable added by the migrations, which insert them when they are missing
+const (
+	searchAnalyticsSaltConfigID     = 132
+	searchReindexCheckpointConfigID = 133
+	searchReindexLockConfigID       = 134
+)
+
 const (
 	defaultSEORobotTxt = `User-agent: *
 Disallow: /admin
@@ -90,6 +98,16 @@ var (
 		&entity.TopicVote{},
 		&entity.PostVote{},
 		&entity.TopicSolution{},
+		&entity.ObjectConversion{},
+		&entity.TopicPoll{},
+		&entity.TopicPollOption{},
+		&entity.TopicPollVote{},
+		&entity.AIEmbedding{},
+		&entity.SearchLog{},
+		&entity.SearchLogDaily{},
+		&entity.SavedSearch{},
+		&entity.SavedSearchMatch{},
+		&entity.AIUsage{},
 	}
 
 	roles = []*entity.Role{
@@ -365,6 +383,10 @@ var (
 		{ID: 129, Key: "rank.question.undeleted", Value: `-1`},
 		{ID: 130, Key: "rank.tag.undeleted", Value: `-1`},
 		{ID: 131, Key: "ai_config.provider", Value: `[{"default_api_host":"https://api.openai.com","display_name":"OpenAI","name":"openai"},{"default_api_host":"https://generativelanguage.googleapis.com","display_name":"Gemini","name":"gemini"},{"default_api_host":"https://api.anthropic.com","display_name":"Anthropic","name":"anthropic"}]`},
+		// the salt is generated for each site when it is installed or migrated
+		{ID: searchAnalyticsSaltConfigID, Key: constant.SearchAnalyticsSaltKey, Value: ``},
+		{ID: searchReindexCheckpointConfigID, Key: constant.SearchReindexCheckpointKey, Value: ``},
+		{ID: searchReindexLockConfigID, Key: constant.SearchReindexLockKey, Value: ``},
 	}
 
 	defaultBadgeGroupTable = []*entity.BadgeGroup{
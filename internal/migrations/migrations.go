@@ -108,6 +108,20 @@ var migrations = []Migration{
 	NewMigration("v1.8.0", "change admin menu", updateAdminMenuSettings, true),
 	NewMigration("v1.8.1", "ai feat", aiFeat, true),
 	NewMigration("v1.9.0", "add forum core tables", addForumCore, true),
+	NewMigration("v1.9.1", "add object conversions", addObjectConversions, false),
+	NewMigration("v1.9.2", "add topic polls", addTopicPolls, false),
+	NewMigration("v1.9.3", "add api key mcp write tools", addAPIKeyMCPWriteTools, false),
+	NewMigration("v1.9.4", "add ai embedding", addAIEmbedding, false),
+	NewMigration("v1.9.5", "add ai conversation record sources", addAIConversationRecordSources, false),
+	NewMigration("v1.9.6", "add ai conversation record usage", addAIConversationRecordUsage, false),
+	NewMigration("v1.9.7", "add ai conversation summary", addAIConversationSummary, false),
+	NewMigration("v1.9.8", "add ai conversation question", addAIConversationQuestion, false),
+	NewMigration("v1.9.9", "add full-text search indexes", addFullTextSearch, false),
+	NewMigration("v1.10.0", "add search analytics", addSearchAnalytics, false),
+	NewMigration("v1.10.1", "add saved search", addSavedSearch, false),
+	NewMigration("v1.10.2", "add search reindex checkpoint", addSearchReindexCheckpoint, false),
+	NewMigration("v1.10.3", "add ai usage", addAIUsage, false),
+	NewMigration("v1.10.4", "add search reindex lock", addSearchReindexLock, false),
 }
 
 func GetMigrations() []Migration {
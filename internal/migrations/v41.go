@@ -0,0 +1,125 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// addFullTextSearch creates the full-text indexes the built-in search uses:
+// FULLTEXT indexes on MySQL, GIN indexes over tsvector on PostgreSQL and FTS5 tables kept in sync by triggers on SQLite
+func addFullTextSearch(ctx context.Context, x *xorm.Engine) error {
+	switch x.Dialect().URI().DBType {
+	case schemas.MYSQL:
+		return addMySQLFullTextIndexes(ctx, x)
+	case schemas.POSTGRES:
+		return addPostgresFullTextIndexes(ctx, x)
+	case schemas.SQLITE:
+		return addSQLiteFullTextTables(ctx, x)
+	}
+	return nil
+}
+
+func addMySQLFullTextIndexes(ctx context.Context, x *xorm.Engine) error {
+	indexes := []struct {
+		table, name, columns string
+	}{
+		{"question", "FT_question_search", "`title`, `original_text`"},
+		{"answer", "FT_answer_search", "`original_text`"},
+	}
+	for _, index := range indexes {
+		var count int64
+		_, err := x.Context(ctx).SQL("SELECT COUNT(*) FROM information_schema.statistics "+
+			"WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?", index.table, index.name).Get(&count)
+		if err != nil {
+			return fmt.Errorf("check full-text index %s failed: %w", index.name, err)
+		}
+		if count > 0 {
+			continue
+		}
+		_, err = x.Context(ctx).Exec(fmt.Sprintf("ALTER TABLE `%s` ADD FULLTEXT INDEX `%s` (%s)",
+			index.table, index.name, index.columns))
+		if err != nil {
+			return fmt.Errorf("add full-text index %s failed: %w", index.name, err)
+		}
+	}
+	return nil
+}
+
+func addPostgresFullTextIndexes(ctx context.Context, x *xorm.Engine) error {
+	statements := []string{
+		`CREATE INDEX IF NOT EXISTS "IDX_question_search" ON "question" ` +
+			`USING GIN (to_tsvector('english', "title" || ' ' || "original_text"))`,
+		`CREATE INDEX IF NOT EXISTS "IDX_answer_search" ON "answer" ` +
+			`USING GIN (to_tsvector('english', "original_text"))`,
+	}
+	for _, statement := range statements {
+		if _, err := x.Context(ctx).Exec(statement); err != nil {
+			return fmt.Errorf("add full-text index failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func addSQLiteFullTextTables(ctx context.Context, x *xorm.Engine) error {
+	tables := []struct {
+		table   string
+		columns []string
+	}{
+		{"question", []string{"title", "original_text"}},
+		{"answer", []string{"original_text"}},
+	}
+	for _, t := range tables {
+		fts := t.table + "_fts"
+		columns, newValues, oldValues := "", "", ""
+		for i, column := range t.columns {
+			if i > 0 {
+				columns += ", "
+				newValues += ", "
+				oldValues += ", "
+			}
+			columns += column
+			newValues += "new." + column
+			oldValues += "old." + column
+		}
+		insert := fmt.Sprintf("INSERT INTO %s(rowid, %s) VALUES (new.id, %s);", fts, columns, newValues)
+		remove := fmt.Sprintf("INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.id, %s);", fts, fts, columns, oldValues)
+		statements := []string{
+			fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s, content='%s', content_rowid='id', "+
+				"tokenize='porter unicode61')", fts, columns, t.table),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_ai AFTER INSERT ON %s BEGIN %s END", fts, t.table, insert),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_ad AFTER DELETE ON %s BEGIN %s END", fts, t.table, remove),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_au AFTER UPDATE OF %s ON %s BEGIN %s %s END",
+				fts, columns, t.table, remove, insert),
+			// index the rows written before the triggers existed
+			fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", fts, fts),
+		}
+		for _, statement := range statements {
+			if _, err := x.Context(ctx).Exec(statement); err != nil {
+				return fmt.Errorf("add full-text table %s failed: %w", fts, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/entity"
+	"github.com/google/uuid"
+	"xorm.io/xorm"
+)
+
+func addSearchAnalytics(ctx context.Context, x *xorm.Engine) error {
+	if err := x.Context(ctx).Sync(new(entity.SearchLog), new(entity.SearchLogDaily)); err != nil {
+		return fmt.Errorf("sync search log tables failed: %w", err)
+	}
+	if err := addDefaultConfig(ctx, x, searchAnalyticsSaltConfigID); err != nil {
+		return err
+	}
+	return generateSearchAnalyticsSalt(ctx, x)
+}
+
+// addDefaultConfig inserts the config of defaultConfigTable with the id, it is kept when its key exists
+func addDefaultConfig(ctx context.Context, x *xorm.Engine, id int) error {
+	for _, c := range defaultConfigTable {
+		if c.ID != id {
+			continue
+		}
+		exist, err := x.Context(ctx).Exist(&entity.Config{Key: c.Key})
+		if err != nil {
+			return fmt.Errorf("get config %s failed: %w", c.Key, err)
+		}
+		if exist {
+			return nil
+		}
+		if _, err = x.Context(ctx).Insert(&entity.Config{ID: c.ID, Key: c.Key, Value: c.Value}); err != nil {
+			return fmt.Errorf("add config %s failed: %w", c.Key, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("config %d is not in the default configs", id)
+}
+
+// generateSearchAnalyticsSalt generates the salt of the site, it is kept when it is set
+func generateSearchAnalyticsSalt(ctx context.Context, x *xorm.Engine) error {
+	_, err := x.Context(ctx).Where("`key` = ? AND value = ?", constant.SearchAnalyticsSaltKey, "").
+		Cols("value").Update(&entity.Config{Value: uuid.NewString()})
+	if err != nil {
+		return fmt.Errorf("generate search analytics salt failed: %w", err)
+	}
+	return nil
+}
@@ -21,6 +21,8 @@ package ai_conversation
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/apache/answer/internal/base/data"
 	"github.com/apache/answer/internal/base/pager"
@@ -46,6 +48,11 @@ type AIConversationRepo interface {
 	GetConversationsForAdmin(ctx context.Context, page, pageSize int, cond *entity.AIConversation) (list []*entity.AIConversation, total int64, err error)
 	GetConversationWithVoteStats(ctx context.Context, conversationID string) (helpful, unhelpful int64, err error)
 	DeleteConversation(ctx context.Context, conversationID string) error
+	SumTokens(ctx context.Context, userID string, since time.Time) (int64, error)
+	UpdateConversationSummary(ctx context.Context, conversationID, summary string, summarizedRecords int) error
+	UpdateConversationQuestion(ctx context.Context, conversationID, fromQuestionID, toQuestionID string) (bool, error)
+	GetUsage(ctx context.Context, start, end time.Time) ([]*entity.AIConversationUsage, error)
+	CreateUsage(ctx context.Context, usage *entity.AIUsage) error
 }
 
 type aiConversationRepo struct {
@@ -203,3 +210,99 @@ func (r *aiConversationRepo) DeleteConversation(ctx context.Context, conversatio
 
 	return nil
 }
+
+// UpdateConversationSummary updates the running summary of the conversation
+func (r *aiConversationRepo) UpdateConversationSummary(ctx context.Context, conversationID, summary string,
+	summarizedRecords int) error {
+	_, err := r.data.DB.Context(ctx).Where(builder.Eq{"conversation_id": conversationID}).
+		Cols("summary", "summarized_records").
+		Update(&entity.AIConversation{Summary: summary, SummarizedRecords: summarizedRecords})
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// UpdateConversationQuestion links the conversation to the question it was published as. The question is only
+// changed while it is still fromQuestionID, so concurrent updates can not overwrite each other.
+func (r *aiConversationRepo) UpdateConversationQuestion(ctx context.Context, conversationID, fromQuestionID, toQuestionID string) (
+	updated bool, err error) {
+	affected, err := r.data.DB.Context(ctx).
+		Where(builder.Eq{"conversation_id": conversationID}.And(builder.Eq{"question_id": fromQuestionID})).
+		Cols("question_id").
+		Update(&entity.AIConversation{QuestionID: toQuestionID})
+	if err != nil {
+		return false, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return affected > 0, nil
+}
+
+// SumTokens sums the tokens used since the given time, by one user or by the whole site when userID is empty.
+// It counts the assistant replies and the usage of the other AI features.
+func (r *aiConversationRepo) SumTokens(ctx context.Context, userID string, since time.Time) (int64, error) {
+	session := r.data.DB.Context(ctx).Table(entity.AIConversationRecord{}.TableName()).Alias("r").
+		Select("COALESCE(SUM(r.prompt_tokens + r.completion_tokens), 0) AS total").
+		Where(builder.Eq{"r.role": "assistant"}).
+		And(builder.Gte{"r.created_at": since})
+	if len(userID) > 0 {
+		session.Join("INNER", []string{entity.AIConversation{}.TableName(), "c"}, "c.conversation_id = r.conversation_id").
+			And(builder.Eq{"c.user_id": userID})
+	}
+	var total int64
+	if _, err := session.Get(&total); err != nil {
+		return 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+
+	session = r.data.DB.Context(ctx).Table(entity.AIUsage{}.TableName()).
+		Select("COALESCE(SUM(prompt_tokens + completion_tokens), 0) AS total").
+		Where(builder.Gte{"created_at": since})
+	if len(userID) > 0 {
+		session.And(builder.Eq{"user_id": userID})
+	}
+	var other int64
+	if _, err := session.Get(&other); err != nil {
+		return 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return total + other, nil
+}
+
+// GetUsage gets the token usage of the assistant replies and of the other AI features created in [start, end)
+func (r *aiConversationRepo) GetUsage(ctx context.Context, start, end time.Time) ([]*entity.AIConversationUsage, error) {
+	list := make([]*entity.AIConversationUsage, 0)
+	err := r.data.DB.Context(ctx).Table(entity.AIConversationRecord{}.TableName()).Alias("r").
+		Join("INNER", []string{entity.AIConversation{}.TableName(), "c"}, "c.conversation_id = r.conversation_id").
+		Select("c.user_id, r.model, r.prompt_tokens, r.completion_tokens, r.created_at").
+		Where(builder.Eq{"r.role": "assistant"}).
+		And(builder.Gte{"r.created_at": start}).
+		And(builder.Lt{"r.created_at": end}).
+		Asc("r.created_at").
+		Find(&list)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+
+	other := make([]*entity.AIConversationUsage, 0)
+	err = r.data.DB.Context(ctx).Table(entity.AIUsage{}.TableName()).
+		Select("user_id, model, prompt_tokens, completion_tokens, created_at").
+		Where(builder.Gte{"created_at": start}).
+		And(builder.Lt{"created_at": end}).
+		Asc("created_at").
+		Find(&other)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	list = append(list, other...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list, nil
+}
+
+// CreateUsage records the tokens used by an AI feature other than the conversations
+func (r *aiConversationRepo) CreateUsage(ctx context.Context, usage *entity.AIUsage) error {
+	_, err := r.data.DB.Context(ctx).Insert(usage)
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_embedding
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
+)
+
+// GetKeywordCandidates gets the visible questions, topics and wiki documents containing any of the terms,
+// at most limit of each kind. They are shaped like embeddings without a vector so both are ranked the same way.
+// The limit keeps the candidates matching the most terms, then the most recently updated.
+func (r *aiEmbeddingRepo) GetKeywordCandidates(ctx context.Context, terms []string, limit int) (
+	[]*entity.AIEmbedding, error) {
+	candidates := make([]*entity.AIEmbedding, 0)
+	if len(terms) == 0 {
+		return candidates, nil
+	}
+	matchAny := func(cols ...string) builder.Cond {
+		cond := builder.NewCond()
+		for _, term := range terms {
+			for _, col := range cols {
+				cond = cond.Or(builder.Like{col, term})
+			}
+		}
+		return cond
+	}
+	// matchCount orders by the number of the terms each column contains, the relevance the database can tell
+	matchCount := func(cols ...string) *builder.Expression {
+		counts := make([]string, 0, len(terms)*len(cols))
+		args := make([]any, 0, len(terms)*len(cols))
+		for _, term := range terms {
+			for _, col := range cols {
+				counts = append(counts, "CASE WHEN "+col+" LIKE ? THEN 1 ELSE 0 END")
+				args = append(args, "%"+term+"%")
+			}
+		}
+		return builder.Expr("("+strings.Join(counts, " + ")+") DESC", args...).(*builder.Expression)
+	}
+
+	questions := make([]*entity.Question, 0)
+	err := r.data.DB.Context(ctx).
+		Where(builder.In("status", entity.QuestionStatusAvailable, entity.QuestionStatusClosed)).
+		And(builder.Eq{"`show`": entity.QuestionShow}).
+		And(matchAny("title", "original_text")).
+		Cols("id", "title", "original_text").
+		OrderBy(matchCount("title", "original_text")).
+		Desc("updated_at").
+		Limit(limit).
+		Find(&questions)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	for _, question := range questions {
+		candidates = append(candidates, &entity.AIEmbedding{
+			ObjectID:   question.ID,
+			ObjectType: entity.AIEmbeddingObjectTypeQuestion,
+			QuestionID: question.ID,
+			Title:      question.Title,
+			Content:    question.OriginalText,
+		})
+	}
+
+	topics := make([]*entity.Topic, 0)
+	err = r.data.DB.Context(ctx).
+		Where(builder.In("status", entity.TopicStatusAvailable, entity.TopicStatusClosed)).
+		And(builder.Or(matchAny("title"),
+			builder.In("id", builder.Select("topic_id").From(entity.Post{}.TableName()).
+				Where(builder.Eq{"merge_state": entity.PostMergeStateActive, "status": 1}.And(matchAny("original_text")))))).
+		Cols("id", "title").
+		OrderBy(matchCount("title")).
+		Desc("updated_at").
+		Limit(limit).
+		Find(&topics)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	if len(topics) > 0 {
+		topicIDs := make([]string, 0, len(topics))
+		for _, topic := range topics {
+			topicIDs = append(topicIDs, topic.ID)
+		}
+		firstPosts, err := r.getFirstPosts(ctx, topicIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, topic := range topics {
+			candidates = append(candidates, &entity.AIEmbedding{
+				ObjectID:   topic.ID,
+				ObjectType: entity.AIEmbeddingObjectTypeTopic,
+				Title:      topic.Title,
+				Content:    firstPosts[topic.ID],
+			})
+		}
+	}
+
+	wikis := make([]*struct {
+		TopicID  string `xorm:"topic_id"`
+		Title    string `xorm:"title"`
+		Document string `xorm:"document"`
+	}, 0)
+	err = r.data.DB.Context(ctx).Table(entity.Topic{}.TableName()).Alias("t").
+		Join("INNER", []string{entity.WikiRevision{}.TableName(), "w"}, "w.id = t.current_wiki_revision_id").
+		Where(builder.Eq{"t.is_wiki_enabled": true, "t.status": entity.TopicStatusAvailable}).
+		And(matchAny("w.title", "w.document")).
+		Select("t.id AS topic_id, CASE WHEN w.title = '' THEN t.title ELSE w.title END AS title, w.document").
+		OrderBy(matchCount("w.title", "w.document")).
+		Desc("w.created_at").
+		Limit(limit).
+		Find(&wikis)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	for _, wiki := range wikis {
+		candidates = append(candidates, &entity.AIEmbedding{
+			ObjectID:   wiki.TopicID,
+			ObjectType: entity.AIEmbeddingObjectTypeWiki,
+			Title:      wiki.Title,
+			Content:    wiki.Document,
+		})
+	}
+	return candidates, nil
+}
+
+// CountDocuments counts the visible questions, topics and wiki documents
+func (r *aiEmbeddingRepo) CountDocuments(ctx context.Context) (int64, error) {
+	questions, err := r.data.DB.Context(ctx).
+		Where(builder.In("status", entity.QuestionStatusAvailable, entity.QuestionStatusClosed)).
+		And(builder.Eq{"`show`": entity.QuestionShow}).
+		Count(&entity.Question{})
+	if err != nil {
+		return 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	topics, err := r.data.DB.Context(ctx).
+		Where(builder.In("status", entity.TopicStatusAvailable, entity.TopicStatusClosed)).
+		Count(&entity.Topic{})
+	if err != nil {
+		return 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	wikis, err := r.data.DB.Context(ctx).
+		Where(builder.Eq{"is_wiki_enabled": true, "status": entity.TopicStatusAvailable}).
+		And(builder.Neq{"current_wiki_revision_id": 0}).
+		Count(&entity.Topic{})
+	if err != nil {
+		return 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return questions + topics + wikis, nil
+}
+
+// getFirstPosts gets the content of the opening post of each topic
+func (r *aiEmbeddingRepo) getFirstPosts(ctx context.Context, topicIDs []string) (map[string]string, error) {
+	posts := make([]*entity.Post, 0)
+	err := r.data.DB.Context(ctx).
+		Where(builder.In("id", builder.Select("MIN(id)").From(entity.Post{}.TableName()).
+			Where(builder.In("topic_id", topicIDs)).GroupBy("topic_id"))).
+		Cols("topic_id", "original_text").
+		Find(&posts)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	firstPosts := make(map[string]string, len(posts))
+	for _, post := range posts {
+		firstPosts[post.TopicID] = post.Original
+	}
+	return firstPosts, nil
+}
@@ -0,0 +1,240 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_embedding
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/data"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
+)
+
+// AIEmbeddingRepo
+type AIEmbeddingRepo interface {
+	SaveEmbedding(ctx context.Context, embedding *entity.AIEmbedding) error
+	GetEmbedding(ctx context.Context, objectType, objectID string) (*entity.AIEmbedding, bool, error)
+	GetEmbeddingVectorsByModel(ctx context.Context, model string) ([]*entity.AIEmbedding, error)
+	GetEmbeddingsByIDs(ctx context.Context, ids []int) ([]*entity.AIEmbedding, error)
+	DeleteEmbedding(ctx context.Context, objectType, objectID string) error
+	DeleteAnswerEmbeddingsByQuestionID(ctx context.Context, questionID string) error
+	GetUnindexedQuestionIDs(ctx context.Context, model string, limit int) ([]string, error)
+	GetUnindexedAcceptedAnswerIDs(ctx context.Context, model string, limit int) ([]string, error)
+	GetUnindexedWikiTopicIDs(ctx context.Context, model string, limit int) ([]string, error)
+	GetUnindexedTopicIDs(ctx context.Context, model string, limit int) ([]string, error)
+	GetKeywordCandidates(ctx context.Context, terms []string, limit int) ([]*entity.AIEmbedding, error)
+	CountDocuments(ctx context.Context) (int64, error)
+	GetSimilarContentCache(ctx context.Context, key string) (*schema.SimilarContentResp, bool, error)
+	SetSimilarContentCache(ctx context.Context, key string, resp *schema.SimilarContentResp) error
+}
+
+type aiEmbeddingRepo struct {
+	data *data.Data
+}
+
+// NewAIEmbeddingRepo new AIEmbeddingRepo
+func NewAIEmbeddingRepo(data *data.Data) AIEmbeddingRepo {
+	return &aiEmbeddingRepo{
+		data: data,
+	}
+}
+
+// SaveEmbedding inserts the embedding or replaces the existing one of the same object
+func (r *aiEmbeddingRepo) SaveEmbedding(ctx context.Context, embedding *entity.AIEmbedding) error {
+	old, exist, err := r.GetEmbedding(ctx, embedding.ObjectType, embedding.ObjectID)
+	if err != nil {
+		return err
+	}
+	if exist {
+		embedding.ID = old.ID
+		_, err = r.data.DB.Context(ctx).ID(old.ID).
+			Cols("question_id", "title", "content", "content_hash", "model", "dimensions", "vector").
+			Update(embedding)
+	} else {
+		_, err = r.data.DB.Context(ctx).Insert(embedding)
+	}
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// GetEmbedding gets the embedding of an object
+func (r *aiEmbeddingRepo) GetEmbedding(ctx context.Context, objectType, objectID string) (*entity.AIEmbedding, bool, error) {
+	embedding := &entity.AIEmbedding{}
+	exist, err := r.data.DB.Context(ctx).
+		Where(builder.Eq{"object_type": objectType, "object_id": objectID}).
+		Get(embedding)
+	if err != nil {
+		return nil, false, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return embedding, exist, nil
+}
+
+// GetEmbeddingVectorsByModel gets the id and vector of all embeddings computed by the model, without the content
+func (r *aiEmbeddingRepo) GetEmbeddingVectorsByModel(ctx context.Context, model string) ([]*entity.AIEmbedding, error) {
+	list := make([]*entity.AIEmbedding, 0)
+	err := r.data.DB.Context(ctx).Cols("id", "object_id", "object_type", "dimensions", "vector").
+		Where(builder.Eq{"model": model}).Find(&list)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return list, nil
+}
+
+// GetEmbeddingsByIDs gets the embeddings by ids
+func (r *aiEmbeddingRepo) GetEmbeddingsByIDs(ctx context.Context, ids []int) ([]*entity.AIEmbedding, error) {
+	list := make([]*entity.AIEmbedding, 0)
+	if len(ids) == 0 {
+		return list, nil
+	}
+	err := r.data.DB.Context(ctx).In("id", ids).Find(&list)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return list, nil
+}
+
+// DeleteEmbedding deletes the embedding of an object
+func (r *aiEmbeddingRepo) DeleteEmbedding(ctx context.Context, objectType, objectID string) error {
+	_, err := r.data.DB.Context(ctx).
+		Where(builder.Eq{"object_type": objectType, "object_id": objectID}).
+		Delete(&entity.AIEmbedding{})
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// DeleteAnswerEmbeddingsByQuestionID deletes the embeddings of all answers of a question
+func (r *aiEmbeddingRepo) DeleteAnswerEmbeddingsByQuestionID(ctx context.Context, questionID string) error {
+	_, err := r.data.DB.Context(ctx).
+		Where(builder.Eq{"object_type": entity.AIEmbeddingObjectTypeAnswer, "question_id": questionID}).
+		Delete(&entity.AIEmbedding{})
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// GetUnindexedQuestionIDs gets visible questions that have no embedding of the model yet
+func (r *aiEmbeddingRepo) GetUnindexedQuestionIDs(ctx context.Context, model string, limit int) ([]string, error) {
+	ids := make([]string, 0)
+	err := r.data.DB.Context(ctx).Table(entity.Question{}.TableName()).Alias("q").
+		Join("LEFT", []string{entity.AIEmbedding{}.TableName(), "e"},
+			"e.object_id = q.id AND e.object_type = ? AND e.model = ?", entity.AIEmbeddingObjectTypeQuestion, model).
+		Where(builder.In("q.status", entity.QuestionStatusAvailable, entity.QuestionStatusClosed)).
+		And(builder.Eq{"q.show": entity.QuestionShow}).
+		And(builder.IsNull{"e.id"}).
+		Limit(limit).
+		Cols("q.id").
+		Find(&ids)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return ids, nil
+}
+
+// GetUnindexedAcceptedAnswerIDs gets accepted answers that have no embedding of the model yet
+func (r *aiEmbeddingRepo) GetUnindexedAcceptedAnswerIDs(ctx context.Context, model string, limit int) ([]string, error) {
+	ids := make([]string, 0)
+	err := r.data.DB.Context(ctx).Table(entity.Answer{}.TableName()).Alias("a").
+		Join("LEFT", []string{entity.AIEmbedding{}.TableName(), "e"},
+			"e.object_id = a.id AND e.object_type = ? AND e.model = ?", entity.AIEmbeddingObjectTypeAnswer, model).
+		Where(builder.Eq{"a.status": entity.AnswerStatusAvailable, "a.adopted": schema.AnswerAcceptedEnable}).
+		And(builder.IsNull{"e.id"}).
+		Limit(limit).
+		Cols("a.id").
+		Find(&ids)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return ids, nil
+}
+
+// GetUnindexedWikiTopicIDs gets topics with a current wiki document that has no embedding of the model yet
+func (r *aiEmbeddingRepo) GetUnindexedWikiTopicIDs(ctx context.Context, model string, limit int) ([]string, error) {
+	ids := make([]string, 0)
+	err := r.data.DB.Context(ctx).Table(entity.Topic{}.TableName()).Alias("t").
+		Join("LEFT", []string{entity.AIEmbedding{}.TableName(), "e"},
+			"e.object_id = t.id AND e.object_type = ? AND e.model = ?", entity.AIEmbeddingObjectTypeWiki, model).
+		Where(builder.Eq{"t.is_wiki_enabled": true, "t.status": entity.TopicStatusAvailable}).
+		And(builder.Neq{"t.current_wiki_revision_id": 0}).
+		And(builder.IsNull{"e.id"}).
+		Limit(limit).
+		Cols("t.id").
+		Find(&ids)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return ids, nil
+}
+
+// GetUnindexedTopicIDs gets visible topics that have no embedding of the model yet
+func (r *aiEmbeddingRepo) GetUnindexedTopicIDs(ctx context.Context, model string, limit int) ([]string, error) {
+	ids := make([]string, 0)
+	err := r.data.DB.Context(ctx).Table(entity.Topic{}.TableName()).Alias("t").
+		Join("LEFT", []string{entity.AIEmbedding{}.TableName(), "e"},
+			"e.object_id = t.id AND e.object_type = ? AND e.model = ?", entity.AIEmbeddingObjectTypeTopic, model).
+		Where(builder.In("t.status", entity.TopicStatusAvailable, entity.TopicStatusClosed)).
+		And(builder.IsNull{"e.id"}).
+		Limit(limit).
+		Cols("t.id").
+		Find(&ids)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return ids, nil
+}
+
+// GetSimilarContentCache gets the similar content found recently for the same title
+func (r *aiEmbeddingRepo) GetSimilarContentCache(ctx context.Context, key string) (*schema.SimilarContentResp, bool, error) {
+	cacheData, exist, err := r.data.Cache.GetString(ctx, constant.SimilarContentCacheKeyPrefix+key)
+	if err != nil {
+		return nil, false, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	if !exist {
+		return nil, false, nil
+	}
+	resp := &schema.SimilarContentResp{}
+	if err := json.Unmarshal([]byte(cacheData), resp); err != nil {
+		return nil, false, nil
+	}
+	return resp, true, nil
+}
+
+// SetSimilarContentCache keeps the similar content of a title for a short time
+func (r *aiEmbeddingRepo) SetSimilarContentCache(ctx context.Context, key string, resp *schema.SimilarContentResp) error {
+	cacheData, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	err = r.data.Cache.SetString(ctx, constant.SimilarContentCacheKeyPrefix+key, string(cacheData),
+		constant.SimilarContentCacheTime)
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
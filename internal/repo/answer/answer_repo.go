@@ -463,13 +463,7 @@ func (ar *answerRepo) SumVotesByQuestionID(ctx context.Context, questionID strin
 func (ar *answerRepo) updateSearch(ctx context.Context, answerID string) (err error) {
 	answerID = uid.DeShortID(answerID)
 	// check search plugin
-	var (
-		s plugin.Search
-	)
-	_ = plugin.CallSearch(func(search plugin.Search) error {
-		s = search
-		return nil
-	})
+	s := plugin.GetSearch()
 	if s == nil {
 		return
 	}
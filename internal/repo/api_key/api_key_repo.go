@@ -58,8 +58,17 @@ func (ar *apiKeyRepo) GetAPIKey(ctx context.Context, apiKey string) (key *entity
 	return
 }
 
+func (ar *apiKeyRepo) GetAPIKeyByID(ctx context.Context, id int) (key *entity.APIKey, exist bool, err error) {
+	key = &entity.APIKey{}
+	exist, err = ar.data.DB.Context(ctx).ID(id).Get(key)
+	if err != nil {
+		err = errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return
+}
+
 func (ar *apiKeyRepo) UpdateAPIKey(ctx context.Context, apiKey entity.APIKey) (err error) {
-	_, err = ar.data.DB.Context(ctx).ID(apiKey.ID).Update(&apiKey)
+	_, err = ar.data.DB.Context(ctx).ID(apiKey.ID).Cols("description", "mcp_write_tools").Update(&apiKey)
 	if err != nil {
 		err = errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
 	}
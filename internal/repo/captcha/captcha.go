@@ -51,6 +51,7 @@ func (cr *captchaRepo) SetActionType(ctx context.Context, unit, actionType, conf
 	value := &entity.ActionRecordInfo{}
 	value.LastTime = now.Unix()
 	value.Num = amount
+	value.Config = config
 	valueStr, err := json.Marshal(value)
 	if err != nil {
 		return nil
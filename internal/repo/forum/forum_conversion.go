@@ -0,0 +1,657 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package forum
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/activity_type"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+const questionTitleMaxLength = 150
+
+// conversionItem is one piece of content that becomes a post (question to topic)
+// or an answer (topic to question) during a conversion.
+type conversionItem struct {
+	sourceType string
+	sourceID   string
+	userID     string
+	original   string
+	parsed     string
+	createdAt  time.Time
+	voteCount  int
+}
+
+type conversionVote struct {
+	userID string
+	value  int
+}
+
+func (r *ForumRepo) GetObjectConversion(ctx context.Context, sourceObjectID string) (*entity.ObjectConversion, bool, error) {
+	conversion := &entity.ObjectConversion{}
+	exist, err := r.data.DB.Context(ctx).Where("source_object_id = ?", uid.DeShortID(sourceObjectID)).
+		Desc("created_at").Get(conversion)
+	if err != nil {
+		return nil, false, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return conversion, exist, nil
+}
+
+// ConvertQuestionToTopic moves a question with its answers and comments into a new topic of the given category.
+// The question body becomes the first post, answers and comments follow in creation order and the accepted
+// answer becomes the topic solution. Tags move to the topic. The question is removed from Q&A and the
+// conversion is recorded.
+func (r *ForumRepo) ConvertQuestionToTopic(
+	ctx context.Context,
+	questionID, categoryID, topicKind, operatorID string,
+) (*entity.Topic, error) {
+	questionID = uid.DeShortID(questionID)
+	question := &entity.Question{ID: questionID}
+	exist, err := r.data.DB.Context(ctx).Get(question)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	if !exist {
+		return nil, errors.NotFound(reason.QuestionNotFound)
+	}
+	if question.Status != entity.QuestionStatusAvailable && question.Status != entity.QuestionStatusClosed {
+		return nil, errors.BadRequest(reason.StatusInvalid)
+	}
+
+	answers := make([]*entity.Answer, 0)
+	if err := r.data.DB.Context(ctx).Where("question_id = ? AND status = ?", questionID, entity.AnswerStatusAvailable).
+		Find(&answers); err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	comments := make([]*entity.Comment, 0)
+	if err := r.data.DB.Context(ctx).Where("question_id = ? AND status = ?", questionID, entity.CommentStatusAvailable).
+		Find(&comments); err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+
+	items := []*conversionItem{{
+		sourceType: constant.QuestionObjectType,
+		sourceID:   question.ID,
+		userID:     question.UserID,
+		original:   question.OriginalText,
+		parsed:     question.ParsedText,
+		createdAt:  question.CreatedAt,
+	}}
+	objectIDs := make([]string, 0, len(answers)+len(comments))
+	for _, answer := range answers {
+		items = append(items, &conversionItem{
+			sourceType: constant.AnswerObjectType,
+			sourceID:   answer.ID,
+			userID:     answer.UserID,
+			original:   answer.OriginalText,
+			parsed:     answer.ParsedText,
+			createdAt:  answer.CreatedAt,
+			voteCount:  answer.VoteCount,
+		})
+		objectIDs = append(objectIDs, answer.ID)
+	}
+	for _, comment := range comments {
+		items = append(items, &conversionItem{
+			sourceType: constant.CommentObjectType,
+			sourceID:   comment.ID,
+			userID:     comment.UserID,
+			original:   comment.OriginalText,
+			parsed:     comment.ParsedText,
+			createdAt:  comment.CreatedAt,
+			voteCount:  comment.VoteCount,
+		})
+		objectIDs = append(objectIDs, comment.ID)
+	}
+	// The question body always stays first, everything else follows in creation order.
+	sort.SliceStable(items[1:], func(i, j int) bool {
+		return items[1+i].createdAt.Before(items[1+j].createdAt)
+	})
+
+	activityTypes, err := r.getActivityTypes(ctx,
+		activity_type.QuestionVoteUp, activity_type.QuestionVoteDown,
+		activity_type.AnswerVoteUp, activity_type.AnswerVoteDown, activity_type.CommentVoteUp)
+	if err != nil {
+		return nil, err
+	}
+	voteValues := map[string]int{
+		activity_type.QuestionVoteUp:   1,
+		activity_type.QuestionVoteDown: -1,
+		activity_type.AnswerVoteUp:     1,
+		activity_type.AnswerVoteDown:   -1,
+		activity_type.CommentVoteUp:    1,
+	}
+	votes, err := r.listActivityVotes(ctx, append([]string{question.ID}, objectIDs...), activityTypes, voteValues)
+	if err != nil {
+		return nil, err
+	}
+
+	topicID, err := r.genID(ctx, entity.Topic{}.TableName())
+	if err != nil {
+		return nil, err
+	}
+	topic := &entity.Topic{
+		ID:         topicID,
+		CreatedAt:  question.CreatedAt,
+		CategoryID: uid.DeShortID(categoryID),
+		UserID:     question.UserID,
+		Title:      question.Title,
+		TopicKind:  topicKind,
+		Status:     entity.TopicStatusAvailable,
+		PostCount:  len(items),
+		VoteCount:  question.VoteCount,
+	}
+	if question.Status == entity.QuestionStatusClosed {
+		topic.Status = entity.TopicStatusClosed
+	}
+
+	posts := make([]*entity.Post, 0, len(items))
+	postIDBySource := make(map[string]string, len(items))
+	for _, item := range items {
+		postID, err := r.genID(ctx, entity.Post{}.TableName())
+		if err != nil {
+			return nil, err
+		}
+		postIDBySource[item.sourceID] = postID
+		posts = append(posts, &entity.Post{
+			ID:         postID,
+			CreatedAt:  item.createdAt,
+			TopicID:    topicID,
+			UserID:     item.userID,
+			Original:   item.original,
+			Parsed:     item.parsed,
+			MergeState: entity.PostMergeStateActive,
+			VoteCount:  item.voteCount,
+			Status:     1,
+		})
+	}
+	topic.LastPostID = posts[len(posts)-1].ID
+
+	var solution *entity.TopicSolution
+	if postID, ok := postIDBySource[question.AcceptedAnswerID]; ok {
+		solutionID, err := r.genID(ctx, entity.TopicSolution{}.TableName())
+		if err != nil {
+			return nil, err
+		}
+		topic.SolvedPostID = postID
+		solution = &entity.TopicSolution{
+			ID:          solutionID,
+			TopicID:     topicID,
+			PostID:      postID,
+			SetByUserID: question.UserID,
+		}
+	}
+
+	topicVotes := make([]*entity.TopicVote, 0)
+	postVotes := make([]*entity.PostVote, 0)
+	for objectID, objectVotes := range votes {
+		for _, vote := range objectVotes {
+			if objectID == question.ID {
+				voteID, err := r.genID(ctx, entity.TopicVote{}.TableName())
+				if err != nil {
+					return nil, err
+				}
+				topicVotes = append(topicVotes, &entity.TopicVote{
+					ID: voteID, TopicID: topicID, UserID: vote.userID, Value: vote.value,
+				})
+				continue
+			}
+			voteID, err := r.genID(ctx, entity.PostVote{}.TableName())
+			if err != nil {
+				return nil, err
+			}
+			postVotes = append(postVotes, &entity.PostVote{
+				ID: voteID, PostID: postIDBySource[objectID], UserID: vote.userID, Value: vote.value,
+			})
+		}
+	}
+
+	conversions, err := r.buildConversions(ctx, items, func(item *conversionItem) (string, string) {
+		if item.sourceType == constant.QuestionObjectType {
+			return constant.TopicObjectType, topicID
+		}
+		return constant.PostObjectType, postIDBySource[item.sourceID]
+	}, topicID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.data.DB.Transaction(func(session *xorm.Session) (any, error) {
+		session = session.Context(ctx)
+		affected, err := session.ID(question.ID).In("status", entity.QuestionStatusAvailable, entity.QuestionStatusClosed).
+			Cols("status").Update(&entity.Question{Status: entity.QuestionStatusDeleted})
+		if err != nil {
+			return nil, err
+		}
+		if affected == 0 {
+			return nil, errors.BadRequest(reason.StatusInvalid)
+		}
+		// the answers and comments live on as posts, so they leave the user lists, the search and the index
+		if len(answers) > 0 {
+			answerIDs := make([]string, 0, len(answers))
+			for _, answer := range answers {
+				answerIDs = append(answerIDs, answer.ID)
+			}
+			if _, err := session.In("id", answerIDs).Cols("status").
+				Update(&entity.Answer{Status: entity.AnswerStatusDeleted}); err != nil {
+				return nil, err
+			}
+		}
+		if len(comments) > 0 {
+			commentIDs := make([]string, 0, len(comments))
+			for _, comment := range comments {
+				commentIDs = append(commentIDs, comment.ID)
+			}
+			if _, err := session.In("id", commentIDs).Cols("status").
+				Update(&entity.Comment{Status: entity.CommentStatusDeleted}); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := session.Insert(topic); err != nil {
+			return nil, err
+		}
+		tagIDs, err := objectTagIDs(session, question.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := moveTagRels(session, question.ID, topic.ID); err != nil {
+			return nil, err
+		}
+		answerUserIDs := make([]string, 0, len(answers))
+		for _, answer := range answers {
+			answerUserIDs = append(answerUserIDs, answer.UserID)
+		}
+		if err := refreshConversionCounts(session, []string{question.UserID}, answerUserIDs, tagIDs); err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			if _, err := session.Insert(post); err != nil {
+				return nil, err
+			}
+		}
+		if solution != nil {
+			if _, err := session.Insert(solution); err != nil {
+				return nil, err
+			}
+		}
+		for _, vote := range topicVotes {
+			if _, err := session.Insert(vote); err != nil {
+				return nil, err
+			}
+		}
+		for _, vote := range postVotes {
+			if _, err := session.Insert(vote); err != nil {
+				return nil, err
+			}
+		}
+		for _, conversion := range conversions {
+			if _, err := session.Insert(conversion); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		if _, ok := err.(*errors.Error); ok {
+			return nil, err
+		}
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return topic, nil
+}
+
+// ConvertTopicToQuestion moves a topic with its posts into a new question. The first post becomes the question
+// body, the remaining posts become answers and the topic solution becomes the accepted answer.
+// Votes are carried over without granting reputation again and tags move to the question. The topic is marked
+// as converted.
+func (r *ForumRepo) ConvertTopicToQuestion(ctx context.Context, topicID, operatorID string) (*entity.Question, error) {
+	topic, exist, err := r.GetTopic(ctx, topicID)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, errors.NotFound(reason.ObjectNotFound)
+	}
+	if topic.Status == entity.TopicStatusConverted {
+		return nil, errors.BadRequest(reason.StatusInvalid)
+	}
+
+	posts := make([]*entity.Post, 0)
+	if err := r.data.DB.Context(ctx).Where("topic_id = ? AND status = ?", topic.ID, 1).
+		Asc("created_at", "id").Find(&posts); err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	if len(posts) == 0 {
+		return nil, errors.BadRequest(reason.ObjectNotFound)
+	}
+
+	activityTypes, err := r.getActivityTypes(ctx,
+		activity_type.QuestionVoteUp, activity_type.QuestionVoteDown,
+		activity_type.AnswerVoteUp, activity_type.AnswerVoteDown)
+	if err != nil {
+		return nil, err
+	}
+
+	questionID, err := r.genID(ctx, entity.Question{}.TableName())
+	if err != nil {
+		return nil, err
+	}
+	body := posts[0]
+	question := &entity.Question{
+		ID:             questionID,
+		CreatedAt:      topic.CreatedAt,
+		UserID:         topic.UserID,
+		Title:          truncateRunes(topic.Title, questionTitleMaxLength),
+		OriginalText:   body.Original,
+		ParsedText:     body.Parsed,
+		Pin:            entity.QuestionUnPin,
+		Show:           entity.QuestionShow,
+		Status:         entity.QuestionStatusAvailable,
+		VoteCount:      topic.VoteCount,
+		AnswerCount:    len(posts) - 1,
+		PostUpdateTime: posts[len(posts)-1].CreatedAt,
+	}
+	if topic.Status == entity.TopicStatusClosed {
+		question.Status = entity.QuestionStatusClosed
+	}
+
+	items := []*conversionItem{{sourceType: constant.TopicObjectType, sourceID: topic.ID}}
+	answers := make([]*entity.Answer, 0, len(posts)-1)
+	answerIDByPost := make(map[string]string, len(posts)-1)
+	for _, post := range posts[1:] {
+		answerID, err := r.genID(ctx, entity.Answer{}.TableName())
+		if err != nil {
+			return nil, err
+		}
+		answer := &entity.Answer{
+			ID:           answerID,
+			CreatedAt:    post.CreatedAt,
+			UpdatedAt:    post.CreatedAt,
+			QuestionID:   questionID,
+			UserID:       post.UserID,
+			OriginalText: post.Original,
+			ParsedText:   post.Parsed,
+			Status:       entity.AnswerStatusAvailable,
+			Accepted:     schema.AnswerAcceptedFailed,
+			VoteCount:    post.VoteCount,
+		}
+		if post.ID == topic.SolvedPostID {
+			answer.Accepted = schema.AnswerAcceptedEnable
+			question.AcceptedAnswerID = answerID
+		}
+		answers = append(answers, answer)
+		answerIDByPost[post.ID] = answerID
+		question.LastAnswerID = answerID
+		items = append(items, &conversionItem{sourceType: constant.PostObjectType, sourceID: post.ID})
+	}
+	items = append(items, &conversionItem{sourceType: constant.PostObjectType, sourceID: body.ID})
+
+	activities := make([]*entity.Activity, 0)
+	topicVotes := make([]*entity.TopicVote, 0)
+	if err := r.data.DB.Context(ctx).Where("topic_id = ?", topic.ID).Find(&topicVotes); err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	for _, vote := range topicVotes {
+		activities = append(activities, newVoteActivity(questionID, vote.UserID, vote.Value,
+			activityTypes[activity_type.QuestionVoteUp], activityTypes[activity_type.QuestionVoteDown]))
+	}
+	postVotes := make([]*entity.PostVote, 0)
+	if len(answerIDByPost) > 0 {
+		postIDs := make([]string, 0, len(answerIDByPost))
+		for postID := range answerIDByPost {
+			postIDs = append(postIDs, postID)
+		}
+		if err := r.data.DB.Context(ctx).In("post_id", postIDs).Find(&postVotes); err != nil {
+			return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+		}
+	}
+	for _, vote := range postVotes {
+		activities = append(activities, newVoteActivity(answerIDByPost[vote.PostID], vote.UserID, vote.Value,
+			activityTypes[activity_type.AnswerVoteUp], activityTypes[activity_type.AnswerVoteDown]))
+	}
+
+	conversions, err := r.buildConversions(ctx, items, func(item *conversionItem) (string, string) {
+		if answerID, ok := answerIDByPost[item.sourceID]; ok {
+			return constant.AnswerObjectType, answerID
+		}
+		return constant.QuestionObjectType, questionID
+	}, questionID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.data.DB.Transaction(func(session *xorm.Session) (any, error) {
+		session = session.Context(ctx)
+		affected, err := session.ID(topic.ID).Where("status <> ?", entity.TopicStatusConverted).
+			Cols("status").Update(&entity.Topic{Status: entity.TopicStatusConverted})
+		if err != nil {
+			return nil, err
+		}
+		if affected == 0 {
+			return nil, errors.BadRequest(reason.StatusInvalid)
+		}
+		if _, err := session.Insert(question); err != nil {
+			return nil, err
+		}
+		tagIDs, err := objectTagIDs(session, topic.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := moveTagRels(session, topic.ID, question.ID); err != nil {
+			return nil, err
+		}
+		answerUserIDs := make([]string, 0, len(answers))
+		for _, answer := range answers {
+			// keep the original post time instead of the insert time
+			if _, err := session.NoAutoTime().Insert(answer); err != nil {
+				return nil, err
+			}
+			answerUserIDs = append(answerUserIDs, answer.UserID)
+		}
+		if err := refreshConversionCounts(session, []string{question.UserID}, answerUserIDs, tagIDs); err != nil {
+			return nil, err
+		}
+		for _, activity := range activities {
+			if _, err := session.Insert(activity); err != nil {
+				return nil, err
+			}
+		}
+		for _, conversion := range conversions {
+			if _, err := session.Insert(conversion); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		if _, ok := err.(*errors.Error); ok {
+			return nil, err
+		}
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return question, nil
+}
+
+// objectTagIDs returns the tags of an object inside a conversion transaction
+func objectTagIDs(session *xorm.Session, objectID string) ([]string, error) {
+	tagIDs := make([]string, 0)
+	err := session.Table(entity.TagRel{}.TableName()).Where("object_id = ?", objectID).Cols("tag_id").Find(&tagIDs)
+	return tagIDs, err
+}
+
+// refreshConversionCounts recounts, inside a conversion transaction, the questions and answers of the users whose
+// content moved and the questions of the moved tags. Topics are not counted as questions of their tags.
+func refreshConversionCounts(session *xorm.Session, questionUserIDs, answerUserIDs, tagIDs []string) error {
+	for _, userID := range uniqueIDs(questionUserIDs) {
+		count, err := session.Where(builder.Lt{"status": entity.QuestionStatusDeleted}).
+			Count(&entity.Question{UserID: userID})
+		if err != nil {
+			return err
+		}
+		if _, err := session.ID(userID).Cols("question_count").Update(&entity.User{QuestionCount: int(count)}); err != nil {
+			return err
+		}
+	}
+	for _, userID := range uniqueIDs(answerUserIDs) {
+		count, err := session.Count(&entity.Answer{UserID: userID, Status: entity.AnswerStatusAvailable})
+		if err != nil {
+			return err
+		}
+		if _, err := session.ID(userID).Cols("answer_count").Update(&entity.User{AnswerCount: int(count)}); err != nil {
+			return err
+		}
+	}
+	for _, tagID := range uniqueIDs(tagIDs) {
+		count, err := session.Where(builder.NotIn("object_id", builder.Select("id").From(entity.Topic{}.TableName()))).
+			Count(&entity.TagRel{TagID: tagID, Status: entity.TagRelStatusAvailable})
+		if err != nil {
+			return err
+		}
+		if _, err := session.ID(tagID).Cols("question_count").Update(&entity.Tag{QuestionCount: int(count)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if len(id) == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
+
+func (r *ForumRepo) buildConversions(
+	ctx context.Context,
+	items []*conversionItem,
+	target func(item *conversionItem) (objectType, objectID string),
+	targetRootID, operatorID string,
+) ([]*entity.ObjectConversion, error) {
+	conversions := make([]*entity.ObjectConversion, 0, len(items))
+	for _, item := range items {
+		id, err := r.genID(ctx, entity.ObjectConversion{}.TableName())
+		if err != nil {
+			return nil, err
+		}
+		targetType, targetID := target(item)
+		conversions = append(conversions, &entity.ObjectConversion{
+			ID:               id,
+			SourceObjectType: item.sourceType,
+			SourceObjectID:   item.sourceID,
+			TargetObjectType: targetType,
+			TargetObjectID:   targetID,
+			TargetRootID:     targetRootID,
+			OperatorID:       operatorID,
+		})
+	}
+	return conversions, nil
+}
+
+func (r *ForumRepo) getActivityTypes(ctx context.Context, keys ...string) (map[string]int, error) {
+	configs := make([]*entity.Config, 0)
+	if err := r.data.DB.Context(ctx).In("`key`", keys).Find(&configs); err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	activityTypes := make(map[string]int, len(configs))
+	for _, cfg := range configs {
+		activityTypes[cfg.Key] = cfg.ID
+	}
+	return activityTypes, nil
+}
+
+// listActivityVotes returns the effective vote of every user on the given objects, keyed by object id.
+// activityTypes maps the action names to their activity type, voteValues the action names to their vote value.
+func (r *ForumRepo) listActivityVotes(ctx context.Context, objectIDs []string,
+	activityTypes map[string]int, voteValues map[string]int) (map[string][]*conversionVote, error) {
+	valueByType := make(map[int]int, len(voteValues))
+	for action, value := range voteValues {
+		if activityType, ok := activityTypes[action]; ok {
+			valueByType[activityType] = value
+		}
+	}
+	votes := make(map[string][]*conversionVote)
+	if len(valueByType) == 0 {
+		return votes, nil
+	}
+	types := make([]int, 0, len(valueByType))
+	for activityType := range valueByType {
+		types = append(types, activityType)
+	}
+	activities := make([]*entity.Activity, 0)
+	err := r.data.DB.Context(ctx).Where(builder.In("object_id", objectIDs)).
+		And(builder.In("activity_type", types)).
+		And(builder.Eq{"cancelled": entity.ActivityAvailable}).
+		Asc("updated_at").
+		Find(&activities)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+
+	latest := make(map[string]map[string]int)
+	for _, activity := range activities {
+		if latest[activity.ObjectID] == nil {
+			latest[activity.ObjectID] = make(map[string]int)
+		}
+		latest[activity.ObjectID][activity.UserID] = valueByType[activity.ActivityType]
+	}
+	for objectID, userVotes := range latest {
+		for userID, value := range userVotes {
+			votes[objectID] = append(votes[objectID], &conversionVote{userID: userID, value: value})
+		}
+	}
+	return votes, nil
+}
+
+func newVoteActivity(objectID, userID string, value, voteUpType, voteDownType int) *entity.Activity {
+	activityType := voteUpType
+	if value < 0 {
+		activityType = voteDownType
+	}
+	return &entity.Activity{
+		ObjectID:         objectID,
+		OriginalObjectID: objectID,
+		UserID:           userID,
+		ActivityType:     activityType,
+		Cancelled:        entity.ActivityAvailable,
+	}
+}
+
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
@@ -0,0 +1,232 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package forum
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/segmentfault/pacman/errors"
+	"xorm.io/xorm"
+)
+
+func (r *ForumRepo) GetTopicPoll(ctx context.Context, topicID string) (*entity.TopicPoll, bool, error) {
+	poll := &entity.TopicPoll{}
+	exist, err := r.data.DB.Context(ctx).Where("topic_id = ?", uid.DeShortID(topicID)).Get(poll)
+	if err != nil {
+		return nil, false, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return poll, exist, nil
+}
+
+func (r *ForumRepo) ListTopicPollOptions(ctx context.Context, pollID string) ([]*entity.TopicPollOption, error) {
+	options := make([]*entity.TopicPollOption, 0)
+	if err := r.data.DB.Context(ctx).Where("poll_id = ?", pollID).Asc("position").Find(&options); err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return options, nil
+}
+
+func (r *ForumRepo) ListUserTopicPollVotes(ctx context.Context, pollID, userID string) ([]*entity.TopicPollVote, error) {
+	votes := make([]*entity.TopicPollVote, 0)
+	if err := r.data.DB.Context(ctx).Where("poll_id = ? AND user_id = ?", pollID, userID).Find(&votes); err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return votes, nil
+}
+
+func (r *ForumRepo) ListTopicPollVoters(ctx context.Context, pollID string) ([]*schema.TopicPollVoter, error) {
+	voters := make([]*schema.TopicPollVoter, 0)
+	query := `
+SELECT
+	v.user_id,
+	v.option_id,
+	u.username,
+	u.display_name
+FROM topic_poll_votes AS v
+LEFT JOIN user AS u ON u.id = v.user_id
+WHERE v.poll_id = ?
+ORDER BY v.created_at ASC`
+	if err := r.data.DB.Context(ctx).SQL(query, pollID).Find(&voters); err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return voters, nil
+}
+
+// SaveTopicPoll creates the poll with its options when it has no ID yet, otherwise it updates the poll.
+// On update, a non-nil options list replaces the current options, which is refused once somebody has voted.
+func (r *ForumRepo) SaveTopicPoll(ctx context.Context, poll *entity.TopicPoll, options []*entity.TopicPollOption) error {
+	create := poll.ID == ""
+	if create {
+		id, err := r.genID(ctx, poll.TableName())
+		if err != nil {
+			return err
+		}
+		poll.ID = id
+	}
+	for _, option := range options {
+		id, err := r.genID(ctx, option.TableName())
+		if err != nil {
+			return err
+		}
+		option.ID = id
+		option.PollID = poll.ID
+	}
+
+	_, err := r.data.DB.Transaction(func(session *xorm.Session) (any, error) {
+		session = session.Context(ctx)
+		if create {
+			if _, err := session.Insert(poll); err != nil {
+				return nil, err
+			}
+		} else {
+			current := &entity.TopicPoll{}
+			exist, err := session.ID(poll.ID).Get(current)
+			if err != nil {
+				return nil, err
+			}
+			if !exist {
+				return nil, errors.NotFound(reason.ObjectNotFound)
+			}
+			if options != nil && current.VoterCount > 0 {
+				return nil, errors.BadRequest(reason.RequestFormatError).WithMsg("poll options cannot change once voting has started")
+			}
+			if _, err := session.ID(poll.ID).Cols("question", "multiple", "anonymous", "close_at").Update(poll); err != nil {
+				return nil, err
+			}
+			if options != nil {
+				if _, err := session.Where("poll_id = ?", poll.ID).Delete(&entity.TopicPollOption{}); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, option := range options {
+			if _, err := session.Insert(option); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		if _, ok := err.(*errors.Error); ok {
+			return err
+		}
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// VoteTopicPoll replaces the votes of the user on the poll with the given options. Option counters and the
+// voter count are updated in the same transaction, after checking that the poll is still open.
+func (r *ForumRepo) VoteTopicPoll(ctx context.Context, pollID, userID string, optionIDs []string, now time.Time) error {
+	voteIDs := make([]string, 0, len(optionIDs))
+	for range optionIDs {
+		id, err := r.genID(ctx, entity.TopicPollVote{}.TableName())
+		if err != nil {
+			return err
+		}
+		voteIDs = append(voteIDs, id)
+	}
+
+	_, err := r.data.DB.Transaction(func(session *xorm.Session) (any, error) {
+		session = session.Context(ctx)
+		poll := &entity.TopicPoll{}
+		exist, err := session.ID(pollID).Get(poll)
+		if err != nil {
+			return nil, err
+		}
+		if !exist {
+			return nil, errors.NotFound(reason.ObjectNotFound)
+		}
+		if !poll.IsOpen(now) {
+			return nil, errors.Forbidden(reason.StatusInvalid)
+		}
+		if !poll.Multiple && len(optionIDs) > 1 {
+			return nil, errors.BadRequest(reason.RequestFormatError).WithMsg("this poll accepts a single choice")
+		}
+		count, err := session.Where("poll_id = ?", pollID).In("id", optionIDs).Count(&entity.TopicPollOption{})
+		if err != nil {
+			return nil, err
+		}
+		if int(count) != len(optionIDs) {
+			return nil, errors.BadRequest(reason.ObjectNotFound)
+		}
+
+		previous := make([]*entity.TopicPollVote, 0)
+		if err := session.Where("poll_id = ? AND user_id = ?", pollID, userID).Find(&previous); err != nil {
+			return nil, err
+		}
+		for _, vote := range previous {
+			if _, err := session.ID(vote.OptionID).Decr("vote_count", 1).Update(&entity.TopicPollOption{}); err != nil {
+				return nil, err
+			}
+		}
+		if len(previous) > 0 {
+			if _, err := session.Where("poll_id = ? AND user_id = ?", pollID, userID).Delete(&entity.TopicPollVote{}); err != nil {
+				return nil, err
+			}
+		}
+
+		for i, optionID := range optionIDs {
+			vote := &entity.TopicPollVote{
+				ID:       voteIDs[i],
+				PollID:   pollID,
+				UserID:   userID,
+				OptionID: optionID,
+			}
+			if _, err := session.Insert(vote); err != nil {
+				return nil, err
+			}
+			if _, err := session.ID(optionID).Incr("vote_count", 1).Update(&entity.TopicPollOption{}); err != nil {
+				return nil, err
+			}
+		}
+		if len(previous) == 0 {
+			if _, err := session.ID(pollID).Incr("voter_count", 1).Update(&entity.TopicPoll{}); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		if _, ok := err.(*errors.Error); ok {
+			return err
+		}
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// CloseDueTopicPolls closes every open poll whose close time has passed.
+func (r *ForumRepo) CloseDueTopicPolls(ctx context.Context, now time.Time) (int64, error) {
+	affected, err := r.data.DB.Context(ctx).
+		Where("status = ?", entity.TopicPollStatusOpen).
+		And("close_at IS NOT NULL AND close_at <= ?", now).
+		Cols("status", "closed_at").
+		Update(&entity.TopicPoll{Status: entity.TopicPollStatusClosed, ClosedAt: &now})
+	if err != nil {
+		return 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return affected, nil
+}
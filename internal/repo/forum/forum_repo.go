@@ -29,6 +29,7 @@ import (
 	"github.com/apache/answer/internal/service/unique"
 	"github.com/apache/answer/pkg/uid"
 	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
 	"xorm.io/xorm"
 )
 
@@ -93,6 +94,16 @@ func (r *ForumRepo) GetCategory(ctx context.Context, categoryID string) (*entity
 	return category, exist, nil
 }
 
+// GetCategoryBySlug returns an available category by its slug
+func (r *ForumRepo) GetCategoryBySlug(ctx context.Context, slug string) (*entity.Category, bool, error) {
+	category := &entity.Category{}
+	exist, err := r.data.DB.Context(ctx).Where("slug = ?", slug).And("status = ?", 1).Get(category)
+	if err != nil {
+		return nil, false, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return category, exist, nil
+}
+
 func (r *ForumRepo) ListCategories(ctx context.Context, page, pageSize int) ([]*entity.Category, int64, error) {
 	if page < 1 {
 		page = 1
@@ -119,13 +130,29 @@ func (r *ForumRepo) ListCategories(ctx context.Context, page, pageSize int) ([]*
 	return categories, total, nil
 }
 
-func (r *ForumRepo) AddTopic(ctx context.Context, topic *entity.Topic) error {
+// AddTopic adds the topic together with the relations to its tags, so a topic is never left without its tags
+func (r *ForumRepo) AddTopic(ctx context.Context, topic *entity.Topic, tagIDs []string) error {
 	id, err := r.genID(ctx, topic.TableName())
 	if err != nil {
 		return err
 	}
 	topic.ID = id
-	if _, err = r.data.DB.Context(ctx).Insert(topic); err != nil {
+	_, err = r.data.DB.Transaction(func(session *xorm.Session) (any, error) {
+		session = session.Context(ctx)
+		if _, err := session.Insert(topic); err != nil {
+			return nil, err
+		}
+		if len(tagIDs) == 0 {
+			return nil, nil
+		}
+		tagRels := make([]*entity.TagRel, 0, len(tagIDs))
+		for _, tagID := range tagIDs {
+			tagRels = append(tagRels, &entity.TagRel{TagID: tagID, ObjectID: topic.ID, Status: entity.TagRelStatusAvailable})
+		}
+		_, err := session.Insert(tagRels)
+		return nil, err
+	})
+	if err != nil {
 		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
 	}
 	return nil
@@ -140,6 +167,21 @@ func (r *ForumRepo) GetTopic(ctx context.Context, topicID string) (*entity.Topic
 	return topic, exist, nil
 }
 
+func (r *ForumRepo) GetTopicsByIDs(ctx context.Context, topicIDs []string) ([]*entity.Topic, error) {
+	ids := make([]string, 0, len(topicIDs))
+	for _, id := range topicIDs {
+		ids = append(ids, uid.DeShortID(id))
+	}
+	topics := make([]*entity.Topic, 0)
+	if len(ids) == 0 {
+		return topics, nil
+	}
+	if err := r.data.DB.Context(ctx).In("id", ids).Find(&topics); err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return topics, nil
+}
+
 func (r *ForumRepo) UpdateTopic(ctx context.Context, topic *entity.Topic, cols ...string) error {
 	topic.ID = uid.DeShortID(topic.ID)
 	if len(cols) == 0 {
@@ -156,7 +198,9 @@ func (r *ForumRepo) UpdateTopic(ctx context.Context, topic *entity.Topic, cols .
 	return nil
 }
 
-func (r *ForumRepo) ListTopicsByCategory(ctx context.Context, categoryID string, page, pageSize int) ([]*entity.Topic, int64, error) {
+func (r *ForumRepo) ListTopicsByCategory(ctx context.Context, categoryID string, tagIDs []string, page, pageSize int) (
+	[]*entity.Topic, int64, error,
+) {
 	if page < 1 {
 		page = 1
 	}
@@ -167,13 +211,18 @@ func (r *ForumRepo) ListTopicsByCategory(ctx context.Context, categoryID string,
 		pageSize = 100
 	}
 
+	cond := builder.Eq{"category_id": uid.DeShortID(categoryID)}.
+		And(builder.In("status", entity.TopicStatusAvailable, entity.TopicStatusClosed))
+	if len(tagIDs) > 0 {
+		cond = cond.And(builder.In("id", taggedObjectIDs(tagIDs)))
+	}
 	topics := make([]*entity.Topic, 0)
-	total, err := r.data.DB.Context(ctx).Where("category_id = ?", uid.DeShortID(categoryID)).Count(&entity.Topic{})
+	total, err := r.data.DB.Context(ctx).Where(cond).Count(&entity.Topic{})
 	if err != nil {
 		return nil, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
 	}
 	if err := r.data.DB.Context(ctx).
-		Where("category_id = ?", uid.DeShortID(categoryID)).
+		Where(cond).
 		Desc("created_at").
 		Limit(pageSize, (page-1)*pageSize).
 		Find(&topics); err != nil {
@@ -0,0 +1,93 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package forum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/handler"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
+	"xorm.io/builder"
+)
+
+// sitemapTopicStatus topics in these statuses are public and belong in the sitemap
+var sitemapTopicStatus = []string{entity.TopicStatusAvailable, entity.TopicStatusClosed}
+
+// GetSitemapTopicCount count topics that are listed in the sitemap
+func (r *ForumRepo) GetSitemapTopicCount(ctx context.Context) (int64, error) {
+	count, err := r.data.DB.Context(ctx).Where(builder.In("status", sitemapTopicStatus)).Count(&entity.Topic{})
+	if err != nil {
+		return 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return count, nil
+}
+
+// SitemapTopics get one page of sitemap topics, the page is cached like the question sitemap
+func (r *ForumRepo) SitemapTopics(ctx context.Context, page, pageSize int) ([]*schema.SiteMapTopicInfo, error) {
+	page--
+	topicList := make([]*schema.SiteMapTopicInfo, 0)
+
+	cacheKey := fmt.Sprintf(constant.SiteMapTopicCacheKeyPrefix, page)
+	cacheData, exist, err := r.data.Cache.GetString(ctx, cacheKey)
+	if err == nil && exist {
+		_ = json.Unmarshal([]byte(cacheData), &topicList)
+		return topicList, nil
+	}
+
+	rows := make([]*entity.Topic, 0)
+	err = r.data.DB.Context(ctx).
+		Select("id,created_at,updated_at,current_wiki_revision_id").
+		Where(builder.In("status", sitemapTopicStatus)).
+		Asc("created_at").
+		Limit(pageSize, page*pageSize).
+		Find(&rows)
+	if err != nil {
+		return topicList, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+
+	for _, topic := range rows {
+		item := &schema.SiteMapTopicInfo{ID: topic.ID}
+		if handler.GetEnableShortID(ctx) {
+			item.ID = uid.EnShortID(topic.ID)
+		}
+		item.HasWiki = topic.CurrentWikiRevisionID != "" && topic.CurrentWikiRevisionID != "0"
+		if topic.UpdatedAt.IsZero() {
+			item.UpdateTime = topic.CreatedAt.Format(time.RFC3339)
+		} else {
+			item.UpdateTime = topic.UpdatedAt.Format(time.RFC3339)
+		}
+		topicList = append(topicList, item)
+	}
+
+	cacheDataByte, _ := json.Marshal(topicList)
+	if err := r.data.Cache.SetString(ctx, cacheKey, string(cacheDataByte), constant.SiteMapTopicCacheTime); err != nil {
+		log.Error(err)
+	}
+	return topicList, nil
+}
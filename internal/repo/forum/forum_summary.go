@@ -0,0 +1,87 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package forum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
+)
+
+// TopicSummaryCache is a generated summary together with the fingerprint of the posts it was built from.
+type TopicSummaryCache struct {
+	Fingerprint string `json:"fingerprint"`
+	Summary     string `json:"summary"`
+}
+
+// ListSummaryPosts returns the posts of a topic in creation order. Without postIDs only active posts are
+// returned, otherwise exactly the given posts are.
+func (r *ForumRepo) ListSummaryPosts(ctx context.Context, topicID string, postIDs []string) ([]*TopicPostView, error) {
+	cond := builder.NewCond().And(builder.Eq{"p.topic_id": uid.DeShortID(topicID)})
+	if len(postIDs) > 0 {
+		cond = cond.And(builder.In("p.id", postIDs))
+	} else {
+		cond = cond.And(builder.Eq{"p.merge_state": entity.PostMergeStateActive, "p.status": 1})
+	}
+	posts := make([]*TopicPostView, 0)
+	err := r.data.DB.Context(ctx).Table("posts").Alias("p").
+		Select("p.id, p.topic_id, p.user_id, p.original_text, p.created_at, "+
+			"u.username AS author_username, u.display_name AS author_display_name").
+		Join("LEFT", []string{"user", "u"}, "u.id = p.user_id").
+		Where(cond).
+		Asc("p.created_at", "p.id").
+		Find(&posts)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return posts, nil
+}
+
+func (r *ForumRepo) GetTopicSummaryCache(ctx context.Context, topicID, mergeJobID string) (*TopicSummaryCache, bool, error) {
+	cacheKey := fmt.Sprintf(constant.ForumTopicSummaryCacheKeyPrefix, uid.DeShortID(topicID), mergeJobID)
+	cacheData, exist, err := r.data.Cache.GetString(ctx, cacheKey)
+	if err != nil {
+		return nil, false, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	if !exist {
+		return nil, false, nil
+	}
+	cache := &TopicSummaryCache{}
+	if err := json.Unmarshal([]byte(cacheData), cache); err != nil {
+		return nil, false, nil
+	}
+	return cache, true, nil
+}
+
+func (r *ForumRepo) SetTopicSummaryCache(ctx context.Context, topicID, mergeJobID string, cache *TopicSummaryCache) error {
+	cacheKey := fmt.Sprintf(constant.ForumTopicSummaryCacheKeyPrefix, uid.DeShortID(topicID), mergeJobID)
+	cacheData, _ := json.Marshal(cache)
+	if err := r.data.Cache.SetString(ctx, cacheKey, string(cacheData), constant.ForumTopicSummaryCacheTime); err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package forum
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// TagContentView a question or a topic listed on a tag page
+type TagContentView struct {
+	ObjectType string    `json:"object_type" xorm:"object_type"`
+	ID         string    `json:"id" xorm:"id"`
+	Title      string    `json:"title" xorm:"title"`
+	VoteCount  int       `json:"vote_count" xorm:"vote_count"`
+	CreatedAt  time.Time `json:"created_at" xorm:"created_at"`
+}
+
+// taggedObjectIDs sub query of the object ids that carry any of the tags
+func taggedObjectIDs(tagIDs []string) *builder.Builder {
+	return builder.Select("object_id").From(entity.TagRel{}.TableName()).
+		Where(builder.In("tag_id", tagIDs).And(builder.Eq{"status": entity.TagRelStatusAvailable}))
+}
+
+// ListTagContents list the public questions and topics that carry any of the tags, newest first
+func (r *ForumRepo) ListTagContents(ctx context.Context, tagIDs []string, page, pageSize int) (
+	[]*TagContentView, int64, error,
+) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	contents := make([]*TagContentView, 0)
+	if len(tagIDs) == 0 {
+		return contents, 0, nil
+	}
+
+	questionCond := builder.In("status", entity.QuestionStatusAvailable, entity.QuestionStatusClosed).
+		And(builder.Eq{"`show`": entity.QuestionShow}).
+		And(builder.In("id", taggedObjectIDs(tagIDs)))
+	topicCond := builder.In("status", entity.TopicStatusAvailable, entity.TopicStatusClosed).
+		And(builder.In("id", taggedObjectIDs(tagIDs)))
+
+	questionCount, err := r.data.DB.Context(ctx).Where(questionCond).Count(&entity.Question{})
+	if err != nil {
+		return nil, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	topicCount, err := r.data.DB.Context(ctx).Where(topicCond).Count(&entity.Topic{})
+	if err != nil {
+		return nil, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+
+	questionSQL, questionArgs, err := builder.ToSQL(questionCond)
+	if err != nil {
+		return nil, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	topicSQL, topicArgs, err := builder.ToSQL(topicCond)
+	if err != nil {
+		return nil, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	query := fmt.Sprintf(`
+SELECT c.object_type, c.id, c.title, c.vote_count, c.created_at FROM (
+	SELECT 'question' AS object_type, id, title, vote_count, created_at FROM question WHERE %s
+	UNION ALL
+	SELECT 'topic' AS object_type, id, title, vote_count, created_at FROM topics WHERE %s
+) AS c
+ORDER BY c.created_at DESC
+LIMIT ? OFFSET ?`, questionSQL, topicSQL)
+	args := make([]any, 0, len(questionArgs)+len(topicArgs)+2)
+	args = append(args, questionArgs...)
+	args = append(args, topicArgs...)
+	args = append(args, pageSize, (page-1)*pageSize)
+	if err := r.data.DB.Context(ctx).SQL(query, args...).Find(&contents); err != nil {
+		return nil, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return contents, questionCount + topicCount, nil
+}
+
+// ListTopicsByTags lists the open and closed topics carrying any of the tags, newest first
+func (r *ForumRepo) ListTopicsByTags(ctx context.Context, tagIDs []string, page, pageSize int) (
+	[]*entity.Topic, int64, error,
+) {
+	topics := make([]*entity.Topic, 0)
+	if len(tagIDs) == 0 {
+		return topics, 0, nil
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	cond := builder.In("status", entity.TopicStatusAvailable, entity.TopicStatusClosed).
+		And(builder.In("id", taggedObjectIDs(tagIDs)))
+	total, err := r.data.DB.Context(ctx).Where(cond).
+		Desc("created_at").
+		Limit(pageSize, (page-1)*pageSize).
+		FindAndCount(&topics)
+	if err != nil {
+		return nil, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return topics, total, nil
+}
+
+// moveTagRels moves the tag relations of a converted object onto its new object inside a conversion transaction
+func moveTagRels(session *xorm.Session, sourceObjectID, targetObjectID string) error {
+	_, err := session.Table(entity.TagRel{}.TableName()).
+		Where("object_id = ?", sourceObjectID).
+		Update(map[string]any{"object_id": targetObjectID})
+	return err
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/apache/answer/internal/repo/activity"
 	"github.com/apache/answer/internal/repo/activity_common"
 	"github.com/apache/answer/internal/repo/ai_conversation"
+	"github.com/apache/answer/internal/repo/ai_embedding"
 	"github.com/apache/answer/internal/repo/answer"
 	"github.com/apache/answer/internal/repo/api_key"
 	"github.com/apache/answer/internal/repo/auth"
@@ -48,7 +49,10 @@ import (
 	"github.com/apache/answer/internal/repo/review"
 	"github.com/apache/answer/internal/repo/revision"
 	"github.com/apache/answer/internal/repo/role"
+	"github.com/apache/answer/internal/repo/saved_search"
+	"github.com/apache/answer/internal/repo/search_analytics"
 	"github.com/apache/answer/internal/repo/search_common"
+	"github.com/apache/answer/internal/repo/search_sync"
 	"github.com/apache/answer/internal/repo/site_info"
 	"github.com/apache/answer/internal/repo/tag"
 	"github.com/apache/answer/internal/repo/tag_common"
@@ -115,4 +119,8 @@ var ProviderSetRepo = wire.NewSet(
 	forum.NewForumRepo,
 	api_key.NewAPIKeyRepo,
 	ai_conversation.NewAIConversationRepo,
+	ai_embedding.NewAIEmbeddingRepo,
+	search_analytics.NewSearchAnalyticsRepo,
+	saved_search.NewSavedSearchRepo,
+	search_sync.NewSearchReindexRepo,
 )
@@ -583,11 +583,7 @@ func (qr *questionRepo) AdminQuestionPage(ctx context.Context, search *schema.Ad
 // UpdateSearch update search, if search plugin not enable, do nothing
 func (qr *questionRepo) UpdateSearch(ctx context.Context, questionID string) (err error) {
 	// check search plugin
-	var s plugin.Search
-	_ = plugin.CallSearch(func(search plugin.Search) error {
-		s = search
-		return nil
-	})
+	s := plugin.GetSearch()
 	if s == nil {
 		return
 	}
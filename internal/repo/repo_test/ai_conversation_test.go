@@ -0,0 +1,456 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package repo_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/controller"
+	"github.com/apache/answer/internal/entity"
+	aiconversationrepo "github.com/apache/answer/internal/repo/ai_conversation"
+	"github.com/apache/answer/internal/repo/site_info"
+	"github.com/apache/answer/internal/repo/user"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/ai_conversation"
+	"github.com/apache/answer/internal/service/ai_prompt"
+	"github.com/apache/answer/internal/service/ai_provider"
+	forumservice "github.com/apache/answer/internal/service/forum"
+	"github.com/apache/answer/internal/service/siteinfo_common"
+	usercommon "github.com/apache/answer/internal/service/user_common"
+	"github.com/apache/answer/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// saveStubAIConfig enables AI chat with the stub provider and restores the original config when the test ends
+func saveStubAIConfig(t *testing.T, stubURL string, quota *schema.AIQuotaConfig) siteinfo_common.SiteInfoRepo {
+	return saveAIConfigForTest(t, &schema.SiteAIReq{
+		Enabled:        true,
+		ChosenProvider: "stub",
+		SiteAIProviders: []*schema.SiteAIProvider{
+			{Provider: "stub", APIHost: stubURL, APIKey: "test", Model: "stub-model"},
+		},
+		PromptConfig: &schema.AIPromptConfig{},
+		Quota:        quota,
+	})
+}
+
+// saveAIConfigForTest saves the AI config and restores the original config when the test ends
+func saveAIConfigForTest(t *testing.T, req *schema.SiteAIReq) siteinfo_common.SiteInfoRepo {
+	ctx := context.TODO()
+	siteInfoRepo := site_info.NewSiteInfo(testDataSource)
+	original, hadAIConfig, err := siteInfoRepo.GetByType(ctx, constant.SiteTypeAI, true)
+	require.NoError(t, err)
+	aiConfig, _ := json.Marshal(req)
+	require.NoError(t, siteInfoRepo.SaveByType(ctx, constant.SiteTypeAI,
+		&entity.SiteInfo{Type: constant.SiteTypeAI, Content: string(aiConfig), Status: 1}))
+	t.Cleanup(func() {
+		if hadAIConfig {
+			_ = siteInfoRepo.SaveByType(ctx, constant.SiteTypeAI, original)
+			return
+		}
+		_, _ = testDataSource.DB.Context(ctx).Where("type = ?", constant.SiteTypeAI).Delete(&entity.SiteInfo{})
+		_ = testDataSource.Cache.Del(ctx, constant.SiteInfoCacheKey+constant.SiteTypeAI)
+	})
+	return siteInfoRepo
+}
+
+func Test_aiChat_SourcesFromToolCalls(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.TODO()
+
+	repo := newForumRepoForTest()
+	forum := forumservice.NewForumService(repo, nil, nil, nil, nil)
+	_, topic := createTopicFixture(t, repo)
+	revision, err := forum.CreateWikiRevision(ctx, topic.ID, &schema.CreateWikiRevisionReq{
+		Title:    "Release checklist",
+		Document: "1. Deploy to staging.",
+		EditorID: "1",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(revision.ID).Delete(&entity.WikiRevision{})
+	})
+
+	// the first completion asks for the wiki document, the second one answers with it
+	var stubCalls atomic.Int32
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		var chunks []string
+		if stubCalls.Add(1) == 1 {
+			args, _ := json.Marshal(fmt.Sprintf(`{"topic_id":%q}`, topic.ID))
+			chunks = []string{
+				`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_topic_wiki","arguments":` + string(args) + `}}]}}]}`,
+				`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+			}
+		} else {
+			chunks = []string{
+				`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[{"index":0,"delta":{"content":"Deploy to staging first [1]."}}]}`,
+				`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+			}
+		}
+		for _, chunk := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(stub.Close)
+
+	siteInfoRepo := saveStubAIConfig(t, stub.URL, nil)
+	siteInfoService := siteinfo_common.NewSiteInfoCommonService(siteInfoRepo)
+	conversationRepo := aiconversationrepo.NewAIConversationRepo(testDataSource)
+	userRepo := user.NewUserRepo(testDataSource)
+	userCommon := usercommon.NewUserCommon(userRepo, nil, nil, siteInfoService)
+	providerService := ai_provider.NewAIProviderService(siteInfoService)
+	conversationService := ai_conversation.NewAIConversationService(conversationRepo, userCommon, userRepo, providerService)
+	mcpController := controller.NewMCPController(nil, siteInfoService,
+		nil, nil, nil, nil, nil, nil, forum, nil, nil, nil, nil, nil, nil)
+	ai := controller.NewAIController(nil, siteInfoService, nil, nil, nil, nil, nil,
+		mcpController, conversationService, nil, forum, nil, providerService,
+		ai_prompt.NewAIPromptService(siteInfoService, userCommon))
+
+	conversationID := token.GenerateToken()
+	t.Cleanup(func() {
+		_ = conversationRepo.DeleteConversation(ctx, conversationID)
+	})
+
+	r := gin.New()
+	r.POST("/answer/api/v1/chat/completions", authed("1", 1, ai.ChatCompletions))
+	body, _ := json.Marshal(&controller.ChatCompletionsRequest{
+		ConversationID: conversationID,
+		Messages:       []controller.Message{{Role: "user", Content: "How do we release?"}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/answer/api/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.EqualValues(t, 2, stubCalls.Load())
+
+	var final *controller.StreamResponse
+	for _, line := range bytes.Split(w.Body.Bytes(), []byte("\n")) {
+		data, ok := bytes.CutPrefix(line, []byte("data: "))
+		if !ok || string(data) == "[DONE]" {
+			continue
+		}
+		chunk := &controller.StreamResponse{}
+		require.NoError(t, json.Unmarshal(data, chunk))
+		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != nil {
+			final = chunk
+		}
+	}
+	require.NotNil(t, final, w.Body.String())
+	require.Len(t, final.Sources, 1)
+	assert.Equal(t, schema.AIConversationSourceWiki, final.Sources[0].ObjectType)
+	assert.Equal(t, topic.ID, final.Sources[0].ObjectID)
+	assert.Equal(t, "Release checklist", final.Sources[0].Title)
+	assert.Contains(t, final.Sources[0].URL, "/topics/"+topic.ID+"/wiki")
+
+	detail, exist, err := conversationService.GetConversationDetail(ctx, &schema.AIConversationDetailReq{
+		ConversationID: conversationID,
+		UserID:         "1",
+	})
+	require.NoError(t, err)
+	require.True(t, exist)
+	var assistant *schema.AIConversationRecord
+	for _, record := range detail.Records {
+		if record.Role == "assistant" {
+			assistant = record
+		} else {
+			assert.Empty(t, record.Sources)
+		}
+	}
+	require.NotNil(t, assistant)
+	assert.Contains(t, assistant.Content, "Deploy to staging first [1].")
+	assert.Equal(t, final.Sources, assistant.Sources)
+}
+
+func Test_aiChat_UsageAndQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.TODO()
+
+	// the usage comes in a last chunk without choices, after the finish reason
+	var stubCalls atomic.Int32
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stubCalls.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[{"index":0,"delta":{"content":"Hello."}}]}`,
+			`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+			`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[],"usage":{"prompt_tokens":40,"completion_tokens":2,"total_tokens":42}}`,
+		} {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(stub.Close)
+
+	siteInfoRepo := saveStubAIConfig(t, stub.URL, nil)
+	siteInfoService := siteinfo_common.NewSiteInfoCommonService(siteInfoRepo)
+	conversationRepo := aiconversationrepo.NewAIConversationRepo(testDataSource)
+	userRepo := user.NewUserRepo(testDataSource)
+	userCommon := usercommon.NewUserCommon(userRepo, nil, nil, siteInfoService)
+	providerService := ai_provider.NewAIProviderService(siteInfoService)
+	conversationService := ai_conversation.NewAIConversationService(conversationRepo, userCommon, userRepo, providerService)
+	ai := controller.NewAIController(nil, siteInfoService, nil, nil, nil, nil, nil,
+		nil, conversationService, nil, nil, nil, providerService,
+		ai_prompt.NewAIPromptService(siteInfoService, userCommon))
+	r := gin.New()
+	r.POST("/answer/api/v1/chat/completions", authed("1", 1, ai.ChatCompletions))
+	chat := func() *httptest.ResponseRecorder {
+		conversationID := token.GenerateToken()
+		t.Cleanup(func() {
+			_ = conversationRepo.DeleteConversation(ctx, conversationID)
+		})
+		body, _ := json.Marshal(&controller.ChatCompletionsRequest{
+			ConversationID: conversationID,
+			Messages:       []controller.Message{{Role: "user", Content: "Hi"}},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/answer/api/v1/chat/completions", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	before, err := conversationRepo.SumTokens(ctx, "1", dayStart)
+	require.NoError(t, err)
+	w := chat()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	used, err := conversationRepo.SumTokens(ctx, "1", dayStart)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, used-before)
+
+	// only the assistant replies count, like in the usage report
+	conversation := &entity.AIConversation{}
+	_, err = testDataSource.DB.Context(ctx).Where("user_id = ?", "1").Desc("id").Get(conversation)
+	require.NoError(t, err)
+	userRecord := &entity.AIConversationRecord{ConversationID: conversation.ConversationID, Role: "user",
+		Content: "Hi", PromptTokens: 100}
+	_, err = testDataSource.DB.Context(ctx).Insert(userRecord)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(userRecord.ID).Delete(&entity.AIConversationRecord{})
+	})
+	sum, err := conversationRepo.SumTokens(ctx, "1", dayStart)
+	require.NoError(t, err)
+	assert.Equal(t, used, sum)
+
+	assert.NoError(t, conversationService.CheckQuota(ctx, "1", &schema.AIQuotaConfig{UserDailyTokens: used + 1}))
+	assert.Error(t, conversationService.CheckQuota(ctx, "1", &schema.AIQuotaConfig{UserDailyTokens: used}))
+	assert.NoError(t, conversationService.CheckQuota(ctx, "no-usage-user", &schema.AIQuotaConfig{UserDailyTokens: used}))
+
+	// once the quota is reached the request is refused before calling the provider
+	saveStubAIConfig(t, stub.URL, &schema.AIQuotaConfig{UserMonthlyTokens: used})
+	w = chat()
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.EqualValues(t, 1, stubCalls.Load())
+
+	today := now.Format("2006-01-02")
+	report, err := conversationService.GetUsageReport(ctx, &schema.AIUsageReportReq{StartDate: today, EndDate: today})
+	require.NoError(t, err)
+	require.Len(t, report.Days, 1)
+	assert.Equal(t, today, report.Days[0].Date)
+	assert.GreaterOrEqual(t, report.Total.TotalTokens, int64(42))
+	assert.Equal(t, report.Total, report.Days[0].AIUsageStat)
+	var model *schema.AIUsageModelStat
+	for _, stat := range report.Models {
+		if stat.Model == "stub-model" {
+			model = stat
+		}
+	}
+	require.NotNil(t, model)
+	assert.GreaterOrEqual(t, model.PromptTokens, int64(40))
+	require.NotEmpty(t, report.Users)
+	assert.NotEmpty(t, report.Users[0].UserInfo.Username)
+
+	_, err = conversationService.GetUsageReport(ctx, &schema.AIUsageReportReq{StartDate: today, EndDate: "2000-01-01"})
+	assert.Error(t, err)
+}
+
+func Test_aiConversation_BuildHistorySummarizesOldTurns(t *testing.T) {
+	ctx := context.TODO()
+
+	var summaryRequests []string
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(req)
+		summaryRequests = append(summaryRequests, req.Messages[0].Content)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"id":"stub","object":"chat.completion","model":"stub-model","choices":[{"index":0,"message":{"role":"assistant","content":"The user asked about turns 1 to 3."},"finish_reason":"stop"}],"usage":{"prompt_tokens":300,"completion_tokens":10,"total_tokens":310}}`)
+	}))
+	t.Cleanup(stub.Close)
+
+	siteInfoService := siteinfo_common.NewSiteInfoCommonService(saveStubAIConfig(t, stub.URL, nil))
+	conversationRepo := aiconversationrepo.NewAIConversationRepo(testDataSource)
+	conversationService := ai_conversation.NewAIConversationService(conversationRepo, nil, nil,
+		ai_provider.NewAIProviderService(siteInfoService))
+
+	conversationID := token.GenerateToken()
+	require.NoError(t, conversationService.CreateConversation(ctx, "1", conversationID, "topic"))
+	t.Cleanup(func() {
+		_ = conversationRepo.DeleteConversation(ctx, conversationID)
+	})
+	// four turns of about 100 tokens per message
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, conversationService.SaveConversationRecords(ctx, conversationID, token.GenerateToken(),
+			[]*ai_conversation.ConversationMessage{
+				{Role: "user", Content: fmt.Sprintf("question %d %s", i, strings.Repeat("q", 390))},
+				{Role: "assistant", Content: fmt.Sprintf("answer %d %s", i, strings.Repeat("a", 390))},
+			}, nil, nil))
+	}
+	detail, exist, err := conversationService.GetConversationDetail(ctx, &schema.AIConversationDetailReq{
+		ConversationID: conversationID,
+		UserID:         "1",
+	})
+	require.NoError(t, err)
+	require.True(t, exist)
+	records := make([]*ai_conversation.ConversationMessage, 0)
+	for _, record := range detail.Records {
+		records = append(records, &ai_conversation.ConversationMessage{
+			ChatCompletionID: record.ChatCompletionID,
+			Role:             record.Role,
+			Content:          record.Content,
+		})
+	}
+	require.Len(t, records, 8)
+
+	history, err := conversationService.BuildHistory(ctx, conversationID, records, 2000, 1)
+	require.NoError(t, err)
+	assert.Len(t, history.Messages, 8)
+	assert.Empty(t, history.Summary)
+	assert.Nil(t, history.Usage)
+	assert.Empty(t, summaryRequests)
+
+	// only the last turn fits in half of the budget, the first three are summarised
+	history, err = conversationService.BuildHistory(ctx, conversationID, records, 500, 1)
+	require.NoError(t, err)
+	require.Len(t, summaryRequests, 1)
+	assert.Contains(t, summaryRequests[0], "question 3")
+	assert.NotContains(t, summaryRequests[0], "question 4")
+	assert.Equal(t, "The user asked about turns 1 to 3.", history.Summary)
+	require.Len(t, history.Messages, 2)
+	assert.Contains(t, history.Messages[0].Content, "question 4")
+	require.NotNil(t, history.Usage)
+	assert.Equal(t, 300, history.Usage.PromptTokens)
+
+	// the summary is saved, so the next turn sends it with the records that followed
+	history, err = conversationService.BuildHistory(ctx, conversationID, records, 500, 1)
+	require.NoError(t, err)
+	assert.Len(t, summaryRequests, 1)
+	assert.Equal(t, "The user asked about turns 1 to 3.", history.Summary)
+	assert.Len(t, history.Messages, 2)
+	assert.Nil(t, history.Usage)
+}
+
+func Test_aiConversation_PublishDraftAndBot(t *testing.T) {
+	ctx := context.TODO()
+	conversationRepo := aiconversationrepo.NewAIConversationRepo(testDataSource)
+	userRepo := user.NewUserRepo(testDataSource)
+	conversationService := ai_conversation.NewAIConversationService(conversationRepo, nil, userRepo, nil)
+
+	conversationID := token.GenerateToken()
+	topic := "\n  How do I   rotate the signing keys?\nWe run three replicas."
+	require.NoError(t, conversationService.CreateConversation(ctx, "1", conversationID, topic))
+	t.Cleanup(func() {
+		_ = conversationRepo.DeleteConversation(ctx, conversationID)
+	})
+	req := &schema.AIConversationPublishReq{ConversationID: conversationID, UserID: "1"}
+
+	// a conversation without a reply has nothing to publish
+	_, err := conversationService.GetPublishDraft(ctx, req)
+	assert.Error(t, err)
+
+	require.NoError(t, conversationService.SaveConversationRecords(ctx, conversationID, token.GenerateToken(),
+		[]*ai_conversation.ConversationMessage{
+			{Role: "user", Content: topic},
+			{Role: "assistant", Content: "Rotate one replica at a time."},
+		}, nil, nil))
+	require.NoError(t, conversationService.SaveConversationRecords(ctx, conversationID, token.GenerateToken(),
+		[]*ai_conversation.ConversationMessage{
+			{Role: "user", Content: "And the old keys?"},
+			{Role: "assistant", Content: "Keep the old keys until every token signed by them expires."},
+		}, nil, nil))
+
+	_, err = conversationService.GetPublishDraft(ctx, &schema.AIConversationPublishReq{ConversationID: conversationID, UserID: "2"})
+	assert.Error(t, err)
+
+	draft, err := conversationService.GetPublishDraft(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "How do I rotate the signing keys?", draft.Title)
+	assert.Equal(t, strings.TrimSpace(topic), draft.Question)
+	assert.Equal(t, "Keep the old keys until every token signed by them expires.", draft.Answer)
+
+	draft, err = conversationService.GetPublishDraft(ctx, &schema.AIConversationPublishReq{
+		ConversationID: conversationID, UserID: "1", Title: "Rotating signing keys", Content: "Edited question",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Rotating signing keys", draft.Title)
+	assert.Equal(t, "Edited question", draft.Question)
+
+	// the bot user is created once and then reused
+	_, existed, err := userRepo.GetByUsername(ctx, ai_conversation.BotUsername)
+	require.NoError(t, err)
+	botUserID, err := conversationService.GetBotUserID(ctx)
+	require.NoError(t, err)
+	if !existed {
+		t.Cleanup(func() {
+			_, _ = testDataSource.DB.Context(ctx).ID(botUserID).Delete(&entity.User{})
+		})
+	}
+	again, err := conversationService.GetBotUserID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, botUserID, again)
+
+	// only one request can claim the conversation, a released claim can be taken again
+	require.NoError(t, conversationService.ClaimPublish(ctx, conversationID))
+	assert.Error(t, conversationService.ClaimPublish(ctx, conversationID))
+	_, err = conversationService.GetPublishDraft(ctx, req)
+	assert.Error(t, err)
+	conversationService.ReleasePublish(ctx, conversationID)
+	_, err = conversationService.GetPublishDraft(ctx, req)
+	require.NoError(t, err)
+	require.NoError(t, conversationService.ClaimPublish(ctx, conversationID))
+
+	require.NoError(t, conversationService.SetPublishedQuestion(ctx, conversationID, "10010000000000001"))
+	_, err = conversationService.GetPublishDraft(ctx, req)
+	assert.Error(t, err)
+	assert.Error(t, conversationService.ClaimPublish(ctx, conversationID))
+	conversation, _, err := conversationRepo.GetConversation(ctx, conversationID)
+	require.NoError(t, err)
+	assert.Equal(t, "10010000000000001", conversation.QuestionID)
+}
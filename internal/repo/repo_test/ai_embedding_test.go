@@ -0,0 +1,320 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package repo_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/repo/ai_embedding"
+	"github.com/apache/answer/internal/repo/answer"
+	"github.com/apache/answer/internal/repo/question"
+	"github.com/apache/answer/internal/repo/site_info"
+	"github.com/apache/answer/internal/repo/unique"
+	"github.com/apache/answer/internal/schema"
+	aiembedding "github.com/apache/answer/internal/service/ai_embedding"
+	"github.com/apache/answer/internal/service/ai_provider"
+	"github.com/apache/answer/internal/service/eventqueue"
+	forumservice "github.com/apache/answer/internal/service/forum"
+	"github.com/apache/answer/internal/service/siteinfo_common"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubEmbeddingVocabulary are the dimensions of the vectors returned by the stub embedding server
+var stubEmbeddingVocabulary = []string{"deploy", "friday", "postgres", "vacuum"}
+
+func newStubEmbeddingServer(t *testing.T, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		calls.Add(1)
+		req := &struct {
+			Input []string `json:"input"`
+			Model string   `json:"model"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(req)
+
+		resp := openai.EmbeddingResponse{Object: "list", Model: openai.EmbeddingModel(req.Model)}
+		for i, input := range req.Input {
+			input = strings.ToLower(input)
+			vector := []float32{0.1}
+			for _, word := range stubEmbeddingVocabulary {
+				vector = append(vector, float32(strings.Count(input, word)))
+			}
+			resp.Data = append(resp.Data, openai.Embedding{Object: "embedding", Embedding: vector, Index: i})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func Test_aiEmbedding_IndexAndSearch(t *testing.T) {
+	ctx := context.TODO()
+	var stubCalls atomic.Int32
+	stub := newStubEmbeddingServer(t, &stubCalls)
+
+	siteInfoRepo := site_info.NewSiteInfo(testDataSource)
+	original, hadAIConfig, err := siteInfoRepo.GetByType(ctx, constant.SiteTypeAI, true)
+	require.NoError(t, err)
+	aiConfig, _ := json.Marshal(&schema.SiteAIReq{
+		Enabled:        true,
+		ChosenProvider: "stub",
+		SiteAIProviders: []*schema.SiteAIProvider{
+			{Provider: "stub", APIHost: stub.URL, APIKey: "test", Model: "stub-model", EmbeddingModel: "stub-embedding"},
+		},
+	})
+	require.NoError(t, siteInfoRepo.SaveByType(ctx, constant.SiteTypeAI,
+		&entity.SiteInfo{Type: constant.SiteTypeAI, Content: string(aiConfig), Status: 1}))
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).Where("model = ?", "stub-embedding").Delete(&entity.AIEmbedding{})
+		if hadAIConfig {
+			_ = siteInfoRepo.SaveByType(ctx, constant.SiteTypeAI, original)
+			return
+		}
+		_, _ = testDataSource.DB.Context(ctx).Where("type = ?", constant.SiteTypeAI).Delete(&entity.SiteInfo{})
+		_ = testDataSource.Cache.Del(ctx, constant.SiteInfoCacheKey+constant.SiteTypeAI)
+	})
+
+	uniqueIDRepo := unique.NewUniqueIDRepo(testDataSource)
+	forumRepo := newForumRepoForTest()
+	embeddingRepo := ai_embedding.NewAIEmbeddingRepo(testDataSource)
+	eventQueue := eventqueue.NewService()
+	t.Cleanup(eventQueue.Close)
+	siteInfoService := siteinfo_common.NewSiteInfoCommonService(siteInfoRepo)
+	service := aiembedding.NewAIEmbeddingService(embeddingRepo,
+		question.NewQuestionRepo(testDataSource, uniqueIDRepo),
+		answer.NewAnswerRepo(testDataSource, uniqueIDRepo, nil, nil),
+		forumRepo, siteInfoService, ai_provider.NewAIProviderService(siteInfoService), eventQueue)
+
+	questionID, err := uniqueIDRepo.GenUniqueIDStr(ctx, entity.Question{}.TableName())
+	require.NoError(t, err)
+	answerID, err := uniqueIDRepo.GenUniqueIDStr(ctx, entity.Answer{}.TableName())
+	require.NoError(t, err)
+	_, err = testDataSource.DB.Context(ctx).Insert(&entity.Question{
+		ID:               questionID,
+		UserID:           "1",
+		Title:            "Postgres table keeps growing",
+		OriginalText:     "Our postgres table grows although rows are deleted.",
+		ParsedText:       "<p>Our postgres table grows although rows are deleted.</p>",
+		Status:           entity.QuestionStatusAvailable,
+		Show:             entity.QuestionShow,
+		Pin:              entity.QuestionUnPin,
+		AcceptedAnswerID: answerID,
+		PostUpdateTime:   time.Now(),
+	})
+	require.NoError(t, err)
+	_, err = testDataSource.DB.Context(ctx).Insert(&entity.Answer{
+		ID:           answerID,
+		QuestionID:   questionID,
+		UserID:       "1",
+		OriginalText: "Run vacuum full, autovacuum could not keep up.",
+		ParsedText:   "<p>Run vacuum full, autovacuum could not keep up.</p>",
+		Status:       entity.AnswerStatusAvailable,
+		Accepted:     schema.AnswerAcceptedEnable,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(questionID).Delete(&entity.Question{})
+		_, _ = testDataSource.DB.Context(ctx).ID(answerID).Delete(&entity.Answer{})
+	})
+
+	// the wiki document is indexed from the event sent by the forum service
+	_, topic := createTopicFixture(t, forumRepo)
+	forum := forumservice.NewForumService(forumRepo, nil, nil, eventQueue, nil)
+	_, err = forum.CreateWikiRevision(ctx, topic.ID, &schema.CreateWikiRevisionReq{
+		Title:    "Release checklist",
+		Document: "We deploy on Friday after the deploy freeze is lifted.",
+		EditorID: "1",
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, exist, err := embeddingRepo.GetEmbedding(ctx, entity.AIEmbeddingObjectTypeWiki, topic.ID)
+		return err == nil && exist
+	}, 2*time.Second, 20*time.Millisecond)
+
+	require.NoError(t, service.IndexQuestion(ctx, questionID))
+	stored, exist, err := embeddingRepo.GetEmbedding(ctx, entity.AIEmbeddingObjectTypeAnswer, answerID)
+	require.NoError(t, err)
+	require.True(t, exist)
+	assert.Equal(t, questionID, stored.QuestionID)
+	assert.Equal(t, len(stubEmbeddingVocabulary)+1, stored.Dimensions)
+
+	// unchanged content is not sent to the embedding endpoint again
+	calls := stubCalls.Load()
+	require.NoError(t, service.IndexQuestion(ctx, questionID))
+	assert.Equal(t, calls, stubCalls.Load())
+
+	passages, err := service.Search(ctx, "when do we deploy on friday?", 2)
+	require.NoError(t, err)
+	require.NotEmpty(t, passages)
+	assert.Equal(t, entity.AIEmbeddingObjectTypeWiki, passages[0].ObjectType)
+	assert.Equal(t, "Release checklist", passages[0].Title)
+	assert.True(t, strings.HasSuffix(passages[0].URL, "/topics/"+topic.ID+"/wiki"), passages[0].URL)
+
+	passages, err = service.Search(ctx, "should I vacuum?", 1)
+	require.NoError(t, err)
+	require.Len(t, passages, 1)
+	assert.Equal(t, entity.AIEmbeddingObjectTypeAnswer, passages[0].ObjectType)
+	assert.Equal(t, answerID, passages[0].ObjectID)
+	assert.Contains(t, passages[0].URL, "/questions/"+questionID+"/")
+
+	// hidden questions and their answers leave the index
+	_, err = testDataSource.DB.Context(ctx).ID(questionID).Cols("status").
+		Update(&entity.Question{Status: entity.QuestionStatusDeleted})
+	require.NoError(t, err)
+	require.NoError(t, service.HandleEvent(ctx, schema.NewEvent(constant.EventQuestionDelete, "1").TID(questionID)))
+	_, exist, err = embeddingRepo.GetEmbedding(ctx, entity.AIEmbeddingObjectTypeQuestion, questionID)
+	require.NoError(t, err)
+	assert.False(t, exist)
+	_, exist, err = embeddingRepo.GetEmbedding(ctx, entity.AIEmbeddingObjectTypeAnswer, answerID)
+	require.NoError(t, err)
+	assert.False(t, exist)
+
+	// the backfill picks up content indexed before the embedding model was configured
+	_, err = testDataSource.DB.Context(ctx).ID(questionID).Cols("status").
+		Update(&entity.Question{Status: entity.QuestionStatusAvailable})
+	require.NoError(t, err)
+	service.BackfillCron(ctx)
+	_, exist, err = embeddingRepo.GetEmbedding(ctx, entity.AIEmbeddingObjectTypeQuestion, questionID)
+	require.NoError(t, err)
+	assert.True(t, exist)
+	_, exist, err = embeddingRepo.GetEmbedding(ctx, entity.AIEmbeddingObjectTypeAnswer, answerID)
+	require.NoError(t, err)
+	assert.True(t, exist)
+	_, exist, err = embeddingRepo.GetEmbedding(ctx, entity.AIEmbeddingObjectTypeTopic, topic.ID)
+	require.NoError(t, err)
+	assert.True(t, exist)
+
+	// the accepted answer stands for its question in the similar content
+	resp, err := service.SimilarContent(ctx, &schema.SimilarContentReq{Title: "vacuum vacuum", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, schema.SimilarContentMethodEmbedding, resp.Method)
+	require.Len(t, resp.List, 1)
+	assert.Equal(t, entity.AIEmbeddingObjectTypeQuestion, resp.List[0].ObjectType)
+	assert.Equal(t, questionID, resp.List[0].ObjectID)
+	assert.Greater(t, resp.List[0].Confidence, 0.5)
+}
+
+func Test_aiEmbedding_SimilarContentByKeyword(t *testing.T) {
+	ctx := context.TODO()
+	siteInfoRepo := saveAIConfigForTest(t, &schema.SiteAIReq{Enabled: false})
+
+	uniqueIDRepo := unique.NewUniqueIDRepo(testDataSource)
+	forumRepo := newForumRepoForTest()
+	siteInfoService := siteinfo_common.NewSiteInfoCommonService(siteInfoRepo)
+	service := aiembedding.NewAIEmbeddingService(ai_embedding.NewAIEmbeddingRepo(testDataSource),
+		question.NewQuestionRepo(testDataSource, uniqueIDRepo), nil,
+		forumRepo, siteInfoService, ai_provider.NewAIProviderService(siteInfoService), nil)
+
+	questionID, err := uniqueIDRepo.GenUniqueIDStr(ctx, entity.Question{}.TableName())
+	require.NoError(t, err)
+	_, err = testDataSource.DB.Context(ctx).Insert(&entity.Question{
+		ID:               questionID,
+		UserID:           "1",
+		Title:            "Zorblax table does not shrink after vacuum",
+		OriginalText:     "The zorblax table keeps its size although old rows are deleted.",
+		ParsedText:       "<p>The zorblax table keeps its size although old rows are deleted.</p>",
+		Status:           entity.QuestionStatusAvailable,
+		Show:             entity.QuestionShow,
+		Pin:              entity.QuestionUnPin,
+		AcceptedAnswerID: "0",
+		PostUpdateTime:   time.Now(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(questionID).Delete(&entity.Question{})
+	})
+
+	_, topic := createTopicFixture(t, forumRepo)
+	topic.Title = "Zorblax deploy freeze"
+	require.NoError(t, forumRepo.UpdateTopic(ctx, topic, "title"))
+	post := &entity.Post{TopicID: topic.ID, UserID: "1", Original: "No zorblax releases during the holidays.",
+		Parsed: "No zorblax releases during the holidays.", MergeState: entity.PostMergeStateActive, Status: 1}
+	require.NoError(t, forumRepo.AddPost(ctx, post))
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(post.ID).Delete(&entity.Post{})
+	})
+
+	resp, err := service.SimilarContent(ctx, &schema.SimilarContentReq{Title: "How to shrink a zorblax table after vacuum?"})
+	require.NoError(t, err)
+	assert.Equal(t, schema.SimilarContentMethodBM25, resp.Method)
+	require.Len(t, resp.List, 2)
+	assert.Equal(t, entity.AIEmbeddingObjectTypeQuestion, resp.List[0].ObjectType)
+	assert.Contains(t, resp.List[0].URL, "/questions/")
+	assert.Contains(t, resp.List[0].Excerpt, "zorblax")
+	assert.Greater(t, resp.List[0].Confidence, 0.8)
+	assert.Equal(t, entity.AIEmbeddingObjectTypeTopic, resp.List[1].ObjectType)
+	assert.Less(t, resp.List[1].Confidence, resp.List[0].Confidence)
+
+	// the opening post counts as the body of the topic
+	resp, err = service.SimilarContent(ctx, &schema.SimilarContentReq{Title: "zorblax holidays releases", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, resp.List, 1)
+	assert.Equal(t, entity.AIEmbeddingObjectTypeTopic, resp.List[0].ObjectType)
+	assert.Equal(t, topic.ID, resp.List[0].ObjectID)
+	assert.True(t, strings.HasSuffix(resp.List[0].URL, "/topics/"+topic.ID), resp.List[0].URL)
+
+	// the candidates matching the most terms are kept, the merged posts are not searched
+	archived := &entity.Post{TopicID: topic.ID, UserID: "1", Original: "The glorbnix release is archived.",
+		Parsed: "The glorbnix release is archived.", MergeState: entity.PostMergeStateArchived, Status: 1}
+	require.NoError(t, forumRepo.AddPost(ctx, archived))
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(archived.ID).Delete(&entity.Post{})
+	})
+	embeddingRepo := ai_embedding.NewAIEmbeddingRepo(testDataSource)
+	candidates, err := embeddingRepo.GetKeywordCandidates(ctx, []string{"glorbnix"}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, candidates)
+	candidates, err = embeddingRepo.GetKeywordCandidates(ctx, []string{"zorblax", "vacuum", "shrink"}, 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, candidates)
+	assert.Equal(t, questionID, candidates[0].ObjectID)
+
+	resp, err = service.SimilarContent(ctx, &schema.SimilarContentReq{Title: "qwxyzzy plorf"})
+	require.NoError(t, err)
+	assert.Empty(t, resp.List)
+
+	// a short title is not looked up
+	resp, err = service.SimilarContent(ctx, &schema.SimilarContentReq{Title: "zorblax"})
+	require.NoError(t, err)
+	assert.Empty(t, resp.List)
+
+	// the results of a title are cached for a short time, whatever its case and spacing
+	_, err = testDataSource.DB.Context(ctx).ID(questionID).Delete(&entity.Question{})
+	require.NoError(t, err)
+	resp, err = service.SimilarContent(ctx, &schema.SimilarContentReq{Title: "how to shrink a  Zorblax table after vacuum? "})
+	require.NoError(t, err)
+	assert.Len(t, resp.List, 2)
+}
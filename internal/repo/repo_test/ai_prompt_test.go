@@ -0,0 +1,88 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/repo/user"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/ai_prompt"
+	"github.com/apache/answer/internal/service/siteinfo_common"
+	usercommon "github.com/apache/answer/internal/service/user_common"
+	"github.com/segmentfault/pacman/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_aiPrompt_BuildAndPreview(t *testing.T) {
+	ctx := context.TODO()
+	siteInfoRepo := saveAIConfigForTest(t, &schema.SiteAIReq{
+		Enabled: true,
+		PromptConfig: &schema.AIPromptConfig{
+			EnUS: "Question: %s",
+			Templates: map[string]string{
+				"de_DE": "Hallo {{.UserDisplayName}} ({{.Language}}). Frage: {{.Question}}",
+				"fr_FR": "{{.Missing}}",
+			},
+		},
+	})
+	siteInfoService := siteinfo_common.NewSiteInfoCommonService(siteInfoRepo)
+	userCommon := usercommon.NewUserCommon(user.NewUserRepo(testDataSource), nil, nil, siteInfoService)
+	service := ai_prompt.NewAIPromptService(siteInfoService, userCommon)
+	userInfo, exist, err := userCommon.GetUserBasicInfoByID(ctx, "1")
+	require.NoError(t, err)
+	require.True(t, exist)
+
+	assert.Equal(t, "Hallo "+userInfo.DisplayName+" (de_DE). Frage: Wie?",
+		service.BuildPrompt(ctx, "de_DE", "1", "Wie?"))
+	// the languages without a template use English, here saved in the %s format
+	assert.Equal(t, "Question: How?", service.BuildPrompt(ctx, "ja_JP", "1", "How?"))
+	// a template failing to render falls back to the default template
+	assert.Contains(t, service.BuildPrompt(ctx, "fr_FR", "1", "Comment ?"), "User question: Comment ?")
+
+	resp, err := service.PreviewPrompt(ctx, &schema.AIPromptPreviewReq{
+		Language: "de_DE",
+		Question: "Wie?",
+		UserID:   "1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hallo "+userInfo.DisplayName+" (de_DE). Frage: Wie?", resp.Prompt)
+
+	resp, err = service.PreviewPrompt(ctx, &schema.AIPromptPreviewReq{
+		Template: "{{.UserDisplayName}}: {{.Question}}",
+		UserID:   "1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, string(i18n.LanguageEnglish), resp.Language)
+	assert.Equal(t, userInfo.DisplayName+": How do I reset my password?", resp.Prompt)
+
+	_, err = service.PreviewPrompt(ctx, &schema.AIPromptPreviewReq{Template: "{{.Question"})
+	assert.Error(t, err)
+
+	// without a saved template the default one is used
+	saveAIConfigForTest(t, &schema.SiteAIReq{Enabled: true})
+	resp, err = service.PreviewPrompt(ctx, &schema.AIPromptPreviewReq{Language: "zh_CN", Question: "怎么办"})
+	require.NoError(t, err)
+	assert.Equal(t, constant.DefaultAIPromptConfigZhCN, resp.Template)
+	assert.Contains(t, resp.Prompt, "用户问题：怎么办")
+}
@@ -21,9 +21,13 @@ package repo_test
 
 import (
 	"context"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/apache/answer/internal/entity"
 	"github.com/apache/answer/internal/repo/captcha"
+	"github.com/apache/answer/internal/service/action"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -57,3 +61,25 @@ func Test_captchaRepo_SetCaptcha(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, capt, gotCaptcha)
 }
+
+func Test_captchaService_ActionRecordOverLimit(t *testing.T) {
+	ctx := context.TODO()
+	captchaRepo := captcha.NewCaptchaRepo(testDataSource)
+	captchaService := action.NewCaptchaService(captchaRepo)
+	unit := "over-limit-unit"
+	t.Cleanup(func() {
+		_ = captchaRepo.DelActionType(ctx, unit, entity.CaptchaActionSimilar)
+		_ = captchaRepo.DelActionType(ctx, "other-unit", entity.CaptchaActionSimilar)
+	})
+
+	for range 3 {
+		assert.False(t, captchaService.ActionRecordOverLimit(ctx, entity.CaptchaActionSimilar, unit, 3, time.Minute))
+	}
+	assert.True(t, captchaService.ActionRecordOverLimit(ctx, entity.CaptchaActionSimilar, unit, 3, time.Minute))
+	assert.False(t, captchaService.ActionRecordOverLimit(ctx, entity.CaptchaActionSimilar, "other-unit", 3, time.Minute))
+
+	// a new window starts once the window of the first action has passed
+	require.NoError(t, captchaRepo.SetActionType(ctx, unit, entity.CaptchaActionSimilar,
+		strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10), 3))
+	assert.False(t, captchaService.ActionRecordOverLimit(ctx, entity.CaptchaActionSimilar, unit, 3, time.Minute))
+}
@@ -26,20 +26,45 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/apache/answer/internal/base/constant"
 	"github.com/apache/answer/internal/base/handler"
 	"github.com/apache/answer/internal/base/middleware"
 	"github.com/apache/answer/internal/base/reason"
 	"github.com/apache/answer/internal/controller"
 	"github.com/apache/answer/internal/entity"
+	aiconversationrepo "github.com/apache/answer/internal/repo/ai_conversation"
+	"github.com/apache/answer/internal/repo/api_key"
 	authrepo "github.com/apache/answer/internal/repo/auth"
 	forumrepo "github.com/apache/answer/internal/repo/forum"
+	"github.com/apache/answer/internal/repo/limit"
+	rolerepo "github.com/apache/answer/internal/repo/role"
+	"github.com/apache/answer/internal/repo/site_info"
+	"github.com/apache/answer/internal/repo/tag"
+	"github.com/apache/answer/internal/repo/tag_common"
 	"github.com/apache/answer/internal/repo/unique"
+	"github.com/apache/answer/internal/repo/user"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/schema/mcp_tools"
+	"github.com/apache/answer/internal/service/ai_conversation"
+	"github.com/apache/answer/internal/service/ai_provider"
 	authservice "github.com/apache/answer/internal/service/auth"
+	"github.com/apache/answer/internal/service/feature_toggle"
 	forumservice "github.com/apache/answer/internal/service/forum"
+	roleservice "github.com/apache/answer/internal/service/role"
+	"github.com/apache/answer/internal/service/siteinfo_common"
+	tagcommon "github.com/apache/answer/internal/service/tag_common"
+	usercommon "github.com/apache/answer/internal/service/user_common"
+	"github.com/apache/answer/pkg/converter"
 	"github.com/gin-gonic/gin"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/sashabaranov/go-openai"
 	pmerrors "github.com/segmentfault/pacman/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -117,7 +142,7 @@ func Test_forumAPI_ReplyVoteSolvedFlow(t *testing.T) {
 	ctx := context.TODO()
 
 	repo := forumrepo.NewForumRepo(testDataSource, unique.NewUniqueIDRepo(testDataSource))
-	service := forumservice.NewForumService(repo, nil)
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
 	fc := controller.NewForumController(service)
 
 	r := gin.New()
@@ -161,7 +186,7 @@ func Test_forumAPI_Unauthorized_ForVoteAndSolved(t *testing.T) {
 	ctx := context.TODO()
 
 	repo := forumrepo.NewForumRepo(testDataSource, unique.NewUniqueIDRepo(testDataSource))
-	service := forumservice.NewForumService(repo, nil)
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
 	fc := controller.NewForumController(service)
 
 	r := gin.New()
@@ -214,7 +239,7 @@ func Test_forumAPI_Unauthorized_ForVoteAndSolved(t *testing.T) {
 func Test_forumAPI_Forbidden_CreateCategory_WhenUserNotModeratorAdmin(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	repo := forumrepo.NewForumRepo(testDataSource, unique.NewUniqueIDRepo(testDataSource))
-	service := forumservice.NewForumService(repo, nil)
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
 	fc := controller.NewForumController(service)
 
 	r := gin.New()
@@ -242,7 +267,7 @@ func Test_forumAPI_WithAuthMiddleware_RequiresTokenAndAllowsValidToken(t *testin
 	ctx := context.TODO()
 
 	repo := forumrepo.NewForumRepo(testDataSource, unique.NewUniqueIDRepo(testDataSource))
-	service := forumservice.NewForumService(repo, nil)
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
 	fc := controller.NewForumController(service)
 
 	authRepo := authrepo.NewAuthRepo(testDataSource)
@@ -291,7 +316,7 @@ func Test_forumAPI_WithAuthMiddleware_CreateCategoryRoleCheck(t *testing.T) {
 	ctx := context.TODO()
 
 	repo := forumrepo.NewForumRepo(testDataSource, unique.NewUniqueIDRepo(testDataSource))
-	service := forumservice.NewForumService(repo, nil)
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
 	fc := controller.NewForumController(service)
 
 	authRepo := authrepo.NewAuthRepo(testDataSource)
@@ -344,7 +369,7 @@ func Test_forumAPI_WithAuthMiddleware_PostVoteAndSolved(t *testing.T) {
 	ctx := context.TODO()
 
 	repo := forumrepo.NewForumRepo(testDataSource, unique.NewUniqueIDRepo(testDataSource))
-	service := forumservice.NewForumService(repo, nil)
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
 	fc := controller.NewForumController(service)
 
 	authRepo := authrepo.NewAuthRepo(testDataSource)
@@ -416,7 +441,7 @@ func Test_forumAPI_MergeWorkflow_EndToEndAndIdempotent(t *testing.T) {
 	ctx := context.TODO()
 
 	repo := forumrepo.NewForumRepo(testDataSource, unique.NewUniqueIDRepo(testDataSource))
-	service := forumservice.NewForumService(repo, nil)
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
 	fc := controller.NewForumController(service)
 
 	r := gin.New()
@@ -683,3 +708,585 @@ func toIntSafe(value any, fallback int) int {
 		return fallback
 	}
 }
+
+func Test_forumAPI_TopicTags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.TODO()
+
+	uniqueIDRepo := unique.NewUniqueIDRepo(testDataSource)
+	tagCommonRepo := tag_common.NewTagCommonRepo(testDataSource, uniqueIDRepo)
+	tagCommonService := tagcommon.NewTagCommonService(
+		tagCommonRepo,
+		tag.NewTagRelRepo(testDataSource, uniqueIDRepo),
+		tag.NewTagRepo(testDataSource, uniqueIDRepo),
+		nil,
+		siteinfo_common.NewSiteInfoCommonService(site_info.NewSiteInfo(testDataSource)),
+		nil,
+	)
+	repo := forumrepo.NewForumRepo(testDataSource, uniqueIDRepo)
+	service := forumservice.NewForumService(repo, nil, tagCommonService, nil, nil)
+	fc := controller.NewForumController(service)
+
+	r := gin.New()
+	r.POST("/api/v1/topics", authed("1", 1, fc.CreateTopic))
+	r.PUT("/api/v1/topics/:id/tags", authed("1", 1, fc.UpdateTopicTags))
+	r.GET("/api/v1/categories/:id/topics", fc.ListCategoryTopics)
+	r.GET("/api/v1/tags/:name/contents", fc.ListTagContents)
+
+	suffix := time.Now().UnixNano()
+	mainTag := &entity.Tag{SlugName: fmt.Sprintf("forum-main-%d", suffix), Status: entity.TagStatusAvailable}
+	synonymTag := &entity.Tag{SlugName: fmt.Sprintf("forum-syn-%d", suffix), Status: entity.TagStatusAvailable}
+	reservedTag := &entity.Tag{SlugName: fmt.Sprintf("forum-reserved-%d", suffix), Status: entity.TagStatusAvailable, Reserved: true}
+	for _, item := range []*entity.Tag{mainTag, synonymTag, reservedTag} {
+		item.DisplayName = item.SlugName
+	}
+	require.NoError(t, tagCommonRepo.AddTagList(ctx, []*entity.Tag{mainTag, synonymTag, reservedTag}))
+	_, err := testDataSource.DB.Context(ctx).ID(synonymTag.ID).Cols("main_tag_id", "main_tag_slug_name").
+		Update(&entity.Tag{MainTagID: converter.StringToInt64(mainTag.ID), MainTagSlugName: mainTag.SlugName})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).In("id", mainTag.ID, synonymTag.ID, reservedTag.ID).Delete(&entity.Tag{})
+		_, _ = testDataSource.DB.Context(ctx).In("tag_id", mainTag.ID, synonymTag.ID, reservedTag.ID).Delete(&entity.TagRel{})
+	})
+
+	category, untaggedTopic := createTopicFixture(t, repo)
+
+	// synonyms are stored as their main tag
+	payload := []byte(fmt.Sprintf(`{"category_id":%q,"title":"tagged topic","topic_kind":"discussion","tags":[%q]}`,
+		category.ID, synonymTag.SlugName))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/topics", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	resp := &forumAPIResp{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+	topic := &createPostResp{}
+	require.NoError(t, json.Unmarshal(resp.Data, topic))
+	require.NotEmpty(t, topic.ID)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(topic.ID).Delete(&entity.Topic{})
+	})
+
+	tags, err := service.GetTopicTags(ctx, topic.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, mainTag.SlugName, tags[0].SlugName)
+
+	// reserved tags need a moderator
+	payload = []byte(fmt.Sprintf(`{"tags":[%q,%q]}`, mainTag.SlugName, reservedTag.SlugName))
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/topics/"+topic.ID+"/tags", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	// filtering by the synonym finds the topic through its main tag
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/categories/"+category.ID+"/topics?tag="+synonymTag.SlugName, nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	resp = &forumAPIResp{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+	topicList := &struct {
+		List []struct {
+			ID string `json:"id"`
+		} `json:"list"`
+		Total int `json:"total"`
+	}{}
+	require.NoError(t, json.Unmarshal(resp.Data, topicList))
+	require.Equal(t, 1, topicList.Total)
+	assert.Equal(t, topic.ID, topicList.List[0].ID)
+	assert.NotEqual(t, untaggedTopic.ID, topicList.List[0].ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/tags/"+mainTag.SlugName+"/contents", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	resp = &forumAPIResp{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+	contents := &struct {
+		List []struct {
+			ObjectType string `json:"object_type"`
+			ID         string `json:"id"`
+		} `json:"list"`
+		Total int `json:"total"`
+	}{}
+	require.NoError(t, json.Unmarshal(resp.Data, contents))
+	require.Equal(t, 1, contents.Total)
+	assert.Equal(t, "topic", contents.List[0].ObjectType)
+	assert.Equal(t, topic.ID, contents.List[0].ID)
+
+	topics, total, err := service.ListTagTopics(ctx, synonymTag.SlugName, 1, 20)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	assert.Equal(t, topic.ID, topics[0].ID)
+
+	// the tags of topics are not counted as questions of the tag
+	payload = []byte(fmt.Sprintf(`{"tags":[%q]}`, mainTag.SlugName))
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/topics/"+topic.ID+"/tags", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	tagInfo, exist, err := tagCommonService.GetTagByID(ctx, mainTag.ID)
+	require.NoError(t, err)
+	require.True(t, exist)
+	assert.Equal(t, 0, tagInfo.QuestionCount)
+}
+
+func Test_forumAPI_GetConvertedTopic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.TODO()
+
+	repo := newForumRepoForTest()
+	fc := controller.NewForumController(forumservice.NewForumService(repo, nil, nil, nil, nil))
+	_, topic := createTopicFixture(t, repo)
+	// an author without a user row, so the conversion leaves the counts of the fixture users alone
+	_, err := testDataSource.DB.Context(ctx).ID(topic.ID).Cols("user_id").Update(&entity.Topic{UserID: "999999999"})
+	require.NoError(t, err)
+	post := &entity.Post{TopicID: topic.ID, UserID: "999999999", Original: "opening post", Parsed: "opening post", Status: 1}
+	require.NoError(t, repo.AddPost(ctx, post))
+	question, err := repo.ConvertTopicToQuestion(ctx, topic.ID, "1")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(post.ID).Delete(&entity.Post{})
+		_, _ = testDataSource.DB.Context(ctx).ID(question.ID).Delete(&entity.Question{})
+		_, _ = testDataSource.DB.Context(ctx).Where("target_root_id = ?", question.ID).Delete(&entity.ObjectConversion{})
+	})
+
+	r := gin.New()
+	r.GET("/api/v1/topics/:id", fc.GetTopic)
+
+	// the API answers with where the question lives now instead of redirecting to the question page
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/topics/"+topic.ID, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	detail := mustDecodeForumData[schema.TopicDetailResp](t, w.Body.Bytes())
+	assert.Equal(t, entity.TopicStatusConverted, detail.Status)
+	require.NotNil(t, detail.ConvertedTo)
+	assert.Equal(t, constant.QuestionObjectType, detail.ConvertedTo.ObjectType)
+	assert.Equal(t, question.ID, detail.ConvertedTo.ObjectID)
+}
+
+func Test_forumAPI_TopicPoll(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.TODO()
+
+	repo := newForumRepoForTest()
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
+	fc := controller.NewForumController(service)
+	_, topic := createTopicFixture(t, repo)
+	t.Cleanup(func() {
+		poll, exist, _ := repo.GetTopicPoll(ctx, topic.ID)
+		if exist {
+			_, _ = testDataSource.DB.Context(ctx).Where("poll_id = ?", poll.ID).Delete(&entity.TopicPollVote{})
+			_, _ = testDataSource.DB.Context(ctx).Where("poll_id = ?", poll.ID).Delete(&entity.TopicPollOption{})
+			_, _ = testDataSource.DB.Context(ctx).ID(poll.ID).Delete(&entity.TopicPoll{})
+		}
+	})
+
+	r := gin.New()
+	r.GET("/api/v1/topics/:id", authed("2", 1, fc.GetTopic))
+	r.PUT("/author/topics/:id/poll", authed("1", 1, fc.SaveTopicPoll))
+	r.PUT("/other/topics/:id/poll", authed("2", 1, fc.SaveTopicPoll))
+	r.POST("/api/v1/topics/:id/poll/votes", authed("2", 1, fc.VoteTopicPoll))
+
+	doJSON := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	pollPayload := `{"question":"Which release day?","options":["Monday","Friday"],"multiple":false,"anonymous":false}`
+
+	// only the author can attach a poll
+	w := doJSON(http.MethodPut, "/other/topics/"+topic.ID+"/poll", pollPayload)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+	w = doJSON(http.MethodPut, "/author/topics/"+topic.ID+"/poll", pollPayload)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	poll := mustDecodeForumData[schema.TopicPollResp](t, w.Body.Bytes())
+	require.Len(t, poll.Options, 2)
+	assert.Equal(t, entity.TopicPollStatusOpen, poll.Status)
+	monday, friday := poll.Options[0].ID, poll.Options[1].ID
+
+	w = doJSON(http.MethodPost, "/api/v1/topics/"+topic.ID+"/poll/votes", fmt.Sprintf(`{"option_ids":[%q,%q]}`, monday, friday))
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = doJSON(http.MethodPost, "/api/v1/topics/"+topic.ID+"/poll/votes", fmt.Sprintf(`{"option_ids":[%q]}`, monday))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	// voting again moves the vote instead of adding one
+	w = doJSON(http.MethodPost, "/api/v1/topics/"+topic.ID+"/poll/votes", fmt.Sprintf(`{"option_ids":[%q]}`, friday))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	poll = mustDecodeForumData[schema.TopicPollResp](t, w.Body.Bytes())
+	assert.Equal(t, 1, poll.VoterCount)
+	assert.Equal(t, 0, poll.Options[0].VoteCount)
+	assert.Equal(t, 1, poll.Options[1].VoteCount)
+	assert.Equal(t, []string{friday}, poll.VotedOptionIDs)
+
+	// options are fixed once voting has started
+	w = doJSON(http.MethodPut, "/author/topics/"+topic.ID+"/poll",
+		`{"question":"Which release day?","options":["Monday","Friday","Sunday"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	// the topic API carries the results, with voters for a public poll
+	w = doJSON(http.MethodGet, "/api/v1/topics/"+topic.ID, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	detail := mustDecodeForumData[schema.TopicDetailResp](t, w.Body.Bytes())
+	require.NotNil(t, detail.Poll)
+	assert.Equal(t, topic.ID, detail.ID)
+	require.Len(t, detail.Poll.Options[1].Voters, 1)
+	assert.Equal(t, "2", detail.Poll.Options[1].Voters[0].UserID)
+
+	// the poll of a closed topic takes no votes
+	_, err := testDataSource.DB.Context(ctx).ID(topic.ID).Cols("status").
+		Update(&entity.Topic{Status: entity.TopicStatusClosed})
+	require.NoError(t, err)
+	w = doJSON(http.MethodPost, "/api/v1/topics/"+topic.ID+"/poll/votes", fmt.Sprintf(`{"option_ids":[%q]}`, monday))
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	_, err = testDataSource.DB.Context(ctx).ID(topic.ID).Cols("status").
+		Update(&entity.Topic{Status: entity.TopicStatusAvailable})
+	require.NoError(t, err)
+
+	// the cron job closes polls past their close time
+	closeAt := time.Now().Add(-time.Minute)
+	_, err = testDataSource.DB.Context(ctx).ID(poll.ID).Cols("close_at").Update(&entity.TopicPoll{CloseAt: &closeAt})
+	require.NoError(t, err)
+	service.ClosePollsCron(ctx)
+	stored, exist, err := repo.GetTopicPoll(ctx, topic.ID)
+	require.NoError(t, err)
+	require.True(t, exist)
+	assert.Equal(t, entity.TopicPollStatusClosed, stored.Status)
+	assert.NotNil(t, stored.ClosedAt)
+
+	w = doJSON(http.MethodPost, "/api/v1/topics/"+topic.ID+"/poll/votes", fmt.Sprintf(`{"option_ids":[%q]}`, monday))
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+}
+
+func Test_forumAPI_TopicSummary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.TODO()
+
+	var stubCalls atomic.Int32
+	var lastPrompt atomic.Value
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stubCalls.Add(1)
+		body := &openai.ChatCompletionRequest{}
+		_ = json.NewDecoder(r.Body).Decode(body)
+		if len(body.Messages) > 0 {
+			lastPrompt.Store(body.Messages[0].Content)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[{"index":0,"delta":{"content":"## Key points\n"}}]}`,
+			`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[{"index":0,"delta":{"content":"Ship on Friday."}}]}`,
+			`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+			`{"id":"stub","object":"chat.completion.chunk","model":"stub-model","choices":[],"usage":{"prompt_tokens":30,"completion_tokens":5,"total_tokens":35}}`,
+		} {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(stub.Close)
+
+	siteInfoRepo := site_info.NewSiteInfo(testDataSource)
+	original, hadAIConfig, err := siteInfoRepo.GetByType(ctx, constant.SiteTypeAI, true)
+	require.NoError(t, err)
+	saveConfig := func(quota *schema.AIQuotaConfig) {
+		aiConfig, _ := json.Marshal(&schema.SiteAIReq{
+			Enabled:        true,
+			ChosenProvider: "stub",
+			SiteAIProviders: []*schema.SiteAIProvider{
+				{Provider: "stub", APIHost: stub.URL, APIKey: "test", Model: "stub-model"},
+			},
+			Quota: quota,
+		})
+		require.NoError(t, siteInfoRepo.SaveByType(ctx, constant.SiteTypeAI,
+			&entity.SiteInfo{Type: constant.SiteTypeAI, Content: string(aiConfig), Status: 1}))
+	}
+	saveConfig(nil)
+	featureToggleService := feature_toggle.NewFeatureToggleService(siteInfoRepo)
+	toggles, err := featureToggleService.GetAll(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = featureToggleService.UpdateAll(ctx, toggles)
+		if hadAIConfig {
+			_ = siteInfoRepo.SaveByType(ctx, constant.SiteTypeAI, original)
+			return
+		}
+		_, _ = testDataSource.DB.Context(ctx).Where("type = ?", constant.SiteTypeAI).Delete(&entity.SiteInfo{})
+		_ = testDataSource.Cache.Del(ctx, constant.SiteInfoCacheKey+constant.SiteTypeAI)
+	})
+
+	repo := newForumRepoForTest()
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
+	fc := controller.NewForumController(service)
+	siteInfoService := siteinfo_common.NewSiteInfoCommonService(siteInfoRepo)
+	providerService := ai_provider.NewAIProviderService(siteInfoService)
+	conversationRepo := aiconversationrepo.NewAIConversationRepo(testDataSource)
+	conversationService := ai_conversation.NewAIConversationService(conversationRepo, nil, nil, providerService)
+	ai := controller.NewAIController(nil, siteInfoService, nil, nil, nil, nil, nil, nil, conversationService,
+		featureToggleService, service, nil, providerService, nil)
+	_, topic := createTopicFixture(t, repo)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).Where("object_id = ?", topic.ID).Delete(&entity.AIUsage{})
+	})
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	before, err := conversationRepo.SumTokens(ctx, "1", dayStart)
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.POST("/api/v1/topics/:id/posts", authed("1", 1, fc.CreateTopicPost))
+	r.POST("/api/v1/topics/:id/summary", authed("1", 1, ai.TopicSummary))
+
+	summarise := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/topics/"+topic.ID+"/summary", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	createTopicPostByAPI(t, r, topic.ID, "Should we release on Monday or Friday?")
+	createTopicPostByAPI(t, r, topic.ID, "Friday, the on-call rota is lighter on Monday.")
+
+	w := summarise()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "Ship on Friday.")
+	assert.Contains(t, w.Body.String(), "data: [DONE]")
+	assert.EqualValues(t, 1, stubCalls.Load())
+	assert.Contains(t, lastPrompt.Load(), "the on-call rota is lighter")
+	used, err := conversationRepo.SumTokens(ctx, "1", dayStart)
+	require.NoError(t, err)
+	assert.EqualValues(t, 35, used-before)
+
+	// served from the cache while no post was added
+	w = summarise()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "Ship on Friday.")
+	assert.EqualValues(t, 1, stubCalls.Load())
+
+	createTopicPostByAPI(t, r, topic.ID, "Agreed, Friday it is.")
+	w = summarise()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.EqualValues(t, 2, stubCalls.Load())
+	assert.Contains(t, lastPrompt.Load(), "Agreed, Friday it is.")
+
+	// an edited post changes the summary too
+	_, err = testDataSource.DB.Context(ctx).Table("posts").
+		Where("topic_id = ? AND original_text = ?", topic.ID, "Agreed, Friday it is.").
+		Update(map[string]any{"original_text": "Agreed, Monday it is."})
+	require.NoError(t, err)
+	w = summarise()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.EqualValues(t, 3, stubCalls.Load())
+	assert.Contains(t, lastPrompt.Load(), "Agreed, Monday it is.")
+
+	// once the quota is reached a new summary is refused before calling the provider, the cached one is still served
+	used, err = conversationRepo.SumTokens(ctx, "1", dayStart)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3*35, used-before)
+	saveConfig(&schema.AIQuotaConfig{UserDailyTokens: used})
+	w = summarise()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	_, err = testDataSource.DB.Context(ctx).Table("posts").
+		Where("topic_id = ? AND original_text = ?", topic.ID, "Agreed, Monday it is.").
+		Update(map[string]any{"original_text": "Agreed, Tuesday it is."})
+	require.NoError(t, err)
+	w = summarise()
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.EqualValues(t, 3, stubCalls.Load())
+
+	require.NoError(t, featureToggleService.UpdateAll(ctx, map[string]bool{feature_toggle.FeatureForumSummary: false}))
+	w = summarise()
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.EqualValues(t, 3, stubCalls.Load())
+}
+
+func Test_forumMCP_StreamableHTTPTools(t *testing.T) {
+	ctx := context.TODO()
+
+	repo := newForumRepoForTest()
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
+	mcpController := controller.NewMCPController(nil, siteinfo_common.NewSiteInfoCommonService(site_info.NewSiteInfo(testDataSource)),
+		nil, nil, nil, nil, nil, nil, service, nil, nil, nil, nil, nil, nil)
+	_, topic := createTopicFixture(t, repo)
+	post := &entity.Post{TopicID: topic.ID, UserID: "1", Original: "Use the staging cluster first.", Parsed: "Use the staging cluster first.", Status: 1}
+	require.NoError(t, repo.AddPost(ctx, post))
+	revision, err := service.CreateWikiRevision(ctx, topic.ID, &schema.CreateWikiRevisionReq{
+		Title:    "Release checklist",
+		Document: "1. Deploy to staging.",
+		EditorID: "1",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(post.ID).Delete(&entity.Post{})
+		_, _ = testDataSource.DB.Context(ctx).ID(revision.ID).Delete(&entity.WikiRevision{})
+	})
+
+	s := mcpserver.NewMCPServer("test", "1.0.0")
+	s.AddTool(mcp_tools.NewTopicPostsTool(), mcpController.MCPTopicPostsHandler())
+	s.AddTool(mcp_tools.NewTopicWikiTool(), mcpController.MCPTopicWikiHandler())
+	s.AddTool(mcp_tools.NewWikiRevisionsTool(), mcpController.MCPWikiRevisionsHandler())
+	httpServer := httptest.NewServer(mcpserver.NewStreamableHTTPServer(s))
+	t.Cleanup(httpServer.Close)
+
+	client, err := mcpclient.NewStreamableHttpClient(httpServer.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	require.NoError(t, client.Start(ctx))
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initReq.Params.ClientInfo = mcp.Implementation{Name: "forum-test", Version: "1.0.0"}
+	_, err = client.Initialize(ctx, initReq)
+	require.NoError(t, err)
+
+	callTool := func(name string, args map[string]any) string {
+		req := mcp.CallToolRequest{}
+		req.Params.Name = name
+		req.Params.Arguments = args
+		result, err := client.CallTool(ctx, req)
+		require.NoError(t, err)
+		require.NotEmpty(t, result.Content)
+		text, ok := result.Content[0].(mcp.TextContent)
+		require.True(t, ok)
+		return text.Text
+	}
+
+	posts := &schema.MCPTopicPostsResp{}
+	require.NoError(t, json.Unmarshal([]byte(callTool("get_topic_posts", map[string]any{"topic_id": topic.ID})), posts))
+	assert.Equal(t, topic.Title, posts.Title)
+	require.Len(t, posts.Posts, 1)
+	assert.Equal(t, "Use the staging cluster first.", posts.Posts[0].Content)
+
+	wiki := &schema.MCPWikiResp{}
+	require.NoError(t, json.Unmarshal([]byte(callTool("get_topic_wiki", map[string]any{"topic_id": topic.ID})), wiki))
+	assert.Equal(t, revision.ID, wiki.RevisionID)
+	assert.Equal(t, "1. Deploy to staging.", wiki.Document)
+
+	revisions := make([]*schema.MCPWikiRevisionResp, 0)
+	require.NoError(t, json.Unmarshal([]byte(callTool("get_wiki_revisions", map[string]any{"topic_id": topic.ID})), &revisions))
+	require.Len(t, revisions, 1)
+	assert.True(t, revisions[0].Current)
+
+	assert.Equal(t, "Topic not found.", callTool("get_topic_posts", map[string]any{"topic_id": "999999999999"}))
+}
+
+func Test_forumMCP_WriteToolsPerKey(t *testing.T) {
+	ctx := context.TODO()
+
+	repo := newForumRepoForTest()
+	service := forumservice.NewForumService(repo, nil, nil, nil, nil)
+	siteInfoService := siteinfo_common.NewSiteInfoCommonService(site_info.NewSiteInfo(testDataSource))
+	userRoleRelService := roleservice.NewUserRoleRelService(rolerepo.NewUserRoleRelRepo(testDataSource),
+		roleservice.NewRoleService(rolerepo.NewRoleRepo(testDataSource)))
+	userRepo := user.NewUserRepo(testDataSource)
+	userCommon := usercommon.NewUserCommon(userRepo, userRoleRelService, nil, siteInfoService)
+	mcpController := controller.NewMCPController(nil, siteInfoService, nil, nil, nil, userCommon, nil, nil, service,
+		nil, nil, nil, nil, userRoleRelService, middleware.NewRateLimitMiddleware(limit.NewRateLimitRepo(testDataSource)))
+	_, topic := createTopicFixture(t, repo)
+
+	require.NoError(t, userRepo.AddUser(ctx, &entity.User{
+		Username:    "mcp_writer",
+		Pass:        "mcp_writer",
+		EMail:       "mcp_writer@example.com",
+		MailStatus:  entity.EmailStatusAvailable,
+		Status:      entity.UserStatusAvailable,
+		DisplayName: "mcp_writer",
+	}))
+	owner, exist, err := userRepo.GetByUsername(ctx, "mcp_writer")
+	require.NoError(t, err)
+	require.True(t, exist)
+
+	keyRepo := api_key.NewAPIKeyRepo(testDataSource)
+	writeKey := entity.APIKey{Description: "agent", AccessKey: "sk_mcp_write_test", Scope: "global", UserID: owner.ID, LastUsedAt: time.Now()}
+	writeKey.SetMCPWriteTools([]string{schema.MCPToolCreateForumPost})
+	defaultKey := entity.APIKey{Description: "reader", AccessKey: "sk_mcp_default_test", Scope: "global", UserID: owner.ID, LastUsedAt: time.Now()}
+	require.NoError(t, keyRepo.AddAPIKey(ctx, writeKey))
+	require.NoError(t, keyRepo.AddAPIKey(ctx, defaultKey))
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).In("access_key", writeKey.AccessKey, defaultKey.AccessKey).Delete(&entity.APIKey{})
+		_, _ = testDataSource.DB.Context(ctx).Where("topic_id = ?", topic.ID).Delete(&entity.Post{})
+		_, _ = testDataSource.DB.Context(ctx).ID(owner.ID).Delete(&entity.User{})
+	})
+
+	s := mcpserver.NewMCPServer("test", "1.0.0", mcpserver.WithToolFilter(mcpController.MCPToolFilter))
+	s.AddTool(mcp_tools.NewTopicPostsTool(), mcpController.MCPTopicPostsHandler())
+	s.AddTool(mcp_tools.NewCreateForumPostTool(), mcpController.MCPCreateForumPostHandler())
+	s.AddTool(mcp_tools.NewProposeMergeJobTool(), mcpController.MCPProposeMergeJobHandler())
+	authMiddleware := middleware.NewAuthUserMiddleware(
+		authservice.NewAuthService(authrepo.NewAuthRepo(testDataSource), keyRepo), siteInfoService)
+	r := gin.New()
+	r.Use(authMiddleware.AuthAPIKey())
+	r.Any("/mcp", gin.WrapH(mcpserver.NewStreamableHTTPServer(s, mcpserver.WithEndpointPath("/mcp"))))
+	httpServer := httptest.NewServer(r)
+	t.Cleanup(httpServer.Close)
+
+	connect := func(accessKey string) *mcpclient.Client {
+		client, err := mcpclient.NewStreamableHttpClient(httpServer.URL+"/mcp",
+			transport.WithHTTPHeaders(map[string]string{"Authorization": accessKey}))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = client.Close() })
+		require.NoError(t, client.Start(ctx))
+		initReq := mcp.InitializeRequest{}
+		initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+		initReq.Params.ClientInfo = mcp.Implementation{Name: "forum-test", Version: "1.0.0"}
+		_, err = client.Initialize(ctx, initReq)
+		require.NoError(t, err)
+		return client
+	}
+	listTools := func(client *mcpclient.Client) []string {
+		result, err := client.ListTools(ctx, mcp.ListToolsRequest{})
+		require.NoError(t, err)
+		names := make([]string, 0, len(result.Tools))
+		for _, tool := range result.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	callTool := func(client *mcpclient.Client, name string, args map[string]any) (string, bool) {
+		req := mcp.CallToolRequest{}
+		req.Params.Name = name
+		req.Params.Arguments = args
+		result, err := client.CallTool(ctx, req)
+		require.NoError(t, err)
+		require.NotEmpty(t, result.Content)
+		text, ok := result.Content[0].(mcp.TextContent)
+		require.True(t, ok)
+		return text.Text, result.IsError
+	}
+	postArgs := map[string]any{"topic_id": topic.ID, "content": "Posted by the release agent."}
+
+	// write tools are off unless the key enables them
+	defaultClient := connect(defaultKey.AccessKey)
+	assert.ElementsMatch(t, []string{"get_topic_posts"}, listTools(defaultClient))
+	text, isError := callTool(defaultClient, schema.MCPToolCreateForumPost, postArgs)
+	assert.True(t, isError)
+	assert.Contains(t, text, "does not enable")
+
+	writeClient := connect(writeKey.AccessKey)
+	assert.ElementsMatch(t, []string{"get_topic_posts", schema.MCPToolCreateForumPost}, listTools(writeClient))
+	text, isError = callTool(writeClient, schema.MCPToolCreateForumPost, postArgs)
+	require.False(t, isError, text)
+	created := &schema.MCPWriteResp{}
+	require.NoError(t, json.Unmarshal([]byte(text), created))
+	assert.Equal(t, constant.PostObjectType, created.ObjectType)
+	assert.False(t, created.Pending)
+
+	posts, _, err := service.ListTopicPosts(ctx, topic.ID, &schema.PostListReq{Page: 1, PageSize: 20})
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	assert.Equal(t, created.ObjectID, posts[0].ID)
+	assert.Equal(t, owner.ID, posts[0].UserID)
+
+	// the same content again is rejected like a duplicate HTTP request
+	_, isError = callTool(writeClient, schema.MCPToolCreateForumPost, postArgs)
+	assert.True(t, isError)
+
+	text, isError = callTool(writeClient, schema.MCPToolProposeMergeJob, map[string]any{"topic_id": topic.ID, "post_ids": created.ObjectID})
+	assert.True(t, isError)
+	assert.Contains(t, text, "does not enable")
+}
@@ -26,6 +26,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/apache/answer/internal/base/constant"
 	"github.com/apache/answer/internal/entity"
 	"github.com/apache/answer/internal/repo/forum"
 	"github.com/apache/answer/internal/repo/unique"
@@ -59,7 +60,7 @@ func createTopicFixture(t *testing.T, repo *forum.ForumRepo) (*entity.Category,
 		IsWikiEnabled: true,
 		Status:        entity.TopicStatusAvailable,
 	}
-	require.NoError(t, repo.AddTopic(ctx, topic))
+	require.NoError(t, repo.AddTopic(ctx, topic, nil))
 
 	t.Cleanup(func() {
 		_, _ = testDataSource.DB.Context(ctx).ID(topic.ID).Delete(&entity.Topic{})
@@ -181,3 +182,245 @@ func Test_forumRepo_AddPostConcurrent_NoSQLiteBusy(t *testing.T) {
 		_, _ = testDataSource.DB.Context(ctx).In("id", createdIDs).Delete(&entity.Post{})
 	})
 }
+
+func Test_forumRepo_ConvertQuestionToTopicAndBack(t *testing.T) {
+	ctx := context.TODO()
+	repo := newForumRepoForTest()
+	uniqueIDRepo := unique.NewUniqueIDRepo(testDataSource)
+	category, _ := createTopicFixture(t, repo)
+
+	// the ids stay clear of the fixtures of other tests, the tag relations of the question move with it
+	suffix := time.Now().UnixNano() % 100000
+	questionID := fmt.Sprintf("1001999999%07d", suffix)
+	answerID := fmt.Sprintf("1002999999%07d", suffix)
+	commentID, err := uniqueIDRepo.GenUniqueIDStr(ctx, (&entity.Comment{}).TableName())
+	require.NoError(t, err)
+	author := &entity.User{
+		Username:   fmt.Sprintf("convert_author_%d", suffix),
+		EMail:      fmt.Sprintf("convert_author_%d@example.com", suffix),
+		Status:     entity.UserStatusAvailable,
+		MailStatus: entity.EmailStatusAvailable,
+	}
+	_, err = testDataSource.DB.Context(ctx).Insert(author)
+	require.NoError(t, err)
+	tag := &entity.Tag{
+		ID:          fmt.Sprintf("1003999999%07d", suffix),
+		SlugName:    fmt.Sprintf("convert-%d", suffix),
+		DisplayName: fmt.Sprintf("convert-%d", suffix),
+		Status:      entity.TagStatusAvailable,
+	}
+	_, err = testDataSource.DB.Context(ctx).Insert(tag)
+	require.NoError(t, err)
+	_, err = testDataSource.DB.Context(ctx).Insert(&entity.TagRel{TagID: tag.ID, ObjectID: questionID, Status: entity.TagRelStatusAvailable})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(author.ID).Delete(&entity.User{})
+		_, _ = testDataSource.DB.Context(ctx).ID(tag.ID).Delete(&entity.Tag{})
+		_, _ = testDataSource.DB.Context(ctx).Where("tag_id = ?", tag.ID).Delete(&entity.TagRel{})
+	})
+	userCounts := func() (questionCount, answerCount int) {
+		user := &entity.User{ID: author.ID}
+		_, err := testDataSource.DB.Context(ctx).Get(user)
+		require.NoError(t, err)
+		return user.QuestionCount, user.AnswerCount
+	}
+	tagQuestionCount := func() int {
+		tagInfo := &entity.Tag{ID: tag.ID}
+		_, err := testDataSource.DB.Context(ctx).Get(tagInfo)
+		require.NoError(t, err)
+		return tagInfo.QuestionCount
+	}
+
+	createdAt := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	question := &entity.Question{
+		ID:               questionID,
+		CreatedAt:        createdAt,
+		UserID:           author.ID,
+		Title:            "question to convert",
+		OriginalText:     "question body",
+		ParsedText:       "<p>question body</p>",
+		Status:           entity.QuestionStatusAvailable,
+		Show:             entity.QuestionShow,
+		Pin:              entity.QuestionUnPin,
+		VoteCount:        1,
+		AnswerCount:      1,
+		AcceptedAnswerID: answerID,
+		PostUpdateTime:   createdAt,
+	}
+	_, err = testDataSource.DB.Context(ctx).Insert(question)
+	require.NoError(t, err)
+	answer := &entity.Answer{
+		ID:           answerID,
+		CreatedAt:    createdAt.Add(time.Hour),
+		QuestionID:   questionID,
+		UserID:       author.ID,
+		OriginalText: "accepted answer",
+		ParsedText:   "<p>accepted answer</p>",
+		Status:       entity.AnswerStatusAvailable,
+		Accepted:     2,
+		VoteCount:    3,
+	}
+	_, err = testDataSource.DB.Context(ctx).NoAutoTime().Insert(answer)
+	require.NoError(t, err)
+	comment := &entity.Comment{
+		ID:           commentID,
+		CreatedAt:    createdAt.Add(2 * time.Hour),
+		UserID:       author.ID,
+		ObjectID:     answerID,
+		QuestionID:   questionID,
+		OriginalText: "a comment",
+		ParsedText:   "<p>a comment</p>",
+		Status:       entity.CommentStatusAvailable,
+	}
+	_, err = testDataSource.DB.Context(ctx).NoAutoTime().Insert(comment)
+	require.NoError(t, err)
+	voteUp := &entity.Config{Key: "question.vote_up"}
+	_, err = testDataSource.DB.Context(ctx).Get(voteUp)
+	require.NoError(t, err)
+	_, err = testDataSource.DB.Context(ctx).Insert(&entity.Activity{
+		ObjectID:         questionID,
+		OriginalObjectID: questionID,
+		UserID:           "1",
+		ActivityType:     voteUp.ID,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(questionID).Delete(&entity.Question{})
+		_, _ = testDataSource.DB.Context(ctx).ID(answerID).Delete(&entity.Answer{})
+		_, _ = testDataSource.DB.Context(ctx).ID(commentID).Delete(&entity.Comment{})
+		_, _ = testDataSource.DB.Context(ctx).Where("object_id = ?", questionID).Delete(&entity.Activity{})
+	})
+
+	topic, err := repo.ConvertQuestionToTopic(ctx, questionID, category.ID, entity.TopicKindDiscussion, "1")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(topic.ID).Delete(&entity.Topic{})
+		_, _ = testDataSource.DB.Context(ctx).Where("topic_id = ?", topic.ID).Delete(&entity.Post{})
+		_, _ = testDataSource.DB.Context(ctx).Where("topic_id = ?", topic.ID).Delete(&entity.TopicVote{})
+		_, _ = testDataSource.DB.Context(ctx).Where("topic_id = ?", topic.ID).Delete(&entity.TopicSolution{})
+	})
+	assert.Equal(t, "question to convert", topic.Title)
+	assert.Equal(t, 3, topic.PostCount)
+	assert.Equal(t, 1, topic.VoteCount)
+
+	posts, total, err := repo.ListTopicPosts(ctx, topic.ID, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	assert.Equal(t, "question body", posts[0].OriginalText)
+	assert.Equal(t, "accepted answer", posts[1].OriginalText)
+	assert.Equal(t, 3, posts[1].VoteCount)
+	assert.True(t, posts[0].CreatedAt.Equal(createdAt))
+
+	topicAfter, _, err := repo.GetTopic(ctx, topic.ID)
+	require.NoError(t, err)
+	assert.Equal(t, posts[1].ID, topicAfter.SolvedPostID)
+	topicVotes, err := testDataSource.DB.Context(ctx).Where("topic_id = ?", topic.ID).Count(&entity.TopicVote{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), topicVotes)
+
+	questionAfter := &entity.Question{ID: questionID}
+	_, err = testDataSource.DB.Context(ctx).Get(questionAfter)
+	require.NoError(t, err)
+	assert.Equal(t, entity.QuestionStatusDeleted, questionAfter.Status)
+	answerAfter := &entity.Answer{ID: answerID}
+	_, err = testDataSource.DB.Context(ctx).Get(answerAfter)
+	require.NoError(t, err)
+	assert.Equal(t, entity.AnswerStatusDeleted, answerAfter.Status)
+	commentAfter := &entity.Comment{ID: commentID}
+	_, err = testDataSource.DB.Context(ctx).Get(commentAfter)
+	require.NoError(t, err)
+	assert.Equal(t, entity.CommentStatusDeleted, commentAfter.Status)
+	questionCount, answerCount := userCounts()
+	assert.Equal(t, 0, questionCount)
+	assert.Equal(t, 0, answerCount)
+	assert.Equal(t, 0, tagQuestionCount())
+
+	conversion, exist, err := repo.GetObjectConversion(ctx, questionID)
+	require.NoError(t, err)
+	require.True(t, exist)
+	assert.Equal(t, topic.ID, conversion.TargetRootID)
+
+	// converting the same question twice must fail
+	_, err = repo.ConvertQuestionToTopic(ctx, questionID, category.ID, entity.TopicKindDiscussion, "1")
+	require.Error(t, err)
+
+	newQuestion, err := repo.ConvertTopicToQuestion(ctx, topic.ID, "1")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(newQuestion.ID).Delete(&entity.Question{})
+		_, _ = testDataSource.DB.Context(ctx).Where("question_id = ?", newQuestion.ID).Delete(&entity.Answer{})
+		_, _ = testDataSource.DB.Context(ctx).Where("object_id = ?", newQuestion.ID).Delete(&entity.Activity{})
+		_, _ = testDataSource.DB.Context(ctx).Where("target_root_id IN (?, ?)", topic.ID, newQuestion.ID).
+			Delete(&entity.ObjectConversion{})
+	})
+	assert.Equal(t, "question body", newQuestion.OriginalText)
+	assert.Equal(t, 2, newQuestion.AnswerCount)
+	assert.Equal(t, 1, newQuestion.VoteCount)
+	assert.True(t, newQuestion.CreatedAt.Equal(createdAt))
+
+	answers := make([]*entity.Answer, 0)
+	require.NoError(t, testDataSource.DB.Context(ctx).Where("question_id = ?", newQuestion.ID).
+		Asc("created_at").Find(&answers))
+	require.Len(t, answers, 2)
+	assert.Equal(t, newQuestion.AcceptedAnswerID, answers[0].ID)
+	assert.Equal(t, 2, answers[0].Accepted)
+	assert.True(t, answers[0].CreatedAt.Equal(createdAt.Add(time.Hour)))
+	// the comment comes back as an answer too
+	questionCount, answerCount = userCounts()
+	assert.Equal(t, 1, questionCount)
+	assert.Equal(t, 2, answerCount)
+	assert.Equal(t, 1, tagQuestionCount())
+
+	votes, err := testDataSource.DB.Context(ctx).Where("object_id = ? AND activity_type = ?", newQuestion.ID, voteUp.ID).
+		Count(&entity.Activity{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), votes)
+
+	topicAfter, _, err = repo.GetTopic(ctx, topic.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.TopicStatusConverted, topicAfter.Status)
+	// the converted topic is neither listed nor counted in its category
+	categoryTopics, categoryTotal, err := repo.ListTopicsByCategory(ctx, category.ID, nil, 1, 100)
+	require.NoError(t, err)
+	for _, categoryTopic := range categoryTopics {
+		assert.NotEqual(t, topic.ID, categoryTopic.ID)
+	}
+	assert.Equal(t, int64(len(categoryTopics)), categoryTotal)
+	conversion, exist, err = repo.GetObjectConversion(ctx, topic.ID)
+	require.NoError(t, err)
+	require.True(t, exist)
+	assert.Equal(t, newQuestion.ID, conversion.TargetRootID)
+}
+
+func Test_forumRepo_SitemapTopics(t *testing.T) {
+	ctx := context.TODO()
+	repo := newForumRepoForTest()
+	_, topic := createTopicFixture(t, repo)
+	cacheKey := fmt.Sprintf(constant.SiteMapTopicCacheKeyPrefix, 0)
+	require.NoError(t, testDataSource.Cache.Del(ctx, cacheKey))
+	t.Cleanup(func() {
+		_ = testDataSource.Cache.Del(ctx, cacheKey)
+	})
+
+	count, err := repo.GetSitemapTopicCount(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, count, int64(1))
+
+	list, err := repo.SitemapTopics(ctx, 1, constant.SitemapMaxSize)
+	require.NoError(t, err)
+	var found bool
+	for _, item := range list {
+		if item.ID == topic.ID {
+			found = true
+			assert.False(t, item.HasWiki)
+			assert.NotEmpty(t, item.UpdateTime)
+		}
+	}
+	assert.True(t, found)
+
+	topic.Status = entity.TopicStatusConverted
+	require.NoError(t, repo.UpdateTopic(ctx, topic, "status"))
+	afterCount, err := repo.GetSitemapTopicCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, count-1, afterCount)
+}
@@ -0,0 +1,161 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package repo_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/entity"
+	savedsearchrepo "github.com/apache/answer/internal/repo/saved_search"
+	"github.com/apache/answer/internal/repo/unique"
+	"github.com/apache/answer/internal/repo/user"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/content"
+	"github.com/apache/answer/internal/service/saved_search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/builder"
+)
+
+// recordingQueue keeps the messages sent instead of handling them
+type recordingQueue[T any] struct {
+	mu   sync.Mutex
+	msgs []T
+}
+
+func (q *recordingQueue[T]) Send(ctx context.Context, msg T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.msgs = append(q.msgs, msg)
+}
+
+func (q *recordingQueue[T]) RegisterHandler(handler func(ctx context.Context, msg T) error) {}
+
+func (q *recordingQueue[T]) Close() {}
+
+func (q *recordingQueue[T]) take() []T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := q.msgs
+	q.msgs = nil
+	return msgs
+}
+
+func Test_savedSearch_AlertCron(t *testing.T) {
+	ctx := context.TODO()
+	uniqueIDRepo := unique.NewUniqueIDRepo(testDataSource)
+	searchRepo, parser := newSearchRepoForTest()
+	inbox := &recordingQueue[*schema.NotificationMsg]{}
+	email := &recordingQueue[*schema.ExternalNotificationMsg]{}
+	service := saved_search.NewSavedSearchService(testDataSource, savedsearchrepo.NewSavedSearchRepo(testDataSource),
+		content.NewSearchService(parser, searchRepo), user.NewUserRepo(testDataSource), inbox, email)
+
+	questionIDs := make([]string, 0)
+	addQuestion := func(userID, title string) string {
+		questionID, err := uniqueIDRepo.GenUniqueIDStr(ctx, entity.Question{}.TableName())
+		require.NoError(t, err)
+		_, err = testDataSource.DB.Context(ctx).Insert(&entity.Question{
+			ID:               questionID,
+			UserID:           userID,
+			Title:            title,
+			OriginalText:     title,
+			ParsedText:       "<p>" + title + "</p>",
+			Status:           entity.QuestionStatusAvailable,
+			Show:             entity.QuestionShow,
+			Pin:              entity.QuestionUnPin,
+			AcceptedAnswerID: "0",
+			PostUpdateTime:   time.Now(),
+		})
+		require.NoError(t, err)
+		questionIDs = append(questionIDs, questionID)
+		return questionID
+	}
+	rerun := func() {
+		_, err := testDataSource.DB.Context(ctx).Exec("UPDATE `saved_search` SET `last_run_at` = ?",
+			time.Now().Add(-2*time.Hour))
+		require.NoError(t, err)
+		service.AlertCron(ctx)
+	}
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).In("id", questionIDs).Delete(&entity.Question{})
+		_, _ = testDataSource.DB.Context(ctx).Where(builder.Expr("1 = 1")).Delete(&entity.SavedSearch{})
+		_, _ = testDataSource.DB.Context(ctx).Where(builder.Expr("1 = 1")).Delete(&entity.SavedSearchMatch{})
+		_ = testDataSource.Cache.Del(ctx, constant.SavedSearchAlertLimitCacheKeyPrefix+"1")
+	})
+
+	// the results found when the search is saved are not alerted
+	addQuestion("2", "Zephyrine pumps lose pressure")
+	savedSearch, err := service.AddSavedSearch(ctx, &schema.AddSavedSearchReq{
+		Name: "pumps", Query: "zephyrine", NotifyInbox: true, NotifyEmail: true, UserID: "1"})
+	require.NoError(t, err)
+	rerun()
+	assert.Empty(t, inbox.take())
+	assert.Empty(t, email.take())
+
+	// the new matches are sent once, those posted by the user are left out
+	newQuestionID := addQuestion("2", "Zephyrine pumps rattle at night")
+	addQuestion("1", "Zephyrine pumps need oil")
+	rerun()
+	msgs := inbox.take()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, newQuestionID, msgs[0].ObjectID)
+	assert.Equal(t, "2", msgs[0].TriggerUserID)
+	assert.Equal(t, constant.NotificationMatchedSavedSearch, msgs[0].NotificationAction)
+	emails := email.take()
+	require.Len(t, emails, 1)
+	require.Len(t, emails[0].SavedSearchDigestTemplateRawData.Matches, 1)
+	assert.Equal(t, "Zephyrine pumps rattle at night", emails[0].SavedSearchDigestTemplateRawData.Matches[0].Title)
+	rerun()
+	assert.Empty(t, inbox.take())
+
+	// the saved search does not rerun within the interval
+	addQuestion("2", "Zephyrine pumps overheat")
+	service.AlertCron(ctx)
+	assert.Empty(t, inbox.take())
+
+	// the matches over the daily limit wait for a later run
+	require.NoError(t, testDataSource.Cache.SetInt64(ctx, constant.SavedSearchAlertLimitCacheKeyPrefix+"1",
+		constant.SavedSearchAlertLimitMax, constant.SavedSearchAlertLimitCacheTime))
+	rerun()
+	assert.Empty(t, inbox.take())
+	require.NoError(t, testDataSource.Cache.Del(ctx, constant.SavedSearchAlertLimitCacheKeyPrefix+"1"))
+	rerun()
+	assert.Len(t, inbox.take(), 1)
+
+	// only the user changes their saved searches
+	err = service.UpdateSavedSearch(ctx, &schema.UpdateSavedSearchReq{
+		ID: savedSearch.ID, Name: "pumps", Query: "zephyrine", UserID: "2"})
+	assert.Error(t, err)
+	err = service.RemoveSavedSearch(ctx, &schema.RemoveSavedSearchReq{ID: savedSearch.ID, UserID: "2"})
+	assert.Error(t, err)
+	list, err := service.GetSavedSearchList(ctx, &schema.GetSavedSearchListReq{UserID: "1"})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "zephyrine", list[0].Query)
+	require.NoError(t, service.RemoveSavedSearch(ctx, &schema.RemoveSavedSearchReq{ID: savedSearch.ID, UserID: "1"}))
+	count, err := testDataSource.DB.Context(ctx).Where("saved_search_id = ?", savedSearch.ID).
+		Count(&entity.SavedSearchMatch{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,96 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/repo/config"
+	searchanalyticsrepo "github.com/apache/answer/internal/repo/search_analytics"
+	"github.com/apache/answer/internal/schema"
+	configservice "github.com/apache/answer/internal/service/config"
+	"github.com/apache/answer/internal/service/search_analytics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/builder"
+)
+
+func Test_searchAnalytics(t *testing.T) {
+	ctx := context.TODO()
+	repo := searchanalyticsrepo.NewSearchAnalyticsRepo(testDataSource)
+	service := search_analytics.NewSearchAnalyticsService(repo,
+		configservice.NewConfigService(config.NewConfigRepo(testDataSource)))
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).Where(builder.Expr("1 = 1")).Delete(&entity.SearchLog{})
+		_, _ = testDataSource.DB.Context(ctx).Where(builder.Expr("1 = 1")).Delete(&entity.SearchLogDaily{})
+	})
+
+	searchID := service.RecordSearch(ctx, "1", "127.0.0.1", "  How to   Deploy ", 3)
+	require.NotEmpty(t, searchID)
+	assert.NotEmpty(t, service.RecordSearch(ctx, "", "127.0.0.2", "how to deploy", 3))
+	assert.NotEmpty(t, service.RecordSearch(ctx, "", "127.0.0.2", "missing page", 0))
+	assert.Empty(t, service.RecordSearch(ctx, "1", "127.0.0.1", "   ", 0))
+
+	// only the user who searched records a click, and only the first one
+	require.NoError(t, service.RecordClick(ctx, &schema.SearchClickReq{
+		SearchID: searchID, ObjectID: "10010000000000001", Position: 2, UserID: "2"}))
+	require.NoError(t, service.RecordClick(ctx, &schema.SearchClickReq{
+		SearchID: searchID, ObjectID: "10010000000000001", Position: 2, UserID: "1"}))
+	require.NoError(t, service.RecordClick(ctx, &schema.SearchClickReq{
+		SearchID: searchID, ObjectID: "10010000000000002", Position: 5, UserID: "1"}))
+	searchLog := &entity.SearchLog{}
+	_, err := testDataSource.DB.Context(ctx).ID(searchID).Get(searchLog)
+	require.NoError(t, err)
+	assert.Equal(t, "how to deploy", searchLog.Query)
+	assert.Equal(t, "10010000000000001", searchLog.ClickedObjectID)
+	assert.Equal(t, 2, searchLog.ClickedPosition)
+	assert.Len(t, searchLog.UserHash, 16)
+
+	// a search older than the retention is rolled up into the daily stats
+	old := time.Now().AddDate(0, 0, -40)
+	oldID := service.RecordSearch(ctx, "1", "", "missing page", 0)
+	_, err = testDataSource.DB.Context(ctx).Exec("UPDATE `search_log` SET `created_at` = ? WHERE `id` = ?", old, oldID)
+	require.NoError(t, err)
+	service.RollUpCron(ctx)
+	exist, err := testDataSource.DB.Context(ctx).ID(oldID).Exist(&entity.SearchLog{})
+	require.NoError(t, err)
+	assert.False(t, exist)
+
+	resp, err := service.GetReport(ctx, &schema.SearchAnalyticsReq{StartDate: old.Format("2006-01-02")})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), resp.Total.Searches)
+	assert.Equal(t, int64(2), resp.Total.ZeroResults)
+	assert.Equal(t, int64(1), resp.Total.Clicks)
+	assert.InDelta(t, 0.25, resp.Total.ClickThroughRate, 0.001)
+	require.Len(t, resp.TopQueries, 2)
+	assert.Equal(t, "how to deploy", resp.TopQueries[0].Query)
+	assert.InDelta(t, 0.5, resp.TopQueries[0].ClickThroughRate, 0.001)
+	require.Len(t, resp.ZeroResultQueries, 1)
+	assert.Equal(t, "missing page", resp.ZeroResultQueries[0].Query)
+	assert.Equal(t, int64(2), resp.ZeroResultQueries[0].ZeroResults)
+	assert.Equal(t, int64(1), resp.Days[0].Searches)
+	assert.Equal(t, int64(3), resp.Days[len(resp.Days)-1].Searches)
+
+	_, err = service.GetReport(ctx, &schema.SearchAnalyticsReq{StartDate: "2020-01-02", EndDate: "2020-01-01"})
+	assert.Error(t, err)
+}
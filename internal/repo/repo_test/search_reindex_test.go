@@ -0,0 +1,220 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package repo_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/repo/config"
+	"github.com/apache/answer/internal/repo/search_sync"
+	"github.com/apache/answer/internal/schema"
+	configService "github.com/apache/answer/internal/service/config"
+	"github.com/apache/answer/internal/service/search_reindex"
+	"github.com/apache/answer/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySearch a search plugin keeping the contents indexed in memory
+type memorySearch struct {
+	lock     sync.Mutex
+	contents map[string]*plugin.SearchContent
+}
+
+func (m *memorySearch) Info() plugin.Info {
+	return plugin.Info{SlugName: "memory_search"}
+}
+
+func (m *memorySearch) Description() plugin.SearchDesc {
+	return plugin.SearchDesc{}
+}
+
+func (m *memorySearch) RegisterSyncer(ctx context.Context, syncer plugin.SearchSyncer) {}
+
+func (m *memorySearch) SearchContents(ctx context.Context, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	return nil, 0, nil
+}
+
+func (m *memorySearch) SearchQuestions(ctx context.Context, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	return m.page(constant.QuestionObjectType, cond)
+}
+
+func (m *memorySearch) SearchAnswers(ctx context.Context, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	return m.page(constant.AnswerObjectType, cond)
+}
+
+// page returns the page of the contents of the type, in the order of their id
+func (m *memorySearch) page(objectType string, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	ids := make([]string, 0)
+	for id, content := range m.contents {
+		if content.Type == objectType {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	start := min((cond.Page-1)*cond.PageSize, len(ids))
+	for _, id := range ids[start:min(start+cond.PageSize, len(ids))] {
+		res = append(res, plugin.SearchResult{ID: id, Type: objectType})
+	}
+	return res, int64(len(ids)), nil
+}
+
+func (m *memorySearch) UpdateContent(ctx context.Context, content *plugin.SearchContent) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.contents[content.ObjectID] = content
+	return nil
+}
+
+func (m *memorySearch) DeleteContent(ctx context.Context, objectID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.contents, objectID)
+	return nil
+}
+
+func (m *memorySearch) CountContents(ctx context.Context) (questions, answers int64, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, content := range m.contents {
+		if content.Status == plugin.SearchContentStatusDeleted {
+			continue
+		}
+		if content.Type == constant.QuestionObjectType {
+			questions++
+		} else {
+			answers++
+		}
+	}
+	return questions, answers, nil
+}
+
+func (m *memorySearch) reset() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.contents = make(map[string]*plugin.SearchContent)
+}
+
+func (m *memorySearch) count() int64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return int64(len(m.contents))
+}
+
+func Test_searchReindex(t *testing.T) {
+	ctx := context.TODO()
+	search := &memorySearch{contents: make(map[string]*plugin.SearchContent)}
+	plugin.Register(search)
+	plugin.StatusManager.Enable(search.Info().SlugName, true)
+	confService := configService.NewConfigService(config.NewConfigRepo(testDataSource))
+	service := search_reindex.NewSearchReindexService(search_sync.NewSearchReindexRepo(testDataSource), confService)
+	t.Cleanup(func() {
+		plugin.StatusManager.Enable(search.Info().SlugName, false)
+		_ = confService.UpdateConfig(ctx, constant.SearchReindexCheckpointKey, "")
+	})
+
+	questions, err := testDataSource.DB.Context(ctx).Count(&entity.Question{})
+	require.NoError(t, err)
+	answers, err := testDataSource.DB.Context(ctx).Count(&entity.Answer{})
+	require.NoError(t, err)
+	require.Greater(t, questions, int64(1))
+
+	// the index is empty before the reindex
+	verify, err := service.Verify(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "memory_search", verify.Plugin)
+	assert.False(t, verify.Match)
+
+	// the contents no longer in the database leave the index
+	require.NoError(t, search.UpdateContent(ctx, &plugin.SearchContent{ObjectID: "10010000000999999",
+		Type: constant.QuestionObjectType}))
+	err = service.Reindex(ctx, &schema.SearchReindexReq{BatchSize: 1, Concurrency: 2}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, questions+answers, search.count())
+	progress, err := service.GetReindexProgress(ctx)
+	require.NoError(t, err)
+	assert.False(t, progress.Running)
+	assert.NotZero(t, progress.FinishedAt)
+	assert.Equal(t, questions, progress.Questions.Indexed)
+	assert.Equal(t, answers, progress.Answers.Indexed)
+	assert.Equal(t, int64(1), progress.Questions.Removed)
+	verify, err = service.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, verify.Match)
+
+	// the reindex interrupted after the first batch resumes from it
+	search.reset()
+	cancelCtx, cancel := context.WithCancel(ctx)
+	err = service.Reindex(cancelCtx, &schema.SearchReindexReq{BatchSize: 1, Restart: true},
+		func(progress *schema.SearchReindexProgress) {
+			cancel()
+		})
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), search.count())
+	progress, err = service.GetReindexProgress(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, progress.FinishedAt)
+	assert.Equal(t, int64(1), progress.Questions.Indexed)
+
+	search.reset()
+	err = service.Reindex(ctx, &schema.SearchReindexReq{BatchSize: 10}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, questions+answers-1, search.count())
+	progress, err = service.GetReindexProgress(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, questions, progress.Questions.Indexed)
+
+	// the reindex is refused while another process, like the command line, holds the lock
+	reindexRepo := search_sync.NewSearchReindexRepo(testDataSource)
+	acquired, err := reindexRepo.AcquireReindexLock(ctx, "cli", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, acquired)
+	t.Cleanup(func() {
+		_ = reindexRepo.ReleaseReindexLock(ctx, "cli")
+	})
+	err = service.Reindex(ctx, &schema.SearchReindexReq{}, nil)
+	assert.Error(t, err)
+	progress, err = service.GetReindexProgress(ctx)
+	require.NoError(t, err)
+	assert.True(t, progress.Running)
+	require.NoError(t, reindexRepo.ReleaseReindexLock(ctx, "cli"))
+
+	// an expired lock is taken over
+	acquired, err = reindexRepo.AcquireReindexLock(ctx, "cli", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.True(t, acquired)
+	err = service.Reindex(ctx, &schema.SearchReindexReq{BatchSize: 10}, nil)
+	require.NoError(t, err)
+	locked, err := reindexRepo.IsReindexLocked(ctx)
+	require.NoError(t, err)
+	assert.False(t, locked)
+}
@@ -0,0 +1,328 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/repo/search_common"
+	"github.com/apache/answer/internal/repo/site_info"
+	"github.com/apache/answer/internal/repo/tag"
+	"github.com/apache/answer/internal/repo/tag_common"
+	"github.com/apache/answer/internal/repo/unique"
+	"github.com/apache/answer/internal/repo/user"
+	"github.com/apache/answer/internal/schema"
+	searchcommon "github.com/apache/answer/internal/service/search_common"
+	"github.com/apache/answer/internal/service/search_parser"
+	"github.com/apache/answer/internal/service/siteinfo_common"
+	tagcommon "github.com/apache/answer/internal/service/tag_common"
+	usercommon "github.com/apache/answer/internal/service/user_common"
+	"github.com/apache/answer/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm/schemas"
+)
+
+func newSearchRepoForTest() (searchcommon.SearchRepo, *search_parser.SearchParser) {
+	uniqueIDRepo := unique.NewUniqueIDRepo(testDataSource)
+	siteInfoService := siteinfo_common.NewSiteInfoCommonService(site_info.NewSiteInfo(testDataSource))
+	tagCommonService := tagcommon.NewTagCommonService(
+		tag_common.NewTagCommonRepo(testDataSource, uniqueIDRepo),
+		tag.NewTagRelRepo(testDataSource, uniqueIDRepo),
+		tag.NewTagRepo(testDataSource, uniqueIDRepo),
+		nil,
+		siteInfoService,
+		nil,
+	)
+	userCommon := usercommon.NewUserCommon(user.NewUserRepo(testDataSource), nil, nil, siteInfoService)
+	return search_common.NewSearchRepo(testDataSource, uniqueIDRepo, userCommon, tagCommonService),
+		search_parser.NewSearchParser(tagCommonService, userCommon, newForumRepoForTest())
+}
+
+func parseSearchForTest(t *testing.T, parser *search_parser.SearchParser, query string) *schema.SearchCondition {
+	t.Helper()
+	cond, err := parser.ParseStructure(context.TODO(), &schema.SearchDTO{Query: query, UserID: "1"})
+	require.NoError(t, err)
+	return cond
+}
+
+func Test_searchRepo_FullText(t *testing.T) {
+	ctx := context.TODO()
+	uniqueIDRepo := unique.NewUniqueIDRepo(testDataSource)
+	searchRepo, parser := newSearchRepoForTest()
+
+	questionID, err := uniqueIDRepo.GenUniqueIDStr(ctx, entity.Question{}.TableName())
+	require.NoError(t, err)
+	_, err = testDataSource.DB.Context(ctx).Insert(&entity.Question{
+		ID:               questionID,
+		UserID:           "1",
+		Title:            "Quillback migrations stall on startup",
+		OriginalText:     "Every quillback migration hangs until the worker is restarted.",
+		ParsedText:       "<p>Every quillback migration hangs until the worker is restarted.</p>",
+		Status:           entity.QuestionStatusAvailable,
+		Show:             entity.QuestionShow,
+		Pin:              entity.QuestionUnPin,
+		AcceptedAnswerID: "0",
+		PostUpdateTime:   time.Now(),
+	})
+	require.NoError(t, err)
+	answerID, err := uniqueIDRepo.GenUniqueIDStr(ctx, entity.Answer{}.TableName())
+	require.NoError(t, err)
+	_, err = testDataSource.DB.Context(ctx).Insert(&entity.Answer{
+		ID:           answerID,
+		QuestionID:   questionID,
+		UserID:       "1",
+		OriginalText: "Raise the lock timeout of the quillback workers.",
+		ParsedText:   "<p>Raise the lock timeout of the quillback workers.</p>",
+		Status:       entity.AnswerStatusAvailable,
+		Accepted:     schema.AnswerAcceptedFailed,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(answerID).Delete(&entity.Answer{})
+		_, _ = testDataSource.DB.Context(ctx).ID(questionID).Delete(&entity.Question{})
+	})
+
+	resp, total, err := searchRepo.SearchContents(ctx, parseSearchForTest(t, parser, "quillback"), 1, 10, "relevance")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, resp, 2)
+	assert.Equal(t, "question", resp[0].ObjectType)
+	assert.Equal(t, "answer", resp[1].ObjectType)
+	assert.Equal(t, "<mark>Quillback</mark> migrations stall on startup", resp[0].Object.TitleHighlight)
+	assert.Equal(t, []string{"Every <mark>quillback</mark> migration hangs until the worker is restarted."},
+		resp[0].Object.Snippets)
+
+	resp, total, err = searchRepo.SearchQuestions(ctx,
+		parseSearchForTest(t, parser, "quillback OR nonexistentword"), 1, 10, "newest")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, resp, 1)
+	assert.Equal(t, questionID, resp[0].Object.ID)
+
+	resp, total, err = searchRepo.SearchAnswers(ctx,
+		parseSearchForTest(t, parser, "timeout inquestion:"+questionID), 1, 10, "relevance")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, resp, 1)
+	assert.Equal(t, answerID, resp[0].Object.ID)
+	assert.Equal(t, []string{"Raise the lock <mark>timeout</mark> of the quillback workers."}, resp[0].Object.Snippets)
+
+	// the highlights of a search plugin are kept, sanitized
+	cond := parseSearchForTest(t, parser, "quillback")
+	resp, err = searchRepo.ParseSearchPluginResult(ctx, []plugin.SearchResult{
+		{ID: questionID, Type: "question", Highlights: map[string][]string{
+			plugin.SearchHighlightContent: {"every <mark>quillback</mark> <b>migration</b>"},
+		}},
+		{ID: answerID, Type: "answer"},
+	}, cond)
+	require.NoError(t, err)
+	require.Len(t, resp, 2)
+	assert.Equal(t, []string{"every <mark>quillback</mark> &lt;b&gt;migration&lt;/b&gt;"}, resp[0].Object.Snippets)
+	assert.Equal(t, "<mark>Quillback</mark> migrations stall on startup", resp[0].Object.TitleHighlight)
+	assert.Equal(t, []string{"Raise the lock timeout of the <mark>quillback</mark> workers."}, resp[1].Object.Snippets)
+
+	// the indexes follow the updates of the content
+	_, err = testDataSource.DB.Context(ctx).ID(questionID).Cols("title").
+		Update(&entity.Question{Title: "Pelican migrations stall on startup 数据库迁移卡住了"})
+	require.NoError(t, err)
+	_, total, err = searchRepo.SearchQuestions(ctx, parseSearchForTest(t, parser, "pelican"), 1, 10, "relevance")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	// the words of the scripts without spaces are found inside the text
+	resp, total, err = searchRepo.SearchQuestions(ctx, parseSearchForTest(t, parser, "迁移"), 1, 10, "relevance")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, resp, 1)
+	assert.Equal(t, questionID, resp[0].Object.ID)
+
+	if testDataSource.DB.Dialect().URI().DBType != schemas.MYSQL {
+		// PostgreSQL and SQLite stem the words, MySQL has no stemmer
+		_, total, err = searchRepo.SearchQuestions(ctx, parseSearchForTest(t, parser, "stalling"), 1, 10, "relevance")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+	}
+}
+
+func Test_searchRepo_QueryLanguage(t *testing.T) {
+	ctx := context.TODO()
+	uniqueIDRepo := unique.NewUniqueIDRepo(testDataSource)
+	searchRepo, parser := newSearchRepoForTest()
+
+	questionIDs := make([]string, 0)
+	for _, title := range []string{"Walrus cache misses after deploy", "Walrus cache is flaky on arm"} {
+		questionID, err := uniqueIDRepo.GenUniqueIDStr(ctx, entity.Question{}.TableName())
+		require.NoError(t, err)
+		_, err = testDataSource.DB.Context(ctx).Insert(&entity.Question{
+			ID:               questionID,
+			UserID:           "1",
+			Title:            title,
+			CreatedAt:        time.Now(),
+			OriginalText:     "The walrus cache keeps missing the lock timeout window.",
+			ParsedText:       "<p>The walrus cache keeps missing the lock timeout window.</p>",
+			Status:           entity.QuestionStatusAvailable,
+			Show:             entity.QuestionShow,
+			Pin:              entity.QuestionUnPin,
+			AcceptedAnswerID: "0",
+			PostUpdateTime:   time.Now(),
+		})
+		require.NoError(t, err)
+		questionIDs = append(questionIDs, questionID)
+	}
+	t.Cleanup(func() {
+		for _, questionID := range questionIDs {
+			_, _ = testDataSource.DB.Context(ctx).ID(questionID).Delete(&entity.Question{})
+		}
+	})
+
+	search := func(query string) (ids []string) {
+		resp, _, err := searchRepo.SearchQuestions(ctx, parseSearchForTest(t, parser, query), 1, 10, "newest")
+		require.NoError(t, err)
+		for _, item := range resp {
+			ids = append(ids, item.Object.ID)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, questionIDs, search("walrus cache"))
+	assert.Equal(t, []string{questionIDs[0]}, search("walrus -flaky"))
+	assert.Equal(t, []string{questionIDs[1]}, search(`walrus (flaky OR qwxyzzy)`))
+	assert.ElementsMatch(t, questionIDs, search(`"lock timeout" walrus`))
+	assert.Empty(t, search(`"timeout lock" walrus`))
+	assert.ElementsMatch(t, questionIDs, search("walrus created:>=2000-01-01"))
+	assert.Empty(t, search("walrus created:<2000-01-01"))
+
+	// topics are searched by their title and posts, within a category
+	forumRepo := newForumRepoForTest()
+	category, topic := createTopicFixture(t, forumRepo)
+	topic.Title = "Walrus meetup schedule"
+	require.NoError(t, forumRepo.UpdateTopic(ctx, topic, "title"))
+	post := &entity.Post{TopicID: topic.ID, UserID: "1", Original: "Bring your own herring.",
+		Parsed: "<p>Bring your own herring.</p>", MergeState: entity.PostMergeStateActive, Status: 1}
+	require.NoError(t, forumRepo.AddPost(ctx, post))
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).ID(post.ID).Delete(&entity.Post{})
+	})
+
+	cond := parseSearchForTest(t, parser, "herring category:"+category.Slug)
+	assert.True(t, cond.SearchTopic())
+	resp, total, err := searchRepo.SearchTopics(ctx, cond, 1, 10, "relevance")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, resp, 1)
+	assert.Equal(t, topic.ID, resp[0].Object.ID)
+	assert.Equal(t, "Walrus meetup schedule", resp[0].Object.Title)
+	assert.Contains(t, resp[0].Object.Excerpt, "herring")
+
+	_, total, err = searchRepo.SearchTopics(ctx, parseSearchForTest(t, parser, "is:topic walrus -herring"), 1, 10, "newest")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+}
+
+func Test_searchRepo_Facets(t *testing.T) {
+	ctx := context.TODO()
+	uniqueIDRepo := unique.NewUniqueIDRepo(testDataSource)
+	searchRepo, parser := newSearchRepoForTest()
+
+	facetTag := &entity.Tag{
+		SlugName:     "narwhal-facet",
+		DisplayName:  "Narwhal",
+		OriginalText: "narwhal",
+		ParsedText:   "<p>narwhal</p>",
+		Status:       entity.TagStatusAvailable,
+	}
+	require.NoError(t, tag_common.NewTagCommonRepo(testDataSource, uniqueIDRepo).AddTagList(ctx, []*entity.Tag{facetTag}))
+
+	questionIDs := make([]string, 0)
+	for range 2 {
+		questionID, err := uniqueIDRepo.GenUniqueIDStr(ctx, entity.Question{}.TableName())
+		require.NoError(t, err)
+		questionIDs = append(questionIDs, questionID)
+	}
+	answerID, err := uniqueIDRepo.GenUniqueIDStr(ctx, entity.Answer{}.TableName())
+	require.NoError(t, err)
+	for i, questionID := range questionIDs {
+		acceptedAnswerID := "0"
+		if i == 0 {
+			acceptedAnswerID = answerID
+		}
+		_, err = testDataSource.DB.Context(ctx).Insert(&entity.Question{
+			ID:               questionID,
+			UserID:           "1",
+			Title:            "Narwhal tusk sensor calibration",
+			CreatedAt:        time.Now(),
+			OriginalText:     "How is the narwhal sensor calibrated?",
+			ParsedText:       "<p>How is the narwhal sensor calibrated?</p>",
+			Status:           entity.QuestionStatusAvailable,
+			Show:             entity.QuestionShow,
+			Pin:              entity.QuestionUnPin,
+			AcceptedAnswerID: acceptedAnswerID,
+			PostUpdateTime:   time.Now(),
+		})
+		require.NoError(t, err)
+	}
+	_, err = testDataSource.DB.Context(ctx).Insert(&entity.Answer{
+		ID:           answerID,
+		QuestionID:   questionIDs[0],
+		UserID:       "1",
+		OriginalText: "Calibrate the narwhal sensor in cold water.",
+		ParsedText:   "<p>Calibrate the narwhal sensor in cold water.</p>",
+		Status:       entity.AnswerStatusAvailable,
+		Accepted:     schema.AnswerAcceptedEnable,
+	})
+	require.NoError(t, err)
+	_, err = testDataSource.DB.Context(ctx).Insert(&entity.TagRel{
+		TagID:    facetTag.ID,
+		ObjectID: questionIDs[0],
+		Status:   entity.TagRelStatusAvailable,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testDataSource.DB.Context(ctx).Where("tag_id = ?", facetTag.ID).Delete(&entity.TagRel{})
+		_, _ = testDataSource.DB.Context(ctx).ID(facetTag.ID).Delete(&entity.Tag{})
+		_, _ = testDataSource.DB.Context(ctx).ID(answerID).Delete(&entity.Answer{})
+		for _, questionID := range questionIDs {
+			_, _ = testDataSource.DB.Context(ctx).ID(questionID).Delete(&entity.Question{})
+		}
+	})
+
+	facets, err := searchRepo.SearchFacets(ctx, parseSearchForTest(t, parser, "narwhal"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"question": 2, "answer": 1}, facets.Types)
+	assert.Equal(t, map[string]int64{plugin.SearchFacetAccepted: 2, plugin.SearchFacetNotAccepted: 1}, facets.Accepted)
+	assert.Equal(t, map[string]int64{"day": 3, "week": 3, "month": 3, "year": 3}, facets.Created)
+	assert.Equal(t, map[string]int64{facetTag.ID: 2}, facets.Tags)
+
+	resp, err := searchRepo.ParseSearchFacets(ctx, facets)
+	require.NoError(t, err)
+	require.Len(t, resp.Tags, 1)
+	assert.Equal(t, &schema.SearchTagFacet{SlugName: "narwhal-facet", DisplayName: "Narwhal", Count: 2}, resp.Tags[0])
+	assert.Equal(t, []*schema.SearchFacet{{Value: "question", Count: 2}, {Value: "answer", Count: 1}}, resp.Types)
+
+	// the facets follow the filters of the search
+	facets, err = searchRepo.SearchFacets(ctx, parseSearchForTest(t, parser, "narwhal hasaccepted:no"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"question": 1}, facets.Types)
+	assert.Equal(t, map[string]int64{plugin.SearchFacetNotAccepted: 1}, facets.Accepted)
+	assert.Empty(t, facets.Tags)
+}
@@ -0,0 +1,197 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package saved_search
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/answer/internal/base/data"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// SavedSearchRepo saved search repository
+type SavedSearchRepo interface {
+	AddSavedSearch(ctx context.Context, savedSearch *entity.SavedSearch) error
+	UpdateSavedSearch(ctx context.Context, savedSearch *entity.SavedSearch, resetMatches bool) error
+	RemoveSavedSearch(ctx context.Context, id int) error
+	GetSavedSearch(ctx context.Context, id int) (*entity.SavedSearch, bool, error)
+	GetSavedSearchList(ctx context.Context, userID string) ([]*entity.SavedSearch, error)
+	CountSavedSearches(ctx context.Context, userID string) (int64, error)
+	GetSavedSearchesToRun(ctx context.Context, before time.Time, limit int) ([]*entity.SavedSearch, error)
+	UpdateLastRunAt(ctx context.Context, id int, lastRunAt time.Time) error
+	GetSentObjectIDs(ctx context.Context, savedSearchID int, objectIDs []string) (map[string]bool, error)
+	AddMatches(ctx context.Context, savedSearchID int, objectIDs []string) error
+}
+
+type savedSearchRepo struct {
+	data *data.Data
+}
+
+// NewSavedSearchRepo new repository
+func NewSavedSearchRepo(data *data.Data) SavedSearchRepo {
+	return &savedSearchRepo{
+		data: data,
+	}
+}
+
+// AddSavedSearch adds a saved search, its id is set
+func (r *savedSearchRepo) AddSavedSearch(ctx context.Context, savedSearch *entity.SavedSearch) error {
+	_, err := r.data.DB.Context(ctx).Insert(savedSearch)
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// UpdateSavedSearch updates the name, query and channels of a saved search,
+// the results sent already are forgotten when resetMatches is set
+func (r *savedSearchRepo) UpdateSavedSearch(ctx context.Context, savedSearch *entity.SavedSearch,
+	resetMatches bool) error {
+	_, err := r.data.DB.Transaction(func(session *xorm.Session) (result any, err error) {
+		session = session.Context(ctx)
+		_, err = session.ID(savedSearch.ID).
+			Cols("name", "query", "notify_inbox", "notify_email").
+			Update(savedSearch)
+		if err != nil || !resetMatches {
+			return nil, err
+		}
+		_, err = session.Where(builder.Eq{"saved_search_id": savedSearch.ID}).Delete(&entity.SavedSearchMatch{})
+		return nil, err
+	})
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// RemoveSavedSearch removes a saved search and the results sent for it
+func (r *savedSearchRepo) RemoveSavedSearch(ctx context.Context, id int) error {
+	_, err := r.data.DB.Transaction(func(session *xorm.Session) (result any, err error) {
+		session = session.Context(ctx)
+		if _, err = session.ID(id).Delete(&entity.SavedSearch{}); err != nil {
+			return nil, err
+		}
+		_, err = session.Where(builder.Eq{"saved_search_id": id}).Delete(&entity.SavedSearchMatch{})
+		return nil, err
+	})
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// GetSavedSearch gets a saved search
+func (r *savedSearchRepo) GetSavedSearch(ctx context.Context, id int) (*entity.SavedSearch, bool, error) {
+	savedSearch := &entity.SavedSearch{}
+	exist, err := r.data.DB.Context(ctx).ID(id).Get(savedSearch)
+	if err != nil {
+		return nil, false, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return savedSearch, exist, nil
+}
+
+// GetSavedSearchList gets the saved searches of the user, the latest first
+func (r *savedSearchRepo) GetSavedSearchList(ctx context.Context, userID string) ([]*entity.SavedSearch, error) {
+	list := make([]*entity.SavedSearch, 0)
+	err := r.data.DB.Context(ctx).Where(builder.Eq{"user_id": userID}).Desc("id").Find(&list)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return list, nil
+}
+
+// CountSavedSearches counts the saved searches of the user
+func (r *savedSearchRepo) CountSavedSearches(ctx context.Context, userID string) (int64, error) {
+	count, err := r.data.DB.Context(ctx).Where(builder.Eq{"user_id": userID}).Count(&entity.SavedSearch{})
+	if err != nil {
+		return 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return count, nil
+}
+
+// GetSavedSearchesToRun gets at most limit of the saved searches with a channel on that last ran before the time,
+// those which ran the longest ago first
+func (r *savedSearchRepo) GetSavedSearchesToRun(ctx context.Context, before time.Time, limit int) (
+	[]*entity.SavedSearch, error) {
+	list := make([]*entity.SavedSearch, 0)
+	err := r.data.DB.Context(ctx).
+		Where(builder.Lt{"last_run_at": before}).
+		And(builder.Or(builder.Eq{"notify_inbox": true}, builder.Eq{"notify_email": true})).
+		Asc("last_run_at", "id").
+		Limit(limit).
+		Find(&list)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return list, nil
+}
+
+// UpdateLastRunAt updates the time the saved search last ran
+func (r *savedSearchRepo) UpdateLastRunAt(ctx context.Context, id int, lastRunAt time.Time) error {
+	_, err := r.data.DB.Context(ctx).ID(id).Cols("last_run_at").
+		Update(&entity.SavedSearch{LastRunAt: lastRunAt})
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// GetSentObjectIDs gets which of the objects were sent for the saved search already
+func (r *savedSearchRepo) GetSentObjectIDs(ctx context.Context, savedSearchID int, objectIDs []string) (
+	map[string]bool, error) {
+	sent := make(map[string]bool)
+	if len(objectIDs) == 0 {
+		return sent, nil
+	}
+	matches := make([]*entity.SavedSearchMatch, 0)
+	err := r.data.DB.Context(ctx).
+		Where(builder.Eq{"saved_search_id": savedSearchID}).
+		And(builder.In("object_id", objectIDs)).
+		Cols("object_id").
+		Find(&matches)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	for _, match := range matches {
+		sent[match.ObjectID] = true
+	}
+	return sent, nil
+}
+
+// AddMatches records the objects as sent for the saved search
+func (r *savedSearchRepo) AddMatches(ctx context.Context, savedSearchID int, objectIDs []string) error {
+	if len(objectIDs) == 0 {
+		return nil
+	}
+	matches := make([]*entity.SavedSearchMatch, 0, len(objectIDs))
+	for _, objectID := range objectIDs {
+		matches = append(matches, &entity.SavedSearchMatch{SavedSearchID: savedSearchID, ObjectID: objectID})
+	}
+	_, err := r.data.DB.Context(ctx).Insert(matches)
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
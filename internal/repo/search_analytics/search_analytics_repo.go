@@ -0,0 +1,188 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package search_analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/answer/internal/base/data"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// SearchAnalyticsRepo search log repository
+type SearchAnalyticsRepo interface {
+	AddSearchLog(ctx context.Context, searchLog *entity.SearchLog) error
+	UpdateSearchLogClick(ctx context.Context, id int64, userHash, objectID string, position int) (bool, error)
+	GetSearchLogsBefore(ctx context.Context, before time.Time, limit int) ([]*entity.SearchLog, error)
+	GetSearchQueryStats(ctx context.Context, start, end time.Time, startDate, endDate, countColumn string, limit int) (
+		[]*entity.SearchLogDaily, error)
+	GetSearchDayStats(ctx context.Context, start, end time.Time, startDate, endDate string) (
+		[]*entity.SearchLogDaily, error)
+	RollUpSearchLogs(ctx context.Context, dailies []*entity.SearchLogDaily, logIDs []int64) error
+}
+
+type searchAnalyticsRepo struct {
+	data *data.Data
+}
+
+// NewSearchAnalyticsRepo new repository
+func NewSearchAnalyticsRepo(data *data.Data) SearchAnalyticsRepo {
+	return &searchAnalyticsRepo{
+		data: data,
+	}
+}
+
+// AddSearchLog adds a search log, its id is set
+func (r *searchAnalyticsRepo) AddSearchLog(ctx context.Context, searchLog *entity.SearchLog) error {
+	_, err := r.data.DB.Context(ctx).Insert(searchLog)
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
+
+// UpdateSearchLogClick records the first result clicked in a search of the user,
+// it returns false when the search is not found or a result was clicked already
+func (r *searchAnalyticsRepo) UpdateSearchLogClick(ctx context.Context, id int64, userHash, objectID string,
+	position int) (bool, error) {
+	affected, err := r.data.DB.Context(ctx).
+		Where(builder.Eq{"id": id, "user_hash": userHash, "clicked_position": 0}).
+		Cols("clicked_object_id", "clicked_position").
+		Update(&entity.SearchLog{ClickedObjectID: objectID, ClickedPosition: position})
+	if err != nil {
+		return false, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return affected > 0, nil
+}
+
+// GetSearchLogsBefore gets at most limit of the oldest search logs created before the time
+func (r *searchAnalyticsRepo) GetSearchLogsBefore(ctx context.Context, before time.Time, limit int) (
+	[]*entity.SearchLog, error) {
+	list := make([]*entity.SearchLog, 0)
+	err := r.data.DB.Context(ctx).
+		Where(builder.Lt{"created_at": before}).
+		Asc("id").
+		Limit(limit).
+		Find(&list)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return list, nil
+}
+
+// GetSearchQueryStats sums the stats of each query in the search logs created in [start, end) and in the daily stats
+// from startDate to endDate, both included. It returns the first limit queries by the count of the column above zero.
+func (r *searchAnalyticsRepo) GetSearchQueryStats(ctx context.Context, start, end time.Time,
+	startDate, endDate, countColumn string, limit int) ([]*entity.SearchLogDaily, error) {
+	list := make([]*entity.SearchLogDaily, 0)
+	sql := r.statsSQL("`query`", "`query`") +
+		fmt.Sprintf(" HAVING SUM(`%[1]s`) > 0 ORDER BY `%[1]s` DESC, `query` ASC LIMIT %[2]d", countColumn, limit)
+	err := r.data.DB.Context(ctx).SQL(sql, start, end, startDate, endDate).Find(&list)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return list, nil
+}
+
+// GetSearchDayStats sums the stats of each day in the search logs created in [start, end) and in the daily stats
+// from startDate to endDate, both included
+func (r *searchAnalyticsRepo) GetSearchDayStats(ctx context.Context, start, end time.Time, startDate, endDate string) (
+	[]*entity.SearchLogDaily, error) {
+	list := make([]*entity.SearchLogDaily, 0)
+	sql := r.statsSQL("`day`", r.createdDay()) + " ORDER BY `day` ASC"
+	err := r.data.DB.Context(ctx).SQL(sql, start, end, startDate, endDate).Find(&list)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return list, nil
+}
+
+// statsSQL sums the stats of the search logs and of the daily stats grouped by the column,
+// logColumn is the column, or the expression giving it, in the search logs
+func (r *searchAnalyticsRepo) statsSQL(column, logColumn string) string {
+	return fmt.Sprintf("SELECT %[1]s, SUM(`searches`) AS `searches`, SUM(`zero_results`) AS `zero_results`, "+
+		"SUM(`clicks`) AS `clicks` FROM ("+
+		"SELECT %[2]s AS %[1]s, COUNT(*) AS `searches`, "+
+		"SUM(CASE WHEN `result_count` = 0 THEN 1 ELSE 0 END) AS `zero_results`, "+
+		"SUM(CASE WHEN `clicked_position` > 0 THEN 1 ELSE 0 END) AS `clicks` "+
+		"FROM `search_log` WHERE `created_at` >= ? AND `created_at` < ? GROUP BY %[2]s "+
+		"UNION ALL "+
+		"SELECT %[1]s, SUM(`searches`), SUM(`zero_results`), SUM(`clicks`) "+
+		"FROM `search_log_daily` WHERE `day` >= ? AND `day` <= ? GROUP BY %[1]s"+
+		") `stats` GROUP BY %[1]s", column, logColumn)
+}
+
+// createdDay the local date the search log is created, in the format of the day of the daily stats
+func (r *searchAnalyticsRepo) createdDay() string {
+	switch r.data.DB.Dialect().URI().DBType {
+	case schemas.MYSQL:
+		return "DATE_FORMAT(`created_at`, '%Y-%m-%d')"
+	case schemas.POSTGRES:
+		return "TO_CHAR(`created_at`, 'YYYY-MM-DD')"
+	}
+	// SQLite keeps the time as text starting with the date
+	return "SUBSTR(`created_at`, 1, 10)"
+}
+
+// RollUpSearchLogs adds the daily stats to those of the same day and query and deletes the search logs they count
+func (r *searchAnalyticsRepo) RollUpSearchLogs(ctx context.Context, dailies []*entity.SearchLogDaily,
+	logIDs []int64) error {
+	_, err := r.data.DB.Transaction(func(session *xorm.Session) (result any, err error) {
+		session = session.Context(ctx)
+		for _, daily := range dailies {
+			exist := &entity.SearchLogDaily{}
+			has, err := session.Where(builder.Eq{"day": daily.Day, "query": daily.Query}).Get(exist)
+			if err != nil {
+				return nil, err
+			}
+			if !has {
+				if _, err = session.Insert(daily); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			_, err = session.ID(exist.ID).
+				Incr("searches", daily.Searches).
+				Incr("zero_results", daily.ZeroResults).
+				Incr("clicks", daily.Clicks).
+				Update(&entity.SearchLogDaily{})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(logIDs) > 0 {
+			if _, err = session.In("id", logIDs).Delete(&entity.SearchLog{}); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return nil
+}
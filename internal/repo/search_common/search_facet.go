@@ -0,0 +1,224 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package search_common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/pkg/converter"
+	"github.com/apache/answer/plugin"
+	"github.com/segmentfault/pacman/errors"
+)
+
+// searchFacetTagLimit the tags with the most results returned in the tag facet
+const searchFacetTagLimit = 10
+
+// the columns the facets are counted by, an answer has the tags of its question
+var (
+	qFacetFields = []string{
+		"`question`.`id` as `question_id`",
+		"'" + constant.QuestionObjectType + "' as `object_type`",
+		"CASE WHEN `question`.`accepted_answer_id` > 0 THEN 2 ELSE 0 END as `accepted`",
+		"`question`.`created_at` as `created_at`",
+	}
+	aFacetFields = []string{
+		"`answer`.`question_id` as `question_id`",
+		"'" + constant.AnswerObjectType + "' as `object_type`",
+		"`answer`.`adopted` as `accepted`",
+		"`answer`.`created_at` as `created_at`",
+	}
+	tFacetFields = []string{
+		"`topics`.`id` as `question_id`",
+		"'" + constant.TopicObjectType + "' as `object_type`",
+		"CASE WHEN `topics`.`solved_post_id` > 0 THEN 2 ELSE 0 END as `accepted`",
+		"`topics`.`created_at` as `created_at`",
+	}
+)
+
+// SearchFacets counts the results of the condition by tag, type, accepted state and creation date.
+// When all the types are searched the topics are counted too, so they can be searched next.
+func (sr *searchRepo) SearchFacets(ctx context.Context, cond *schema.SearchCondition) (
+	facets *plugin.SearchFacets, err error) {
+	facets = &plugin.SearchFacets{
+		Tags:     make(map[string]int64),
+		Types:    make(map[string]int64),
+		Accepted: make(map[string]int64),
+		Created:  make(map[string]int64),
+	}
+	set, args, err := sr.facetQuery(cond)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+
+	rows, err := sr.queryFacet(ctx, "SELECT `object_type`, COUNT(*) AS `total` FROM "+set+" `f` GROUP BY `object_type`", args)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		facets.Types[string(r["object_type"])] = converter.StringToInt64(string(r["total"]))
+	}
+	if cond.SearchAll() {
+		b, topicArgs := topicQuery(cond, tFacetFields, nil)
+		topicSQL, _, err := b.ToSQL()
+		if err != nil {
+			return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+		}
+		rows, err = sr.queryFacet(ctx, "SELECT COUNT(*) AS `total` FROM ("+topicSQL+") `f`", topicArgs)
+		if err != nil {
+			return nil, err
+		}
+		if total := converter.StringToInt64(string(rows[0]["total"])); total > 0 {
+			facets.Types[constant.TopicObjectType] = total
+		}
+	}
+
+	rows, err = sr.queryFacet(ctx, "SELECT `accepted`, COUNT(*) AS `total` FROM "+set+" `f` GROUP BY `accepted`", args)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		state := plugin.SearchFacetNotAccepted
+		if string(r["accepted"]) == "2" {
+			state = plugin.SearchFacetAccepted
+		}
+		facets.Accepted[state] += converter.StringToInt64(string(r["total"]))
+	}
+
+	buckets := plugin.SearchCreatedBuckets(time.Now())
+	bucketFields := make([]string, 0, len(buckets))
+	bucketArgs := make([]any, 0, len(buckets)+len(args))
+	for i, bucket := range buckets {
+		bucketFields = append(bucketFields, fmt.Sprintf("SUM(CASE WHEN `created_at` >= ? THEN 1 ELSE 0 END) AS `bucket%d`", i))
+		bucketArgs = append(bucketArgs, bucket.Since)
+	}
+	rows, err = sr.queryFacet(ctx, "SELECT "+strings.Join(bucketFields, ", ")+" FROM "+set+" `f`",
+		append(bucketArgs, args...))
+	if err != nil {
+		return nil, err
+	}
+	for i, bucket := range buckets {
+		if total := converter.StringToInt64(string(rows[0][fmt.Sprintf("bucket%d", i)])); total > 0 {
+			facets.Created[bucket.Key] = total
+		}
+	}
+
+	rows, err = sr.queryFacet(ctx, "SELECT `tr`.`tag_id` AS `tag_id`, COUNT(*) AS `total` FROM "+set+" `f` "+
+		"INNER JOIN `tag_rel` `tr` ON `tr`.`object_id` = `f`.`question_id` WHERE `tr`.`status` = ? "+
+		"GROUP BY `tr`.`tag_id` ORDER BY `total` DESC, `tr`.`tag_id` ASC "+fmt.Sprintf("LIMIT %d", searchFacetTagLimit),
+		append(append([]any{}, args...), entity.TagRelStatusAvailable))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		facets.Tags[string(r["tag_id"])] = converter.StringToInt64(string(r["total"]))
+	}
+	return facets, nil
+}
+
+// ParseSearchFacets formats the facets of the built-in search or of a search plugin,
+// the unknown tags are left out
+func (sr *searchRepo) ParseSearchFacets(ctx context.Context, facets *plugin.SearchFacets) (
+	resp *schema.SearchFacets, err error) {
+	resp = &schema.SearchFacets{
+		Tags:     make([]*schema.SearchTagFacet, 0),
+		Types:    make([]*schema.SearchFacet, 0),
+		Accepted: make([]*schema.SearchFacet, 0),
+		Created:  make([]*schema.SearchFacet, 0),
+	}
+	for _, objectType := range []string{constant.QuestionObjectType, constant.AnswerObjectType, constant.TopicObjectType} {
+		if count := facets.Types[objectType]; count > 0 {
+			resp.Types = append(resp.Types, &schema.SearchFacet{Value: objectType, Count: count})
+		}
+	}
+	for _, state := range []string{plugin.SearchFacetAccepted, plugin.SearchFacetNotAccepted} {
+		if count := facets.Accepted[state]; count > 0 {
+			resp.Accepted = append(resp.Accepted, &schema.SearchFacet{Value: state, Count: count})
+		}
+	}
+	for _, bucket := range plugin.SearchCreatedBuckets(time.Now()) {
+		if count := facets.Created[bucket.Key]; count > 0 {
+			resp.Created = append(resp.Created, &schema.SearchFacet{Value: bucket.Key, Count: count})
+		}
+	}
+
+	if len(facets.Tags) == 0 {
+		return resp, nil
+	}
+	tagIDs := make([]string, 0, len(facets.Tags))
+	for tagID := range facets.Tags {
+		tagIDs = append(tagIDs, tagID)
+	}
+	tags, err := sr.tagCommon.GetTagListByIDs(ctx, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		resp.Tags = append(resp.Tags, &schema.SearchTagFacet{
+			SlugName:    tag.SlugName,
+			DisplayName: tag.DisplayName,
+			Count:       facets.Tags[tag.ID],
+		})
+	}
+	sort.SliceStable(resp.Tags, func(i, j int) bool {
+		if resp.Tags[i].Count != resp.Tags[j].Count {
+			return resp.Tags[i].Count > resp.Tags[j].Count
+		}
+		return resp.Tags[i].SlugName < resp.Tags[j].SlugName
+	})
+	if len(resp.Tags) > searchFacetTagLimit {
+		resp.Tags = resp.Tags[:searchFacetTagLimit]
+	}
+	return resp, nil
+}
+
+// facetQuery builds the rows matching the condition with the facet fields, as a parenthesized subquery
+func (sr *searchRepo) facetQuery(cond *schema.SearchCondition) (set string, args []any, err error) {
+	switch {
+	case cond.SearchQuestion():
+		b, args := sr.questionQuery(cond, qFacetFields, nil)
+		set, _, err = b.ToSQL()
+		return "(" + set + ")", args, err
+	case cond.SearchAnswer():
+		b, args := sr.answerQuery(cond, aFacetFields, nil)
+		set, _, err = b.ToSQL()
+		return "(" + set + ")", args, err
+	case cond.SearchTopic():
+		b, args := topicQuery(cond, tFacetFields, nil)
+		set, _, err = b.ToSQL()
+		return "(" + set + ")", args, err
+	}
+	return sr.contentsQuery(cond, qFacetFields, aFacetFields, nil, nil)
+}
+
+func (sr *searchRepo) queryFacet(ctx context.Context, sql string, args []any) ([]map[string][]byte, error) {
+	rows, err := sr.data.DB.Context(ctx).Query(append([]any{sql}, args...)...)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return rows, nil
+}
@@ -0,0 +1,260 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package search_common
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/apache/answer/plugin"
+	"xorm.io/builder"
+	"xorm.io/xorm/schemas"
+)
+
+const (
+	// postgresTextSearchConfig must be the configuration the GIN indexes were created with
+	postgresTextSearchConfig = "english"
+	// mysqlMinTokenSize the default innodb_ft_min_token_size, shorter words are not indexed
+	mysqlMinTokenSize = 3
+)
+
+// fullTextColumns the columns indexed for full-text search of each table, the same as the migration creates
+var fullTextColumns = map[string][]string{
+	"question": {"title", "original_text"},
+	"answer":   {"original_text"},
+}
+
+// mysqlStopWords the default InnoDB full-text stop words, they are not indexed
+var mysqlStopWords = map[string]bool{
+	"a": true, "about": true, "an": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"com": true, "de": true, "en": true, "for": true, "from": true, "how": true, "i": true, "in": true,
+	"is": true, "it": true, "la": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "what": true, "when": true, "where": true, "who": true,
+	"will": true, "with": true, "und": true, "www": true,
+}
+
+// fullTextEngine matches and ranks the words with the full-text search of a database
+type fullTextEngine interface {
+	// termCond returns the condition of the rows of the table containing the word,
+	// or the words next to each other in order for a phrase
+	termCond(table, text string, phrase bool) (cond builder.Cond, args []any)
+	// addRelevanceField appends the relevance of the rows of the table to the fields, the higher the better
+	addRelevanceField(table string, words, fields []string) (res []string, args []any)
+}
+
+// newFullTextEngine returns the full-text search of the database, LIKE is used when it has none
+func newFullTextEngine(dbType schemas.DBType) fullTextEngine {
+	switch dbType {
+	case schemas.MYSQL:
+		return &mysqlFullText{}
+	case schemas.POSTGRES:
+		return &postgresFullText{}
+	case schemas.SQLITE:
+		return &sqliteFullText{}
+	}
+	return &likeFullText{}
+}
+
+func (sr *searchRepo) fullText() fullTextEngine {
+	return newFullTextEngine(sr.data.DB.Dialect().URI().DBType)
+}
+
+// exprCond returns the condition of the expression, leafCond gives the condition of its terms and phrases.
+// The arguments are in the order of the placeholders.
+func exprCond(expr *plugin.SearchExpr, leafCond func(text string, phrase bool) (builder.Cond, []any)) (
+	cond builder.Cond, args []any) {
+	switch expr.Kind {
+	case plugin.SearchExprTerm, plugin.SearchExprPhrase:
+		return leafCond(expr.Text, expr.Kind == plugin.SearchExprPhrase)
+	case plugin.SearchExprNot:
+		cond, args = exprCond(expr.Children[0], leafCond)
+		return builder.Not{cond}, args
+	}
+	conds := make([]builder.Cond, 0, len(expr.Children))
+	for _, child := range expr.Children {
+		childCond, childArgs := exprCond(child, leafCond)
+		conds = append(conds, childCond)
+		args = append(args, childArgs...)
+	}
+	if expr.Kind == plugin.SearchExprOr {
+		return builder.Or(conds...), args
+	}
+	return builder.And(conds...), args
+}
+
+// hasCJK reports whether the text has Chinese, Japanese or Korean characters.
+// The full-text parsers split the words on spaces, which these scripts do not use, so LIKE is used for them instead.
+func hasCJK(texts ...string) bool {
+	for _, text := range texts {
+		for _, r := range text {
+			if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func qualifiedColumns(table string) []string {
+	columns := make([]string, 0, len(fullTextColumns[table]))
+	for _, column := range fullTextColumns[table] {
+		columns = append(columns, "`"+table+"`.`"+column+"`")
+	}
+	return columns
+}
+
+// likeFullText scans the columns with LIKE and counts the occurrences of the words as relevance
+type likeFullText struct{}
+
+func (e *likeFullText) termCond(table, text string, _ bool) (cond builder.Cond, args []any) {
+	cond = builder.NewCond()
+	for _, column := range qualifiedColumns(table) {
+		cond = cond.Or(builder.Like{column, text})
+		args = append(args, "%"+text+"%")
+	}
+	return cond, args
+}
+
+func (e *likeFullText) addRelevanceField(table string, words, fields []string) (res []string, args []any) {
+	return addRelevanceField(qualifiedColumns(table), words, fields)
+}
+
+// mysqlFullText uses the FULLTEXT indexes in boolean mode
+type mysqlFullText struct{}
+
+func (e *mysqlFullText) match(table string) string {
+	return fmt.Sprintf("MATCH (%s) AGAINST (? IN BOOLEAN MODE)", strings.Join(qualifiedColumns(table), ", "))
+}
+
+// tokens drops the boolean mode operators from the text, so the words are searched as they are
+func (e *mysqlFullText) tokens(text string) []string {
+	return strings.Fields(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`+-<>()~*"@`, r) {
+			return ' '
+		}
+		return r
+	}, text))
+}
+
+func (e *mysqlFullText) termCond(table, text string, phrase bool) (cond builder.Cond, args []any) {
+	tokens := e.tokens(text)
+	indexed := false
+	for _, token := range tokens {
+		if utf8.RuneCountInString(token) >= mysqlMinTokenSize && !mysqlStopWords[strings.ToLower(token)] {
+			indexed = true
+		}
+	}
+	// the index would match nothing for words it leaves out
+	if !indexed || hasCJK(text) {
+		return (&likeFullText{}).termCond(table, text, phrase)
+	}
+	query := strings.Join(tokens, " ")
+	if len(tokens) > 1 {
+		query = `"` + query + `"`
+	}
+	return builder.Expr(e.match(table), query), []any{query}
+}
+
+func (e *mysqlFullText) addRelevanceField(table string, words, fields []string) (res []string, args []any) {
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		tokens = append(tokens, e.tokens(word)...)
+	}
+	if len(tokens) == 0 || hasCJK(words...) {
+		return (&likeFullText{}).addRelevanceField(table, words, fields)
+	}
+	res = append(append(res, fields...), "("+e.match(table)+") as relevance")
+	return res, []any{strings.Join(tokens, " ")}
+}
+
+// postgresFullText uses the GIN indexes over the tsvector of the columns
+type postgresFullText struct{}
+
+func (e *postgresFullText) document(table string) string {
+	return fmt.Sprintf("to_tsvector('%s', %s)", postgresTextSearchConfig,
+		strings.Join(qualifiedColumns(table), " || ' ' || "))
+}
+
+func (e *postgresFullText) termCond(table, text string, phrase bool) (cond builder.Cond, args []any) {
+	if hasCJK(text) {
+		return (&likeFullText{}).termCond(table, text, phrase)
+	}
+	query := fmt.Sprintf("plainto_tsquery('%s', ?)", postgresTextSearchConfig)
+	if phrase {
+		query = fmt.Sprintf("phraseto_tsquery('%s', ?)", postgresTextSearchConfig)
+	}
+	// the stop words make an empty query, which matches nothing, they are not searched for instead
+	args = []any{text, text}
+	return builder.Expr(fmt.Sprintf("(numnode(%s) = 0 OR %s @@ %s)", query, e.document(table), query), args...), args
+}
+
+func (e *postgresFullText) addRelevanceField(table string, words, fields []string) (res []string, args []any) {
+	if hasCJK(words...) {
+		return (&likeFullText{}).addRelevanceField(table, words, fields)
+	}
+	queries := make([]string, 0, len(words))
+	for _, word := range words {
+		queries = append(queries, fmt.Sprintf("plainto_tsquery('%s', ?)", postgresTextSearchConfig))
+		args = append(args, word)
+	}
+	res = append(append(res, fields...), fmt.Sprintf("ts_rank(%s, (%s)) as relevance",
+		e.document(table), strings.Join(queries, " || ")))
+	return res, args
+}
+
+// sqliteFullText uses the FTS5 tables named after the table with a _fts suffix, ranked by bm25
+type sqliteFullText struct{}
+
+// phrase quotes the text, so it is matched as a phrase whatever characters it has
+func (e *sqliteFullText) phrase(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+func (e *sqliteFullText) termCond(table, text string, phrase bool) (cond builder.Cond, args []any) {
+	if hasCJK(text) {
+		return (&likeFullText{}).termCond(table, text, phrase)
+	}
+	fts := table + "_fts"
+	query := e.phrase(text)
+	return builder.Expr(fmt.Sprintf("`%s`.`id` IN (SELECT rowid FROM %s WHERE %s MATCH ?)", table, fts, fts), query),
+		[]any{query}
+}
+
+func (e *sqliteFullText) addRelevanceField(table string, words, fields []string) (res []string, args []any) {
+	if hasCJK(words...) {
+		return (&likeFullText{}).addRelevanceField(table, words, fields)
+	}
+	phrases := make([]string, 0, len(words))
+	for _, word := range words {
+		phrases = append(phrases, e.phrase(word))
+	}
+	fts := table + "_fts"
+	// bm25 is lower for better matches, the title weighs twice the content
+	weights := strings.Repeat(", 1.0", len(fullTextColumns[table]))
+	if len(fullTextColumns[table]) > 1 {
+		weights = ", 2.0" + strings.Repeat(", 1.0", len(fullTextColumns[table])-1)
+	}
+	res = append(append(res, fields...), fmt.Sprintf(
+		"(SELECT -bm25(%s%s) FROM %s WHERE %s MATCH ? AND rowid = `%s`.`id`) as relevance",
+		fts, weights, fts, fts, table))
+	return res, []any{strings.Join(phrases, " OR ")}
+}
@@ -31,6 +31,7 @@ import (
 
 	"github.com/apache/answer/pkg/htmltext"
 
+	"github.com/apache/answer/internal/base/constant"
 	"github.com/apache/answer/internal/base/data"
 	"github.com/apache/answer/internal/base/handler"
 	"github.com/apache/answer/internal/base/reason"
@@ -46,6 +47,13 @@ import (
 	"xorm.io/builder"
 )
 
+const (
+	// defaultFragmentSize the characters of a snippet when the condition has no size
+	defaultFragmentSize = 100
+	// snippetLimit the snippets of a search result at most
+	snippetLimit = 3
+)
+
 var (
 	qFields = []string{
 		"`question`.`id`",
@@ -99,29 +107,194 @@ func NewSearchRepo(
 }
 
 // SearchContents search question and answer data
-func (sr *searchRepo) SearchContents(ctx context.Context, words []string, tagIDs [][]string, userID string, votes int, page, pageSize int, order string) (resp []*schema.SearchResult, total int64, err error) {
-	words = filterWords(words)
+func (sr *searchRepo) SearchContents(ctx context.Context, cond *schema.SearchCondition, page, pageSize int, order string) (resp []*schema.SearchResult, total int64, err error) {
+	var (
+		words    = filterWords(cond.Words)
+		qfs      = qFields
+		afs      = aFields
+		argsQ    = []any{}
+		argsA    = []any{}
+		fullText = sr.fullText()
+	)
 
+	if order == "relevance" {
+		if len(words) > 0 {
+			qfs, argsQ = fullText.addRelevanceField("question", words, qfs)
+			afs, argsA = fullText.addRelevanceField("answer", words, afs)
+		} else {
+			order = "newest"
+		}
+	}
+
+	sql, args, err := sr.contentsQuery(cond, qfs, afs, argsQ, argsA)
+	if err != nil {
+		return
+	}
+
+	countSQL, _, err := builder.MySQL().Select("count(*) total").From(sql, "c").ToSQL()
+	if err != nil {
+		return
+	}
+
+	startNum := (page - 1) * pageSize
+	querySQL, _, err := builder.MySQL().Select("*").From(sql, "t").OrderBy(sr.parseOrder(ctx, order)).Limit(pageSize, startNum).ToSQL()
+	if err != nil {
+		return
+	}
+
+	queryArgs := []any{}
+	countArgs := []any{}
+
+	queryArgs = append(queryArgs, querySQL)
+	queryArgs = append(queryArgs, args...)
+
+	countArgs = append(countArgs, countSQL)
+	countArgs = append(countArgs, args...)
+
+	res, err := sr.data.DB.Context(ctx).Query(queryArgs...)
+	if err != nil {
+		return
+	}
+
+	tr, err := sr.data.DB.Context(ctx).Query(countArgs...)
+	if len(tr) != 0 {
+		total = converter.StringToInt64(string(tr[0]["total"]))
+	}
+	if err != nil {
+		err = errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+		return
+	} else {
+		resp, err = sr.parseResult(ctx, res, cond, nil)
+		return
+	}
+}
+
+// SearchQuestions search question data
+func (sr *searchRepo) SearchQuestions(ctx context.Context, cond *schema.SearchCondition, page, pageSize int, order string) (resp []*schema.SearchResult, total int64, err error) {
 	var (
-		b     *builder.Builder
-		ub    *builder.Builder
-		qfs   = qFields
-		afs   = aFields
-		argsQ = []any{}
-		argsA = []any{}
+		words    = filterWords(cond.Words)
+		qfs      = qFields
+		args     = []any{}
+		fullText = sr.fullText()
 	)
+	if order == "relevance" {
+		if len(words) > 0 {
+			qfs, args = fullText.addRelevanceField("question", words, qfs)
+		} else {
+			order = "newest"
+		}
+	}
+
+	b, args := sr.questionQuery(cond, qfs, args)
+
+	queryArgs := []any{}
+	countArgs := []any{}
+
+	countSQL, _, err := builder.MySQL().Select("count(*) total").From(b, "c").ToSQL()
+	if err != nil {
+		return
+	}
 
+	startNum := (page - 1) * pageSize
+	querySQL, _, err := b.OrderBy(sr.parseOrder(ctx, order)).Limit(pageSize, startNum).ToSQL()
+	if err != nil {
+		return
+	}
+	queryArgs = append(queryArgs, querySQL)
+	queryArgs = append(queryArgs, args...)
+
+	countArgs = append(countArgs, countSQL)
+	countArgs = append(countArgs, args...)
+
+	res, err := sr.data.DB.Context(ctx).Query(queryArgs...)
+	if err != nil {
+		return
+	}
+
+	tr, err := sr.data.DB.Context(ctx).Query(countArgs...)
+	if err != nil {
+		return
+	}
+
+	if len(tr) != 0 {
+		total = converter.StringToInt64(string(tr[0]["total"]))
+	}
+	resp, err = sr.parseResult(ctx, res, cond, nil)
+	if err != nil {
+		err = errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return
+}
+
+// SearchAnswers search answer data
+func (sr *searchRepo) SearchAnswers(ctx context.Context, cond *schema.SearchCondition, page, pageSize int, order string) (resp []*schema.SearchResult, total int64, err error) {
+	var (
+		words    = filterWords(cond.Words)
+		afs      = aFields
+		args     = []any{}
+		fullText = sr.fullText()
+	)
 	if order == "relevance" {
 		if len(words) > 0 {
-			qfs, argsQ = addRelevanceField([]string{"title", "original_text"}, words, qfs)
-			afs, argsA = addRelevanceField([]string{"`answer`.`original_text`"}, words, afs)
+			afs, args = fullText.addRelevanceField("answer", words, afs)
 		} else {
 			order = "newest"
 		}
 	}
 
-	b = builder.MySQL().Select(qfs...).From("`question`")
-	ub = builder.MySQL().Select(afs...).From("`answer`").
+	b, args := sr.answerQuery(cond, afs, args)
+
+	queryArgs := []any{}
+	countArgs := []any{}
+
+	countSQL, _, err := builder.MySQL().Select("count(*) total").From(b, "c").ToSQL()
+	if err != nil {
+		return
+	}
+
+	startNum := (page - 1) * pageSize
+	querySQL, _, err := b.OrderBy(sr.parseOrder(ctx, order)).Limit(pageSize, startNum).ToSQL()
+	if err != nil {
+		return
+	}
+
+	queryArgs = append(queryArgs, querySQL)
+	queryArgs = append(queryArgs, args...)
+
+	countArgs = append(countArgs, countSQL)
+	countArgs = append(countArgs, args...)
+
+	res, err := sr.data.DB.Context(ctx).Query(queryArgs...)
+	if err != nil {
+		return
+	}
+
+	tr, err := sr.data.DB.Context(ctx).Query(countArgs...)
+	if err != nil {
+		return
+	}
+
+	total = converter.StringToInt64(string(tr[0]["total"]))
+	resp, err = sr.parseResult(ctx, res, cond, nil)
+	if err != nil {
+		err = errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return
+}
+
+// contentsQuery builds the union of the questions and the answers matching the condition,
+// argsQ and argsA are the arguments of the fields
+func (sr *searchRepo) contentsQuery(cond *schema.SearchCondition, qfs, afs []string, argsQ, argsA []any) (
+	sql string, args []any, err error) {
+	var (
+		tagIDs   = cond.Tags
+		userID   = cond.UserID
+		votes    = cond.VoteAmount
+		fullText = sr.fullText()
+	)
+
+	b := builder.MySQL().Select(qfs...).From("`question`")
+	ub := builder.MySQL().Select(afs...).From("`answer`").
 		LeftJoin("`question`", "`question`.id = `answer`.question_id")
 
 	b.Where(builder.Lt{"`question`.`status`": entity.QuestionStatusDeleted}).
@@ -133,20 +306,20 @@ func (sr *searchRepo) SearchContents(ctx context.Context, words []string, tagIDs
 	argsQ = append(argsQ, entity.QuestionStatusDeleted, entity.QuestionShow)
 	argsA = append(argsA, entity.QuestionStatusDeleted, entity.AnswerStatusDeleted, entity.QuestionShow)
 
-	likeConQ := builder.NewCond()
-	likeConA := builder.NewCond()
-	for _, word := range words {
-		likeConQ = likeConQ.Or(builder.Like{"title", word}).
-			Or(builder.Like{"original_text", word})
-		argsQ = append(argsQ, "%"+word+"%")
-		argsQ = append(argsQ, "%"+word+"%")
-
-		likeConA = likeConA.Or(builder.Like{"`answer`.original_text", word})
-		argsA = append(argsA, "%"+word+"%")
+	if cond.Query != nil {
+		matchConQ, matchArgsQ := exprCond(cond.Query, func(text string, phrase bool) (builder.Cond, []any) {
+			return fullText.termCond("question", text, phrase)
+		})
+		matchConA, matchArgsA := exprCond(cond.Query, func(text string, phrase bool) (builder.Cond, []any) {
+			return fullText.termCond("answer", text, phrase)
+		})
+		b.Where(matchConQ)
+		ub.Where(matchConA)
+		argsQ = append(argsQ, matchArgsQ...)
+		argsA = append(argsA, matchArgsA...)
 	}
-
-	b.Where(likeConQ)
-	ub.Where(likeConA)
+	argsQ = append(argsQ, createdCond(b, "`question`.`created_at`", cond)...)
+	argsA = append(argsA, createdCond(ub, "`answer`.`created_at`", cond)...)
 
 	// check tag
 	for ti, tagID := range tagIDs {
@@ -199,76 +372,33 @@ func (sr *searchRepo) SearchContents(ctx context.Context, words []string, tagIDs
 	if err != nil {
 		return
 	}
-	sql := fmt.Sprintf("(%s UNION ALL %s)", bSQL, ubSQL)
-
-	countSQL, _, err := builder.MySQL().Select("count(*) total").From(sql, "c").ToSQL()
-	if err != nil {
-		return
-	}
-
-	startNum := (page - 1) * pageSize
-	querySQL, _, err := builder.MySQL().Select("*").From(sql, "t").OrderBy(sr.parseOrder(ctx, order)).Limit(pageSize, startNum).ToSQL()
-	if err != nil {
-		return
-	}
-
-	queryArgs := []any{}
-	countArgs := []any{}
-
-	queryArgs = append(queryArgs, querySQL)
-	queryArgs = append(queryArgs, argsQ...)
-	queryArgs = append(queryArgs, argsA...)
-
-	countArgs = append(countArgs, countSQL)
-	countArgs = append(countArgs, argsQ...)
-	countArgs = append(countArgs, argsA...)
-
-	res, err := sr.data.DB.Context(ctx).Query(queryArgs...)
-	if err != nil {
-		return
-	}
-
-	tr, err := sr.data.DB.Context(ctx).Query(countArgs...)
-	if len(tr) != 0 {
-		total = converter.StringToInt64(string(tr[0]["total"]))
-	}
-	if err != nil {
-		err = errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
-		return
-	} else {
-		resp, err = sr.parseResult(ctx, res, words)
-		return
-	}
+	sql = fmt.Sprintf("(%s UNION ALL %s)", bSQL, ubSQL)
+	return sql, append(argsQ, argsA...), nil
 }
 
-// SearchQuestions search question data
-func (sr *searchRepo) SearchQuestions(ctx context.Context, words []string, tagIDs [][]string, notAccepted bool, views, answers int, page, pageSize int, order string) (resp []*schema.SearchResult, total int64, err error) {
-	words = filterWords(words)
+// questionQuery builds the query of the questions matching the condition, args are the arguments of the fields
+func (sr *searchRepo) questionQuery(cond *schema.SearchCondition, qfs []string, args []any) (*builder.Builder, []any) {
 	var (
-		qfs  = qFields
-		args = []any{}
+		tagIDs      = cond.Tags
+		notAccepted = cond.NotAccepted
+		views       = cond.Views
+		answers     = cond.AnswerAmount
+		fullText    = sr.fullText()
 	)
-	if order == "relevance" {
-		if len(words) > 0 {
-			qfs, args = addRelevanceField([]string{"title", "original_text"}, words, qfs)
-		} else {
-			order = "newest"
-		}
-	}
 
 	b := builder.MySQL().Select(qfs...).From("question")
 
 	b.Where(builder.Lt{"`question`.`status`": entity.QuestionStatusDeleted}).And(builder.Eq{"`question`.`show`": entity.QuestionShow})
 	args = append(args, entity.QuestionStatusDeleted, entity.QuestionShow)
 
-	likeConQ := builder.NewCond()
-	for _, word := range words {
-		likeConQ = likeConQ.Or(builder.Like{"title", word}).
-			Or(builder.Like{"original_text", word})
-		args = append(args, "%"+word+"%")
-		args = append(args, "%"+word+"%")
+	if cond.Query != nil {
+		matchCond, matchArgs := exprCond(cond.Query, func(text string, phrase bool) (builder.Cond, []any) {
+			return fullText.termCond("question", text, phrase)
+		})
+		b.Where(matchCond)
+		args = append(args, matchArgs...)
 	}
-	b.Where(likeConQ)
+	args = append(args, createdCond(b, "`question`.`created_at`", cond)...)
 
 	// check tag
 	for ti, tagID := range tagIDs {
@@ -312,61 +442,17 @@ func (sr *searchRepo) SearchQuestions(ctx context.Context, words []string, tagID
 		b.And(builder.Gte{"answer_count": answers})
 		args = append(args, answers)
 	}
-
-	queryArgs := []any{}
-	countArgs := []any{}
-
-	countSQL, _, err := builder.MySQL().Select("count(*) total").From(b, "c").ToSQL()
-	if err != nil {
-		return
-	}
-
-	startNum := (page - 1) * pageSize
-	querySQL, _, err := b.OrderBy(sr.parseOrder(ctx, order)).Limit(pageSize, startNum).ToSQL()
-	if err != nil {
-		return
-	}
-	queryArgs = append(queryArgs, querySQL)
-	queryArgs = append(queryArgs, args...)
-
-	countArgs = append(countArgs, countSQL)
-	countArgs = append(countArgs, args...)
-
-	res, err := sr.data.DB.Context(ctx).Query(queryArgs...)
-	if err != nil {
-		return
-	}
-
-	tr, err := sr.data.DB.Context(ctx).Query(countArgs...)
-	if err != nil {
-		return
-	}
-
-	if len(tr) != 0 {
-		total = converter.StringToInt64(string(tr[0]["total"]))
-	}
-	resp, err = sr.parseResult(ctx, res, words)
-	if err != nil {
-		err = errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
-	}
-	return
+	return b, args
 }
 
-// SearchAnswers search answer data
-func (sr *searchRepo) SearchAnswers(ctx context.Context, words []string, tagIDs [][]string, accepted bool, questionID string, page, pageSize int, order string) (resp []*schema.SearchResult, total int64, err error) {
-	words = filterWords(words)
-
+// answerQuery builds the query of the answers matching the condition, args are the arguments of the fields
+func (sr *searchRepo) answerQuery(cond *schema.SearchCondition, afs []string, args []any) (*builder.Builder, []any) {
 	var (
-		afs  = aFields
-		args = []any{}
+		tagIDs     = cond.Tags
+		accepted   = cond.Accepted
+		questionID = cond.QuestionID
+		fullText   = sr.fullText()
 	)
-	if order == "relevance" {
-		if len(words) > 0 {
-			afs, args = addRelevanceField([]string{"`answer`.`original_text`"}, words, afs)
-		} else {
-			order = "newest"
-		}
-	}
 
 	b := builder.MySQL().Select(afs...).From("`answer`").
 		LeftJoin("`question`", "`question`.id = `answer`.question_id")
@@ -375,13 +461,14 @@ func (sr *searchRepo) SearchAnswers(ctx context.Context, words []string, tagIDs
 		And(builder.Lt{"`answer`.`status`": entity.AnswerStatusDeleted}).And(builder.Eq{"`question`.`show`": entity.QuestionShow})
 	args = append(args, entity.QuestionStatusDeleted, entity.AnswerStatusDeleted, entity.QuestionShow)
 
-	likeConA := builder.NewCond()
-	for _, word := range words {
-		likeConA = likeConA.Or(builder.Like{"`answer`.original_text", word})
-		args = append(args, "%"+word+"%")
+	if cond.Query != nil {
+		matchCond, matchArgs := exprCond(cond.Query, func(text string, phrase bool) (builder.Cond, []any) {
+			return fullText.termCond("answer", text, phrase)
+		})
+		b.Where(matchCond)
+		args = append(args, matchArgs...)
 	}
-
-	b.Where(likeConA)
+	args = append(args, createdCond(b, "`answer`.`created_at`", cond)...)
 
 	// check tag
 	for ti, tagID := range tagIDs {
@@ -408,43 +495,7 @@ func (sr *searchRepo) SearchAnswers(ctx context.Context, words []string, tagIDs
 		b.Where(builder.Eq{"question_id": questionID})
 		args = append(args, questionID)
 	}
-
-	queryArgs := []any{}
-	countArgs := []any{}
-
-	countSQL, _, err := builder.MySQL().Select("count(*) total").From(b, "c").ToSQL()
-	if err != nil {
-		return
-	}
-
-	startNum := (page - 1) * pageSize
-	querySQL, _, err := b.OrderBy(sr.parseOrder(ctx, order)).Limit(pageSize, startNum).ToSQL()
-	if err != nil {
-		return
-	}
-
-	queryArgs = append(queryArgs, querySQL)
-	queryArgs = append(queryArgs, args...)
-
-	countArgs = append(countArgs, countSQL)
-	countArgs = append(countArgs, args...)
-
-	res, err := sr.data.DB.Context(ctx).Query(queryArgs...)
-	if err != nil {
-		return
-	}
-
-	tr, err := sr.data.DB.Context(ctx).Query(countArgs...)
-	if err != nil {
-		return
-	}
-
-	total = converter.StringToInt64(string(tr[0]["total"]))
-	resp, err = sr.parseResult(ctx, res, words)
-	if err != nil {
-		err = errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
-	}
-	return
+	return b, args
 }
 
 func (sr *searchRepo) parseOrder(_ context.Context, order string) (res string) {
@@ -464,13 +515,17 @@ func (sr *searchRepo) parseOrder(_ context.Context, order string) (res string) {
 }
 
 // ParseSearchPluginResult parse search plugin result
-func (sr *searchRepo) ParseSearchPluginResult(ctx context.Context, sres []plugin.SearchResult, words []string) (resp []*schema.SearchResult, err error) {
+func (sr *searchRepo) ParseSearchPluginResult(ctx context.Context, sres []plugin.SearchResult, cond *schema.SearchCondition) (resp []*schema.SearchResult, err error) {
 	var (
-		qres []map[string][]byte
-		res  = make([]map[string][]byte, 0)
-		b    *builder.Builder
+		qres       []map[string][]byte
+		res        = make([]map[string][]byte, 0)
+		highlights = make(map[string]map[string][]string)
+		b          *builder.Builder
 	)
 	for _, r := range sres {
+		if len(r.Highlights) > 0 {
+			highlights[r.ID] = r.Highlights
+		}
 		switch r.Type {
 		case "question":
 			b = builder.MySQL().Select(qFields...).From("question").Where(builder.Eq{"id": r.ID}).
@@ -487,11 +542,18 @@ func (sr *searchRepo) ParseSearchPluginResult(ctx context.Context, sres []plugin
 		}
 		res = append(res, qres[0])
 	}
-	return sr.parseResult(ctx, res, words)
+	return sr.parseResult(ctx, res, cond, highlights)
 }
 
-// parseResult parse search result, return the data structure
-func (sr *searchRepo) parseResult(ctx context.Context, res []map[string][]byte, words []string) (resp []*schema.SearchResult, err error) {
+// parseResult parse search result, return the data structure. The highlights of the search plugin are keyed by
+// the object ID, the results without them are highlighted with the words of the condition.
+func (sr *searchRepo) parseResult(ctx context.Context, res []map[string][]byte, cond *schema.SearchCondition,
+	highlights map[string]map[string][]string) (resp []*schema.SearchResult, err error) {
+	words := filterWords(cond.Words)
+	fragmentSize := cond.FragmentSize
+	if fragmentSize <= 0 {
+		fragmentSize = defaultFragmentSize
+	}
 	questionIDs := make([]string, 0)
 	userIDs := make([]string, 0)
 	resultList := make([]*schema.SearchResult, 0)
@@ -522,6 +584,7 @@ func (sr *searchRepo) parseResult(ctx context.Context, res []map[string][]byte,
 			Accepted:    string(r["accepted"]) == "2",
 			AnswerCount: converter.StringToInt(string(r["answer_count"])),
 		}
+		setHighlights(object, r, words, fragmentSize, highlights[string(r["id"])])
 
 		objectKey, err := obj.GetObjectTypeStrByObjectID(string(r["id"]))
 		if err != nil {
@@ -543,6 +606,8 @@ func (sr *searchRepo) parseResult(ctx context.Context, res []map[string][]byte,
 					break
 				}
 			}
+		case constant.TopicObjectType:
+			object.StatusStr = string(r["status"])
 		}
 
 		resultList = append(resultList, &schema.SearchResult{
@@ -575,6 +640,31 @@ func (sr *searchRepo) parseResult(ctx context.Context, res []map[string][]byte,
 	return resultList, nil
 }
 
+// setHighlights sets the highlighted title and snippets of the object, those of the search plugin are sanitized
+// and those missing are built from the row
+func setHighlights(object *schema.SearchObject, r map[string][]byte, words []string, fragmentSize int,
+	highlights map[string][]string) {
+	if titles := highlights[plugin.SearchHighlightTitle]; len(titles) > 0 {
+		object.TitleHighlight = htmltext.SanitizeHighlight(titles[0])
+	} else {
+		object.TitleHighlight = htmltext.HighlightText(string(r["title"]), words)
+	}
+
+	for _, fragment := range highlights[plugin.SearchHighlightContent] {
+		if len(object.Snippets) == snippetLimit {
+			break
+		}
+		object.Snippets = append(object.Snippets, htmltext.SanitizeHighlight(fragment))
+	}
+	if len(object.Snippets) == 0 {
+		object.Snippets = htmltext.FetchHighlightedFragments(string(r["parsed_text"]), words, "...",
+			fragmentSize, snippetLimit)
+	}
+	if object.Snippets == nil {
+		object.Snippets = make([]string, 0)
+	}
+}
+
 func addRelevanceField(searchFields, words, fields []string) (res []string, args []any) {
 	relevanceRes := []string{}
 	args = []any{}
@@ -608,6 +698,19 @@ func addRelevanceField(searchFields, words, fields []string) (res []string, args
 	return
 }
 
+// createdCond limits the creation time of the rows to the condition
+func createdCond(b *builder.Builder, column string, cond *schema.SearchCondition) (args []any) {
+	if !cond.CreatedAfter.IsZero() {
+		b.Where(builder.Gte{column: cond.CreatedAfter})
+		args = append(args, cond.CreatedAfter)
+	}
+	if !cond.CreatedBefore.IsZero() {
+		b.Where(builder.Lt{column: cond.CreatedBefore})
+		args = append(args, cond.CreatedBefore)
+	}
+	return args
+}
+
 func filterWords(words []string) (res []string) {
 	for _, word := range words {
 		if strings.TrimSpace(word) != "" {
@@ -0,0 +1,150 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package search_common
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/pkg/converter"
+	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
+)
+
+// tFields the topic columns shaped like qFields, the opening post stands for the content
+var tFields = []string{
+	"`topics`.`id` as `id`",
+	"`topics`.`id` as `question_id`",
+	"`topics`.`title` as `title`",
+	"(SELECT `first_post`.`parsed_text` FROM `posts` `first_post` WHERE `first_post`.`id` = " +
+		"(SELECT MIN(`p`.`id`) FROM `posts` `p` WHERE `p`.`topic_id` = `topics`.`id`)) as `parsed_text`",
+	"`topics`.`created_at` as `created_at`",
+	"`topics`.`user_id` as `user_id`",
+	"`topics`.`vote_count` as `vote_count`",
+	"`topics`.`post_count` as `answer_count`",
+	"CASE WHEN `topics`.`solved_post_id` > 0 THEN 2 ELSE 0 END as `accepted`",
+	"`topics`.`status` as `status`",
+	"`topics`.`updated_at` as `post_update_time`",
+}
+
+// SearchTopics search forum topic data, by their title and the text of their posts
+func (sr *searchRepo) SearchTopics(ctx context.Context, cond *schema.SearchCondition, page, pageSize int, order string) (resp []*schema.SearchResult, total int64, err error) {
+	var (
+		words = filterWords(cond.Words)
+		tfs   = tFields
+		args  = []any{}
+	)
+	if order == "relevance" {
+		if len(words) > 0 {
+			tfs, args = addRelevanceField([]string{"`topics`.`title`"}, words, tfs)
+		} else {
+			order = "newest"
+		}
+	}
+
+	b, args := topicQuery(cond, tfs, args)
+
+	countSQL, _, err := builder.MySQL().Select("count(*) total").From(b, "c").ToSQL()
+	if err != nil {
+		return
+	}
+
+	startNum := (page - 1) * pageSize
+	querySQL, _, err := b.OrderBy(sr.parseOrder(ctx, order)).Limit(pageSize, startNum).ToSQL()
+	if err != nil {
+		return
+	}
+
+	res, err := sr.data.DB.Context(ctx).Query(append([]any{querySQL}, args...)...)
+	if err != nil {
+		return nil, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	tr, err := sr.data.DB.Context(ctx).Query(append([]any{countSQL}, args...)...)
+	if err != nil {
+		return nil, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	if len(tr) != 0 {
+		total = converter.StringToInt64(string(tr[0]["total"]))
+	}
+	resp, err = sr.parseResult(ctx, res, cond, nil)
+	return resp, total, err
+}
+
+// topicQuery builds the query of the topics matching the condition, args are the arguments of the fields
+func topicQuery(cond *schema.SearchCondition, tfs []string, args []any) (*builder.Builder, []any) {
+	b := builder.MySQL().Select(tfs...).From("`topics`")
+	b.Where(builder.In("`topics`.`status`", entity.TopicStatusAvailable, entity.TopicStatusClosed))
+	args = append(args, entity.TopicStatusAvailable, entity.TopicStatusClosed)
+
+	if cond.Query != nil {
+		matchCond, matchArgs := exprCond(cond.Query, topicTermCond)
+		b.Where(matchCond)
+		args = append(args, matchArgs...)
+	}
+	args = append(args, createdCond(b, "`topics`.`created_at`", cond)...)
+
+	// check tag
+	for ti, tagID := range cond.Tags {
+		ast := "tag_rel" + strconv.Itoa(ti)
+		b.Join("INNER", "tag_rel as "+ast, "`topics`.id = "+ast+".object_id").
+			And(builder.Eq{
+				ast + ".status": entity.TagRelStatusAvailable,
+			}).
+			And(builder.In(ast+".tag_id", tagID))
+		args = append(args, entity.TagRelStatusAvailable)
+		for _, t := range tagID {
+			args = append(args, t)
+		}
+	}
+
+	// check category
+	if cond.CategoryID != "" {
+		b.Where(builder.Eq{"`topics`.`category_id`": cond.CategoryID})
+		args = append(args, cond.CategoryID)
+	}
+
+	// check user
+	if cond.UserID != "" {
+		b.Where(builder.Eq{"`topics`.`user_id`": cond.UserID})
+		args = append(args, cond.UserID)
+	}
+
+	// check vote
+	if cond.VoteAmount == 0 {
+		b.Where(builder.Eq{"`topics`.`vote_count`": cond.VoteAmount})
+		args = append(args, cond.VoteAmount)
+	} else if cond.VoteAmount > 0 {
+		b.Where(builder.Gte{"`topics`.`vote_count`": cond.VoteAmount})
+		args = append(args, cond.VoteAmount)
+	}
+	return b, args
+}
+
+// topicTermCond matches the title of the topics and the text of their posts, there is no full-text index of them
+func topicTermCond(text string, _ bool) (builder.Cond, []any) {
+	return builder.Or(
+		builder.Like{"`topics`.`title`", text},
+		builder.In("`topics`.`id`", builder.Select("`topic_id`").From("`posts`").
+			Where(builder.Like{"`original_text`", text})),
+	), []any{"%" + text + "%", "%" + text + "%"}
+}
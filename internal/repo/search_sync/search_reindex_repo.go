@@ -0,0 +1,222 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package search_sync
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/data"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/plugin"
+	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
+)
+
+// SearchReindexRepo reads the contents to index page by page in the order of their id
+type SearchReindexRepo interface {
+	GetQuestionsAfter(ctx context.Context, afterID string, limit int) (
+		questionList []*plugin.SearchContent, lastID string, err error)
+	GetAnswersAfter(ctx context.Context, afterID string, limit int) (
+		answerList []*plugin.SearchContent, lastID string, err error)
+	CountContents(ctx context.Context, withDeleted bool) (questions, answers int64, err error)
+	GetExistingIDs(ctx context.Context, objectType string, ids []string) (existing map[string]bool, err error)
+	AcquireReindexLock(ctx context.Context, owner string, expiresAt time.Time) (acquired bool, err error)
+	ReleaseReindexLock(ctx context.Context, owner string) error
+	IsReindexLocked(ctx context.Context) (locked bool, err error)
+}
+
+// reindexLock the value of the config of the reindex lock, it is free when the owner is empty or it has expired
+type reindexLock struct {
+	Owner     string `json:"owner"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+func (l *reindexLock) heldByOther(owner string, now time.Time) bool {
+	return len(l.Owner) > 0 && l.Owner != owner && l.ExpiresAt > now.Unix()
+}
+
+// searchReindexRepo search reindex repository
+type searchReindexRepo struct {
+	data   *data.Data
+	syncer *PluginSyncer
+}
+
+// NewSearchReindexRepo new repository
+func NewSearchReindexRepo(data *data.Data) SearchReindexRepo {
+	return &searchReindexRepo{
+		data:   data,
+		syncer: &PluginSyncer{data: data},
+	}
+}
+
+// GetQuestionsAfter get the questions whose id is greater than afterID, lastID is the id of the last one read,
+// it is empty when there are no more questions
+func (sr *searchReindexRepo) GetQuestionsAfter(ctx context.Context, afterID string, limit int) (
+	questionList []*plugin.SearchContent, lastID string, err error) {
+	questions := make([]*entity.Question, 0)
+	err = sr.data.DB.Context(ctx).Where("id > ?", afterID).Asc("id").Limit(limit).Find(&questions)
+	if err != nil {
+		return nil, "", errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	if len(questions) == 0 {
+		return nil, "", nil
+	}
+	questionList, err = sr.syncer.convertQuestions(ctx, questions)
+	if err != nil {
+		return nil, "", err
+	}
+	return questionList, questions[len(questions)-1].ID, nil
+}
+
+// GetAnswersAfter get the answers whose id is greater than afterID, lastID is the id of the last one read,
+// it is empty when there are no more answers
+func (sr *searchReindexRepo) GetAnswersAfter(ctx context.Context, afterID string, limit int) (
+	answerList []*plugin.SearchContent, lastID string, err error) {
+	answers := make([]*entity.Answer, 0)
+	err = sr.data.DB.Context(ctx).Where("id > ?", afterID).Asc("id").Limit(limit).Find(&answers)
+	if err != nil {
+		return nil, "", errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	if len(answers) == 0 {
+		return nil, "", nil
+	}
+	answerList, err = sr.syncer.convertAnswers(ctx, answers)
+	if err != nil {
+		return nil, "", err
+	}
+	return answerList, answers[len(answers)-1].ID, nil
+}
+
+// CountContents count the questions and answers, the deleted ones are left out unless withDeleted
+func (sr *searchReindexRepo) CountContents(ctx context.Context, withDeleted bool) (questions, answers int64, err error) {
+	questionSession := sr.data.DB.Context(ctx)
+	if !withDeleted {
+		questionSession.Where("status <> ?", entity.QuestionStatusDeleted)
+	}
+	questions, err = questionSession.Count(&entity.Question{})
+	if err != nil {
+		return 0, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	answerSession := sr.data.DB.Context(ctx)
+	if !withDeleted {
+		answerSession.Where("status <> ?", entity.AnswerStatusDeleted)
+	}
+	answers, err = answerSession.Count(&entity.Answer{})
+	if err != nil {
+		return 0, 0, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return questions, answers, nil
+}
+
+// GetExistingIDs gets which of the questions or answers are in the database, deleted or not
+func (sr *searchReindexRepo) GetExistingIDs(ctx context.Context, objectType string, ids []string) (
+	existing map[string]bool, err error) {
+	existing = make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return existing, nil
+	}
+	var bean any = &entity.Question{}
+	if objectType == constant.AnswerObjectType {
+		bean = &entity.Answer{}
+	}
+	found := make([]string, 0, len(ids))
+	err = sr.data.DB.Context(ctx).Table(bean).In("id", ids).Cols("id").Find(&found)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	for _, id := range found {
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+// AcquireReindexLock takes the reindex lock for the owner until expiresAt, or extends it when the owner holds it.
+// It returns false when another owner holds it and it has not expired. The config is only changed when it is
+// still the one read, so two processes taking the lock at the same time do not both get it.
+func (sr *searchReindexRepo) AcquireReindexLock(ctx context.Context, owner string, expiresAt time.Time) (
+	acquired bool, err error) {
+	cfg, lock, err := sr.getReindexLock(ctx)
+	if err != nil {
+		return false, err
+	}
+	if lock.heldByOther(owner, time.Now()) {
+		return false, nil
+	}
+	value, _ := json.Marshal(&reindexLock{Owner: owner, ExpiresAt: expiresAt.Unix()})
+	if string(value) == cfg.Value {
+		return true, nil
+	}
+	return sr.swapReindexLock(ctx, cfg.Value, string(value))
+}
+
+// ReleaseReindexLock frees the reindex lock when the owner holds it
+func (sr *searchReindexRepo) ReleaseReindexLock(ctx context.Context, owner string) error {
+	cfg, lock, err := sr.getReindexLock(ctx)
+	if err != nil {
+		return err
+	}
+	if lock.Owner != owner {
+		return nil
+	}
+	_, err = sr.swapReindexLock(ctx, cfg.Value, "")
+	return err
+}
+
+// IsReindexLocked reports whether a process holds the reindex lock
+func (sr *searchReindexRepo) IsReindexLocked(ctx context.Context) (locked bool, err error) {
+	_, lock, err := sr.getReindexLock(ctx)
+	if err != nil {
+		return false, err
+	}
+	return lock.heldByOther("", time.Now()), nil
+}
+
+func (sr *searchReindexRepo) getReindexLock(ctx context.Context) (cfg *entity.Config, lock *reindexLock, err error) {
+	cfg = &entity.Config{}
+	exist, err := sr.data.DB.Context(ctx).Where(builder.Eq{"`key`": constant.SearchReindexLockKey}).Get(cfg)
+	if err != nil {
+		return nil, nil, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	if !exist {
+		return nil, nil, errors.InternalServer(reason.DatabaseError).WithMsg("the search reindex lock config is missing")
+	}
+	lock = &reindexLock{}
+	if len(cfg.Value) > 0 {
+		// a value which cannot be read is taken as a free lock
+		_ = json.Unmarshal([]byte(cfg.Value), lock)
+	}
+	return cfg, lock, nil
+}
+
+// swapReindexLock sets the value of the reindex lock config when it is still the old one
+func (sr *searchReindexRepo) swapReindexLock(ctx context.Context, oldValue, newValue string) (bool, error) {
+	affected, err := sr.data.DB.Context(ctx).
+		Where(builder.Eq{"`key`": constant.SearchReindexLockKey, "value": oldValue}).
+		Cols("value").
+		Update(&entity.Config{Value: newValue})
+	if err != nil {
+		return false, errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
+	}
+	return affected > 0, nil
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/apache/answer/internal/service/unique"
 	"github.com/apache/answer/pkg/uid"
 	"github.com/segmentfault/pacman/errors"
+	"xorm.io/builder"
 	"xorm.io/xorm"
 )
 
@@ -183,9 +184,11 @@ func (tr *tagRelRepo) BatchGetObjectTagRelList(ctx context.Context, objectIds []
 	return
 }
 
-// CountTagRelByTagID count tag relation
+// CountTagRelByTagID count tag relation of questions, the relations of forum topics are not counted
 func (tr *tagRelRepo) CountTagRelByTagID(ctx context.Context, tagID string) (count int64, err error) {
-	count, err = tr.data.DB.Context(ctx).Count(&entity.TagRel{TagID: tagID, Status: entity.AnswerStatusAvailable})
+	count, err = tr.data.DB.Context(ctx).
+		Where(builder.NotIn("object_id", builder.Select("id").From(entity.Topic{}.TableName()))).
+		Count(&entity.TagRel{TagID: tagID, Status: entity.AnswerStatusAvailable})
 	if err != nil {
 		err = errors.InternalServer(reason.DatabaseError).WithError(err).WithStack()
 	}
@@ -63,6 +63,9 @@ type AnswerAPIRouter struct {
 	aiConversationAdminController *controller_admin.AIConversationAdminController
 	mcpController                 *controller.MCPController
 	forumController               *controller.ForumController
+	searchAnalyticsController     *controller_admin.SearchAnalyticsController
+	searchReindexController       *controller_admin.SearchReindexController
+	savedSearchController         *controller.SavedSearchController
 }
 
 func NewAnswerAPIRouter(
@@ -102,6 +105,9 @@ func NewAnswerAPIRouter(
 	aiConversationAdminController *controller_admin.AIConversationAdminController,
 	mcpController *controller.MCPController,
 	forumController *controller.ForumController,
+	searchAnalyticsController *controller_admin.SearchAnalyticsController,
+	searchReindexController *controller_admin.SearchReindexController,
+	savedSearchController *controller.SavedSearchController,
 ) *AnswerAPIRouter {
 	return &AnswerAPIRouter{
 		langController:                langController,
@@ -140,6 +146,9 @@ func NewAnswerAPIRouter(
 		aiConversationAdminController: aiConversationAdminController,
 		mcpController:                 mcpController,
 		forumController:               forumController,
+		searchAnalyticsController:     searchAnalyticsController,
+		searchReindexController:       searchReindexController,
+		savedSearchController:         savedSearchController,
 	}
 }
 
@@ -204,6 +213,7 @@ func (a *AnswerAPIRouter) RegisterUnAuthAnswerAPIRouter(r *gin.RouterGroup) {
 	// search
 	r.GET("/search", a.searchController.Search)
 	r.GET("/search/desc", a.searchController.SearchDesc)
+	r.POST("/search/click", a.searchController.SearchClick)
 
 	// rank
 	r.GET("/personal/rank/page", a.rankController.GetRankPersonalWithPage)
@@ -235,6 +245,9 @@ func (a *AnswerAPIRouter) RegisterForumPublicAPIRouter(r *gin.RouterGroup) {
 	r.GET("/topics/:id/merge-jobs", a.forumController.ListMergeJobs)
 	r.GET("/topics/:id/merge-jobs/:jobId", a.forumController.GetMergeJob)
 	r.GET("/topics/:id/contributors", a.forumController.ListTopicContributors)
+	r.GET("/topics/:id/tags", a.forumController.GetTopicTags)
+	r.GET("/topics/:id/poll", a.forumController.GetTopicPoll)
+	r.GET("/tags/:name/contents", a.forumController.ListTagContents)
 	r.GET("/docs/graph", a.forumController.GetDocGraph)
 	r.GET("/platform/plugins", a.forumController.GetPlatformPlugins)
 	r.GET("/platform/config", a.forumController.GetPlatformConfig)
@@ -244,6 +257,10 @@ func (a *AnswerAPIRouter) RegisterForumAuthAPIRouter(r *gin.RouterGroup) {
 	r.POST("/categories", a.forumController.CreateCategory)
 	r.POST("/topics", a.forumController.CreateTopic)
 	r.POST("/topics/:id/posts", a.forumController.CreateTopicPost)
+	r.PUT("/topics/:id/tags", a.forumController.UpdateTopicTags)
+	r.PUT("/topics/:id/poll", a.forumController.SaveTopicPoll)
+	r.POST("/topics/:id/poll/votes", a.forumController.VoteTopicPoll)
+	r.POST("/topics/:id/summary", a.aiController.TopicSummary)
 
 	r.POST("/topics/:id/wiki/revisions", a.forumController.CreateTopicWikiRevision)
 	r.POST("/topics/:id/merge-jobs", a.forumController.CreateMergeJob)
@@ -301,6 +318,12 @@ func (a *AnswerAPIRouter) RegisterAnswerAPIRouter(r *gin.RouterGroup) {
 	r.POST("/collection/switch", a.collectionController.CollectionSwitch)
 	r.GET("/personal/collection/page", a.questionController.PersonalCollectionPage)
 
+	// saved search
+	r.GET("/saved-searches", a.savedSearchController.GetSavedSearchList)
+	r.POST("/saved-search", a.savedSearchController.AddSavedSearch)
+	r.PUT("/saved-search", a.savedSearchController.UpdateSavedSearch)
+	r.DELETE("/saved-search", a.savedSearchController.RemoveSavedSearch)
+
 	// question
 	r.POST("/question", a.questionController.AddQuestion)
 	r.POST("/question/answer", a.questionController.AddQuestionByAnswer)
@@ -311,6 +334,7 @@ func (a *AnswerAPIRouter) RegisterAnswerAPIRouter(r *gin.RouterGroup) {
 	r.PUT("/question/operation", a.questionController.OperationQuestion)
 	r.PUT("/question/reopen", a.questionController.ReopenQuestion)
 	r.GET("/question/similar", a.questionController.GetSimilarQuestions)
+	r.GET("/similar", a.searchController.SimilarContent)
 	r.POST("/question/recover", a.questionController.QuestionRecover)
 
 	// answer
@@ -367,6 +391,7 @@ func (a *AnswerAPIRouter) RegisterAnswerAPIRouter(r *gin.RouterGroup) {
 	r.GET("/ai/conversation/page", a.aiConversationController.GetConversationList)
 	r.GET("/ai/conversation", a.aiConversationController.GetConversationDetail)
 	r.POST("/ai/conversation/vote", a.aiConversationController.VoteRecord)
+	r.POST("/ai/conversation/publish", a.aiConversationController.PublishConversation)
 }
 
 func (a *AnswerAPIRouter) RegisterAnswerAdminAPIRouter(r *gin.RouterGroup) {
@@ -444,6 +469,7 @@ func (a *AnswerAPIRouter) RegisterAnswerAdminAPIRouter(r *gin.RouterGroup) {
 
 	// plugin
 	r.GET("/plugins", a.pluginController.GetPluginList)
+	r.GET("/plugins/health", a.pluginController.GetPluginHealth)
 	r.PUT("/plugin/status", a.pluginController.UpdatePluginStatus)
 	r.GET("/plugin/config", a.pluginController.GetPluginConfig)
 	r.PUT("/plugin/config", a.pluginController.UpdatePluginConfig)
@@ -462,7 +488,9 @@ func (a *AnswerAPIRouter) RegisterAnswerAdminAPIRouter(r *gin.RouterGroup) {
 	r.GET("/ai-config", a.adminSiteInfoController.GetAIConfig)
 	r.PUT("/ai-config", a.adminSiteInfoController.UpdateAIConfig)
 	r.GET("/ai-provider", a.adminSiteInfoController.GetAIProvider)
+	r.GET("/ai-provider/health", a.adminSiteInfoController.GetAIProviderHealth)
 	r.POST("/ai-models", a.adminSiteInfoController.RequestAIModels)
+	r.POST("/ai-prompt/preview", a.adminSiteInfoController.PreviewAIPrompt)
 
 	// mcp config
 	r.GET("/mcp-config", a.adminSiteInfoController.GetMCPConfig)
@@ -472,4 +500,17 @@ func (a *AnswerAPIRouter) RegisterAnswerAdminAPIRouter(r *gin.RouterGroup) {
 	r.GET("/ai/conversation/page", a.aiConversationAdminController.GetConversationList)
 	r.GET("/ai/conversation", a.aiConversationAdminController.GetConversationDetail)
 	r.DELETE("/ai/conversation", a.aiConversationAdminController.DeleteConversation)
+	r.GET("/ai/usage", a.aiConversationAdminController.GetUsageReport)
+
+	// search analytics
+	r.GET("/search/analytics", a.searchAnalyticsController.GetSearchAnalytics)
+
+	// search reindex
+	r.POST("/search/reindex", a.searchReindexController.StartSearchReindex)
+	r.GET("/search/reindex", a.searchReindexController.GetSearchReindexProgress)
+	r.GET("/search/verify", a.searchReindexController.VerifySearchIndex)
+
+	// forum conversion
+	r.POST("/forum/conversion/question", a.forumController.ConvertQuestionToTopic)
+	r.POST("/forum/conversion/topic", a.forumController.ConvertTopicToQuestion)
 }
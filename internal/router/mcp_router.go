@@ -26,7 +26,9 @@ import (
 )
 
 func (a *AnswerAPIRouter) RegisterMCPRouter(r *gin.RouterGroup) {
-	s := server.NewMCPServer("Answer Enterprise MCP Server", "1.0.0")
+	s := server.NewMCPServer("Answer Enterprise MCP Server", "1.0.0",
+		server.WithToolFilter(a.mcpController.MCPToolFilter),
+	)
 
 	s.AddTool(mcp_tools.NewQuestionsTool(), a.mcpController.MCPQuestionsHandler())
 	s.AddTool(mcp_tools.NewAnswersTool(), a.mcpController.MCPAnswersHandler())
@@ -34,6 +36,19 @@ func (a *AnswerAPIRouter) RegisterMCPRouter(r *gin.RouterGroup) {
 	s.AddTool(mcp_tools.NewTagsTool(), a.mcpController.MCPTagsHandler())
 	s.AddTool(mcp_tools.NewTagDetailTool(), a.mcpController.MCPTagDetailsHandler())
 	s.AddTool(mcp_tools.NewUserTool(), a.mcpController.MCPUserDetailsHandler())
+	s.AddTool(mcp_tools.NewCategoriesTool(), a.mcpController.MCPCategoriesHandler())
+	s.AddTool(mcp_tools.NewTopicsTool(), a.mcpController.MCPTopicsHandler())
+	s.AddTool(mcp_tools.NewTopicPostsTool(), a.mcpController.MCPTopicPostsHandler())
+	s.AddTool(mcp_tools.NewTopicWikiTool(), a.mcpController.MCPTopicWikiHandler())
+	s.AddTool(mcp_tools.NewWikiRevisionsTool(), a.mcpController.MCPWikiRevisionsHandler())
+	s.AddTool(mcp_tools.NewDocGraphTool(), a.mcpController.MCPDocGraphHandler())
+
+	// write tools are only listed for, and callable by, API keys that enable them
+	s.AddTool(mcp_tools.NewCreateQuestionTool(), a.mcpController.MCPCreateQuestionHandler())
+	s.AddTool(mcp_tools.NewPostAnswerTool(), a.mcpController.MCPPostAnswerHandler())
+	s.AddTool(mcp_tools.NewAddCommentTool(), a.mcpController.MCPAddCommentHandler())
+	s.AddTool(mcp_tools.NewCreateForumPostTool(), a.mcpController.MCPCreateForumPostHandler())
+	s.AddTool(mcp_tools.NewProposeMergeJobTool(), a.mcpController.MCPProposeMergeJobHandler())
 
 	sseServer := server.NewSSEServer(s,
 		server.WithSSEEndpoint("/answer/api/v1/mcp/see"),
@@ -41,4 +56,12 @@ func (a *AnswerAPIRouter) RegisterMCPRouter(r *gin.RouterGroup) {
 	)
 	r.GET("/mcp/sse", gin.WrapH(sseServer.SSEHandler()))
 	r.POST("/mcp/message", gin.WrapH(sseServer.MessageHandler()))
+
+	// Streamable HTTP transport, served next to the legacy SSE transport
+	streamableServer := server.NewStreamableHTTPServer(s,
+		server.WithEndpointPath("/answer/api/v1/mcp"),
+	)
+	r.POST("/mcp", gin.WrapH(streamableServer))
+	r.GET("/mcp", gin.WrapH(streamableServer))
+	r.DELETE("/mcp", gin.WrapH(streamableServer))
 }
@@ -72,4 +72,7 @@ func (a *TemplateRouter) RegisterTemplateRouter(r *gin.RouterGroup, baseURLPath
 	seo.GET("/tags", a.templateController.TagList)
 	seo.GET("/tags/:tag", a.templateController.TagInfo)
 	seo.GET("/users/:username", a.templateController.UserInfo)
+	seo.GET("/categories/:id", a.templateController.CategoryInfo)
+	seo.GET("/topics/:id", a.templateController.TopicInfo)
+	seo.GET("/topics/:id/wiki", a.templateController.TopicWiki)
 }
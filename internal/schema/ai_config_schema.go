@@ -49,3 +49,18 @@ type GetAIModelResp struct {
 	Created int    `json:"created"`
 	OwnedBy string `json:"owned_by"`
 }
+
+// AIProviderHealthResp AI provider health probe response
+type AIProviderHealthResp struct {
+	Provider  string `json:"provider"`
+	APIType   string `json:"api_type"`
+	Model     string `json:"model"`
+	Chosen    bool   `json:"chosen"`
+	Fallback  bool   `json:"fallback"`
+	Priority  int    `json:"priority"`
+	Healthy   bool   `json:"healthy"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	// CooldownUntil is the unix time until which a failed provider is tried after the others
+	CooldownUntil int64 `json:"cooldown_until,omitempty"`
+}
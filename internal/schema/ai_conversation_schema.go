@@ -43,14 +43,31 @@ type AIConversationDetailReq struct {
 	UserID         string `validate:"omitempty" json:"-"`
 }
 
+const (
+	AIConversationSourceQuestion = "question"
+	AIConversationSourceAnswer   = "answer"
+	AIConversationSourceComment  = "comment"
+	AIConversationSourceTopic    = "topic"
+	AIConversationSourceWiki     = "wiki"
+)
+
+// AIConversationSource site content the AI used to answer
+type AIConversationSource struct {
+	ObjectType string `json:"object_type"`
+	ObjectID   string `json:"object_id"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+}
+
 // AIConversationRecord ai conversation record
 type AIConversationRecord struct {
-	ChatCompletionID string `json:"chat_completion_id"`
-	Role             string `json:"role"`
-	Content          string `json:"content"`
-	Helpful          int    `json:"helpful"`
-	Unhelpful        int    `json:"unhelpful"`
-	CreatedAt        int64  `json:"created_at"`
+	ChatCompletionID string                  `json:"chat_completion_id"`
+	Role             string                  `json:"role"`
+	Content          string                  `json:"content"`
+	Helpful          int                     `json:"helpful"`
+	Unhelpful        int                     `json:"unhelpful"`
+	Sources          []*AIConversationSource `json:"sources,omitempty"`
+	CreatedAt        int64                   `json:"created_at"`
 }
 
 // AIConversationDetailResp ai conversation detail resp
@@ -60,6 +77,8 @@ type AIConversationDetailResp struct {
 	Records        []*AIConversationRecord `json:"records"`
 	CreatedAt      int64                   `json:"created_at"`
 	UpdatedAt      int64                   `json:"updated_at"`
+	// QuestionID is the question the conversation was published as
+	QuestionID string `json:"question_id,omitempty"`
 }
 
 // AIConversationVoteReq ai conversation vote req
@@ -107,6 +126,9 @@ type AIConversationAdminDetailResp struct {
 	UserInfo       AIConversationUserInfo `json:"user_info"`
 	Records        []AIConversationRecord `json:"records"`
 	CreatedAt      int64                  `json:"created_at"`
+	// Summary is the running summary standing in for the earlier records sent to the model
+	Summary    string `json:"summary,omitempty"`
+	QuestionID string `json:"question_id,omitempty"`
 }
 
 // AIConversationAdminDeleteReq admin delete ai
@@ -114,6 +136,24 @@ type AIConversationAdminDeleteReq struct {
 	ConversationID string `validate:"required" json:"conversation_id"`
 }
 
+// AIConversationPublishReq publishes a conversation as a question with the last reply as its answer,
+// the title and content default to the first prompt
+type AIConversationPublishReq struct {
+	ConversationID string     `validate:"required" json:"conversation_id"`
+	Title          string     `validate:"omitempty,notblank,gte=6,lte=150" json:"title"`
+	Content        string     `validate:"omitempty,lte=65535" json:"content"`
+	Tags           []*TagItem `validate:"dive" json:"tags"`
+	CaptchaID      string     `json:"captcha_id"`
+	CaptchaCode    string     `json:"captcha_code"`
+	UserID         string     `json:"-"`
+}
+
+// AIConversationPublishResp ai conversation publish resp
+type AIConversationPublishResp struct {
+	QuestionID string `json:"question_id"`
+	AnswerID   string `json:"answer_id"`
+}
+
 func (req *AIConversationDetailReq) Check() (errFields []*validator.FormErrorField, err error) {
 	return nil, nil
 }
@@ -121,3 +161,53 @@ func (req *AIConversationDetailReq) Check() (errFields []*validator.FormErrorFie
 func (req *AIConversationVoteReq) Check() (errFields []*validator.FormErrorField, err error) {
 	return nil, nil
 }
+
+// AIUsageReportReq ai usage report req, the dates are inclusive and default to the last 30 days
+type AIUsageReportReq struct {
+	StartDate string `validate:"omitempty,datetime=2006-01-02" form:"start_date"`
+	EndDate   string `validate:"omitempty,datetime=2006-01-02" form:"end_date"`
+}
+
+// AIUsageStat ai token usage
+type AIUsageStat struct {
+	Requests         int64 `json:"requests"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// Add adds the usage of one reply
+func (s *AIUsageStat) Add(promptTokens, completionTokens int) {
+	s.Requests++
+	s.PromptTokens += int64(promptTokens)
+	s.CompletionTokens += int64(completionTokens)
+	s.TotalTokens += int64(promptTokens + completionTokens)
+}
+
+// AIUsageUserStat ai token usage of a user
+type AIUsageUserStat struct {
+	UserInfo AIConversationUserInfo `json:"user_info"`
+	AIUsageStat
+}
+
+// AIUsageModelStat ai token usage of a model
+type AIUsageModelStat struct {
+	Model string `json:"model"`
+	AIUsageStat
+}
+
+// AIUsageDayStat ai token usage of a day
+type AIUsageDayStat struct {
+	Date string `json:"date"`
+	AIUsageStat
+}
+
+// AIUsageReportResp ai usage report resp
+type AIUsageReportResp struct {
+	StartDate string              `json:"start_date"`
+	EndDate   string              `json:"end_date"`
+	Total     AIUsageStat         `json:"total"`
+	Users     []*AIUsageUserStat  `json:"users"`
+	Models    []*AIUsageModelStat `json:"models"`
+	Days      []*AIUsageDayStat   `json:"days"`
+}
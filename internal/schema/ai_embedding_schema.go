@@ -0,0 +1,59 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package schema
+
+// AIRetrievedPassage passage found by semantic retrieval for the AI chat prompt
+type AIRetrievedPassage struct {
+	ObjectType string  `json:"object_type"`
+	ObjectID   string  `json:"object_id"`
+	QuestionID string  `json:"question_id,omitempty"`
+	Title      string  `json:"title"`
+	Content    string  `json:"content"`
+	URL        string  `json:"url"`
+	Score      float64 `json:"score"`
+}
+
+const (
+	SimilarContentMethodEmbedding = "embedding"
+	SimilarContentMethodBM25      = "bm25"
+)
+
+// SimilarContentReq finds the existing content similar to the title of a question or topic being written
+type SimilarContentReq struct {
+	Title string `validate:"required,notblank,lte=180" form:"title"`
+	Limit int    `validate:"omitempty,min=1,max=20" form:"limit"`
+}
+
+// SimilarContentItem existing question, topic or wiki document similar to the title
+type SimilarContentItem struct {
+	ObjectType string `json:"object_type"`
+	ObjectID   string `json:"object_id"`
+	Title      string `json:"title"`
+	Excerpt    string `json:"excerpt"`
+	URL        string `json:"url"`
+	// Confidence from 0 to 1 that the item is a duplicate of the title
+	Confidence float64 `json:"confidence"`
+}
+
+// SimilarContentResp similar content resp, the method tells whether the embedding index or keywords were used
+type SimilarContentResp struct {
+	Method string                `json:"method"`
+	List   []*SimilarContentItem `json:"list"`
+}
@@ -32,13 +32,17 @@ type GetAPIKeyResp struct {
 	Scope       string `json:"scope"`
 	CreatedAt   int64  `json:"created_at"`
 	LastUsedAt  int64  `json:"last_used_at"`
+	// MCPWriteTools the MCP write tools this key may call
+	MCPWriteTools []string `json:"mcp_write_tools"`
 }
 
 // AddAPIKeyReq add api key request
 type AddAPIKeyReq struct {
 	Description string `validate:"required,notblank,lte=150" json:"description"`
 	Scope       string `validate:"required,oneof=read-only global" json:"scope"`
-	UserID      string `json:"-"`
+	// MCPWriteTools the MCP write tools this key may call, none by default
+	MCPWriteTools []string `validate:"omitempty,dive,oneof=create_question post_answer add_comment create_forum_post propose_merge_job" json:"mcp_write_tools"`
+	UserID        string   `json:"-"`
 }
 
 // AddAPIKeyResp add api key response
@@ -50,7 +54,9 @@ type AddAPIKeyResp struct {
 type UpdateAPIKeyReq struct {
 	ID          int    `validate:"required" json:"id"`
 	Description string `validate:"required,notblank,lte=150" json:"description"`
-	UserID      string `json:"-"`
+	// MCPWriteTools the MCP write tools this key may call, none by default
+	MCPWriteTools []string `validate:"omitempty,dive,oneof=create_question post_answer add_comment create_forum_post propose_merge_job" json:"mcp_write_tools"`
+	UserID        string   `json:"-"`
 }
 
 // DeleteAPIKeyReq delete api key request
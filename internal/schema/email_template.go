@@ -143,3 +143,40 @@ type NewQuestionTemplateData struct {
 	Tags           string
 	UnsubscribeUrl string
 }
+
+type SavedSearchDigestTemplateRawData struct {
+	SearchName string
+	Query      string
+	Matches    []*SavedSearchDigestMatch
+}
+
+// SavedSearchDigestMatch a new result of a saved search, the url is set when the email is rendered
+type SavedSearchDigestMatch struct {
+	ObjectType string
+	ObjectID   string
+	QuestionID string
+	Title      string
+	Url        string
+}
+
+type SavedSearchDigestTemplateData struct {
+	SiteName   string
+	SearchName string
+	SearchUrl  string
+	MatchCount int
+	Matches    []*SavedSearchDigestMatch
+}
+
+type PluginDisabledTemplateRawData struct {
+	PluginSlugName string
+	Failures       int
+	Reason         string
+}
+
+type PluginDisabledTemplateData struct {
+	SiteName       string
+	PluginSlugName string
+	Failures       int
+	Reason         string
+	PluginsUrl     string
+}
@@ -20,6 +20,8 @@
 package schema
 
 import (
+	"time"
+
 	"github.com/apache/answer/internal/base/constant"
 	"github.com/apache/answer/pkg/uid"
 )
@@ -41,6 +43,7 @@ type EventMsg struct {
 	CommentUserID string
 
 	ExtraInfo map[string]string
+	CreatedAt time.Time
 }
 
 // NewEvent create a new event
@@ -49,6 +52,7 @@ func NewEvent(e constant.EventType, userID string) *EventMsg {
 		UserID:    userID,
 		EventType: e,
 		ExtraInfo: make(map[string]string),
+		CreatedAt: time.Now(),
 	}
 }
 
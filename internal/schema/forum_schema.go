@@ -19,6 +19,8 @@
 
 package schema
 
+import "github.com/apache/answer/internal/entity"
+
 type CreateCategoryReq struct {
 	Slug        string `validate:"required,gt=1,lte=100" json:"slug"`
 	Name        string `validate:"required,gt=1,lte=120" json:"name"`
@@ -27,11 +29,20 @@ type CreateCategoryReq struct {
 }
 
 type CreateTopicReq struct {
-	CategoryID    string `validate:"required" json:"category_id"`
-	Title         string `validate:"required,gt=1,lte=180" json:"title"`
-	TopicKind     string `validate:"required,oneof=discussion knowledge" json:"topic_kind"`
-	IsWikiEnabled bool   `json:"is_wiki_enabled"`
-	UserID        string `json:"-"`
+	CategoryID        string   `validate:"required" json:"category_id"`
+	Title             string   `validate:"required,gt=1,lte=180" json:"title"`
+	TopicKind         string   `validate:"required,oneof=discussion knowledge" json:"topic_kind"`
+	IsWikiEnabled     bool     `json:"is_wiki_enabled"`
+	Tags              []string `validate:"omitempty,lte=5,dive,gt=0,lte=35" json:"tags"`
+	UserID            string   `json:"-"`
+	CanUseReservedTag bool     `json:"-"`
+}
+
+type UpdateTopicTagsReq struct {
+	Tags              []string `validate:"omitempty,lte=5,dive,gt=0,lte=35" json:"tags"`
+	UserID            string   `json:"-"`
+	IsAdminModerator  bool     `json:"-"`
+	CanUseReservedTag bool     `json:"-"`
 }
 
 type CreatePostReq struct {
@@ -79,12 +90,74 @@ type SetTopicSolutionReq struct {
 	UserID string `json:"-"`
 }
 
+// TopicPollReq creates or edits the poll of a topic. CloseAt is a unix timestamp, 0 means the poll stays open.
+type TopicPollReq struct {
+	Question  string   `validate:"required,gt=1,lte=255" json:"question"`
+	Options   []string `validate:"required,min=2,max=20,dive,required,notblank,lte=200" json:"options"`
+	Multiple  bool     `json:"multiple"`
+	Anonymous bool     `json:"anonymous"`
+	CloseAt   int64    `validate:"omitempty,min=0" json:"close_at"`
+	UserID    string   `json:"-"`
+}
+
+type TopicPollVoteReq struct {
+	OptionIDs []string `validate:"required,min=1,max=20" json:"option_ids"`
+	UserID    string   `json:"-"`
+}
+
+type TopicPollResp struct {
+	ID             string                 `json:"id"`
+	TopicID        string                 `json:"topic_id"`
+	Question       string                 `json:"question"`
+	Multiple       bool                   `json:"multiple"`
+	Anonymous      bool                   `json:"anonymous"`
+	Status         string                 `json:"status"`
+	CloseAt        int64                  `json:"close_at"`
+	VoterCount     int                    `json:"voter_count"`
+	Options        []*TopicPollOptionResp `json:"options"`
+	VotedOptionIDs []string               `json:"voted_option_ids"`
+}
+
+type TopicPollOptionResp struct {
+	ID        string            `json:"id"`
+	Title     string            `json:"title"`
+	VoteCount int               `json:"vote_count"`
+	Voters    []*TopicPollVoter `json:"voters,omitempty"`
+}
+
+type TopicPollVoter struct {
+	UserID      string `json:"user_id" xorm:"user_id"`
+	Username    string `json:"username" xorm:"username"`
+	DisplayName string `json:"display_name" xorm:"display_name"`
+	OptionID    string `json:"-" xorm:"option_id"`
+}
+
+// TopicDetailResp is the topic returned by the topic API together with its poll, if any.
+// A topic converted into a question carries where the question lives now.
+type TopicDetailResp struct {
+	*entity.Topic
+	Poll        *TopicPollResp        `json:"poll,omitempty"`
+	ConvertedTo *ObjectConversionResp `json:"converted_to,omitempty"`
+}
+
+// ObjectConversionResp is the object converted content lives in now.
+type ObjectConversionResp struct {
+	ObjectType string `json:"object_type"`
+	ObjectID   string `json:"object_id"`
+}
+
 type ForumVoteReq struct {
 	Value  int    `validate:"required,oneof=-1 1" json:"value"`
 	UserID string `json:"-"`
 }
 
 type TopicListReq struct {
+	Page     int    `validate:"omitempty,min=1" form:"page"`
+	PageSize int    `validate:"omitempty,min=1,max=100" form:"page_size"`
+	Tag      string `validate:"omitempty,lte=35" form:"tag"`
+}
+
+type TagContentListReq struct {
 	Page     int `validate:"omitempty,min=1" form:"page"`
 	PageSize int `validate:"omitempty,min=1,max=100" form:"page_size"`
 }
@@ -107,3 +180,15 @@ type GetDocGraphReq struct {
 type PlatformPluginConfigReq struct {
 	ConfigFields map[string]any `json:"config_fields"`
 }
+
+type ConvertQuestionToTopicReq struct {
+	QuestionID string `validate:"required" json:"question_id"`
+	CategoryID string `validate:"required" json:"category_id"`
+	TopicKind  string `validate:"omitempty,oneof=discussion knowledge" json:"topic_kind"`
+	OperatorID string `json:"-"`
+}
+
+type ConvertTopicToQuestionReq struct {
+	TopicID    string `validate:"required" json:"topic_id"`
+	OperatorID string `json:"-"`
+}
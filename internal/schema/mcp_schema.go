@@ -36,8 +36,35 @@ const (
 	MCPSearchCondTagName    = "tag_name"
 	MCPSearchCondQuestionID = "question_id"
 	MCPSearchCondObjectID   = "object_id"
+	MCPSearchCondCategoryID = "category_id"
+	MCPSearchCondTopicID    = "topic_id"
+	MCPSearchCondDepth      = "depth"
+
+	MCPWriteCondTitle          = "title"
+	MCPWriteCondContent        = "content"
+	MCPWriteCondReplyCommentID = "reply_comment_id"
+	MCPWriteCondPostIDs        = "post_ids"
+	MCPWriteCondSummary        = "summary"
+)
+
+// MCP tools that change content. Every one of them has to be enabled on the calling API key.
+const (
+	MCPToolCreateQuestion  = "create_question"
+	MCPToolPostAnswer      = "post_answer"
+	MCPToolAddComment      = "add_comment"
+	MCPToolCreateForumPost = "create_forum_post"
+	MCPToolProposeMergeJob = "propose_merge_job"
 )
 
+// MCPWriteTools all MCP write tools, in the order they are listed
+var MCPWriteTools = []string{
+	MCPToolCreateQuestion,
+	MCPToolPostAnswer,
+	MCPToolAddComment,
+	MCPToolCreateForumPost,
+	MCPToolProposeMergeJob,
+}
+
 type MCPSearchCond struct {
 	Keyword    string   `json:"keyword"`
 	Username   string   `json:"username"`
@@ -98,6 +125,108 @@ type MCPSearchCommentInfoResp struct {
 	Link      string `json:"link"`
 }
 
+type MCPForumCond struct {
+	CategoryID string `json:"category_id"`
+	TopicID    string `json:"topic_id"`
+	Tag        string `json:"tag"`
+	Page       int    `json:"page"`
+	Depth      int    `json:"depth"`
+}
+
+type MCPWriteCond struct {
+	Title          string   `json:"title"`
+	Content        string   `json:"content"`
+	Tags           []string `json:"tags"`
+	QuestionID     string   `json:"question_id"`
+	ObjectID       string   `json:"object_id"`
+	ReplyCommentID string   `json:"reply_comment_id"`
+	TopicID        string   `json:"topic_id"`
+	PostIDs        []string `json:"post_ids"`
+	Summary        string   `json:"summary"`
+}
+
+type MCPWriteResp struct {
+	ObjectType string `json:"object_type"`
+	ObjectID   string `json:"object_id"`
+	// Pending the content waits in the review queue and is not visible yet
+	Pending bool   `json:"pending"`
+	Link    string `json:"link"`
+}
+
+type MCPCategoryResp struct {
+	CategoryID  string `json:"category_id"`
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Link        string `json:"link"`
+}
+
+type MCPTopicResp struct {
+	TopicID   string `json:"topic_id"`
+	Title     string `json:"title"`
+	TopicKind string `json:"topic_kind"`
+	Status    string `json:"status"`
+	PostCount int    `json:"post_count"`
+	VoteCount int    `json:"vote_count"`
+	HasWiki   bool   `json:"has_wiki"`
+	Link      string `json:"link"`
+}
+
+type MCPPostResp struct {
+	PostID     string `json:"post_id"`
+	Author     string `json:"author"`
+	Content    string `json:"content"`
+	VoteCount  int    `json:"vote_count"`
+	MergeState string `json:"merge_state"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+type MCPTopicPostsResp struct {
+	TopicID string         `json:"topic_id"`
+	Title   string         `json:"title"`
+	Total   int64          `json:"total"`
+	Posts   []*MCPPostResp `json:"posts"`
+	Link    string         `json:"link"`
+}
+
+type MCPWikiResp struct {
+	TopicID    string `json:"topic_id"`
+	RevisionID string `json:"revision_id"`
+	Title      string `json:"title"`
+	Document   string `json:"document"`
+	Summary    string `json:"summary"`
+	EditorID   string `json:"editor_id"`
+	CreatedAt  int64  `json:"created_at"`
+	Link       string `json:"link"`
+}
+
+type MCPWikiRevisionResp struct {
+	RevisionID       string `json:"revision_id"`
+	ParentRevisionID string `json:"parent_revision_id"`
+	Title            string `json:"title"`
+	Summary          string `json:"summary"`
+	EditorID         string `json:"editor_id"`
+	Current          bool   `json:"current"`
+	CreatedAt        int64  `json:"created_at"`
+}
+
+type MCPDocGraphResp struct {
+	Nodes []*MCPDocGraphNode `json:"nodes"`
+	Edges []*MCPDocGraphEdge `json:"edges"`
+}
+
+type MCPDocGraphNode struct {
+	TopicID string `json:"topic_id"`
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+}
+
+type MCPDocGraphEdge struct {
+	SourceTopicID string `json:"source_topic_id"`
+	TargetTopicID string `json:"target_topic_id"`
+	LinkType      string `json:"link_type"`
+}
+
 func NewMCPSearchCond(request mcp.CallToolRequest) *MCPSearchCond {
 	cond := &MCPSearchCond{}
 	if keyword, ok := getRequestValue(request, MCPSearchCondKeyword); ok {
@@ -158,6 +287,54 @@ func NewMCPSearchUserCond(request mcp.CallToolRequest) *MCPSearchUserCond {
 	return cond
 }
 
+func NewMCPForumCond(request mcp.CallToolRequest) *MCPForumCond {
+	cond := &MCPForumCond{Page: 1}
+	if categoryID, ok := getRequestValue(request, MCPSearchCondCategoryID); ok {
+		cond.CategoryID = categoryID
+	}
+	if topicID, ok := getRequestValue(request, MCPSearchCondTopicID); ok {
+		cond.TopicID = topicID
+	}
+	if tag, ok := getRequestValue(request, MCPSearchCondTag); ok {
+		cond.Tag = tag
+	}
+	if page, ok := getRequestNumber(request, MCPSearchCondPage); ok && page > 0 {
+		cond.Page = page
+	}
+	if depth, ok := getRequestNumber(request, MCPSearchCondDepth); ok {
+		cond.Depth = depth
+	}
+	return cond
+}
+
+func NewMCPWriteCond(request mcp.CallToolRequest) *MCPWriteCond {
+	cond := &MCPWriteCond{}
+	cond.Title, _ = getRequestValue(request, MCPWriteCondTitle)
+	cond.Content, _ = getRequestValue(request, MCPWriteCondContent)
+	cond.QuestionID, _ = getRequestValue(request, MCPSearchCondQuestionID)
+	cond.ObjectID, _ = getRequestValue(request, MCPSearchCondObjectID)
+	cond.ReplyCommentID, _ = getRequestValue(request, MCPWriteCondReplyCommentID)
+	cond.TopicID, _ = getRequestValue(request, MCPSearchCondTopicID)
+	cond.Summary, _ = getRequestValue(request, MCPWriteCondSummary)
+	if tag, ok := getRequestValue(request, MCPSearchCondTag); ok {
+		cond.Tags = splitRequestList(tag)
+	}
+	if postIDs, ok := getRequestValue(request, MCPWriteCondPostIDs); ok {
+		cond.PostIDs = splitRequestList(postIDs)
+	}
+	return cond
+}
+
+func splitRequestList(value string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func getRequestValue(request mcp.CallToolRequest, key string) (string, bool) {
 	value, ok := request.GetArguments()[key].(string)
 	if !ok {
@@ -32,6 +32,21 @@ var (
 		NewTagsTool(),
 		NewTagDetailTool(),
 		NewUserTool(),
+		NewCategoriesTool(),
+		NewTopicsTool(),
+		NewTopicPostsTool(),
+		NewTopicWikiTool(),
+		NewWikiRevisionsTool(),
+		NewDocGraphTool(),
+	}
+
+	// MCPWriteToolsList tools that change content. They are only offered to API keys that enable them.
+	MCPWriteToolsList = []mcp.Tool{
+		NewCreateQuestionTool(),
+		NewPostAnswerTool(),
+		NewAddCommentTool(),
+		NewCreateForumPostTool(),
+		NewProposeMergeJobTool(),
 	}
 )
 
@@ -103,3 +118,164 @@ func NewUserTool() mcp.Tool {
 	)
 	return listFilesTool
 }
+
+func NewCategoriesTool() mcp.Tool {
+	listFilesTool := mcp.NewTool("get_categories",
+		mcp.WithDescription("List the forum categories. Use get_topics with a category ID to list its topics."),
+		mcp.WithNumber(schema.MCPSearchCondPage,
+			mcp.Description("Page number, starting from 1"),
+		),
+	)
+	return listFilesTool
+}
+
+func NewTopicsTool() mcp.Tool {
+	listFilesTool := mcp.NewTool("get_topics",
+		mcp.WithDescription("List the topics of a forum category, newest first. The category ID is provided by get_categories tool."),
+		mcp.WithString(schema.MCPSearchCondCategoryID,
+			mcp.Description("The ID of the category"),
+			mcp.Required(),
+		),
+		mcp.WithString(schema.MCPSearchCondTag,
+			mcp.Description("Only list topics with this tag"),
+		),
+		mcp.WithNumber(schema.MCPSearchCondPage,
+			mcp.Description("Page number, starting from 1"),
+		),
+	)
+	return listFilesTool
+}
+
+func NewTopicPostsTool() mcp.Tool {
+	listFilesTool := mcp.NewTool("get_topic_posts",
+		mcp.WithDescription("Get the posts of a forum topic in the order they were written. Archived posts were merged into the topic wiki."),
+		mcp.WithString(schema.MCPSearchCondTopicID,
+			mcp.Description("The ID of the topic. The topic ID is provided by get_topics tool."),
+			mcp.Required(),
+		),
+		mcp.WithNumber(schema.MCPSearchCondPage,
+			mcp.Description("Page number, starting from 1"),
+		),
+	)
+	return listFilesTool
+}
+
+func NewTopicWikiTool() mcp.Tool {
+	listFilesTool := mcp.NewTool("get_topic_wiki",
+		mcp.WithDescription("Get the current wiki document of a forum topic. Knowledge topics keep their curated answer here."),
+		mcp.WithString(schema.MCPSearchCondTopicID,
+			mcp.Description("The ID of the topic"),
+			mcp.Required(),
+		),
+	)
+	return listFilesTool
+}
+
+func NewWikiRevisionsTool() mcp.Tool {
+	listFilesTool := mcp.NewTool("get_wiki_revisions",
+		mcp.WithDescription("Get the revision history of the wiki document of a forum topic, newest first."),
+		mcp.WithString(schema.MCPSearchCondTopicID,
+			mcp.Description("The ID of the topic"),
+			mcp.Required(),
+		),
+	)
+	return listFilesTool
+}
+
+func NewDocGraphTool() mcp.Tool {
+	listFilesTool := mcp.NewTool("get_doc_graph",
+		mcp.WithDescription("Get the wiki documents linked from a forum topic, following links up to the given depth."),
+		mcp.WithString(schema.MCPSearchCondTopicID,
+			mcp.Description("The ID of the topic to start from"),
+			mcp.Required(),
+		),
+		mcp.WithNumber(schema.MCPSearchCondDepth,
+			mcp.Description("How many links to follow, from 1 to 5. Defaults to 2."),
+		),
+	)
+	return listFilesTool
+}
+
+func NewCreateQuestionTool() mcp.Tool {
+	listFilesTool := mcp.NewTool(schema.MCPToolCreateQuestion,
+		mcp.WithDescription("Ask a new question as the owner of the API key. Search with get_questions first to avoid duplicates. The question may wait in the review queue before it is visible."),
+		mcp.WithString(schema.MCPWriteCondTitle,
+			mcp.Description("Title of the question, 6 to 150 characters"),
+			mcp.Required(),
+		),
+		mcp.WithString(schema.MCPWriteCondContent,
+			mcp.Description("Body of the question in Markdown"),
+			mcp.Required(),
+		),
+		mcp.WithString(schema.MCPSearchCondTag,
+			mcp.Description("Tag slug names, comma separated"),
+		),
+	)
+	return listFilesTool
+}
+
+func NewPostAnswerTool() mcp.Tool {
+	listFilesTool := mcp.NewTool(schema.MCPToolPostAnswer,
+		mcp.WithDescription("Answer a question as the owner of the API key. The answer may wait in the review queue before it is visible."),
+		mcp.WithString(schema.MCPSearchCondQuestionID,
+			mcp.Description("The ID of the question to answer. The question ID is provided by get_questions tool."),
+			mcp.Required(),
+		),
+		mcp.WithString(schema.MCPWriteCondContent,
+			mcp.Description("Body of the answer in Markdown"),
+			mcp.Required(),
+		),
+	)
+	return listFilesTool
+}
+
+func NewAddCommentTool() mcp.Tool {
+	listFilesTool := mcp.NewTool(schema.MCPToolAddComment,
+		mcp.WithDescription("Comment on a question or an answer as the owner of the API key."),
+		mcp.WithString(schema.MCPSearchCondObjectID,
+			mcp.Description("The ID of the question or answer to comment on"),
+			mcp.Required(),
+		),
+		mcp.WithString(schema.MCPWriteCondContent,
+			mcp.Description("Text of the comment, 2 to 600 characters"),
+			mcp.Required(),
+		),
+		mcp.WithString(schema.MCPWriteCondReplyCommentID,
+			mcp.Description("The ID of the comment this one replies to"),
+		),
+	)
+	return listFilesTool
+}
+
+func NewCreateForumPostTool() mcp.Tool {
+	listFilesTool := mcp.NewTool(schema.MCPToolCreateForumPost,
+		mcp.WithDescription("Reply to a forum topic as the owner of the API key. The topic ID is provided by get_topics tool."),
+		mcp.WithString(schema.MCPSearchCondTopicID,
+			mcp.Description("The ID of the topic to reply to"),
+			mcp.Required(),
+		),
+		mcp.WithString(schema.MCPWriteCondContent,
+			mcp.Description("Body of the post in Markdown"),
+			mcp.Required(),
+		),
+	)
+	return listFilesTool
+}
+
+func NewProposeMergeJobTool() mcp.Tool {
+	listFilesTool := mcp.NewTool(schema.MCPToolProposeMergeJob,
+		mcp.WithDescription("Propose merging posts of a forum topic into its wiki document. A moderator or the topic owner reviews and applies the merge job."),
+		mcp.WithString(schema.MCPSearchCondTopicID,
+			mcp.Description("The ID of the topic"),
+			mcp.Required(),
+		),
+		mcp.WithString(schema.MCPWriteCondPostIDs,
+			mcp.Description("IDs of the posts to merge, comma separated. Post IDs are provided by get_topic_posts tool."),
+			mcp.Required(),
+		),
+		mcp.WithString(schema.MCPWriteCondSummary,
+			mcp.Description("Short note for the reviewer, up to 500 characters"),
+		),
+	)
+	return listFilesTool
+}
@@ -33,6 +33,9 @@ type ExternalNotificationMsg struct {
 	NewInviteAnswerTemplateRawData *NewInviteAnswerTemplateRawData `json:"new_invite_answer_template_raw_data,omitempty"`
 	NewCommentTemplateRawData      *NewCommentTemplateRawData      `json:"new_comment_template_raw_data,omitempty"`
 	NewQuestionTemplateRawData     *NewQuestionTemplateRawData     `json:"new_question_template_raw_data,omitempty"`
+
+	SavedSearchDigestTemplateRawData *SavedSearchDigestTemplateRawData `json:"saved_search_digest_template_raw_data,omitempty"`
+	PluginDisabledTemplateRawData    *PluginDisabledTemplateRawData    `json:"plugin_disabled_template_raw_data,omitempty"`
 }
 
 func CreateNewQuestionNotificationMsg(
@@ -46,6 +46,13 @@ type GetPluginListResp struct {
 	Link        string `json:"link"`
 }
 
+// GetPluginHealthResp the health of the calls of a plugin, the plugins not called yet have no calls
+type GetPluginHealthResp struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	plugin.Health
+}
+
 type GetAllPluginStatusResp struct {
 	SlugName string `json:"slug_name"`
 	Enabled  bool   `json:"enabled"`
@@ -0,0 +1,61 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package schema
+
+// AddSavedSearchReq add saved search request, the results found now are not alerted
+type AddSavedSearchReq struct {
+	Name        string `validate:"required,notblank,lte=100" json:"name"`
+	Query       string `validate:"required,notblank,lte=60" json:"query"`
+	NotifyInbox bool   `json:"notify_inbox"`
+	NotifyEmail bool   `json:"notify_email"`
+	UserID      string `json:"-"`
+}
+
+// UpdateSavedSearchReq update saved search request, the results found now are not alerted when the query changes
+type UpdateSavedSearchReq struct {
+	ID          int    `validate:"required" json:"id"`
+	Name        string `validate:"required,notblank,lte=100" json:"name"`
+	Query       string `validate:"required,notblank,lte=60" json:"query"`
+	NotifyInbox bool   `json:"notify_inbox"`
+	NotifyEmail bool   `json:"notify_email"`
+	UserID      string `json:"-"`
+}
+
+// RemoveSavedSearchReq remove saved search request
+type RemoveSavedSearchReq struct {
+	ID     int    `validate:"required" json:"id"`
+	UserID string `json:"-"`
+}
+
+// GetSavedSearchListReq get saved search list request
+type GetSavedSearchListReq struct {
+	UserID string `json:"-"`
+}
+
+// SavedSearchResp saved search response
+type SavedSearchResp struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Query       string `json:"query"`
+	NotifyInbox bool   `json:"notify_inbox"`
+	NotifyEmail bool   `json:"notify_email"`
+	CreatedAt   int64  `json:"created_at"`
+	LastRunAt   int64  `json:"last_run_at"`
+}
@@ -0,0 +1,77 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package schema
+
+// SearchClickReq search click req, the position of the result counts from 1 on the first page
+type SearchClickReq struct {
+	SearchID string `validate:"required" json:"search_id"`
+	ObjectID string `validate:"required" json:"object_id"`
+	Position int    `validate:"required,min=1,max=50" json:"position"`
+	UserID   string `json:"-"`
+	IP       string `json:"-"`
+}
+
+// SearchAnalyticsReq search analytics req, the dates are inclusive and default to the last 30 days
+type SearchAnalyticsReq struct {
+	StartDate string `validate:"omitempty,datetime=2006-01-02" form:"start_date"`
+	EndDate   string `validate:"omitempty,datetime=2006-01-02" form:"end_date"`
+}
+
+// SearchAnalyticsStat the searches, the searches without results and the searches with a click
+type SearchAnalyticsStat struct {
+	Searches         int64   `json:"searches"`
+	ZeroResults      int64   `json:"zero_results"`
+	Clicks           int64   `json:"clicks"`
+	ClickThroughRate float64 `json:"click_through_rate"`
+}
+
+// Add adds the stats of some searches
+func (s *SearchAnalyticsStat) Add(searches, zeroResults, clicks int64) {
+	s.Searches += searches
+	s.ZeroResults += zeroResults
+	s.Clicks += clicks
+	if s.Searches > 0 {
+		s.ClickThroughRate = float64(s.Clicks) / float64(s.Searches)
+	}
+}
+
+// SearchQueryStat the stats of a normalised query
+type SearchQueryStat struct {
+	Query string `json:"query"`
+	SearchAnalyticsStat
+}
+
+// SearchDayStat the stats of a day
+type SearchDayStat struct {
+	Date string `json:"date"`
+	SearchAnalyticsStat
+}
+
+// SearchAnalyticsResp search analytics resp
+type SearchAnalyticsResp struct {
+	StartDate string              `json:"start_date"`
+	EndDate   string              `json:"end_date"`
+	Total     SearchAnalyticsStat `json:"total"`
+	// the queries searched the most
+	TopQueries []*SearchQueryStat `json:"top_queries"`
+	// the queries without results searched the most
+	ZeroResultQueries []*SearchQueryStat `json:"zero_result_queries"`
+	Days              []*SearchDayStat   `json:"days"`
+}
@@ -0,0 +1,65 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package schema
+
+// SearchReindexReq search reindex request
+type SearchReindexReq struct {
+	// BatchSize the number of contents read at once, 100 by default
+	BatchSize int `validate:"omitempty,gte=1,lte=1000" json:"batch_size"`
+	// Concurrency the number of contents sent to the search plugin at the same time, 4 by default
+	Concurrency int `validate:"omitempty,gte=1,lte=32" json:"concurrency"`
+	// Restart reindex from the beginning instead of resuming the last interrupted reindex
+	Restart bool `json:"restart"`
+}
+
+// SearchReindexProgress the progress of a search reindex, it is kept as the checkpoint to resume from
+type SearchReindexProgress struct {
+	Running    bool                `json:"running"`
+	Plugin     string              `json:"plugin"`
+	ObjectType string              `json:"object_type"`
+	LastID     string              `json:"last_id"`
+	Questions  *SearchReindexCount `json:"questions"`
+	Answers    *SearchReindexCount `json:"answers"`
+	StartedAt  int64               `json:"started_at"`
+	FinishedAt int64               `json:"finished_at"`
+	Error      string              `json:"error"`
+}
+
+// SearchReindexCount the contents of a type indexed, and removed from the index as they are no longer in the database
+type SearchReindexCount struct {
+	Total   int64 `json:"total"`
+	Indexed int64 `json:"indexed"`
+	Failed  int64 `json:"failed"`
+	Removed int64 `json:"removed"`
+}
+
+// SearchVerifyResp the contents in the database and in the index of the search plugin
+type SearchVerifyResp struct {
+	Plugin    string             `json:"plugin"`
+	Questions *SearchVerifyCount `json:"questions"`
+	Answers   *SearchVerifyCount `json:"answers"`
+	Match     bool               `json:"match"`
+}
+
+// SearchVerifyCount the contents of a type which are not deleted
+type SearchVerifyCount struct {
+	DB    int64 `json:"db"`
+	Index int64 `json:"index"`
+}
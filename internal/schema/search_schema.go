@@ -22,6 +22,7 @@ package schema
 import (
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/apache/answer/internal/base/constant"
 	"github.com/apache/answer/internal/base/validator"
@@ -36,14 +37,17 @@ type SearchDTO struct {
 	CaptchaID   string `form:"captcha_id"`
 	CaptchaCode string `form:"captcha_code"`
 	UserID      string `json:"-"`
+
+	// the number of characters of a highlighted snippet
+	FragmentSize int `validate:"omitempty,min=20,max=500" form:"fragment_size,default=100"`
+	// count the results by tag, type, accepted state and creation date
+	Facets bool `form:"facets"`
 }
 
 func (s *SearchDTO) Check() (errField []*validator.FormErrorField, err error) {
-	// Replace special characters.
-	// Special characters will cause the search abnormal, such as search for "#" will get nearly all the content that Markdown format.
-	replacedContent, patterns := ReplaceSearchContent(s.Query)
-	s.Query = strings.Join(append(patterns, replacedContent), " ")
-
+	// The special characters are left to the search parser, which splits the words at them,
+	// as quotes, parentheses and "-" are operators of the query.
+	s.Query = strings.TrimSpace(s.Query)
 	return nil, nil
 }
 
@@ -90,6 +94,16 @@ type SearchCondition struct {
 	Tags [][]string
 	// search query keywords
 	Words []string
+	// search query keywords with OR, exclusions and phrases, Words are its terms
+	Query *plugin.SearchExpr
+	// only show the content created at or after
+	CreatedAfter time.Time
+	// only show the content created before
+	CreatedBefore time.Time
+	// only show this category's topics
+	CategoryID string
+	// the number of characters of a highlighted snippet
+	FragmentSize int
 }
 
 // SearchAll check if search all
@@ -107,19 +121,28 @@ func (s *SearchCondition) SearchAnswer() bool {
 	return s.TargetType == constant.AnswerObjectType
 }
 
+// SearchTopic check if search only need topic
+func (s *SearchCondition) SearchTopic() bool {
+	return s.TargetType == constant.TopicObjectType
+}
+
 // Convert2PluginSearchCond convert to plugin search condition
 func (s *SearchCondition) Convert2PluginSearchCond(page, pageSize int, order string) *plugin.SearchBasicCond {
 	basic := &plugin.SearchBasicCond{
-		Page:         page,
-		PageSize:     pageSize,
-		Words:        s.Words,
-		TagIDs:       s.Tags,
-		UserID:       s.UserID,
-		Order:        plugin.SearchOrderCond(order),
-		QuestionID:   s.QuestionID,
-		VoteAmount:   s.VoteAmount,
-		ViewAmount:   s.Views,
-		AnswerAmount: s.AnswerAmount,
+		Page:          page,
+		PageSize:      pageSize,
+		Words:         s.Words,
+		TagIDs:        s.Tags,
+		UserID:        s.UserID,
+		Order:         plugin.SearchOrderCond(order),
+		QuestionID:    s.QuestionID,
+		VoteAmount:    s.VoteAmount,
+		ViewAmount:    s.Views,
+		AnswerAmount:  s.AnswerAmount,
+		Query:         s.Query,
+		CreatedAfter:  s.CreatedAfter,
+		CreatedBefore: s.CreatedBefore,
+		FragmentSize:  s.FragmentSize,
 	}
 	if s.Accepted {
 		basic.AnswerAccepted = plugin.AcceptedCondTrue
@@ -150,6 +173,10 @@ type SearchObject struct {
 	Tags []*TagResp `json:"tags"`
 	// Status
 	StatusStr string `json:"status"`
+	// the title with the matched words wrapped in <mark>, escaped
+	TitleHighlight string `json:"title_highlight"`
+	// the fragments of the content around the matched words, which are wrapped in <mark>, escaped
+	Snippets []string `json:"snippets"`
 }
 
 type SearchObjectUser struct {
@@ -181,6 +208,33 @@ type SearchResp struct {
 	Total int64 `json:"count"`
 	// search response
 	SearchResults []*SearchResult `json:"list"`
+	// the number of results in each facet, only when asked for
+	Facets *SearchFacets `json:"facets,omitempty"`
+	// the id of the search recorded for the analytics, sent back with the clicked result
+	SearchID string `json:"search_id,omitempty"`
+}
+
+// SearchFacets the number of results of the search in each facet
+type SearchFacets struct {
+	// the tags with the most results
+	Tags []*SearchTagFacet `json:"tags"`
+	// question, answer or topics
+	Types []*SearchFacet `json:"types"`
+	// accepted or not_accepted
+	Accepted []*SearchFacet `json:"accepted"`
+	// created in the last day, week, month or year
+	Created []*SearchFacet `json:"created"`
+}
+
+type SearchFacet struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type SearchTagFacet struct {
+	SlugName    string `json:"slug_name"`
+	DisplayName string `json:"display_name"`
+	Count       int64  `json:"count"`
 }
 
 type SearchDescResp struct {
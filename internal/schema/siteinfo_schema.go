@@ -25,7 +25,9 @@ import (
 	"net/mail"
 	"net/url"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/apache/answer/internal/base/constant"
 	"github.com/apache/answer/internal/base/handler"
@@ -33,6 +35,7 @@ import (
 	"github.com/apache/answer/internal/base/translator"
 	"github.com/apache/answer/internal/base/validator"
 	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/i18n"
 )
 
 // SiteGeneralReq site general request
@@ -251,10 +254,70 @@ func (s *SiteSeoResp) IsShortLink() bool {
 		s.Permalink == constant.PermalinkQuestionIDByShortID
 }
 
-// AIPromptConfig AI prompt configuration for different languages
+// AIPromptConfig AI prompt configuration for different languages. The prompts are text/template templates of
+// AIPromptData, Templates is keyed by language such as de_DE. ZhCN and EnUS are the zh_CN and en_US templates.
 type AIPromptConfig struct {
-	ZhCN string `json:"zh_cn"`
-	EnUS string `json:"en_us"`
+	ZhCN      string            `json:"zh_cn"`
+	EnUS      string            `json:"en_us"`
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// GetTemplate gets the template of the language, falling back to English. It is empty when none is configured.
+func (c *AIPromptConfig) GetTemplate(language string) string {
+	if c == nil {
+		return ""
+	}
+	if promptTemplate := c.Templates[language]; len(promptTemplate) > 0 {
+		return promptTemplate
+	}
+	if language == string(i18n.LanguageChinese) && len(c.ZhCN) > 0 {
+		return c.ZhCN
+	}
+	if promptTemplate := c.Templates[string(i18n.LanguageEnglish)]; len(promptTemplate) > 0 {
+		return promptTemplate
+	}
+	return c.EnUS
+}
+
+// AIPromptData the variables of the AI prompt templates, such as {{.Question}}
+type AIPromptData struct {
+	SiteName        string
+	UserDisplayName string
+	Question        string
+	Language        string
+}
+
+// RenderAIPrompt renders the prompt template. The templates saved before text/template was used have a %s in
+// place of the question and are still rendered with fmt.
+func RenderAIPrompt(promptTemplate string, data *AIPromptData) (string, error) {
+	if !strings.Contains(promptTemplate, "{{") && strings.Contains(promptTemplate, "%s") {
+		return fmt.Sprintf(promptTemplate, data.Question), nil
+	}
+	tpl, err := template.New("prompt").Parse(promptTemplate)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err = tpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// AIPromptPreviewReq AI prompt preview request
+type AIPromptPreviewReq struct {
+	Language string `validate:"omitempty,lte=20" json:"language"`
+	// Template is rendered instead of the saved template of the language when not empty
+	Template string `validate:"omitempty,lte=65535" json:"template"`
+	Question string `validate:"omitempty,lte=2000" json:"question"`
+	UserID   string `json:"-"`
+}
+
+// AIPromptPreviewResp AI prompt preview response
+type AIPromptPreviewResp struct {
+	Language string `json:"language"`
+	Template string `json:"template"`
+	Prompt   string `json:"prompt"`
 }
 
 // SiteAIReq AI configuration request
@@ -263,6 +326,67 @@ type SiteAIReq struct {
 	ChosenProvider  string            `validate:"omitempty,lte=50" form:"chosen_provider" json:"chosen_provider"`
 	SiteAIProviders []*SiteAIProvider `validate:"omitempty,dive" form:"ai_providers" json:"ai_providers"`
 	PromptConfig    *AIPromptConfig   `validate:"omitempty" form:"prompt_config" json:"prompt_config,omitempty"`
+	Quota           *AIQuotaConfig    `validate:"omitempty" form:"quota" json:"quota,omitempty"`
+	Memory          *AIMemoryConfig   `validate:"omitempty" form:"memory" json:"memory,omitempty"`
+}
+
+// AIMemoryConfig how much of a conversation is sent to the model, zero uses the default
+type AIMemoryConfig struct {
+	// ContextTokens is the token budget of the messages sent for one turn, tool calls and results included
+	ContextTokens int `validate:"omitempty,min=0" form:"context_tokens" json:"context_tokens"`
+	// RecentTurns is the number of recent turns kept verbatim when the older ones are summarised
+	RecentTurns int `validate:"omitempty,min=0,max=50" form:"recent_turns" json:"recent_turns"`
+}
+
+// Check validates the prompt templates by rendering them with sample data
+func (r *SiteAIReq) Check() (errField []*validator.FormErrorField, err error) {
+	if r.PromptConfig == nil {
+		return nil, nil
+	}
+	templates := map[string]string{
+		"prompt_config.zh_cn": r.PromptConfig.ZhCN,
+		"prompt_config.en_us": r.PromptConfig.EnUS,
+	}
+	for language, promptTemplate := range r.PromptConfig.Templates {
+		field := "prompt_config.templates." + language
+		if !translator.CheckLanguageIsValid(language) {
+			return append(errField, &validator.FormErrorField{
+				ErrorField: field,
+				ErrorMsg:   reason.AIPromptLanguageInvalid,
+			}), errors.BadRequest(reason.AIPromptLanguageInvalid)
+		}
+		templates[field] = promptTemplate
+	}
+	fields := make([]string, 0, len(templates))
+	for field := range templates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if len(templates[field]) == 0 {
+			continue
+		}
+		_, renderErr := RenderAIPrompt(templates[field], &AIPromptData{
+			SiteName:        "Site",
+			UserDisplayName: "User",
+			Question:        "Question",
+		})
+		if renderErr != nil {
+			return append(errField, &validator.FormErrorField{
+				ErrorField: field,
+				ErrorMsg:   reason.AIPromptTemplateInvalid,
+			}), errors.BadRequest(reason.AIPromptTemplateInvalid).WithError(renderErr)
+		}
+	}
+	return nil, nil
+}
+
+// AIQuotaConfig token quotas of the AI chat, zero means unlimited
+type AIQuotaConfig struct {
+	UserDailyTokens   int64 `validate:"omitempty,min=0" form:"user_daily_tokens" json:"user_daily_tokens"`
+	UserMonthlyTokens int64 `validate:"omitempty,min=0" form:"user_monthly_tokens" json:"user_monthly_tokens"`
+	SiteDailyTokens   int64 `validate:"omitempty,min=0" form:"site_daily_tokens" json:"site_daily_tokens"`
+	SiteMonthlyTokens int64 `validate:"omitempty,min=0" form:"site_monthly_tokens" json:"site_monthly_tokens"`
 }
 
 func (s *SiteAIResp) GetProvider() *SiteAIProvider {
@@ -280,11 +404,52 @@ func (s *SiteAIResp) GetProvider() *SiteAIProvider {
 	return &SiteAIProvider{}
 }
 
+// GetProviders gets the chosen provider followed by the fallback providers in priority order
+func (s *SiteAIResp) GetProviders() []*SiteAIProvider {
+	providers := make([]*SiteAIProvider, 0)
+	if !s.Enabled {
+		return providers
+	}
+	chosen := s.GetProvider()
+	if len(chosen.Provider) > 0 {
+		providers = append(providers, chosen)
+	}
+	fallbacks := make([]*SiteAIProvider, 0)
+	for _, provider := range s.SiteAIProviders {
+		if provider.Fallback && provider.Provider != chosen.Provider &&
+			len(provider.APIHost) > 0 && len(provider.Model) > 0 {
+			fallbacks = append(fallbacks, provider)
+		}
+	}
+	sort.SliceStable(fallbacks, func(i, j int) bool {
+		return fallbacks[i].Priority < fallbacks[j].Priority
+	})
+	return append(providers, fallbacks...)
+}
+
+const (
+	AIAPITypeOpenAI    = "openai"
+	AIAPITypeAzure     = "azure"
+	AIAPITypeAnthropic = "anthropic"
+)
+
 type SiteAIProvider struct {
 	Provider string `validate:"omitempty,lte=50" form:"provider" json:"provider"`
 	APIHost  string `validate:"omitempty,lte=512" form:"api_host" json:"api_host"`
 	APIKey   string `validate:"omitempty,lte=256" form:"api_key" json:"api_key"`
 	Model    string `validate:"omitempty,lte=100" form:"model" json:"model"`
+	// EmbeddingModel is used for the semantic retrieval index, retrieval is disabled when empty
+	EmbeddingModel string `validate:"omitempty,lte=100" form:"embedding_model" json:"embedding_model"`
+	// APIType is the API spoken by the provider, OpenAI compatible when empty. For Azure the model is the deployment name.
+	APIType    string `validate:"omitempty,oneof=openai azure anthropic" form:"api_type" json:"api_type,omitempty"`
+	APIVersion string `validate:"omitempty,lte=50" form:"api_version" json:"api_version,omitempty"`
+	// ResponseHeaderTimeout is the seconds to wait for the provider to start responding, RequestTimeout the seconds
+	// of the whole request including the streamed reply. Zero means no limit.
+	ResponseHeaderTimeout int `validate:"omitempty,min=0,max=600" form:"response_header_timeout" json:"response_header_timeout,omitempty"`
+	RequestTimeout        int `validate:"omitempty,min=0,max=3600" form:"request_timeout" json:"request_timeout,omitempty"`
+	// Fallback providers are tried in ascending priority when the chosen provider fails
+	Fallback bool `validate:"omitempty" form:"fallback" json:"fallback,omitempty"`
+	Priority int  `validate:"omitempty,min=0" form:"priority" json:"priority,omitempty"`
 }
 
 // SiteAIResp AI configuration response
@@ -0,0 +1,75 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderAIPrompt(t *testing.T) {
+	data := &AIPromptData{SiteName: "Consensus", UserDisplayName: "Ada", Question: "How?", Language: "de_DE"}
+
+	prompt, err := RenderAIPrompt("{{.SiteName}} / {{.UserDisplayName}} / {{.Language}}: {{.Question}}", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Consensus / Ada / de_DE: How?", prompt)
+
+	// templates saved before text/template was used
+	prompt, err = RenderAIPrompt("Question: %s", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Question: How?", prompt)
+
+	_, err = RenderAIPrompt("{{.Question", data)
+	assert.Error(t, err)
+	_, err = RenderAIPrompt("{{.Unknown}}", data)
+	assert.Error(t, err)
+}
+
+func TestAIPromptConfig_GetTemplate(t *testing.T) {
+	config := &AIPromptConfig{
+		ZhCN:      "zh",
+		EnUS:      "en",
+		Templates: map[string]string{"de_DE": "de", "fr_FR": ""},
+	}
+	assert.Equal(t, "de", config.GetTemplate("de_DE"))
+	assert.Equal(t, "zh", config.GetTemplate("zh_CN"))
+	assert.Equal(t, "en", config.GetTemplate("fr_FR"))
+	assert.Equal(t, "en", config.GetTemplate("ja_JP"))
+
+	config.Templates["en_US"] = "en-template"
+	assert.Equal(t, "en-template", config.GetTemplate("ja_JP"))
+
+	var empty *AIPromptConfig
+	assert.Empty(t, empty.GetTemplate("en_US"))
+}
+
+func TestSiteAIReq_Check(t *testing.T) {
+	req := &SiteAIReq{PromptConfig: &AIPromptConfig{EnUS: "Hello {{.UserDisplayName}}: {{.Question}}"}}
+	_, err := req.Check()
+	assert.NoError(t, err)
+
+	req.PromptConfig.ZhCN = "{{if .Question}}"
+	errFields, err := req.Check()
+	assert.Error(t, err)
+	require.Len(t, errFields, 1)
+	assert.Equal(t, "prompt_config.zh_cn", errFields[0].ErrorField)
+}
@@ -33,3 +33,9 @@ type SiteMapQuestionInfo struct {
 	Title      string `json:"title"`
 	UpdateTime string `json:"time"`
 }
+
+type SiteMapTopicInfo struct {
+	ID         string `json:"id"`
+	HasWiki    bool   `json:"has_wiki"`
+	UpdateTime string `json:"time"`
+}
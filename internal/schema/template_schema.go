@@ -74,3 +74,48 @@ type SuggestedAnswerItem struct {
 		Name string `json:"name"`
 	} `json:"author"`
 }
+
+type DiscussionForumPostingJsonLD struct {
+	Context              string                 `json:"@context"`
+	Type                 string                 `json:"@type"`
+	Headline             string                 `json:"headline"`
+	Text                 string                 `json:"text"`
+	URL                  string                 `json:"url"`
+	DatePublished        time.Time              `json:"datePublished"`
+	Author               *JsonLDPerson          `json:"author"`
+	InteractionStatistic *JsonLDInteraction     `json:"interactionStatistic"`
+	Comment              []*DiscussionForumItem `json:"comment,omitempty"`
+}
+
+type DiscussionForumItem struct {
+	Type          string        `json:"@type"`
+	Text          string        `json:"text"`
+	URL           string        `json:"url"`
+	DatePublished time.Time     `json:"datePublished"`
+	Author        *JsonLDPerson `json:"author"`
+	UpvoteCount   int           `json:"upvoteCount"`
+}
+
+type JsonLDPerson struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type JsonLDInteraction struct {
+	Type                 string `json:"@type"`
+	InteractionType      string `json:"interactionType"`
+	UserInteractionCount int    `json:"userInteractionCount"`
+}
+
+// TemplateForumPost forum post prepared for the template renderer
+type TemplateForumPost struct {
+	ID          string
+	HTML        string
+	VoteCount   int
+	CreateTime  int64
+	Username    string
+	DisplayName string
+	Solution    bool
+	Archived    bool
+}
@@ -21,6 +21,8 @@ package action
 
 import (
 	"context"
+	"strconv"
+	"time"
 
 	"github.com/apache/answer/internal/entity"
 	"github.com/apache/answer/internal/schema"
@@ -122,6 +124,34 @@ func (cs *CaptchaService) ActionRecordAdd(ctx context.Context, actionType string
 	}
 }
 
+// ActionRecordOverLimit counts the action and reports whether the unit did it more than num times in the window.
+// It is for the requests that can not show a captcha, so it applies whether or not a captcha plugin is enabled.
+func (cs *CaptchaService) ActionRecordOverLimit(ctx context.Context, actionType, unit string,
+	num int, window time.Duration) bool {
+	info, err := cs.captchaRepo.GetActionType(ctx, unit, actionType)
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	// the start of the window is kept in the config, the last time moves with every action
+	now := time.Now().Unix()
+	var windowStart int64
+	if info != nil {
+		windowStart, _ = strconv.ParseInt(info.Config, 10, 64)
+	}
+	if info == nil || now-windowStart >= int64(window.Seconds()) {
+		windowStart, info = now, &entity.ActionRecordInfo{}
+	}
+	if info.Num >= num {
+		return true
+	}
+	err = cs.captchaRepo.SetActionType(ctx, unit, actionType, strconv.FormatInt(windowStart, 10), info.Num+1)
+	if err != nil {
+		log.Error(err)
+	}
+	return false
+}
+
 func (cs *CaptchaService) ActionRecordDel(ctx context.Context, actionType string, unit string) {
 	err := cs.captchaRepo.DelActionType(ctx, unit, actionType)
 	if err != nil {
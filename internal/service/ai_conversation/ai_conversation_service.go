@@ -21,6 +21,7 @@ package ai_conversation
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
 	"time"
 
@@ -29,6 +30,7 @@ import (
 	"github.com/apache/answer/internal/entity"
 	"github.com/apache/answer/internal/repo/ai_conversation"
 	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/ai_provider"
 	usercommon "github.com/apache/answer/internal/service/user_common"
 	"github.com/segmentfault/pacman/errors"
 	"github.com/segmentfault/pacman/log"
@@ -37,13 +39,24 @@ import (
 // AIConversationService
 type AIConversationService interface {
 	CreateConversation(ctx context.Context, userID, conversationID, topic string) error
-	SaveConversationRecords(ctx context.Context, conversationID, chatcmplID string, records []*ConversationMessage) error
+	SaveConversationRecords(ctx context.Context, conversationID, chatcmplID string, records []*ConversationMessage,
+		sources []*schema.AIConversationSource, usage *ConversationUsage) error
 	GetConversationList(ctx context.Context, req *schema.AIConversationListReq) (*pager.PageModel, error)
 	GetConversationDetail(ctx context.Context, req *schema.AIConversationDetailReq) (resp *schema.AIConversationDetailResp, exist bool, err error)
 	VoteRecord(ctx context.Context, req *schema.AIConversationVoteReq) error
 	GetConversationListForAdmin(ctx context.Context, req *schema.AIConversationAdminListReq) (*pager.PageModel, error)
 	GetConversationDetailForAdmin(ctx context.Context, req *schema.AIConversationAdminDetailReq) (*schema.AIConversationAdminDetailResp, error)
 	DeleteConversationForAdmin(ctx context.Context, req *schema.AIConversationAdminDeleteReq) error
+	CheckQuota(ctx context.Context, userID string, quota *schema.AIQuotaConfig) error
+	SaveUsage(ctx context.Context, userID, source, objectID string, usage *ConversationUsage) error
+	GetUsageReport(ctx context.Context, req *schema.AIUsageReportReq) (*schema.AIUsageReportResp, error)
+	BuildHistory(ctx context.Context, conversationID string, records []*ConversationMessage, budget, recentTurns int) (
+		*ConversationHistory, error)
+	GetPublishDraft(ctx context.Context, req *schema.AIConversationPublishReq) (*PublishDraft, error)
+	GetBotUserID(ctx context.Context) (string, error)
+	ClaimPublish(ctx context.Context, conversationID string) error
+	ReleasePublish(ctx context.Context, conversationID string)
+	SetPublishedQuestion(ctx context.Context, conversationID, questionID string) error
 }
 
 // ConversationMessage
@@ -53,20 +66,33 @@ type ConversationMessage struct {
 	Content          string `json:"content"`
 }
 
+// ConversationUsage model and tokens used to produce an assistant reply
+type ConversationUsage struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
 // aiConversationService
 type aiConversationService struct {
 	aiConversationRepo ai_conversation.AIConversationRepo
 	userCommon         *usercommon.UserCommon
+	userRepo           usercommon.UserRepo
+	aiProviderService  ai_provider.AIProviderService
 }
 
 // NewAIConversationService
 func NewAIConversationService(
 	aiConversationRepo ai_conversation.AIConversationRepo,
 	userCommon *usercommon.UserCommon,
+	userRepo usercommon.UserRepo,
+	aiProviderService ai_provider.AIProviderService,
 ) AIConversationService {
 	return &aiConversationService{
 		aiConversationRepo: aiConversationRepo,
 		userCommon:         userCommon,
+		userRepo:           userRepo,
+		aiProviderService:  aiProviderService,
 	}
 }
 
@@ -76,6 +102,7 @@ func (s *aiConversationService) CreateConversation(ctx context.Context, userID,
 		ConversationID: conversationID,
 		Topic:          topic,
 		UserID:         userID,
+		QuestionID:     unpublishedQuestionID,
 	}
 	err := s.aiConversationRepo.CreateConversation(ctx, conversation)
 	if err != nil {
@@ -86,8 +113,9 @@ func (s *aiConversationService) CreateConversation(ctx context.Context, userID,
 	return nil
 }
 
-// SaveConversationRecords
-func (s *aiConversationService) SaveConversationRecords(ctx context.Context, conversationID, chatcmplID string, records []*ConversationMessage) error {
+// SaveConversationRecords saves the new messages, the sources are kept with the assistant reply
+func (s *aiConversationService) SaveConversationRecords(ctx context.Context, conversationID, chatcmplID string, records []*ConversationMessage,
+	sources []*schema.AIConversationSource, usage *ConversationUsage) error {
 	conversation, exist, err := s.aiConversationRepo.GetConversation(ctx, conversationID)
 	if err != nil {
 		return errors.InternalServer(reason.DatabaseError).WithError(err)
@@ -129,6 +157,15 @@ func (s *aiConversationService) SaveConversationRecords(ctx context.Context, con
 		Helpful:          0,
 		Unhelpful:        0,
 	}
+	if len(sources) > 0 {
+		data, _ := json.Marshal(sources)
+		aiRecord.Sources = string(data)
+	}
+	if usage != nil {
+		aiRecord.Model = usage.Model
+		aiRecord.PromptTokens = usage.PromptTokens
+		aiRecord.CompletionTokens = usage.CompletionTokens
+	}
 
 	err = s.aiConversationRepo.CreateRecord(ctx, aiRecord)
 	if err != nil {
@@ -192,6 +229,7 @@ func (s *aiConversationService) GetConversationDetail(ctx context.Context, req *
 			Content:          record.Content,
 			Helpful:          record.Helpful,
 			Unhelpful:        record.Unhelpful,
+			Sources:          decodeRecordSources(record),
 			CreatedAt:        record.CreatedAt.Unix(),
 		})
 	}
@@ -202,6 +240,7 @@ func (s *aiConversationService) GetConversationDetail(ctx context.Context, req *
 		Records:        recordList,
 		CreatedAt:      conversation.CreatedAt.Unix(),
 		UpdatedAt:      conversation.UpdatedAt.Unix(),
+		QuestionID:     publishedQuestionID(ctx, conversation),
 	}, true, nil
 }
 
@@ -321,6 +360,7 @@ func (s *aiConversationService) GetConversationDetailForAdmin(ctx context.Contex
 			Content:          record.Content,
 			Helpful:          record.Helpful,
 			Unhelpful:        record.Unhelpful,
+			Sources:          decodeRecordSources(record),
 			CreatedAt:        record.CreatedAt.Unix(),
 		})
 	}
@@ -331,6 +371,8 @@ func (s *aiConversationService) GetConversationDetailForAdmin(ctx context.Contex
 		UserInfo:       userInfo,
 		Records:        recordList,
 		CreatedAt:      conversation.CreatedAt.Unix(),
+		Summary:        conversation.Summary,
+		QuestionID:     publishedQuestionID(ctx, conversation),
 	}, nil
 }
 
@@ -370,3 +412,16 @@ func (s *aiConversationService) DeleteConversationForAdmin(ctx context.Context,
 
 	return nil
 }
+
+// decodeRecordSources
+func decodeRecordSources(record *entity.AIConversationRecord) []*schema.AIConversationSource {
+	if len(record.Sources) == 0 {
+		return nil
+	}
+	sources := make([]*schema.AIConversationSource, 0)
+	if err := json.Unmarshal([]byte(record.Sources), &sources); err != nil {
+		log.Errorf("decode sources of conversation record %d failed: %v", record.ID, err)
+		return nil
+	}
+	return sources
+}
@@ -0,0 +1,161 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_conversation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/sashabaranov/go-openai"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	DefaultContextTokens = 8000
+	DefaultRecentTurns   = 2
+	// summaryMaxTokens bounds the reply of the summarisation, the prompt asks for at most 300 words
+	summaryMaxTokens = 1024
+)
+
+// ConversationHistory the part of the saved conversation sent to the model for a turn
+type ConversationHistory struct {
+	// Summary is the running summary of the records that are not sent verbatim
+	Summary  string
+	Messages []*ConversationMessage
+	// Usage is the usage of the summarisation done for this turn, nil when the summary was not updated
+	Usage *ConversationUsage
+}
+
+// EstimateTokens roughly estimates the tokens of a text, about four characters per token
+func EstimateTokens(content string) int {
+	return (utf8.RuneCountInString(content) + 3) / 4
+}
+
+// BuildHistory fits the saved records of the conversation into the token budget. When they do not fit, the older
+// turns are rolled into the running summary of the conversation, keeping at least the recent turns verbatim when
+// they fit, and the summary is saved with the conversation. If the summarisation fails the older turns are left
+// out of this turn only.
+func (s *aiConversationService) BuildHistory(ctx context.Context, conversationID string, records []*ConversationMessage,
+	budget, recentTurns int) (*ConversationHistory, error) {
+	conversation, exist, err := s.aiConversationRepo.GetConversation(ctx, conversationID)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err)
+	}
+	if !exist {
+		return nil, errors.BadRequest(reason.ObjectNotFound)
+	}
+
+	summarized := min(conversation.SummarizedRecords, len(records))
+	history := &ConversationHistory{
+		Summary:  conversation.Summary,
+		Messages: records[summarized:],
+	}
+	if EstimateTokens(history.Summary)+estimateRecordTokens(history.Messages) <= budget {
+		return history, nil
+	}
+
+	turns := splitTurns(history.Messages)
+	keepFrom, kept := len(turns), 0
+	for keepFrom > 0 {
+		tokens := estimateRecordTokens(turns[keepFrom-1])
+		// use half of the budget so the summary is not updated on every turn, more for the recent turns
+		if kept+tokens > budget || (kept+tokens > budget/2 && len(turns)-keepFrom >= recentTurns) {
+			break
+		}
+		kept += tokens
+		keepFrom--
+	}
+	rolled := make([]*ConversationMessage, 0)
+	for _, turn := range turns[:keepFrom] {
+		rolled = append(rolled, turn...)
+	}
+	if len(rolled) == 0 {
+		return history, nil
+	}
+	history.Messages = history.Messages[len(rolled):]
+
+	summary, usage, err := s.summarize(ctx, conversation.Summary, rolled)
+	if err != nil {
+		log.Errorf("Failed to summarize conversation %s: %v", conversationID, err)
+		return history, nil
+	}
+	if err = s.aiConversationRepo.UpdateConversationSummary(ctx, conversationID, summary, summarized+len(rolled)); err != nil {
+		return nil, err
+	}
+	history.Summary = summary
+	history.Usage = usage
+	return history, nil
+}
+
+// summarize asks the model to fold the messages into the running summary
+func (s *aiConversationService) summarize(ctx context.Context, summary string, messages []*ConversationMessage) (
+	string, *ConversationUsage, error) {
+	if len(summary) == 0 {
+		summary = "None"
+	}
+	var transcript strings.Builder
+	for _, msg := range messages {
+		_, _ = fmt.Fprintf(&transcript, "%s: %s\n\n", msg.Role, strings.TrimSpace(msg.Content))
+	}
+	resp, provider, err := s.aiProviderService.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{
+			Role:    openai.ChatMessageRoleUser,
+			Content: fmt.Sprintf(constant.DefaultAIConversationSummaryPrompt, summary, strings.TrimSpace(transcript.String())),
+		}},
+		MaxTokens: summaryMaxTokens,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(resp.Choices) == 0 || len(strings.TrimSpace(resp.Choices[0].Message.Content)) == 0 {
+		return "", nil, fmt.Errorf("empty summary")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), &ConversationUsage{
+		Model:            provider.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}, nil
+}
+
+// splitTurns splits the records into turns, each starting with a user message
+func splitTurns(records []*ConversationMessage) [][]*ConversationMessage {
+	turns := make([][]*ConversationMessage, 0)
+	for _, record := range records {
+		if record.Role == openai.ChatMessageRoleUser || len(turns) == 0 {
+			turns = append(turns, []*ConversationMessage{record})
+			continue
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], record)
+	}
+	return turns
+}
+
+func estimateRecordTokens(records []*ConversationMessage) int {
+	tokens := 0
+	for _, record := range records {
+		tokens += EstimateTokens(record.Content)
+	}
+	return tokens
+}
@@ -0,0 +1,185 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_conversation
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/apache/answer/internal/base/handler"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	// BotUsername is reserved, so the bot can not be taken over by a registered user
+	BotUsername    = "bot"
+	botEmail       = "bot@answer.invalid"
+	botDisplayName = "AI Assistant"
+
+	publishTitleMinLength = 6
+	publishTitleMaxLength = 150
+
+	// unpublishedQuestionID the question id of a conversation that is not published
+	unpublishedQuestionID = "0"
+	// publishingQuestionID marks a conversation whose question is being created
+	publishingQuestionID = "-1"
+)
+
+// PublishDraft the question and the suggested answer a conversation is published as
+type PublishDraft struct {
+	Title    string
+	Question string
+	Answer   string
+}
+
+// GetPublishDraft builds the question from the first prompt and the answer from the last reply,
+// the title and content of the request take the place of the first prompt when given
+func (s *aiConversationService) GetPublishDraft(ctx context.Context, req *schema.AIConversationPublishReq) (
+	*PublishDraft, error) {
+	conversation, exist, err := s.aiConversationRepo.GetConversation(ctx, req.ConversationID)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err)
+	}
+	if !exist || conversation.UserID != req.UserID {
+		return nil, errors.BadRequest(reason.ObjectNotFound)
+	}
+	if len(conversation.QuestionID) > 0 && conversation.QuestionID != unpublishedQuestionID {
+		return nil, errors.BadRequest(reason.AIConversationPublished)
+	}
+
+	records, err := s.aiConversationRepo.GetRecordsByConversationID(ctx, req.ConversationID)
+	if err != nil {
+		return nil, errors.InternalServer(reason.DatabaseError).WithError(err)
+	}
+	draft := &PublishDraft{
+		Title:    strings.TrimSpace(req.Title),
+		Question: strings.TrimSpace(req.Content),
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Role == "assistant" && len(strings.TrimSpace(records[i].Content)) > 0 {
+			draft.Answer = strings.TrimSpace(records[i].Content)
+			break
+		}
+	}
+	if len(draft.Answer) == 0 {
+		return nil, errors.BadRequest(reason.AIConversationNoReply)
+	}
+	if len(draft.Question) == 0 {
+		draft.Question = strings.TrimSpace(conversation.Topic)
+	}
+	if len(draft.Title) == 0 {
+		draft.Title = publishTitle(conversation.Topic)
+	}
+	if utf8.RuneCountInString(draft.Title) < publishTitleMinLength {
+		return nil, errors.BadRequest(reason.AIConversationTitleRequired)
+	}
+	return draft, nil
+}
+
+// publishTitle takes the first line of the prompt as the title, cut to the length a question title allows
+func publishTitle(prompt string) string {
+	title := ""
+	for _, line := range strings.Split(prompt, "\n") {
+		if title = strings.Join(strings.Fields(line), " "); len(title) > 0 {
+			break
+		}
+	}
+	if utf8.RuneCountInString(title) <= publishTitleMaxLength {
+		return title
+	}
+	return string([]rune(title)[:publishTitleMaxLength-3]) + "..."
+}
+
+// GetBotUserID returns the system bot user the suggested answers are attributed to, it is created when missing
+func (s *aiConversationService) GetBotUserID(ctx context.Context) (string, error) {
+	bot, exist, err := s.userRepo.GetByUsername(ctx, BotUsername)
+	if err != nil {
+		return "", err
+	}
+	if !exist {
+		bot = &entity.User{}
+		bot.Username = BotUsername
+		bot.EMail = botEmail
+		bot.DisplayName = botDisplayName
+		bot.MailStatus = entity.EmailStatusAvailable
+		bot.Status = entity.UserStatusAvailable
+		bot.Rank = 1
+		if err = s.userRepo.AddUser(ctx, bot); err != nil {
+			// another request may have created it at the same time
+			var getErr error
+			if bot, exist, getErr = s.userRepo.GetByUsername(ctx, BotUsername); getErr != nil || !exist {
+				return "", err
+			}
+		}
+	}
+	if bot.EMail != botEmail {
+		log.Errorf("the username %s is taken by a user other than the bot", BotUsername)
+		return "", errors.InternalServer(reason.UsernameDuplicate)
+	}
+	return bot.ID, nil
+}
+
+// ClaimPublish marks the conversation as being published. Only one of the concurrent requests can claim it,
+// the others get the already published error.
+func (s *aiConversationService) ClaimPublish(ctx context.Context, conversationID string) error {
+	claimed, err := s.aiConversationRepo.UpdateConversationQuestion(ctx, conversationID,
+		unpublishedQuestionID, publishingQuestionID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errors.BadRequest(reason.AIConversationPublished)
+	}
+	return nil
+}
+
+// ReleasePublish gives up the claim when the question could not be created, so the conversation can be published again
+func (s *aiConversationService) ReleasePublish(ctx context.Context, conversationID string) {
+	_, err := s.aiConversationRepo.UpdateConversationQuestion(ctx, conversationID,
+		publishingQuestionID, unpublishedQuestionID)
+	if err != nil {
+		log.Errorf("release the publishing of conversation %s failed: %v", conversationID, err)
+	}
+}
+
+// SetPublishedQuestion saves the question the claimed conversation was published as
+func (s *aiConversationService) SetPublishedQuestion(ctx context.Context, conversationID, questionID string) error {
+	_, err := s.aiConversationRepo.UpdateConversationQuestion(ctx, conversationID,
+		publishingQuestionID, uid.DeShortID(questionID))
+	return err
+}
+
+// publishedQuestionID returns the question the conversation was published as, empty when it is not published
+func publishedQuestionID(ctx context.Context, conversation *entity.AIConversation) string {
+	if len(conversation.QuestionID) == 0 || conversation.QuestionID == unpublishedQuestionID ||
+		conversation.QuestionID == publishingQuestionID {
+		return ""
+	}
+	if handler.GetEnableShortID(ctx) {
+		return uid.EnShortID(conversation.QuestionID)
+	}
+	return conversation.QuestionID
+}
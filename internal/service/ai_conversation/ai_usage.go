@@ -0,0 +1,174 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_conversation
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	usageReportDateLayout = "2006-01-02"
+	usageReportDefaultDay = 30
+	usageReportMaxDay     = 366
+)
+
+// CheckQuota checks the daily and monthly token quotas of the user and of the whole site
+func (s *aiConversationService) CheckQuota(ctx context.Context, userID string, quota *schema.AIQuotaConfig) error {
+	if quota == nil {
+		return nil
+	}
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	checks := []struct {
+		userID string
+		since  time.Time
+		limit  int64
+	}{
+		{userID, dayStart, quota.UserDailyTokens},
+		{userID, monthStart, quota.UserMonthlyTokens},
+		{"", dayStart, quota.SiteDailyTokens},
+		{"", monthStart, quota.SiteMonthlyTokens},
+	}
+	for _, check := range checks {
+		if check.limit <= 0 {
+			continue
+		}
+		used, err := s.aiConversationRepo.SumTokens(ctx, check.userID, check.since)
+		if err != nil {
+			return err
+		}
+		if used >= check.limit {
+			return errors.Forbidden(reason.AIQuotaExceeded)
+		}
+	}
+	return nil
+}
+
+// SaveUsage records the tokens used by an AI feature other than the conversations, like the topic summaries
+func (s *aiConversationService) SaveUsage(ctx context.Context, userID, source, objectID string, usage *ConversationUsage) error {
+	if usage == nil || usage.PromptTokens+usage.CompletionTokens == 0 {
+		return nil
+	}
+	return s.aiConversationRepo.CreateUsage(ctx, &entity.AIUsage{
+		UserID:           userID,
+		Source:           source,
+		ObjectID:         objectID,
+		Model:            usage.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	})
+}
+
+// GetUsageReport gets the token usage broken down by user, model and day
+func (s *aiConversationService) GetUsageReport(ctx context.Context, req *schema.AIUsageReportReq) (
+	*schema.AIUsageReportResp, error) {
+	start, end, err := parseUsageReportRange(req)
+	if err != nil {
+		return nil, err
+	}
+	usages, err := s.aiConversationRepo.GetUsage(ctx, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &schema.AIUsageReportResp{
+		StartDate: start.Format(usageReportDateLayout),
+		EndDate:   end.Format(usageReportDateLayout),
+		Users:     make([]*schema.AIUsageUserStat, 0),
+		Models:    make([]*schema.AIUsageModelStat, 0),
+		Days:      make([]*schema.AIUsageDayStat, 0),
+	}
+	days := make(map[string]*schema.AIUsageDayStat)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		stat := &schema.AIUsageDayStat{Date: day.Format(usageReportDateLayout)}
+		days[stat.Date] = stat
+		resp.Days = append(resp.Days, stat)
+	}
+	users := make(map[string]*schema.AIUsageUserStat)
+	models := make(map[string]*schema.AIUsageModelStat)
+	for _, usage := range usages {
+		resp.Total.Add(usage.PromptTokens, usage.CompletionTokens)
+		if day, ok := days[usage.CreatedAt.In(start.Location()).Format(usageReportDateLayout)]; ok {
+			day.Add(usage.PromptTokens, usage.CompletionTokens)
+		}
+		user, ok := users[usage.UserID]
+		if !ok {
+			user = &schema.AIUsageUserStat{UserInfo: schema.AIConversationUserInfo{ID: usage.UserID}}
+			users[usage.UserID] = user
+			resp.Users = append(resp.Users, user)
+		}
+		user.Add(usage.PromptTokens, usage.CompletionTokens)
+		model, ok := models[usage.Model]
+		if !ok {
+			model = &schema.AIUsageModelStat{Model: usage.Model}
+			models[usage.Model] = model
+			resp.Models = append(resp.Models, model)
+		}
+		model.Add(usage.PromptTokens, usage.CompletionTokens)
+	}
+
+	sort.SliceStable(resp.Users, func(i, j int) bool {
+		return resp.Users[i].TotalTokens > resp.Users[j].TotalTokens
+	})
+	sort.SliceStable(resp.Models, func(i, j int) bool {
+		return resp.Models[i].TotalTokens > resp.Models[j].TotalTokens
+	})
+	for _, user := range resp.Users {
+		userInfo, err := s.getUserInfo(ctx, user.UserInfo.ID)
+		if err != nil {
+			log.Warnf("get user info failed for user %s: %v", user.UserInfo.ID, err)
+			continue
+		}
+		user.UserInfo = userInfo
+	}
+	return resp, nil
+}
+
+// parseUsageReportRange parses the inclusive date range of the report in the server time zone
+func parseUsageReportRange(req *schema.AIUsageReportReq) (start, end time.Time, err error) {
+	now := time.Now()
+	end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if len(req.EndDate) > 0 {
+		if end, err = time.ParseInLocation(usageReportDateLayout, req.EndDate, time.Local); err != nil {
+			return start, end, errors.BadRequest(reason.RequestFormatError).WithError(err)
+		}
+	}
+	start = end.AddDate(0, 0, 1-usageReportDefaultDay)
+	if len(req.StartDate) > 0 {
+		if start, err = time.ParseInLocation(usageReportDateLayout, req.StartDate, time.Local); err != nil {
+			return start, end, errors.BadRequest(reason.RequestFormatError).WithError(err)
+		}
+	}
+	if start.After(end) || start.AddDate(0, 0, usageReportMaxDay-1).Before(end) {
+		return start, end, errors.BadRequest(reason.RequestFormatError).
+			WithMsg("the report covers 1 to 366 days and must start before it ends")
+	}
+	return start, end, nil
+}
@@ -0,0 +1,473 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_embedding
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/queue"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/repo/ai_embedding"
+	forumrepo "github.com/apache/answer/internal/repo/forum"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/ai_provider"
+	answercommon "github.com/apache/answer/internal/service/answer_common"
+	"github.com/apache/answer/internal/service/eventqueue"
+	questioncommon "github.com/apache/answer/internal/service/question_common"
+	"github.com/apache/answer/internal/service/siteinfo_common"
+	"github.com/apache/answer/pkg/display"
+	"github.com/apache/answer/pkg/encryption"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	// maxEmbeddingInputRunes keeps the embedding input within the context of common embedding models
+	maxEmbeddingInputRunes = 6000
+	// maxPassageRunes limits the length of each passage injected into the prompt
+	maxPassageRunes    = 800
+	defaultSearchLimit = 5
+	backfillBatchSize  = 100
+)
+
+// AIEmbeddingService semantic retrieval over questions, accepted answers, topics and wiki documents
+type AIEmbeddingService interface {
+	Search(ctx context.Context, query string, limit int) ([]*schema.AIRetrievedPassage, error)
+	IndexQuestion(ctx context.Context, questionID string) error
+	IndexAnswer(ctx context.Context, answerID string) error
+	IndexWiki(ctx context.Context, topicID string) error
+	IndexTopic(ctx context.Context, topicID string) error
+	SimilarContent(ctx context.Context, req *schema.SimilarContentReq) (*schema.SimilarContentResp, error)
+	HandleEvent(ctx context.Context, msg *schema.EventMsg) error
+	BackfillCron(ctx context.Context)
+}
+
+// aiEmbeddingService
+type aiEmbeddingService struct {
+	aiEmbeddingRepo   ai_embedding.AIEmbeddingRepo
+	questionRepo      questioncommon.QuestionRepo
+	answerRepo        answercommon.AnswerRepo
+	forumRepo         *forumrepo.ForumRepo
+	siteInfoService   siteinfo_common.SiteInfoCommonService
+	aiProviderService ai_provider.AIProviderService
+	indexQueue        queue.Service[*schema.EventMsg]
+}
+
+// NewAIEmbeddingService new AIEmbeddingService, it keeps the index up to date from the event queue
+func NewAIEmbeddingService(
+	aiEmbeddingRepo ai_embedding.AIEmbeddingRepo,
+	questionRepo questioncommon.QuestionRepo,
+	answerRepo answercommon.AnswerRepo,
+	forumRepo *forumrepo.ForumRepo,
+	siteInfoService siteinfo_common.SiteInfoCommonService,
+	aiProviderService ai_provider.AIProviderService,
+	eventQueueService eventqueue.Service,
+) AIEmbeddingService {
+	s := &aiEmbeddingService{
+		aiEmbeddingRepo:   aiEmbeddingRepo,
+		questionRepo:      questionRepo,
+		answerRepo:        answerRepo,
+		forumRepo:         forumRepo,
+		siteInfoService:   siteInfoService,
+		aiProviderService: aiProviderService,
+		indexQueue:        queue.New[*schema.EventMsg]("ai_embedding", 128),
+	}
+	// the embedding requests have their own queue, so they do not hold up the other handlers of the events
+	s.indexQueue.RegisterHandler(s.HandleEvent)
+	if eventQueueService != nil {
+		eventQueueService.RegisterHandler(s.queueEvent)
+	}
+	return s
+}
+
+// queueEvent queues the event for indexing when retrieval is on
+func (s *aiEmbeddingService) queueEvent(ctx context.Context, msg *schema.EventMsg) error {
+	if _, ok := s.getEmbeddingModel(ctx); !ok {
+		return nil
+	}
+	s.indexQueue.Send(ctx, msg)
+	return nil
+}
+
+// Search returns the passages most similar to the query, it returns nothing when no embedding model is configured
+func (s *aiEmbeddingService) Search(ctx context.Context, query string, limit int) ([]*schema.AIRetrievedPassage, error) {
+	query = strings.TrimSpace(query)
+	if len(query) == 0 {
+		return nil, nil
+	}
+	model, ok := s.getEmbeddingModel(ctx)
+	if !ok {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	queryVector, _, err := s.aiProviderService.CreateEmbedding(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	// Only the vectors are loaded for scoring, the content is fetched for the top results.
+	vectors, err := s.aiEmbeddingRepo.GetEmbeddingVectorsByModel(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[int]float64)
+	ids := make([]int, 0, len(vectors))
+	for _, embedding := range vectors {
+		if embedding.Dimensions != len(queryVector) {
+			continue
+		}
+		vector := make([]float32, 0, embedding.Dimensions)
+		if err := json.Unmarshal([]byte(embedding.Vector), &vector); err != nil {
+			log.Warnf("decode embedding %s %s failed: %v", embedding.ObjectType, embedding.ObjectID, err)
+			continue
+		}
+		score := cosineSimilarity(queryVector, vector)
+		if score <= 0 {
+			continue
+		}
+		scores[embedding.ID] = score
+		ids = append(ids, embedding.ID)
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return scores[ids[i]] > scores[ids[j]]
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	embeddings, err := s.aiEmbeddingRepo.GetEmbeddingsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(embeddings, func(i, j int) bool {
+		return scores[embeddings[i].ID] > scores[embeddings[j].ID]
+	})
+
+	siteURL, permalink := s.getSiteURL(ctx)
+	passages := make([]*schema.AIRetrievedPassage, 0, len(embeddings))
+	for _, embedding := range embeddings {
+		passages = append(passages, &schema.AIRetrievedPassage{
+			ObjectType: embedding.ObjectType,
+			ObjectID:   embedding.ObjectID,
+			QuestionID: embedding.QuestionID,
+			Title:      embedding.Title,
+			Content:    truncateRunes(embedding.Content, maxPassageRunes),
+			URL:        passageURL(embedding, siteURL, permalink),
+			Score:      scores[embedding.ID],
+		})
+	}
+	return passages, nil
+}
+
+// IndexQuestion computes the embedding of a visible question, or removes it when the question is no longer visible
+func (s *aiEmbeddingService) IndexQuestion(ctx context.Context, questionID string) error {
+	questionID = uid.DeShortID(questionID)
+	question, exist, err := s.questionRepo.GetQuestion(ctx, questionID)
+	if err != nil {
+		return err
+	}
+	if !exist || !isQuestionVisible(question) {
+		if err := s.aiEmbeddingRepo.DeleteAnswerEmbeddingsByQuestionID(ctx, questionID); err != nil {
+			return err
+		}
+		return s.aiEmbeddingRepo.DeleteEmbedding(ctx, entity.AIEmbeddingObjectTypeQuestion, questionID)
+	}
+
+	err = s.save(ctx, &entity.AIEmbedding{
+		ObjectID:   questionID,
+		ObjectType: entity.AIEmbeddingObjectTypeQuestion,
+		QuestionID: questionID,
+		Title:      question.Title,
+		Content:    question.OriginalText,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The answer passage embeds the question title, so it follows question edits.
+	if acceptedAnswerID := uid.DeShortID(question.AcceptedAnswerID); acceptedAnswerID != "" && acceptedAnswerID != "0" {
+		return s.IndexAnswer(ctx, acceptedAnswerID)
+	}
+	return nil
+}
+
+// IndexAnswer computes the embedding of an accepted answer, or removes it when the answer is no longer accepted
+func (s *aiEmbeddingService) IndexAnswer(ctx context.Context, answerID string) error {
+	answerID = uid.DeShortID(answerID)
+	answer, exist, err := s.answerRepo.GetAnswer(ctx, answerID)
+	if err != nil {
+		return err
+	}
+	if !exist || answer.Status != entity.AnswerStatusAvailable || answer.Accepted != schema.AnswerAcceptedEnable {
+		return s.aiEmbeddingRepo.DeleteEmbedding(ctx, entity.AIEmbeddingObjectTypeAnswer, answerID)
+	}
+	questionID := uid.DeShortID(answer.QuestionID)
+	question, exist, err := s.questionRepo.GetQuestion(ctx, questionID)
+	if err != nil {
+		return err
+	}
+	if !exist || !isQuestionVisible(question) {
+		return s.aiEmbeddingRepo.DeleteEmbedding(ctx, entity.AIEmbeddingObjectTypeAnswer, answerID)
+	}
+
+	return s.save(ctx, &entity.AIEmbedding{
+		ObjectID:   answerID,
+		ObjectType: entity.AIEmbeddingObjectTypeAnswer,
+		QuestionID: questionID,
+		Title:      question.Title,
+		Content:    answer.OriginalText,
+	})
+}
+
+// IndexWiki computes the embedding of the current wiki document of a topic
+func (s *aiEmbeddingService) IndexWiki(ctx context.Context, topicID string) error {
+	topicID = uid.DeShortID(topicID)
+	topic, exist, err := s.forumRepo.GetTopic(ctx, topicID)
+	if err != nil {
+		return err
+	}
+	if !exist || !topic.IsWikiEnabled || topic.Status != entity.TopicStatusAvailable ||
+		topic.CurrentWikiRevisionID == "" || topic.CurrentWikiRevisionID == "0" {
+		return s.aiEmbeddingRepo.DeleteEmbedding(ctx, entity.AIEmbeddingObjectTypeWiki, topicID)
+	}
+	revision, exist, err := s.forumRepo.GetWikiRevision(ctx, topic.CurrentWikiRevisionID)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return s.aiEmbeddingRepo.DeleteEmbedding(ctx, entity.AIEmbeddingObjectTypeWiki, topicID)
+	}
+	title := revision.Title
+	if len(title) == 0 {
+		title = topic.Title
+	}
+
+	return s.save(ctx, &entity.AIEmbedding{
+		ObjectID:   topicID,
+		ObjectType: entity.AIEmbeddingObjectTypeWiki,
+		Title:      title,
+		Content:    revision.Document,
+	})
+}
+
+// IndexTopic computes the embedding of a visible topic from its title and opening post
+func (s *aiEmbeddingService) IndexTopic(ctx context.Context, topicID string) error {
+	topicID = uid.DeShortID(topicID)
+	topic, exist, err := s.forumRepo.GetTopic(ctx, topicID)
+	if err != nil {
+		return err
+	}
+	if !exist || (topic.Status != entity.TopicStatusAvailable && topic.Status != entity.TopicStatusClosed) {
+		return s.aiEmbeddingRepo.DeleteEmbedding(ctx, entity.AIEmbeddingObjectTypeTopic, topicID)
+	}
+	posts, _, err := s.forumRepo.ListTopicPosts(ctx, topicID, 1, 1)
+	if err != nil {
+		return err
+	}
+	content := ""
+	if len(posts) > 0 {
+		content = posts[0].OriginalText
+	}
+
+	return s.save(ctx, &entity.AIEmbedding{
+		ObjectID:   topicID,
+		ObjectType: entity.AIEmbeddingObjectTypeTopic,
+		Title:      topic.Title,
+		Content:    content,
+	})
+}
+
+// HandleEvent keeps the index up to date when content changes
+func (s *aiEmbeddingService) HandleEvent(ctx context.Context, msg *schema.EventMsg) error {
+	if _, ok := s.getEmbeddingModel(ctx); !ok {
+		return nil
+	}
+	var err error
+	switch msg.EventType {
+	case constant.EventQuestionCreate, constant.EventQuestionUpdate, constant.EventQuestionDelete:
+		err = s.IndexQuestion(ctx, eventQuestionID(msg))
+	case constant.EventQuestionAccept:
+		// Only the accepted answer of a question is indexed.
+		if err = s.aiEmbeddingRepo.DeleteAnswerEmbeddingsByQuestionID(ctx, uid.DeShortID(eventQuestionID(msg))); err == nil {
+			err = s.IndexAnswer(ctx, msg.AnswerID)
+		}
+	case constant.EventAnswerCreate, constant.EventAnswerUpdate, constant.EventAnswerDelete:
+		answerID := msg.AnswerID
+		if len(answerID) == 0 {
+			answerID = msg.TriggerObjectID
+		}
+		err = s.IndexAnswer(ctx, answerID)
+	case constant.EventWikiUpdate:
+		err = s.IndexWiki(ctx, msg.TriggerObjectID)
+	}
+	if err != nil {
+		log.Errorf("update ai embedding of %s event failed: %v", msg.EventType, err)
+	}
+	return err
+}
+
+// BackfillCron indexes the content that has no embedding of the configured model yet
+func (s *aiEmbeddingService) BackfillCron(ctx context.Context) {
+	model, ok := s.getEmbeddingModel(ctx)
+	if !ok {
+		return
+	}
+
+	backfills := []struct {
+		list  func(ctx context.Context, model string, limit int) ([]string, error)
+		index func(ctx context.Context, id string) error
+	}{
+		{s.aiEmbeddingRepo.GetUnindexedQuestionIDs, s.IndexQuestion},
+		{s.aiEmbeddingRepo.GetUnindexedAcceptedAnswerIDs, s.IndexAnswer},
+		{s.aiEmbeddingRepo.GetUnindexedWikiTopicIDs, s.IndexWiki},
+		{s.aiEmbeddingRepo.GetUnindexedTopicIDs, s.IndexTopic},
+	}
+	for _, backfill := range backfills {
+		ids, err := backfill.list(ctx, model, backfillBatchSize)
+		if err != nil {
+			log.Errorf("list unindexed content failed: %v", err)
+			continue
+		}
+		for _, id := range ids {
+			if err := backfill.index(ctx, id); err != nil {
+				log.Errorf("backfill ai embedding of %s failed: %v", id, err)
+				// The embedding endpoint is most likely unavailable, try again on the next run.
+				return
+			}
+		}
+	}
+}
+
+// save computes and stores the embedding, it skips the request when the content has not changed
+func (s *aiEmbeddingService) save(ctx context.Context, embedding *entity.AIEmbedding) error {
+	model, ok := s.getEmbeddingModel(ctx)
+	if !ok {
+		return nil
+	}
+	input := truncateRunes(embedding.Title+"\n\n"+embedding.Content, maxEmbeddingInputRunes)
+	embedding.Model = model
+	embedding.ContentHash = encryption.MD5(input)
+
+	old, exist, err := s.aiEmbeddingRepo.GetEmbedding(ctx, embedding.ObjectType, embedding.ObjectID)
+	if err != nil {
+		return err
+	}
+	if exist && old.Model == embedding.Model && old.ContentHash == embedding.ContentHash {
+		return nil
+	}
+
+	// The configured model is stored, the backfill looks for the content without an embedding of it.
+	vector, _, err := s.aiProviderService.CreateEmbedding(ctx, input)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(vector)
+	if err != nil {
+		return err
+	}
+	embedding.Vector = string(content)
+	embedding.Dimensions = len(vector)
+	return s.aiEmbeddingRepo.SaveEmbedding(ctx, embedding)
+}
+
+// getEmbeddingModel returns the embedding model of the chosen AI provider, retrieval is off without it
+func (s *aiEmbeddingService) getEmbeddingModel(ctx context.Context) (string, bool) {
+	model, err := s.aiProviderService.GetEmbeddingModel(ctx)
+	if err != nil {
+		log.Errorf("Failed to get AI config: %v", err)
+		return "", false
+	}
+	return model, len(model) > 0
+}
+
+func (s *aiEmbeddingService) getSiteURL(ctx context.Context) (siteURL string, permalink int) {
+	if general, err := s.siteInfoService.GetSiteGeneral(ctx); err == nil {
+		siteURL = general.SiteUrl
+	}
+	if seo, err := s.siteInfoService.GetSiteSeo(ctx); err == nil {
+		permalink = seo.Permalink
+	}
+	return siteURL, permalink
+}
+
+func passageURL(embedding *entity.AIEmbedding, siteURL string, permalink int) string {
+	switch embedding.ObjectType {
+	case entity.AIEmbeddingObjectTypeQuestion:
+		return display.QuestionURL(permalink, siteURL, embedding.QuestionID, embedding.Title)
+	case entity.AIEmbeddingObjectTypeAnswer:
+		return display.AnswerURL(permalink, siteURL, embedding.QuestionID, embedding.Title, embedding.ObjectID)
+	case entity.AIEmbeddingObjectTypeWiki:
+		topicID := embedding.ObjectID
+		if permalink == constant.PermalinkQuestionIDAndTitleByShortID || permalink == constant.PermalinkQuestionIDByShortID {
+			topicID = uid.EnShortID(topicID)
+		}
+		return siteURL + "/topics/" + topicID + "/wiki"
+	case entity.AIEmbeddingObjectTypeTopic:
+		topicID := embedding.ObjectID
+		if permalink == constant.PermalinkQuestionIDAndTitleByShortID || permalink == constant.PermalinkQuestionIDByShortID {
+			topicID = uid.EnShortID(topicID)
+		}
+		return siteURL + "/topics/" + topicID
+	}
+	return ""
+}
+
+func eventQuestionID(msg *schema.EventMsg) string {
+	if len(msg.QuestionID) > 0 {
+		return msg.QuestionID
+	}
+	return msg.TriggerObjectID
+}
+
+func isQuestionVisible(question *entity.Question) bool {
+	return (question.Status == entity.QuestionStatusAvailable || question.Status == entity.QuestionStatusClosed) &&
+		question.Show == entity.QuestionShow
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
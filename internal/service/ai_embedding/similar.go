@@ -0,0 +1,309 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_embedding
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/apache/answer/internal/base/handler"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/pkg/converter"
+	"github.com/apache/answer/pkg/encryption"
+	"github.com/apache/answer/pkg/htmltext"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	defaultSimilarLimit = 5
+	similarExcerptRunes = 160
+	// similarMinTitleRunes is the length a title needs before it is looked up, shorter ones match too loosely
+	similarMinTitleRunes = 10
+
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// bm25TitleWeight counts a term in the title as much as this many terms in the body
+	bm25TitleWeight = 2
+	// keywordTermLimit caps the terms looked up in the database, the longest ones are kept
+	keywordTermLimit = 8
+	// keywordCandidateLimit caps the candidates of each kind ranked in memory
+	keywordCandidateLimit = 50
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"can": true, "do": true, "does": true, "for": true, "from": true, "how": true, "i": true, "in": true,
+	"is": true, "it": true, "my": true, "of": true, "on": true, "or": true, "the": true, "to": true,
+	"what": true, "when": true, "why": true, "with": true,
+}
+
+type similarCandidate struct {
+	embedding  *entity.AIEmbedding
+	score      float64
+	confidence float64
+}
+
+// SimilarContent ranks the questions, topics and wiki documents similar to the title, with the embedding index
+// when it is available and with BM25 over keyword matches otherwise. It is called while the title is typed, so
+// short titles are not looked up and the results of a title are cached for a short time.
+func (s *aiEmbeddingService) SimilarContent(ctx context.Context, req *schema.SimilarContentReq) (
+	*schema.SimilarContentResp, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSimilarLimit
+	}
+	resp := &schema.SimilarContentResp{List: make([]*schema.SimilarContentItem, 0)}
+	title := strings.Join(strings.Fields(strings.ToLower(req.Title)), " ")
+	if utf8.RuneCountInString(title) < similarMinTitleRunes {
+		return resp, nil
+	}
+	shortID := handler.GetEnableShortID(ctx)
+	cacheKey := encryption.MD5(fmt.Sprintf("%s:%d:%t", title, limit, shortID))
+	if cached, exist, err := s.aiEmbeddingRepo.GetSimilarContentCache(ctx, cacheKey); err != nil {
+		log.Error(err)
+	} else if exist {
+		return cached, nil
+	}
+
+	candidates, err := s.similarByEmbedding(ctx, title, limit)
+	if err != nil {
+		log.Errorf("search similar content by embedding failed: %v", err)
+	}
+	resp.Method = schema.SimilarContentMethodEmbedding
+	if len(candidates) == 0 {
+		candidates, err = s.similarByKeyword(ctx, title)
+		if err != nil {
+			return nil, err
+		}
+		resp.Method = schema.SimilarContentMethodBM25
+	}
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	siteURL, permalink := s.getSiteURL(ctx)
+	terms := tokenize(title)
+	for _, c := range candidates {
+		objectID := c.embedding.ObjectID
+		if c.embedding.ObjectType == entity.AIEmbeddingObjectTypeQuestion && shortID {
+			objectID = uid.EnShortID(objectID)
+		}
+		resp.List = append(resp.List, &schema.SimilarContentItem{
+			ObjectType: c.embedding.ObjectType,
+			ObjectID:   objectID,
+			Title:      c.embedding.Title,
+			Excerpt: htmltext.FetchMatchedExcerpt(converter.Markdown2HTML(c.embedding.Content), terms,
+				"...", similarExcerptRunes),
+			URL:        passageURL(c.embedding, siteURL, permalink),
+			Confidence: math.Round(c.confidence*100) / 100,
+		})
+	}
+	if err := s.aiEmbeddingRepo.SetSimilarContentCache(ctx, cacheKey, resp); err != nil {
+		log.Error(err)
+	}
+	return resp, nil
+}
+
+// similarByEmbedding ranks by cosine similarity, an accepted answer stands for its question.
+// The confidence is the cosine similarity.
+func (s *aiEmbeddingService) similarByEmbedding(ctx context.Context, title string, limit int) (
+	[]*similarCandidate, error) {
+	// accepted answers share the question with the question passage, so ask for more to fill the limit
+	passages, err := s.Search(ctx, title, limit*2)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]*similarCandidate, 0, len(passages))
+	seenQuestions := make(map[string]bool)
+	for _, passage := range passages {
+		embedding := &entity.AIEmbedding{
+			ObjectType: passage.ObjectType,
+			ObjectID:   passage.ObjectID,
+			QuestionID: passage.QuestionID,
+			Title:      passage.Title,
+			Content:    passage.Content,
+		}
+		if passage.ObjectType == entity.AIEmbeddingObjectTypeAnswer {
+			embedding.ObjectType = entity.AIEmbeddingObjectTypeQuestion
+			embedding.ObjectID = passage.QuestionID
+		}
+		if embedding.ObjectType == entity.AIEmbeddingObjectTypeQuestion {
+			if seenQuestions[embedding.ObjectID] {
+				continue
+			}
+			seenQuestions[embedding.ObjectID] = true
+		}
+		candidates = append(candidates, &similarCandidate{
+			embedding:  embedding,
+			score:      passage.Score,
+			confidence: math.Max(0, math.Min(1, passage.Score)),
+		})
+	}
+	return candidates, nil
+}
+
+// similarByKeyword ranks the content containing the longest terms of the title with BM25
+func (s *aiEmbeddingService) similarByKeyword(ctx context.Context, title string) ([]*similarCandidate, error) {
+	terms := uniqueTerms(tokenize(title))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	lookup := append([]string{}, terms...)
+	sort.SliceStable(lookup, func(i, j int) bool {
+		return len([]rune(lookup[i])) > len([]rune(lookup[j]))
+	})
+	if len(lookup) > keywordTermLimit {
+		lookup = lookup[:keywordTermLimit]
+	}
+	docs, err := s.aiEmbeddingRepo.GetKeywordCandidates(ctx, lookup, keywordCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	total, err := s.aiEmbeddingRepo.CountDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return rankBM25(terms, docs, total), nil
+}
+
+// rankBM25 scores the documents with BM25 over the title and the body, the title weighing bm25TitleWeight times.
+// The confidence compares the title with the query alone, as duplicates are mostly given away by their title:
+// it is the title score divided by the score of a title made of the query terms.
+func rankBM25(queryTerms []string, docs []*entity.AIEmbedding, totalDocs int64) []*similarCandidate {
+	queryTerms = uniqueTerms(queryTerms)
+	titles := make([]map[string]int, len(docs))
+	bodies := make([]map[string]int, len(docs))
+	titleLengths := make([]int, len(docs))
+	bodyLengths := make([]int, len(docs))
+	var titleTotal, bodyTotal int
+	df := make(map[string]int)
+	for i, doc := range docs {
+		titleTerms, bodyTerms := tokenize(doc.Title), tokenize(doc.Content)
+		titles[i], bodies[i] = termFrequencies(titleTerms), termFrequencies(bodyTerms)
+		titleLengths[i], bodyLengths[i] = len(titleTerms), len(bodyTerms)
+		titleTotal += len(titleTerms)
+		bodyTotal += len(bodyTerms)
+		for _, term := range queryTerms {
+			if titles[i][term] > 0 || bodies[i][term] > 0 {
+				df[term]++
+			}
+		}
+	}
+	n := float64(max(totalDocs, int64(len(docs))))
+	avgTitle := math.Max(1, float64(titleTotal)/float64(len(docs)))
+	avgBody := math.Max(1, float64(bodyTotal)/float64(len(docs)))
+
+	idf := make(map[string]float64, len(queryTerms))
+	selfScore := 0.0
+	for _, term := range queryTerms {
+		idf[term] = math.Log(1 + (n-float64(df[term])+0.5)/(float64(df[term])+0.5))
+		selfScore += idf[term] * bm25Term(1, len(queryTerms), avgTitle)
+	}
+
+	candidates := make([]*similarCandidate, 0, len(docs))
+	for i, doc := range docs {
+		var titleScore, bodyScore float64
+		for _, term := range queryTerms {
+			titleScore += idf[term] * bm25Term(titles[i][term], titleLengths[i], avgTitle)
+			bodyScore += idf[term] * bm25Term(bodies[i][term], bodyLengths[i], avgBody)
+		}
+		score := bm25TitleWeight*titleScore + bodyScore
+		if score <= 0 {
+			continue
+		}
+		confidence := 0.0
+		if selfScore > 0 {
+			confidence = math.Min(1, titleScore/selfScore)
+		}
+		candidates = append(candidates, &similarCandidate{embedding: doc, score: score, confidence: confidence})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	return candidates
+}
+
+func bm25Term(tf, length int, avgLength float64) float64 {
+	if tf == 0 {
+		return 0
+	}
+	f := float64(tf)
+	return f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(length)/avgLength))
+}
+
+// tokenize splits the text into lower-case words without stop words,
+// each Han, Kana or Hangul character is a word of its own
+func tokenize(text string) []string {
+	terms := make([]string, 0)
+	var word strings.Builder
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		term := word.String()
+		word.Reset()
+		if len(term) > 1 && !stopWords[term] {
+			terms = append(terms, term)
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			terms = append(terms, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+func termFrequencies(terms []string) map[string]int {
+	tf := make(map[string]int, len(terms))
+	for _, term := range terms {
+		tf[term]++
+	}
+	return tf
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	list := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			list = append(list, term)
+		}
+	}
+	return list
+}
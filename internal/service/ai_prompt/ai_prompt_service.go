@@ -0,0 +1,132 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_prompt
+
+import (
+	"context"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/siteinfo_common"
+	usercommon "github.com/apache/answer/internal/service/user_common"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/i18n"
+	"github.com/segmentfault/pacman/log"
+)
+
+// previewQuestion is the question of the preview when the admin does not give one
+const previewQuestion = "How do I reset my password?"
+
+// AIPromptService renders the prompt templates of the AI chat
+type AIPromptService interface {
+	BuildPrompt(ctx context.Context, language i18n.Language, userID, question string) string
+	PreviewPrompt(ctx context.Context, req *schema.AIPromptPreviewReq) (*schema.AIPromptPreviewResp, error)
+}
+
+type aiPromptService struct {
+	siteInfoService siteinfo_common.SiteInfoCommonService
+	userCommon      *usercommon.UserCommon
+}
+
+// NewAIPromptService new AI prompt service
+func NewAIPromptService(
+	siteInfoService siteinfo_common.SiteInfoCommonService,
+	userCommon *usercommon.UserCommon,
+) AIPromptService {
+	return &aiPromptService{
+		siteInfoService: siteInfoService,
+		userCommon:      userCommon,
+	}
+}
+
+// BuildPrompt renders the template of the language, the default template is used when the saved one fails
+func (s *aiPromptService) BuildPrompt(ctx context.Context, language i18n.Language, userID, question string) string {
+	data := s.getPromptData(ctx, string(language), userID, question)
+	promptTemplate := s.getTemplate(ctx, string(language))
+	prompt, err := schema.RenderAIPrompt(promptTemplate, data)
+	if err == nil {
+		return prompt
+	}
+	log.Errorf("Failed to render AI prompt of %s: %v", language, err)
+	prompt, _ = schema.RenderAIPrompt(defaultTemplate(string(language)), data)
+	return prompt
+}
+
+// PreviewPrompt renders the given template, or the saved template of the language, for the admin
+func (s *aiPromptService) PreviewPrompt(ctx context.Context, req *schema.AIPromptPreviewReq) (
+	*schema.AIPromptPreviewResp, error) {
+	resp := &schema.AIPromptPreviewResp{
+		Language: req.Language,
+		Template: req.Template,
+	}
+	if len(resp.Language) == 0 {
+		resp.Language = string(i18n.DefaultLanguage)
+	}
+	if len(resp.Template) == 0 {
+		resp.Template = s.getTemplate(ctx, resp.Language)
+	}
+	question := req.Question
+	if len(question) == 0 {
+		question = previewQuestion
+	}
+
+	prompt, err := schema.RenderAIPrompt(resp.Template, s.getPromptData(ctx, resp.Language, req.UserID, question))
+	if err != nil {
+		return nil, errors.BadRequest(reason.AIPromptTemplateInvalid).WithMsg(err.Error())
+	}
+	resp.Prompt = prompt
+	return resp, nil
+}
+
+func (s *aiPromptService) getTemplate(ctx context.Context, language string) string {
+	aiConfig, err := s.siteInfoService.GetSiteAI(ctx)
+	if err != nil {
+		log.Errorf("Failed to get AI config: %v", err)
+		return defaultTemplate(language)
+	}
+	if promptTemplate := aiConfig.PromptConfig.GetTemplate(language); len(promptTemplate) > 0 {
+		return promptTemplate
+	}
+	return defaultTemplate(language)
+}
+
+func (s *aiPromptService) getPromptData(ctx context.Context, language, userID, question string) *schema.AIPromptData {
+	data := &schema.AIPromptData{
+		Question: question,
+		Language: language,
+	}
+	if siteInfo, err := s.siteInfoService.GetSiteGeneral(ctx); err == nil {
+		data.SiteName = siteInfo.Name
+	}
+	if len(userID) > 0 {
+		if user, exist, err := s.userCommon.GetUserBasicInfoByID(ctx, userID); err == nil && exist {
+			data.UserDisplayName = user.DisplayName
+		}
+	}
+	return data
+}
+
+func defaultTemplate(language string) string {
+	if language == string(i18n.LanguageChinese) {
+		return constant.DefaultAIPromptConfigZhCN
+	}
+	return constant.DefaultAIPromptConfigEnUS
+}
@@ -0,0 +1,262 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/siteinfo_common"
+	"github.com/sashabaranov/go-openai"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	// failedProviderCooldown is how long a failed provider is tried after the others
+	failedProviderCooldown = 30 * time.Second
+	healthProbeTimeout     = 15 * time.Second
+)
+
+// AIProviderService sends the chat completions to the configured AI providers
+type AIProviderService interface {
+	// CreateChatCompletionStream starts the stream with the first provider that accepts the request. The model of
+	// the request is replaced by the model of the provider, which is returned with the stream.
+	CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (
+		stream *openai.ChatCompletionStream, provider *schema.SiteAIProvider, err error)
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (
+		resp openai.ChatCompletionResponse, provider *schema.SiteAIProvider, err error)
+	// GetEmbeddingModel returns the embedding model of the chosen provider, it is empty when retrieval is off
+	GetEmbeddingModel(ctx context.Context) (model string, err error)
+	// CreateEmbedding computes the vector of the input with the embedding model of the chosen provider.
+	// There is no failover as the vectors of different models can not be compared.
+	CreateEmbedding(ctx context.Context, input string) (vector []float32, model string, err error)
+	CheckHealth(ctx context.Context) (resp []*schema.AIProviderHealthResp, err error)
+}
+
+type aiProviderService struct {
+	siteInfoService siteinfo_common.SiteInfoCommonService
+	mu              sync.Mutex
+	cooldowns       map[string]time.Time
+}
+
+// NewAIProviderService new AI provider service
+func NewAIProviderService(siteInfoService siteinfo_common.SiteInfoCommonService) AIProviderService {
+	return &aiProviderService{
+		siteInfoService: siteInfoService,
+		cooldowns:       make(map[string]time.Time),
+	}
+}
+
+// CreateChatCompletionStream tries the chosen provider and then the fallback providers in priority order.
+// The providers that failed recently are tried last so a rate limited provider is not hit on every request.
+func (s *aiProviderService) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (
+	stream *openai.ChatCompletionStream, provider *schema.SiteAIProvider, err error) {
+	provider, err = s.failover(ctx, func(client *openai.Client, provider *schema.SiteAIProvider) (err error) {
+		req.Model = provider.Model
+		stream, err = client.CreateChatCompletionStream(ctx, req)
+		return err
+	})
+	return stream, provider, err
+}
+
+// CreateChatCompletion sends the request without streaming, with the same failover as CreateChatCompletionStream
+func (s *aiProviderService) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (
+	resp openai.ChatCompletionResponse, provider *schema.SiteAIProvider, err error) {
+	provider, err = s.failover(ctx, func(client *openai.Client, provider *schema.SiteAIProvider) (err error) {
+		req.Model = provider.Model
+		resp, err = client.CreateChatCompletion(ctx, req)
+		return err
+	})
+	return resp, provider, err
+}
+
+// GetEmbeddingModel returns the embedding model of the chosen provider
+func (s *aiProviderService) GetEmbeddingModel(ctx context.Context) (model string, err error) {
+	provider, err := s.embeddingProvider(ctx)
+	if err != nil || provider == nil {
+		return "", err
+	}
+	return provider.EmbeddingModel, nil
+}
+
+// CreateEmbedding computes the vector of the input with the embedding model of the chosen provider
+func (s *aiProviderService) CreateEmbedding(ctx context.Context, input string) (vector []float32, model string, err error) {
+	provider, err := s.embeddingProvider(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if provider == nil {
+		return nil, "", errors.ServiceUnavailable("AI embedding is not enabled")
+	}
+	resp, err := NewClient(provider).CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: []string{input},
+		Model: openai.EmbeddingModel(provider.EmbeddingModel),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("create embeddings failed: %w", err)
+	}
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return nil, "", fmt.Errorf("create embeddings returned no vector")
+	}
+	return resp.Data[0].Embedding, provider.EmbeddingModel, nil
+}
+
+// embeddingProvider returns the chosen provider when it has an embedding model, otherwise nil
+func (s *aiProviderService) embeddingProvider(ctx context.Context) (*schema.SiteAIProvider, error) {
+	aiConfig, err := s.siteInfoService.GetSiteAI(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !aiConfig.Enabled {
+		return nil, nil
+	}
+	provider := aiConfig.GetProvider()
+	if len(provider.EmbeddingModel) == 0 {
+		return nil, nil
+	}
+	return provider, nil
+}
+
+// failover calls the providers in order until one succeeds
+func (s *aiProviderService) failover(ctx context.Context,
+	call func(client *openai.Client, provider *schema.SiteAIProvider) error) (*schema.SiteAIProvider, error) {
+	aiConfig, err := s.siteInfoService.GetSiteAI(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providers := s.orderProviders(aiConfig.GetProviders())
+	if len(providers) == 0 {
+		return nil, errors.ServiceUnavailable("AI service is not enabled")
+	}
+
+	for _, provider := range providers {
+		err = call(NewClient(provider), provider)
+		if err == nil {
+			s.markHealthy(provider.Provider)
+			return provider, nil
+		}
+		log.Warnf("AI provider %s failed: %v", provider.Provider, err)
+		s.markFailed(provider.Provider)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+// CheckHealth sends a one token completion to every configured provider
+func (s *aiProviderService) CheckHealth(ctx context.Context) (resp []*schema.AIProviderHealthResp, err error) {
+	aiConfig, err := s.siteInfoService.GetSiteAI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make([]*schema.SiteAIProvider, 0)
+	for _, provider := range aiConfig.SiteAIProviders {
+		if len(provider.APIHost) > 0 && len(provider.Model) > 0 {
+			providers = append(providers, provider)
+		}
+	}
+	resp = make([]*schema.AIProviderHealthResp, len(providers))
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		resp[i] = &schema.AIProviderHealthResp{
+			Provider: provider.Provider,
+			APIType:  provider.APIType,
+			Model:    provider.Model,
+			Chosen:   provider.Provider == aiConfig.ChosenProvider,
+			Fallback: provider.Fallback,
+			Priority: provider.Priority,
+		}
+		if len(resp[i].APIType) == 0 {
+			resp[i].APIType = schema.AIAPITypeOpenAI
+		}
+		wg.Add(1)
+		go func(health *schema.AIProviderHealthResp, provider *schema.SiteAIProvider) {
+			defer wg.Done()
+			s.probe(ctx, health, provider)
+		}(resp[i], provider)
+	}
+	wg.Wait()
+	return resp, nil
+}
+
+func (s *aiProviderService) probe(ctx context.Context, health *schema.AIProviderHealthResp, provider *schema.SiteAIProvider) {
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewClient(provider).CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:     provider.Model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "ping"}},
+		MaxTokens: 1,
+	})
+	health.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		health.Error = err.Error()
+		s.markFailed(provider.Provider)
+	} else {
+		health.Healthy = true
+		s.markHealthy(provider.Provider)
+	}
+	if until := s.cooldownUntil(provider.Provider); !until.IsZero() {
+		health.CooldownUntil = until.Unix()
+	}
+}
+
+// orderProviders moves the providers in cooldown after the others, keeping the configured order within each group
+func (s *aiProviderService) orderProviders(providers []*schema.SiteAIProvider) []*schema.SiteAIProvider {
+	available := make([]*schema.SiteAIProvider, 0, len(providers))
+	coolingDown := make([]*schema.SiteAIProvider, 0)
+	for _, provider := range providers {
+		if s.cooldownUntil(provider.Provider).IsZero() {
+			available = append(available, provider)
+		} else {
+			coolingDown = append(coolingDown, provider)
+		}
+	}
+	return append(available, coolingDown...)
+}
+
+func (s *aiProviderService) cooldownUntil(name string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	until, ok := s.cooldowns[name]
+	if !ok || time.Now().After(until) {
+		return time.Time{}
+	}
+	return until
+}
+
+func (s *aiProviderService) markFailed(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cooldowns[name] = time.Now().Add(failedProviderCooldown)
+}
+
+func (s *aiProviderService) markHealthy(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cooldowns, name)
+}
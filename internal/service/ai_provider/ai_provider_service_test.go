@@ -0,0 +1,264 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/mock"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newRateLimitedServer(t *testing.T, calls *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = fmt.Fprint(w, `{"error":{"type":"rate_limit_exceeded","message":"slow down"}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newAnthropicServer answers like the messages API, with some text and a tool use
+func newAnthropicServer(t *testing.T, requests *[]*anthropicRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "anthropic-key", r.Header.Get("x-api-key"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		req := &anthropicRequest{}
+		assert.NoError(t, json.Unmarshal(body, req))
+		*requests = append(*requests, req)
+
+		if !req.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, `{"id":"msg_1","model":"claude","content":[{"type":"text","text":"pong"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"message_start","message":{"id":"msg_1","model":"claude","content":[],"usage":{"input_tokens":12,"output_tokens":0}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me look."}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_topics","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"query\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"release\"}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`,
+			`{"type":"message_stop"}`,
+		} {
+			var typ struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(event), &typ)
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, event)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAIProviderService_FailoverToAnthropic(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	var primaryCalls atomic.Int32
+	primary := newRateLimitedServer(t, &primaryCalls)
+	var requests []*anthropicRequest
+	fallback := newAnthropicServer(t, &requests)
+
+	siteInfoService := mock.NewMockSiteInfoCommonService(ctl)
+	siteInfoService.EXPECT().GetSiteAI(gomock.Any()).Return(&schema.SiteAIResp{
+		Enabled:        true,
+		ChosenProvider: "openai",
+		SiteAIProviders: []*schema.SiteAIProvider{
+			{Provider: "openai", APIHost: primary.URL, APIKey: "openai-key", Model: "gpt"},
+			{Provider: "unused", APIHost: primary.URL, APIKey: "unused", Model: "gpt"},
+			{Provider: "anthropic", APIHost: fallback.URL, APIKey: "anthropic-key", Model: "claude",
+				APIType: schema.AIAPITypeAnthropic, Fallback: true, Priority: 1},
+		},
+	}, nil).AnyTimes()
+	service := NewAIProviderService(siteInfoService)
+
+	req := openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "Be brief."},
+			{Role: openai.ChatMessageRoleUser, Content: "How do we release?"},
+		},
+		Tools: []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+			Name:       "get_topics",
+			Parameters: map[string]any{"type": "object"},
+		}}},
+		Stream:        true,
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	}
+	stream, provider, err := service.CreateChatCompletionStream(context.TODO(), req)
+	require.NoError(t, err)
+	defer func() {
+		_ = stream.Close()
+	}()
+	assert.Equal(t, "anthropic", provider.Provider)
+	assert.EqualValues(t, 1, primaryCalls.Load())
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, "claude", requests[0].Model)
+	assert.Equal(t, "Be brief.", requests[0].System)
+	require.Len(t, requests[0].Messages, 1)
+	assert.Equal(t, "How do we release?", requests[0].Messages[0].Content[0].Text)
+	require.Len(t, requests[0].Tools, 1)
+
+	var content, arguments string
+	var finishReason openai.FinishReason
+	var usage *openai.Usage
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if response.Usage != nil {
+			usage = response.Usage
+		}
+		for _, choice := range response.Choices {
+			content += choice.Delta.Content
+			for _, toolCall := range choice.Delta.ToolCalls {
+				require.NotNil(t, toolCall.Index)
+				assert.Equal(t, 0, *toolCall.Index)
+				arguments += toolCall.Function.Arguments
+			}
+			if len(choice.FinishReason) > 0 {
+				finishReason = choice.FinishReason
+			}
+		}
+	}
+	assert.Equal(t, "Let me look.", content)
+	assert.JSONEq(t, `{"query":"release"}`, arguments)
+	assert.Equal(t, openai.FinishReasonToolCalls, finishReason)
+	require.NotNil(t, usage)
+	assert.Equal(t, 12, usage.PromptTokens)
+	assert.Equal(t, 7, usage.CompletionTokens)
+
+	// the rate limited provider is cooling down, so the fallback provider is tried first
+	stream, provider, err = service.CreateChatCompletionStream(context.TODO(), req)
+	require.NoError(t, err)
+	_ = stream.Close()
+	assert.Equal(t, "anthropic", provider.Provider)
+	assert.EqualValues(t, 1, primaryCalls.Load())
+}
+
+func TestAIProviderService_CheckHealth(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	var primaryCalls atomic.Int32
+	primary := newRateLimitedServer(t, &primaryCalls)
+	var requests []*anthropicRequest
+	fallback := newAnthropicServer(t, &requests)
+
+	siteInfoService := mock.NewMockSiteInfoCommonService(ctl)
+	siteInfoService.EXPECT().GetSiteAI(gomock.Any()).Return(&schema.SiteAIResp{
+		Enabled:        true,
+		ChosenProvider: "openai",
+		SiteAIProviders: []*schema.SiteAIProvider{
+			{Provider: "openai", APIHost: primary.URL, APIKey: "openai-key", Model: "gpt"},
+			{Provider: "gemini"},
+			{Provider: "anthropic", APIHost: fallback.URL, APIKey: "anthropic-key", Model: "claude",
+				APIType: schema.AIAPITypeAnthropic, Fallback: true},
+		},
+	}, nil)
+	resp, err := NewAIProviderService(siteInfoService).CheckHealth(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, resp, 2)
+
+	assert.Equal(t, "openai", resp[0].Provider)
+	assert.Equal(t, schema.AIAPITypeOpenAI, resp[0].APIType)
+	assert.True(t, resp[0].Chosen)
+	assert.False(t, resp[0].Healthy)
+	assert.Contains(t, resp[0].Error, "slow down")
+	assert.NotZero(t, resp[0].CooldownUntil)
+
+	assert.Equal(t, "anthropic", resp[1].Provider)
+	assert.True(t, resp[1].Fallback)
+	assert.True(t, resp[1].Healthy)
+	assert.Empty(t, resp[1].Error)
+	require.Len(t, requests, 1)
+	assert.Equal(t, 1, requests[0].MaxTokens)
+}
+
+func TestToAnthropicRequest_ToolResults(t *testing.T) {
+	req := toAnthropicRequest(&openai.ChatCompletionRequest{
+		Model: "claude",
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "Be brief."},
+			{Role: openai.ChatMessageRoleSystem, Content: "Cite sources."},
+			{Role: openai.ChatMessageRoleUser, Content: "How do we release?"},
+			{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{
+				{ID: "call_1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "a", Arguments: `{"x":1}`}},
+				{ID: "call_2", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "b"}},
+			}},
+			{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: "first"},
+			{Role: openai.ChatMessageRoleTool, ToolCallID: "call_2", Content: "second"},
+		},
+	})
+	assert.Equal(t, "Be brief.\n\nCite sources.", req.System)
+	assert.Equal(t, anthropicDefaultMaxTokens, req.MaxTokens)
+	require.Len(t, req.Messages, 3)
+
+	assistant := req.Messages[1]
+	assert.Equal(t, openai.ChatMessageRoleAssistant, assistant.Role)
+	require.Len(t, assistant.Content, 2)
+	assert.Equal(t, "tool_use", assistant.Content[0].Type)
+	assert.JSONEq(t, `{"x":1}`, string(assistant.Content[0].Input))
+	assert.JSONEq(t, `{}`, string(assistant.Content[1].Input))
+
+	// the results of one turn are sent together in a single user message
+	results := req.Messages[2]
+	assert.Equal(t, openai.ChatMessageRoleUser, results.Role)
+	require.Len(t, results.Content, 2)
+	assert.Equal(t, "call_1", results.Content[0].ToolUseID)
+	assert.Equal(t, "second", results.Content[1].Content)
+}
+
+func TestNewClient_Cached(t *testing.T) {
+	provider := &schema.SiteAIProvider{Provider: "cached", APIHost: "https://api.example.com", APIKey: "key"}
+	client := NewClient(provider)
+	assert.Same(t, client, NewClient(provider))
+	assert.Same(t, client, NewClient(&schema.SiteAIProvider{
+		Provider: "cached", APIHost: "https://api.example.com", APIKey: "key", Priority: 1}))
+
+	// the client is built again when the config of the provider changes
+	provider.APIKey = "rotated"
+	rotated := NewClient(provider)
+	assert.NotSame(t, client, rotated)
+	assert.Same(t, rotated, NewClient(provider))
+}
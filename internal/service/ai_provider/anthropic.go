@@ -0,0 +1,426 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_provider
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	anthropicDefaultAPIVersion = "2023-06-01"
+	anthropicDefaultMaxTokens  = 4096
+)
+
+// anthropicTransport serves the chat completions requests of the OpenAI client with the Anthropic messages API.
+// Other requests are sent to the provider as they are, with the Anthropic authentication headers.
+type anthropicTransport struct {
+	apiKey     string
+	apiVersion string
+	baseURL    string
+	base       http.RoundTripper
+}
+
+type anthropicRequest struct {
+	Model       string              `json:"model"`
+	MaxTokens   int                 `json:"max_tokens"`
+	System      string              `json:"system,omitempty"`
+	Messages    []*anthropicMessage `json:"messages"`
+	Tools       []*anthropicTool    `json:"tools,omitempty"`
+	Temperature *float32            `json:"temperature,omitempty"`
+	Stream      bool                `json:"stream,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                   `json:"role"`
+	Content []*anthropicContentBlock `json:"content"`
+}
+
+type anthropicContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	ID         string                   `json:"id"`
+	Model      string                   `json:"model"`
+	Content    []*anthropicContentBlock `json:"content"`
+	StopReason string                   `json:"stop_reason"`
+	Usage      anthropicUsage           `json:"usage"`
+}
+
+type anthropicStreamEvent struct {
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	Message      *anthropicResponse     `json:"message"`
+	ContentBlock *anthropicContentBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *anthropicTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Del("Authorization")
+	req.Header.Set("x-api-key", t.apiKey)
+	apiVersion := t.apiVersion
+	if len(apiVersion) == 0 {
+		apiVersion = anthropicDefaultAPIVersion
+	}
+	req.Header.Set("anthropic-version", apiVersion)
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return t.base.RoundTrip(req)
+	}
+
+	chatReq := &openai.ChatCompletionRequest{}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(body, chatReq); err != nil {
+		return nil, err
+	}
+	body, err = json.Marshal(toAnthropicRequest(chatReq))
+	if err != nil {
+		return nil, err
+	}
+	messagesReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, t.baseURL+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	messagesReq.Header = req.Header
+	messagesReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.base.RoundTrip(messagesReq)
+	if err != nil {
+		return nil, err
+	}
+	// the errors of both APIs are {"error":{"type":"","message":""}} so they are passed through
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp, nil
+	}
+
+	includeUsage := chatReq.StreamOptions != nil && chatReq.StreamOptions.IncludeUsage
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	if chatReq.Stream {
+		reader, writer := io.Pipe()
+		go func(body io.ReadCloser) {
+			defer func() {
+				_ = body.Close()
+			}()
+			_ = writer.CloseWithError(convertAnthropicStream(body, writer, includeUsage))
+		}(resp.Body)
+		resp.Body = reader
+		resp.Header.Set("Content-Type", "text/event-stream")
+		return resp, nil
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	anthropicResp := &anthropicResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(anthropicResp); err != nil {
+		return nil, err
+	}
+	body, err = json.Marshal(fromAnthropicResponse(anthropicResp))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.Header.Set("Content-Type", "application/json")
+	return resp, nil
+}
+
+// toAnthropicRequest converts the chat completion request, the system messages become the system prompt and the
+// tool results are sent back in user messages
+func toAnthropicRequest(req *openai.ChatCompletionRequest) *anthropicRequest {
+	anthropicReq := &anthropicRequest{
+		Model:     req.Model,
+		MaxTokens: anthropicDefaultMaxTokens,
+		Stream:    req.Stream,
+	}
+	if req.MaxCompletionTokens > 0 {
+		anthropicReq.MaxTokens = req.MaxCompletionTokens
+	} else if req.MaxTokens > 0 {
+		anthropicReq.MaxTokens = req.MaxTokens
+	}
+	if req.Temperature > 0 {
+		anthropicReq.Temperature = &req.Temperature
+	}
+
+	system := make([]string, 0)
+	for _, msg := range req.Messages {
+		role := openai.ChatMessageRoleUser
+		blocks := make([]*anthropicContentBlock, 0)
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			system = append(system, msg.Content)
+			continue
+		case openai.ChatMessageRoleAssistant:
+			role = openai.ChatMessageRoleAssistant
+			if len(msg.Content) > 0 {
+				blocks = append(blocks, &anthropicContentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, toolCall := range msg.ToolCalls {
+				input := json.RawMessage(toolCall.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, &anthropicContentBlock{
+					Type:  "tool_use",
+					ID:    toolCall.ID,
+					Name:  toolCall.Function.Name,
+					Input: input,
+				})
+			}
+		case openai.ChatMessageRoleTool:
+			blocks = append(blocks, &anthropicContentBlock{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Content,
+			})
+		default:
+			if len(msg.Content) > 0 {
+				blocks = append(blocks, &anthropicContentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, part := range msg.MultiContent {
+				if part.Type == openai.ChatMessagePartTypeText && len(part.Text) > 0 {
+					blocks = append(blocks, &anthropicContentBlock{Type: "text", Text: part.Text})
+				}
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		// the messages API wants the roles to alternate, so consecutive messages of one role are merged
+		if last := len(anthropicReq.Messages) - 1; last >= 0 && anthropicReq.Messages[last].Role == role {
+			anthropicReq.Messages[last].Content = append(anthropicReq.Messages[last].Content, blocks...)
+			continue
+		}
+		anthropicReq.Messages = append(anthropicReq.Messages, &anthropicMessage{Role: role, Content: blocks})
+	}
+	anthropicReq.System = strings.Join(system, "\n\n")
+
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		inputSchema := tool.Function.Parameters
+		if inputSchema == nil {
+			inputSchema = map[string]any{"type": "object"}
+		}
+		anthropicReq.Tools = append(anthropicReq.Tools, &anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: inputSchema,
+		})
+	}
+	return anthropicReq
+}
+
+// fromAnthropicResponse converts the reply of a request without streaming
+func fromAnthropicResponse(resp *anthropicResponse) *openai.ChatCompletionResponse {
+	message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			message.Content += block.Text
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:       block.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+	return &openai.ChatCompletionResponse{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   resp.Model,
+		Choices: []openai.ChatCompletionChoice{
+			{Index: 0, Message: message, FinishReason: toFinishReason(resp.StopReason)},
+		},
+		Usage: openai.Usage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		},
+	}
+}
+
+// convertAnthropicStream writes the events of the messages stream as chat completion chunks
+func convertAnthropicStream(body io.Reader, w io.Writer, includeUsage bool) error {
+	var (
+		id, model string
+		usage     anthropicUsage
+		created   = time.Now().Unix()
+		// the content block index of each tool use to the index of the tool call
+		toolCallIndexes = make(map[int]int)
+	)
+	send := func(delta openai.ChatCompletionStreamChoiceDelta, finishReason openai.FinishReason) error {
+		data, err := json.Marshal(&openai.ChatCompletionStreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []openai.ChatCompletionStreamChoice{{Index: 0, Delta: delta, FinishReason: finishReason}},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		return err
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		event := &anthropicStreamEvent{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), event); err != nil {
+			return err
+		}
+
+		var err error
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				id, model = event.Message.ID, event.Message.Model
+				usage = event.Message.Usage
+			}
+			err = send(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant}, "")
+		case "content_block_start":
+			if event.ContentBlock != nil && event.ContentBlock.Type == "tool_use" {
+				index := len(toolCallIndexes)
+				toolCallIndexes[event.Index] = index
+				err = send(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{{
+					Index:    &index,
+					ID:       event.ContentBlock.ID,
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: event.ContentBlock.Name},
+				}}}, "")
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				err = send(openai.ChatCompletionStreamChoiceDelta{Content: event.Delta.Text}, "")
+			case "input_json_delta":
+				index, exist := toolCallIndexes[event.Index]
+				if exist && len(event.Delta.PartialJSON) > 0 {
+					err = send(openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{{
+						Index:    &index,
+						Function: openai.FunctionCall{Arguments: event.Delta.PartialJSON},
+					}}}, "")
+				}
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+			if len(event.Delta.StopReason) > 0 {
+				err = send(openai.ChatCompletionStreamChoiceDelta{}, toFinishReason(event.Delta.StopReason))
+			}
+		case "message_stop":
+			if includeUsage {
+				data, _ := json.Marshal(&openai.ChatCompletionStreamResponse{
+					ID:      id,
+					Object:  "chat.completion.chunk",
+					Created: created,
+					Model:   model,
+					Choices: []openai.ChatCompletionStreamChoice{},
+					Usage: &openai.Usage{
+						PromptTokens:     usage.InputTokens,
+						CompletionTokens: usage.OutputTokens,
+						TotalTokens:      usage.InputTokens + usage.OutputTokens,
+					},
+				})
+				if _, err = fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+					return err
+				}
+			}
+			_, err = fmt.Fprint(w, "data: [DONE]\n\n")
+			return err
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("anthropic stream error %s: %s", event.Error.Type, event.Error.Message)
+			}
+			return fmt.Errorf("anthropic stream error: %s", strconv.Quote(data))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// toFinishReason maps the stop reason of the messages API
+func toFinishReason(stopReason string) openai.FinishReason {
+	switch stopReason {
+	case "tool_use":
+		return openai.FinishReasonToolCalls
+	case "max_tokens":
+		return openai.FinishReasonLength
+	default:
+		return openai.FinishReasonStop
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package ai_provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/answer/internal/schema"
+	"github.com/sashabaranov/go-openai"
+)
+
+// defaultAzureAPIVersion is the first GA version of Azure OpenAI supporting tool calls
+const defaultAzureAPIVersion = "2024-10-21"
+
+// cachedClient the client of a provider with the config it was built from
+type cachedClient struct {
+	fingerprint string
+	client      *openai.Client
+	transport   *http.Transport
+}
+
+var (
+	clientLock sync.Mutex
+	// clients the clients keyed by the name of the provider, so the connections to the provider are reused
+	clients = make(map[string]*cachedClient)
+)
+
+// NewClient returns the OpenAI client of the provider. It is cached by provider and built again when the
+// config of the provider changes.
+func NewClient(provider *schema.SiteAIProvider) *openai.Client {
+	fingerprint := clientFingerprint(provider)
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	cached, ok := clients[provider.Provider]
+	if ok && cached.fingerprint == fingerprint {
+		return cached.client
+	}
+	if ok {
+		cached.transport.CloseIdleConnections()
+	}
+	client, transport := newClient(provider)
+	clients[provider.Provider] = &cachedClient{fingerprint: fingerprint, client: client, transport: transport}
+	return client
+}
+
+// clientFingerprint hashes the config the client of the provider is built from
+func clientFingerprint(provider *schema.SiteAIProvider) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%d\x00%d", provider.APIType, provider.APIHost,
+		provider.APIKey, provider.APIVersion, provider.ResponseHeaderTimeout, provider.RequestTimeout)))
+	return hex.EncodeToString(hash[:])
+}
+
+// newClient builds the OpenAI client of the provider. Azure OpenAI is supported by the client itself, the
+// Anthropic messages API is adapted by the transport so the callers speak the chat completions API to every provider.
+func newClient(provider *schema.SiteAIProvider) (*openai.Client, *http.Transport) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = time.Duration(provider.ResponseHeaderTimeout) * time.Second
+	httpClient := &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(provider.RequestTimeout) * time.Second,
+	}
+
+	var config openai.ClientConfig
+	switch provider.APIType {
+	case schema.AIAPITypeAzure:
+		config = openai.DefaultAzureConfig(provider.APIKey, strings.TrimRight(provider.APIHost, "/"))
+		config.APIVersion = defaultAzureAPIVersion
+		if len(provider.APIVersion) > 0 {
+			config.APIVersion = provider.APIVersion
+		}
+		// the model of an Azure provider is the name of the deployment
+		config.AzureModelMapperFunc = func(model string) string {
+			return model
+		}
+	case schema.AIAPITypeAnthropic:
+		config = openai.DefaultConfig(provider.APIKey)
+		config.BaseURL = apiBaseURL(provider.APIHost)
+		httpClient.Transport = &anthropicTransport{
+			apiKey:     provider.APIKey,
+			apiVersion: provider.APIVersion,
+			baseURL:    config.BaseURL,
+			base:       transport,
+		}
+	default:
+		config = openai.DefaultConfig(provider.APIKey)
+		config.BaseURL = apiBaseURL(provider.APIHost)
+	}
+	config.HTTPClient = httpClient
+	return openai.NewClientWithConfig(config), transport
+}
+
+// apiBaseURL appends the /v1 version prefix to the host when missing
+func apiBaseURL(apiHost string) string {
+	baseURL := strings.TrimRight(apiHost, "/")
+	if !strings.HasSuffix(baseURL, "/v1") {
+		baseURL += "/v1"
+	}
+	return baseURL
+}
@@ -24,14 +24,17 @@ import (
 	"strings"
 	"time"
 
+	"github.com/apache/answer/internal/base/reason"
 	"github.com/apache/answer/internal/entity"
 	"github.com/apache/answer/internal/schema"
 	"github.com/apache/answer/pkg/token"
+	"github.com/segmentfault/pacman/errors"
 )
 
 type APIKeyRepo interface {
 	GetAPIKeyList(ctx context.Context) (keys []*entity.APIKey, err error)
 	GetAPIKey(ctx context.Context, apiKey string) (key *entity.APIKey, exist bool, err error)
+	GetAPIKeyByID(ctx context.Context, id int) (key *entity.APIKey, exist bool, err error)
 	UpdateAPIKey(ctx context.Context, apiKey entity.APIKey) (err error)
 	AddAPIKey(ctx context.Context, apiKey entity.APIKey) (err error)
 	DeleteAPIKey(ctx context.Context, id int) (err error)
@@ -65,22 +68,34 @@ func (s *APIKeyService) GetAPIKeyList(ctx context.Context, req *schema.GetAPIKey
 		}
 
 		resp = append(resp, &schema.GetAPIKeyResp{
-			ID:          key.ID,
-			AccessKey:   key.AccessKey,
-			Description: key.Description,
-			Scope:       key.Scope,
-			CreatedAt:   key.CreatedAt.Unix(),
-			LastUsedAt:  key.LastUsedAt.Unix(),
+			ID:            key.ID,
+			AccessKey:     key.AccessKey,
+			Description:   key.Description,
+			Scope:         key.Scope,
+			CreatedAt:     key.CreatedAt.Unix(),
+			LastUsedAt:    key.LastUsedAt.Unix(),
+			MCPWriteTools: key.GetMCPWriteTools(),
 		})
 	}
 	return resp, nil
 }
 
 func (s *APIKeyService) UpdateAPIKey(ctx context.Context, req *schema.UpdateAPIKeyReq) (err error) {
+	existKey, exist, err := s.apiKeyRepo.GetAPIKeyByID(ctx, req.ID)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return errors.NotFound(reason.ObjectNotFound)
+	}
+	if err = checkMCPWriteTools(existKey.Scope, req.MCPWriteTools); err != nil {
+		return err
+	}
 	apiKey := entity.APIKey{
 		ID:          req.ID,
 		Description: req.Description,
 	}
+	apiKey.SetMCPWriteTools(req.MCPWriteTools)
 	err = s.apiKeyRepo.UpdateAPIKey(ctx, apiKey)
 	if err != nil {
 		return err
@@ -89,6 +104,9 @@ func (s *APIKeyService) UpdateAPIKey(ctx context.Context, req *schema.UpdateAPIK
 }
 
 func (s *APIKeyService) AddAPIKey(ctx context.Context, req *schema.AddAPIKeyReq) (resp *schema.AddAPIKeyResp, err error) {
+	if err = checkMCPWriteTools(req.Scope, req.MCPWriteTools); err != nil {
+		return nil, err
+	}
 	ak := "sk_" + strings.ReplaceAll(token.GenerateToken(), "-", "")
 	apiKey := entity.APIKey{
 		Description: req.Description,
@@ -97,6 +115,7 @@ func (s *APIKeyService) AddAPIKey(ctx context.Context, req *schema.AddAPIKeyReq)
 		LastUsedAt:  time.Now(),
 		UserID:      req.UserID,
 	}
+	apiKey.SetMCPWriteTools(req.MCPWriteTools)
 	err = s.apiKeyRepo.AddAPIKey(ctx, apiKey)
 	if err != nil {
 		return nil, err
@@ -114,3 +133,11 @@ func (s *APIKeyService) DeleteAPIKey(ctx context.Context, req *schema.DeleteAPIK
 	}
 	return nil
 }
+
+// checkMCPWriteTools read-only keys can never call MCP tools that change content
+func checkMCPWriteTools(scope string, tools []string) error {
+	if scope == "read-only" && len(tools) > 0 {
+		return errors.BadRequest(reason.RequestFormatError).WithMsg("read-only API keys cannot enable MCP write tools")
+	}
+	return nil
+}
@@ -156,19 +156,21 @@ func (as *AuthService) SetAdminUserCacheInfo(ctx context.Context, accessToken st
 func (as *AuthService) RemoveAdminUserCacheInfo(ctx context.Context, accessToken string) (err error) {
 	return as.authRepo.RemoveAdminUserCacheInfo(ctx, accessToken)
 }
-func (as *AuthService) AuthAPIKey(ctx context.Context, read bool, apiKey string) (pass bool, err error) {
+
+// AuthAPIKey checks the api key and returns it when the request is allowed
+func (as *AuthService) AuthAPIKey(ctx context.Context, read bool, apiKey string) (apiKeyInfo *entity.APIKey, pass bool, err error) {
 	apiKeyInfo, exist, err := as.apiKeyRepo.GetAPIKey(ctx, apiKey)
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 	if !exist {
-		return false, nil
+		return nil, false, nil
 	}
 	// If the request is not read-only, check if the API key has write permissions
 	if !read && apiKeyInfo.Scope == "read-only" {
 		log.Warnf("API key %s does not have write permissions", apiKeyInfo.AccessKey)
-		return false, nil
+		return nil, false, nil
 	}
 	log.Infof("API key %s is valid, scope: %s", apiKeyInfo.AccessKey, apiKeyInfo.Scope)
-	return true, nil
+	return apiKeyInfo, true, nil
 }
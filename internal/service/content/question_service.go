@@ -1366,11 +1366,7 @@ func (qs *QuestionService) GetQuestionsByTitle(ctx context.Context, title string
 		return resp, nil
 	}
 	// check search plugin
-	var finder plugin.Search
-	_ = plugin.CallSearch(func(search plugin.Search) error {
-		finder = search
-		return nil
-	})
+	finder := plugin.GetSearch()
 
 	var questions []*entity.Question
 	if finder != nil {
@@ -26,6 +26,7 @@ import (
 	"github.com/apache/answer/internal/service/search_common"
 	"github.com/apache/answer/internal/service/search_parser"
 	"github.com/apache/answer/plugin"
+	"github.com/segmentfault/pacman/log"
 )
 
 type SearchService struct {
@@ -56,32 +57,61 @@ func (ss *SearchService) Search(ctx context.Context, dto *schema.SearchDTO) (res
 	}
 
 	// search type
-	cond := ss.searchParser.ParseStructure(ctx, dto)
+	cond, err := ss.searchParser.ParseStructure(ctx, dto)
+	if err != nil {
+		return nil, err
+	}
 
 	// check search plugin
-	var finder plugin.Search
-	_ = plugin.CallSearch(func(search plugin.Search) error {
-		finder = search
-		return nil
-	})
+	finder := plugin.GetSearch()
 
 	resp = &schema.SearchResp{}
-	// search plugin is not found, call system search
-	if finder == nil {
+	// search plugin is not found, call system search, the search plugins do not index topics
+	if finder == nil || cond.SearchTopic() {
 		switch {
 		case cond.SearchAll():
-			resp.SearchResults, resp.Total, err =
-				ss.searchRepo.SearchContents(ctx, cond.Words, cond.Tags, cond.UserID, cond.VoteAmount, dto.Page, dto.Size, dto.Order)
+			resp.SearchResults, resp.Total, err = ss.searchRepo.SearchContents(ctx, cond, dto.Page, dto.Size, dto.Order)
 		case cond.SearchQuestion():
-			resp.SearchResults, resp.Total, err =
-				ss.searchRepo.SearchQuestions(ctx, cond.Words, cond.Tags, cond.NotAccepted, cond.Views, cond.AnswerAmount, dto.Page, dto.Size, dto.Order)
+			resp.SearchResults, resp.Total, err = ss.searchRepo.SearchQuestions(ctx, cond, dto.Page, dto.Size, dto.Order)
 		case cond.SearchAnswer():
-			resp.SearchResults, resp.Total, err =
-				ss.searchRepo.SearchAnswers(ctx, cond.Words, cond.Tags, cond.Accepted, cond.QuestionID, dto.Page, dto.Size, dto.Order)
+			resp.SearchResults, resp.Total, err = ss.searchRepo.SearchAnswers(ctx, cond, dto.Page, dto.Size, dto.Order)
+		case cond.SearchTopic():
+			resp.SearchResults, resp.Total, err = ss.searchRepo.SearchTopics(ctx, cond, dto.Page, dto.Size, dto.Order)
 		}
+		if err != nil || !dto.Facets {
+			return
+		}
+		facets, facetErr := ss.searchRepo.SearchFacets(ctx, cond)
+		resp.Facets = ss.parseFacets(ctx, facets, facetErr)
 		return
 	}
-	return ss.searchByPlugin(ctx, finder, cond, dto)
+	resp, err = ss.searchByPlugin(ctx, finder, cond, dto)
+	if err != nil || !dto.Facets {
+		return
+	}
+	// the facets are optional for the search plugins
+	if facetFinder, ok := finder.(plugin.Facets); ok {
+		facets, facetErr := facetFinder.Facets(ctx, cond.Convert2PluginSearchCond(dto.Page, dto.Size, dto.Order))
+		resp.Facets = ss.parseFacets(ctx, facets, facetErr)
+	}
+	return
+}
+
+// parseFacets formats the facets counted, the search results are returned without them when they fail
+func (ss *SearchService) parseFacets(ctx context.Context, facets *plugin.SearchFacets, err error) *schema.SearchFacets {
+	if err != nil {
+		log.Errorf("count search facets failed: %v", err)
+		return nil
+	}
+	if facets == nil {
+		return nil
+	}
+	resp, err := ss.searchRepo.ParseSearchFacets(ctx, facets)
+	if err != nil {
+		log.Errorf("parse search facets failed: %v", err)
+		return nil
+	}
+	return resp
 }
 
 func (ss *SearchService) searchByPlugin(ctx context.Context, finder plugin.Search, cond *schema.SearchCondition, dto *schema.SearchDTO) (resp *schema.SearchResp, err error) {
@@ -99,6 +129,6 @@ func (ss *SearchService) searchByPlugin(ctx context.Context, finder plugin.Searc
 		return resp, err
 	}
 
-	resp.SearchResults, err = ss.searchRepo.ParseSearchPluginResult(ctx, res, cond.Words)
+	resp.SearchResults, err = ss.searchRepo.ParseSearchPluginResult(ctx, res, cond)
 	return resp, err
 }
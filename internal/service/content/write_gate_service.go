@@ -0,0 +1,226 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package content
+
+import (
+	"context"
+
+	"github.com/apache/answer/internal/base/handler"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/base/translator"
+	"github.com/apache/answer/internal/base/validator"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/action"
+	"github.com/apache/answer/internal/service/permission"
+	"github.com/apache/answer/internal/service/rank"
+	"github.com/apache/answer/internal/service/siteinfo_common"
+	"github.com/segmentfault/pacman/errors"
+)
+
+// WriteGateService checks whether a user may write a question, an answer or a comment. It is shared by the
+// HTTP API, the MCP tools and the publishing of AI conversations, so that all of them apply the same ranks and
+// action limits.
+type WriteGateService struct {
+	rankService     *rank.RankService
+	actionService   *action.CaptchaService
+	questionService *QuestionService
+	answerService   *AnswerService
+	siteInfoService siteinfo_common.SiteInfoCommonService
+}
+
+// NewWriteGateService new write gate service
+func NewWriteGateService(
+	rankService *rank.RankService,
+	actionService *action.CaptchaService,
+	questionService *QuestionService,
+	answerService *AnswerService,
+	siteInfoService siteinfo_common.SiteInfoCommonService,
+) *WriteGateService {
+	return &WriteGateService{
+		rankService:     rankService,
+		actionService:   actionService,
+		questionService: questionService,
+		answerService:   answerService,
+		siteInfoService: siteInfoService,
+	}
+}
+
+// WriteActor the user that writes
+type WriteActor struct {
+	UserID string
+	// IsAdmin the user is an admin or a moderator
+	IsAdmin     bool
+	CaptchaID   string
+	CaptchaCode string
+}
+
+// WritePass a passed check, the write is counted in the action limit with RecordWrite once it is done
+type WritePass struct {
+	actionType string
+	userID     string
+	limited    bool
+}
+
+// CheckAddQuestion checks the ranks, the action limit and the new tags of a question, and fills in its permissions
+func (s *WriteGateService) CheckAddQuestion(ctx context.Context, actor *WriteActor, req *schema.QuestionAdd) (
+	pass *WritePass, errFields []*validator.FormErrorField, err error) {
+	canList, requireRanks, err := s.rankService.CheckOperationPermissionsForRanks(ctx, actor.UserID, []string{
+		permission.QuestionAdd,
+		permission.QuestionEdit,
+		permission.QuestionDelete,
+		permission.QuestionClose,
+		permission.QuestionReopen,
+		permission.TagUseReservedTag,
+		permission.TagAdd,
+		permission.LinkUrlLimit,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	pass = s.newWritePass(actor, entity.CaptchaActionQuestion, canList[7])
+	if errFields, err = s.checkActionLimit(ctx, actor, pass); err != nil {
+		return nil, errFields, err
+	}
+
+	req.CanAdd = canList[0]
+	req.CanEdit = canList[1]
+	req.CanDelete = canList[2]
+	req.CanClose = canList[3]
+	req.CanReopen = canList[4]
+	req.CanUseReservedTag = canList[5]
+	req.CanAddTag = canList[6]
+	if !req.CanAdd {
+		return nil, nil, errors.Forbidden(reason.RankFailToMeetTheCondition)
+	}
+
+	hasNewTag, err := s.questionService.HasNewTag(ctx, req.Tags)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !req.CanAddTag && hasNewTag {
+		msg := translator.TrWithData(handler.GetLangByCtx(ctx), reason.NoEnoughRankToOperate,
+			&schema.PermissionTrTplData{Rank: requireRanks[6]})
+		return nil, nil, errors.Forbidden(reason.NoEnoughRankToOperate).WithMsg(msg)
+	}
+	return pass, nil, nil
+}
+
+// CheckAddAnswer checks the ranks, the action limit and the answer restriction of the site, and fills in the
+// permissions of the answer
+func (s *WriteGateService) CheckAddAnswer(ctx context.Context, actor *WriteActor, req *schema.AnswerAddReq) (
+	pass *WritePass, errFields []*validator.FormErrorField, err error) {
+	canList, err := s.rankService.CheckOperationPermissions(ctx, actor.UserID, []string{
+		permission.AnswerEdit,
+		permission.AnswerDelete,
+		permission.LinkUrlLimit,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	pass = s.newWritePass(actor, entity.CaptchaActionAnswer, canList[2])
+	if errFields, err = s.checkActionLimit(ctx, actor, pass); err != nil {
+		return nil, errFields, err
+	}
+
+	can, err := s.rankService.CheckOperationPermission(ctx, actor.UserID, permission.AnswerAdd, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if !can {
+		return nil, nil, errors.Forbidden(reason.RankFailToMeetTheCondition)
+	}
+	req.CanEdit = canList[0]
+	req.CanDelete = canList[1]
+
+	write, err := s.siteInfoService.GetSiteQuestion(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if write.RestrictAnswer {
+		// check if there's already an answer by this user
+		ids, err := s.answerService.GetCountByUserIDQuestionID(ctx, actor.UserID, req.QuestionID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(ids) >= 1 {
+			return nil, nil, errors.Forbidden(reason.AnswerRestrictAnswer)
+		}
+	}
+	return pass, nil, nil
+}
+
+// CheckAddComment checks the ranks and the action limit of a comment, and fills in its permissions
+func (s *WriteGateService) CheckAddComment(ctx context.Context, actor *WriteActor, req *schema.AddCommentReq) (
+	pass *WritePass, errFields []*validator.FormErrorField, err error) {
+	canList, err := s.rankService.CheckOperationPermissions(ctx, actor.UserID, []string{
+		permission.CommentAdd,
+		permission.CommentEdit,
+		permission.CommentDelete,
+		permission.LinkUrlLimit,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	pass = s.newWritePass(actor, entity.CaptchaActionComment, canList[3])
+	if errFields, err = s.checkActionLimit(ctx, actor, pass); err != nil {
+		return nil, errFields, err
+	}
+
+	req.CanAdd = canList[0]
+	req.CanEdit = canList[1]
+	req.CanDelete = canList[2]
+	if !req.CanAdd {
+		return nil, nil, errors.Forbidden(reason.RankFailToMeetTheCondition)
+	}
+	return pass, nil, nil
+}
+
+// RecordWrite counts the write in the action limit of the user
+func (s *WriteGateService) RecordWrite(ctx context.Context, pass *WritePass) {
+	if pass != nil && pass.limited {
+		s.actionService.ActionRecordAdd(ctx, pass.actionType, pass.userID)
+	}
+}
+
+// newWritePass the action limit applies to everyone but the admins and moderators who may post links
+func (s *WriteGateService) newWritePass(actor *WriteActor, actionType string, linkUrlLimitUser bool) *WritePass {
+	return &WritePass{
+		actionType: actionType,
+		userID:     actor.UserID,
+		limited:    !actor.IsAdmin || !linkUrlLimitUser,
+	}
+}
+
+// checkActionLimit asks for a captcha once the user reached the action limit
+func (s *WriteGateService) checkActionLimit(ctx context.Context, actor *WriteActor, pass *WritePass) (
+	[]*validator.FormErrorField, error) {
+	if !pass.limited {
+		return nil, nil
+	}
+	if s.actionService.ActionRecordVerifyCaptcha(ctx, pass.actionType, actor.UserID, actor.CaptchaID, actor.CaptchaCode) {
+		return nil, nil
+	}
+	errFields := append([]*validator.FormErrorField{}, &validator.FormErrorField{
+		ErrorField: "captcha_code",
+		ErrorMsg:   translator.Tr(handler.GetLangByCtx(ctx), reason.CaptchaVerificationFailed),
+	})
+	return errFields, errors.BadRequest(reason.CaptchaVerificationFailed)
+}
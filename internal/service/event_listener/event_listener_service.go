@@ -0,0 +1,138 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package event_listener
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/apache/answer/internal/base/queue"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/eventqueue"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/apache/answer/plugin"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	// deliveryMaxAttempts the times an event is delivered to a listener before it is dropped
+	deliveryMaxAttempts = 5
+	// deliveryRetryDelay the delay before delivering an event again, it doubles after each attempt
+	deliveryRetryDelay = 10 * time.Second
+)
+
+// delivery an event to deliver, to every listener handling it or only to the listener failing it before
+type delivery struct {
+	slugName string
+	payload  plugin.EventPayload
+}
+
+// EventListenerService delivers the events of the event queue to the event listener plugins.
+// The deliveries and their retries are kept in memory, not in the database, so an event still waiting to be
+// delivered or retried is lost when the server stops. The delivery is not guaranteed across restarts.
+type EventListenerService struct {
+	deliveryQueue queue.Service[*delivery]
+	retryDelay    time.Duration
+}
+
+// NewEventListenerService new event listener service
+func NewEventListenerService(eventQueueService eventqueue.Service) *EventListenerService {
+	s := &EventListenerService{
+		deliveryQueue: queue.New[*delivery]("event_listener", 128),
+		retryDelay:    deliveryRetryDelay,
+	}
+	// the deliveries have their own queue, so the slow listeners do not hold up the other handlers of the events
+	eventQueueService.RegisterHandler(s.Handler)
+	s.deliveryQueue.RegisterHandler(s.deliver)
+	return s
+}
+
+// Handler queues the delivery of the event to the listeners
+func (s *EventListenerService) Handler(ctx context.Context, msg *schema.EventMsg) error {
+	s.deliveryQueue.Send(ctx, &delivery{payload: NewEventPayload(msg)})
+	return nil
+}
+
+// NewEventPayload converts the event message to the payload delivered to the listeners
+func NewEventPayload(msg *schema.EventMsg) plugin.EventPayload {
+	createdAt := msg.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	return plugin.EventPayload{
+		Version:        plugin.EventPayloadVersion,
+		ID:             uid.IDStr(),
+		Type:           plugin.EventType(msg.EventType),
+		Time:           createdAt.Unix(),
+		Attempt:        1,
+		UserID:         msg.UserID,
+		ObjectID:       msg.GetObjectID(),
+		QuestionID:     msg.QuestionID,
+		QuestionUserID: msg.QuestionUserID,
+		AnswerID:       msg.AnswerID,
+		AnswerUserID:   msg.AnswerUserID,
+		CommentID:      msg.CommentID,
+		CommentUserID:  msg.CommentUserID,
+		Extra:          msg.ExtraInfo,
+	}
+}
+
+// deliver delivers the event to the listeners handling it, and queues it again for the listeners failing it.
+// The listener panicking or timing out is not retried, it is counted by the circuit breaker of the plugin.
+func (s *EventListenerService) deliver(ctx context.Context, d *delivery) error {
+	payload, err := json.Marshal(d.payload)
+	if err != nil {
+		return err
+	}
+	return plugin.CallEventListener(func(listener plugin.EventListener) error {
+		slugName := listener.Info().SlugName
+		if len(d.slugName) > 0 && d.slugName != slugName {
+			return nil
+		}
+		if !slices.Contains(listener.Events(), d.payload.Type) {
+			return nil
+		}
+		callCtx, cancel := plugin.WithCallTimeout(ctx, "EventListener")
+		defer cancel()
+		if err := listener.HandleEvent(callCtx, payload); err != nil {
+			log.Warnf("plugin %s failed to handle %s event %s attempt %d: %v",
+				slugName, d.payload.Type, d.payload.ID, d.payload.Attempt, err)
+			s.retry(slugName, d.payload)
+		}
+		// the error is not returned, so the other listeners still get the event
+		return nil
+	})
+}
+
+// retry queues the event again for the listener after the delay of the attempt, or drops it after the last attempt
+func (s *EventListenerService) retry(slugName string, payload plugin.EventPayload) {
+	if payload.Attempt >= deliveryMaxAttempts {
+		log.Errorf("plugin %s failed to handle %s event %s %d times, it is dropped",
+			slugName, payload.Type, payload.ID, payload.Attempt)
+		return
+	}
+	delay := s.retryDelay << (payload.Attempt - 1)
+	payload.Attempt++
+	time.AfterFunc(delay, func() {
+		s.deliveryQueue.Send(context.Background(), &delivery{slugName: slugName, payload: payload})
+	})
+}
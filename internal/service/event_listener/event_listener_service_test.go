@@ -0,0 +1,148 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package event_listener
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/eventqueue"
+	"github.com/apache/answer/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testListener handles question.create, failing the first attempt of each event
+type testListener struct {
+	lock     sync.Mutex
+	received []plugin.EventPayload
+}
+
+func (l *testListener) Info() plugin.Info {
+	return plugin.Info{
+		Name:     plugin.MakeTranslator("test_event_listener_name"),
+		SlugName: "test_event_listener",
+		Version:  "1.0.0",
+	}
+}
+
+func (l *testListener) Events() []plugin.EventType {
+	return []plugin.EventType{plugin.EventQuestionCreate}
+}
+
+func (l *testListener) HandleEvent(ctx context.Context, payload []byte) error {
+	event := plugin.EventPayload{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.received = append(l.received, event)
+	if event.Attempt == 1 {
+		return errors.New("try again later")
+	}
+	return nil
+}
+
+func (l *testListener) getReceived() []plugin.EventPayload {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]plugin.EventPayload{}, l.received...)
+}
+
+func TestEventListenerService_Deliver(t *testing.T) {
+	listener := &testListener{}
+	plugin.Register(listener)
+	plugin.StatusManager.Enable("test_event_listener", true)
+
+	eventQueueService := eventqueue.NewService()
+	defer eventQueueService.Close()
+	s := NewEventListenerService(eventQueueService)
+	s.retryDelay = 10 * time.Millisecond
+
+	ctx := context.TODO()
+	eventQueueService.Send(ctx, schema.NewEvent(constant.EventAnswerCreate, "1").TID("10020000000000001").QID("10010000000000001", "2"))
+	eventQueueService.Send(ctx, schema.NewEvent(constant.EventQuestionCreate, "1").TID("10010000000000001").QID("10010000000000001", "1").
+		AddExtra("source", "test"))
+
+	require.Eventually(t, func() bool {
+		return len(listener.getReceived()) == 2
+	}, time.Second, 10*time.Millisecond)
+	received := listener.getReceived()
+	for i, event := range received {
+		assert.Equal(t, plugin.EventPayloadVersion, event.Version)
+		assert.Equal(t, plugin.EventQuestionCreate, event.Type)
+		assert.Equal(t, i+1, event.Attempt)
+		assert.Equal(t, "1", event.UserID)
+		assert.Equal(t, "10010000000000001", event.ObjectID)
+		assert.Equal(t, "10010000000000001", event.QuestionID)
+		assert.Equal(t, "test", event.Extra["source"])
+		assert.NotZero(t, event.Time)
+	}
+	assert.NotEmpty(t, received[0].ID)
+	assert.Equal(t, received[0].ID, received[1].ID)
+
+	// the event handled is not delivered again
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, listener.getReceived(), 2)
+}
+
+// the event types of the plugins are the ones of Answer, converted as they are
+func TestEventTypes(t *testing.T) {
+	eventTypes := map[constant.EventType]plugin.EventType{
+		constant.EventUserUpdate:     plugin.EventUserUpdate,
+		constant.EventUserShare:      plugin.EventUserShare,
+		constant.EventQuestionCreate: plugin.EventQuestionCreate,
+		constant.EventQuestionUpdate: plugin.EventQuestionUpdate,
+		constant.EventQuestionDelete: plugin.EventQuestionDelete,
+		constant.EventQuestionVote:   plugin.EventQuestionVote,
+		constant.EventQuestionAccept: plugin.EventQuestionAccept,
+		constant.EventQuestionFlag:   plugin.EventQuestionFlag,
+		constant.EventQuestionReact:  plugin.EventQuestionReact,
+		constant.EventAnswerCreate:   plugin.EventAnswerCreate,
+		constant.EventAnswerUpdate:   plugin.EventAnswerUpdate,
+		constant.EventAnswerDelete:   plugin.EventAnswerDelete,
+		constant.EventAnswerVote:     plugin.EventAnswerVote,
+		constant.EventAnswerFlag:     plugin.EventAnswerFlag,
+		constant.EventAnswerReact:    plugin.EventAnswerReact,
+		constant.EventCommentCreate:  plugin.EventCommentCreate,
+		constant.EventCommentUpdate:  plugin.EventCommentUpdate,
+		constant.EventCommentDelete:  plugin.EventCommentDelete,
+		constant.EventCommentVote:    plugin.EventCommentVote,
+		constant.EventCommentFlag:    plugin.EventCommentFlag,
+		constant.EventWikiUpdate:     plugin.EventWikiUpdate,
+		constant.EventTopicCreate:    plugin.EventTopicCreate,
+		constant.EventTopicVote:      plugin.EventTopicVote,
+		constant.EventTopicSolve:     plugin.EventTopicSolve,
+		constant.EventPostCreate:     plugin.EventPostCreate,
+		constant.EventPostVote:       plugin.EventPostVote,
+		constant.EventMergeJobCreate: plugin.EventMergeJobCreate,
+		constant.EventMergeJobApply:  plugin.EventMergeJobApply,
+	}
+	for eventType, pluginEventType := range eventTypes {
+		assert.Equal(t, pluginEventType, plugin.EventType(eventType))
+	}
+}
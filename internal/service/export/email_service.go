@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"mime"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -328,6 +329,59 @@ func (es *EmailService) NewQuestionTemplate(ctx context.Context, raw *schema.New
 	return title, body, nil
 }
 
+// SavedSearchDigestTemplate renders the digest of the new results of a saved search
+func (es *EmailService) SavedSearchDigestTemplate(ctx context.Context, raw *schema.SavedSearchDigestTemplateRawData) (
+	title, body string, err error) {
+	siteInfo, err := es.siteInfoService.GetSiteGeneral(ctx)
+	if err != nil {
+		return
+	}
+	seoInfo, err := es.siteInfoService.GetSiteSeo(ctx)
+	if err != nil {
+		return
+	}
+	templateData := &schema.SavedSearchDigestTemplateData{
+		SiteName:   siteInfo.Name,
+		SearchName: raw.SearchName,
+		SearchUrl:  fmt.Sprintf("%s/search?q=%s", siteInfo.SiteUrl, url.QueryEscape(raw.Query)),
+		MatchCount: len(raw.Matches),
+		Matches:    raw.Matches,
+	}
+	for _, match := range raw.Matches {
+		if match.ObjectType == constant.AnswerObjectType {
+			match.Url = display.AnswerURL(seoInfo.Permalink, siteInfo.SiteUrl, match.QuestionID, match.Title, match.ObjectID)
+		} else {
+			match.Url = display.QuestionURL(seoInfo.Permalink, siteInfo.SiteUrl, match.ObjectID, match.Title)
+		}
+	}
+
+	lang := handler.GetLangByCtx(ctx)
+	title = translator.TrWithData(lang, constant.EmailTplKeySavedSearchDigestTitle, templateData)
+	body = translator.TrWithData(lang, constant.EmailTplKeySavedSearchDigestBody, templateData)
+	return title, body, nil
+}
+
+// PluginDisabledTemplate renders the alert of a plugin disabled by the circuit breaker
+func (es *EmailService) PluginDisabledTemplate(ctx context.Context, raw *schema.PluginDisabledTemplateRawData) (
+	title, body string, err error) {
+	siteInfo, err := es.siteInfoService.GetSiteGeneral(ctx)
+	if err != nil {
+		return
+	}
+	templateData := &schema.PluginDisabledTemplateData{
+		SiteName:       siteInfo.Name,
+		PluginSlugName: raw.PluginSlugName,
+		Failures:       raw.Failures,
+		Reason:         raw.Reason,
+		PluginsUrl:     fmt.Sprintf("%s/admin/installed-plugins", siteInfo.SiteUrl),
+	}
+
+	lang := handler.GetLangByCtx(ctx)
+	title = translator.TrWithData(lang, constant.EmailTplKeyPluginDisabledTitle, templateData)
+	body = translator.TrWithData(lang, constant.EmailTplKeyPluginDisabledBody, templateData)
+	return title, body, nil
+}
+
 func (es *EmailService) GetEmailConfig(ctx context.Context) (ec *EmailConfig, err error) {
 	emailConf, err := es.configService.GetStringValue(ctx, constant.EmailConfigKey)
 	if err != nil {
@@ -39,6 +39,7 @@ const (
 	FeatureAIChatbot    = "ai_chatbot"
 	FeatureArticle      = "article"
 	FeatureCategory     = "category"
+	FeatureForumSummary = "forum_summary"
 )
 
 type toggleConfig struct {
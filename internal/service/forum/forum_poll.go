@@ -0,0 +1,239 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package forum
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/schema"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
+)
+
+// GetTopicPoll returns the poll of a topic with its results, or nil when the topic has no poll.
+// Voters are listed only for public polls; the options chosen by userID are always reported.
+func (s *ForumService) GetTopicPoll(ctx context.Context, topicID, userID string) (*schema.TopicPollResp, error) {
+	poll, exist, err := s.forumRepo.GetTopicPoll(ctx, topicID)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, nil
+	}
+	options, err := s.forumRepo.ListTopicPollOptions(ctx, poll.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &schema.TopicPollResp{
+		ID:             poll.ID,
+		TopicID:        poll.TopicID,
+		Question:       poll.Question,
+		Multiple:       poll.Multiple,
+		Anonymous:      poll.Anonymous,
+		Status:         poll.Status,
+		VoterCount:     poll.VoterCount,
+		Options:        make([]*schema.TopicPollOptionResp, 0, len(options)),
+		VotedOptionIDs: make([]string, 0),
+	}
+	if poll.CloseAt != nil {
+		resp.CloseAt = poll.CloseAt.Unix()
+	}
+	// the cron job may not have caught up with the close time yet
+	if !poll.IsOpen(time.Now()) {
+		resp.Status = entity.TopicPollStatusClosed
+	}
+
+	optionMapping := make(map[string]*schema.TopicPollOptionResp, len(options))
+	for _, option := range options {
+		item := &schema.TopicPollOptionResp{
+			ID:        option.ID,
+			Title:     option.Title,
+			VoteCount: option.VoteCount,
+		}
+		optionMapping[option.ID] = item
+		resp.Options = append(resp.Options, item)
+	}
+	if !poll.Anonymous {
+		voters, err := s.forumRepo.ListTopicPollVoters(ctx, poll.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, voter := range voters {
+			if option, ok := optionMapping[voter.OptionID]; ok {
+				option.Voters = append(option.Voters, voter)
+			}
+		}
+	}
+	if len(userID) > 0 {
+		votes, err := s.forumRepo.ListUserTopicPollVotes(ctx, poll.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, vote := range votes {
+			resp.VotedOptionIDs = append(resp.VotedOptionIDs, vote.OptionID)
+		}
+	}
+	return resp, nil
+}
+
+// SaveTopicPoll creates or edits the poll of a topic. Only the topic author may do so. Once somebody has
+// voted, the options, the choice type and the visibility of the voters are fixed.
+func (s *ForumService) SaveTopicPoll(ctx context.Context, topicID string, req *schema.TopicPollReq) (*schema.TopicPollResp, error) {
+	topic, exist, err := s.forumRepo.GetTopic(ctx, topicID)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, errors.NotFound(reason.ObjectNotFound)
+	}
+	if topic.Status != entity.TopicStatusAvailable {
+		return nil, errors.Forbidden(reason.StatusInvalid)
+	}
+	if topic.UserID != req.UserID {
+		return nil, errors.Forbidden(reason.ForbiddenError)
+	}
+
+	var closeAt *time.Time
+	if req.CloseAt > 0 {
+		t := time.Unix(req.CloseAt, 0)
+		if !t.After(time.Now()) {
+			return nil, errors.BadRequest(reason.RequestFormatError).WithMsg("close time must be in the future")
+		}
+		closeAt = &t
+	}
+	titles := make([]string, 0, len(req.Options))
+	seen := make(map[string]bool, len(req.Options))
+	for _, title := range req.Options {
+		title = strings.TrimSpace(title)
+		if seen[title] {
+			return nil, errors.BadRequest(reason.RequestFormatError).WithMsg("poll options must be unique")
+		}
+		seen[title] = true
+		titles = append(titles, title)
+	}
+
+	poll, exist, err := s.forumRepo.GetTopicPoll(ctx, topicID)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		poll = &entity.TopicPoll{
+			TopicID:   topic.ID,
+			UserID:    req.UserID,
+			Question:  req.Question,
+			Multiple:  req.Multiple,
+			Anonymous: req.Anonymous,
+			Status:    entity.TopicPollStatusOpen,
+			CloseAt:   closeAt,
+		}
+		if err := s.forumRepo.SaveTopicPoll(ctx, poll, newTopicPollOptions(titles)); err != nil {
+			return nil, err
+		}
+		return s.GetTopicPoll(ctx, topicID, req.UserID)
+	}
+
+	if !poll.IsOpen(time.Now()) {
+		return nil, errors.Forbidden(reason.StatusInvalid)
+	}
+	options, err := s.forumRepo.ListTopicPollOptions(ctx, poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	optionsChanged := len(options) != len(titles)
+	for i := 0; !optionsChanged && i < len(options); i++ {
+		optionsChanged = options[i].Title != titles[i]
+	}
+	if poll.VoterCount > 0 && (optionsChanged || poll.Multiple != req.Multiple || poll.Anonymous != req.Anonymous) {
+		return nil, errors.BadRequest(reason.RequestFormatError).
+			WithMsg("poll options, choice type and visibility cannot change once voting has started")
+	}
+
+	poll.Question = req.Question
+	poll.Multiple = req.Multiple
+	poll.Anonymous = req.Anonymous
+	poll.CloseAt = closeAt
+	var replaced []*entity.TopicPollOption
+	if optionsChanged {
+		replaced = newTopicPollOptions(titles)
+	}
+	if err := s.forumRepo.SaveTopicPoll(ctx, poll, replaced); err != nil {
+		return nil, err
+	}
+	return s.GetTopicPoll(ctx, topicID, req.UserID)
+}
+
+// VoteTopicPoll records the choice of a user, replacing any earlier vote on the same poll.
+// The polls of the closed, deleted and converted topics take no votes.
+func (s *ForumService) VoteTopicPoll(ctx context.Context, topicID string, req *schema.TopicPollVoteReq) (*schema.TopicPollResp, error) {
+	topic, exist, err := s.forumRepo.GetTopic(ctx, topicID)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, errors.NotFound(reason.ObjectNotFound)
+	}
+	if topic.Status != entity.TopicStatusAvailable {
+		return nil, errors.Forbidden(reason.StatusInvalid)
+	}
+	poll, exist, err := s.forumRepo.GetTopicPoll(ctx, topicID)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, errors.NotFound(reason.ObjectNotFound)
+	}
+	optionIDs := make([]string, 0, len(req.OptionIDs))
+	seen := make(map[string]bool, len(req.OptionIDs))
+	for _, id := range req.OptionIDs {
+		if !seen[id] {
+			seen[id] = true
+			optionIDs = append(optionIDs, id)
+		}
+	}
+	if err := s.forumRepo.VoteTopicPoll(ctx, poll.ID, req.UserID, optionIDs, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.GetTopicPoll(ctx, topicID, req.UserID)
+}
+
+// ClosePollsCron closes the polls whose close time has passed.
+func (s *ForumService) ClosePollsCron(ctx context.Context) {
+	closed, err := s.forumRepo.CloseDueTopicPolls(ctx, time.Now())
+	if err != nil {
+		log.Errorf("close topic polls error: %v", err)
+		return
+	}
+	if closed > 0 {
+		log.Infof("closed %d topic polls", closed)
+	}
+}
+
+func newTopicPollOptions(titles []string) []*entity.TopicPollOption {
+	options := make([]*entity.TopicPollOption, 0, len(titles))
+	for i, title := range titles {
+		options = append(options, &entity.TopicPollOption{Position: i, Title: title})
+	}
+	return options
+}
@@ -21,32 +21,48 @@ package forum
 
 import (
 	"context"
+	"math"
 	"sort"
+	"strconv"
 	"time"
 
+	"github.com/apache/answer/internal/base/constant"
 	"github.com/apache/answer/internal/base/reason"
 	domainforum "github.com/apache/answer/internal/domain/forum"
 	"github.com/apache/answer/internal/entity"
 	forumrepo "github.com/apache/answer/internal/repo/forum"
 	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/eventqueue"
 	"github.com/apache/answer/internal/service/plugin_common"
+	"github.com/apache/answer/internal/service/siteinfo_common"
+	tagcommon "github.com/apache/answer/internal/service/tag_common"
 	"github.com/apache/answer/pkg/uid"
 	"github.com/apache/answer/plugin"
 	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
 )
 
 type ForumService struct {
 	forumRepo           *forumrepo.ForumRepo
 	pluginCommonService *plugin_common.PluginCommonService
+	tagCommonService    *tagcommon.TagCommonService
+	eventQueueService   eventqueue.Service
+	siteInfoService     siteinfo_common.SiteInfoCommonService
 }
 
 func NewForumService(
 	forumRepo *forumrepo.ForumRepo,
 	pluginCommonService *plugin_common.PluginCommonService,
+	tagCommonService *tagcommon.TagCommonService,
+	eventQueueService eventqueue.Service,
+	siteInfoService siteinfo_common.SiteInfoCommonService,
 ) *ForumService {
 	return &ForumService{
 		forumRepo:           forumRepo,
 		pluginCommonService: pluginCommonService,
+		tagCommonService:    tagCommonService,
+		eventQueueService:   eventQueueService,
+		siteInfoService:     siteInfoService,
 	}
 }
 
@@ -70,6 +86,18 @@ func (s *ForumService) ListCategories(ctx context.Context, req *schema.CategoryL
 	return s.forumRepo.ListCategories(ctx, req.Page, req.PageSize)
 }
 
+// GetCategory returns a category by id.
+func (s *ForumService) GetCategory(ctx context.Context, categoryID string) (*entity.Category, error) {
+	category, exist, err := s.forumRepo.GetCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, errors.NotFound(reason.ObjectNotFound)
+	}
+	return category, nil
+}
+
 func (s *ForumService) GetTopic(ctx context.Context, topicID string) (*entity.Topic, error) {
 	topic, exist, err := s.forumRepo.GetTopic(ctx, topicID)
 	if err != nil {
@@ -81,12 +109,24 @@ func (s *ForumService) GetTopic(ctx context.Context, topicID string) (*entity.To
 	return topic, nil
 }
 
+// GetTopicsByIDs returns the topics with the given ids; missing topics are skipped.
+func (s *ForumService) GetTopicsByIDs(ctx context.Context, topicIDs []string) ([]*entity.Topic, error) {
+	return s.forumRepo.GetTopicsByIDs(ctx, topicIDs)
+}
+
 func (s *ForumService) CreateTopic(ctx context.Context, req *schema.CreateTopicReq) (*entity.Topic, error) {
 	if _, exist, err := s.forumRepo.GetCategory(ctx, req.CategoryID); err != nil {
 		return nil, err
 	} else if !exist {
 		return nil, errors.NotFound(reason.ObjectNotFound)
 	}
+	tags, err := s.resolveTopicTags(ctx, req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReservedTagChange(nil, tags, req.CanUseReservedTag); err != nil {
+		return nil, err
+	}
 
 	topic := &entity.Topic{
 		CategoryID:    uid.DeShortID(req.CategoryID),
@@ -96,16 +136,28 @@ func (s *ForumService) CreateTopic(ctx context.Context, req *schema.CreateTopicR
 		IsWikiEnabled: req.IsWikiEnabled,
 		Status:        entity.TopicStatusAvailable,
 	}
-	if err := s.forumRepo.AddTopic(ctx, topic); err != nil {
+	if err := s.forumRepo.AddTopic(ctx, topic, tagIDs(tags)); err != nil {
 		return nil, err
 	}
+	s.sendEvent(ctx, schema.NewEvent(constant.EventTopicCreate, req.UserID).TID(topic.ID).
+		AddExtra("category_id", topic.CategoryID))
 	return topic, nil
 }
 
 func (s *ForumService) ListTopicsByCategory(ctx context.Context, categoryID string, req *schema.TopicListReq) (
 	topics []*entity.Topic, total int64, err error,
 ) {
-	return s.forumRepo.ListTopicsByCategory(ctx, categoryID, req.Page, req.PageSize)
+	var filterTagIDs []string
+	if len(req.Tag) > 0 {
+		filterTagIDs, err = s.tagWithSynonymIDs(ctx, req.Tag)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(filterTagIDs) == 0 {
+			return make([]*entity.Topic, 0), 0, nil
+		}
+	}
+	return s.forumRepo.ListTopicsByCategory(ctx, categoryID, filterTagIDs, req.Page, req.PageSize)
 }
 
 func (s *ForumService) ListTopicPosts(ctx context.Context, topicID string, req *schema.PostListReq) (
@@ -142,6 +194,8 @@ func (s *ForumService) CreatePost(ctx context.Context, topicID string, req *sche
 	if err := s.forumRepo.AddPost(ctx, post); err != nil {
 		return nil, err
 	}
+	s.sendEvent(ctx, schema.NewEvent(constant.EventPostCreate, req.UserID).TID(post.ID).
+		AddExtra("topic_id", topic.ID))
 	return post, nil
 }
 
@@ -201,6 +255,7 @@ func (s *ForumService) CreateWikiRevision(ctx context.Context, topicID string, r
 	if err := s.forumRepo.UpdateTopic(ctx, topic, "current_wiki_revision_id"); err != nil {
 		return nil, err
 	}
+	s.sendEvent(ctx, schema.NewEvent(constant.EventWikiUpdate, req.EditorID).TID(topic.ID))
 	return revision, nil
 }
 
@@ -236,6 +291,8 @@ func (s *ForumService) CreateMergeJob(ctx context.Context, topicID string, req *
 	if err := s.forumRepo.AddMergeJob(ctx, job, req.PostIDs); err != nil {
 		return nil, err
 	}
+	s.sendEvent(ctx, schema.NewEvent(constant.EventMergeJobCreate, req.CreatorID).TID(job.ID).
+		AddExtra("topic_id", job.TopicID))
 	return job, nil
 }
 
@@ -384,9 +441,19 @@ func (s *ForumService) ApplyMergeJob(ctx context.Context, topicID, jobID string,
 	if err := s.forumRepo.UpdateMergeJob(ctx, job, "status", "applied_revision_id", "applied_at", "reviewer_id"); err != nil {
 		return nil, err
 	}
+	s.sendEvent(ctx, schema.NewEvent(constant.EventWikiUpdate, req.OperatorID).TID(topic.ID))
+	s.sendEvent(ctx, schema.NewEvent(constant.EventMergeJobApply, req.OperatorID).TID(job.ID).
+		AddExtra("topic_id", topic.ID).AddExtra("revision_id", revision.ID))
 	return revision, nil
 }
 
+func (s *ForumService) sendEvent(ctx context.Context, event *schema.EventMsg) {
+	if s.eventQueueService == nil {
+		return
+	}
+	s.eventQueueService.Send(ctx, event)
+}
+
 func (s *ForumService) ListContributorsByTopic(ctx context.Context, topicID string) ([]*forumrepo.ContributorStat, error) {
 	if _, exist, err := s.forumRepo.GetTopic(ctx, topicID); err != nil {
 		return nil, err
@@ -470,7 +537,12 @@ func (s *ForumService) SetTopicSolution(ctx context.Context, topicID string, req
 	if !exist {
 		return errors.NotFound(reason.ObjectNotFound)
 	}
-	return s.forumRepo.UpsertTopicSolution(ctx, topicID, req.PostID, req.UserID)
+	if err := s.forumRepo.UpsertTopicSolution(ctx, topicID, req.PostID, req.UserID); err != nil {
+		return err
+	}
+	s.sendEvent(ctx, schema.NewEvent(constant.EventTopicSolve, req.UserID).TID(topicID).
+		AddExtra("post_id", uid.DeShortID(req.PostID)))
+	return nil
 }
 
 func (s *ForumService) VotePost(ctx context.Context, postID string, req *schema.ForumVoteReq) error {
@@ -481,7 +553,12 @@ func (s *ForumService) VotePost(ctx context.Context, postID string, req *schema.
 	if !exist {
 		return errors.NotFound(reason.ObjectNotFound)
 	}
-	return s.forumRepo.UpsertPostVote(ctx, postID, req.UserID, req.Value)
+	if err := s.forumRepo.UpsertPostVote(ctx, postID, req.UserID, req.Value); err != nil {
+		return err
+	}
+	s.sendEvent(ctx, schema.NewEvent(constant.EventPostVote, req.UserID).TID(postID).
+		AddExtra("value", strconv.Itoa(req.Value)))
+	return nil
 }
 
 func (s *ForumService) VoteTopic(ctx context.Context, topicID string, req *schema.ForumVoteReq) error {
@@ -492,7 +569,79 @@ func (s *ForumService) VoteTopic(ctx context.Context, topicID string, req *schem
 	if !exist {
 		return errors.NotFound(reason.ObjectNotFound)
 	}
-	return s.forumRepo.UpsertTopicVote(ctx, topicID, req.UserID, req.Value)
+	if err := s.forumRepo.UpsertTopicVote(ctx, topicID, req.UserID, req.Value); err != nil {
+		return err
+	}
+	s.sendEvent(ctx, schema.NewEvent(constant.EventTopicVote, req.UserID).TID(topicID).
+		AddExtra("value", strconv.Itoa(req.Value)))
+	return nil
+}
+
+// ConvertQuestionToTopic turns a question with its answers and comments into a topic of the given category.
+func (s *ForumService) ConvertQuestionToTopic(ctx context.Context, req *schema.ConvertQuestionToTopicReq) (*entity.Topic, error) {
+	if _, exist, err := s.forumRepo.GetCategory(ctx, req.CategoryID); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, errors.NotFound(reason.ObjectNotFound)
+	}
+	if req.TopicKind == "" {
+		req.TopicKind = entity.TopicKindDiscussion
+	}
+	topic, err := s.forumRepo.ConvertQuestionToTopic(ctx, req.QuestionID, req.CategoryID, req.TopicKind, req.OperatorID)
+	if err != nil {
+		return nil, err
+	}
+	// the question and its answers are gone from Q&A, the listeners drop them from their indexes
+	s.sendEvent(ctx, schema.NewEvent(constant.EventQuestionDelete, req.OperatorID).TID(uid.DeShortID(req.QuestionID)))
+	return topic, nil
+}
+
+// ConvertTopicToQuestion turns a topic with its posts into a question with answers.
+func (s *ForumService) ConvertTopicToQuestion(ctx context.Context, req *schema.ConvertTopicToQuestionReq) (*entity.Question, error) {
+	question, err := s.forumRepo.ConvertTopicToQuestion(ctx, req.TopicID, req.OperatorID)
+	if err != nil {
+		return nil, err
+	}
+	// the question is indexed like an edited one, it is not announced as a new question
+	s.sendEvent(ctx, schema.NewEvent(constant.EventQuestionUpdate, req.OperatorID).QID(question.ID, question.UserID))
+	return question, nil
+}
+
+// GetObjectConversion returns where a converted question, answer, comment, topic or post lives now.
+func (s *ForumService) GetObjectConversion(ctx context.Context, objectID string) (*entity.ObjectConversion, bool, error) {
+	return s.forumRepo.GetObjectConversion(ctx, objectID)
+}
+
+// GetSitemapTopicCount returns the number of topics listed in the sitemap.
+func (s *ForumService) GetSitemapTopicCount(ctx context.Context) (int64, error) {
+	return s.forumRepo.GetSitemapTopicCount(ctx)
+}
+
+// SitemapTopics returns one sitemap page of topics.
+func (s *ForumService) SitemapTopics(ctx context.Context, page, pageSize int) ([]*schema.SiteMapTopicInfo, error) {
+	return s.forumRepo.SitemapTopics(ctx, page, pageSize)
+}
+
+// SitemapCron warms the topic sitemap cache page by page.
+func (s *ForumService) SitemapCron(ctx context.Context) {
+	siteSeo, err := s.siteInfoService.GetSiteSeo(ctx)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	ctx = context.WithValue(ctx, constant.ShortIDContextKey, siteSeo.IsShortLink())
+	topicNum, err := s.forumRepo.GetSitemapTopicCount(ctx)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	totalPages := int(math.Ceil(float64(topicNum) / float64(constant.SitemapMaxSize)))
+	for i := 1; i <= totalPages; i++ {
+		if _, err = s.forumRepo.SitemapTopics(ctx, i, constant.SitemapMaxSize); err != nil {
+			log.Errorf("get site map topic error: %v", err)
+			return
+		}
+	}
 }
 
 func (s *ForumService) GetPlatformPlugins(ctx context.Context) ([]*schema.GetAllPluginStatusResp, error) {
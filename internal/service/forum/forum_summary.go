@@ -0,0 +1,168 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package forum
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	forumrepo "github.com/apache/answer/internal/repo/forum"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/segmentfault/pacman/errors"
+)
+
+// maxSummaryTranscriptRunes bounds the text sent to the model for a single summary.
+const maxSummaryTranscriptRunes = 24000
+
+// TopicSummarySource is what a topic summary is generated from.
+type TopicSummarySource struct {
+	Topic      *entity.Topic
+	MergeJobID string
+	Posts      []*forumrepo.TopicPostView
+	// Fingerprint changes whenever the summarised posts change, which invalidates the cached summary.
+	Fingerprint string
+	// CachedSummary is set when a summary of the same posts was generated before.
+	CachedSummary string
+}
+
+// GetTopicSummarySource collects the active posts of a topic, or the posts of one of its merge jobs so that
+// reviewers can start the wiki revision from a draft, and looks up a cached summary of them.
+func (s *ForumService) GetTopicSummarySource(ctx context.Context, topicID, mergeJobID string) (*TopicSummarySource, error) {
+	topic, exist, err := s.forumRepo.GetTopic(ctx, topicID)
+	if err != nil {
+		return nil, err
+	}
+	if !exist || topic.Status == entity.TopicStatusConverted {
+		return nil, errors.NotFound(reason.ObjectNotFound)
+	}
+
+	var postIDs []string
+	if len(mergeJobID) > 0 {
+		job, refs, exist, err := s.forumRepo.GetMergeJob(ctx, mergeJobID)
+		if err != nil {
+			return nil, err
+		}
+		if !exist || job.TopicID != topic.ID {
+			return nil, errors.NotFound(reason.ObjectNotFound)
+		}
+		mergeJobID = job.ID
+		for _, ref := range refs {
+			postIDs = append(postIDs, ref.PostID)
+		}
+	}
+	posts, err := s.forumRepo.ListSummaryPosts(ctx, topic.ID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, errors.BadRequest(reason.RequestFormatError).WithMsg("there are no posts to summarise")
+	}
+
+	source := &TopicSummarySource{
+		Topic:       topic,
+		MergeJobID:  mergeJobID,
+		Posts:       posts,
+		Fingerprint: summaryFingerprint(posts),
+	}
+	cache, exist, err := s.forumRepo.GetTopicSummaryCache(ctx, topic.ID, mergeJobID)
+	if err != nil {
+		return nil, err
+	}
+	if exist && cache.Fingerprint == source.Fingerprint {
+		source.CachedSummary = cache.Summary
+	}
+	return source, nil
+}
+
+// summaryFingerprint hashes the summarised posts with their text and author, so that a post added, edited
+// or hidden changes it
+func summaryFingerprint(posts []*forumrepo.TopicPostView) string {
+	hash := sha256.New()
+	for _, post := range posts {
+		_, _ = fmt.Fprintf(hash, "%s\x00%s\x00%s\x00", post.ID, post.AuthorDisplayName, post.OriginalText)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// SaveTopicSummary caches a generated summary until the summarised posts change.
+func (s *ForumService) SaveTopicSummary(ctx context.Context, source *TopicSummarySource, summary string) error {
+	return s.forumRepo.SetTopicSummaryCache(ctx, source.Topic.ID, source.MergeJobID, &forumrepo.TopicSummaryCache{
+		Fingerprint: source.Fingerprint,
+		Summary:     summary,
+	})
+}
+
+// BuildTopicSummaryPrompt renders the summary prompt. The opening post is always kept; when the transcript is
+// too long, the oldest replies are left out first.
+func BuildTopicSummaryPrompt(source *TopicSummarySource) string {
+	entries := make([]string, len(source.Posts))
+	for i, post := range source.Posts {
+		author := post.AuthorDisplayName
+		if author == "" {
+			author = post.AuthorUsername
+		}
+		if author == "" {
+			author = "user " + uid.EnShortID(post.UserID)
+		}
+		entries[i] = fmt.Sprintf("[#%d] %s (%s):\n%s\n",
+			i+1, author, post.CreatedAt.UTC().Format("2006-01-02 15:04"), strings.TrimSpace(post.OriginalText))
+	}
+
+	budget := maxSummaryTranscriptRunes - len([]rune(source.Topic.Title))
+	kept := make([]bool, len(entries))
+	for i := range entries {
+		// walk the opening post first, then the replies from newest to oldest
+		idx := 0
+		if i > 0 {
+			idx = len(entries) - i
+		}
+		size := len([]rune(entries[idx]))
+		if idx > 0 && size > budget {
+			break
+		}
+		kept[idx] = true
+		budget -= size
+	}
+
+	var transcript strings.Builder
+	if len(source.MergeJobID) > 0 {
+		transcript.WriteString("The posts below were selected to be merged into the wiki of the topic.\n")
+	}
+	transcript.WriteString("Topic: " + source.Topic.Title + "\n\n")
+	omitted := 0
+	for i, entry := range entries {
+		if !kept[i] {
+			omitted++
+			continue
+		}
+		if omitted > 0 {
+			transcript.WriteString(fmt.Sprintf("(%d earlier replies omitted)\n\n", omitted))
+			omitted = 0
+		}
+		transcript.WriteString(entry + "\n")
+	}
+	return fmt.Sprintf(constant.DefaultForumSummaryPrompt, transcript.String())
+}
@@ -0,0 +1,207 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package forum
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	forumrepo "github.com/apache/answer/internal/repo/forum"
+	"github.com/apache/answer/internal/schema"
+	"github.com/segmentfault/pacman/errors"
+)
+
+// GetTopicTags returns the tags of a topic.
+func (s *ForumService) GetTopicTags(ctx context.Context, topicID string) ([]*schema.TagResp, error) {
+	topic, err := s.GetTopic(ctx, topicID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tagCommonService.GetObjectTag(ctx, topic.ID)
+}
+
+// UpdateTopicTags replaces the tags of a topic. Only the author or a moderator may change them and only
+// moderators may add or remove reserved tags.
+func (s *ForumService) UpdateTopicTags(ctx context.Context, topicID string, req *schema.UpdateTopicTagsReq) (
+	[]*schema.TagResp, error,
+) {
+	topic, err := s.GetTopic(ctx, topicID)
+	if err != nil {
+		return nil, err
+	}
+	if topic.Status == entity.TopicStatusConverted {
+		return nil, errors.Forbidden(reason.StatusInvalid)
+	}
+	if topic.UserID != req.UserID && !req.IsAdminModerator {
+		return nil, errors.Forbidden(reason.ForbiddenError)
+	}
+
+	oldTags, err := s.tagCommonService.GetObjectEntityTag(ctx, topic.ID)
+	if err != nil {
+		return nil, err
+	}
+	newTags, err := s.resolveTopicTags(ctx, req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReservedTagChange(oldTags, newTags, req.CanUseReservedTag); err != nil {
+		return nil, err
+	}
+	if err := s.tagCommonService.CreateOrUpdateTagRelList(ctx, topic.ID, tagIDs(newTags)); err != nil {
+		return nil, err
+	}
+	return s.tagCommonService.TagFormat(ctx, newTags)
+}
+
+// ListTagContents lists the questions and topics carrying a tag or one of its synonyms.
+func (s *ForumService) ListTagContents(ctx context.Context, tagName string, req *schema.TagContentListReq) (
+	[]*forumrepo.TagContentView, int64, error,
+) {
+	ids, err := s.tagWithSynonymIDs(ctx, tagName)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return nil, 0, errors.NotFound(reason.TagNotFound)
+	}
+	return s.forumRepo.ListTagContents(ctx, ids, req.Page, req.PageSize)
+}
+
+// ListTagTopics lists the topics carrying a tag or one of its synonyms.
+func (s *ForumService) ListTagTopics(ctx context.Context, tagName string, page, pageSize int) (
+	[]*entity.Topic, int64, error,
+) {
+	ids, err := s.tagWithSynonymIDs(ctx, tagName)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.forumRepo.ListTopicsByTags(ctx, ids, page, pageSize)
+}
+
+// resolveTopicTags loads the named tags, replacing synonyms with their main tag. Unknown tags are rejected.
+func (s *ForumService) resolveTopicTags(ctx context.Context, names []string) ([]*entity.Tag, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	slugNames := make([]string, 0, len(names))
+	for _, name := range names {
+		slugNames = append(slugNames, strings.ToLower(strings.TrimSpace(name)))
+	}
+	tagList, err := s.tagCommonService.GetTagListByNames(ctx, slugNames)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]*entity.Tag, len(tagList))
+	for _, tag := range tagList {
+		found[strings.ToLower(tag.SlugName)] = tag
+	}
+	missing := make([]string, 0)
+	for _, name := range slugNames {
+		if _, ok := found[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, errors.BadRequest(reason.TagNotFound).WithMsg(
+			fmt.Sprintf("tag [%s] does not exist", strings.Join(missing, ",")))
+	}
+
+	tags := make([]*entity.Tag, 0, len(slugNames))
+	seen := make(map[string]bool, len(slugNames))
+	for _, name := range slugNames {
+		tag := found[name]
+		if tag.MainTagID != 0 {
+			mainTag, exist, err := s.tagCommonService.GetTagByID(ctx, strconv.FormatInt(tag.MainTagID, 10))
+			if err != nil {
+				return nil, err
+			}
+			if exist {
+				tag = mainTag
+			}
+		}
+		if seen[tag.ID] {
+			continue
+		}
+		seen[tag.ID] = true
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// tagWithSynonymIDs returns the id of the tag's main tag together with all its synonyms,
+// empty when the tag does not exist
+func (s *ForumService) tagWithSynonymIDs(ctx context.Context, tagName string) ([]string, error) {
+	tag, exist, err := s.tagCommonService.GetTagBySlugName(ctx, strings.ToLower(tagName))
+	if err != nil || !exist {
+		return nil, err
+	}
+	mainTagID := tag.ID
+	if tag.MainTagID != 0 {
+		mainTagID = strconv.FormatInt(tag.MainTagID, 10)
+	}
+	ids, err := s.tagCommonService.GetTagIDsByMainTagID(ctx, mainTagID)
+	if err != nil {
+		return nil, err
+	}
+	return append(ids, mainTagID), nil
+}
+
+// checkReservedTagChange rejects adding or removing reserved tags by users who may not use them
+func checkReservedTagChange(oldTags, newTags []*entity.Tag, canUseReservedTag bool) error {
+	if canUseReservedTag {
+		return nil
+	}
+	reserved := make(map[string]int)
+	for _, tag := range oldTags {
+		if tag.Reserved {
+			reserved[tag.SlugName]--
+		}
+	}
+	for _, tag := range newTags {
+		if tag.Reserved {
+			reserved[tag.SlugName]++
+		}
+	}
+	changed := make([]string, 0)
+	for slugName, diff := range reserved {
+		if diff != 0 {
+			changed = append(changed, slugName)
+		}
+	}
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		return errors.BadRequest(reason.RecommendTagEnter).WithMsg(
+			fmt.Sprintf(`"%s" can only be used by moderators.`, strings.Join(changed, ",")))
+	}
+	return nil
+}
+
+func tagIDs(tags []*entity.Tag) []string {
+	ids := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.ID)
+	}
+	return ids
+}
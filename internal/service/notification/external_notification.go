@@ -92,6 +92,12 @@ func (ns *ExternalNotificationService) Handler(ctx context.Context, msg *schema.
 	if msg.NewInviteAnswerTemplateRawData != nil {
 		return ns.handleInviteAnswerNotification(ctx, msg)
 	}
+	if msg.SavedSearchDigestTemplateRawData != nil {
+		return ns.handleSavedSearchDigestNotification(ctx, msg)
+	}
+	if msg.PluginDisabledTemplateRawData != nil {
+		return ns.handlePluginDisabledNotification(ctx, msg)
+	}
 	log.Errorf("unknown notification message: %+v", msg)
 	return nil
 }
@@ -214,7 +214,10 @@ func (ns *ExternalNotificationService) syncNewQuestionNotificationToPlugin(ctx c
 		}
 
 		// 2. get all new question's followers
-		questionSubscribers := fn.GetNewQuestionSubscribers()
+		questionSubscribers, err := plugin.GetNewQuestionSubscribersWithTimeout(fn)
+		if err != nil {
+			return err
+		}
 		for _, subscriber := range questionSubscribers {
 			subscribersMapping[subscriber] = plugin.NotificationNewQuestion
 		}
@@ -259,7 +262,9 @@ func (ns *ExternalNotificationService) syncNewQuestionNotificationToPlugin(ctx c
 			if exist {
 				newMsg.ReceiverExternalID = userInfo.ExternalID
 			}
-			fn.Notify(newMsg)
+			if err := plugin.NotifyWithTimeout(fn, newMsg); err != nil {
+				return err
+			}
 		}
 		return nil
 	})
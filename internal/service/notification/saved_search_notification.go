@@ -0,0 +1,54 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package notification
+
+import (
+	"context"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/schema"
+	"github.com/segmentfault/pacman/i18n"
+	"github.com/segmentfault/pacman/log"
+)
+
+// handleSavedSearchDigestNotification emails the new results of a saved search,
+// the user chose the email channel on the saved search itself
+func (ns *ExternalNotificationService) handleSavedSearchDigestNotification(ctx context.Context,
+	msg *schema.ExternalNotificationMsg) error {
+	log.Debugf("try to send saved search digest notification %+v", msg)
+	if unavailable := ns.checkUserStatusBeforeNotification(ctx, msg.ReceiverUserID); unavailable {
+		return nil
+	}
+	if len(msg.ReceiverEmail) == 0 {
+		return nil
+	}
+
+	// If receiver has set language, use it to send email.
+	if len(msg.ReceiverLang) > 0 {
+		ctx = context.WithValue(ctx, constant.AcceptLanguageContextKey, i18n.Language(msg.ReceiverLang))
+	}
+	title, body, err := ns.emailService.SavedSearchDigestTemplate(ctx, msg.SavedSearchDigestTemplateRawData)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	ns.emailService.Send(ctx, msg.ReceiverEmail, title, body)
+	return nil
+}
@@ -439,7 +439,6 @@ func (ns *NotificationCommon) syncNotificationToPlugin(ctx context.Context, objI
 		if exist {
 			pluginNotificationMsg.ReceiverExternalID = userInfo.ExternalID
 		}
-		fn.Notify(pluginNotificationMsg)
-		return nil
+		return plugin.NotifyWithTimeout(fn, pluginNotificationMsg)
 	})
 }
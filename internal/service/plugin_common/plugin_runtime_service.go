@@ -0,0 +1,106 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package plugin_common
+
+import (
+	"context"
+
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/event_listener"
+	"github.com/apache/answer/internal/service/noticequeue"
+	"github.com/apache/answer/internal/service/role"
+	"github.com/apache/answer/plugin"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentfault/pacman/log"
+)
+
+// PluginRuntimeService watches the plugins while the application runs: it alerts the admins of the plugins
+// the circuit breaker disables, reports the health of the calls of the plugins, and holds the delivery
+// of the events to the listener plugins
+type PluginRuntimeService struct {
+	pluginCommonService  *PluginCommonService
+	userRoleRelService   *role.UserRoleRelService
+	notificationQueue    noticequeue.ExternalService
+	eventListenerService *event_listener.EventListenerService
+}
+
+// NewPluginRuntimeService new plugin runtime service
+func NewPluginRuntimeService(
+	pluginCommonService *PluginCommonService,
+	userRoleRelService *role.UserRoleRelService,
+	notificationQueue noticequeue.ExternalService,
+	eventListenerService *event_listener.EventListenerService,
+) *PluginRuntimeService {
+	ps := &PluginRuntimeService{
+		pluginCommonService:  pluginCommonService,
+		userRoleRelService:   userRoleRelService,
+		notificationQueue:    notificationQueue,
+		eventListenerService: eventListenerService,
+	}
+	plugin.RegisterCircuitBreakerFunc(ps.handleCircuitBreak)
+	return ps
+}
+
+// GetPluginHealth get the health of the calls of each plugin, the plugins not called yet have no calls
+func (ps *PluginRuntimeService) GetPluginHealth(ctx *gin.Context) (resp []*schema.GetPluginHealthResp) {
+	healthMapping := make(map[string]plugin.Health)
+	for _, health := range plugin.GetHealth() {
+		healthMapping[health.SlugName] = health
+	}
+
+	resp = make([]*schema.GetPluginHealthResp, 0)
+	_ = plugin.CallBase(func(base plugin.Base) error {
+		info := base.Info()
+		health, ok := healthMapping[info.SlugName]
+		if !ok {
+			health = plugin.Health{SlugName: info.SlugName}
+		}
+		resp = append(resp, &schema.GetPluginHealthResp{
+			Name:    info.Name.Translate(ctx),
+			Enabled: plugin.StatusManager.IsEnabled(info.SlugName),
+			Health:  health,
+		})
+		return nil
+	})
+	return resp
+}
+
+// handleCircuitBreak saves the status of the plugin disabled by the circuit breaker and alerts the admins
+func (ps *PluginRuntimeService) handleCircuitBreak(slugName string, failure error) {
+	ctx := context.Background()
+	if err := ps.pluginCommonService.UpdatePluginStatus(ctx); err != nil {
+		log.Errorf("save status of plugin %s failed: %v", slugName, err)
+	}
+	admins, err := ps.userRoleRelService.GetUserByRoleID(ctx, []int{role.RoleAdminID})
+	if err != nil {
+		log.Errorf("get admins failed: %v", err)
+		return
+	}
+	for _, admin := range admins {
+		ps.notificationQueue.Send(ctx, &schema.ExternalNotificationMsg{
+			ReceiverUserID: admin.UserID,
+			PluginDisabledTemplateRawData: &schema.PluginDisabledTemplateRawData{
+				PluginSlugName: slugName,
+				Failures:       plugin.CircuitBreakerThreshold,
+				Reason:         failure.Error(),
+			},
+		})
+	}
+}
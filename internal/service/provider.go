@@ -25,6 +25,9 @@ import (
 	"github.com/apache/answer/internal/service/activity_common"
 	"github.com/apache/answer/internal/service/activityqueue"
 	"github.com/apache/answer/internal/service/ai_conversation"
+	"github.com/apache/answer/internal/service/ai_embedding"
+	"github.com/apache/answer/internal/service/ai_prompt"
+	"github.com/apache/answer/internal/service/ai_provider"
 	answercommon "github.com/apache/answer/internal/service/answer_common"
 	"github.com/apache/answer/internal/service/apikey"
 	"github.com/apache/answer/internal/service/auth"
@@ -36,6 +39,7 @@ import (
 	"github.com/apache/answer/internal/service/config"
 	"github.com/apache/answer/internal/service/content"
 	"github.com/apache/answer/internal/service/dashboard"
+	"github.com/apache/answer/internal/service/event_listener"
 	"github.com/apache/answer/internal/service/eventqueue"
 	"github.com/apache/answer/internal/service/export"
 	"github.com/apache/answer/internal/service/feature_toggle"
@@ -58,7 +62,10 @@ import (
 	"github.com/apache/answer/internal/service/review"
 	"github.com/apache/answer/internal/service/revision_common"
 	"github.com/apache/answer/internal/service/role"
+	"github.com/apache/answer/internal/service/saved_search"
+	"github.com/apache/answer/internal/service/search_analytics"
 	"github.com/apache/answer/internal/service/search_parser"
+	"github.com/apache/answer/internal/service/search_reindex"
 	"github.com/apache/answer/internal/service/siteinfo"
 	"github.com/apache/answer/internal/service/siteinfo_common"
 	"github.com/apache/answer/internal/service/tag"
@@ -86,6 +93,7 @@ var ProviderSetService = wire.NewSet(
 	content.NewUserService,
 	content.NewQuestionService,
 	content.NewAnswerService,
+	content.NewWriteGateService,
 	export.NewEmailService,
 	forum.NewForumService,
 	tagcommon.NewTagCommonService,
@@ -118,6 +126,7 @@ var ProviderSetService = wire.NewSet(
 	user_external_login.NewUserExternalLoginService,
 	user_external_login.NewUserCenterLoginService,
 	plugin_common.NewPluginCommonService,
+	plugin_common.NewPluginRuntimeService,
 	config.NewConfigService,
 	noticequeue.NewService,
 	activityqueue.NewService,
@@ -129,11 +138,18 @@ var ProviderSetService = wire.NewSet(
 	eventqueue.NewService,
 	badge.NewBadgeService,
 	badge.NewBadgeEventService,
+	event_listener.NewEventListenerService,
 	badge.NewBadgeAwardService,
 	badge.NewBadgeGroupService,
 	importer.NewImporterService,
 	file_record.NewFileRecordService,
 	apikey.NewAPIKeyService,
 	ai_conversation.NewAIConversationService,
+	ai_embedding.NewAIEmbeddingService,
+	ai_provider.NewAIProviderService,
+	ai_prompt.NewAIPromptService,
+	search_analytics.NewSearchAnalyticsService,
+	saved_search.NewSavedSearchService,
+	search_reindex.NewSearchReindexService,
 	feature_toggle.NewFeatureToggleService,
 )
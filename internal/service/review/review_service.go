@@ -217,18 +217,28 @@ func (cs *ReviewService) callPluginToReview(ctx context.Context, userID, objectI
 		reviewContent.Language = siteInterface.Language
 	}
 
-	_ = plugin.CallReviewer(func(reviewer plugin.Reviewer) error {
+	err := plugin.CallReviewer(func(reviewer plugin.Reviewer) error {
 		// If one of the reviewer plugin return false, then the review is not approved
 		if reviewStatus != plugin.ReviewStatusApproved {
 			return nil
 		}
-		if result := reviewer.Review(reviewContent); !result.Approved {
+		r.Submitter = reviewer.Info().SlugName
+		result, err := plugin.ReviewWithTimeout(reviewer, reviewContent)
+		if err != nil {
+			return err
+		}
+		if !result.Approved {
 			reviewStatus = result.ReviewStatus
 			r.Reason = result.Reason
-			r.Submitter = reviewer.Info().SlugName
 		}
 		return nil
 	})
+	// The reviewer panicking or timing out leaves the content to the moderators, rather than approving it
+	if plugin.IsCallFailure(err) {
+		log.Errorf("plugin %s failed to review %s: %v", r.Submitter, objectID, err)
+		reviewStatus = plugin.ReviewStatusNeedReview
+		r.Reason = err.Error()
+	}
 
 	if reviewStatus == plugin.ReviewStatusNeedReview {
 		if err := cs.reviewRepo.AddReview(ctx, r); err != nil {
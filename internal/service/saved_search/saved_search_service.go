@@ -0,0 +1,360 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package saved_search
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/data"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/repo/saved_search"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/content"
+	"github.com/apache/answer/internal/service/noticequeue"
+	usercommon "github.com/apache/answer/internal/service/user_common"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	// maxSavedSearchesPerUser the saved searches a user may have
+	maxSavedSearchesPerUser = 20
+	// savedSearchRunInterval a saved search reruns at most once in this interval
+	savedSearchRunInterval = time.Hour
+	// savedSearchRunsPerUser the saved searches of a user rerun in a cron execution, the others wait for the next one
+	savedSearchRunsPerUser  = 5
+	savedSearchRunBatchSize = 200
+	// savedSearchResultSize the newest results looked at in each rerun
+	savedSearchResultSize = 50
+)
+
+// SavedSearchService saved searches and the alerts of their new matches
+type SavedSearchService interface {
+	AddSavedSearch(ctx context.Context, req *schema.AddSavedSearchReq) (*schema.SavedSearchResp, error)
+	UpdateSavedSearch(ctx context.Context, req *schema.UpdateSavedSearchReq) error
+	RemoveSavedSearch(ctx context.Context, req *schema.RemoveSavedSearchReq) error
+	GetSavedSearchList(ctx context.Context, req *schema.GetSavedSearchListReq) ([]*schema.SavedSearchResp, error)
+	AlertCron(ctx context.Context)
+}
+
+type savedSearchService struct {
+	data                             *data.Data
+	savedSearchRepo                  saved_search.SavedSearchRepo
+	searchService                    *content.SearchService
+	userRepo                         usercommon.UserRepo
+	notificationQueueService         noticequeue.Service
+	externalNotificationQueueService noticequeue.ExternalService
+}
+
+// NewSavedSearchService new SavedSearchService
+func NewSavedSearchService(
+	data *data.Data,
+	savedSearchRepo saved_search.SavedSearchRepo,
+	searchService *content.SearchService,
+	userRepo usercommon.UserRepo,
+	notificationQueueService noticequeue.Service,
+	externalNotificationQueueService noticequeue.ExternalService,
+) SavedSearchService {
+	return &savedSearchService{
+		data:                             data,
+		savedSearchRepo:                  savedSearchRepo,
+		searchService:                    searchService,
+		userRepo:                         userRepo,
+		notificationQueueService:         notificationQueueService,
+		externalNotificationQueueService: externalNotificationQueueService,
+	}
+}
+
+// AddSavedSearch saves a search of the user, the results found now are marked as sent
+func (s *savedSearchService) AddSavedSearch(ctx context.Context, req *schema.AddSavedSearchReq) (
+	*schema.SavedSearchResp, error) {
+	count, err := s.savedSearchRepo.CountSavedSearches(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxSavedSearchesPerUser {
+		return nil, errors.BadRequest(reason.SavedSearchLimitExceeded)
+	}
+	savedSearch := &entity.SavedSearch{
+		UserID:      req.UserID,
+		Name:        req.Name,
+		Query:       req.Query,
+		NotifyInbox: req.NotifyInbox,
+		NotifyEmail: req.NotifyEmail,
+		LastRunAt:   time.Now(),
+	}
+	// the query is checked before it is saved
+	matches, err := s.search(ctx, savedSearch)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.savedSearchRepo.AddSavedSearch(ctx, savedSearch); err != nil {
+		return nil, err
+	}
+	if err = s.savedSearchRepo.AddMatches(ctx, savedSearch.ID, matchIDs(matches)); err != nil {
+		return nil, err
+	}
+	return convertSavedSearch(savedSearch), nil
+}
+
+// UpdateSavedSearch updates a saved search of the user, the results found now are marked as sent
+// when the query changes
+func (s *savedSearchService) UpdateSavedSearch(ctx context.Context, req *schema.UpdateSavedSearchReq) error {
+	savedSearch, err := s.getUserSavedSearch(ctx, req.ID, req.UserID)
+	if err != nil {
+		return err
+	}
+	queryChanged := savedSearch.Query != req.Query
+	savedSearch.Name = req.Name
+	savedSearch.Query = req.Query
+	savedSearch.NotifyInbox = req.NotifyInbox
+	savedSearch.NotifyEmail = req.NotifyEmail
+	if !queryChanged {
+		return s.savedSearchRepo.UpdateSavedSearch(ctx, savedSearch, false)
+	}
+
+	matches, err := s.search(ctx, savedSearch)
+	if err != nil {
+		return err
+	}
+	if err = s.savedSearchRepo.UpdateSavedSearch(ctx, savedSearch, true); err != nil {
+		return err
+	}
+	return s.savedSearchRepo.AddMatches(ctx, savedSearch.ID, matchIDs(matches))
+}
+
+// RemoveSavedSearch removes a saved search of the user
+func (s *savedSearchService) RemoveSavedSearch(ctx context.Context, req *schema.RemoveSavedSearchReq) error {
+	if _, err := s.getUserSavedSearch(ctx, req.ID, req.UserID); err != nil {
+		return err
+	}
+	return s.savedSearchRepo.RemoveSavedSearch(ctx, req.ID)
+}
+
+// GetSavedSearchList gets the saved searches of the user
+func (s *savedSearchService) GetSavedSearchList(ctx context.Context, req *schema.GetSavedSearchListReq) (
+	[]*schema.SavedSearchResp, error) {
+	list, err := s.savedSearchRepo.GetSavedSearchList(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*schema.SavedSearchResp, 0, len(list))
+	for _, savedSearch := range list {
+		resp = append(resp, convertSavedSearch(savedSearch))
+	}
+	return resp, nil
+}
+
+// AlertCron reruns the saved searches that did not run in the interval and sends their new matches.
+// A user has at most savedSearchRunsPerUser searches rerun each time.
+func (s *savedSearchService) AlertCron(ctx context.Context) {
+	savedSearches, err := s.savedSearchRepo.GetSavedSearchesToRun(ctx,
+		time.Now().Add(-savedSearchRunInterval), savedSearchRunBatchSize)
+	if err != nil {
+		log.Errorf("get saved searches to run failed: %v", err)
+		return
+	}
+	runs := make(map[string]int)
+	for _, savedSearch := range savedSearches {
+		if runs[savedSearch.UserID] >= savedSearchRunsPerUser {
+			continue
+		}
+		runs[savedSearch.UserID]++
+		if err := s.alert(ctx, savedSearch); err != nil {
+			log.Errorf("alert saved search %d failed: %v", savedSearch.ID, err)
+		}
+	}
+}
+
+// alert reruns the saved search and sends the matches not sent yet, as many as the daily limit of the user allows.
+// The matches over the limit are sent by a later run.
+func (s *savedSearchService) alert(ctx context.Context, savedSearch *entity.SavedSearch) error {
+	runAt := time.Now()
+	matches, err := s.search(ctx, savedSearch)
+	if err != nil {
+		return err
+	}
+	sent, err := s.savedSearchRepo.GetSentObjectIDs(ctx, savedSearch.ID, matchIDs(matches))
+	if err != nil {
+		return err
+	}
+	newMatches := make([]*schema.SearchResult, 0, len(matches))
+	for _, match := range matches {
+		if !sent[uid.DeShortID(match.Object.ID)] {
+			newMatches = append(newMatches, match)
+		}
+	}
+	if remaining := s.remainingAlerts(ctx, savedSearch.UserID); len(newMatches) > remaining {
+		newMatches = newMatches[:remaining]
+	}
+	if len(newMatches) > 0 {
+		// the matches are marked first, so they are never sent twice
+		if err = s.savedSearchRepo.AddMatches(ctx, savedSearch.ID, matchIDs(newMatches)); err != nil {
+			return err
+		}
+		s.recordAlerts(ctx, savedSearch.UserID, len(newMatches))
+		s.send(ctx, savedSearch, newMatches)
+	}
+	return s.savedSearchRepo.UpdateLastRunAt(ctx, savedSearch.ID, runAt)
+}
+
+// search runs the saved search as its user, it returns the newest questions and answers posted by others
+func (s *savedSearchService) search(ctx context.Context, savedSearch *entity.SavedSearch) (
+	[]*schema.SearchResult, error) {
+	resp, err := s.searchService.Search(ctx, &schema.SearchDTO{
+		Query:  savedSearch.Query,
+		Page:   1,
+		Size:   savedSearchResultSize,
+		Order:  "newest",
+		UserID: savedSearch.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]*schema.SearchResult, 0, len(resp.SearchResults))
+	for _, result := range resp.SearchResults {
+		if result.ObjectType != constant.QuestionObjectType && result.ObjectType != constant.AnswerObjectType {
+			continue
+		}
+		if result.Object.UserInfo != nil && result.Object.UserInfo.ID == savedSearch.UserID {
+			continue
+		}
+		matches = append(matches, result)
+	}
+	return matches, nil
+}
+
+// send sends each match to the inbox and all of them in one email, as the saved search asks
+func (s *savedSearchService) send(ctx context.Context, savedSearch *entity.SavedSearch,
+	matches []*schema.SearchResult) {
+	if savedSearch.NotifyInbox {
+		for _, match := range matches {
+			// the inbox shows who posted the match
+			if match.Object.UserInfo == nil || len(match.Object.UserInfo.ID) == 0 {
+				continue
+			}
+			s.notificationQueueService.Send(ctx, &schema.NotificationMsg{
+				TriggerUserID:       match.Object.UserInfo.ID,
+				ReceiverUserID:      savedSearch.UserID,
+				Type:                schema.NotificationTypeInbox,
+				ObjectID:            uid.DeShortID(match.Object.ID),
+				ObjectType:          match.ObjectType,
+				NotificationAction:  constant.NotificationMatchedSavedSearch,
+				NoNeedPushAllFollow: true,
+			})
+		}
+	}
+	if !savedSearch.NotifyEmail {
+		return
+	}
+	userInfo, exist, err := s.userRepo.GetByUserID(ctx, savedSearch.UserID)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if !exist {
+		log.Warnf("user %s not found", savedSearch.UserID)
+		return
+	}
+	rawData := &schema.SavedSearchDigestTemplateRawData{
+		SearchName: savedSearch.Name,
+		Query:      savedSearch.Query,
+	}
+	for _, match := range matches {
+		rawData.Matches = append(rawData.Matches, &schema.SavedSearchDigestMatch{
+			ObjectType: match.ObjectType,
+			ObjectID:   uid.DeShortID(match.Object.ID),
+			QuestionID: uid.DeShortID(match.Object.QuestionID),
+			Title:      match.Object.Title,
+		})
+	}
+	s.externalNotificationQueueService.Send(ctx, &schema.ExternalNotificationMsg{
+		ReceiverUserID:                   userInfo.ID,
+		ReceiverEmail:                    userInfo.EMail,
+		ReceiverLang:                     userInfo.Language,
+		SavedSearchDigestTemplateRawData: rawData,
+	})
+}
+
+// remainingAlerts the matches the user may still be sent today
+func (s *savedSearchService) remainingAlerts(ctx context.Context, userID string) int {
+	sent, exist, err := s.data.Cache.GetInt64(ctx, constant.SavedSearchAlertLimitCacheKeyPrefix+userID)
+	if err != nil {
+		log.Error(err)
+		return 0
+	}
+	if !exist {
+		return constant.SavedSearchAlertLimitMax
+	}
+	return max(0, constant.SavedSearchAlertLimitMax-int(sent))
+}
+
+func (s *savedSearchService) recordAlerts(ctx context.Context, userID string, count int) {
+	key := constant.SavedSearchAlertLimitCacheKeyPrefix + userID
+	_, exist, err := s.data.Cache.GetInt64(ctx, key)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if !exist {
+		err = s.data.Cache.SetInt64(ctx, key, int64(count), constant.SavedSearchAlertLimitCacheTime)
+	} else {
+		_, err = s.data.Cache.Increase(ctx, key, int64(count))
+	}
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func (s *savedSearchService) getUserSavedSearch(ctx context.Context, id int, userID string) (
+	*entity.SavedSearch, error) {
+	savedSearch, exist, err := s.savedSearchRepo.GetSavedSearch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exist || savedSearch.UserID != userID {
+		return nil, errors.NotFound(reason.SavedSearchNotFound)
+	}
+	return savedSearch, nil
+}
+
+func matchIDs(matches []*schema.SearchResult) []string {
+	ids := make([]string, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, uid.DeShortID(match.Object.ID))
+	}
+	return ids
+}
+
+func convertSavedSearch(savedSearch *entity.SavedSearch) *schema.SavedSearchResp {
+	return &schema.SavedSearchResp{
+		ID:          savedSearch.ID,
+		Name:        savedSearch.Name,
+		Query:       savedSearch.Query,
+		NotifyInbox: savedSearch.NotifyInbox,
+		NotifyEmail: savedSearch.NotifyEmail,
+		CreatedAt:   savedSearch.CreatedAt.Unix(),
+		LastRunAt:   savedSearch.LastRunAt.Unix(),
+	}
+}
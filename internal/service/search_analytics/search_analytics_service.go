@@ -0,0 +1,271 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package search_analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/entity"
+	"github.com/apache/answer/internal/repo/search_analytics"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/config"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	reportDateLayout = "2006-01-02"
+	reportDefaultDay = 30
+	reportMaxDay     = 366
+	reportQueryLimit = 20
+
+	// searchLogRetentionDay the search logs older than this are rolled up into the daily stats
+	searchLogRetentionDay = 30
+	rollUpBatchSize       = 1000
+	maxQueryRunes         = 128
+	userHashLength        = 16
+)
+
+// SearchAnalyticsService records the searches and the results clicked, and reports on them
+type SearchAnalyticsService interface {
+	RecordSearch(ctx context.Context, userID, ip, query string, resultCount int64) (searchID string)
+	RecordClick(ctx context.Context, req *schema.SearchClickReq) error
+	GetReport(ctx context.Context, req *schema.SearchAnalyticsReq) (*schema.SearchAnalyticsResp, error)
+	RollUpCron(ctx context.Context)
+}
+
+type searchAnalyticsService struct {
+	searchAnalyticsRepo search_analytics.SearchAnalyticsRepo
+	configService       *config.ConfigService
+}
+
+// NewSearchAnalyticsService new SearchAnalyticsService
+func NewSearchAnalyticsService(
+	searchAnalyticsRepo search_analytics.SearchAnalyticsRepo,
+	configService *config.ConfigService,
+) SearchAnalyticsService {
+	return &searchAnalyticsService{
+		searchAnalyticsRepo: searchAnalyticsRepo,
+		configService:       configService,
+	}
+}
+
+// RecordSearch records a search, the search is not failed by it so it returns an empty id when it is not recorded
+func (s *searchAnalyticsService) RecordSearch(ctx context.Context, userID, ip, query string, resultCount int64) string {
+	query = NormalizeQuery(query)
+	if len(query) == 0 {
+		return ""
+	}
+	searchLog := &entity.SearchLog{
+		UserHash:        s.hashUser(ctx, userID, ip),
+		Query:           query,
+		ResultCount:     resultCount,
+		ClickedObjectID: "0",
+	}
+	if err := s.searchAnalyticsRepo.AddSearchLog(ctx, searchLog); err != nil {
+		log.Errorf("record search failed: %v", err)
+		return ""
+	}
+	return strconv.FormatInt(searchLog.ID, 10)
+}
+
+// RecordClick records the first result clicked in a search, by the user who searched
+func (s *searchAnalyticsService) RecordClick(ctx context.Context, req *schema.SearchClickReq) error {
+	searchID, err := strconv.ParseInt(req.SearchID, 10, 64)
+	if err != nil {
+		return errors.BadRequest(reason.RequestFormatError).WithError(err)
+	}
+	objectID := uid.DeShortID(req.ObjectID)
+	if _, err := strconv.ParseInt(objectID, 10, 64); err != nil {
+		return errors.BadRequest(reason.RequestFormatError).WithError(err)
+	}
+	// the later clicks and the clicks on the searches of others are ignored
+	_, err = s.searchAnalyticsRepo.UpdateSearchLogClick(ctx, searchID, s.hashUser(ctx, req.UserID, req.IP),
+		objectID, req.Position)
+	return err
+}
+
+// GetReport gets the search stats of the top queries, of the queries without results and of each day.
+// The stats are summed by the database from the search logs and the daily stats they are rolled up into.
+func (s *searchAnalyticsService) GetReport(ctx context.Context, req *schema.SearchAnalyticsReq) (
+	*schema.SearchAnalyticsResp, error) {
+	start, end, err := parseReportRange(req)
+	if err != nil {
+		return nil, err
+	}
+	logEnd := end.AddDate(0, 0, 1)
+	startDate, endDate := start.Format(reportDateLayout), end.Format(reportDateLayout)
+	dayStats, err := s.searchAnalyticsRepo.GetSearchDayStats(ctx, start, logEnd, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	topQueries, err := s.searchAnalyticsRepo.GetSearchQueryStats(ctx, start, logEnd, startDate, endDate,
+		"searches", reportQueryLimit)
+	if err != nil {
+		return nil, err
+	}
+	zeroResultQueries, err := s.searchAnalyticsRepo.GetSearchQueryStats(ctx, start, logEnd, startDate, endDate,
+		"zero_results", reportQueryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &schema.SearchAnalyticsResp{
+		StartDate:         startDate,
+		EndDate:           endDate,
+		TopQueries:        queryStats(topQueries),
+		ZeroResultQueries: queryStats(zeroResultQueries),
+		Days:              make([]*schema.SearchDayStat, 0),
+	}
+	days := make(map[string]*schema.SearchDayStat)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		stat := &schema.SearchDayStat{Date: day.Format(reportDateLayout)}
+		days[stat.Date] = stat
+		resp.Days = append(resp.Days, stat)
+	}
+	for _, dayStat := range dayStats {
+		resp.Total.Add(dayStat.Searches, dayStat.ZeroResults, dayStat.Clicks)
+		if day, ok := days[dayStat.Day]; ok {
+			day.Add(dayStat.Searches, dayStat.ZeroResults, dayStat.Clicks)
+		}
+	}
+	return resp, nil
+}
+
+// RollUpCron rolls the search logs older than the retention up into the daily stats of their query.
+// The logs are cut at the start of a day so each day is rolled up at once.
+func (s *searchAnalyticsService) RollUpCron(ctx context.Context) {
+	now := time.Now()
+	before := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).
+		AddDate(0, 0, -searchLogRetentionDay)
+	for {
+		searchLogs, err := s.searchAnalyticsRepo.GetSearchLogsBefore(ctx, before, rollUpBatchSize)
+		if err != nil {
+			log.Errorf("get search logs to roll up failed: %v", err)
+			return
+		}
+		if len(searchLogs) == 0 {
+			return
+		}
+		dailies, logIDs := rollUp(searchLogs)
+		if err = s.searchAnalyticsRepo.RollUpSearchLogs(ctx, dailies, logIDs); err != nil {
+			log.Errorf("roll up search logs failed: %v", err)
+			return
+		}
+		log.Infof("rolled up %d search logs", len(searchLogs))
+		if len(searchLogs) < rollUpBatchSize {
+			return
+		}
+	}
+}
+
+// NormalizeQuery lowercases the query, collapses its spaces and cuts it to the length of the column
+func NormalizeQuery(query string) string {
+	query = strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	if runes := []rune(query); len(runes) > maxQueryRunes {
+		query = strings.TrimSpace(string(runes[:maxQueryRunes]))
+	}
+	return query
+}
+
+// hashUser hashes the user, or the IP of a guest, with the salt of the site so the searches of a user
+// are told apart without being traced back to them
+func (s *searchAnalyticsService) hashUser(ctx context.Context, userID, ip string) string {
+	salt, err := s.configService.GetStringValue(ctx, constant.SearchAnalyticsSaltKey)
+	if err != nil {
+		log.Errorf("get search analytics salt failed: %v", err)
+	}
+	unit := ip
+	if len(userID) > 0 {
+		unit = userID
+	}
+	sum := sha256.Sum256([]byte(salt + ":" + unit))
+	return hex.EncodeToString(sum[:])[:userHashLength]
+}
+
+// rollUp sums the search logs by local date and query
+func rollUp(searchLogs []*entity.SearchLog) (dailies []*entity.SearchLogDaily, logIDs []int64) {
+	days := make(map[[2]string]*entity.SearchLogDaily)
+	for _, searchLog := range searchLogs {
+		logIDs = append(logIDs, searchLog.ID)
+		key := [2]string{searchLog.CreatedAt.In(time.Local).Format(reportDateLayout), searchLog.Query}
+		daily, ok := days[key]
+		if !ok {
+			daily = &entity.SearchLogDaily{Day: key[0], Query: key[1]}
+			days[key] = daily
+			dailies = append(dailies, daily)
+		}
+		zeroResults, clicks := countSearchLog(searchLog)
+		daily.Searches++
+		daily.ZeroResults += zeroResults
+		daily.Clicks += clicks
+	}
+	return dailies, logIDs
+}
+
+func countSearchLog(searchLog *entity.SearchLog) (zeroResults, clicks int64) {
+	if searchLog.ResultCount == 0 {
+		zeroResults = 1
+	}
+	if searchLog.ClickedPosition > 0 {
+		clicks = 1
+	}
+	return zeroResults, clicks
+}
+
+func queryStats(stats []*entity.SearchLogDaily) []*schema.SearchQueryStat {
+	resp := make([]*schema.SearchQueryStat, 0, len(stats))
+	for _, stat := range stats {
+		queryStat := &schema.SearchQueryStat{Query: stat.Query}
+		queryStat.Add(stat.Searches, stat.ZeroResults, stat.Clicks)
+		resp = append(resp, queryStat)
+	}
+	return resp
+}
+
+// parseReportRange parses the inclusive date range of the report in the server time zone
+func parseReportRange(req *schema.SearchAnalyticsReq) (start, end time.Time, err error) {
+	now := time.Now()
+	end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if len(req.EndDate) > 0 {
+		if end, err = time.ParseInLocation(reportDateLayout, req.EndDate, time.Local); err != nil {
+			return start, end, errors.BadRequest(reason.RequestFormatError).WithError(err)
+		}
+	}
+	start = end.AddDate(0, 0, 1-reportDefaultDay)
+	if len(req.StartDate) > 0 {
+		if start, err = time.ParseInLocation(reportDateLayout, req.StartDate, time.Local); err != nil {
+			return start, end, errors.BadRequest(reason.RequestFormatError).WithError(err)
+		}
+	}
+	if start.After(end) || start.AddDate(0, 0, reportMaxDay-1).Before(end) {
+		return start, end, errors.BadRequest(reason.RequestFormatError).
+			WithMsg("the report covers 1 to 366 days and must start before it ends")
+	}
+	return start, end, nil
+}
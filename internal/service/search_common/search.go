@@ -27,8 +27,11 @@ import (
 )
 
 type SearchRepo interface {
-	SearchContents(ctx context.Context, words []string, tagIDs [][]string, userID string, votes, page, size int, order string) (resp []*schema.SearchResult, total int64, err error)
-	SearchQuestions(ctx context.Context, words []string, tagIDs [][]string, notAccepted bool, views, answers int, page, size int, order string) (resp []*schema.SearchResult, total int64, err error)
-	SearchAnswers(ctx context.Context, words []string, tagIDs [][]string, accepted bool, questionID string, page, size int, order string) (resp []*schema.SearchResult, total int64, err error)
-	ParseSearchPluginResult(ctx context.Context, sres []plugin.SearchResult, words []string) (resp []*schema.SearchResult, err error)
+	SearchContents(ctx context.Context, cond *schema.SearchCondition, page, size int, order string) (resp []*schema.SearchResult, total int64, err error)
+	SearchQuestions(ctx context.Context, cond *schema.SearchCondition, page, size int, order string) (resp []*schema.SearchResult, total int64, err error)
+	SearchAnswers(ctx context.Context, cond *schema.SearchCondition, page, size int, order string) (resp []*schema.SearchResult, total int64, err error)
+	SearchTopics(ctx context.Context, cond *schema.SearchCondition, page, size int, order string) (resp []*schema.SearchResult, total int64, err error)
+	ParseSearchPluginResult(ctx context.Context, sres []plugin.SearchResult, cond *schema.SearchCondition) (resp []*schema.SearchResult, err error)
+	SearchFacets(ctx context.Context, cond *schema.SearchCondition) (facets *plugin.SearchFacets, err error)
+	ParseSearchFacets(ctx context.Context, facets *plugin.SearchFacets) (resp *schema.SearchFacets, err error)
 }
@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package search_parser
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/apache/answer/internal/base/reason"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenPhrase
+	tokenTag
+	tokenFilter
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	// text the word, the phrase, the tag slug name or the filter value
+	text string
+	// field the filter name
+	field string
+	// pos the position of the token in the query, counted in characters from 1
+	pos int
+}
+
+// filterFields the names a filter can have, `name:value` with another name is a word
+var filterFields = map[string]bool{
+	"user":        true,
+	"score":       true,
+	"views":       true,
+	"answers":     true,
+	"hasaccepted": true,
+	"isaccepted":  true,
+	"inquestion":  true,
+	"is":          true,
+	"created":     true,
+	"category":    true,
+}
+
+// wordSeparator the characters a word is split at. They are Markdown syntax,
+// searching them would match nearly all the content.
+var wordSeparator = regexp.MustCompile(`[+#.<>\-_()*]`)
+
+// lex splits the query into tokens, the last one is always tokenEOF
+func lex(query string) ([]*token, error) {
+	runes := []rune(query)
+	tokens := make([]*token, 0)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			end := indexRune(runes, i+1, '"')
+			if end < 0 {
+				return nil, &QueryError{Reason: reason.SearchQueryUnclosedQuote, Position: i + 1, Token: `"`}
+			}
+			tokens = append(tokens, &token{kind: tokenPhrase, text: string(runes[i+1 : end]), pos: i + 1})
+			i = end + 1
+		case r == '[':
+			end := indexRune(runes, i+1, ']')
+			if end < 0 {
+				return nil, &QueryError{Reason: reason.SearchQueryUnclosedBracket, Position: i + 1, Token: "["}
+			}
+			slugName := strings.TrimSpace(string(runes[i+1 : end]))
+			if len(slugName) == 0 {
+				return nil, &QueryError{Reason: reason.SearchQueryEmptyTag, Position: i + 1, Token: "[]"}
+			}
+			tokens = append(tokens, &token{kind: tokenTag, text: slugName, pos: i + 1})
+			i = end + 1
+		case r == '(':
+			tokens = append(tokens, &token{kind: tokenOpen, text: "(", pos: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, &token{kind: tokenClose, text: ")", pos: i + 1})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && !strings.ContainsRune("-)", runes[i+1]):
+			tokens = append(tokens, &token{kind: tokenNot, text: "-", pos: i + 1})
+			i++
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`"()[`, runes[end]) {
+				end++
+			}
+			tokens = append(tokens, wordToken(string(runes[i:end]), i+1))
+			i = end
+		}
+	}
+	return append(tokens, &token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+// wordToken tells OR and the filters from the other words
+func wordToken(word string, pos int) *token {
+	if word == "OR" {
+		return &token{kind: tokenOr, text: word, pos: pos}
+	}
+	if field, value, ok := cutFilter(word); ok {
+		return &token{kind: tokenFilter, field: field, text: value, pos: pos}
+	}
+	return &token{kind: tokenWord, text: word, pos: pos}
+}
+
+func cutFilter(word string) (field, value string, ok bool) {
+	field, value, ok = strings.Cut(word, ":")
+	if !ok || !filterFields[field] {
+		return "", "", false
+	}
+	return field, value, true
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasLetterOrDigit reports whether the text has something to search for
+func hasLetterOrDigit(text string) bool {
+	return strings.IndexFunc(text, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) >= 0
+}
@@ -0,0 +1,300 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package search_parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apache/answer/internal/base/reason"
+)
+
+const (
+	// maxQueryTerms caps the words and phrases of a query, the excluded ones included
+	maxQueryTerms = 10
+	// maxQueryDepth caps the nesting of parentheses
+	maxQueryDepth = 5
+)
+
+type NodeKind int
+
+const (
+	NodeAnd NodeKind = iota
+	NodeOr
+	NodeNot
+	NodeTerm
+	NodePhrase
+	NodeTag
+	NodeFilter
+)
+
+// Node is a node of the tree a search query is parsed into
+type Node struct {
+	Kind NodeKind
+	// Text the word of a term, the words of a phrase, the slug name of a tag or the value of a filter
+	Text string
+	// Field the name of a filter
+	Field string
+	// Pos the position in the query, counted in characters from 1
+	Pos int
+	// Children the operands of and, or and not
+	Children []*Node
+}
+
+// QueryError is a syntax error of a search query, Reason is the translation key of the message
+// and the other fields are the data of it.
+type QueryError struct {
+	Reason   string
+	Position int
+	Token    string
+	Field    string
+	Limit    int
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d near %q", e.Reason, e.Position, e.Token)
+}
+
+// Parse parses the query into a tree. The words are ANDed unless joined by OR, which binds tighter,
+// a leading - excludes what follows and parentheses group. Nil is returned when there is nothing to search for.
+//
+//	query   = { or }
+//	or      = unary { "OR" unary }
+//	unary   = "-" unary | primary
+//	primary = word | '"' phrase '"' | "[" tag "]" | field ":" value | "(" query ")"
+func Parse(query string) (*Node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	// parseAnd only stops early at a closing parenthesis
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, unexpectedToken(tok)
+	}
+	if terms := countTerms(node); terms > maxQueryTerms {
+		return nil, &QueryError{Reason: reason.SearchQueryTooManyTerms, Position: 1, Limit: maxQueryTerms}
+	}
+	return node, nil
+}
+
+type queryParser struct {
+	tokens []*token
+	cursor int
+	depth  int
+}
+
+func (p *queryParser) peek() *token {
+	return p.tokens[p.cursor]
+}
+
+func (p *queryParser) next() *token {
+	tok := p.tokens[p.cursor]
+	if tok.kind != tokenEOF {
+		p.cursor++
+	}
+	return tok
+}
+
+func (p *queryParser) parseAnd() (*Node, error) {
+	nodes := make([]*Node, 0)
+	for {
+		if kind := p.peek().kind; kind == tokenEOF || kind == tokenClose {
+			return joinNodes(NodeAnd, nodes), nil
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+func (p *queryParser) parseOr() (*Node, error) {
+	if tok := p.peek(); tok.kind == tokenOr {
+		return nil, missingOperand(tok)
+	}
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*Node{node}
+	for p.peek().kind == tokenOr {
+		or := p.next()
+		if !p.startsOperand() {
+			return nil, missingOperand(or)
+		}
+		if node, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return joinNodes(NodeOr, nodes), nil
+}
+
+func (p *queryParser) parseUnary() (*Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNot:
+		if !p.startsOperand() {
+			return nil, missingOperand(tok)
+		}
+		child, err := p.parseUnary()
+		if err != nil || child == nil {
+			return nil, err
+		}
+		// a double exclusion is no exclusion
+		if child.Kind == NodeNot {
+			return child.Children[0], nil
+		}
+		return &Node{Kind: NodeNot, Pos: tok.pos, Children: []*Node{child}}, nil
+	case tokenOpen:
+		if p.depth++; p.depth > maxQueryDepth {
+			return nil, &QueryError{Reason: reason.SearchQueryTooDeep, Position: tok.pos, Token: tok.text,
+				Limit: maxQueryDepth}
+		}
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenClose {
+			return nil, &QueryError{Reason: reason.SearchQueryUnclosedParen, Position: tok.pos, Token: tok.text}
+		}
+		p.depth--
+		return node, nil
+	case tokenWord:
+		return wordNode(tok), nil
+	case tokenPhrase:
+		if !hasLetterOrDigit(tok.text) {
+			return nil, nil
+		}
+		return &Node{Kind: NodePhrase, Text: tok.text, Pos: tok.pos}, nil
+	case tokenTag:
+		return &Node{Kind: NodeTag, Text: tok.text, Pos: tok.pos}, nil
+	case tokenFilter:
+		return &Node{Kind: NodeFilter, Field: tok.field, Text: tok.text, Pos: tok.pos}, nil
+	}
+	return nil, unexpectedToken(tok)
+}
+
+// startsOperand reports whether the next token can start the operand of OR or -
+func (p *queryParser) startsOperand() bool {
+	kind := p.peek().kind
+	return kind != tokenEOF && kind != tokenClose && kind != tokenOr
+}
+
+// wordNode splits the word at the Markdown syntax characters into terms, the parts left
+// with nothing to search for are dropped and the parts looking like filters are filters
+func wordNode(tok *token) *Node {
+	nodes := make([]*Node, 0)
+	for _, part := range strings.Fields(wordSeparator.ReplaceAllString(tok.text, " ")) {
+		if field, value, ok := cutFilter(part); ok {
+			nodes = append(nodes, &Node{Kind: NodeFilter, Field: field, Text: value, Pos: tok.pos})
+			continue
+		}
+		// OR is an operator when it stands alone, not a word
+		if part == "OR" || !hasLetterOrDigit(part) {
+			continue
+		}
+		nodes = append(nodes, &Node{Kind: NodeTerm, Text: part, Pos: tok.pos})
+	}
+	return joinNodes(NodeAnd, nodes)
+}
+
+// joinNodes joins the nodes with and or or, nodes of the same kind are merged and nil ones dropped
+func joinNodes(kind NodeKind, nodes []*Node) *Node {
+	children := make([]*Node, 0, len(nodes))
+	for _, node := range nodes {
+		switch {
+		case node == nil:
+		case node.Kind == kind:
+			children = append(children, node.Children...)
+		default:
+			children = append(children, node)
+		}
+	}
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return children[0]
+	}
+	return &Node{Kind: kind, Pos: children[0].Pos, Children: children}
+}
+
+func countTerms(node *Node) (count int) {
+	if node == nil {
+		return 0
+	}
+	if node.Kind == NodeTerm || node.Kind == NodePhrase {
+		return 1
+	}
+	for _, child := range node.Children {
+		count += countTerms(child)
+	}
+	return count
+}
+
+func unexpectedToken(tok *token) *QueryError {
+	return &QueryError{Reason: reason.SearchQueryUnexpectedToken, Position: tok.pos, Token: tok.text}
+}
+
+// missingOperand is the error of an OR or - with nothing to apply to
+func missingOperand(tok *token) *QueryError {
+	return &QueryError{Reason: reason.SearchQueryMissingOperand, Position: tok.pos, Token: tok.text}
+}
+
+// Query formats the tree in the query syntax, parsing it gives the same tree
+func (n *Node) Query() string {
+	if n == nil {
+		return ""
+	}
+	switch n.Kind {
+	case NodeTerm:
+		return n.Text
+	case NodePhrase:
+		return `"` + n.Text + `"`
+	case NodeTag:
+		return "[" + n.Text + "]"
+	case NodeFilter:
+		return n.Field + ":" + n.Text
+	case NodeNot:
+		return "-" + n.Children[0].groupQuery()
+	}
+	parts := make([]string, 0, len(n.Children))
+	for _, child := range n.Children {
+		parts = append(parts, child.groupQuery())
+	}
+	if n.Kind == NodeOr {
+		return strings.Join(parts, " OR ")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (n *Node) groupQuery() string {
+	if n.Kind == NodeAnd || n.Kind == NodeOr {
+		return "(" + n.Query() + ")"
+	}
+	return n.Query()
+}
@@ -0,0 +1,135 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package search_parser
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"deploy", "deploy"},
+		{"deploy friday", "deploy friday"},
+		{"deploy OR release friday", "(deploy OR release) friday"},
+		{"a OR b OR c", "a OR b OR c"},
+		{`"blue green" deploy`, `"blue green" deploy`},
+		{"deploy -friday", "deploy -friday"},
+		{"deploy -(friday OR weekend)", "deploy -(friday OR weekend)"},
+		{"-(-deploy)", "deploy"},
+		{"(a b) OR c", "(a b) OR c"},
+		{"((a))", "a"},
+		{"e-mail node.js c++", "e mail node js c"},
+		{"# ** -", ""},
+		{`"#"`, ""},
+		{"a OR #", "a"},
+		{"[go] user:me score:3 deploy", "[go] user:me score:3 deploy"},
+		{"created:>2024-01-01 is:topic category:ops", "created:>2024-01-01 is:topic category:ops"},
+		{"error:42 http://example.com", "error:42 http://example com"},
+	}
+	for _, c := range cases {
+		node, err := Parse(c.query)
+		require.NoError(t, err, c.query)
+		assert.Equal(t, c.want, node.Query(), c.query)
+	}
+
+	node, err := Parse("deploy -friday (blue OR green)")
+	require.NoError(t, err)
+	require.Equal(t, NodeAnd, node.Kind)
+	require.Len(t, node.Children, 3)
+	assert.Equal(t, NodeTerm, node.Children[0].Kind)
+	assert.Equal(t, NodeNot, node.Children[1].Kind)
+	assert.Equal(t, 8, node.Children[1].Pos)
+	assert.Equal(t, NodeOr, node.Children[2].Kind)
+	assert.Equal(t, "green", node.Children[2].Children[1].Text)
+}
+
+func TestParse_Errors(t *testing.T) {
+	cases := []struct {
+		query    string
+		reason   string
+		position int
+		token    string
+	}{
+		{`deploy "blue green`, reason.SearchQueryUnclosedQuote, 8, `"`},
+		{"deploy [go", reason.SearchQueryUnclosedBracket, 8, "["},
+		{"deploy [ ]", reason.SearchQueryEmptyTag, 8, "[]"},
+		{"(deploy friday", reason.SearchQueryUnclosedParen, 1, "("},
+		{"deploy)", reason.SearchQueryUnexpectedToken, 7, ")"},
+		{"OR deploy", reason.SearchQueryMissingOperand, 1, "OR"},
+		{"deploy OR", reason.SearchQueryMissingOperand, 8, "OR"},
+		{"deploy OR OR friday", reason.SearchQueryMissingOperand, 8, "OR"},
+		{"(deploy OR) friday", reason.SearchQueryMissingOperand, 9, "OR"},
+		{"deploy -OR friday", reason.SearchQueryMissingOperand, 8, "-"},
+		{"a b c d e f g h i j k", reason.SearchQueryTooManyTerms, 1, ""},
+		{"((((((a))))))", reason.SearchQueryTooDeep, 6, "("},
+		{"日本 \"語", reason.SearchQueryUnclosedQuote, 4, `"`},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.query)
+		require.Error(t, err, c.query)
+		queryErr, ok := err.(*QueryError)
+		require.True(t, ok, c.query)
+		assert.Equal(t, c.reason, queryErr.Reason, c.query)
+		assert.Equal(t, c.position, queryErr.Position, c.query)
+		assert.Equal(t, c.token, queryErr.Token, c.query)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"deploy friday",
+		`"blue green" OR canary -friday`,
+		"(a OR (b -c)) [go] user:me created:>=2024-01-01",
+		"is:topic category:ops score:3",
+		`-"x" OR ( ) "`,
+		"e-mail node.js c++ _is:question",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, query string) {
+		if !utf8.ValidString(query) {
+			return
+		}
+		node, err := Parse(query)
+		if err != nil {
+			queryErr, ok := err.(*QueryError)
+			require.True(t, ok, "%q: %v", query, err)
+			assert.NotEmpty(t, queryErr.Reason)
+			assert.GreaterOrEqual(t, queryErr.Position, 1)
+			assert.LessOrEqual(t, queryErr.Position, utf8.RuneCountInString(query)+1)
+			return
+		}
+		assert.LessOrEqual(t, countTerms(node), maxQueryTerms)
+		// the formatted tree parses back into the same tree
+		again, err := Parse(node.Query())
+		require.NoError(t, err, "%q formatted as %q", query, node.Query())
+		assert.Equal(t, node.Query(), again.Query(), query)
+	})
+}
@@ -23,313 +23,304 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/apache/answer/internal/base/constant"
-
+	"github.com/apache/answer/internal/base/handler"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/base/translator"
+	forumrepo "github.com/apache/answer/internal/repo/forum"
 	"github.com/apache/answer/internal/schema"
 	"github.com/apache/answer/internal/service/tag_common"
 	usercommon "github.com/apache/answer/internal/service/user_common"
 	"github.com/apache/answer/pkg/converter"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/apache/answer/plugin"
+	"github.com/segmentfault/pacman/errors"
+)
+
+const (
+	// maxSearchTags caps the tags of a query, the others are ignored
+	maxSearchTags = 5
+	dateLayout    = "2006-01-02"
+)
+
+var (
+	numberValue = regexp.MustCompile(`^\d+$`)
+	idValue     = regexp.MustCompile(`^[0-9A-Za-z]+$`)
 )
 
 type SearchParser struct {
 	tagCommonService *tag_common.TagCommonService
 	userCommon       *usercommon.UserCommon
+	forumRepo        *forumrepo.ForumRepo
 }
 
-func NewSearchParser(tagCommonService *tag_common.TagCommonService, userCommon *usercommon.UserCommon) *SearchParser {
+func NewSearchParser(
+	tagCommonService *tag_common.TagCommonService,
+	userCommon *usercommon.UserCommon,
+	forumRepo *forumrepo.ForumRepo,
+) *SearchParser {
 	return &SearchParser{
 		tagCommonService: tagCommonService,
 		userCommon:       userCommon,
+		forumRepo:        forumRepo,
 	}
 }
 
-// ParseStructure parse search structure, maybe match one of type all/questions/answers,
-// but if match two type, it will return false
-func (sp *SearchParser) ParseStructure(ctx context.Context, dto *schema.SearchDTO) (cond *schema.SearchCondition) {
-	cond = &schema.SearchCondition{}
-	var (
-		query      = dto.Query
-		limitWords = 5
-	)
-
-	// match tags
-	cond.Tags = sp.parseTags(ctx, &query)
-
-	// match all
-	cond.UserID = sp.parseUserID(ctx, &query, dto.UserID)
-	cond.VoteAmount = sp.parseVotes(&query)
-	cond.Words = sp.parseWithin(&query)
-
-	// match questions
-	cond.NotAccepted = sp.parseNotAccepted(&query)
-	if cond.NotAccepted {
-		cond.TargetType = constant.QuestionObjectType
+// ParseStructure parse search structure. The filters narrow the target type down to
+// questions, answers or topics, a query with filters of two types is an error.
+func (sp *SearchParser) ParseStructure(ctx context.Context, dto *schema.SearchDTO) (
+	cond *schema.SearchCondition, err error) {
+	cond = &schema.SearchCondition{VoteAmount: -1, Views: -1, AnswerAmount: -1, FragmentSize: dto.FragmentSize}
+	root, err := Parse(dto.Query)
+	if err != nil {
+		return nil, translateQueryError(ctx, err)
 	}
-	cond.Views = sp.parseViews(&query)
-	if cond.Views != -1 {
-		cond.TargetType = constant.QuestionObjectType
+
+	var nodes []*Node
+	if root != nil && root.Kind == NodeAnd {
+		nodes = root.Children
+	} else if root != nil {
+		nodes = []*Node{root}
 	}
-	cond.AnswerAmount = sp.parseAnswers(&query)
-	if cond.AnswerAmount != -1 {
-		cond.TargetType = constant.QuestionObjectType
+	exprs := make([]*plugin.SearchExpr, 0, len(nodes))
+	for _, node := range nodes {
+		switch node.Kind {
+		case NodeTag:
+			err = sp.parseTag(ctx, cond, node)
+		case NodeFilter:
+			err = sp.parseFilter(ctx, cond, node, dto.UserID)
+		default:
+			// the filters apply to the whole query
+			if filter := findFilter(node); filter != nil {
+				err = &QueryError{Reason: reason.SearchQueryFilterNotAllowed, Position: filter.Pos,
+					Token: filter.Query(), Field: filterName(filter)}
+				break
+			}
+			exprs = append(exprs, searchExpr(node))
+		}
+		if err != nil {
+			return nil, translateQueryError(ctx, err)
+		}
 	}
 
-	// match answers
-	cond.Accepted = sp.parseAccepted(&query)
-	if cond.Accepted {
-		cond.TargetType = constant.AnswerObjectType
-	}
-	cond.QuestionID = sp.parseQuestionID(&query)
-	if cond.QuestionID != "" {
-		cond.TargetType = constant.AnswerObjectType
+	switch len(exprs) {
+	case 0:
+	case 1:
+		cond.Query = exprs[0]
+	default:
+		cond.Query = &plugin.SearchExpr{Kind: plugin.SearchExprAnd, Children: exprs}
 	}
+	cond.Words = cond.Query.Terms()
+	return cond, nil
+}
 
-	if sp.parseIsQuestion(&query) {
-		cond.TargetType = constant.QuestionObjectType
+// parseTag adds the tag and its synonyms as a group, any of them matches
+func (sp *SearchParser) parseTag(ctx context.Context, cond *schema.SearchCondition, node *Node) error {
+	if len(cond.Tags) >= maxSearchTags {
+		return nil
 	}
-	if sp.parseIsAnswer(&query) {
-		cond.TargetType = constant.AnswerObjectType
+	tag, exists, err := sp.tagCommonService.GetTagBySlugName(ctx, node.Text)
+	if err != nil || !exists {
+		return err
 	}
-
-	if len(strings.TrimSpace(query)) > 0 {
-		words := strings.Split(strings.TrimSpace(query), " ")
-		cond.Words = append(cond.Words, words...)
+	tagGroup := []string{tag.ID}
+	if tag.MainTagID > 0 {
+		tagGroup = append(tagGroup, fmt.Sprintf("%d", tag.MainTagID))
 	}
-
-	// check limit words
-	if len(cond.Words) > limitWords {
-		cond.Words = cond.Words[:limitWords]
+	synIDs, err := sp.tagCommonService.GetTagIDsByMainTagID(ctx, tag.ID)
+	if err != nil {
+		return err
 	}
-	return
+	tagGroup = append(tagGroup, synIDs...)
+	cond.Tags = append(cond.Tags, converter.UniqueArray(tagGroup))
+	return nil
 }
 
-// parseTags parse search tags, return tag ids array
-func (sp *SearchParser) parseTags(ctx context.Context, query *string) (tags [][]string) {
-	var (
-		// expire tag pattern
-		exprTag = `\[(.*?)\]`
-		q       = *query
-		limit   = 5
-	)
-
-	re := regexp.MustCompile(exprTag)
-	res := re.FindAllStringSubmatch(q, -1)
-	if len(res) == 0 {
-		return
-	}
-
-	tags = make([][]string, 0)
-	for _, item := range res {
-		tagGroup := make([]string, 0)
-		tag, exists, err := sp.tagCommonService.GetTagBySlugName(ctx, item[1])
-		if err != nil || !exists {
-			continue
+func (sp *SearchParser) parseFilter(ctx context.Context, cond *schema.SearchCondition, node *Node,
+	currentUserID string) (err error) {
+	invalid := &QueryError{Reason: reason.SearchQueryInvalidFilter, Position: node.Pos, Token: node.Text,
+		Field: node.Field}
+	switch node.Field {
+	case "user":
+		if node.Text == "me" {
+			cond.UserID = currentUserID
+			return nil
 		}
-		tagGroup = append(tagGroup, tag.ID)
-		if tag.MainTagID > 0 {
-			tagGroup = append(tagGroup, fmt.Sprintf("%d", tag.MainTagID))
+		if len(node.Text) == 0 {
+			return invalid
 		}
-		synIDs, err := sp.tagCommonService.GetTagIDsByMainTagID(ctx, tag.ID)
-		if err != nil || !exists {
-			continue
+		user, exist, err := sp.userCommon.GetUserBasicInfoByUserName(ctx, node.Text)
+		if err != nil {
+			return err
 		}
-		tagGroup = append(tagGroup, tag.ID)
-		tagGroup = append(tagGroup, synIDs...)
-		tagGroup = converter.UniqueArray(tagGroup)
-		tags = append(tags, tagGroup)
-	}
-
-	// limit maximum 5 tags
-	if len(tags) > limit {
-		tags = tags[:limit]
+		if !exist {
+			return &QueryError{Reason: reason.SearchQueryUnknownValue, Position: node.Pos, Token: node.Text,
+				Field: node.Field}
+		}
+		cond.UserID = user.ID
+	case "score", "views", "answers":
+		if !numberValue.MatchString(node.Text) {
+			return invalid
+		}
+		amount, err := strconv.Atoi(node.Text)
+		if err != nil {
+			return invalid
+		}
+		switch node.Field {
+		case "score":
+			cond.VoteAmount = amount
+		case "views":
+			cond.Views = amount
+			return setTargetType(cond, constant.QuestionObjectType, node)
+		case "answers":
+			cond.AnswerAmount = amount
+			return setTargetType(cond, constant.QuestionObjectType, node)
+		}
+	case "hasaccepted":
+		if node.Text != "no" {
+			return invalid
+		}
+		cond.NotAccepted = true
+		return setTargetType(cond, constant.QuestionObjectType, node)
+	case "isaccepted":
+		if node.Text != "yes" {
+			return invalid
+		}
+		cond.Accepted = true
+		return setTargetType(cond, constant.AnswerObjectType, node)
+	case "inquestion":
+		if !idValue.MatchString(node.Text) {
+			return invalid
+		}
+		cond.QuestionID = uid.DeShortID(node.Text)
+		return setTargetType(cond, constant.AnswerObjectType, node)
+	case "is":
+		switch node.Text {
+		case "question":
+			return setTargetType(cond, constant.QuestionObjectType, node)
+		case "answer":
+			return setTargetType(cond, constant.AnswerObjectType, node)
+		case "topic":
+			return setTargetType(cond, constant.TopicObjectType, node)
+		}
+		return invalid
+	case "created":
+		return parseCreated(cond, node, invalid)
+	case "category":
+		if len(node.Text) == 0 {
+			return invalid
+		}
+		category, exist, err := sp.forumRepo.GetCategoryBySlug(ctx, node.Text)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return &QueryError{Reason: reason.SearchQueryUnknownValue, Position: node.Pos, Token: node.Text,
+				Field: node.Field}
+		}
+		cond.CategoryID = category.ID
+		return setTargetType(cond, constant.TopicObjectType, node)
 	}
-
-	q = strings.TrimSpace(re.ReplaceAllString(q, ""))
-	*query = q
-	return
+	return nil
 }
 
-// parseUserID return user id or current login user id
-func (sp *SearchParser) parseUserID(ctx context.Context, query *string, currentUserID string) (userID string) {
-	var (
-		exprUsername = `user:(\S+)`
-		exprMe       = "user:me"
-		q            = *query
-	)
-
-	re := regexp.MustCompile(exprUsername)
-	res := re.FindStringSubmatch(q)
-	if strings.Contains(q, exprMe) {
-		userID = currentUserID
-		q = strings.ReplaceAll(q, exprMe, "")
-	} else if len(res) > 1 {
-		name := res[1]
-		user, has, err := sp.userCommon.GetUserBasicInfoByUserName(ctx, name)
-		if err == nil && has {
-			userID = user.ID
-			q = re.ReplaceAllString(q, "")
+// parseCreated narrows the creation time down with a date, like created:2024-01-01 for that day
+// or created:>=2024-01-01 with one of >, >=, < and <= for the time around it
+func parseCreated(cond *schema.SearchCondition, node *Node, invalid error) error {
+	op, value := "", node.Text
+	for _, prefix := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(value, prefix) {
+			op, value = prefix, strings.TrimPrefix(value, prefix)
+			break
 		}
 	}
-	*query = strings.TrimSpace(q)
-	return
-}
-
-// parseVotes return the votes of search query
-func (sp *SearchParser) parseVotes(query *string) (votes int) {
-	var (
-		expr = `score:(\d+)`
-		q    = *query
-	)
-	votes = -1
-
-	re := regexp.MustCompile(expr)
-	res := re.FindStringSubmatch(q)
-	if len(res) > 1 {
-		votes = converter.StringToInt(res[1])
-		q = re.ReplaceAllString(q, "")
+	day, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return invalid
 	}
-
-	*query = strings.TrimSpace(q)
-	return
-}
-
-// parseWithin parse quotes within words like: "hello world"
-func (sp *SearchParser) parseWithin(query *string) (words []string) {
-	var (
-		q    = *query
-		expr = `(?U)(".+")`
-	)
-	re := regexp.MustCompile(expr)
-	matches := re.FindAllStringSubmatch(q, -1)
-	words = []string{}
-	for _, match := range matches {
-		if len(match[1]) == 0 {
-			continue
-		}
-		words = append(words, match[1])
+	nextDay := day.AddDate(0, 0, 1)
+	after, before := time.Time{}, time.Time{}
+	switch op {
+	case ">":
+		after = nextDay
+	case ">=":
+		after = day
+	case "<":
+		before = day
+	case "<=":
+		before = nextDay
+	default:
+		after, before = day, nextDay
 	}
-	q = re.ReplaceAllString(q, "")
-	*query = strings.TrimSpace(q)
-	return
-}
-
-// parseNotAccepted return the question has not accepted the answer
-func (sp *SearchParser) parseNotAccepted(query *string) (notAccepted bool) {
-	var (
-		q    = *query
-		expr = `hasaccepted:no`
-	)
-
-	if strings.Contains(q, expr) {
-		q = strings.ReplaceAll(q, expr, "")
-		notAccepted = true
+	if !after.IsZero() && after.After(cond.CreatedAfter) {
+		cond.CreatedAfter = after
 	}
-
-	*query = strings.TrimSpace(q)
-	return
-}
-
-// parseIsQuestion check the result if only limit question or not
-func (sp *SearchParser) parseIsQuestion(query *string) (isQuestion bool) {
-	var (
-		q    = *query
-		expr = `is:question`
-	)
-
-	if strings.Contains(q, expr) {
-		q = strings.ReplaceAll(q, expr, "")
-		isQuestion = true
+	if !before.IsZero() && (cond.CreatedBefore.IsZero() || before.Before(cond.CreatedBefore)) {
+		cond.CreatedBefore = before
 	}
-
-	*query = strings.TrimSpace(q)
-	return
+	return nil
 }
 
-// parseViews check search has views or not
-func (sp *SearchParser) parseViews(query *string) (views int) {
-	var (
-		q    = *query
-		expr = `views:(\d+)`
-	)
-	views = -1
-
-	re := regexp.MustCompile(expr)
-	res := re.FindStringSubmatch(q)
-	if len(res) > 1 {
-		views = converter.StringToInt(res[1])
-		q = re.ReplaceAllString(q, "")
+func setTargetType(cond *schema.SearchCondition, targetType string, node *Node) error {
+	if len(cond.TargetType) > 0 && cond.TargetType != targetType {
+		return &QueryError{Reason: reason.SearchQueryConflictingFilter, Position: node.Pos, Token: node.Text,
+			Field: node.Field}
 	}
-	*query = strings.TrimSpace(q)
-	return
+	cond.TargetType = targetType
+	return nil
 }
 
-// parseAnswers check whether specified answer count for question
-func (sp *SearchParser) parseAnswers(query *string) (answers int) {
-	var (
-		q    = *query
-		expr = `answers:(\d+)`
-	)
-	answers = -1
-
-	re := regexp.MustCompile(expr)
-	res := re.FindStringSubmatch(q)
-	if len(res) > 1 {
-		answers = converter.StringToInt(res[1])
-		q = re.ReplaceAllString(q, "")
+// findFilter returns the first filter or tag in the tree
+func findFilter(node *Node) *Node {
+	if node.Kind == NodeFilter || node.Kind == NodeTag {
+		return node
 	}
-
-	*query = strings.TrimSpace(q)
-	return
+	for _, child := range node.Children {
+		if filter := findFilter(child); filter != nil {
+			return filter
+		}
+	}
+	return nil
 }
 
-// parseAccepted check the search is limit accepted answer or not
-func (sp *SearchParser) parseAccepted(query *string) (accepted bool) {
-	var (
-		q    = *query
-		expr = `isaccepted:yes`
-	)
-
-	if strings.Contains(q, expr) {
-		accepted = true
-		q = strings.ReplaceAll(q, expr, "")
+func filterName(node *Node) string {
+	if node.Kind == NodeTag {
+		return "tag"
 	}
-
-	*query = strings.TrimSpace(q)
-	return
+	return node.Field
 }
 
-// parseQuestionID check whether specified question's id
-func (sp *SearchParser) parseQuestionID(query *string) (questionID string) {
-	var (
-		q    = *query
-		expr = `inquestion:(\d+)`
-	)
-
-	re := regexp.MustCompile(expr)
-	res := re.FindStringSubmatch(q)
-	if len(res) == 2 {
-		questionID = res[1]
-		q = re.ReplaceAllString(q, "")
+// searchExpr converts the keywords of the tree, it has no filters
+func searchExpr(node *Node) *plugin.SearchExpr {
+	expr := &plugin.SearchExpr{Text: node.Text}
+	switch node.Kind {
+	case NodeTerm:
+		expr.Kind = plugin.SearchExprTerm
+	case NodePhrase:
+		expr.Kind = plugin.SearchExprPhrase
+	case NodeNot:
+		expr.Kind = plugin.SearchExprNot
+	case NodeOr:
+		expr.Kind = plugin.SearchExprOr
+	default:
+		expr.Kind = plugin.SearchExprAnd
 	}
-
-	*query = strings.TrimSpace(q)
-	return
+	for _, child := range node.Children {
+		expr.Children = append(expr.Children, searchExpr(child))
+	}
+	return expr
 }
 
-// parseIsAnswer check the result if only limit answer or not
-func (sp *SearchParser) parseIsAnswer(query *string) (isAnswer bool) {
-	var (
-		q    = *query
-		expr = `is:answer`
-	)
-
-	if strings.Contains(q, expr) {
-		isAnswer = true
-		q = strings.ReplaceAll(q, expr, "")
+// translateQueryError turns the syntax errors into bad requests with a message in the language of the user
+func translateQueryError(ctx context.Context, err error) error {
+	queryErr, ok := err.(*QueryError)
+	if !ok {
+		return err
 	}
-
-	*query = strings.TrimSpace(q)
-	return
+	return errors.BadRequest(queryErr.Reason).
+		WithMsg(translator.TrWithData(handler.GetLangByCtx(ctx), queryErr.Reason, queryErr))
 }
@@ -0,0 +1,480 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package search_reindex
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/internal/base/reason"
+	"github.com/apache/answer/internal/repo/search_sync"
+	"github.com/apache/answer/internal/schema"
+	"github.com/apache/answer/internal/service/config"
+	"github.com/apache/answer/pkg/uid"
+	"github.com/apache/answer/plugin"
+	"github.com/google/uuid"
+	"github.com/segmentfault/pacman/errors"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	defaultBatchSize   = 100
+	defaultConcurrency = 4
+	// lockTTL the time the reindex lock is held for, it is extended after each batch. The lock of a process
+	// stopped without releasing it is taken again once it expires.
+	lockTTL = 10 * time.Minute
+)
+
+// SearchReindexService rebuilds the index of the enabled search plugin from the database and verifies it
+type SearchReindexService interface {
+	// Reindex indexes all the questions and answers and removes the contents no longer in the database
+	// from the index, it returns when it is done or the ctx is canceled, onProgress is called after each batch
+	Reindex(ctx context.Context, req *schema.SearchReindexReq, onProgress func(*schema.SearchReindexProgress)) error
+	// StartReindex starts Reindex in the background
+	StartReindex(ctx context.Context, req *schema.SearchReindexReq) error
+	GetReindexProgress(ctx context.Context) (*schema.SearchReindexProgress, error)
+	Verify(ctx context.Context) (*schema.SearchVerifyResp, error)
+}
+
+type searchReindexService struct {
+	searchReindexRepo search_sync.SearchReindexRepo
+	configService     *config.ConfigService
+
+	// owner identifies this process in the reindex lock kept in the database, which is shared with
+	// the other servers and the command line
+	owner    string
+	lock     sync.Mutex
+	progress *schema.SearchReindexProgress
+}
+
+// NewSearchReindexService new SearchReindexService
+func NewSearchReindexService(
+	searchReindexRepo search_sync.SearchReindexRepo,
+	configService *config.ConfigService,
+) SearchReindexService {
+	return &searchReindexService{
+		searchReindexRepo: searchReindexRepo,
+		configService:     configService,
+		owner:             uuid.NewString(),
+	}
+}
+
+// Reindex indexes all the questions and answers, it resumes from the checkpoint of the last reindex
+// which was interrupted unless req.Restart
+func (s *searchReindexService) Reindex(ctx context.Context, req *schema.SearchReindexReq,
+	onProgress func(*schema.SearchReindexProgress)) error {
+	search, err := s.begin(ctx, req)
+	if err != nil {
+		return err
+	}
+	return s.run(ctx, search, req, onProgress)
+}
+
+// StartReindex starts the reindex in the background, its progress is got by GetReindexProgress
+func (s *searchReindexService) StartReindex(ctx context.Context, req *schema.SearchReindexReq) error {
+	search, err := s.begin(ctx, req)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.run(context.Background(), search, req, nil); err != nil {
+			log.Errorf("search reindex failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// GetReindexProgress get the progress of the running reindex, or the checkpoint of the last one
+// when there is none running here. The checkpoint is running when another process holds the lock.
+func (s *searchReindexService) GetReindexProgress(ctx context.Context) (*schema.SearchReindexProgress, error) {
+	s.lock.Lock()
+	if s.progress != nil && s.progress.Running {
+		progress := copyProgress(s.progress)
+		s.lock.Unlock()
+		return progress, nil
+	}
+	s.lock.Unlock()
+
+	progress, err := s.getCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		progress = &schema.SearchReindexProgress{}
+	}
+	progress.Running, err = s.searchReindexRepo.IsReindexLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// Verify compares the number of questions and answers not deleted in the database with those in the index
+func (s *searchReindexService) Verify(ctx context.Context) (resp *schema.SearchVerifyResp, err error) {
+	search := plugin.GetSearch()
+	if search == nil {
+		return nil, errors.BadRequest(reason.SearchReindexNoPlugin)
+	}
+	resp = &schema.SearchVerifyResp{
+		Plugin:    search.Info().SlugName,
+		Questions: &schema.SearchVerifyCount{},
+		Answers:   &schema.SearchVerifyCount{},
+	}
+	resp.Questions.DB, resp.Answers.DB, err = s.searchReindexRepo.CountContents(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	resp.Questions.Index, resp.Answers.Index, err = countIndex(ctx, search)
+	if err != nil {
+		return nil, errors.InternalServer(reason.UnknownError).WithError(err).WithStack()
+	}
+	resp.Match = resp.Questions.DB == resp.Questions.Index && resp.Answers.DB == resp.Answers.Index
+	return resp, nil
+}
+
+// begin marks the reindex as running, takes the lock in the database and sets up its progress from the checkpoint
+func (s *searchReindexService) begin(ctx context.Context, req *schema.SearchReindexReq) (
+	search plugin.Search, err error) {
+	search = plugin.GetSearch()
+	if search == nil {
+		return nil, errors.BadRequest(reason.SearchReindexNoPlugin)
+	}
+
+	s.lock.Lock()
+	if s.progress != nil && s.progress.Running {
+		s.lock.Unlock()
+		return nil, errors.BadRequest(reason.SearchReindexRunning)
+	}
+	s.progress = &schema.SearchReindexProgress{Running: true}
+	s.lock.Unlock()
+
+	acquired, err := s.searchReindexRepo.AcquireReindexLock(ctx, s.owner, time.Now().Add(lockTTL))
+	if err == nil && !acquired {
+		err = errors.BadRequest(reason.SearchReindexRunning)
+	}
+	var progress *schema.SearchReindexProgress
+	if err == nil {
+		progress, err = s.initProgress(ctx, search, req)
+		if err != nil {
+			s.releaseLock()
+		}
+	}
+	if err != nil {
+		s.lock.Lock()
+		s.progress = nil
+		s.lock.Unlock()
+		return nil, err
+	}
+	s.lock.Lock()
+	s.progress = progress
+	s.lock.Unlock()
+	return search, nil
+}
+
+func (s *searchReindexService) initProgress(ctx context.Context, search plugin.Search, req *schema.SearchReindexReq) (
+	progress *schema.SearchReindexProgress, err error) {
+	if !req.Restart {
+		progress, err = s.getCheckpoint(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the checkpoint is only resumed when the same plugin was interrupted
+	if progress == nil || progress.FinishedAt > 0 || progress.Plugin != search.Info().SlugName {
+		progress = &schema.SearchReindexProgress{
+			Plugin:     search.Info().SlugName,
+			ObjectType: constant.QuestionObjectType,
+			LastID:     "0",
+			Questions:  &schema.SearchReindexCount{},
+			Answers:    &schema.SearchReindexCount{},
+			StartedAt:  time.Now().Unix(),
+		}
+	}
+	progress.Running = true
+	progress.Error = ""
+	progress.Questions.Total, progress.Answers.Total, err = s.searchReindexRepo.CountContents(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (s *searchReindexService) run(ctx context.Context, search plugin.Search, req *schema.SearchReindexReq,
+	onProgress func(*schema.SearchReindexProgress)) (err error) {
+	defer func() {
+		s.lock.Lock()
+		s.progress.Running = false
+		if err == nil {
+			s.progress.FinishedAt = time.Now().Unix()
+		} else {
+			s.progress.Error = err.Error()
+		}
+		progress := copyProgress(s.progress)
+		s.lock.Unlock()
+		// the checkpoint is saved even if the ctx is canceled, to resume from it
+		if saveErr := s.saveCheckpoint(context.Background(), progress); saveErr != nil {
+			log.Error(saveErr)
+		}
+		s.releaseLock()
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}()
+
+	batchSize, concurrency := defaultBatchSize, defaultConcurrency
+	if req.BatchSize > 0 {
+		batchSize = req.BatchSize
+	}
+	if req.Concurrency > 0 {
+		concurrency = req.Concurrency
+	}
+
+	for _, objectType := range []string{constant.QuestionObjectType, constant.AnswerObjectType} {
+		s.lock.Lock()
+		if objectType == constant.QuestionObjectType && s.progress.ObjectType == constant.AnswerObjectType {
+			s.lock.Unlock()
+			continue
+		}
+		if s.progress.ObjectType != objectType {
+			s.progress.ObjectType = objectType
+			s.progress.LastID = "0"
+		}
+		count, lastID := s.progress.Questions, s.progress.LastID
+		if objectType == constant.AnswerObjectType {
+			count = s.progress.Answers
+		}
+		s.lock.Unlock()
+
+		for {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			var contents []*plugin.SearchContent
+			if objectType == constant.QuestionObjectType {
+				contents, lastID, err = s.searchReindexRepo.GetQuestionsAfter(ctx, lastID, batchSize)
+			} else {
+				contents, lastID, err = s.searchReindexRepo.GetAnswersAfter(ctx, lastID, batchSize)
+			}
+			if err != nil {
+				return err
+			}
+			if len(lastID) == 0 {
+				break
+			}
+
+			indexed, failed := indexContents(ctx, search, contents, concurrency)
+			// the contents failing since the ctx is canceled are indexed again when it is resumed
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			s.lock.Lock()
+			count.Indexed += indexed
+			count.Failed += failed
+			s.progress.LastID = lastID
+			progress := copyProgress(s.progress)
+			s.lock.Unlock()
+
+			if err = s.saveCheckpoint(ctx, progress); err != nil {
+				return err
+			}
+			if err = s.extendLock(ctx); err != nil {
+				return err
+			}
+			if onProgress != nil {
+				onProgress(progress)
+			}
+		}
+	}
+	return s.removeStale(ctx, search, batchSize)
+}
+
+// removeStale deletes the questions and answers of the index which are no longer in the database.
+// The index is paged through with unconditional searches, and the contents are deleted once it has been read.
+func (s *searchReindexService) removeStale(ctx context.Context, search plugin.Search, pageSize int) error {
+	for _, objectType := range []string{constant.QuestionObjectType, constant.AnswerObjectType} {
+		indexed := make([]string, 0)
+		cond := &plugin.SearchBasicCond{
+			PageSize:   pageSize,
+			Order:      plugin.SearchNewestOrder,
+			VoteAmount: -1,
+			ViewAmount: -1,
+		}
+		for cond.Page = 1; ; cond.Page++ {
+			var res []plugin.SearchResult
+			var total int64
+			var err error
+			if objectType == constant.QuestionObjectType {
+				cond.AnswerAmount = -1
+				res, total, err = search.SearchQuestions(ctx, cond)
+			} else {
+				res, total, err = search.SearchAnswers(ctx, cond)
+			}
+			if err != nil {
+				return err
+			}
+			for _, result := range res {
+				indexed = append(indexed, uid.DeShortID(result.ID))
+			}
+			if len(res) == 0 || int64(cond.Page*pageSize) >= total {
+				break
+			}
+		}
+
+		var removed int64
+		for start := 0; start < len(indexed); start += pageSize {
+			ids := indexed[start:min(start+pageSize, len(indexed))]
+			existing, err := s.searchReindexRepo.GetExistingIDs(ctx, objectType, ids)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if existing[id] {
+					continue
+				}
+				if err := search.DeleteContent(ctx, id); err != nil {
+					log.Errorf("remove %s %s from the index failed: %v", objectType, id, err)
+					continue
+				}
+				removed++
+			}
+		}
+		s.lock.Lock()
+		if objectType == constant.QuestionObjectType {
+			s.progress.Questions.Removed = removed
+		} else {
+			s.progress.Answers.Removed = removed
+		}
+		s.lock.Unlock()
+	}
+	return nil
+}
+
+// extendLock extends the reindex lock, it fails when the lock has expired and another process has taken it
+func (s *searchReindexService) extendLock(ctx context.Context) error {
+	acquired, err := s.searchReindexRepo.AcquireReindexLock(ctx, s.owner, time.Now().Add(lockTTL))
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return errors.BadRequest(reason.SearchReindexRunning)
+	}
+	return nil
+}
+
+func (s *searchReindexService) releaseLock() {
+	// the lock is released even if the ctx is canceled, so the next reindex does not wait for it to expire
+	if err := s.searchReindexRepo.ReleaseReindexLock(context.Background(), s.owner); err != nil {
+		log.Errorf("release search reindex lock failed: %v", err)
+	}
+}
+
+// indexContents sends the contents to the search plugin, concurrency of them at the same time
+func indexContents(ctx context.Context, search plugin.Search, contents []*plugin.SearchContent, concurrency int) (
+	indexed, failed int64) {
+	var indexedCount, failedCount atomic.Int64
+	limit := make(chan struct{}, concurrency)
+	wg := &sync.WaitGroup{}
+	for _, content := range contents {
+		limit <- struct{}{}
+		wg.Add(1)
+		go func(content *plugin.SearchContent) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			if err := search.UpdateContent(ctx, content); err != nil {
+				log.Errorf("index %s %s failed: %v", content.Type, content.ObjectID, err)
+				failedCount.Add(1)
+				return
+			}
+			indexedCount.Add(1)
+		}(content)
+	}
+	wg.Wait()
+	return indexedCount.Load(), failedCount.Load()
+}
+
+// countIndex counts the contents in the index, with the totals of unconditional searches
+// when the plugin is not a SearchCounter
+func countIndex(ctx context.Context, search plugin.Search) (questions, answers int64, err error) {
+	if counter, ok := search.(plugin.SearchCounter); ok {
+		return counter.CountContents(ctx)
+	}
+	cond := &plugin.SearchBasicCond{
+		Page:       1,
+		PageSize:   1,
+		Order:      plugin.SearchNewestOrder,
+		VoteAmount: -1,
+		ViewAmount: -1,
+	}
+	_, answers, err = search.SearchAnswers(ctx, cond)
+	if err != nil {
+		return 0, 0, err
+	}
+	cond.AnswerAmount = -1
+	_, questions, err = search.SearchQuestions(ctx, cond)
+	if err != nil {
+		return 0, 0, err
+	}
+	return questions, answers, nil
+}
+
+func (s *searchReindexService) getCheckpoint(ctx context.Context) (*schema.SearchReindexProgress, error) {
+	value, err := s.configService.GetStringValueFromDB(ctx, constant.SearchReindexCheckpointKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) == 0 {
+		return nil, nil
+	}
+	progress := &schema.SearchReindexProgress{}
+	if err = json.Unmarshal([]byte(value), progress); err != nil {
+		log.Errorf("parse search reindex checkpoint failed: %v", err)
+		return nil, nil
+	}
+	if progress.Questions == nil || progress.Answers == nil {
+		return nil, nil
+	}
+	return progress, nil
+}
+
+func (s *searchReindexService) saveCheckpoint(ctx context.Context, progress *schema.SearchReindexProgress) error {
+	checkpoint := copyProgress(progress)
+	checkpoint.Running = false
+	value, _ := json.Marshal(checkpoint)
+	return s.configService.UpdateConfig(ctx, constant.SearchReindexCheckpointKey, string(value))
+}
+
+func copyProgress(progress *schema.SearchReindexProgress) *schema.SearchReindexProgress {
+	c := *progress
+	if progress.Questions != nil {
+		questions := *progress.Questions
+		c.Questions = &questions
+	}
+	if progress.Answers != nil {
+		answers := *progress.Answers
+		c.Answers = &answers
+	}
+	return &c
+}
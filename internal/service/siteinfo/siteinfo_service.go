@@ -360,7 +360,7 @@ func (s *SiteInfoService) GetSiteAI(ctx context.Context) (resp *schema.SiteAIRes
 			})
 		}
 	}
-	resp.SiteAIProviders = providers
+	resp.SiteAIProviders = append(providers, customAIProviders(resp.SiteAIProviders, aiProvider)...)
 	s.maskAIKeys(resp)
 	return resp, nil
 }
@@ -401,7 +401,7 @@ func (s *SiteInfoService) SaveSiteAI(ctx context.Context, req *schema.SiteAIReq)
 			})
 		}
 	}
-	req.SiteAIProviders = providers
+	req.SiteAIProviders = append(providers, customAIProviders(req.SiteAIProviders, aiProvider)...)
 
 	content, _ := json.Marshal(req)
 	siteInfo := &entity.SiteInfo{
@@ -412,6 +412,21 @@ func (s *SiteInfoService) SaveSiteAI(ctx context.Context, req *schema.SiteAIReq)
 	return s.siteInfoRepo.SaveByType(ctx, constant.SiteTypeAI, siteInfo)
 }
 
+// customAIProviders gets the providers added by the admin besides the built-in ones, such as an Azure deployment
+func customAIProviders(providers []*schema.SiteAIProvider, builtIn []*schema.GetAIProviderResp) []*schema.SiteAIProvider {
+	builtInNames := make(map[string]bool, len(builtIn))
+	for _, p := range builtIn {
+		builtInNames[p.Name] = true
+	}
+	custom := make([]*schema.SiteAIProvider, 0)
+	for _, provider := range providers {
+		if len(provider.Provider) > 0 && len(provider.APIHost) > 0 && !builtInNames[provider.Provider] {
+			custom = append(custom, provider)
+		}
+	}
+	return custom
+}
+
 func (s *SiteInfoService) maskAIKeys(resp *schema.SiteAIResp) {
 	for _, provider := range resp.SiteAIProviders {
 		if provider.APIKey == "" {
@@ -0,0 +1,193 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package htmltext
+
+import (
+	"html"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/apache/answer/pkg/converter"
+)
+
+const (
+	HighlightOpenTag  = "<mark>"
+	HighlightCloseTag = "</mark>"
+)
+
+// runeRange the runes from begin to end, end excluded
+type runeRange struct {
+	begin, end int
+}
+
+// HighlightText escapes the plain text and wraps the words matched in it in <mark>, ignoring the case
+func HighlightText(text string, words []string) string {
+	runeText := []rune(text)
+	return highlightRange(runeText, matchWords(runeText, words), 0, len(runeText))
+}
+
+// FetchHighlightedFragments returns at most limit fragments of the clear text of the HTML around the matched words,
+// each about size runes long, escaped and with the matched words wrapped in <mark>. It returns nil when no word matched.
+func FetchHighlightedFragments(htmlText string, words []string, trimMarker string, size, limit int) []string {
+	runeText := []rune(html.UnescapeString(ClearText(htmlText)))
+	matches := matchWords(runeText, words)
+	if len(matches) == 0 || limit <= 0 {
+		return nil
+	}
+
+	fragments := make([]string, 0, limit)
+	covered := 0
+	for _, match := range matches {
+		if len(fragments) == limit {
+			break
+		}
+		// the match is shown by the previous fragment already
+		if match.begin < covered {
+			continue
+		}
+		begin, end := fragmentRange(runeText, match, covered, size)
+		fragment := highlightRange(runeText, matches, begin, end)
+		if begin > 0 {
+			fragment = trimMarker + fragment
+		}
+		if end < len(runeText) {
+			fragment += trimMarker
+		}
+		fragments = append(fragments, fragment)
+		covered = end
+	}
+	return fragments
+}
+
+// SanitizeHighlight escapes the plain text fragment but for its <mark> tags, which are balanced,
+// so the fragments highlighted by a search engine are safe to render
+func SanitizeHighlight(fragment string) string {
+	var b strings.Builder
+	marked := false
+	for {
+		openIndex := strings.Index(fragment, HighlightOpenTag)
+		closeIndex := strings.Index(fragment, HighlightCloseTag)
+		index, tag := openIndex, HighlightOpenTag
+		if closeIndex >= 0 && (openIndex < 0 || closeIndex < openIndex) {
+			index, tag = closeIndex, HighlightCloseTag
+		}
+		if index < 0 {
+			b.WriteString(html.EscapeString(fragment))
+			break
+		}
+		b.WriteString(html.EscapeString(fragment[:index]))
+		if opening := tag == HighlightOpenTag; opening != marked {
+			b.WriteString(tag)
+			marked = opening
+		}
+		fragment = fragment[index+len(tag):]
+	}
+	if marked {
+		b.WriteString(HighlightCloseTag)
+	}
+	return b.String()
+}
+
+// matchWords finds the words in the text ignoring the case, the overlapping matches are merged
+func matchWords(runeText []rune, words []string) []runeRange {
+	lowerText := toLowerRunes(runeText)
+	matches := make([]runeRange, 0)
+	for _, word := range converter.UniqueArray(words) {
+		lowerWord := toLowerRunes([]rune(strings.TrimSpace(word)))
+		if len(lowerWord) == 0 {
+			continue
+		}
+		for i := 0; i+len(lowerWord) <= len(lowerText); i++ {
+			if slices.Equal(lowerText[i:i+len(lowerWord)], lowerWord) {
+				matches = append(matches, runeRange{begin: i, end: i + len(lowerWord)})
+				i += len(lowerWord) - 1
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].begin < matches[j].begin
+	})
+
+	merged := make([]runeRange, 0, len(matches))
+	for _, match := range matches {
+		if last := len(merged) - 1; last >= 0 && match.begin <= merged[last].end {
+			merged[last].end = max(merged[last].end, match.end)
+			continue
+		}
+		merged = append(merged, match)
+	}
+	return merged
+}
+
+// fragmentRange centers a range of size runes on the match, not before covered. The range starts and ends
+// at a space when there is one between its bounds and the match, so the words are not cut.
+func fragmentRange(runeText []rune, match runeRange, covered, size int) (begin, end int) {
+	begin = max(covered, match.begin-max(0, size-(match.end-match.begin))/2)
+	end = min(len(runeText), max(begin+size, match.end))
+	// the room left at the end of the text goes before the match
+	begin = max(covered, min(begin, end-size))
+
+	if begin > 0 && !unicode.IsSpace(runeText[begin-1]) {
+		for i := begin; i < match.begin; i++ {
+			if unicode.IsSpace(runeText[i]) {
+				begin = i + 1
+				break
+			}
+		}
+	}
+	if end < len(runeText) && !unicode.IsSpace(runeText[end]) {
+		for i := end - 1; i >= match.end; i-- {
+			if unicode.IsSpace(runeText[i]) {
+				end = i
+				break
+			}
+		}
+	}
+	return begin, end
+}
+
+// highlightRange escapes the runes from begin to end and wraps the matches in <mark>
+func highlightRange(runeText []rune, matches []runeRange, begin, end int) string {
+	var b strings.Builder
+	pos := begin
+	for _, match := range matches {
+		if match.end <= begin || match.begin >= end {
+			continue
+		}
+		markBegin, markEnd := max(match.begin, begin), min(match.end, end)
+		b.WriteString(html.EscapeString(string(runeText[pos:markBegin])))
+		b.WriteString(HighlightOpenTag)
+		b.WriteString(html.EscapeString(string(runeText[markBegin:markEnd])))
+		b.WriteString(HighlightCloseTag)
+		pos = markEnd
+	}
+	b.WriteString(html.EscapeString(string(runeText[pos:end])))
+	return b.String()
+}
+
+func toLowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
@@ -0,0 +1,68 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package htmltext
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlightText(t *testing.T) {
+	assert.Equal(t, "How to <mark>Install</mark> <mark>go</mark> &lt;1.20&gt;?",
+		HighlightText("How to Install go <1.20>?", []string{"install", "GO"}))
+	// overlapping words are marked once
+	assert.Equal(t, "<mark>database</mark> index",
+		HighlightText("database index", []string{"data", "base"}))
+	assert.Equal(t, "没有<mark>匹配</mark>", HighlightText("没有匹配", []string{"匹配"}))
+	assert.Equal(t, "no match", HighlightText("no match", []string{"other", ""}))
+}
+
+func TestFetchHighlightedFragments(t *testing.T) {
+	assert.Nil(t, FetchHighlightedFragments("<p>nothing here</p>", []string{"word"}, "...", 20, 3))
+
+	html := "<p>" + strings.Repeat("lorem ipsum ", 10) + "the first keyword is here " +
+		strings.Repeat("dolor sit amet ", 10) + "another keyword there &amp; " + strings.Repeat("consectetur ", 10) + "</p>"
+	fragments := FetchHighlightedFragments(html, []string{"keyword"}, "...", 30, 3)
+	assert.Equal(t, []string{
+		"...the first <mark>keyword</mark> is here...",
+		"...another <mark>keyword</mark> there &amp;...",
+	}, fragments)
+
+	// at most limit fragments
+	assert.Len(t, FetchHighlightedFragments(html, []string{"keyword"}, "...", 30, 1), 1)
+
+	// the matches close together share a fragment, the fragment keeps to the text
+	fragments = FetchHighlightedFragments("<p>go is a language, go</p>", []string{"go"}, "...", 100, 3)
+	assert.Equal(t, []string{"<mark>go</mark> is a language, <mark>go</mark>"}, fragments)
+
+	// the text is escaped
+	fragments = FetchHighlightedFragments("<p>use &lt;script&gt; tags</p>", []string{"script"}, "...", 100, 3)
+	assert.Equal(t, []string{"use &lt;<mark>script</mark>&gt; tags"}, fragments)
+}
+
+func TestSanitizeHighlight(t *testing.T) {
+	assert.Equal(t, "a <mark>b</mark> &lt;script&gt;", SanitizeHighlight("a <mark>b</mark> <script>"))
+	// unbalanced tags are dropped or closed
+	assert.Equal(t, "a b <mark>c</mark>", SanitizeHighlight("a</mark> b <mark>c"))
+	assert.Equal(t, "<mark>a b</mark>", SanitizeHighlight("<mark>a <mark>b</mark>"))
+	assert.Equal(t, "&lt;mark onclick=&#34;x&#34;&gt;a", SanitizeHighlight(`<mark onclick="x">a`))
+}
@@ -0,0 +1,119 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package plugin
+
+import (
+	"context"
+
+	"github.com/apache/answer/internal/base/constant"
+)
+
+// EventPayloadVersion the version of the EventPayload. It is increased when a field is removed or changes
+// its meaning, adding a field keeps the version.
+const EventPayloadVersion = 1
+
+// EventType the type of a domain event, object.action. The values are the ones of the events of Answer,
+// they are defined in the constant package and exported here for the plugins.
+type EventType string
+
+const (
+	EventUserUpdate = EventType(constant.EventUserUpdate)
+	EventUserShare  = EventType(constant.EventUserShare)
+
+	EventQuestionCreate = EventType(constant.EventQuestionCreate)
+	EventQuestionUpdate = EventType(constant.EventQuestionUpdate)
+	EventQuestionDelete = EventType(constant.EventQuestionDelete)
+	EventQuestionVote   = EventType(constant.EventQuestionVote)
+	EventQuestionAccept = EventType(constant.EventQuestionAccept)
+	EventQuestionFlag   = EventType(constant.EventQuestionFlag)
+	EventQuestionReact  = EventType(constant.EventQuestionReact)
+
+	EventAnswerCreate = EventType(constant.EventAnswerCreate)
+	EventAnswerUpdate = EventType(constant.EventAnswerUpdate)
+	EventAnswerDelete = EventType(constant.EventAnswerDelete)
+	EventAnswerVote   = EventType(constant.EventAnswerVote)
+	EventAnswerFlag   = EventType(constant.EventAnswerFlag)
+	EventAnswerReact  = EventType(constant.EventAnswerReact)
+
+	EventCommentCreate = EventType(constant.EventCommentCreate)
+	EventCommentUpdate = EventType(constant.EventCommentUpdate)
+	EventCommentDelete = EventType(constant.EventCommentDelete)
+	EventCommentVote   = EventType(constant.EventCommentVote)
+	EventCommentFlag   = EventType(constant.EventCommentFlag)
+
+	EventWikiUpdate = EventType(constant.EventWikiUpdate)
+
+	EventTopicCreate = EventType(constant.EventTopicCreate)
+	EventTopicVote   = EventType(constant.EventTopicVote)
+	EventTopicSolve  = EventType(constant.EventTopicSolve)
+
+	EventPostCreate = EventType(constant.EventPostCreate)
+	EventPostVote   = EventType(constant.EventPostVote)
+
+	EventMergeJobCreate = EventType(constant.EventMergeJobCreate)
+	EventMergeJobApply  = EventType(constant.EventMergeJobApply)
+)
+
+// EventListener reacts to the domain events, like a question being created or a merge job being applied
+type EventListener interface {
+	Base
+
+	// Events returns the types of the events the plugin handles, e.g. question.create
+	Events() []EventType
+
+	// HandleEvent handles an event, payload is the JSON of the EventPayload.
+	// The events are delivered asynchronously, and delivered again later when it returns an error,
+	// so the plugin should be idempotent on the id of the event. The deliveries waiting are kept in memory only,
+	// an event not handled yet when Answer stops is lost. ctx is done after the timeout of the EventListener calls.
+	HandleEvent(ctx context.Context, payload []byte) error
+}
+
+// EventPayload the payload of a domain event
+type EventPayload struct {
+	// The version of the payload, the plugin should check it before reading the fields
+	Version int `json:"version"`
+	// The unique id of the event, it stays the same when the event is delivered again
+	ID string `json:"id"`
+	// The type of the event, e.g. question.create
+	Type EventType `json:"type"`
+	// The unix time the event happened
+	Time int64 `json:"time"`
+	// The delivery attempt of the event, starting at 1
+	Attempt int `json:"attempt"`
+	// The user triggering the event
+	UserID string `json:"user_id"`
+	// The object of the event, e.g. the answer accepted for question.accept or the merge job for merge_job.apply
+	ObjectID string `json:"object_id"`
+	// The question, answer and comment the event is about and their authors, if any
+	QuestionID     string `json:"question_id,omitempty"`
+	QuestionUserID string `json:"question_user_id,omitempty"`
+	AnswerID       string `json:"answer_id,omitempty"`
+	AnswerUserID   string `json:"answer_user_id,omitempty"`
+	CommentID      string `json:"comment_id,omitempty"`
+	CommentUserID  string `json:"comment_user_id,omitempty"`
+	// The extra information of the event, e.g. topic_id for the forum events
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+var (
+	// CallEventListener is a function that calls all registered event listeners
+	CallEventListener,
+	registerEventListener = MakePlugin[EventListener](false)
+)
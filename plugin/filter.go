@@ -29,3 +29,14 @@ var (
 	CallFilter,
 	registerFilter = MakePlugin[Filter](false)
 )
+
+// FilterTextWithTimeout filters the text with the plugin, returning ErrTimeout when it does not return in time
+func FilterTextWithTimeout(filter Filter, text string) error {
+	filterErr, err := awaitCall("Filter", filter, func() error {
+		return filter.FilterText(text)
+	})
+	if err != nil {
+		return err
+	}
+	return filterErr
+}
@@ -0,0 +1,251 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentfault/pacman/log"
+)
+
+var (
+	// ErrPanic the plugin panicked in the call
+	ErrPanic = errors.New("plugin panicked")
+	// ErrTimeout the plugin did not return in the timeout of its type
+	ErrTimeout = errors.New("plugin timed out")
+)
+
+// IsCallFailure returns whether err is the plugin panicking or timing out, rather than an error it returned
+func IsCallFailure(err error) bool {
+	return errors.Is(err, ErrPanic) || errors.Is(err, ErrTimeout)
+}
+
+// CircuitBreakerThreshold the plugin is disabled when this many calls in a row panic or time out
+const CircuitBreakerThreshold = 5
+
+var (
+	callTimeoutLock sync.RWMutex
+	// callTimeouts the time the calls of a plugin type have, keyed by the name of the type.
+	// The other types have no timeout, since they work on the request like Storage and Connector,
+	// or run as long as they need like Agent and Importer.
+	callTimeouts = map[string]time.Duration{
+		"Search":        10 * time.Second,
+		"Reviewer":      10 * time.Second,
+		"Filter":        5 * time.Second,
+		"Parser":        5 * time.Second,
+		"Notification":  time.Minute,
+		"EventListener": 30 * time.Second,
+	}
+)
+
+// failClosedTypes the plugin types whose panics and timeouts are returned to the caller rather than skipped,
+// since skipping a reviewer approves the content it did not review
+var failClosedTypes = map[string]bool{
+	"Reviewer": true,
+}
+
+// SetCallTimeout sets the time the calls of the plugin type have, like "Reviewer", zero means no timeout
+func SetCallTimeout(pluginType string, timeout time.Duration) {
+	callTimeoutLock.Lock()
+	defer callTimeoutLock.Unlock()
+	callTimeouts[pluginType] = timeout
+}
+
+func getCallTimeout(pluginType string) time.Duration {
+	callTimeoutLock.RLock()
+	defer callTimeoutLock.RUnlock()
+	return callTimeouts[pluginType]
+}
+
+// WithCallTimeout returns ctx with the deadline of the calls of the plugin type, like "Search",
+// for the plugins taking a context to give up in time. It does not set a deadline when the type has no timeout.
+func WithCallTimeout(ctx context.Context, pluginType string) (context.Context, context.CancelFunc) {
+	if timeout := getCallTimeout(pluginType); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Health the metrics of the calls of a plugin since the start
+type Health struct {
+	SlugName string `json:"slug_name"`
+	Calls    int64  `json:"calls"`
+	// Errors the calls returning an error, like a text the filter rejects
+	Errors   int64 `json:"errors"`
+	Panics   int64 `json:"panics"`
+	Timeouts int64 `json:"timeouts"`
+	// ConsecutiveFailures the calls in a row panicking or timing out
+	ConsecutiveFailures int64  `json:"consecutive_failures"`
+	LastFailure         string `json:"last_failure"`
+	LastFailureAt       int64  `json:"last_failure_at"`
+	// Tripped the plugin is disabled by the circuit breaker, it is reset when the plugin is enabled again
+	Tripped   bool  `json:"tripped"`
+	TrippedAt int64 `json:"tripped_at"`
+}
+
+type healthManager struct {
+	lock    sync.Mutex
+	health  map[string]*Health
+	onTrips []func(slugName string, err error)
+}
+
+var plugins = &healthManager{health: make(map[string]*Health)}
+
+// RegisterCircuitBreakerFunc registers the function called when a plugin is disabled by the circuit breaker,
+// err is the failure tripping it
+func RegisterCircuitBreakerFunc(fn func(slugName string, err error)) {
+	plugins.lock.Lock()
+	defer plugins.lock.Unlock()
+	plugins.onTrips = append(plugins.onTrips, fn)
+}
+
+// GetHealth returns the health of each plugin called, ordered by the slug name
+func GetHealth() []Health {
+	plugins.lock.Lock()
+	defer plugins.lock.Unlock()
+	list := make([]Health, 0, len(plugins.health))
+	for _, health := range plugins.health {
+		list = append(list, *health)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SlugName < list[j].SlugName
+	})
+	return list
+}
+
+// record counts the result of a call, and trips the circuit breaker of the plugin when it keeps failing
+func (m *healthManager) record(slugName string, canDisable bool, err error) {
+	m.lock.Lock()
+	health, ok := m.health[slugName]
+	if !ok {
+		health = &Health{SlugName: slugName}
+		m.health[slugName] = health
+	}
+	health.Calls++
+	switch {
+	case err == nil:
+		health.ConsecutiveFailures = 0
+	case IsCallFailure(err):
+		if errors.Is(err, ErrPanic) {
+			health.Panics++
+		} else {
+			health.Timeouts++
+		}
+		health.ConsecutiveFailures++
+		health.LastFailure = err.Error()
+		health.LastFailureAt = time.Now().Unix()
+	default:
+		health.Errors++
+		health.ConsecutiveFailures = 0
+	}
+	trip := canDisable && !health.Tripped && health.ConsecutiveFailures >= CircuitBreakerThreshold
+	if trip {
+		health.Tripped = true
+		health.TrippedAt = time.Now().Unix()
+	}
+	onTrips := m.onTrips
+	m.lock.Unlock()
+
+	if !trip {
+		return
+	}
+	log.Errorf("plugin %s failed %d times in a row, it is disabled: %v", slugName, CircuitBreakerThreshold, err)
+	StatusManager.disable(slugName)
+	for _, onTrip := range onTrips {
+		go onTrip(slugName, err)
+	}
+}
+
+// reset resets the circuit breaker of the plugin
+func (m *healthManager) reset(slugName string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if health, ok := m.health[slugName]; ok {
+		health.ConsecutiveFailures = 0
+		health.Tripped = false
+		health.TrippedAt = 0
+	}
+}
+
+// guardCall calls fn with the plugin, recovering from its panic and failing the call returning after the timeout
+// of its type. fn runs in place, as it writes the results of the caller. The plugins taking a context get
+// the timeout from WithCallTimeout, and the methods taking none are called through awaitCall, like
+// ReviewWithTimeout, which gives up on them with ErrTimeout.
+func guardCall[T Base](pluginType string, p T, canDisable bool, fn Caller[T]) (err error) {
+	slugName := p.Info().SlugName
+	start := time.Now()
+	err = recoverCall(slugName, p, fn)
+	if timeout := getCallTimeout(pluginType); timeout > 0 && !IsCallFailure(err) {
+		if elapsed := time.Since(start); elapsed > timeout {
+			err = fmt.Errorf("%w: %s plugin %s returned in %s, over %s", ErrTimeout, pluginType, slugName,
+				elapsed.Round(time.Millisecond), timeout)
+		}
+	}
+	plugins.record(slugName, canDisable, err)
+	return err
+}
+
+func recoverCall[T Base](slugName string, p T, fn Caller[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("plugin %s panicked: %v\n%s", slugName, r, debug.Stack())
+			err = fmt.Errorf("%w: %s: %v", ErrPanic, slugName, r)
+		}
+	}()
+	return fn(p)
+}
+
+// awaitCall runs call, a method of the plugin taking no context, in a goroutine and waits for it until the
+// timeout of the plugin type. The result is only handed over when the call returns in time, a call timing out
+// is left to finish in the background and its result is dropped.
+func awaitCall[R any](pluginType string, p Base, call func() R) (result R, err error) {
+	timeout := getCallTimeout(pluginType)
+	if timeout <= 0 {
+		return call(), nil
+	}
+	slugName := p.Info().SlugName
+	done := make(chan R, 1)
+	failed := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Errorf("plugin %s panicked: %v\n%s", slugName, r, debug.Stack())
+				failed <- fmt.Errorf("%w: %s: %v", ErrPanic, slugName, r)
+			}
+		}()
+		done <- call()
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case result = <-done:
+		return result, nil
+	case err = <-failed:
+		return result, err
+	case <-timer.C:
+		return result, fmt.Errorf("%w: %s plugin %s did not return in %s", ErrTimeout, pluginType, slugName, timeout)
+	}
+}
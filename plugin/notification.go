@@ -90,3 +90,18 @@ var (
 	CallNotification,
 	registerNotification = MakePlugin[Notification](false)
 )
+
+// NotifyWithTimeout sends the notification with the plugin, returning ErrTimeout when it does not return in time
+func NotifyWithTimeout(notification Notification, msg NotificationMessage) error {
+	_, err := awaitCall("Notification", notification, func() struct{} {
+		notification.Notify(msg)
+		return struct{}{}
+	})
+	return err
+}
+
+// GetNewQuestionSubscribersWithTimeout returns the subscribers of the plugin, or ErrTimeout when it does not
+// return in time
+func GetNewQuestionSubscribersWithTimeout(notification Notification) ([]string, error) {
+	return awaitCall("Notification", notification, notification.GetNewQuestionSubscribers)
+}
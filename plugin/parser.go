@@ -29,3 +29,19 @@ var (
 	CallParser,
 	registerParser = MakePlugin[Parser](false)
 )
+
+// ParseWithTimeout parses the text with the plugin, returning ErrTimeout when it does not return in time
+func ParseWithTimeout(parser Parser, text string) (string, error) {
+	type parsed struct {
+		text string
+		err  error
+	}
+	result, err := awaitCall("Parser", parser, func() parsed {
+		text, err := parser.Parse(text)
+		return parsed{text: text, err: err}
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.text, result.err
+}
@@ -21,6 +21,7 @@ package plugin
 
 import (
 	"encoding/json"
+	"reflect"
 	"sync"
 
 	"github.com/segmentfault/pacman/cache"
@@ -54,7 +55,20 @@ var StatusManager = statusManager{
 // Register registers a plugin
 func Register(p Base) {
 	registerBase(p)
+	registerTypes(p)
+}
 
+// RegisterWith registers base as a plugin of the types the impls implement, rather than those it implements.
+// It is used by the plugins whose types are only known once they are running, like the ones out of the process.
+func RegisterWith(base Base, impls ...Base) {
+	registerBase(base)
+	for _, impl := range impls {
+		registerTypes(impl)
+	}
+}
+
+// registerTypes registers p as each of the plugin types it implements
+func registerTypes(p Base) {
 	if _, ok := p.(Config); ok {
 		registerConfig(p.(Config))
 	}
@@ -91,6 +105,10 @@ func Register(p Base) {
 		registerAgent(p.(Agent))
 	}
 
+	if _, ok := p.(EventListener); ok {
+		registerEventListener(p.(EventListener))
+	}
+
 	if _, ok := p.(Search); ok {
 		registerSearch(p.(Search))
 	}
@@ -147,6 +165,7 @@ type CallFn[T Base] func(fn Caller[T]) error
 // The caller function is used to call all registered plugins
 func MakePlugin[T Base](super bool) (CallFn[T], RegisterFn[T]) {
 	stack := Stack[T]{}
+	pluginType := reflect.TypeOf((*T)(nil)).Elem().Name()
 
 	call := func(fn Caller[T]) error {
 		for _, p := range stack.plugins {
@@ -155,7 +174,12 @@ func MakePlugin[T Base](super bool) (CallFn[T], RegisterFn[T]) {
 				continue
 			}
 
-			if err := fn(p); err != nil {
+			// The plugin panicking or timing out is skipped, rather than failing the call of the others,
+			// unless its type fails closed
+			if err := guardCall(pluginType, p, !super, fn); err != nil {
+				if IsCallFailure(err) && !failClosedTypes[pluginType] {
+					continue
+				}
 				return err
 			}
 		}
@@ -187,6 +211,7 @@ func (m *statusManager) Enable(name string, enabled bool) {
 		return
 	}
 	m.status[name] = enabled
+	plugins.reset(name)
 
 	for _, slugName := range coordinatedCaptchaPlugins(name) {
 		m.status[slugName] = false
@@ -197,6 +222,13 @@ func (m *statusManager) Enable(name string, enabled bool) {
 	}
 }
 
+// disable disables the plugin, like Enable with false
+func (m *statusManager) disable(name string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.status[name] = false
+}
+
 func (m *statusManager) IsEnabled(name string) bool {
 	if status, ok := m.status[name]; ok {
 		return status
@@ -0,0 +1,199 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package plugin_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/answer/plugin"
+)
+
+// testFailingFilter is a filter panicking or returning late as the mode is set
+type testFailingFilter struct {
+	mode atomic.Value
+}
+
+func (f *testFailingFilter) Info() plugin.Info {
+	return plugin.Info{
+		Name:     plugin.MakeTranslator("test_failing_filter_name"),
+		SlugName: "test_failing_filter",
+		Version:  "1.0.0",
+	}
+}
+
+func (f *testFailingFilter) FilterText(text string) error {
+	switch f.mode.Load() {
+	case "panic":
+		panic("boom")
+	case "hang":
+		time.Sleep(100 * time.Millisecond)
+	case "reject":
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func findHealth(slugName string) plugin.Health {
+	for _, health := range plugin.GetHealth() {
+		if health.SlugName == slugName {
+			return health
+		}
+	}
+	return plugin.Health{}
+}
+
+func TestGuardCall(t *testing.T) {
+	filter := &testFailingFilter{}
+	filter.mode.Store("ok")
+	plugin.Register(filter)
+	plugin.StatusManager.Enable("test_failing_filter", true)
+	plugin.SetCallTimeout("Filter", 50*time.Millisecond)
+	defer plugin.SetCallTimeout("Filter", 5*time.Second)
+
+	tripped := make(chan string, 1)
+	plugin.RegisterCircuitBreakerFunc(func(slugName string, err error) {
+		tripped <- slugName
+	})
+	callFilter := func() error {
+		return plugin.CallFilter(func(fn plugin.Filter) error {
+			return fn.FilterText("text")
+		})
+	}
+
+	// the business error is returned, but does not count as a failure
+	filter.mode.Store("reject")
+	if err := callFilter(); err == nil {
+		t.Fatal("expected the error of the filter")
+	}
+
+	// the panic and the timeout are recovered and skipped
+	filter.mode.Store("panic")
+	for i := 0; i < plugin.CircuitBreakerThreshold-2; i++ {
+		if err := callFilter(); err != nil {
+			t.Fatalf("expected the panic to be skipped, got %v", err)
+		}
+	}
+	filter.mode.Store("hang")
+	if err := callFilter(); err != nil {
+		t.Fatalf("expected the timeout to be skipped, got %v", err)
+	}
+	health := findHealth("test_failing_filter")
+	if health.Errors != 1 || health.Panics != plugin.CircuitBreakerThreshold-2 || health.Timeouts != 1 {
+		t.Fatalf("unexpected health %+v", health)
+	}
+	if health.Tripped || !plugin.StatusManager.IsEnabled("test_failing_filter") {
+		t.Fatal("expected the plugin to be enabled before the threshold")
+	}
+
+	// the last failure trips the circuit breaker
+	filter.mode.Store("panic")
+	_ = callFilter()
+	select {
+	case slugName := <-tripped:
+		if slugName != "test_failing_filter" {
+			t.Fatalf("expected test_failing_filter to be tripped, got %s", slugName)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the circuit breaker func to be called")
+	}
+	if plugin.StatusManager.IsEnabled("test_failing_filter") {
+		t.Fatal("expected the plugin to be disabled")
+	}
+	if calls := findHealth("test_failing_filter").Calls; callFilter() != nil || findHealth("test_failing_filter").Calls != calls {
+		t.Fatal("expected the disabled plugin not to be called")
+	}
+
+	// enabling the plugin again resets the circuit breaker
+	plugin.StatusManager.Enable("test_failing_filter", true)
+	health = findHealth("test_failing_filter")
+	if health.Tripped || health.ConsecutiveFailures != 0 {
+		t.Fatalf("expected the circuit breaker to be reset, got %+v", health)
+	}
+	filter.mode.Store("ok")
+	if err := callFilter(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testFailingReviewer is a reviewer approving everything, unless it panics or hangs
+type testFailingReviewer struct{}
+
+func (r *testFailingReviewer) Info() plugin.Info {
+	return plugin.Info{
+		Name:     plugin.MakeTranslator("test_failing_reviewer_name"),
+		SlugName: "test_failing_reviewer",
+		Version:  "1.0.0",
+	}
+}
+
+func (r *testFailingReviewer) Review(content *plugin.ReviewContent) *plugin.ReviewResult {
+	switch content.Content {
+	case "panic":
+		panic("boom")
+	case "hang":
+		time.Sleep(time.Second)
+	}
+	return &plugin.ReviewResult{Approved: true, ReviewStatus: plugin.ReviewStatusApproved}
+}
+
+func TestGuardCall_FailClosed(t *testing.T) {
+	plugin.Register(&testFailingReviewer{})
+	plugin.StatusManager.Enable("test_failing_reviewer", true)
+	defer plugin.StatusManager.Enable("test_failing_reviewer", false)
+	plugin.SetCallTimeout("Reviewer", 50*time.Millisecond)
+	defer plugin.SetCallTimeout("Reviewer", 10*time.Second)
+
+	review := func(content string) (approved bool, err error) {
+		err = plugin.CallReviewer(func(reviewer plugin.Reviewer) error {
+			result, err := plugin.ReviewWithTimeout(reviewer, &plugin.ReviewContent{Content: content})
+			if err != nil {
+				return err
+			}
+			approved = result.Approved
+			return nil
+		})
+		return approved, err
+	}
+
+	approved, err := review("hello")
+	if err != nil || !approved {
+		t.Fatalf("expected the content to be approved, got %v %v", approved, err)
+	}
+	// the reviewer panicking is returned rather than skipped, so the caller does not approve the content
+	if _, err = review("panic"); !plugin.IsCallFailure(err) {
+		t.Fatalf("expected the panic of the reviewer to be returned, got %v", err)
+	}
+
+	// a hanging reviewer is given up on at the timeout and counted toward the circuit breaker
+	timeouts := findHealth("test_failing_reviewer").Timeouts
+	start := time.Now()
+	if _, err = review("hang"); !errors.Is(err, plugin.ErrTimeout) {
+		t.Fatalf("expected the timeout of the reviewer to be returned, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected the call to return at the timeout, it took %s", elapsed)
+	}
+	if findHealth("test_failing_reviewer").Timeouts != timeouts+1 {
+		t.Fatal("expected the timeout to be counted")
+	}
+}
@@ -0,0 +1,384 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apache/answer/plugin"
+	"github.com/segmentfault/pacman/log"
+)
+
+const (
+	defaultCallTimeout         = 10 * time.Second
+	defaultHealthCheckInterval = 10 * time.Second
+	handshakeTimeout           = 10 * time.Second
+	// healthCheckFailures the plugin is restarted when its health check fails this many times in a row
+	healthCheckFailures = 3
+	minRestartBackoff   = time.Second
+	maxRestartBackoff   = time.Minute
+)
+
+// ErrUnavailable the plugin process is not running, it is being restarted
+var ErrUnavailable = errors.New("plugin is unavailable")
+
+// Config the config of a plugin running out of the process
+type Config struct {
+	// Command the executable of the plugin
+	Command string `json:"command" mapstructure:"command" yaml:"command"`
+	// Args the arguments of the command
+	Args []string `json:"args" mapstructure:"args" yaml:"args,omitempty"`
+	// Env the environment variables added to those of Answer, like KEY=value
+	Env []string `json:"env" mapstructure:"env" yaml:"env,omitempty"`
+	// Transport TransportStdio by default, or TransportUnix
+	Transport string `json:"transport" mapstructure:"transport" yaml:"transport,omitempty"`
+	// CallTimeout the seconds a call to the plugin waits for, 10 by default
+	CallTimeout int `json:"call_timeout" mapstructure:"call_timeout" yaml:"call_timeout,omitempty"`
+	// HealthCheckInterval the seconds between the health checks of the plugin, 10 by default
+	HealthCheckInterval int `json:"health_check_interval" mapstructure:"health_check_interval" yaml:"health_check_interval,omitempty"`
+}
+
+func (c *Config) callTimeout() time.Duration {
+	if c.CallTimeout > 0 {
+		return time.Duration(c.CallTimeout) * time.Second
+	}
+	return defaultCallTimeout
+}
+
+func (c *Config) healthCheckInterval() time.Duration {
+	if c.HealthCheckInterval > 0 {
+		return time.Duration(c.HealthCheckInterval) * time.Second
+	}
+	return defaultHealthCheckInterval
+}
+
+// Client starts the process of a plugin and calls it, the process is restarted when it exits
+// or fails its health checks
+type Client struct {
+	conf *Config
+
+	lock      sync.RWMutex
+	rpcClient *rpc.Client
+	process   *os.Process
+	handshake *HandshakeReply
+
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewClient new client of the plugin, it is started by Start
+func NewClient(conf *Config) *Client {
+	return &Client{
+		conf:    conf,
+		closing: make(chan struct{}),
+	}
+}
+
+// Start starts the plugin process and keeps it running until Close
+func (c *Client) Start() error {
+	exited, err := c.connect()
+	if err != nil {
+		return err
+	}
+	go c.supervise(exited)
+	return nil
+}
+
+// Close stops the plugin process
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closing)
+		c.disconnect()
+	})
+}
+
+// Info returns the information of the plugin from its handshake
+func (c *Client) Info() Info {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.handshake.Info
+}
+
+// Capabilities returns the plugin types the plugin implements from its handshake
+func (c *Client) Capabilities() []Capability {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.handshake.Capabilities
+}
+
+// Events returns the types of the events the plugin handles from its handshake
+func (c *Client) Events() []plugin.EventType {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.handshake.Events
+}
+
+// Call calls the method of the plugin, it gives up when the ctx is done or the call timeout passes
+func (c *Client) Call(ctx context.Context, method string, args, reply any) error {
+	c.lock.RLock()
+	rpcClient := c.rpcClient
+	c.lock.RUnlock()
+	if rpcClient == nil {
+		return ErrUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.conf.callTimeout())
+	defer cancel()
+	call := rpcClient.Go(serviceName+"."+method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if errors.Is(call.Error, rpc.ErrShutdown) {
+			return ErrUnavailable
+		}
+		return call.Error
+	case <-ctx.Done():
+		return fmt.Errorf("call %s of plugin %s failed: %w", method, c.name(), ctx.Err())
+	}
+}
+
+// supervise checks the health of the plugin, and restarts it when it exits until the client is closed
+func (c *Client) supervise(exited <-chan struct{}) {
+	ticker := time.NewTicker(c.conf.healthCheckInterval())
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-c.closing:
+			return
+		case <-ticker.C:
+			if err := c.Call(context.Background(), "Health", &Empty{}, &Empty{}); err != nil {
+				failures++
+				log.Warnf("health check of plugin %s failed %d times: %v", c.name(), failures, err)
+				if failures >= healthCheckFailures {
+					c.kill()
+				}
+			} else {
+				failures = 0
+			}
+			continue
+		case <-exited:
+		}
+
+		c.disconnect()
+		failures = 0
+		log.Errorf("plugin %s exited, restarting it", c.name())
+		for backoff := minRestartBackoff; ; backoff = min(backoff*2, maxRestartBackoff) {
+			select {
+			case <-c.closing:
+				return
+			case <-time.After(backoff):
+			}
+			var err error
+			if exited, err = c.connect(); err == nil {
+				log.Infof("plugin %s restarted", c.name())
+				break
+			}
+			log.Errorf("restart plugin %s failed: %v", c.name(), err)
+		}
+	}
+}
+
+// connect starts the plugin process and shakes hands with it, exited is closed when the process exits
+func (c *Client) connect() (exited <-chan struct{}, err error) {
+	cmd := exec.Command(c.conf.Command, c.conf.Args...)
+	cmd.Env = append(os.Environ(), c.conf.Env...)
+	cmd.Env = append(cmd.Env, EnvProtocolVersion+"="+strconv.Itoa(ProtocolVersion))
+	cmd.Stderr = &logWriter{command: c.conf.Command}
+
+	var conn io.ReadWriteCloser
+	switch c.conf.Transport {
+	case "", TransportStdio:
+		conn, err = startStdio(cmd)
+	case TransportUnix:
+		conn, err = startUnix(cmd)
+	default:
+		err = fmt.Errorf("transport %s is not supported", c.conf.Transport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("start plugin %s failed: %w", c.conf.Command, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+
+	rpcClient := jsonrpc.NewClient(conn)
+	handshake, err := c.shakeHands(rpcClient)
+	if err != nil {
+		_ = rpcClient.Close()
+		_ = cmd.Process.Kill()
+		return nil, err
+	}
+
+	c.lock.Lock()
+	c.rpcClient = rpcClient
+	c.process = cmd.Process
+	c.handshake = handshake
+	c.lock.Unlock()
+	return done, nil
+}
+
+// shakeHands checks the plugin talks the protocol version, and that it is the same plugin after a restart
+func (c *Client) shakeHands(rpcClient *rpc.Client) (*HandshakeReply, error) {
+	handshake := &HandshakeReply{}
+	call := rpcClient.Go(serviceName+".Handshake", &HandshakeArgs{ProtocolVersion: ProtocolVersion}, handshake,
+		make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if call.Error != nil {
+			return nil, fmt.Errorf("handshake with plugin %s failed: %w", c.conf.Command, call.Error)
+		}
+	case <-time.After(handshakeTimeout):
+		return nil, fmt.Errorf("handshake with plugin %s timed out", c.conf.Command)
+	}
+	if handshake.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf("plugin %s talks protocol version %d, Answer talks %d",
+			c.conf.Command, handshake.ProtocolVersion, ProtocolVersion)
+	}
+	if len(handshake.Info.SlugName) == 0 {
+		return nil, fmt.Errorf("plugin %s has no slug name", c.conf.Command)
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.handshake != nil && c.handshake.Info.SlugName != handshake.Info.SlugName {
+		return nil, fmt.Errorf("plugin %s was %s, it is %s after the restart",
+			c.conf.Command, c.handshake.Info.SlugName, handshake.Info.SlugName)
+	}
+	return handshake, nil
+}
+
+// disconnect closes the connection and kills the process
+func (c *Client) disconnect() {
+	c.lock.Lock()
+	rpcClient, process := c.rpcClient, c.process
+	c.rpcClient, c.process = nil, nil
+	c.lock.Unlock()
+	if rpcClient != nil {
+		_ = rpcClient.Close()
+	}
+	if process != nil {
+		_ = process.Kill()
+	}
+}
+
+// kill kills the process, it is restarted by supervise
+func (c *Client) kill() {
+	c.lock.RLock()
+	process := c.process
+	c.lock.RUnlock()
+	if process != nil {
+		_ = process.Kill()
+	}
+}
+
+func (c *Client) name() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.handshake != nil {
+		return c.handshake.Info.SlugName
+	}
+	return c.conf.Command
+}
+
+// startStdio starts the process, talking over its stdin and stdout
+func startStdio(cmd *exec.Cmd) (io.ReadWriteCloser, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &pipeConn{reader: stdout, writer: stdin}, nil
+}
+
+// startUnix starts the process, talking over a unix socket it dials
+func startUnix(cmd *exec.Cmd) (io.ReadWriteCloser, error) {
+	dir, err := os.MkdirTemp("", "answer-plugin-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	socket := filepath.Join(dir, "plugin.sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	cmd.Env = append(cmd.Env, EnvSocket+"="+socket)
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	_ = listener.SetDeadline(time.Now().Add(handshakeTimeout))
+	conn, err := listener.Accept()
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// logWriter logs the lines the plugin writes to its stderr
+type logWriter struct {
+	command string
+	lock    sync.Mutex
+	buf     []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		log.Infof("[plugin %s] %s", w.command, w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,269 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/apache/answer/internal/base/handler"
+	"github.com/apache/answer/plugin"
+	"github.com/segmentfault/pacman/log"
+)
+
+// StartPlugins starts the plugins out of the process and registers them as the plugin types they implement.
+// The plugins failing to start are logged and left out. The returned function stops them.
+func StartPlugins(configs []*Config) (stop func()) {
+	clients := make([]*Client, 0, len(configs))
+	for _, conf := range configs {
+		client := NewClient(conf)
+		if err := client.Start(); err != nil {
+			log.Errorf("start plugin %s failed: %v", conf.Command, err)
+			continue
+		}
+		Register(client)
+		clients = append(clients, client)
+		log.Infof("plugin %s started", client.Info().SlugName)
+	}
+	return func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}
+}
+
+// Register registers the plugin of the client as the plugin types from its handshake
+func Register(client *Client) {
+	base := &remoteBase{client: client}
+	impls := make([]plugin.Base, 0)
+	for _, capability := range client.Capabilities() {
+		switch capability {
+		case CapabilitySearch:
+			impls = append(impls, &remoteSearch{base})
+		case CapabilityReviewer:
+			impls = append(impls, &remoteReviewer{base})
+		case CapabilityNotification:
+			impls = append(impls, &remoteNotification{base})
+		case CapabilityFilter:
+			impls = append(impls, &remoteFilter{base})
+		case CapabilityParser:
+			impls = append(impls, &remoteParser{base})
+		case CapabilityStorage:
+			impls = append(impls, &remoteStorage{base})
+		case CapabilityEventListener:
+			impls = append(impls, &remoteEventListener{base})
+		default:
+			log.Warnf("plugin %s implements %s, it is not supported", client.Info().SlugName, capability)
+		}
+	}
+	plugin.RegisterWith(base, impls...)
+}
+
+type remoteBase struct {
+	client *Client
+}
+
+func (b *remoteBase) Info() plugin.Info {
+	info := b.client.Info()
+	return plugin.Info{
+		Name:        text(info.Name),
+		SlugName:    info.SlugName,
+		Description: text(info.Description),
+		Author:      info.Author,
+		Version:     info.Version,
+		Link:        info.Link,
+	}
+}
+
+// remoteSearch the search plugin out of the process
+type remoteSearch struct {
+	*remoteBase
+}
+
+func (s *remoteSearch) Description() (desc plugin.SearchDesc) {
+	if err := s.client.Call(context.Background(), "SearchDescription", &Empty{}, &desc); err != nil {
+		log.Errorf("get search description of plugin %s failed: %v", s.client.name(), err)
+	}
+	return desc
+}
+
+// RegisterSyncer does nothing, the syncer is not callable from the plugin process.
+// The index is rebuilt with `answer search reindex` instead.
+func (s *remoteSearch) RegisterSyncer(ctx context.Context, syncer plugin.SearchSyncer) {}
+
+func (s *remoteSearch) SearchContents(ctx context.Context, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	reply := &SearchReply{}
+	err = s.client.Call(ctx, "SearchContents", &SearchArgs{Cond: cond}, reply)
+	return reply.Results, reply.Total, err
+}
+
+func (s *remoteSearch) SearchQuestions(ctx context.Context, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	reply := &SearchReply{}
+	err = s.client.Call(ctx, "SearchQuestions", &SearchArgs{Cond: cond}, reply)
+	return reply.Results, reply.Total, err
+}
+
+func (s *remoteSearch) SearchAnswers(ctx context.Context, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	reply := &SearchReply{}
+	err = s.client.Call(ctx, "SearchAnswers", &SearchArgs{Cond: cond}, reply)
+	return reply.Results, reply.Total, err
+}
+
+func (s *remoteSearch) UpdateContent(ctx context.Context, content *plugin.SearchContent) error {
+	return s.client.Call(ctx, "UpdateContent", &UpdateContentArgs{Content: content}, &Empty{})
+}
+
+func (s *remoteSearch) DeleteContent(ctx context.Context, objectID string) error {
+	return s.client.Call(ctx, "DeleteContent", &DeleteContentArgs{ObjectID: objectID}, &Empty{})
+}
+
+// remoteReviewer the reviewer plugin out of the process
+type remoteReviewer struct {
+	*remoteBase
+}
+
+// Review asks for a review of the content when the plugin fails
+func (r *remoteReviewer) Review(content *plugin.ReviewContent) *plugin.ReviewResult {
+	reply := &ReviewReply{}
+	err := r.client.Call(context.Background(), "Review", &ReviewArgs{Content: content}, reply)
+	if err != nil || reply.Result == nil {
+		log.Errorf("review with plugin %s failed: %v", r.client.name(), err)
+		return &plugin.ReviewResult{
+			Approved:     false,
+			ReviewStatus: plugin.ReviewStatusNeedReview,
+			Reason:       "review plugin is unavailable",
+		}
+	}
+	return reply.Result
+}
+
+// remoteNotification the notification plugin out of the process
+type remoteNotification struct {
+	*remoteBase
+}
+
+func (n *remoteNotification) GetNewQuestionSubscribers() []string {
+	reply := &SubscribersReply{}
+	if err := n.client.Call(context.Background(), "GetNewQuestionSubscribers", &Empty{}, reply); err != nil {
+		log.Errorf("get new question subscribers of plugin %s failed: %v", n.client.name(), err)
+		return nil
+	}
+	return reply.UserIDs
+}
+
+func (n *remoteNotification) Notify(msg plugin.NotificationMessage) {
+	if err := n.client.Call(context.Background(), "Notify", &NotifyArgs{Message: msg}, &Empty{}); err != nil {
+		log.Errorf("notify with plugin %s failed: %v", n.client.name(), err)
+	}
+}
+
+// remoteEventListener the event listener plugin out of the process
+type remoteEventListener struct {
+	*remoteBase
+}
+
+func (l *remoteEventListener) Events() []plugin.EventType {
+	return l.client.Events()
+}
+
+func (l *remoteEventListener) HandleEvent(ctx context.Context, payload []byte) error {
+	return l.client.Call(ctx, "HandleEvent", &HandleEventArgs{Payload: payload}, &Empty{})
+}
+
+// remoteFilter the filter plugin out of the process
+type remoteFilter struct {
+	*remoteBase
+}
+
+func (f *remoteFilter) FilterText(text string) error {
+	return f.client.Call(context.Background(), "FilterText", &TextArgs{Text: text}, &Empty{})
+}
+
+// remoteParser the parser plugin out of the process
+type remoteParser struct {
+	*remoteBase
+}
+
+func (p *remoteParser) Parse(text string) (string, error) {
+	reply := &TextReply{}
+	if err := p.client.Call(context.Background(), "Parse", &TextArgs{Text: text}, reply); err != nil {
+		return "", err
+	}
+	return reply.Text, nil
+}
+
+// remoteStorage the storage plugin out of the process, the file uploaded is passed to it
+type remoteStorage struct {
+	*remoteBase
+}
+
+func (s *remoteStorage) UploadFile(ctx *plugin.GinContext, condition plugin.UploadFileCondition) (
+	resp plugin.UploadFileResponse) {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		resp.OriginalError = err
+		return resp
+	}
+	reader, err := file.Open()
+	if err != nil {
+		resp.OriginalError = err
+		return resp
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		resp.OriginalError = err
+		return resp
+	}
+
+	reply := &UploadFileReply{}
+	err = s.client.Call(ctx, "UploadFile", &UploadFileArgs{
+		Condition:   condition,
+		Lang:        string(handler.GetLangByCtx(ctx)),
+		FileName:    file.Filename,
+		ContentType: file.Header.Get("Content-Type"),
+		Content:     content,
+	}, reply)
+	if err != nil {
+		resp.OriginalError = err
+		return resp
+	}
+	resp.FullURL = reply.FullURL
+	if len(reply.Error) > 0 {
+		resp.OriginalError = errors.New(reply.Error)
+	}
+	if len(reply.DisplayErrorMsg) > 0 {
+		resp.DisplayErrorMsg = text(reply.DisplayErrorMsg)
+	}
+	return resp
+}
+
+// text the translator of a text translated already
+func text(s string) plugin.Translator {
+	return plugin.Translator{Fn: func(ctx *plugin.GinContext) string {
+		return s
+	}}
+}
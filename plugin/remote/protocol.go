@@ -0,0 +1,173 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package remote runs plugins as separate processes. Answer starts the executable of the plugin and talks
+// to it with JSON-RPC over its stdin and stdout or over a unix socket, the plugin calls Serve in its main.
+package remote
+
+import (
+	"encoding/json"
+
+	"github.com/apache/answer/plugin"
+)
+
+// ProtocolVersion the version of the protocol, Answer and the plugin have to talk the same version
+const ProtocolVersion = 1
+
+const (
+	// EnvProtocolVersion the environment variable of the protocol version Answer talks
+	EnvProtocolVersion = "ANSWER_PLUGIN_PROTOCOL_VERSION"
+	// EnvSocket the environment variable of the unix socket to dial, the plugin talks over stdio when it is empty
+	EnvSocket = "ANSWER_PLUGIN_SOCKET"
+)
+
+const (
+	TransportStdio = "stdio"
+	TransportUnix  = "unix"
+)
+
+// serviceName the name of the RPC service of the plugin
+const serviceName = "Plugin"
+
+// Capability a plugin type the plugin implements
+type Capability string
+
+const (
+	CapabilitySearch       Capability = "search"
+	CapabilityReviewer     Capability = "reviewer"
+	CapabilityNotification Capability = "notification"
+	CapabilityFilter       Capability = "filter"
+	CapabilityParser       Capability = "parser"
+	CapabilityStorage      Capability = "storage"
+	// CapabilityEventListener the events it handles are in the handshake
+	CapabilityEventListener Capability = "event_listener"
+)
+
+// capabilitiesOf returns the plugin types p implements which can run out of the process
+func capabilitiesOf(p plugin.Base) (capabilities []Capability) {
+	if _, ok := p.(plugin.Search); ok {
+		capabilities = append(capabilities, CapabilitySearch)
+	}
+	if _, ok := p.(plugin.Reviewer); ok {
+		capabilities = append(capabilities, CapabilityReviewer)
+	}
+	if _, ok := p.(plugin.Notification); ok {
+		capabilities = append(capabilities, CapabilityNotification)
+	}
+	if _, ok := p.(plugin.Filter); ok {
+		capabilities = append(capabilities, CapabilityFilter)
+	}
+	if _, ok := p.(plugin.Parser); ok {
+		capabilities = append(capabilities, CapabilityParser)
+	}
+	if _, ok := p.(plugin.Storage); ok {
+		capabilities = append(capabilities, CapabilityStorage)
+	}
+	if _, ok := p.(plugin.EventListener); ok {
+		capabilities = append(capabilities, CapabilityEventListener)
+	}
+	return capabilities
+}
+
+// Info the plugin information with the texts translated
+type Info struct {
+	Name        string `json:"name"`
+	SlugName    string `json:"slug_name"`
+	Description string `json:"description"`
+	Author      string `json:"author"`
+	Version     string `json:"version"`
+	Link        string `json:"link"`
+}
+
+type Empty struct{}
+
+type HandshakeArgs struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+type HandshakeReply struct {
+	ProtocolVersion int          `json:"protocol_version"`
+	Info            Info         `json:"info"`
+	Capabilities    []Capability `json:"capabilities"`
+	// Events the types of the events the plugin handles, if it is an event listener
+	Events []plugin.EventType `json:"events,omitempty"`
+}
+
+type SearchArgs struct {
+	Cond *plugin.SearchBasicCond `json:"cond"`
+}
+
+type SearchReply struct {
+	Results []plugin.SearchResult `json:"results"`
+	Total   int64                 `json:"total"`
+}
+
+type UpdateContentArgs struct {
+	Content *plugin.SearchContent `json:"content"`
+}
+
+type DeleteContentArgs struct {
+	ObjectID string `json:"object_id"`
+}
+
+type ReviewArgs struct {
+	Content *plugin.ReviewContent `json:"content"`
+}
+
+type ReviewReply struct {
+	Result *plugin.ReviewResult `json:"result"`
+}
+
+type SubscribersReply struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+type NotifyArgs struct {
+	Message plugin.NotificationMessage `json:"message"`
+}
+
+type HandleEventArgs struct {
+	// Payload the JSON of the plugin.EventPayload
+	Payload json.RawMessage `json:"payload"`
+}
+
+type TextArgs struct {
+	Text string `json:"text"`
+}
+
+type TextReply struct {
+	Text string `json:"text"`
+}
+
+type UploadFileArgs struct {
+	Condition plugin.UploadFileCondition `json:"condition"`
+	// Lang the language of the user uploading
+	Lang        string `json:"lang"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"`
+}
+
+type UploadFileReply struct {
+	FullURL string `json:"full_url"`
+	// Error the error of the storage, it is logged
+	Error string `json:"error"`
+	// DisplayErrorMsg the error message shown to the user, translated in the language of the user
+	DisplayErrorMsg string `json:"display_error_msg"`
+}
@@ -0,0 +1,172 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package remote_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/answer/plugin"
+	"github.com/apache/answer/plugin/remote"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildExample builds the example plugin in testdata
+func buildExample(t *testing.T) string {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "example")
+	output, err := exec.Command("go", "build", "-o", binary, "./testdata/example").CombinedOutput()
+	require.NoError(t, err, string(output))
+	return binary
+}
+
+func TestRemotePlugin(t *testing.T) {
+	binary := buildExample(t)
+
+	t.Run("registered as the plugin types", func(t *testing.T) {
+		client := remote.NewClient(&remote.Config{Command: binary})
+		require.NoError(t, client.Start())
+		t.Cleanup(client.Close)
+
+		assert.Equal(t, "remote_example", client.Info().SlugName)
+		assert.ElementsMatch(t, []remote.Capability{remote.CapabilitySearch, remote.CapabilityReviewer,
+			remote.CapabilityFilter, remote.CapabilityParser, remote.CapabilityStorage,
+			remote.CapabilityEventListener}, client.Capabilities())
+		assert.Equal(t, []plugin.EventType{plugin.EventQuestionCreate}, client.Events())
+
+		remote.Register(client)
+		plugin.StatusManager.Enable("remote_example", true)
+		t.Cleanup(func() {
+			plugin.StatusManager.Enable("remote_example", false)
+		})
+
+		_ = plugin.CallBase(func(base plugin.Base) error {
+			if base.Info().SlugName == "remote_example" {
+				assert.Equal(t, "Example", base.Info().Name.Translate(nil))
+				assert.Equal(t, "1.0.0", base.Info().Version)
+			}
+			return nil
+		})
+
+		called := 0
+		_ = plugin.CallParser(func(parser plugin.Parser) error {
+			called++
+			html, err := parser.Parse("hello")
+			assert.NoError(t, err)
+			assert.Equal(t, "<p>hello</p>", html)
+			return nil
+		})
+		_ = plugin.CallFilter(func(filter plugin.Filter) error {
+			called++
+			assert.NoError(t, filter.FilterText("hello"))
+			assert.ErrorContains(t, filter.FilterText("buy spam"), "spam is not allowed")
+			return nil
+		})
+		_ = plugin.CallReviewer(func(reviewer plugin.Reviewer) error {
+			called++
+			result := reviewer.Review(&plugin.ReviewContent{Content: "hello", Author: plugin.ReviewContentAuthor{Rank: 1}})
+			assert.False(t, result.Approved)
+			assert.Equal(t, plugin.ReviewStatusNeedReview, result.ReviewStatus)
+			result = reviewer.Review(&plugin.ReviewContent{Content: "hello", Author: plugin.ReviewContentAuthor{Rank: 100}})
+			assert.True(t, result.Approved)
+			return nil
+		})
+		_ = plugin.CallSearch(func(search plugin.Search) error {
+			called++
+			ctx := context.TODO()
+			assert.Equal(t, "https://search.example.com", search.Description().Link)
+			require.NoError(t, search.UpdateContent(ctx, &plugin.SearchContent{
+				ObjectID: "1", Type: "question", Title: "How to deploy", Content: "on friday"}))
+			require.NoError(t, search.UpdateContent(ctx, &plugin.SearchContent{
+				ObjectID: "2", Type: "answer", Title: "How to deploy", Content: "on monday"}))
+			res, total, err := search.SearchQuestions(ctx, &plugin.SearchBasicCond{Words: []string{"deploy"}})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), total)
+			assert.Equal(t, "1", res[0].ID)
+			require.NoError(t, search.DeleteContent(ctx, "2"))
+			_, total, err = search.SearchContents(ctx, &plugin.SearchBasicCond{Words: []string{"deploy"}})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), total)
+			return nil
+		})
+		_ = plugin.CallStorage(func(storage plugin.Storage) error {
+			called++
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, _ := writer.CreateFormFile("file", "logo.png")
+			_, _ = part.Write([]byte("png"))
+			_ = writer.Close()
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/upload", body)
+			ctx.Request.Header.Set("Content-Type", writer.FormDataContentType())
+			resp := storage.UploadFile(ctx, plugin.UploadFileCondition{Source: plugin.AdminBranding})
+			assert.NoError(t, resp.OriginalError)
+			assert.Equal(t, "https://files.example.com/admin_branding/logo.png?size=3", resp.FullURL)
+			return nil
+		})
+		_ = plugin.CallEventListener(func(listener plugin.EventListener) error {
+			called++
+			assert.Equal(t, []plugin.EventType{plugin.EventQuestionCreate}, listener.Events())
+			ctx := context.TODO()
+			payload, _ := json.Marshal(&plugin.EventPayload{
+				Version: plugin.EventPayloadVersion, Type: plugin.EventQuestionCreate, Attempt: 1})
+			assert.ErrorContains(t, listener.HandleEvent(ctx, payload), "try again later")
+			payload, _ = json.Marshal(&plugin.EventPayload{
+				Version: plugin.EventPayloadVersion, Type: plugin.EventQuestionCreate, Attempt: 2})
+			assert.NoError(t, listener.HandleEvent(ctx, payload))
+			return nil
+		})
+		assert.Equal(t, 6, called)
+	})
+
+	t.Run("restarted after it exits", func(t *testing.T) {
+		client := remote.NewClient(&remote.Config{Command: binary, Transport: remote.TransportUnix})
+		require.NoError(t, client.Start())
+		t.Cleanup(client.Close)
+
+		reply := &remote.TextReply{}
+		require.NoError(t, client.Call(context.TODO(), "Parse", &remote.TextArgs{Text: "hello"}, reply))
+		assert.Equal(t, "<p>hello</p>", reply.Text)
+
+		assert.Error(t, client.Call(context.TODO(), "Parse", &remote.TextArgs{Text: "exit"}, reply))
+		assert.Eventually(t, func() bool {
+			return client.Call(context.TODO(), "Parse", &remote.TextArgs{Text: "again"}, reply) == nil
+		}, 10*time.Second, 100*time.Millisecond)
+		assert.Equal(t, "<p>again</p>", reply.Text)
+	})
+
+	t.Run("not a plugin", func(t *testing.T) {
+		client := remote.NewClient(&remote.Config{Command: "sh", Args: []string{"-c", "exit 0"}})
+		assert.Error(t, client.Start())
+
+		client = remote.NewClient(&remote.Config{Command: binary, Transport: "tcp"})
+		assert.Error(t, client.Start())
+	})
+}
@@ -0,0 +1,286 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"net/textproto"
+	"os"
+
+	"github.com/apache/answer/internal/base/constant"
+	"github.com/apache/answer/plugin"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentfault/pacman/i18n"
+)
+
+// Serve runs p in the process Answer started, it returns when Answer disconnects.
+// With the stdio transport, what the plugin writes to os.Stdout afterwards goes to the stderr,
+// the stdout is what Answer talks over. Answer logs the stderr.
+func Serve(p plugin.Base) error {
+	gin.SetMode(gin.ReleaseMode)
+	conn, err := dialHost()
+	if err != nil {
+		return err
+	}
+	server := rpc.NewServer()
+	if err = server.RegisterName(serviceName, &rpcServer{impl: p}); err != nil {
+		return fmt.Errorf("register plugin failed: %w", err)
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	return nil
+}
+
+// dialHost connects to Answer over the transport it chose
+func dialHost() (io.ReadWriteCloser, error) {
+	if socket := os.Getenv(EnvSocket); len(socket) > 0 {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, fmt.Errorf("dial %s failed: %w", socket, err)
+		}
+		return conn, nil
+	}
+	conn := &pipeConn{reader: os.Stdin, writer: os.Stdout}
+	os.Stdout = os.Stderr
+	gin.DefaultWriter = os.Stderr
+	return conn, nil
+}
+
+// pipeConn the connection over a reader and a writer, like the stdin and the stdout
+type pipeConn struct {
+	reader io.ReadCloser
+	writer io.WriteCloser
+}
+
+func (c *pipeConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (c *pipeConn) Write(p []byte) (int, error) {
+	return c.writer.Write(p)
+}
+
+func (c *pipeConn) Close() error {
+	err := c.writer.Close()
+	if readErr := c.reader.Close(); err == nil {
+		err = readErr
+	}
+	return err
+}
+
+// rpcServer the RPC service calling the plugin in its process
+type rpcServer struct {
+	impl plugin.Base
+}
+
+func (s *rpcServer) Handshake(args *HandshakeArgs, reply *HandshakeReply) error {
+	if args.ProtocolVersion != ProtocolVersion {
+		return fmt.Errorf("protocol version %d is not supported, the plugin talks %d",
+			args.ProtocolVersion, ProtocolVersion)
+	}
+	ctx := newGinContext()
+	info := s.impl.Info()
+	reply.ProtocolVersion = ProtocolVersion
+	reply.Info = Info{
+		Name:        info.Name.Translate(ctx),
+		SlugName:    info.SlugName,
+		Description: info.Description.Translate(ctx),
+		Author:      info.Author,
+		Version:     info.Version,
+		Link:        info.Link,
+	}
+	reply.Capabilities = capabilitiesOf(s.impl)
+	if listener, ok := s.impl.(plugin.EventListener); ok {
+		reply.Events = listener.Events()
+	}
+	return nil
+}
+
+func (s *rpcServer) Health(args *Empty, reply *Empty) error {
+	return nil
+}
+
+func (s *rpcServer) SearchDescription(args *Empty, reply *plugin.SearchDesc) error {
+	search, err := implOf[plugin.Search](s.impl, CapabilitySearch)
+	if err != nil {
+		return err
+	}
+	*reply = search.Description()
+	return nil
+}
+
+func (s *rpcServer) SearchContents(args *SearchArgs, reply *SearchReply) (err error) {
+	search, err := implOf[plugin.Search](s.impl, CapabilitySearch)
+	if err != nil {
+		return err
+	}
+	reply.Results, reply.Total, err = search.SearchContents(context.Background(), args.Cond)
+	return err
+}
+
+func (s *rpcServer) SearchQuestions(args *SearchArgs, reply *SearchReply) (err error) {
+	search, err := implOf[plugin.Search](s.impl, CapabilitySearch)
+	if err != nil {
+		return err
+	}
+	reply.Results, reply.Total, err = search.SearchQuestions(context.Background(), args.Cond)
+	return err
+}
+
+func (s *rpcServer) SearchAnswers(args *SearchArgs, reply *SearchReply) (err error) {
+	search, err := implOf[plugin.Search](s.impl, CapabilitySearch)
+	if err != nil {
+		return err
+	}
+	reply.Results, reply.Total, err = search.SearchAnswers(context.Background(), args.Cond)
+	return err
+}
+
+func (s *rpcServer) UpdateContent(args *UpdateContentArgs, reply *Empty) error {
+	search, err := implOf[plugin.Search](s.impl, CapabilitySearch)
+	if err != nil {
+		return err
+	}
+	return search.UpdateContent(context.Background(), args.Content)
+}
+
+func (s *rpcServer) DeleteContent(args *DeleteContentArgs, reply *Empty) error {
+	search, err := implOf[plugin.Search](s.impl, CapabilitySearch)
+	if err != nil {
+		return err
+	}
+	return search.DeleteContent(context.Background(), args.ObjectID)
+}
+
+func (s *rpcServer) Review(args *ReviewArgs, reply *ReviewReply) error {
+	reviewer, err := implOf[plugin.Reviewer](s.impl, CapabilityReviewer)
+	if err != nil {
+		return err
+	}
+	reply.Result = reviewer.Review(args.Content)
+	return nil
+}
+
+func (s *rpcServer) GetNewQuestionSubscribers(args *Empty, reply *SubscribersReply) error {
+	notification, err := implOf[plugin.Notification](s.impl, CapabilityNotification)
+	if err != nil {
+		return err
+	}
+	reply.UserIDs = notification.GetNewQuestionSubscribers()
+	return nil
+}
+
+func (s *rpcServer) Notify(args *NotifyArgs, reply *Empty) error {
+	notification, err := implOf[plugin.Notification](s.impl, CapabilityNotification)
+	if err != nil {
+		return err
+	}
+	notification.Notify(args.Message)
+	return nil
+}
+
+func (s *rpcServer) HandleEvent(args *HandleEventArgs, reply *Empty) error {
+	listener, err := implOf[plugin.EventListener](s.impl, CapabilityEventListener)
+	if err != nil {
+		return err
+	}
+	return listener.HandleEvent(context.Background(), args.Payload)
+}
+
+func (s *rpcServer) FilterText(args *TextArgs, reply *Empty) error {
+	filter, err := implOf[plugin.Filter](s.impl, CapabilityFilter)
+	if err != nil {
+		return err
+	}
+	return filter.FilterText(args.Text)
+}
+
+func (s *rpcServer) Parse(args *TextArgs, reply *TextReply) (err error) {
+	parser, err := implOf[plugin.Parser](s.impl, CapabilityParser)
+	if err != nil {
+		return err
+	}
+	reply.Text, err = parser.Parse(args.Text)
+	return err
+}
+
+// UploadFile passes the file to the storage in the form of a request, as it is uploaded to Answer
+func (s *rpcServer) UploadFile(args *UploadFileArgs, reply *UploadFileReply) error {
+	storage, err := implOf[plugin.Storage](s.impl, CapabilityStorage)
+	if err != nil {
+		return err
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data",
+		map[string]string{"name": "file", "filename": args.FileName}))
+	if len(args.ContentType) > 0 {
+		header.Set("Content-Type", args.ContentType)
+	}
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err = part.Write(args.Content); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	ctx := newGinContext()
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", body)
+	ctx.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	ctx.Set(constant.AcceptLanguageFlag, i18n.Language(args.Lang))
+
+	resp := storage.UploadFile(ctx, args.Condition)
+	reply.FullURL = resp.FullURL
+	if resp.OriginalError != nil {
+		reply.Error = resp.OriginalError.Error()
+	}
+	reply.DisplayErrorMsg = resp.DisplayErrorMsg.Translate(ctx)
+	return nil
+}
+
+// implOf returns the plugin as the type of the capability
+func implOf[T plugin.Base](impl plugin.Base, capability Capability) (t T, err error) {
+	t, ok := impl.(T)
+	if !ok {
+		return t, fmt.Errorf("the plugin is not a %s plugin", capability)
+	}
+	return t, nil
+}
+
+func newGinContext() *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	return ctx
+}
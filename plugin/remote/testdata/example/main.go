@@ -0,0 +1,169 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// The example plugin running out of the process, it is built and started by the tests of the remote package.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/apache/answer/plugin"
+	"github.com/apache/answer/plugin/remote"
+)
+
+type example struct {
+	lock     sync.Mutex
+	contents map[string]*plugin.SearchContent
+}
+
+func (e *example) Info() plugin.Info {
+	return plugin.Info{
+		Name:     plugin.Translator{Fn: func(ctx *plugin.GinContext) string { return "Example" }},
+		SlugName: "remote_example",
+		Author:   "answer",
+		Version:  "1.0.0",
+	}
+}
+
+func (e *example) Parse(text string) (string, error) {
+	// exit to test the restart of the plugin
+	if text == "exit" {
+		os.Exit(1)
+	}
+	return "<p>" + text + "</p>", nil
+}
+
+func (e *example) FilterText(text string) error {
+	if strings.Contains(text, "spam") {
+		return errors.New("spam is not allowed")
+	}
+	return nil
+}
+
+func (e *example) Review(content *plugin.ReviewContent) *plugin.ReviewResult {
+	if content.Author.Rank < 10 {
+		return &plugin.ReviewResult{ReviewStatus: plugin.ReviewStatusNeedReview, Reason: "new user"}
+	}
+	return &plugin.ReviewResult{Approved: true, ReviewStatus: plugin.ReviewStatusApproved}
+}
+
+func (e *example) UploadFile(ctx *plugin.GinContext, condition plugin.UploadFileCondition) (
+	resp plugin.UploadFileResponse) {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		resp.OriginalError = err
+		return resp
+	}
+	reader, err := file.Open()
+	if err != nil {
+		resp.OriginalError = err
+		return resp
+	}
+	defer reader.Close()
+	content, _ := io.ReadAll(reader)
+	resp.FullURL = fmt.Sprintf("https://files.example.com/%s/%s?size=%d", condition.Source, file.Filename, len(content))
+	return resp
+}
+
+func (e *example) Events() []plugin.EventType {
+	return []plugin.EventType{plugin.EventQuestionCreate}
+}
+
+func (e *example) HandleEvent(ctx context.Context, payload []byte) error {
+	event := &plugin.EventPayload{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return err
+	}
+	if event.Version != plugin.EventPayloadVersion {
+		return fmt.Errorf("event payload version %d is not supported", event.Version)
+	}
+	// fail the first attempt to test the retry
+	if event.Attempt == 1 {
+		return errors.New("try again later")
+	}
+	return nil
+}
+
+func (e *example) Description() plugin.SearchDesc {
+	return plugin.SearchDesc{Link: "https://search.example.com"}
+}
+
+func (e *example) RegisterSyncer(ctx context.Context, syncer plugin.SearchSyncer) {}
+
+func (e *example) SearchContents(ctx context.Context, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	return e.search(cond, "")
+}
+
+func (e *example) SearchQuestions(ctx context.Context, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	return e.search(cond, "question")
+}
+
+func (e *example) SearchAnswers(ctx context.Context, cond *plugin.SearchBasicCond) (
+	res []plugin.SearchResult, total int64, err error) {
+	return e.search(cond, "answer")
+}
+
+func (e *example) search(cond *plugin.SearchBasicCond, objectType string) (
+	res []plugin.SearchResult, total int64, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, content := range e.contents {
+		if len(objectType) > 0 && content.Type != objectType {
+			continue
+		}
+		matched := true
+		for _, word := range cond.Words {
+			matched = matched && strings.Contains(content.Title+" "+content.Content, word)
+		}
+		if matched {
+			res = append(res, plugin.SearchResult{ID: content.ObjectID, Type: content.Type})
+		}
+	}
+	return res, int64(len(res)), nil
+}
+
+func (e *example) UpdateContent(ctx context.Context, content *plugin.SearchContent) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.contents[content.ObjectID] = content
+	return nil
+}
+
+func (e *example) DeleteContent(ctx context.Context, objectID string) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	delete(e.contents, objectID)
+	return nil
+}
+
+func main() {
+	if err := remote.Serve(&example{contents: make(map[string]*plugin.SearchContent)}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -79,3 +79,10 @@ var (
 	CallReviewer,
 	registerReviewer = MakePlugin[Reviewer](false)
 )
+
+// ReviewWithTimeout reviews the content with the reviewer, returning ErrTimeout when it does not return in time
+func ReviewWithTimeout(reviewer Reviewer, content *ReviewContent) (*ReviewResult, error) {
+	return awaitCall("Reviewer", reviewer, func() *ReviewResult {
+		return reviewer.Review(content)
+	})
+}
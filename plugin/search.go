@@ -21,6 +21,7 @@ package plugin
 
 import (
 	"context"
+	"time"
 )
 
 type SearchResult struct {
@@ -28,6 +29,10 @@ type SearchResult struct {
 	ID string
 	// Type content type, example: "answer", "question"
 	Type string
+	// Highlights optional, the fragments of the content matching the query keyed by the field,
+	// SearchHighlightTitle or SearchHighlightContent. The matched words are wrapped in <mark></mark>,
+	// the rest is plain text and is escaped. The fragments are built by the server when there are none.
+	Highlights map[string][]string
 }
 
 type SearchContent struct {
@@ -76,6 +81,16 @@ type SearchBasicCond struct {
 	ViewAmount int
 	// greater than or equal to the number of answers. Only support search question.
 	AnswerAmount int
+
+	// The keywords parsed with OR, exclusions and phrases. Words holds the terms of it
+	// for the plugins only searching keywords. Nil when the query has no keywords.
+	Query *SearchExpr
+	// Created at or after this time, zero means no limit.
+	CreatedAfter time.Time
+	// Created before this time, zero means no limit.
+	CreatedBefore time.Time
+	// The number of characters of a highlighted fragment around the matched words.
+	FragmentSize int
 }
 
 type SearchAcceptedCond int
@@ -93,6 +108,11 @@ const (
 	SearchContentStatusDeleted   = 10
 )
 
+const (
+	SearchHighlightTitle   = "title"
+	SearchHighlightContent = "content"
+)
+
 const (
 	SearchNewestOrder    SearchOrderCond = "newest"
 	SearchActiveOrder    SearchOrderCond = "active"
@@ -111,6 +131,52 @@ type Search interface {
 	DeleteContent(ctx context.Context, objectID string) (err error)
 }
 
+// Facets is the optional capability of a Search plugin counting the results of a condition by facet,
+// the search results have no facets when the plugin does not implement it.
+type Facets interface {
+	Facets(ctx context.Context, cond *SearchBasicCond) (facets *SearchFacets, err error)
+}
+
+// SearchFacets the number of results of the search condition in each facet, whatever the page
+type SearchFacets struct {
+	// Tags the results of each tag ID, the answers have the tags of their question.
+	Tags map[string]int64
+	// Types the results of each content type, "question" or "answer".
+	Types map[string]int64
+	// Accepted the results of each accepted state, SearchFacetAccepted or SearchFacetNotAccepted.
+	// A question is accepted when it has an accepted answer, an answer when it is the accepted one.
+	Accepted map[string]int64
+	// Created the results created since each of the SearchCreatedBuckets, keyed by bucket.
+	Created map[string]int64
+}
+
+// SearchCounter is the optional capability of a Search plugin counting the contents of its index
+// that are not deleted, the totals of unconditional searches are used when the plugin does not implement it.
+type SearchCounter interface {
+	CountContents(ctx context.Context) (questions, answers int64, err error)
+}
+
+const (
+	SearchFacetAccepted    = "accepted"
+	SearchFacetNotAccepted = "not_accepted"
+)
+
+// SearchCreatedBucket a creation date facet, counting the results created since the time
+type SearchCreatedBucket struct {
+	Key   string
+	Since time.Time
+}
+
+// SearchCreatedBuckets returns the creation date facets: the last day, week, month and year before now
+func SearchCreatedBuckets(now time.Time) []SearchCreatedBucket {
+	return []SearchCreatedBucket{
+		{Key: "day", Since: now.AddDate(0, 0, -1)},
+		{Key: "week", Since: now.AddDate(0, 0, -7)},
+		{Key: "month", Since: now.AddDate(0, -1, 0)},
+		{Key: "year", Since: now.AddDate(-1, 0, 0)},
+	}
+}
+
 type SearchDesc struct {
 	// A svg icon it wil be display in search result page. optional
 	Icon string `json:"icon"`
@@ -128,3 +194,125 @@ var (
 	CallSearch,
 	registerSearch = MakePlugin[Search](false)
 )
+
+// GetSearch returns the search plugin enabled, with its calls guarded like the calls of CallSearch: its panics
+// are recovered, its context has the deadline of the Search type, and its failures count to its circuit breaker.
+// It keeps the optional capabilities of the plugin. It returns nil when no search plugin is enabled.
+func GetSearch() Search {
+	var search Search
+	_ = CallSearch(func(s Search) error {
+		search = s
+		return nil
+	})
+	if search == nil {
+		return nil
+	}
+	guarded := &guardedSearch{Search: search}
+	_, hasFacets := search.(Facets)
+	_, hasCounter := search.(SearchCounter)
+	switch {
+	case hasFacets && hasCounter:
+		return &guardedFacetsCounterSearch{guarded}
+	case hasFacets:
+		return &guardedFacetsSearch{guarded}
+	case hasCounter:
+		return &guardedCounterSearch{guarded}
+	}
+	return guarded
+}
+
+// guardedSearch the search plugin with its calls guarded
+type guardedSearch struct {
+	Search
+}
+
+func (g *guardedSearch) call(ctx context.Context, fn func(ctx context.Context, search Search) error) error {
+	ctx, cancel := WithCallTimeout(ctx, "Search")
+	defer cancel()
+	return guardCall("Search", g.Search, true, func(search Search) error {
+		return fn(ctx, search)
+	})
+}
+
+func (g *guardedSearch) SearchContents(ctx context.Context, cond *SearchBasicCond) (
+	res []SearchResult, total int64, err error) {
+	err = g.call(ctx, func(ctx context.Context, search Search) (err error) {
+		res, total, err = search.SearchContents(ctx, cond)
+		return err
+	})
+	return res, total, err
+}
+
+func (g *guardedSearch) SearchQuestions(ctx context.Context, cond *SearchBasicCond) (
+	res []SearchResult, total int64, err error) {
+	err = g.call(ctx, func(ctx context.Context, search Search) (err error) {
+		res, total, err = search.SearchQuestions(ctx, cond)
+		return err
+	})
+	return res, total, err
+}
+
+func (g *guardedSearch) SearchAnswers(ctx context.Context, cond *SearchBasicCond) (
+	res []SearchResult, total int64, err error) {
+	err = g.call(ctx, func(ctx context.Context, search Search) (err error) {
+		res, total, err = search.SearchAnswers(ctx, cond)
+		return err
+	})
+	return res, total, err
+}
+
+func (g *guardedSearch) UpdateContent(ctx context.Context, content *SearchContent) error {
+	return g.call(ctx, func(ctx context.Context, search Search) error {
+		return search.UpdateContent(ctx, content)
+	})
+}
+
+func (g *guardedSearch) DeleteContent(ctx context.Context, objectID string) error {
+	return g.call(ctx, func(ctx context.Context, search Search) error {
+		return search.DeleteContent(ctx, objectID)
+	})
+}
+
+func (g *guardedSearch) facets(ctx context.Context, cond *SearchBasicCond) (facets *SearchFacets, err error) {
+	err = g.call(ctx, func(ctx context.Context, search Search) (err error) {
+		facets, err = search.(Facets).Facets(ctx, cond)
+		return err
+	})
+	return facets, err
+}
+
+func (g *guardedSearch) countContents(ctx context.Context) (questions, answers int64, err error) {
+	err = g.call(ctx, func(ctx context.Context, search Search) (err error) {
+		questions, answers, err = search.(SearchCounter).CountContents(ctx)
+		return err
+	})
+	return questions, answers, err
+}
+
+type guardedFacetsSearch struct {
+	*guardedSearch
+}
+
+func (g *guardedFacetsSearch) Facets(ctx context.Context, cond *SearchBasicCond) (*SearchFacets, error) {
+	return g.facets(ctx, cond)
+}
+
+type guardedCounterSearch struct {
+	*guardedSearch
+}
+
+func (g *guardedCounterSearch) CountContents(ctx context.Context) (questions, answers int64, err error) {
+	return g.countContents(ctx)
+}
+
+type guardedFacetsCounterSearch struct {
+	*guardedSearch
+}
+
+func (g *guardedFacetsCounterSearch) Facets(ctx context.Context, cond *SearchBasicCond) (*SearchFacets, error) {
+	return g.facets(ctx, cond)
+}
+
+func (g *guardedFacetsCounterSearch) CountContents(ctx context.Context) (questions, answers int64, err error) {
+	return g.countContents(ctx)
+}
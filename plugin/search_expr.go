@@ -0,0 +1,100 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package plugin
+
+import (
+	"strings"
+)
+
+type SearchExprKind string
+
+const (
+	// SearchExprTerm matches a word
+	SearchExprTerm SearchExprKind = "term"
+	// SearchExprPhrase matches words next to each other in order
+	SearchExprPhrase SearchExprKind = "phrase"
+	// SearchExprAnd matches when all the children match
+	SearchExprAnd SearchExprKind = "and"
+	// SearchExprOr matches when any of the children matches
+	SearchExprOr SearchExprKind = "or"
+	// SearchExprNot matches when its only child does not match
+	SearchExprNot SearchExprKind = "not"
+)
+
+// SearchExpr is the tree of the keywords of a search query, like `deploy -friday ("blue green" OR canary)`.
+// The filters such as [tag] or user:name are not part of it, they are the other fields of SearchBasicCond.
+type SearchExpr struct {
+	Kind SearchExprKind
+	// Text the word of a term or the words of a phrase
+	Text string
+	// Children the operands of and, or and not
+	Children []*SearchExpr
+}
+
+// Terms returns the text of the terms and phrases the results contain, the excluded ones are left out
+func (e *SearchExpr) Terms() (terms []string) {
+	if e == nil {
+		return nil
+	}
+	switch e.Kind {
+	case SearchExprTerm, SearchExprPhrase:
+		return []string{e.Text}
+	case SearchExprNot:
+		return nil
+	}
+	for _, child := range e.Children {
+		terms = append(terms, child.Terms()...)
+	}
+	return terms
+}
+
+// String formats the expression in the query syntax
+func (e *SearchExpr) String() string {
+	if e == nil {
+		return ""
+	}
+	switch e.Kind {
+	case SearchExprTerm:
+		return e.Text
+	case SearchExprPhrase:
+		return `"` + e.Text + `"`
+	case SearchExprNot:
+		if len(e.Children) == 0 {
+			return ""
+		}
+		return "-" + e.Children[0].groupString()
+	}
+	parts := make([]string, 0, len(e.Children))
+	for _, child := range e.Children {
+		parts = append(parts, child.groupString())
+	}
+	if e.Kind == SearchExprOr {
+		return strings.Join(parts, " OR ")
+	}
+	return strings.Join(parts, " ")
+}
+
+// groupString wraps and and or in parentheses, so they keep their meaning as an operand
+func (e *SearchExpr) groupString() string {
+	if (e.Kind == SearchExprAnd || e.Kind == SearchExprOr) && len(e.Children) > 1 {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
@@ -506,6 +506,8 @@ export interface SearchParams extends ImgCodeReq {
   order: string;
   page: number;
   size?: number;
+  fragment_size?: number;
+  facets?: boolean;
 }
 
 /**
@@ -526,10 +528,50 @@ export interface SearchResItem {
     accepted: boolean;
     tags: TagBase[];
     status?: string;
+    title_highlight?: string;
+    snippets?: string[];
   };
 }
+export interface SearchFacet {
+  value: string;
+  count: number;
+}
+
+export interface SearchFacets {
+  tags: Array<{ slug_name: string; display_name: string; count: number }>;
+  types: SearchFacet[];
+  accepted: SearchFacet[];
+  created: SearchFacet[];
+}
+
 export interface SearchRes extends ListResult<SearchResItem> {
   extra: any;
+  facets?: SearchFacets;
+  search_id?: string;
+}
+
+export interface SearchClickReq {
+  search_id: string;
+  object_id: string;
+  position: number;
+}
+
+export interface SavedSearchReq {
+  id?: number;
+  name: string;
+  query: string;
+  notify_inbox: boolean;
+  notify_email: boolean;
+}
+
+export interface SavedSearchItem {
+  id: number;
+  name: string;
+  query: string;
+  notify_inbox: boolean;
+  notify_email: boolean;
+  created_at: number;
+  last_run_at: number;
 }
 
 export interface AdminDashboard {
@@ -18,21 +18,39 @@
  */
 
 import { FC, memo } from 'react';
+import { Button } from 'react-bootstrap';
 import { useTranslation } from 'react-i18next';
 
 import { QueryGroup } from '@/components';
+import { postSavedSearch } from '@/services';
+import { toastStore } from '@/stores';
 
 const sortBtns = ['relevance', 'newest', 'active', 'score'];
 
 interface Props {
   count: number;
   sort: string;
+  query?: string;
 }
-const Index: FC<Props> = ({ sort, count = 0 }) => {
+const Index: FC<Props> = ({ sort, count = 0, query = '' }) => {
   const { t } = useTranslation('translation', {
     keyPrefix: 'search.sort_btns',
   });
 
+  const handleSave = () => {
+    postSavedSearch({
+      name: query.slice(0, 100),
+      query,
+      notify_inbox: true,
+      notify_email: false,
+    }).then(() => {
+      toastStore.getState().show({
+        msg: t('saved', { keyPrefix: 'search.save_search' }),
+        variant: 'success',
+      });
+    });
+  };
+
   return (
     <div className="d-flex flex-wrap align-items-center justify-content-between pt-2 pb-3">
       <h5 className="mb-0">
@@ -40,6 +58,15 @@ const Index: FC<Props> = ({ sort, count = 0 }) => {
           ? t('counts_loading', { keyPrefix: 'search' })
           : t('counts', { count, keyPrefix: 'search' })}
       </h5>
+      {query && (
+        <Button
+          variant="link"
+          size="sm"
+          className="ms-auto me-2"
+          onClick={handleSave}>
+          {t('btn', { keyPrefix: 'search.save_search' })}
+        </Button>
+      )}
       <QueryGroup
         data={sortBtns}
         currentSort={sort}
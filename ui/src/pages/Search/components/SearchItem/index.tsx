@@ -36,8 +36,9 @@ import { escapeRemove } from '@/utils';
 
 interface Props {
   data: SearchResItem;
+  onClick?: () => void;
 }
-const Index: FC<Props> = ({ data }) => {
+const Index: FC<Props> = ({ data, onClick }) => {
   const { t } = useTranslation('translation', { keyPrefix: 'question' });
   if (!data?.object_type) {
     return null;
@@ -70,8 +71,18 @@ const Index: FC<Props> = ({ data }) => {
           style={{ marginTop: '2px' }}>
           {t(data.object_type, { keyPrefix: 'btns' })}
         </span>
-        <Link className="h5 mb-0 link-dark text-break" to={itemUrl}>
-          <HighlightText text={data.object.title} keywords={keywords} />
+        <Link
+          className="h5 mb-0 link-dark text-break"
+          to={itemUrl}
+          onClick={onClick}>
+          {data.object.title_highlight ? (
+            // the highlights are escaped by the server but for <mark>
+            <span
+              dangerouslySetInnerHTML={{ __html: data.object.title_highlight }}
+            />
+          ) : (
+            <HighlightText text={data.object.title} keywords={keywords} />
+          )}
           {data.object.status === 'closed'
             ? ` [${t('closed', { keyPrefix: 'question' })}]`
             : null}
@@ -97,7 +108,14 @@ const Index: FC<Props> = ({ data }) => {
         />
       </div>
 
-      {data.object?.excerpt && (
+      {data.object?.snippets?.length ? (
+        <p
+          className="small text-truncate-2 mb-2 last-p text-break"
+          dangerouslySetInnerHTML={{ __html: data.object.snippets.join(' ') }}
+        />
+      ) : null}
+
+      {!data.object?.snippets?.length && data.object?.excerpt && (
         <p className="small text-truncate-2 mb-2 last-p text-break">
           <HighlightText
             text={escapeRemove(data.object.excerpt) || ''}
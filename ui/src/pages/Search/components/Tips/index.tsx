@@ -42,9 +42,24 @@ const Index: FC = () => {
         <div className="mb-1">
           <Trans i18nKey="search.tips.question" components={{ 1: <code /> }} />
         </div>
-        <div>
+        <div className="mb-1">
           <Trans i18nKey="search.tips.is_answer" components={{ 1: <code /> }} />
         </div>
+        <div className="mb-1">
+          <Trans i18nKey="search.tips.phrase" components={{ 1: <code /> }} />
+        </div>
+        <div className="mb-1">
+          <Trans i18nKey="search.tips.or" components={{ 1: <code /> }} />
+        </div>
+        <div className="mb-1">
+          <Trans i18nKey="search.tips.exclude" components={{ 1: <code /> }} />
+        </div>
+        <div className="mb-1">
+          <Trans i18nKey="search.tips.created" components={{ 1: <code /> }} />
+        </div>
+        <div>
+          <Trans i18nKey="search.tips.topic" components={{ 1: <code /> }} />
+        </div>
       </Card.Body>
     </Card>
   );
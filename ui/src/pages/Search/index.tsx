@@ -25,7 +25,7 @@ import { useEffect, useState } from 'react';
 import { usePageTags, useSkeletonControl } from '@/hooks';
 import { useCaptchaPlugin } from '@/utils/pluginKit';
 import { Pagination } from '@/components';
-import { getSearchResult } from '@/services';
+import { getSearchResult, postSearchClick } from '@/services';
 import type { SearchParams, SearchRes } from '@/common/interface';
 import { logged } from '@/utils/guard';
 
@@ -110,13 +110,31 @@ const Index = () => {
       <Col className="page-main flex-auto">
         <Head data={extra} />
         {isLogged && <AiCard />}
-        <SearchHead sort={order} count={isLoading ? -1 : count} />
+        <SearchHead
+          sort={order}
+          count={isLoading ? -1 : count}
+          query={isLogged ? q : ''}
+        />
         <ListGroup className="rounded-0 mb-5">
           {isSkeletonShow ? (
             <ListLoader />
           ) : (
-            list?.map((item) => {
-              return <SearchItem key={item.object.id} data={item} />;
+            list?.map((item, index) => {
+              return (
+                <SearchItem
+                  key={item.object.id}
+                  data={item}
+                  onClick={() => {
+                    if (data.search_id) {
+                      postSearchClick({
+                        search_id: data.search_id,
+                        object_id: item.object.id,
+                        position: index + 1,
+                      }).catch(() => {});
+                    }
+                  }}
+                />
+              );
             })
           )}
         </ListGroup>
@@ -27,3 +27,28 @@ export const getSearchResult = (params?: Type.SearchParams) => {
     params,
   });
 };
+
+export const postSearchClick = (params: Type.SearchClickReq) => {
+  return request.post('/answer/api/v1/search/click', params);
+};
+
+export const getSavedSearches = () => {
+  return request.get<Type.SavedSearchItem[]>('/answer/api/v1/saved-searches');
+};
+
+export const postSavedSearch = (params: Type.SavedSearchReq) => {
+  return request.post<Type.SavedSearchItem>(
+    '/answer/api/v1/saved-search',
+    params,
+  );
+};
+
+export const putSavedSearch = (params: Type.SavedSearchReq) => {
+  return request.put('/answer/api/v1/saved-search', params);
+};
+
+export const deleteSavedSearch = (id: number) => {
+  return request.delete('/answer/api/v1/saved-search', {
+    data: { id },
+  });
+};